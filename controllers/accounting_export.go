@@ -0,0 +1,314 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"schoolPayment/constants"
+	request "schoolPayment/dtos/request"
+	services "schoolPayment/services"
+	"schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type AccountingExportController struct {
+	accountingExportService services.AccountingExportServiceInterface
+}
+
+func NewAccountingExportController(accountingExportService services.AccountingExportServiceInterface) *AccountingExportController {
+	return &AccountingExportController{accountingExportService: accountingExportService}
+}
+
+// @Summary Create Accounting Account Mapping
+// @Description Map a ledger account, billing type or bank account to an account code of the accounting software. mappingType is ledger_account, billing_type or bank_account; reference is the ledger account code or the billing type or bank account ID.
+// @Tags Accounting Export
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param request body request.AccountingAccountMappingRequest true "Accounting Account Mapping Request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/accountingExport/mapping/create [post]
+func (accountingExportController *AccountingExportController) CreateAccountingAccountMapping(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	var mappingRequest request.AccountingAccountMappingRequest
+	if err := c.BodyParser(&mappingRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.CannotParseJsonMessage,
+		})
+	}
+
+	mapping, err := accountingExportController.accountingExportService.CreateAccountingAccountMapping(&mappingRequest, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil disimpan.",
+		"data":    mapping,
+	})
+}
+
+// @Summary Update Accounting Account Mapping
+// @Description Update an account mapping. Exports made before are downloaded with the new mapping.
+// @Tags Accounting Export
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Accounting Account Mapping ID"
+// @Param request body request.AccountingAccountMappingRequest true "Accounting Account Mapping Request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/accountingExport/mapping/update/{id} [put]
+func (accountingExportController *AccountingExportController) UpdateAccountingAccountMapping(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	mappingID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid accounting account mapping ID",
+		})
+	}
+
+	var mappingRequest request.AccountingAccountMappingRequest
+	if err := c.BodyParser(&mappingRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.CannotParseJsonMessage,
+		})
+	}
+
+	mapping, err := accountingExportController.accountingExportService.UpdateAccountingAccountMapping(uint(mappingID), &mappingRequest, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil dirubah.",
+		"data":    mapping,
+	})
+}
+
+// @Summary Delete Accounting Account Mapping
+// @Description Delete an account mapping. The ledger account is exported with its own code again.
+// @Tags Accounting Export
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Accounting Account Mapping ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/accountingExport/mapping/delete/{id} [delete]
+func (accountingExportController *AccountingExportController) DeleteAccountingAccountMapping(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	mappingID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid accounting account mapping ID",
+		})
+	}
+
+	if err := accountingExportController.accountingExportService.DeleteAccountingAccountMapping(uint(mappingID), userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil dihapus.",
+	})
+}
+
+// @Summary Get List Accounting Account Mapping
+// @Description Get the account mappings of the school
+// @Tags Accounting Export
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/accountingExport/mapping/getList [get]
+func (accountingExportController *AccountingExportController) GetAccountingAccountMappings(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	mappings, err := accountingExportController.accountingExportService.GetAccountingAccountMappings(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": mappings,
+	})
+}
+
+// @Summary Create Accounting Export
+// @Description Export the journal entries of the period that were not exported before as daily journals for the accounting software. exportFormat is accurate, jurnal_id or generic; fileType is csv (default) or xlsx.
+// @Tags Accounting Export
+// @Accept json
+// @Produce octet-stream
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param request body request.CreateAccountingExportRequest true "Create Accounting Export Request"
+// @Success 200 {file} file "Journal import file"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/accountingExport/create [post]
+func (accountingExportController *AccountingExportController) CreateAccountingExport(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	var exportRequest request.CreateAccountingExportRequest
+	if err := c.BodyParser(&exportRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.CannotParseJsonMessage,
+		})
+	}
+
+	buffer, filename, err := accountingExportController.accountingExportService.CreateAccountingExport(&exportRequest, userID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return sendAccountingExport(c, buffer, filename)
+}
+
+// @Summary Get List Accounting Export
+// @Description Get paginated accounting exports of the school
+// @Tags Accounting Export
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param page query int false "Page number"
+// @Param limit query int false "Limit per page"
+// @Success 200 {object} response.AccountingExportListResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/accountingExport/getList [get]
+func (accountingExportController *AccountingExportController) GetAllAccountingExport(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 10)
+
+	exports, err := accountingExportController.accountingExportService.GetAllAccountingExport(page, limit, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(exports)
+}
+
+// @Summary Download Accounting Export
+// @Description Download the file of an earlier export again, with the current account mappings
+// @Tags Accounting Export
+// @Produce octet-stream
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Accounting Export ID"
+// @Param fileType query string false "csv or xlsx"
+// @Success 200 {file} file "Journal import file"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/accountingExport/download/{id} [get]
+func (accountingExportController *AccountingExportController) DownloadAccountingExport(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	exportID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid accounting export ID",
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	buffer, filename, err := accountingExportController.accountingExportService.DownloadAccountingExport(uint(exportID), c.Query("fileType"), userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return sendAccountingExport(c, buffer, filename)
+}
+
+func sendAccountingExport(c *fiber.Ctx, buffer *bytes.Buffer, filename string) error {
+	contentType := "text/csv"
+	if strings.HasSuffix(filename, ".xlsx") {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+
+	c.Set("Content-Type", contentType)
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	return c.Send(buffer.Bytes())
+}
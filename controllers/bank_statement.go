@@ -0,0 +1,300 @@
+package controllers
+
+import (
+	"io"
+	"strconv"
+
+	"schoolPayment/constants"
+	request "schoolPayment/dtos/request"
+	services "schoolPayment/services"
+	"schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type BankStatementController struct {
+	bankStatementService services.BankStatementServiceInterface
+}
+
+func NewBankStatementController(bankStatementService services.BankStatementServiceInterface) *BankStatementController {
+	return &BankStatementController{bankStatementService: bankStatementService}
+}
+
+// @Summary Import Bank Statement
+// @Description Upload a mutation file of a school bank account. fileFormat is bca_csv, bri_csv, mandiri_csv or mt940. Incoming transfers are stored and matched to open installments for review; transfers imported before are skipped.
+// @Tags Bank Statement
+// @Accept multipart/form-data
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param file formData file true "Mutation file"
+// @Param bankAccountId formData int true "Bank Account ID"
+// @Param fileFormat formData string true "File format"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/bankStatement/import [post]
+func (bankStatementController *BankStatementController) ImportBankStatement(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	var importRequest request.ImportBankStatementRequest
+	if err := c.BodyParser(&importRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.CannotParseJsonMessage,
+		})
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to upload file",
+		})
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	defer src.Close()
+
+	content, err := io.ReadAll(src)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	statementImport, err := bankStatementController.bankStatementService.ImportBankStatement(&importRequest, file.Filename, content, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil disimpan.",
+		"data":    statementImport,
+	})
+}
+
+// @Summary Get List Bank Statement Import
+// @Description Get paginated bank statement uploads of the school
+// @Tags Bank Statement
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param page query int false "Page number"
+// @Param limit query int false "Limit per page"
+// @Param bankAccountId query int false "Filter by bank account"
+// @Success 200 {object} response.BankStatementImportListResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/bankStatement/getList [get]
+func (bankStatementController *BankStatementController) GetAllBankStatementImport(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 10)
+	bankAccountID := c.QueryInt("bankAccountId", 0)
+
+	imports, err := bankStatementController.bankStatementService.GetAllBankStatementImport(page, limit, uint(bankAccountID), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(imports)
+}
+
+// @Summary Get Bank Statement Import
+// @Description Get a bank statement upload with its totals
+// @Tags Bank Statement
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Bank Statement Import ID"
+// @Success 200 {object} models.BankStatementImport
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/bankStatement/detail/{id} [get]
+func (bankStatementController *BankStatementController) GetBankStatementImportByID(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	importID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid bank statement import ID",
+		})
+	}
+
+	statementImport, err := bankStatementController.bankStatementService.GetBankStatementImportByID(uint(importID), userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": constants.DataNotFoundMessage,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": statementImport,
+	})
+}
+
+// @Summary Get List Bank Statement Line
+// @Description Get paginated incoming transfers with their proposed match. matchStatus is BS01 (unmatched), BS02 (proposed), BS03 (posted) or BS04 (ignored).
+// @Tags Bank Statement
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param page query int false "Page number"
+// @Param limit query int false "Limit per page"
+// @Param importId query int false "Filter by upload"
+// @Param matchStatus query string false "Filter by match status"
+// @Success 200 {object} response.BankStatementLineListResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/bankStatement/line/getList [get]
+func (bankStatementController *BankStatementController) GetAllBankStatementLine(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 10)
+	importID := c.QueryInt("importId", 0)
+	matchStatus := c.Query("matchStatus")
+
+	lines, err := bankStatementController.bankStatementService.GetAllBankStatementLine(page, limit, uint(importID), matchStatus, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(lines)
+}
+
+// @Summary Confirm Bank Statement Line
+// @Description Post an incoming transfer as a paid bank transfer transaction. Without a body the proposed match is used; otherwise studentId and billingStudentIds choose the installments. Money left over becomes student credit.
+// @Tags Bank Statement
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Bank Statement Line ID"
+// @Param request body request.ConfirmBankStatementLineRequest false "Confirm Bank Statement Line Request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/bankStatement/line/confirm/{id} [put]
+func (bankStatementController *BankStatementController) ConfirmBankStatementLine(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	lineID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid bank statement line ID",
+		})
+	}
+
+	var confirmRequest request.ConfirmBankStatementLineRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&confirmRequest); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": constants.CannotParseJsonMessage,
+			})
+		}
+	}
+
+	line, err := bankStatementController.bankStatementService.ConfirmBankStatementLine(uint(lineID), &confirmRequest, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil disimpan.",
+		"data":    line,
+	})
+}
+
+// @Summary Ignore Bank Statement Line
+// @Description Close an incoming transfer that is not a school payment, such as interest or a transfer between the school's own accounts.
+// @Tags Bank Statement
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Bank Statement Line ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/bankStatement/line/ignore/{id} [put]
+func (bankStatementController *BankStatementController) IgnoreBankStatementLine(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	lineID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid bank statement line ID",
+		})
+	}
+
+	line, err := bankStatementController.bankStatementService.IgnoreBankStatementLine(uint(lineID), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil dirubah.",
+		"data":    line,
+	})
+}
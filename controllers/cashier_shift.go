@@ -0,0 +1,312 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+
+	"schoolPayment/constants"
+	request "schoolPayment/dtos/request"
+	services "schoolPayment/services"
+	"schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type CashierShiftController struct {
+	cashierShiftService services.CashierShiftServiceInterface
+}
+
+func NewCashierShiftController(cashierShiftService services.CashierShiftServiceInterface) *CashierShiftController {
+	return &CashierShiftController{cashierShiftService: cashierShiftService}
+}
+
+// @Summary Open Cashier Shift
+// @Description Open a cashier shift with the opening float in the drawer. Every kasir payment is linked to the open shift of the user taking it.
+// @Tags Cashier Shift
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param request body request.OpenCashierShiftRequest true "Open Cashier Shift Request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/cashierShift/open [post]
+func (cashierShiftController *CashierShiftController) OpenCashierShift(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var openRequest request.OpenCashierShiftRequest
+	if err := c.BodyParser(&openRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.FailedToParseRequestBodyMessage,
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	shift, err := cashierShiftController.cashierShiftService.OpenCashierShift(&openRequest, userID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Shift kasir berhasil dibuka.",
+		"data":    shift,
+	})
+}
+
+// @Summary Get Current Cashier Shift
+// @Description Get the open shift of the logged in user with the running cash totals
+// @Tags Cashier Shift
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Success 200 {object} models.CashierShift
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/cashierShift/current [get]
+func (cashierShiftController *CashierShiftController) GetCurrentCashierShift(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	shift, err := cashierShiftController.cashierShiftService.GetCurrentCashierShift(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": shift,
+	})
+}
+
+// @Summary Close Cashier Shift
+// @Description Close a cashier shift with the counted cash. The variance is the counted cash minus the opening float and the cash received during the shift.
+// @Tags Cashier Shift
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Cashier Shift ID"
+// @Param request body request.CloseCashierShiftRequest true "Close Cashier Shift Request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/cashierShift/close/{id} [put]
+func (cashierShiftController *CashierShiftController) CloseCashierShift(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	shiftID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid cashier shift ID",
+		})
+	}
+
+	var closeRequest request.CloseCashierShiftRequest
+	if err := c.BodyParser(&closeRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.FailedToParseRequestBodyMessage,
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	shift, err := cashierShiftController.cashierShiftService.CloseCashierShift(uint(shiftID), &closeRequest, userID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Shift kasir berhasil ditutup.",
+		"data":    shift,
+	})
+}
+
+// @Summary Get List Cashier Shift
+// @Description Get paginated cashier shifts of the school. A kasir only sees their own shifts.
+// @Tags Cashier Shift
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param page query int false "Page number"
+// @Param limit query int false "Limit per page"
+// @Param userId query int false "Filter by cashier user ID"
+// @Param status query string false "Filter by status (open/closed)"
+// @Param startDate query string false "Start date filter in yyyy-MM-dd format"
+// @Param endDate query string false "End date filter in yyyy-MM-dd format"
+// @Success 200 {object} response.CashierShiftListResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/cashierShift/getList [get]
+func (cashierShiftController *CashierShiftController) GetAllCashierShift(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 10)
+	cashierID := c.QueryInt("userId", 0)
+	status := c.Query("status", "")
+
+	var startDate, endDate time.Time
+	if startDateStr := c.Query("startDate", ""); startDateStr != "" {
+		startDate, err = utilities.ParseDate(startDateStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid start date format",
+			})
+		}
+	}
+	if endDateStr := c.Query("endDate", ""); endDateStr != "" {
+		endDate, err = utilities.ParseDate(endDateStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid end date format",
+			})
+		}
+		if !startDate.IsZero() && endDate.Before(startDate) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "End date must be after start date",
+			})
+		}
+	}
+
+	shifts, err := cashierShiftController.cashierShiftService.GetAllCashierShift(page, limit, cashierID, status, startDate, endDate, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(shifts)
+}
+
+// @Summary Get Cashier Shift Report
+// @Description Get a cashier shift with its kasir payments and cash variance
+// @Tags Cashier Shift
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Cashier Shift ID"
+// @Success 200 {object} response.CashierShiftReport
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/cashierShift/detail/{id} [get]
+func (cashierShiftController *CashierShiftController) GetCashierShiftReport(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	shiftID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid cashier shift ID",
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	report, err := cashierShiftController.cashierShiftService.GetCashierShiftReport(uint(shiftID), userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": report,
+	})
+}
+
+// @Summary Export Cashier Shift Report to PDF
+// @Description Generate the shift report with the cash variance as a PDF file
+// @Tags Cashier Shift
+// @Produce application/pdf
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Cashier Shift ID"
+// @Success 200 {file} file "PDF file with the shift report"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/cashierShift/exportPdf/{id} [get]
+func (cashierShiftController *CashierShiftController) ExportCashierShiftPDF(c *fiber.Ctx) error {
+	return cashierShiftController.exportCashierShift(c, "application/pdf", cashierShiftController.cashierShiftService.ExportCashierShiftPDF)
+}
+
+// @Summary Export Cashier Shift Report to Excel
+// @Description Generate the shift report with the cash variance as an Excel file
+// @Tags Cashier Shift
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Cashier Shift ID"
+// @Success 200 {file} file "Excel file with the shift report"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/cashierShift/exportExcel/{id} [get]
+func (cashierShiftController *CashierShiftController) ExportCashierShiftExcel(c *fiber.Ctx) error {
+	return cashierShiftController.exportCashierShift(c, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", cashierShiftController.cashierShiftService.ExportCashierShiftExcel)
+}
+
+func (cashierShiftController *CashierShiftController) exportCashierShift(c *fiber.Ctx, contentType string, export func(id uint, userID int) (*bytes.Buffer, string, error)) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	shiftID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid cashier shift ID",
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	buffer, filename, err := export(uint(shiftID), userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Set("Content-Type", contentType)
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	return c.Send(buffer.Bytes())
+}
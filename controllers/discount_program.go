@@ -0,0 +1,447 @@
+package controllers
+
+import (
+	"strconv"
+
+	"schoolPayment/constants"
+	request "schoolPayment/dtos/request"
+	services "schoolPayment/services"
+	"schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type DiscountProgramController struct {
+	discountProgramService services.DiscountProgramServiceInterface
+}
+
+func NewDiscountProgramController(discountProgramService services.DiscountProgramServiceInterface) *DiscountProgramController {
+	return &DiscountProgramController{discountProgramService: discountProgramService}
+}
+
+// @Summary Create Discount Program
+// @Description Create a scholarship or discount program. discountType is percentage or fixed; billingTypeId and schoolYearId limit where it applies.
+// @Tags Discount Program
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param request body request.DiscountProgramRequest true "Discount Program Request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/discountProgram/create [post]
+func (discountProgramController *DiscountProgramController) CreateDiscountProgram(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	var programRequest request.DiscountProgramRequest
+	if err := c.BodyParser(&programRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.CannotParseJsonMessage,
+		})
+	}
+
+	program, err := discountProgramController.discountProgramService.CreateDiscountProgram(&programRequest, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil disimpan.",
+		"data":    program,
+	})
+}
+
+// @Summary Update Discount Program
+// @Description Update a discount program. Installments already billed keep their discount.
+// @Tags Discount Program
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Discount Program ID"
+// @Param request body request.DiscountProgramRequest true "Discount Program Request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/discountProgram/update/{id} [put]
+func (discountProgramController *DiscountProgramController) UpdateDiscountProgram(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	programID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid discount program ID",
+		})
+	}
+
+	var programRequest request.DiscountProgramRequest
+	if err := c.BodyParser(&programRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.CannotParseJsonMessage,
+		})
+	}
+
+	program, err := discountProgramController.discountProgramService.UpdateDiscountProgram(uint(programID), &programRequest, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil dirubah.",
+		"data":    program,
+	})
+}
+
+// @Summary Delete Discount Program
+// @Description Delete a discount program. Installments already billed keep their discount.
+// @Tags Discount Program
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Discount Program ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/discountProgram/delete/{id} [delete]
+func (discountProgramController *DiscountProgramController) DeleteDiscountProgram(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	programID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid discount program ID",
+		})
+	}
+
+	if err := discountProgramController.discountProgramService.DeleteDiscountProgram(uint(programID), userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil dihapus.",
+	})
+}
+
+// @Summary Get List Discount Program
+// @Description Get paginated discount programs of the school
+// @Tags Discount Program
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param page query int false "Page number"
+// @Param limit query int false "Limit per page"
+// @Param search query string false "Search by program name"
+// @Success 200 {object} response.DiscountProgramListResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/discountProgram/getList [get]
+func (discountProgramController *DiscountProgramController) GetAllDiscountProgram(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 10)
+	search := c.Query("search")
+
+	programs, err := discountProgramController.discountProgramService.GetAllDiscountProgram(page, limit, search, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(programs)
+}
+
+// @Summary Get Discount Program
+// @Description Get a discount program of the school
+// @Tags Discount Program
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Discount Program ID"
+// @Success 200 {object} models.DiscountProgram
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/discountProgram/detail/{id} [get]
+func (discountProgramController *DiscountProgramController) GetDiscountProgramByID(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	programID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid discount program ID",
+		})
+	}
+
+	program, err := discountProgramController.discountProgramService.GetDiscountProgramByID(uint(programID), userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": constants.DataNotFoundMessage,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": program,
+	})
+}
+
+// @Summary Assign Discount To Student
+// @Description Assign a discount program to a student for a validity period (validFrom, validUntil as YYYY-MM-DD). The assignment waits for Admin Sekolah approval.
+// @Tags Student Discount
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param request body request.CreateStudentDiscountRequest true "Create Student Discount Request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/studentDiscount/create [post]
+func (discountProgramController *DiscountProgramController) CreateStudentDiscount(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	var discountRequest request.CreateStudentDiscountRequest
+	if err := c.BodyParser(&discountRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.CannotParseJsonMessage,
+		})
+	}
+
+	studentDiscount, err := discountProgramController.discountProgramService.CreateStudentDiscount(&discountRequest, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil disimpan.",
+		"data":    studentDiscount,
+	})
+}
+
+// @Summary Approve Student Discount
+// @Description Approve a student discount. Installments created afterwards for the student are billed net of the discount.
+// @Tags Student Discount
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Student Discount ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/studentDiscount/approve/{id} [put]
+func (discountProgramController *DiscountProgramController) ApproveStudentDiscount(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	studentDiscountID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid student discount ID",
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	studentDiscount, err := discountProgramController.discountProgramService.ApproveStudentDiscount(uint(studentDiscountID), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Student discount approved successfully.",
+		"data":    studentDiscount,
+	})
+}
+
+// @Summary Reject Student Discount
+// @Description Reject a waiting student discount with a reason.
+// @Tags Student Discount
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Student Discount ID"
+// @Param request body request.RejectStudentDiscountRequest true "Reject Student Discount Request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/studentDiscount/reject/{id} [put]
+func (discountProgramController *DiscountProgramController) RejectStudentDiscount(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	studentDiscountID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid student discount ID",
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	var rejectRequest request.RejectStudentDiscountRequest
+	if err := c.BodyParser(&rejectRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.CannotParseJsonMessage,
+		})
+	}
+
+	studentDiscount, err := discountProgramController.discountProgramService.RejectStudentDiscount(uint(studentDiscountID), &rejectRequest, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Student discount rejected successfully.",
+		"data":    studentDiscount,
+	})
+}
+
+// @Summary Get List Student Discount
+// @Description Get paginated student discounts of the school
+// @Tags Student Discount
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param page query int false "Page number"
+// @Param limit query int false "Limit per page"
+// @Param studentId query int false "Student ID"
+// @Param discountStatus query string false "Discount status code (SD01, SD02, SD03)"
+// @Success 200 {object} response.StudentDiscountListResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/studentDiscount/getList [get]
+func (discountProgramController *DiscountProgramController) GetAllStudentDiscount(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 10)
+	studentID := c.QueryInt("studentId", 0)
+	discountStatus := c.Query("discountStatus")
+
+	studentDiscounts, err := discountProgramController.discountProgramService.GetAllStudentDiscount(page, limit, uint(studentID), discountStatus, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(studentDiscounts)
+}
+
+// @Summary Get Student Discount
+// @Description Get a student discount with its program
+// @Tags Student Discount
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Student Discount ID"
+// @Success 200 {object} models.StudentDiscount
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/studentDiscount/detail/{id} [get]
+func (discountProgramController *DiscountProgramController) GetStudentDiscountByID(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	studentDiscountID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid student discount ID",
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	studentDiscount, err := discountProgramController.discountProgramService.GetStudentDiscountByID(uint(studentDiscountID), userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": constants.DataNotFoundMessage,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": studentDiscount,
+	})
+}
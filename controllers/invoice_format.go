@@ -14,6 +14,7 @@ import (
 type InvoiceFormatServiceInterface interface {
 	Create(request *request.CreateInvoiceFormatRequest, userID int) (*models.InvoiceFormat, error)
 	GetBySchoolID(schoolID uint) (*models.InvoiceFormat, error)
+	Preview(request *request.CreateInvoiceFormatRequest) ([]string, error)
 }
 
 type InvoiceFormatController struct {
@@ -27,7 +28,7 @@ func NewInvoiceFormatController(service InvoiceFormatServiceInterface) *InvoiceF
 }
 
 // @Summary Create Invoice Format
-// @Description Create a new invoice format for a school
+// @Description Create a new invoice format for a school. A template such as "{PREFIX}/{SCHOOL_CODE}/{YYYY}/{MM}/{SEQ:6}" replaces the fixed format; with preview set the next numbers are returned without saving.
 // @Tags Invoice Format
 // @Accept json
 // @Produce json
@@ -72,6 +73,19 @@ func (c *InvoiceFormatController) Create(ctx *fiber.Ctx) error {
 		})
 	}
 
+	if request.Preview {
+		invoiceNumbers, err := c.service.Preview(&request)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+			"data": invoiceNumbers,
+		})
+	}
+
 	// Call the service to create or update the invoice format
 	invoiceFormat, err := c.service.Create(&request, userID)
 	if err != nil {
@@ -121,6 +135,8 @@ func (c *InvoiceFormatController) GetBySchoolID(ctx *fiber.Ctx) error {
 		SchoolID:               invoiceFormat.SchoolID,
 		Prefix:                 invoiceFormat.Prefix,
 		Format:                 invoiceFormat.Format,
+		Template:               invoiceFormat.Template,
+		ResetPeriod:            invoiceFormat.ResetPeriod,
 		GeneratedInvoiceFormat: invoiceFormat.GeneratedInvoiceFormat,
 		CreatedBy:              invoiceFormat.CreatedBy,
 		UpdatedBy:              invoiceFormat.UpdatedBy,
@@ -40,6 +40,14 @@ func (m *MockInvoiceFormatService) GetBySchoolID(schoolID uint) (*models.Invoice
 	return nil, args.Error(1)
 }
 
+func (m *MockInvoiceFormatService) Preview(req *request.CreateInvoiceFormatRequest) ([]string, error) {
+	args := m.Called(req)
+	if args.Get(0) != nil {
+		return args.Get(0).([]string), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 func generateJWTInvoiceFormat(claims jwt.MapClaims) string {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, _ := token.SignedString([]byte("your-secret-key"))
@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"schoolPayment/constants"
+	services "schoolPayment/services"
+	"schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type JournalController struct {
+	journalService services.JournalServiceInterface
+}
+
+func NewJournalController(journalService services.JournalServiceInterface) *JournalController {
+	return &JournalController{journalService: journalService}
+}
+
+// @Summary Run Journal
+// @Description Post the general ledger entries of every financial event that is not in the journal yet. Called by the scheduler.
+// @Tags Journal
+// @Produce json
+// @Success 200 {object} response.JournalSyncResponse
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/journal/run [get]
+func (journalController *JournalController) RunJournal(c *fiber.Ctx) error {
+	result, err := journalController.journalService.RunJournal()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Journal finished.",
+		"data":    result,
+	})
+}
+
+// @Summary Get Ledger Accounts
+// @Description Get the chart of accounts of the school
+// @Tags Journal
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/journal/account/getList [get]
+func (journalController *JournalController) GetLedgerAccounts(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	accounts, err := journalController.journalService.GetLedgerAccounts(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": accounts,
+	})
+}
+
+// @Summary Get List Journal Entry
+// @Description Get paginated journal entries of the school with their lines. sourceType is billing_student, transaction_billing, transaction_refund or transaction_void.
+// @Tags Journal
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param page query int false "Page number"
+// @Param limit query int false "Limit per page"
+// @Param sourceType query string false "Filter by source type"
+// @Param startDate query string false "Start date filter in yyyy-MM-dd format"
+// @Param endDate query string false "End date filter in yyyy-MM-dd format"
+// @Success 200 {object} response.JournalEntryListResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/journal/getList [get]
+func (journalController *JournalController) GetAllJournalEntry(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 10)
+	sourceType := c.Query("sourceType", "")
+
+	startDate, endDate, err := parseJournalPeriod(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	entries, err := journalController.journalService.GetAllJournalEntry(page, limit, sourceType, startDate, endDate, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(entries)
+}
+
+// @Summary Get Journal Entry
+// @Description Get a journal entry with its lines
+// @Tags Journal
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Journal Entry ID"
+// @Success 200 {object} models.JournalEntry
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/journal/detail/{id} [get]
+func (journalController *JournalController) GetJournalEntryByID(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	entryID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid journal entry ID",
+		})
+	}
+
+	entry, err := journalController.journalService.GetJournalEntryByID(uint(entryID), userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": constants.DataNotFoundMessage,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": entry,
+	})
+}
+
+// @Summary Get Trial Balance
+// @Description Get the opening balance, movement and closing balance of every account of the school for a period. Without dates the current month is used.
+// @Tags Journal
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param startDate query string false "Start date in yyyy-MM-dd format"
+// @Param endDate query string false "End date in yyyy-MM-dd format"
+// @Success 200 {object} response.TrialBalanceResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/journal/trialBalance [get]
+func (journalController *JournalController) GetTrialBalance(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	startDate, endDate, err := parseJournalPeriod(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	trialBalance, err := journalController.journalService.GetTrialBalance(startDate, endDate, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": trialBalance,
+	})
+}
+
+// parseJournalPeriod reads the optional startDate and endDate query parameters.
+func parseJournalPeriod(c *fiber.Ctx) (time.Time, time.Time, error) {
+	var startDate, endDate time.Time
+	var err error
+	if startDateStr := c.Query("startDate", ""); startDateStr != "" {
+		startDate, err = utilities.ParseDate(startDateStr)
+		if err != nil {
+			return startDate, endDate, errors.New("Invalid start date format")
+		}
+	}
+	if endDateStr := c.Query("endDate", ""); endDateStr != "" {
+		endDate, err = utilities.ParseDate(endDateStr)
+		if err != nil {
+			return startDate, endDate, errors.New("Invalid end date format")
+		}
+		if !startDate.IsZero() && endDate.Before(startDate) {
+			return startDate, endDate, errors.New("End date must be after start date")
+		}
+	}
+	return startDate, endDate, nil
+}
@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"strconv"
+
+	"schoolPayment/constants"
+	request "schoolPayment/dtos/request"
+	services "schoolPayment/services"
+	"schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type LateFeeController struct {
+	lateFeeService services.LateFeeServiceInterface
+}
+
+func NewLateFeeController(lateFeeService services.LateFeeServiceInterface) *LateFeeController {
+	return &LateFeeController{lateFeeService: lateFeeService}
+}
+
+// @Summary Run Late Fee
+// @Description Charge the penalty of every overdue installment up to what its late fee rule allows today. Called daily by the scheduler.
+// @Tags Late Fee
+// @Produce json
+// @Success 200 {object} response.LateFeeRunResponse
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/lateFee/run [get]
+func (lateFeeController *LateFeeController) RunLateFee(c *fiber.Ctx) error {
+	result, err := lateFeeController.lateFeeService.RunLateFee()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Late fee finished.",
+		"data":    result,
+	})
+}
+
+// @Summary Create Late Fee Rule
+// @Description Create a penalty rule for a billing or a billing type. calculationType is flat or percentage, period is once, daily or monthly.
+// @Tags Late Fee
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param request body request.LateFeeRuleRequest true "Late Fee Rule Request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/lateFee/create [post]
+func (lateFeeController *LateFeeController) CreateLateFeeRule(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	var ruleRequest request.LateFeeRuleRequest
+	if err := c.BodyParser(&ruleRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.CannotParseJsonMessage,
+		})
+	}
+
+	rule, err := lateFeeController.lateFeeService.CreateLateFeeRule(&ruleRequest, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil disimpan.",
+		"data":    rule,
+	})
+}
+
+// @Summary Update Late Fee Rule
+// @Description Update a penalty rule. Penalties already charged are kept.
+// @Tags Late Fee
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Late Fee Rule ID"
+// @Param request body request.LateFeeRuleRequest true "Late Fee Rule Request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/lateFee/update/{id} [put]
+func (lateFeeController *LateFeeController) UpdateLateFeeRule(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	ruleID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid late fee rule ID",
+		})
+	}
+
+	var ruleRequest request.LateFeeRuleRequest
+	if err := c.BodyParser(&ruleRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.CannotParseJsonMessage,
+		})
+	}
+
+	rule, err := lateFeeController.lateFeeService.UpdateLateFeeRule(uint(ruleID), &ruleRequest, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil dirubah.",
+		"data":    rule,
+	})
+}
+
+// @Summary Delete Late Fee Rule
+// @Description Delete a penalty rule. Penalties already charged are kept.
+// @Tags Late Fee
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Late Fee Rule ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/lateFee/delete/{id} [delete]
+func (lateFeeController *LateFeeController) DeleteLateFeeRule(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	ruleID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid late fee rule ID",
+		})
+	}
+
+	if err := lateFeeController.lateFeeService.DeleteLateFeeRule(uint(ruleID), userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil dihapus.",
+	})
+}
+
+// @Summary Get List Late Fee Rule
+// @Description Get paginated late fee rules of the school
+// @Tags Late Fee
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param page query int false "Page number"
+// @Param limit query int false "Limit per page"
+// @Success 200 {object} response.LateFeeRuleListResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/lateFee/getList [get]
+func (lateFeeController *LateFeeController) GetAllLateFeeRule(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 10)
+
+	rules, err := lateFeeController.lateFeeService.GetAllLateFeeRule(page, limit, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(rules)
+}
+
+// @Summary Get Late Fee Rule
+// @Description Get a late fee rule of the school
+// @Tags Late Fee
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Late Fee Rule ID"
+// @Success 200 {object} models.LateFeeRule
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/lateFee/detail/{id} [get]
+func (lateFeeController *LateFeeController) GetLateFeeRuleByID(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	ruleID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid late fee rule ID",
+		})
+	}
+
+	rule, err := lateFeeController.lateFeeService.GetLateFeeRuleByID(uint(ruleID), userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": constants.DataNotFoundMessage,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": rule,
+	})
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"strconv"
+
+	"schoolPayment/constants"
+	request "schoolPayment/dtos/request"
+	services "schoolPayment/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type PaymentLinkController struct {
+	paymentLinkService services.PaymentLinkServiceInterface
+}
+
+func NewPaymentLinkController(paymentLinkService services.PaymentLinkServiceInterface) *PaymentLinkController {
+	return &PaymentLinkController{paymentLinkService: paymentLinkService}
+}
+
+// @Summary Get Payment Links
+// @Description Get the payment links of a student that were not revoked, with their URL and WhatsApp text
+// @Tags Payment Link
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param studentId path int true "Student ID"
+// @Success 200 {array} response.PaymentLinkResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/paymentLink/getList/{studentId} [get]
+func (paymentLinkController *PaymentLinkController) GetPaymentLinks(c *fiber.Ctx) error {
+	studentID, err := strconv.Atoi(c.Params("studentId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid student ID",
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	paymentLinks, err := paymentLinkController.paymentLinkService.GetPaymentLinks(uint(studentID), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(paymentLinks)
+}
+
+// @Summary Create Payment Link
+// @Description Create a signed link that lets someone without an account pay the selected installments of a student. The link expires after expiryHours (72 by default, at most 168).
+// @Tags Payment Link
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param request body request.CreatePaymentLinkRequest true "Create Payment Link Request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/paymentLink/create [post]
+func (paymentLinkController *PaymentLinkController) CreatePaymentLink(c *fiber.Ctx) error {
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	var linkRequest request.CreatePaymentLinkRequest
+	if err := c.BodyParser(&linkRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.CannotParseJsonMessage,
+		})
+	}
+
+	paymentLink, err := paymentLinkController.paymentLinkService.CreatePaymentLink(&linkRequest, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil disimpan.",
+		"data":    paymentLink,
+	})
+}
+
+// @Summary Revoke Payment Link
+// @Description Revoke a payment link so it can no longer be opened or paid with
+// @Tags Payment Link
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Payment Link ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/paymentLink/revoke/{id} [delete]
+func (paymentLinkController *PaymentLinkController) RevokePaymentLink(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid ID",
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	if err := paymentLinkController.paymentLinkService.RevokePaymentLink(uint(id), userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil dihapus.",
+	})
+}
+
+// @Summary Get Public Payment Link
+// @Description Get the summary of a payment link for whoever opens it, without login. The student name is masked.
+// @Tags Payment Link
+// @Produce json
+// @Param token path string true "Payment Link Token"
+// @Success 200 {object} response.PublicPaymentLinkResponse
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/paymentLink/public/{token} [get]
+func (paymentLinkController *PaymentLinkController) GetPublicPaymentLink(c *fiber.Ctx) error {
+	paymentLink, err := paymentLinkController.paymentLinkService.GetPublicPaymentLink(c.Params("token"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(paymentLink)
+}
+
+// @Summary Checkout Public Payment Link
+// @Description Start a Snap checkout for the installments of a payment link that are still open, without login
+// @Tags Payment Link
+// @Accept json
+// @Produce json
+// @Param token path string true "Payment Link Token"
+// @Param request body request.PaymentLinkCheckoutRequest true "Payment Link Checkout Request"
+// @Success 200 {object} response.MidtransResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/paymentLink/public/{token}/checkout [post]
+func (paymentLinkController *PaymentLinkController) CheckoutPaymentLink(c *fiber.Ctx) error {
+	var checkoutRequest request.PaymentLinkCheckoutRequest
+	if err := c.BodyParser(&checkoutRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.CannotParseJsonMessage,
+		})
+	}
+
+	rsp, err := paymentLinkController.paymentLinkService.CheckoutPaymentLink(c.Params("token"), &checkoutRequest)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(rsp)
+}
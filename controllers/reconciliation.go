@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"strconv"
+
+	"schoolPayment/constants"
+	services "schoolPayment/services"
+	"schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReconciliationController struct {
+	reconciliationService services.ReconciliationServiceInterface
+}
+
+func NewReconciliationController(reconciliationService services.ReconciliationServiceInterface) *ReconciliationController {
+	return &ReconciliationController{reconciliationService: reconciliationService}
+}
+
+// @Summary Run Gateway Reconciliation
+// @Description Check every pending online transaction at the payment gateway, apply the real status and store a discrepancy report. Called by the scheduler.
+// @Tags Reconciliation
+// @Produce json
+// @Success 200 {object} models.ReconciliationRun
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/reconciliation/run [get]
+func (reconciliationController *ReconciliationController) RunReconciliation(c *fiber.Ctx) error {
+	run, err := reconciliationController.reconciliationService.RunReconciliation(c)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Reconciliation finished.",
+		"data":    run,
+	})
+}
+
+// @Summary Get List Reconciliation Run
+// @Description Get paginated reconciliation runs with their totals
+// @Tags Reconciliation
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param page query int false "Page number"
+// @Param limit query int false "Limit per page"
+// @Success 200 {object} response.ReconciliationRunListResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/reconciliation/getList [get]
+func (reconciliationController *ReconciliationController) GetAllReconciliationRun(c *fiber.Ctx) error {
+	err := utilities.CheckAccessSuperAdmin(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 10)
+
+	runs, err := reconciliationController.reconciliationService.GetAllReconciliationRun(page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(runs)
+}
+
+// @Summary Get Reconciliation Report
+// @Description Get a reconciliation run with its discrepancies (paid_not_recorded, amount_mismatch, unknown_order)
+// @Tags Reconciliation
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Reconciliation Run ID"
+// @Success 200 {object} models.ReconciliationRun
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/reconciliation/detail/{id} [get]
+func (reconciliationController *ReconciliationController) GetReconciliationRunByID(c *fiber.Ctx) error {
+	err := utilities.CheckAccessSuperAdmin(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	runID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid reconciliation run ID",
+		})
+	}
+
+	run, err := reconciliationController.reconciliationService.GetReconciliationRunByID(uint(runID))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": constants.DataNotFoundMessage,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": run,
+	})
+}
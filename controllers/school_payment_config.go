@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"strconv"
+
+	"schoolPayment/constants"
+	request "schoolPayment/dtos/request"
+	services "schoolPayment/services"
+	"schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type SchoolPaymentConfigController struct {
+	schoolPaymentConfigService services.SchoolPaymentConfigServiceInterface
+}
+
+func NewSchoolPaymentConfigController(schoolPaymentConfigService services.SchoolPaymentConfigServiceInterface) *SchoolPaymentConfigController {
+	return &SchoolPaymentConfigController{schoolPaymentConfigService: schoolPaymentConfigService}
+}
+
+// @Summary Get School Payment Config
+// @Description Get the payment gateway configuration of the user's school
+// @Tags School Payment Config
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Success 200 {object} response.SchoolPaymentConfigResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/schoolPaymentConfig/detail [get]
+func (schoolPaymentConfigController *SchoolPaymentConfigController) GetSchoolPaymentConfig(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	config, err := schoolPaymentConfigController.schoolPaymentConfigService.GetSchoolPaymentConfig(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": config,
+	})
+}
+
+// @Summary Update School Payment Config
+// @Description Choose the payment gateway provider (midtrans, xendit), the Midtrans merchant account (sandbox or production) used for online payments of the user's school and who pays the admin fee. adminFeeBearer is parent, school or split; adminFeeParentPercentage is the parent's share of a split.
+// @Tags School Payment Config
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param request body request.SchoolPaymentConfigRequest true "School Payment Config Request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/schoolPaymentConfig/update [put]
+func (schoolPaymentConfigController *SchoolPaymentConfigController) UpdateSchoolPaymentConfig(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	var configRequest request.SchoolPaymentConfigRequest
+	if err := c.BodyParser(&configRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.CannotParseJsonMessage,
+		})
+	}
+
+	config, err := schoolPaymentConfigController.schoolPaymentConfigService.UpdateSchoolPaymentConfig(&configRequest, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil disimpan.",
+		"data":    config,
+	})
+}
+
+// @Summary Get List Payment Method Admin Fee
+// @Description Get the payment methods whose admin fee is paid differently from the school's setting
+// @Tags School Payment Config
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/schoolPaymentConfig/adminFee/getList [get]
+func (schoolPaymentConfigController *SchoolPaymentConfigController) GetSchoolPaymentMethodFees(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	fees, err := schoolPaymentConfigController.schoolPaymentConfigService.GetSchoolPaymentMethodFees(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": fees,
+	})
+}
+
+// @Summary Save Payment Method Admin Fee
+// @Description Set who pays the admin fee of one payment method. adminFeeBearer is parent, school or split; adminFeeParentPercentage is the parent's share of a split.
+// @Tags School Payment Config
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param request body request.SchoolPaymentMethodFeeRequest true "School Payment Method Fee Request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/schoolPaymentConfig/adminFee/save [put]
+func (schoolPaymentConfigController *SchoolPaymentConfigController) SaveSchoolPaymentMethodFee(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	var feeRequest request.SchoolPaymentMethodFeeRequest
+	if err := c.BodyParser(&feeRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.CannotParseJsonMessage,
+		})
+	}
+
+	fee, err := schoolPaymentConfigController.schoolPaymentConfigService.SaveSchoolPaymentMethodFee(&feeRequest, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil disimpan.",
+		"data":    fee,
+	})
+}
+
+// @Summary Delete Payment Method Admin Fee
+// @Description Put the payment method back on the school's admin fee setting
+// @Tags School Payment Config
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param paymentMethodId path int true "Payment Method ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/schoolPaymentConfig/adminFee/delete/{paymentMethodId} [delete]
+func (schoolPaymentConfigController *SchoolPaymentConfigController) DeleteSchoolPaymentMethodFee(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	paymentMethodID, err := strconv.Atoi(c.Params("paymentMethodId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid payment method ID",
+		})
+	}
+
+	if err := schoolPaymentConfigController.schoolPaymentConfigService.DeleteSchoolPaymentMethodFee(uint(paymentMethodID), userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil dihapus.",
+	})
+}
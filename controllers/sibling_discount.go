@@ -0,0 +1,262 @@
+package controllers
+
+import (
+	"strconv"
+
+	"schoolPayment/constants"
+	request "schoolPayment/dtos/request"
+	services "schoolPayment/services"
+	"schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type SiblingDiscountController struct {
+	siblingDiscountService services.SiblingDiscountServiceInterface
+}
+
+func NewSiblingDiscountController(siblingDiscountService services.SiblingDiscountServiceInterface) *SiblingDiscountController {
+	return &SiblingDiscountController{siblingDiscountService: siblingDiscountService}
+}
+
+// @Summary Create Sibling Discount Tier
+// @Description Create a sibling discount tier. childOrder is the child in the household (oldest first) the tier starts at, at least 2; discountType is percentage or fixed.
+// @Tags Sibling Discount
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param request body request.SiblingDiscountTierRequest true "Sibling Discount Tier Request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/siblingDiscount/create [post]
+func (siblingDiscountController *SiblingDiscountController) CreateSiblingDiscountTier(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	var tierRequest request.SiblingDiscountTierRequest
+	if err := c.BodyParser(&tierRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.CannotParseJsonMessage,
+		})
+	}
+
+	tier, err := siblingDiscountController.siblingDiscountService.CreateSiblingDiscountTier(&tierRequest, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil disimpan.",
+		"data":    tier,
+	})
+}
+
+// @Summary Update Sibling Discount Tier
+// @Description Update a sibling discount tier. Open installments follow after a recalculation.
+// @Tags Sibling Discount
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Sibling Discount Tier ID"
+// @Param request body request.SiblingDiscountTierRequest true "Sibling Discount Tier Request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/siblingDiscount/update/{id} [put]
+func (siblingDiscountController *SiblingDiscountController) UpdateSiblingDiscountTier(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	tierID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid sibling discount tier ID",
+		})
+	}
+
+	var tierRequest request.SiblingDiscountTierRequest
+	if err := c.BodyParser(&tierRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.CannotParseJsonMessage,
+		})
+	}
+
+	tier, err := siblingDiscountController.siblingDiscountService.UpdateSiblingDiscountTier(uint(tierID), &tierRequest, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil dirubah.",
+		"data":    tier,
+	})
+}
+
+// @Summary Delete Sibling Discount Tier
+// @Description Delete a sibling discount tier. Open installments follow after a recalculation.
+// @Tags Sibling Discount
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Sibling Discount Tier ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/siblingDiscount/delete/{id} [delete]
+func (siblingDiscountController *SiblingDiscountController) DeleteSiblingDiscountTier(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	tierID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid sibling discount tier ID",
+		})
+	}
+
+	if err := siblingDiscountController.siblingDiscountService.DeleteSiblingDiscountTier(uint(tierID), userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil dihapus.",
+	})
+}
+
+// @Summary Get List Sibling Discount Tier
+// @Description Get paginated sibling discount tiers of the school, ordered by child order
+// @Tags Sibling Discount
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param page query int false "Page number"
+// @Param limit query int false "Limit per page"
+// @Success 200 {object} response.SiblingDiscountTierListResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/siblingDiscount/getList [get]
+func (siblingDiscountController *SiblingDiscountController) GetAllSiblingDiscountTier(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 10)
+
+	tiers, err := siblingDiscountController.siblingDiscountService.GetAllSiblingDiscountTier(page, limit, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(tiers)
+}
+
+// @Summary Get Sibling Discount Tier
+// @Description Get a sibling discount tier of the school
+// @Tags Sibling Discount
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Sibling Discount Tier ID"
+// @Success 200 {object} models.SiblingDiscountTier
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/siblingDiscount/detail/{id} [get]
+func (siblingDiscountController *SiblingDiscountController) GetSiblingDiscountTierByID(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserTuKasirAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	tierID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid sibling discount tier ID",
+		})
+	}
+
+	tier, err := siblingDiscountController.siblingDiscountService.GetSiblingDiscountTierByID(uint(tierID), userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": constants.DataNotFoundMessage,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": tier,
+	})
+}
+
+// @Summary Recalculate Sibling Discount
+// @Description Recalculate the sibling discount of every open installment of the school from the current households and tiers. Paid, partly paid and reserved installments are left as they are.
+// @Tags Sibling Discount
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Success 200 {object} response.SiblingDiscountRecalculateResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/siblingDiscount/recalculate [post]
+func (siblingDiscountController *SiblingDiscountController) RecalculateSiblingDiscount(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	result, err := siblingDiscountController.siblingDiscountService.RecalculateSiblingDiscount(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": result,
+	})
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"strconv"
+
+	"schoolPayment/constants"
+	request "schoolPayment/dtos/request"
+	services "schoolPayment/services"
+	"schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type StudentCreditController struct {
+	studentCreditService services.StudentCreditServiceInterface
+}
+
+func NewStudentCreditController(studentCreditService services.StudentCreditServiceInterface) *StudentCreditController {
+	return &StudentCreditController{studentCreditService: studentCreditService}
+}
+
+// @Summary Get Student Credit History
+// @Description Get the credit balance of a student with its paginated movements (overpayments, refunds to credit, adjustments and usage)
+// @Tags Student Credit
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param studentId path int true "Student ID"
+// @Param page query int false "Page number"
+// @Param limit query int false "Limit per page"
+// @Success 200 {object} response.StudentCreditHistoryResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/studentCredit/history/{studentId} [get]
+func (studentCreditController *StudentCreditController) GetStudentCreditHistory(c *fiber.Ctx) error {
+	studentID, err := strconv.Atoi(c.Params("studentId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid student ID",
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 10)
+
+	history, err := studentCreditController.studentCreditService.GetStudentCreditHistory(uint(studentID), page, limit, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(history)
+}
+
+// @Summary Adjust Student Credit
+// @Description Add or take off credit manually. A negative amount cannot take the balance below zero.
+// @Tags Student Credit
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param request body request.StudentCreditAdjustmentRequest true "Student Credit Adjustment Request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/studentCredit/adjust [post]
+func (studentCreditController *StudentCreditController) AdjustStudentCredit(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	var adjustmentRequest request.StudentCreditAdjustmentRequest
+	if err := c.BodyParser(&adjustmentRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.CannotParseJsonMessage,
+		})
+	}
+
+	credit, err := studentCreditController.studentCreditService.AdjustStudentCredit(&adjustmentRequest, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil disimpan.",
+		"data":    credit,
+	})
+}
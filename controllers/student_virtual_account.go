@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"strconv"
+
+	"schoolPayment/constants"
+	request "schoolPayment/dtos/request"
+	services "schoolPayment/services"
+	"schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type StudentVirtualAccountController struct {
+	studentVirtualAccountService services.StudentVirtualAccountServiceInterface
+}
+
+func NewStudentVirtualAccountController(studentVirtualAccountService services.StudentVirtualAccountServiceInterface) *StudentVirtualAccountController {
+	return &StudentVirtualAccountController{studentVirtualAccountService: studentVirtualAccountService}
+}
+
+// @Summary Get Student Virtual Accounts
+// @Description Get the fixed virtual account numbers issued to a student
+// @Tags Student Virtual Account
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param studentId path int true "Student ID"
+// @Success 200 {array} models.StudentVirtualAccount
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/studentVirtualAccount/getList/{studentId} [get]
+func (studentVirtualAccountController *StudentVirtualAccountController) GetStudentVirtualAccounts(c *fiber.Ctx) error {
+	studentID, err := strconv.Atoi(c.Params("studentId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid student ID",
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	virtualAccounts, err := studentVirtualAccountController.studentVirtualAccountService.GetStudentVirtualAccounts(uint(studentID), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(virtualAccounts)
+}
+
+// @Summary Issue Student Virtual Account
+// @Description Issue a fixed virtual account number to a student, derived from the school's VA prefix and the student's NIS. Payments to it settle the oldest unpaid billings first.
+// @Tags Student Virtual Account
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param request body request.IssueStudentVirtualAccountRequest true "Issue Student Virtual Account Request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/studentVirtualAccount/issue [post]
+func (studentVirtualAccountController *StudentVirtualAccountController) IssueStudentVirtualAccount(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	var issueRequest request.IssueStudentVirtualAccountRequest
+	if err := c.BodyParser(&issueRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.CannotParseJsonMessage,
+		})
+	}
+
+	virtualAccount, err := studentVirtualAccountController.studentVirtualAccountService.IssueStudentVirtualAccount(&issueRequest, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil disimpan.",
+		"data":    virtualAccount,
+	})
+}
+
+// @Summary Revoke Student Virtual Account
+// @Description Revoke a fixed virtual account number. Later checkouts of the student get a one-off number again.
+// @Tags Student Virtual Account
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Student Virtual Account ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/studentVirtualAccount/revoke/{id} [delete]
+func (studentVirtualAccountController *StudentVirtualAccountController) RevokeStudentVirtualAccount(c *fiber.Ctx) error {
+	err := utilities.CheckAccessAdminSekolah(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid ID",
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	if err := studentVirtualAccountController.studentVirtualAccountService.RevokeStudentVirtualAccount(uint(id), userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Data berhasil dihapus.",
+	})
+}
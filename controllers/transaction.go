@@ -2,10 +2,12 @@ package controllers
 
 import (
 	"fmt"
+	"strconv"
 
 	"schoolPayment/constants"
 	"schoolPayment/dtos/request"
 	services "schoolPayment/services"
+	"schoolPayment/utilities"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
@@ -68,6 +70,7 @@ func (transactionController *TransactionController) MidtransPayment(c *fiber.Ctx
 		request.PaymentMethodId,
 		userID,
 		request.BillingStudentIds,
+		request.UseCredit,
 	)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -77,6 +80,120 @@ func (transactionController *TransactionController) MidtransPayment(c *fiber.Ctx
 	return c.JSON(rsp)
 }
 
+// MidtransDirectPayment charges the installments through the Midtrans Core API.
+// @Summary Make Midtrans Direct Payment
+// @Description Charge the installments without the Snap payment page and get the virtual account number, QRIS string or e-wallet deeplink so the app can show its own payment instructions. The payment status is sent to the webhook like a Snap payment.
+// @Tags Transactions
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param request body request.CreateTransactionRequest true "Create Transaction Request"
+// @Success 200 {object} response.DirectPaymentResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/transaction/midtrans/charge [post]
+func (transactionController *TransactionController) MidtransDirectPayment(c *fiber.Ctx) error {
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	var request request.CreateTransactionRequest
+
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.FailedToParseRequestBodyMessage,
+		})
+	}
+
+	rsp, err := transactionController.transactionService.MidtransDirectPayment(
+		request.StudentId,
+		request.PaymentMethodId,
+		userID,
+		request.BillingStudentIds,
+		request.UseCredit,
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(rsp)
+}
+
+// MidtransGroupPayment pays the installments of several children with one Snap payment.
+// @Summary Make Midtrans Combined Payment
+// @Description Pay installments of several children linked to the logged-in parent with one Snap payment and one admin fee. Each child gets its own transaction and invoice under the order. studentId and useCredit are ignored.
+// @Tags Transactions
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param request body request.CreateTransactionRequest true "Create Transaction Request"
+// @Success 200 {object} response.MidtransResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/transaction/midtrans/groupPayment [post]
+func (transactionController *TransactionController) MidtransGroupPayment(c *fiber.Ctx) error {
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	var request request.CreateTransactionRequest
+
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.FailedToParseRequestBodyMessage,
+		})
+	}
+
+	rsp, err := transactionController.transactionService.MidtransGroupPayment(
+		request.PaymentMethodId,
+		userID,
+		request.BillingStudentIds,
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(rsp)
+}
+
+// PreviewAdminFee shows what each payment method costs the parent before checkout.
+// @Summary Preview Admin Fee
+// @Description Get the admin fee and the gross amount charged at the gateway for the installments with every payment method, or only with paymentMethodId when it is given. The fee follows the school's admin fee bearer setting.
+// @Tags Transactions
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param request body request.CreateTransactionRequest true "Create Transaction Request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/transaction/midtrans/feePreview [post]
+func (transactionController *TransactionController) PreviewAdminFee(c *fiber.Ctx) error {
+	var request request.CreateTransactionRequest
+
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": constants.FailedToParseRequestBodyMessage,
+		})
+	}
+
+	previews, err := transactionController.transactionService.PreviewAdminFee(
+		request.StudentId,
+		request.PaymentMethodId,
+		request.BillingStudentIds,
+		request.UseCredit,
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": previews,
+	})
+}
+
 // PaymentDonation processes a donation payment.
 // @Summary Make Payment Donation
 // @Description Process a payment donation for a student.
@@ -184,6 +301,40 @@ func (transactionController *TransactionController) CreateTransaction(c *fiber.C
 	})
 }
 
+// @Summary Cancel Pending Online Payment
+// @Description Cancel a pending online payment at the payment gateway so the parent can pay the installments with another method.
+// @Tags Transactions
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param orderId path string true "Order ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/transaction/cancel/{orderId} [put]
+func (transactionController *TransactionController) CancelPendingTransaction(c *fiber.Ctx) error {
+	err := utilities.CheckAccessUserOrtu(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	userID := int(userClaims["user_id"].(float64))
+
+	transaction, err := transactionController.transactionService.CancelPendingTransaction(c.Params("orderId"), userID, c)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Transaction cancelled successfully.",
+		"data":    transaction,
+	})
+}
+
 // HandleWebhook processes a webhook from payment gateway.
 // @Summary Handle Payment Webhook
 // @Description Handle incoming webhook from the payment gateway for transaction status updates.
@@ -227,3 +378,141 @@ func (transactionController *TransactionController) HandleWebhook(c *fiber.Ctx)
 		"message": "Webhook received",
 	})
 }
+
+// HandleGatewayWebhook processes a notification from a specific payment gateway provider.
+// @Summary Handle Payment Gateway Webhook
+// @Description Handle incoming notification from the given payment gateway provider (midtrans, xendit).
+// @Tags Transactions
+// @Accept json
+// @Produce json
+// @Param provider path string true "Payment gateway provider"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/webhook/{provider} [post]
+func (transactionController *TransactionController) HandleGatewayWebhook(c *fiber.Ctx) error {
+	provider := c.Params("provider")
+
+	err := transactionController.transactionService.UpdateFromGatewayWebhook(provider, c)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "Webhook received",
+	})
+}
+
+// @Summary Get List Webhook Event
+// @Description Get paginated webhook events received from the payment gateway
+// @Tags Transactions
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param page query int false "Page number"
+// @Param limit query int false "Limit per page"
+// @Param processStatus query string false "Process status code (WS01, WS02, WS03, WS04)"
+// @Param orderId query string false "Order ID"
+// @Success 200 {object} response.WebhookEventListResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/webhookEvent/getList [get]
+func (transactionController *TransactionController) GetAllWebhookEvent(c *fiber.Ctx) error {
+	err := utilities.CheckAccessSuperAdmin(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 10)
+	processStatus := c.Query("processStatus")
+	orderID := c.Query("orderId")
+
+	events, err := transactionController.transactionService.GetAllWebhookEvent(page, limit, processStatus, orderID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(events)
+}
+
+// @Summary Get Webhook Event Detail
+// @Description Get a webhook event with its stored payload
+// @Tags Transactions
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Webhook Event ID"
+// @Success 200 {object} models.WebhookEvent
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/webhookEvent/detail/{id} [get]
+func (transactionController *TransactionController) GetWebhookEventByID(c *fiber.Ctx) error {
+	err := utilities.CheckAccessSuperAdmin(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	eventID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid webhook event ID",
+		})
+	}
+
+	event, err := transactionController.transactionService.GetWebhookEventByID(uint(eventID))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": constants.DataNotFoundMessage,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data": event,
+	})
+}
+
+// @Summary Replay Webhook Event
+// @Description Re-run a failed webhook event after the underlying data has been fixed
+// @Tags Transactions
+// @Produce json
+// @Param Authorization header string true "Authorization" format("Bearer token")
+// @Param id path int true "Webhook Event ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/webhookEvent/replay/{id} [post]
+func (transactionController *TransactionController) ReplayWebhookEvent(c *fiber.Ctx) error {
+	err := utilities.CheckAccessSuperAdmin(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	eventID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid webhook event ID",
+		})
+	}
+
+	event, err := transactionController.transactionService.ReplayWebhookEvent(uint(eventID), c)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Webhook event replayed successfully.",
+		"data":    event,
+	})
+}
@@ -0,0 +1,15 @@
+package request
+
+type AccountingAccountMappingRequest struct {
+	MappingType string `json:"mappingType"`
+	Reference   string `json:"reference"`
+	AccountCode string `json:"accountCode"`
+	AccountName string `json:"accountName"`
+}
+
+type CreateAccountingExportRequest struct {
+	StartDate    string `json:"startDate"`
+	EndDate      string `json:"endDate"`
+	ExportFormat string `json:"exportFormat"`
+	FileType     string `json:"fileType"`
+}
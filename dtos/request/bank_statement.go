@@ -0,0 +1,12 @@
+package request
+
+type ImportBankStatementRequest struct {
+	BankAccountId uint   `json:"bankAccountId" form:"bankAccountId"`
+	FileFormat    string `json:"fileFormat" form:"fileFormat"`
+}
+
+// ConfirmBankStatementLineRequest overrides the proposed match; leave it empty to post the proposal.
+type ConfirmBankStatementLineRequest struct {
+	StudentId         uint  `json:"studentId"`
+	BillingStudentIds []int `json:"billingStudentIds"`
+}
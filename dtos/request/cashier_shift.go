@@ -0,0 +1,11 @@
+package request
+
+type OpenCashierShiftRequest struct {
+	OpeningFloat int64  `json:"openingFloat"`
+	Note         string `json:"note"`
+}
+
+type CloseCashierShiftRequest struct {
+	CountedCash int64  `json:"countedCash"`
+	Note        string `json:"note"`
+}
@@ -0,0 +1,30 @@
+package request
+
+type DiscountProgramRequest struct {
+	ProgramName   string  `json:"programName"`
+	DiscountType  string  `json:"discountType"`
+	Amount        float64 `json:"amount"`
+	BillingTypeId *uint   `json:"billingTypeId"`
+	SchoolYearId  *uint   `json:"schoolYearId"`
+	Description   string  `json:"description"`
+	IsActive      *bool   `json:"isActive"`
+}
+
+type CreateStudentDiscountRequest struct {
+	StudentId         uint   `json:"studentId"`
+	DiscountProgramId uint   `json:"discountProgramId"`
+	ValidFrom         string `json:"validFrom"`
+	ValidUntil        string `json:"validUntil"`
+	Note              string `json:"note"`
+}
+
+type RejectStudentDiscountRequest struct {
+	RejectionReason string `json:"rejectionReason"`
+}
+
+type SiblingDiscountTierRequest struct {
+	ChildOrder   int     `json:"childOrder"`
+	DiscountType string  `json:"discountType"`
+	Amount       float64 `json:"amount"`
+	IsActive     *bool   `json:"isActive"`
+}
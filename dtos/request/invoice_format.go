@@ -3,6 +3,13 @@ package request
 type CreateInvoiceFormatRequest struct {
 	SchoolID               uint   `json:"schoolId" validate:"required"`
 	Prefix                 string `json:"prefix" validate:"required"`
-	Format                 string `json:"format" validate:"required"`
+	Format                 string `json:"format" validate:"required_without=Template"`
 	GeneratedInvoiceFormat string `json:"generatedInvoiceFormat"`
+	// Template builds the number from tokens, e.g. "{PREFIX}/{SCHOOL_CODE}/{YYYY}/{MM}/{SEQ:6}".
+	// When set it is used instead of Format.
+	Template    string `json:"template"`
+	ResetPeriod string `json:"resetPeriod" validate:"omitempty,oneof=never yearly monthly daily"`
+	// Preview returns the next PreviewCount numbers without saving the format
+	Preview      bool `json:"preview"`
+	PreviewCount int  `json:"previewCount"`
 }
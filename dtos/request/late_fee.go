@@ -0,0 +1,12 @@
+package request
+
+type LateFeeRuleRequest struct {
+	BillingId       *uint   `json:"billingId"`
+	BillingTypeId   *uint   `json:"billingTypeId"`
+	CalculationType string  `json:"calculationType"`
+	Amount          float64 `json:"amount"`
+	Period          string  `json:"period"`
+	GraceDays       int     `json:"graceDays"`
+	MaxAmount       *int64  `json:"maxAmount"`
+	IsActive        *bool   `json:"isActive"`
+}
@@ -0,0 +1,12 @@
+package request
+
+type CreatePaymentLinkRequest struct {
+	StudentID         uint     `json:"studentId"`
+	BillingStudentIds []string `json:"billingStudentIds"`
+	// ExpiryHours is how long the link can be paid with, 72 hours when left empty
+	ExpiryHours int `json:"expiryHours"`
+}
+
+type PaymentLinkCheckoutRequest struct {
+	PaymentMethodId int `json:"paymentMethodId"`
+}
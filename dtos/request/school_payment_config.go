@@ -0,0 +1,30 @@
+package request
+
+type SchoolPaymentConfigRequest struct {
+	PaymentGateway     string `json:"paymentGateway" validate:"required"`
+	MidtransMerchantID string `json:"midtransMerchantId"`
+	MidtransClientKey  string `json:"midtransClientKey"`
+	// MidtransServerKey is only sent when it changes, an empty value keeps the stored key
+	MidtransServerKey string `json:"midtransServerKey"`
+	IsProduction      bool   `json:"isProduction"`
+	// XenditSecretKey and XenditCallbackToken are only sent when they change, like the server key
+	XenditSecretKey     string `json:"xenditSecretKey"`
+	XenditCallbackToken string `json:"xenditCallbackToken"`
+	// AdminFeeBearer is parent, school or split, an empty value keeps the stored setting
+	AdminFeeBearer           string `json:"adminFeeBearer"`
+	AdminFeeParentPercentage int    `json:"adminFeeParentPercentage"`
+	// PaymentExpiryMinutes of 0 uses the default time to pay an online payment
+	PaymentExpiryMinutes int    `json:"paymentExpiryMinutes"`
+	FinishRedirectURL    string `json:"finishRedirectUrl"`
+	// VAPrefix only applies to fixed virtual accounts issued after it changes
+	VAPrefix string `json:"vaPrefix"`
+}
+
+// SchoolPaymentMethodFeeRequest sets the gateway, who pays the admin fee or both for one payment
+// method. An empty value keeps the school's setting.
+type SchoolPaymentMethodFeeRequest struct {
+	MasterPaymentMethodID    uint   `json:"masterPaymentMethodId" validate:"required"`
+	PaymentGateway           string `json:"paymentGateway"`
+	AdminFeeBearer           string `json:"adminFeeBearer"`
+	AdminFeeParentPercentage int    `json:"adminFeeParentPercentage"`
+}
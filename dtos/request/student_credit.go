@@ -0,0 +1,8 @@
+package request
+
+type StudentCreditAdjustmentRequest struct {
+	StudentID uint `json:"studentId"`
+	// Amount is added to the balance, a negative amount takes credit off
+	Amount int64  `json:"amount"`
+	Note   string `json:"note"`
+}
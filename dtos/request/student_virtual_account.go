@@ -0,0 +1,7 @@
+package request
+
+type IssueStudentVirtualAccountRequest struct {
+	StudentID uint `json:"studentId"`
+	// PaymentMethodID is the virtual account payment method the number is issued at
+	PaymentMethodID uint `json:"paymentMethodId"`
+}
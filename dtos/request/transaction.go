@@ -26,6 +26,11 @@ type CreateTransactionRequest struct {
 	Description       string   `json:"description"`
 	PaymentMethodId   int      `json:"paymentMethodId"`
 	Amount            int      `json:"amount"`
+	AllowPartial      bool     `json:"allowPartial"`
+	// UseCredit is the amount of the student's credit balance to spend on this payment
+	UseCredit int `json:"useCredit"`
+	// KeepChangeAsCredit books the change on the student's credit instead of handing it out
+	KeepChangeAsCredit bool `json:"keepChangeAsCredit"`
 }
 
 type SubmitTransactionRequest struct {
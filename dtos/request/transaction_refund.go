@@ -0,0 +1,13 @@
+package request
+
+type CreateRefundRequest struct {
+	TransactionBillingId uint     `json:"transactionBillingId"`
+	BillingStudentIds    []string `json:"billingStudentIds"`
+	Reason               string   `json:"reason"`
+	// ToCredit books the refund on the student's credit balance instead of paying it out
+	ToCredit bool `json:"toCredit"`
+}
+
+type RejectRefundRequest struct {
+	RejectionReason string `json:"rejectionReason"`
+}
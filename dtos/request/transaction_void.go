@@ -0,0 +1,10 @@
+package request
+
+type CreateVoidRequest struct {
+	TransactionBillingId uint   `json:"transactionBillingId"`
+	Reason               string `json:"reason"`
+}
+
+type RejectVoidRequest struct {
+	RejectionReason string `json:"rejectionReason"`
+}
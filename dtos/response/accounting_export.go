@@ -0,0 +1,39 @@
+package response
+
+import (
+	"time"
+
+	"schoolPayment/models"
+)
+
+type AccountingExportListResponse struct {
+	Page      int                       `json:"page"`
+	Limit     int                       `json:"limit"`
+	TotalPage int                       `json:"totalPage"`
+	TotalData int64                     `json:"totalData"`
+	Data      []models.AccountingExport `json:"data"`
+}
+
+// AccountingJournalLine is a journal line with what the export needs of its entry and account.
+type AccountingJournalLine struct {
+	JournalEntryID    uint      `json:"journalEntryId"`
+	EntryDate         time.Time `json:"entryDate"`
+	SourceType        string    `json:"sourceType"`
+	BillingTypeID     *uint     `json:"billingTypeId"`
+	BankAccountID     *uint     `json:"bankAccountId"`
+	LedgerAccountCode string    `json:"ledgerAccountCode"`
+	LedgerAccountName string    `json:"ledgerAccountName"`
+	Debit             int64     `json:"debit"`
+	Credit            int64     `json:"credit"`
+}
+
+// AccountingExportRow is one line of a daily journal in the accounting package's accounts.
+type AccountingExportRow struct {
+	VoucherNumber string    `json:"voucherNumber"`
+	Date          time.Time `json:"date"`
+	Description   string    `json:"description"`
+	AccountCode   string    `json:"accountCode"`
+	AccountName   string    `json:"accountName"`
+	Debit         int64     `json:"debit"`
+	Credit        int64     `json:"credit"`
+}
@@ -0,0 +1,24 @@
+package response
+
+import "schoolPayment/models"
+
+type BankStatementImportListResponse struct {
+	Page      int                          `json:"page"`
+	Limit     int                          `json:"limit"`
+	TotalPage int                          `json:"totalPage"`
+	TotalData int64                        `json:"totalData"`
+	Data      []models.BankStatementImport `json:"data"`
+}
+
+type BankStatementLineListResponse struct {
+	Page      int                        `json:"page"`
+	Limit     int                        `json:"limit"`
+	TotalPage int                        `json:"totalPage"`
+	TotalData int64                      `json:"totalData"`
+	Data      []models.BankStatementLine `json:"data"`
+}
+
+type BankStatementStudent struct {
+	StudentID uint   `json:"studentId"`
+	Nis       string `json:"nis"`
+}
@@ -9,7 +9,11 @@ type BillingReport struct {
 	SchoolGradeName   string `json:"schoolGradeName"`
 	SchoolClassName   string `json:"schoolClassName"`
 	SchoolYearName    string `json:"schoolYearName"`
+	GrossAmount       int64  `json:"grossAmount"`
+	DiscountAmount    int64  `json:"discountAmount"`
 	Amount            int64  `json:"amount"`
+	PaidAmount        int64  `json:"paidAmount"`
+	RemainingAmount   int64  `json:"remainingAmount"`
 	BankAccountId     int    `json:"bankAccountId"`
 	BankAccountName   string `json:"bankAccountName"`
 	BankName          string `json:"bank_name"`
@@ -24,7 +28,11 @@ type BillingReportDetail struct {
 	SchoolGradeName   string `json:"schoolGradeName"`
 	SchoolClassName   string `json:"schoolClassName"`
 	SchoolYearName    string `json:"schoolYearName"`
+	GrossAmount       int64  `json:"grossAmount"`
+	DiscountAmount    int64  `json:"discountAmount"`
 	Amount            int64  `json:"amount"`
+	PaidAmount        int64  `json:"paidAmount"`
+	RemainingAmount   int64  `json:"remainingAmount"`
 	BankAccountName   string `json:"bankAccountName"`
 	PaymentStatus     string `json:"paymentStatus"`
 }
@@ -38,6 +46,12 @@ type ListReportBilling struct {
 }
 
 type BillingReportResponse struct {
+	// TotalGrossAmount is the total before discounts (string representation of big.Int)
+	// @swagger:strfmt string
+	TotalGrossAmount *big.Int `json:"totalGrossAmount"`
+	// TotalDiscountAmount is the total of scholarships and discounts (string representation of big.Int)
+	// @swagger:strfmt string
+	TotalDiscountAmount *big.Int `json:"totalDiscountAmount"`
 	// TotalBillingAmount is the total billing amount (string representation of big.Int)
 	// @swagger:strfmt string
 	TotalBillingAmount *big.Int `json:"totalBillingAmount"`
@@ -46,20 +60,24 @@ type BillingReportResponse struct {
 	TotalPayAmount *big.Int `json:"totalPayAmount"`
 	// TotalNotPayAmount is the total not pay amount (string representation of big.Int)
 	// @swagger:strfmt string
-	TotalNotPayAmount  *big.Int          `json:"totalNotPayAmount"`
-	TotalBillingPay    int               `json:"totalBillingPay"`
-	TotalBillingNotPay int               `json:"totalBillingNotPay"`
-	TotalStudent       int               `json:"totalStudent"`
-	ListBillingReport  ListReportBilling `json:"listBillingReport"`
+	TotalNotPayAmount      *big.Int          `json:"totalNotPayAmount"`
+	TotalBillingPay        int               `json:"totalBillingPay"`
+	TotalBillingNotPay     int               `json:"totalBillingNotPay"`
+	TotalBillingPartialPay int               `json:"totalBillingPartialPay"`
+	TotalStudent           int               `json:"totalStudent"`
+	ListBillingReport      ListReportBilling `json:"listBillingReport"`
 }
 
 type BillingReportSummary struct {
-	TotalBillingAmount string `json:"totalBillingAmount"`
-	TotalPayAmount     string `json:"totalPayAmount"`
-	TotalNotPayAmount  string `json:"totalNotPayAmount"`
-	TotalBillingPay    int    `json:"totalBillingPay"`
-	TotalBillingNotPay int    `json:"totalBillingNotPay"`
-	TotalStudent       int    `json:"totalStudent"`
+	TotalGrossAmount       string `json:"totalGrossAmount"`
+	TotalDiscountAmount    string `json:"totalDiscountAmount"`
+	TotalBillingAmount     string `json:"totalBillingAmount"`
+	TotalPayAmount         string `json:"totalPayAmount"`
+	TotalNotPayAmount      string `json:"totalNotPayAmount"`
+	TotalBillingPay        int    `json:"totalBillingPay"`
+	TotalBillingNotPay     int    `json:"totalBillingNotPay"`
+	TotalBillingPartialPay int    `json:"totalBillingPartialPay"`
+	TotalStudent           int    `json:"totalStudent"`
 }
 
 // for export Excel
@@ -1,6 +1,7 @@
 package response
 
 import (
+	"schoolPayment/models"
 	"time"
 )
 
@@ -40,6 +41,9 @@ type DataListBillingPerStudent struct {
 	SchoolClassName   string `json:"schoolClass"`
 	StudentName       string `json:"studentName"`
 	Amount            int    `json:"amount"`
+	PaidAmount        int    `json:"paidAmount"`
+	RemainingAmount   int    `json:"remainingAmount"`
+	LateFeeForID      *int   `json:"lateFeeForId"`
 }
 
 type DetailBillingStudentResponse struct {
@@ -80,6 +84,8 @@ type BillingStudentByStudentIDBillingID struct {
 	BillingStudentIds string     `json:"billingStudentIds"`
 	PaymentMethodId   int        `json:"paymentMethodId"`
 	TransactionType   string     `json:"transactionType"`
+	CreditAmount      int64      `json:"creditAmount"`
+	ParentAdminFee    int64      `json:"parentAdminFee"`
 }
 
 type InstallmentDetail struct {
@@ -117,9 +123,12 @@ type BillingStudentByStudentIDDetailResponse struct {
 	BillingStudentID  uint       `json:"billingStudentId"`
 	DetailBillingName string     `json:"detailBillingName"`
 	Amount            int        `json:"amount"`
+	PaidAmount        int        `json:"paidAmount"`
+	RemainingAmount   int        `json:"remainingAmount"`
 	DueDate           *time.Time `json:"dueDate"`
 	BillingType       string     `json:"billingType"`
 	PaymentStatus     string     `json:"paymentStatus"`
+	LateFeeForID      *uint      `json:"lateFeeForId"`
 }
 
 type BillingStudentDetailResponse struct {
@@ -140,6 +149,9 @@ type ListBillingHistory struct {
 	TotalPage int                      `json:"totalPage"`
 	TotalData int64                    `json:"totalData"`
 	Data      []DataListBillingHistory `json:"data"`
+	// The credit of the student the history is filtered on
+	CreditBalance int64                  `json:"creditBalance"`
+	CreditHistory []models.StudentCredit `json:"creditHistory,omitempty"`
 }
 
 type DataListBillingHistory struct {
@@ -178,6 +190,7 @@ type DetailBillingHistory struct {
 	PaymentDate                *time.Time                 `json:"paymentDate"`
 	AdminFee                   int64                      `json:"adminFee"`
 	PaymentMethod              string                     `json:"paymentMethod"`
+	CreditAmount               int64                      `json:"creditAmount"`
 	ListBilling                []BillingStudentForHistory `json:"listBilling"`
 }
 
@@ -0,0 +1,39 @@
+package response
+
+import (
+	"time"
+
+	"schoolPayment/models"
+)
+
+type CashierShiftData struct {
+	models.CashierShift
+	Username string `json:"username"`
+}
+
+type CashierShiftListResponse struct {
+	Page      int                `json:"page"`
+	Limit     int                `json:"limit"`
+	TotalPage int                `json:"totalPage"`
+	TotalData int64              `json:"totalData"`
+	Data      []CashierShiftData `json:"data"`
+}
+
+// CashierShiftTransaction is a kasir payment taken during a shift with the cash it left in the drawer.
+type CashierShiftTransaction struct {
+	ID                uint      `json:"id"`
+	InvoiceNumber     string    `json:"invoiceNumber"`
+	CreatedAt         time.Time `json:"createdAt"`
+	StudentName       string    `json:"studentName"`
+	Nis               string    `json:"nis"`
+	TotalAmount       int64     `json:"totalAmount"`
+	ChangeAmount      int64     `json:"changeAmount"`
+	CashAmount        int64     `json:"cashAmount"`
+	TransactionStatus string    `json:"transactionStatus"`
+}
+
+type CashierShiftReport struct {
+	Shift        CashierShiftData          `json:"shift"`
+	SchoolName   string                    `json:"schoolName"`
+	Transactions []CashierShiftTransaction `json:"transactions"`
+}
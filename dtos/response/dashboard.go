@@ -20,11 +20,13 @@ type StudentResponse struct {
 }
 
 type ListLatestBillingResponse struct {
-	BillingName string `json:"billingName"`
-	StudentName string `json:"studentName"`
-	DueDate     string `json:"dueDate"` // Use string if you want formatted date, otherwise `time.Time`
-	Amount      int    `json:"amount"`
-	Status      string `json:"status"`
+	BillingName     string `json:"billingName"`
+	StudentName     string `json:"studentName"`
+	DueDate         string `json:"dueDate"` // Use string if you want formatted date, otherwise `time.Time`
+	Amount          int    `json:"amount"`
+	PaidAmount      int    `json:"paidAmount"`
+	RemainingAmount int    `json:"remainingAmount"`
+	Status          string `json:"status"`
 }
 
 type DashboardAdminResponse struct {
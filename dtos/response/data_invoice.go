@@ -19,6 +19,8 @@ type DataInvoice struct {
 	SubTotal		  int64		 `json:"subTotal"`
 	Discount			  int64		 `json:"discount"`
 	TotalAmount       int64      `json:"totalAmount"`
+	TransactionStatus string     `json:"transactionStatus"`
+	VoidReason        string     `json:"voidReason"`
 }
 
 type RespDataInvoice struct {
@@ -34,4 +36,7 @@ type RespDataInvoice struct {
 	Discount		int64					`json:"diskon"`
 	TotalAmount     int64                   `json:"totalAmount"`
 	BillingStudents []models.BillingStudent `json:"billingStudents"`
+	// A voided payment is still printable, the invoice carries a void mark and the reason
+	IsVoid     bool   `json:"isVoid"`
+	VoidReason string `json:"voidReason"`
 }
@@ -0,0 +1,57 @@
+package response
+
+import (
+	"time"
+
+	"schoolPayment/models"
+)
+
+type DiscountProgramListResponse struct {
+	Page      int                      `json:"page"`
+	Limit     int                      `json:"limit"`
+	TotalPage int                      `json:"totalPage"`
+	TotalData int64                    `json:"totalData"`
+	Data      []models.DiscountProgram `json:"data"`
+}
+
+type StudentDiscountListResponse struct {
+	Page      int                      `json:"page"`
+	Limit     int                      `json:"limit"`
+	TotalPage int                      `json:"totalPage"`
+	TotalData int64                    `json:"totalData"`
+	Data      []models.StudentDiscount `json:"data"`
+}
+
+// ApprovedStudentDiscount is an approved assignment joined with its active program.
+type ApprovedStudentDiscount struct {
+	StudentDiscountID uint       `json:"studentDiscountId"`
+	StudentID         uint       `json:"studentId"`
+	ValidFrom         time.Time  `json:"validFrom"`
+	ValidUntil        *time.Time `json:"validUntil"`
+	DiscountType      string     `json:"discountType"`
+	Amount            float64    `json:"amount"`
+	BillingTypeID     *uint      `json:"billingTypeId"`
+	SchoolYearID      *uint      `json:"schoolYearId"`
+}
+
+type SiblingDiscountTierListResponse struct {
+	Page      int                          `json:"page"`
+	Limit     int                          `json:"limit"`
+	TotalPage int                          `json:"totalPage"`
+	TotalData int64                        `json:"totalData"`
+	Data      []models.SiblingDiscountTier `json:"data"`
+}
+
+// HouseholdLink ties an active student to something shared with siblings: a parent account,
+// a parent email or a parent phone number.
+type HouseholdLink struct {
+	StudentID    uint       `json:"studentId"`
+	BirthDate    *time.Time `json:"birthDate"`
+	HouseholdKey string     `json:"householdKey"`
+}
+
+type SiblingDiscountRecalculateResponse struct {
+	TotalChecked  int   `json:"totalChecked"`
+	TotalUpdated  int   `json:"totalUpdated"`
+	TotalDiscount int64 `json:"totalDiscount"`
+}
@@ -7,6 +7,8 @@ type InvoiceFormatResponse struct {
 	SchoolID               uint      `json:"schoolId"`
 	Prefix                 string    `json:"prefix"`
 	Format                 string    `json:"format"`
+	Template               string    `json:"template"`
+	ResetPeriod            string    `json:"resetPeriod"`
 	GeneratedInvoiceFormat string    `json:"generatedInvoiceFormat"`
 	CreatedBy              uint      `json:"createdBy"`
 	UpdatedBy              uint      `json:"updatedBy"`
@@ -0,0 +1,42 @@
+package response
+
+import "schoolPayment/models"
+
+type JournalEntryListResponse struct {
+	Page      int                   `json:"page"`
+	Limit     int                   `json:"limit"`
+	TotalPage int                   `json:"totalPage"`
+	TotalData int64                 `json:"totalData"`
+	Data      []models.JournalEntry `json:"data"`
+}
+
+type JournalSyncResponse struct {
+	TotalSchools int `json:"totalSchools"`
+	TotalPosted  int `json:"totalPosted"`
+}
+
+// TrialBalanceAccount shows each balance on the side it falls on, so a debit balance of a
+// credit account is shown as a debit.
+type TrialBalanceAccount struct {
+	LedgerAccountID uint   `json:"ledgerAccountId"`
+	Code            string `json:"code"`
+	Name            string `json:"name"`
+	AccountType     string `json:"accountType"`
+	NormalBalance   string `json:"normalBalance"`
+	OpeningBalance  int64  `json:"-"`
+	OpeningDebit    int64  `json:"openingDebit"`
+	OpeningCredit   int64  `json:"openingCredit"`
+	Debit           int64  `json:"debit"`
+	Credit          int64  `json:"credit"`
+	ClosingDebit    int64  `json:"closingDebit"`
+	ClosingCredit   int64  `json:"closingCredit"`
+}
+
+type TrialBalanceResponse struct {
+	StartDate   string                `json:"startDate"`
+	EndDate     string                `json:"endDate"`
+	Accounts    []TrialBalanceAccount `json:"accounts"`
+	TotalDebit  int64                 `json:"totalDebit"`
+	TotalCredit int64                 `json:"totalCredit"`
+	IsBalanced  bool                  `json:"isBalanced"`
+}
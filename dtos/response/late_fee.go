@@ -0,0 +1,36 @@
+package response
+
+import (
+	"time"
+
+	"schoolPayment/models"
+)
+
+type LateFeeRuleListResponse struct {
+	Page      int                  `json:"page"`
+	Limit     int                  `json:"limit"`
+	TotalPage int                  `json:"totalPage"`
+	TotalData int64                `json:"totalData"`
+	Data      []models.LateFeeRule `json:"data"`
+}
+
+// LateFeeOverdueInstallment is an unpaid installment past its due date with what is needed
+// to pick its penalty rule.
+type LateFeeOverdueInstallment struct {
+	ID                uint      `json:"id"`
+	StudentID         uint      `json:"studentId"`
+	BillingID         uint      `json:"billingId"`
+	BillingDetailID   uint      `json:"billingDetailId"`
+	DetailBillingName string    `json:"detailBillingName"`
+	Amount            int64     `json:"amount"`
+	PaidAmount        int64     `json:"paidAmount"`
+	DueDate           time.Time `json:"dueDate"`
+	BillingType       string    `json:"billingType"`
+	SchoolID          uint      `json:"schoolId"`
+}
+
+type LateFeeRunResponse struct {
+	TotalChecked int   `json:"totalChecked"`
+	TotalCharged int   `json:"totalCharged"`
+	TotalAmount  int64 `json:"totalAmount"`
+}
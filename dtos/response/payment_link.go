@@ -0,0 +1,34 @@
+package response
+
+import "time"
+
+type PaymentLinkResponse struct {
+	ID                uint      `json:"id"`
+	StudentID         uint      `json:"studentId"`
+	BillingStudentIds []string  `json:"billingStudentIds"`
+	TotalAmount       int64     `json:"totalAmount"`
+	ExpiresAt         time.Time `json:"expiresAt"`
+	OrderID           *string   `json:"orderId"`
+	URL               string    `json:"url"`
+	WhatsAppText      string    `json:"whatsAppText"`
+	WhatsAppURL       string    `json:"whatsAppUrl"`
+	CreatedAt         time.Time `json:"createdAt"`
+}
+
+// PublicPaymentLinkResponse is what anyone holding the link sees, so the student name is masked.
+type PublicPaymentLinkResponse struct {
+	SchoolName  string                  `json:"schoolName"`
+	StudentName string                  `json:"studentName"`
+	Items       []PublicPaymentLinkItem `json:"items"`
+	TotalAmount int64                   `json:"totalAmount"`
+	ExpiresAt   time.Time               `json:"expiresAt"`
+	Paid        bool                    `json:"paid"`
+}
+
+type PublicPaymentLinkItem struct {
+	Name    string     `json:"name"`
+	DueDate *time.Time `json:"dueDate"`
+	// Amount is what is left to pay of the installment
+	Amount int64 `json:"amount"`
+	Paid   bool  `json:"paid"`
+}
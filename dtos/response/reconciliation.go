@@ -0,0 +1,23 @@
+package response
+
+import "schoolPayment/models"
+
+type ReconciliationRunListResponse struct {
+	Page      int                        `json:"page"`
+	Limit     int                        `json:"limit"`
+	TotalPage int                        `json:"totalPage"`
+	TotalData int64                      `json:"totalData"`
+	Data      []models.ReconciliationRun `json:"data"`
+}
+
+// ReconciliationPendingTransaction is a pending online transaction with the admin fee the parent
+// was charged on top of it at the gateway.
+type ReconciliationPendingTransaction struct {
+	ID                uint   `json:"id"`
+	OrderID           string `json:"orderId"`
+	TotalAmount       int    `json:"totalAmount"`
+	TransactionStatus string `json:"transactionStatus"`
+	PaymentGateway    string `json:"paymentGateway"`
+	PaymentMethod     string `json:"paymentMethod"`
+	ParentAdminFee    int64  `json:"parentAdminFee"`
+}
@@ -0,0 +1,18 @@
+package response
+
+type SchoolPaymentConfigResponse struct {
+	ID                       uint   `json:"id"`
+	SchoolID                 uint   `json:"schoolId"`
+	PaymentGateway           string `json:"paymentGateway"`
+	MidtransMerchantID       string `json:"midtransMerchantId"`
+	MidtransClientKey        string `json:"midtransClientKey"`
+	HasMidtransServerKey     bool   `json:"hasMidtransServerKey"`
+	HasXenditSecretKey       bool   `json:"hasXenditSecretKey"`
+	HasXenditCallbackToken   bool   `json:"hasXenditCallbackToken"`
+	IsProduction             bool   `json:"isProduction"`
+	AdminFeeBearer           string `json:"adminFeeBearer"`
+	AdminFeeParentPercentage int    `json:"adminFeeParentPercentage"`
+	PaymentExpiryMinutes     int    `json:"paymentExpiryMinutes"`
+	FinishRedirectURL        string `json:"finishRedirectUrl"`
+	VAPrefix                 string `json:"vaPrefix"`
+}
@@ -0,0 +1,12 @@
+package response
+
+import "schoolPayment/models"
+
+type StudentCreditHistoryResponse struct {
+	Page      int                    `json:"page"`
+	Limit     int                    `json:"limit"`
+	TotalPage int                    `json:"totalPage"`
+	TotalData int64                  `json:"totalData"`
+	Balance   int64                  `json:"balance"`
+	Data      []models.StudentCredit `json:"data"`
+}
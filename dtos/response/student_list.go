@@ -1,6 +1,7 @@
 package response
 
 import (
+	"schoolPayment/models"
 	"time"
 )
 
@@ -45,4 +46,7 @@ type DetailStudentResponse struct {
 	Placeholder        string     `json:"placeholder"`
 	SchoolYearID       uint       `json:"schoolYearId"`
 	SchoolYearName     string     `json:"schoolYearName"`
+	CreditBalance      int64      `json:"creditBalance" gorm:"-"`
+	// CreditHistory holds the latest credit movements, it is only filled on the detail
+	CreditHistory []models.StudentCredit `json:"creditHistory,omitempty" gorm:"-"`
 }
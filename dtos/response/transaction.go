@@ -16,6 +16,37 @@ type MidtransResponse struct {
 	RedirectURL *string `json:"redirectUrl"`
 }
 
+// DirectPaymentResponse is the payment instruction of a direct charge. Only the fields of the
+// payment type are filled: a virtual account number, a Mandiri bill, a QRIS string or a deeplink.
+type DirectPaymentResponse struct {
+	OrderID     string `json:"orderId"`
+	PaymentType string `json:"paymentType"`
+	GrossAmount string `json:"grossAmount"`
+	Bank        string `json:"bank"`
+	VaNumber    string `json:"vaNumber"`
+	BillerCode  string `json:"billerCode"`
+	BillKey     string `json:"billKey"`
+	QrString    string `json:"qrString"`
+	QrImageURL  string `json:"qrImageUrl"`
+	Deeplink    string `json:"deeplink"`
+	ExpiryTime  string `json:"expiryTime"`
+}
+
+// AdminFeePreviewResponse is what the parent pays at checkout with a payment method.
+type AdminFeePreviewResponse struct {
+	PaymentMethodID uint   `json:"paymentMethodId"`
+	PaymentMethod   string `json:"paymentMethod"`
+	BankName        string `json:"bankName"`
+	MethodLogo      string `json:"methodLogo"`
+	BillingAmount   int64  `json:"billingAmount"`
+	CreditAmount    int64  `json:"creditAmount"`
+	AdminFeeBearer  string `json:"adminFeeBearer"`
+	AdminFee        int64  `json:"adminFee"`
+	ParentAdminFee  int64  `json:"parentAdminFee"`
+	SchoolAdminFee  int64  `json:"schoolAdminFee"`
+	GrossAmount     int64  `json:"grossAmount"`
+}
+
 type MidtransExtractAPIError struct {
 	StatusCode    string `json:"status_code"`
 	StatusMessage string `json:"status_message"`
@@ -0,0 +1,28 @@
+package response
+
+import "schoolPayment/models"
+
+type TransactionRefundListResponse struct {
+	Page      int                        `json:"page"`
+	Limit     int                        `json:"limit"`
+	TotalPage int                        `json:"totalPage"`
+	TotalData int64                      `json:"totalData"`
+	Data      []models.TransactionRefund `json:"data"`
+}
+
+type RefundReceipt struct {
+	InvoiceNumber string              `json:"invoiceNumber"`
+	StudentName   string              `json:"studentName"`
+	StudentNis    string              `json:"studentNis"`
+	RefundType    string              `json:"refundType"`
+	RefundMethod  string              `json:"refundMethod"`
+	RefundDate    string              `json:"refundDate"`
+	Reason        string              `json:"reason"`
+	Amount        int64               `json:"amount"`
+	ListBilling   []RefundReceiptItem `json:"listBilling"`
+}
+
+type RefundReceiptItem struct {
+	DetailBillingName string `json:"detailBillingName"`
+	Amount            int64  `json:"amount"`
+}
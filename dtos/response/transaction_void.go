@@ -0,0 +1,11 @@
+package response
+
+import "schoolPayment/models"
+
+type TransactionVoidListResponse struct {
+	Page      int                      `json:"page"`
+	Limit     int                      `json:"limit"`
+	TotalPage int                      `json:"totalPage"`
+	TotalData int64                    `json:"totalData"`
+	Data      []models.TransactionVoid `json:"data"`
+}
@@ -0,0 +1,11 @@
+package response
+
+import "schoolPayment/models"
+
+type WebhookEventListResponse struct {
+	Page      int                   `json:"page"`
+	Limit     int                   `json:"limit"`
+	TotalPage int                   `json:"totalPage"`
+	TotalData int64                 `json:"totalData"`
+	Data      []models.WebhookEvent `json:"data"`
+}
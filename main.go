@@ -69,18 +69,32 @@ func main() {
 	dashboardRepository := repositories.NewDashboardRepository(configs.DB)
 	announcementRepository := repositories.NewAnnouncementRepository(configs.DB)
 	invoiceFormatRepository := repositories.NewInvoiceFormatRepository(configs.DB)
+	transactionRefundRepository := repositories.NewTransactionRefundRepository(configs.DB)
+	webhookEventRepository := repositories.NewWebhookEventRepository(configs.DB)
+	schoolPaymentConfigRepository := repositories.NewSchoolPaymentConfigRepository(configs.DB)
+	reconciliationRepository := repositories.NewReconciliationRepository(configs.DB)
+	studentCreditRepository := repositories.NewStudentCreditRepository(configs.DB)
+	studentVirtualAccountRepository := repositories.NewStudentVirtualAccountRepository(configs.DB)
+	paymentLinkRepository := repositories.NewPaymentLinkRepository(configs.DB)
+	cashierShiftRepository := repositories.NewCashierShiftRepository(configs.DB)
+	transactionVoidRepository := repositories.NewTransactionVoidRepository(configs.DB)
+	lateFeeRepository := repositories.NewLateFeeRepository(configs.DB)
+	discountProgramRepository := repositories.NewDiscountProgramRepository(configs.DB)
+	bankStatementRepository := repositories.NewBankStatementRepository(configs.DB)
+	journalRepository := repositories.NewJournalRepository(configs.DB)
+	accountingExportRepository := repositories.NewAccountingExportRepository(configs.DB)
 
 	// Initialize Services
 	userService := services.NewUserService(userRepository, roleRepository, schoolRepository)
 	roleService := services.NewRoleService(roleRepository)
 	studentParentService := services.NewStudentParentService(studentParentRepository, userRepository)
 	billingHistoryService := services.NewBillingHistoryService(billingHistoryRepository, userRepository, schoolRepository, paymentMethodRepository)
-	transactionService := services.NewTransactionService(transactionRepository, userRepository, schoolRepository, billingHistoryService, billingStudentRepository, billingRepository, studentRepository)
+	transactionService := services.NewTransactionService(transactionRepository, userRepository, schoolRepository, billingHistoryService, billingStudentRepository, billingRepository, studentRepository, webhookEventRepository)
 	schoolYearService := services.NewSchoolYearService(schoolYearRepository, userRepository)
 	schoolGradeService := services.NewSchoolGradeService(schoolGradeRepository)
 	schoolService := services.NewSchoolService(schoolRepository, userRepository)
-	billingService := services.NewBillingService(billingRepository, userRepository, schoolClassRepository, schoolYearRepository, schoolGradeRepository, studentRepository)
-	billingStudentService := services.NewBillingStudentService(billingStudentRepository, userRepository, schoolYearRepository, schoolClassRepository, billingRepository, schoolGradeRepository, studentRepository)
+	billingService := services.NewBillingService(billingRepository, userRepository, schoolClassRepository, schoolYearRepository, schoolGradeRepository, studentRepository, discountProgramRepository)
+	billingStudentService := services.NewBillingStudentService(billingStudentRepository, userRepository, schoolYearRepository, schoolClassRepository, billingRepository, schoolGradeRepository, studentRepository, discountProgramRepository)
 	bankAccountService := services.NewBankAccountService(bankAccountRepository, userRepository)
 	loginService := services.NewLoginService(userRepository, auditTrailRepository)
 	dashboardService := services.NewDashboardService(userRepository, schoolClassRepository, dashboardRepository)
@@ -91,6 +105,20 @@ func main() {
 	billingReportService := services.NewBillingReportService(billingReportRepository, userRepository)
 	announcementService := services.NewAnnouncementService(announcementRepository, userRepository)
 	invoiceFormatService := services.NewInvoiceFormatService(invoiceFormatRepository)
+	transactionRefundService := services.NewTransactionRefundService(transactionRefundRepository, userRepository)
+	schoolPaymentConfigService := services.NewSchoolPaymentConfigService(schoolPaymentConfigRepository, userRepository)
+	reconciliationService := services.NewReconciliationService(reconciliationRepository, transactionService)
+	studentCreditService := services.NewStudentCreditService(studentCreditRepository, studentRepository, userRepository)
+	studentVirtualAccountService := services.NewStudentVirtualAccountService(studentVirtualAccountRepository, studentRepository, userRepository, schoolPaymentConfigRepository, paymentMethodRepository)
+	paymentLinkService := services.NewPaymentLinkService(paymentLinkRepository, billingStudentRepository, studentRepository, userRepository, schoolRepository, transactionService)
+	cashierShiftService := services.NewCashierShiftService(cashierShiftRepository, userRepository, schoolRepository)
+	transactionVoidService := services.NewTransactionVoidService(transactionVoidRepository, userRepository)
+	lateFeeService := services.NewLateFeeService(lateFeeRepository, userRepository)
+	discountProgramService := services.NewDiscountProgramService(discountProgramRepository, userRepository)
+	siblingDiscountService := services.NewSiblingDiscountService(discountProgramRepository, userRepository)
+	bankStatementService := services.NewBankStatementService(bankStatementRepository, userRepository)
+	journalService := services.NewJournalService(journalRepository, userRepository)
+	accountingExportService := services.NewAccountingExportService(accountingExportRepository, journalRepository, userRepository)
 
 	// Initialize Controllers
 	userController := controllers.NewUserController(userService)
@@ -114,6 +142,20 @@ func main() {
 	billingReportController := controllers.NewBillingReportController(billingReportService)
 	announcementController := controllers.NewAnnouncementController(announcementService)
 	invoiceFormatController := controllers.NewInvoiceFormatController(invoiceFormatService)
+	transactionRefundController := controllers.NewTransactionRefundController(transactionRefundService)
+	schoolPaymentConfigController := controllers.NewSchoolPaymentConfigController(schoolPaymentConfigService)
+	reconciliationController := controllers.NewReconciliationController(reconciliationService)
+	studentCreditController := controllers.NewStudentCreditController(studentCreditService)
+	studentVirtualAccountController := controllers.NewStudentVirtualAccountController(studentVirtualAccountService)
+	paymentLinkController := controllers.NewPaymentLinkController(paymentLinkService)
+	cashierShiftController := controllers.NewCashierShiftController(cashierShiftService)
+	transactionVoidController := controllers.NewTransactionVoidController(transactionVoidService)
+	lateFeeController := controllers.NewLateFeeController(lateFeeService)
+	discountProgramController := controllers.NewDiscountProgramController(discountProgramService)
+	siblingDiscountController := controllers.NewSiblingDiscountController(siblingDiscountService)
+	bankStatementController := controllers.NewBankStatementController(bankStatementService)
+	journalController := controllers.NewJournalController(journalService)
+	accountingExportController := controllers.NewAccountingExportController(accountingExportService)
 
 	// Setup routes
 	api := app.Group("/v1")
@@ -141,6 +183,20 @@ func main() {
 	routes.SetupBillingReportRoutes(api, billingReportController)
 	routes.SetupAnnouncementRoutes(api, announcementController)
 	routes.SetupInvoiceFormatRoutes(api, invoiceFormatController)
+	routes.SetupTransactionRefundRoutes(api, transactionRefundController)
+	routes.SetupSchoolPaymentConfigRoutes(api, schoolPaymentConfigController)
+	routes.SetupReconciliationRoutes(api, reconciliationController)
+	routes.SetupStudentCreditRoutes(api, studentCreditController)
+	routes.SetupStudentVirtualAccountRoutes(api, studentVirtualAccountController)
+	routes.SetupPaymentLinkRoutes(api, paymentLinkController)
+	routes.SetupCashierShiftRoutes(api, cashierShiftController)
+	routes.SetupTransactionVoidRoutes(api, transactionVoidController)
+	routes.SetupLateFeeRoutes(api, lateFeeController)
+	routes.SetupDiscountProgramRoutes(api, discountProgramController)
+	routes.SetupSiblingDiscountRoutes(api, siblingDiscountController)
+	routes.SetupBankStatementRoutes(api, bankStatementController)
+	routes.SetupJournalRoutes(api, journalController)
+	routes.SetupAccountingExportRoutes(api, accountingExportController)
 	routes.SetupRoutes(api)
 
 	app.Get("/swagger/*", swagger.HandlerDefault)
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// AccountingAccountMapping is the account code of the foundation's accounting package used for a
+// ledger account, a billing type's revenue or a bank account's money.
+type AccountingAccountMapping struct {
+	Master
+	SchoolID    uint   `json:"schoolId"`
+	MappingType string `json:"mappingType"`
+	Reference   string `json:"reference"`
+	AccountCode string `json:"accountCode"`
+	AccountName string `json:"accountName"`
+}
+
+// AccountingExport is one download of journals for the accounting package. The journal entries it
+// contains point back at it, so they are never exported twice.
+type AccountingExport struct {
+	Master
+	SchoolID     uint      `json:"schoolId"`
+	ExportFormat string    `json:"exportFormat"`
+	StartDate    time.Time `json:"startDate"`
+	EndDate      time.Time `json:"endDate"`
+	TotalEntries int       `json:"totalEntries"`
+	TotalAmount  int64     `json:"totalAmount"`
+}
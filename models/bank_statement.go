@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+type BankStatementImport struct {
+	Master
+	SchoolID      uint   `json:"schoolId"`
+	BankAccountID uint   `json:"bankAccountId"`
+	FileName      string `json:"fileName"`
+	FileFormat    string `json:"fileFormat"`
+	// TotalLines counts the credits stored; TotalSkipped the ones already imported before
+	TotalLines    int   `json:"totalLines"`
+	TotalSkipped  int   `json:"totalSkipped"`
+	TotalAmount   int64 `json:"totalAmount"`
+	TotalProposed int   `json:"totalProposed"`
+}
+
+type BankStatementLine struct {
+	Master
+	BankStatementImportID uint      `json:"bankStatementImportId"`
+	SchoolID              uint      `json:"schoolId"`
+	BankAccountID         uint      `json:"bankAccountId"`
+	TransactionDate       time.Time `json:"transactionDate"`
+	Description           string    `json:"description"`
+	Reference             string    `json:"reference"`
+	Amount                int64     `json:"amount"`
+	LineHash              string    `json:"-"`
+	MatchStatus           string    `json:"matchStatus"`
+	MatchMethod           string    `json:"matchMethod"`
+	// StudentID and BillingStudentIds hold the proposed match until it is posted as TransactionBillingID
+	StudentID            *uint      `json:"studentId"`
+	BillingStudentIds    string     `json:"billingStudentIds"`
+	TransactionBillingID *uint      `json:"transactionBillingId"`
+	ConfirmedBy          *int       `json:"confirmedBy"`
+	ConfirmedAt          *time.Time `json:"confirmedAt"`
+}
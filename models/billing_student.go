@@ -10,5 +10,16 @@ type BillingStudent struct {
 	DueDate           *time.Time `json:"dueDate"`
 	DetailBillingName string     `json:"detailBillingName"`
 	Amount            int64      `json:"amount"`
+	PaidAmount        int64      `json:"paidAmount"`
 	BillingDetailID   uint       `json:"billingDetailId"`
+	// ReservedOrderID locks the installment to an open checkout until ReservedUntil
+	ReservedOrderID *string    `json:"reservedOrderId"`
+	ReservedUntil   *time.Time `json:"reservedUntil"`
+	// LateFeeForID points a penalty line at the overdue installment it was charged for
+	LateFeeForID *uint `json:"lateFeeForId"`
+	// DiscountAmount was taken off the gross amount by StudentDiscountID; Amount is what is left to pay
+	DiscountAmount    int64 `json:"discountAmount"`
+	StudentDiscountID *uint `json:"studentDiscountId"`
+	// SiblingDiscountAmount is the part of DiscountAmount given by the household's sibling tier
+	SiblingDiscountAmount int64 `json:"siblingDiscountAmount"`
 }
@@ -0,0 +1,8 @@
+package models
+
+type BillingStudentPayment struct {
+	Master
+	BillingStudentID     uint  `json:"billingStudentId"`
+	TransactionBillingID uint  `json:"transactionBillingId"`
+	Amount               int64 `json:"amount"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type CashierShift struct {
+	Master
+	SchoolID         uint       `json:"schoolId"`
+	UserID           uint       `json:"userId"`
+	Status           string     `json:"status"`
+	OpenedAt         time.Time  `json:"openedAt"`
+	ClosedAt         *time.Time `json:"closedAt"`
+	OpeningFloat     int64      `json:"openingFloat"`
+	CashReceived     int64      `json:"cashReceived"`
+	ExpectedCash     int64      `json:"expectedCash"`
+	CountedCash      *int64     `json:"countedCash"`
+	Variance         *int64     `json:"variance"`
+	TotalTransaction int        `json:"totalTransaction"`
+	Note             string     `json:"note"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type DiscountProgram struct {
+	Master
+	SchoolID     uint    `json:"schoolId"`
+	ProgramName  string  `json:"programName"`
+	DiscountType string  `json:"discountType"`
+	Amount       float64 `json:"amount"`
+	// Empty BillingTypeID or SchoolYearID means the program applies to all of them
+	BillingTypeID *uint  `json:"billingTypeId"`
+	SchoolYearID  *uint  `json:"schoolYearId"`
+	Description   string `json:"description"`
+	IsActive      bool   `json:"isActive"`
+}
+
+type StudentDiscount struct {
+	Master
+	SchoolID          uint             `json:"schoolId"`
+	StudentID         uint             `json:"studentId"`
+	DiscountProgramID uint             `json:"discountProgramId"`
+	DiscountStatus    string           `json:"discountStatus"`
+	ValidFrom         time.Time        `json:"validFrom"`
+	ValidUntil        *time.Time       `json:"validUntil"`
+	Note              string           `json:"note"`
+	RejectionReason   string           `json:"rejectionReason"`
+	ApprovedBy        *int             `json:"approvedBy"`
+	ApprovedAt        *time.Time       `json:"approvedAt"`
+	DiscountProgram   *DiscountProgram `gorm:"foreignKey:DiscountProgramID" json:"discountProgram,omitempty"`
+}
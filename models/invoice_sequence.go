@@ -0,0 +1,9 @@
+package models
+
+type InvoiceSequence struct {
+	Master
+	SchoolID  uint   `json:"schoolId"`
+	Prefix    string `json:"prefix"`
+	Period    string `json:"period"`
+	LastValue int64  `json:"lastValue"`
+}
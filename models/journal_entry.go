@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// LedgerAccount is one account of a school's chart of accounts.
+type LedgerAccount struct {
+	Master
+	SchoolID      uint   `json:"schoolId"`
+	Code          string `json:"code"`
+	Name          string `json:"name"`
+	AccountType   string `json:"accountType"`
+	NormalBalance string `json:"normalBalance"`
+}
+
+// JournalEntry is a balanced posting made for one financial event. SourceType and SourceID
+// point at the record the entry was made from.
+type JournalEntry struct {
+	Master
+	SchoolID    uint      `json:"schoolId"`
+	EntryDate   time.Time `json:"entryDate"`
+	SourceType  string    `json:"sourceType"`
+	SourceID    uint      `json:"sourceId"`
+	Description string    `json:"description"`
+	TotalAmount int64     `json:"totalAmount"`
+	// BillingTypeID and BankAccountID let the accounting export map the entry to the foundation's accounts
+	BillingTypeID      *uint         `json:"billingTypeId"`
+	BankAccountID      *uint         `json:"bankAccountId"`
+	AccountingExportID *uint         `json:"accountingExportId"`
+	Lines              []JournalLine `gorm:"foreignKey:JournalEntryID" json:"lines"`
+}
+
+type JournalLine struct {
+	Master
+	JournalEntryID  uint           `json:"journalEntryId"`
+	LedgerAccountID uint           `json:"ledgerAccountId"`
+	Debit           int64          `json:"debit"`
+	Credit          int64          `json:"credit"`
+	LedgerAccount   *LedgerAccount `gorm:"foreignKey:LedgerAccountID" json:"ledgerAccount,omitempty"`
+}
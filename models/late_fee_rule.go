@@ -0,0 +1,15 @@
+package models
+
+type LateFeeRule struct {
+	Master
+	SchoolID uint `json:"schoolId"`
+	// A rule for a billing wins over a rule for its billing type
+	BillingID       *uint   `json:"billingId"`
+	BillingTypeID   *uint   `json:"billingTypeId"`
+	CalculationType string  `json:"calculationType"`
+	Amount          float64 `json:"amount"`
+	Period          string  `json:"period"`
+	GraceDays       int     `json:"graceDays"`
+	MaxAmount       *int64  `json:"maxAmount"`
+	IsActive        bool    `json:"isActive"`
+}
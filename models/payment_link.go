@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// PaymentLink lets someone without an account pay a set of a student's installments. The link
+// carries Code and a signature over it; a revoked link is soft deleted.
+type PaymentLink struct {
+	Master
+	SchoolID          uint      `json:"schoolId"`
+	StudentID         uint      `json:"studentId"`
+	Code              string    `json:"-"`
+	BillingStudentIds string    `json:"billingStudentIds"`
+	ExpiresAt         time.Time `json:"expiresAt"`
+	OrderID           *string   `json:"orderId"`
+}
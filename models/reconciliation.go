@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type ReconciliationRun struct {
+	Master
+	StartedAt          time.Time                   `json:"startedAt"`
+	FinishedAt         *time.Time                  `json:"finishedAt"`
+	TotalChecked       int                         `json:"totalChecked"`
+	TotalUpdated       int                         `json:"totalUpdated"`
+	TotalDiscrepancies int                         `json:"totalDiscrepancies"`
+	Discrepancies      []ReconciliationDiscrepancy `gorm:"foreignKey:ReconciliationRunID" json:"discrepancies"`
+}
+
+type ReconciliationDiscrepancy struct {
+	Master
+	ReconciliationRunID  uint   `json:"reconciliationRunId"`
+	TransactionBillingID *uint  `json:"transactionBillingId"`
+	OrderID              string `json:"orderId"`
+	DiscrepancyType      string `json:"discrepancyType"`
+	LocalStatus          string `json:"localStatus"`
+	GatewayStatus        string `json:"gatewayStatus"`
+	LocalAmount          int64  `json:"localAmount"`
+	GatewayAmount        int64  `json:"gatewayAmount"`
+	Note                 string `json:"note"`
+}
@@ -0,0 +1,34 @@
+package models
+
+type SchoolPaymentConfig struct {
+	Master
+	SchoolID           uint   `json:"schoolId"`
+	PaymentGateway     string `json:"paymentGateway"`
+	MidtransMerchantID string `json:"midtransMerchantId"`
+	MidtransClientKey  string `json:"midtransClientKey"`
+	// MidtransServerKey is stored encrypted and never serialised
+	MidtransServerKey string `json:"-"`
+	IsProduction      bool   `json:"isProduction"`
+	// XenditSecretKey and XenditCallbackToken are the school's own Xendit account, stored encrypted
+	XenditSecretKey     string `json:"-"`
+	XenditCallbackToken string `json:"-"`
+	// AdminFeeBearer is who pays the gateway admin fee unless the payment method says otherwise
+	AdminFeeBearer           string `json:"adminFeeBearer"`
+	AdminFeeParentPercentage int    `json:"adminFeeParentPercentage"`
+	// PaymentExpiryMinutes is how long the parent has to pay, 0 keeps the default reservation time
+	PaymentExpiryMinutes int    `json:"paymentExpiryMinutes"`
+	FinishRedirectURL    string `json:"finishRedirectUrl"`
+	// VAPrefix starts every fixed student virtual account number of the school
+	VAPrefix string `json:"vaPrefix" gorm:"column:va_prefix"`
+}
+
+// SchoolPaymentMethodFee overrides the gateway and who pays the admin fee of one payment method
+// for a school. An empty value keeps the school's setting.
+type SchoolPaymentMethodFee struct {
+	Master
+	SchoolID                 uint   `json:"schoolId"`
+	MasterPaymentMethodID    uint   `json:"masterPaymentMethodId"`
+	PaymentGateway           string `json:"paymentGateway"`
+	AdminFeeBearer           string `json:"adminFeeBearer"`
+	AdminFeeParentPercentage int    `json:"adminFeeParentPercentage"`
+}
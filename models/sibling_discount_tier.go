@@ -0,0 +1,11 @@
+package models
+
+type SiblingDiscountTier struct {
+	Master
+	SchoolID uint `json:"schoolId"`
+	// ChildOrder is the first child in the household, oldest first, the tier applies to
+	ChildOrder   int     `json:"childOrder"`
+	DiscountType string  `json:"discountType"`
+	Amount       float64 `json:"amount"`
+	IsActive     bool    `json:"isActive"`
+}
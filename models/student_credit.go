@@ -0,0 +1,14 @@
+package models
+
+// StudentCredit is one movement of a student's credit balance. Credit in is positive, credit
+// used at checkout is negative, so the balance is the sum of the amounts.
+type StudentCredit struct {
+	Master
+	StudentID            uint    `json:"studentId"`
+	EntryType            string  `json:"entryType"`
+	Amount               int64   `json:"amount"`
+	TransactionBillingID *uint   `json:"transactionBillingId"`
+	TransactionRefundID  *uint   `json:"transactionRefundId"`
+	OrderID              *string `json:"orderId"`
+	Note                 string  `json:"note"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// StudentVirtualAccount is the fixed virtual account number of a student at one bank. Money paid
+// into it without a checkout settles the student's oldest open installments.
+type StudentVirtualAccount struct {
+	Master
+	SchoolID              uint   `json:"schoolId"`
+	StudentID             uint   `json:"studentId"`
+	MasterPaymentMethodID uint   `json:"masterPaymentMethodId"`
+	Bank                  string `json:"bank"`
+	VANumber              string `json:"vaNumber" gorm:"column:va_number"`
+}
@@ -2,18 +2,25 @@ package models
 
 type TransactionBilling struct {
 	Master
-	BillingID            string                      `json:"billingId"`
-	StudentID            uint                        `json:"studentId"`
-	TransactionType      string                      `json:"transactionType"`
-	VirtualAccountNumber string                      `json:"virtualAccountNumber"`
-	TotalAmount          int                         `json:"totalAmount"`
-	ReferenceNumber      string                      `json:"referenceNumber"`
-	Description          string                      `json:"description"`
-	OrderID              string                      `json:"orderId"`
-	TransactionStatus    string                      `json:"transactionStatus"`
-	InvoiceNumber        string                      `json:"invoiceNumber"`
-	BillingStudentIds    string                      `json:"billingStudentIds"`
-	AccountNumber        string                      `json:"accountNumber"`
-	ExpiryTime           string                      `json:"expiryTime"`
-	TransactionHistory   []TransactionBillingHistory `gorm:"foreignKey:TransactionBillingId"`
+	BillingID            string `json:"billingId"`
+	StudentID            uint   `json:"studentId"`
+	TransactionType      string `json:"transactionType"`
+	VirtualAccountNumber string `json:"virtualAccountNumber"`
+	TotalAmount          int    `json:"totalAmount"`
+	ReferenceNumber      string `json:"referenceNumber"`
+	Description          string `json:"description"`
+	OrderID              string `json:"orderId"`
+	TransactionStatus    string `json:"transactionStatus"`
+	InvoiceNumber        string `json:"invoiceNumber"`
+	BillingStudentIds    string `json:"billingStudentIds"`
+	AccountNumber        string `json:"accountNumber"`
+	ExpiryTime           string `json:"expiryTime"`
+	PaymentGateway       string `json:"paymentGateway"`
+	CreditAmount         int64  `json:"creditAmount"`
+	CashierShiftID       *uint  `json:"cashierShiftId"`
+	// AdminFee is what the gateway charges on top of TotalAmount for an online payment
+	AdminFee int64 `json:"adminFee"`
+	// TransactionBillingGroupID is set when the order paid the installments of several children
+	TransactionBillingGroupID *uint                       `json:"transactionBillingGroupId"`
+	TransactionHistory        []TransactionBillingHistory `gorm:"foreignKey:TransactionBillingId"`
 }
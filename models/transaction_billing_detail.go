@@ -13,4 +13,10 @@ type TransactionBillingDetail struct {
 	VirtualAccountNumber  *string    `gorm:"column:virtual_account_number"`
 	TransactionTime       *time.Time `json:"transactionTime"`
 	IsDonation            bool       `json:"isDonation" gorm:"default:false"`
+	// AdminFee is the gateway fee of an online payment, split into what the parent paid on top
+	// of the bill and what the school absorbed
+	AdminFeeBearer *string `json:"adminFeeBearer"`
+	AdminFee       int64   `json:"adminFee"`
+	ParentAdminFee int64   `json:"parentAdminFee"`
+	SchoolAdminFee int64   `json:"schoolAdminFee"`
 }
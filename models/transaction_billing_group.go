@@ -0,0 +1,12 @@
+package models
+
+// TransactionBillingGroup is one gateway order that pays the installments of several children of
+// a parent. Each child gets its own TransactionBilling with the order ID of the group.
+type TransactionBillingGroup struct {
+	Master
+	OrderID        string `json:"orderId"`
+	SchoolID       uint   `json:"schoolId"`
+	BillingAmount  int64  `json:"billingAmount"`
+	AdminFee       int64  `json:"adminFee"`
+	PaymentGateway string `json:"paymentGateway"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type TransactionRefund struct {
+	Master
+	TransactionBillingID uint               `json:"transactionBillingId"`
+	SchoolID             uint               `json:"schoolId"`
+	RefundType           string             `json:"refundType"`
+	BillingStudentIds    string             `json:"billingStudentIds"`
+	Amount               int64              `json:"amount"`
+	CreditAmount         int64              `json:"creditAmount"`
+	ReversedAmount       int64              `json:"reversedAmount"`
+	Reason               string             `json:"reason"`
+	RefundStatus         string             `json:"refundStatus"`
+	RefundKey            string             `json:"refundKey"`
+	GatewayResponse      string             `json:"gatewayResponse"`
+	RejectionReason      string             `json:"rejectionReason"`
+	ToCredit             bool               `json:"toCredit"`
+	ApprovedBy           *int               `json:"approvedBy"`
+	ApprovedAt           *time.Time         `json:"approvedAt"`
+	TransactionBilling   TransactionBilling `gorm:"foreignKey:TransactionBillingID" json:"transactionBilling"`
+}
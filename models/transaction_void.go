@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+type TransactionVoid struct {
+	Master
+	TransactionBillingID uint               `json:"transactionBillingId"`
+	SchoolID             uint               `json:"schoolId"`
+	Reason               string             `json:"reason"`
+	VoidStatus           string             `json:"voidStatus"`
+	RejectionReason      string             `json:"rejectionReason"`
+	ApprovedBy           *int               `json:"approvedBy"`
+	ApprovedAt           *time.Time         `json:"approvedAt"`
+	TransactionBilling   TransactionBilling `gorm:"foreignKey:TransactionBillingID" json:"transactionBilling"`
+}
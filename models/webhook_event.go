@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type WebhookEvent struct {
+	Master
+	EventKey          string     `json:"eventKey"`
+	OrderID           string     `json:"orderId"`
+	TransactionStatus string     `json:"transactionStatus"`
+	StatusCode        string     `json:"statusCode"`
+	PaymentStatus     string     `json:"paymentStatus"`
+	Payload           string     `json:"payload"`
+	ProcessStatus     string     `json:"processStatus"`
+	Attempts          int        `json:"attempts"`
+	ErrorMessage      string     `json:"errorMessage"`
+	ProcessedAt       *time.Time `json:"processedAt"`
+}
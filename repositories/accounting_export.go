@@ -0,0 +1,173 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"schoolPayment/dtos/response"
+	"schoolPayment/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	AccountingMappingLedgerAccount = "ledger_account"
+	AccountingMappingBillingType   = "billing_type"
+	AccountingMappingBankAccount   = "bank_account"
+
+	AccountingExportFormatAccurate = "accurate"
+	AccountingExportFormatJurnal   = "jurnal_id"
+	AccountingExportFormatGeneric  = "generic"
+)
+
+var ErrAccountingExportConflict = errors.New("some journal entries were exported at the same time, please try again")
+
+type AccountingExportRepository interface {
+	GetAccountingAccountMappings(schoolID uint) ([]models.AccountingAccountMapping, error)
+	GetAccountingAccountMappingByID(id uint, schoolID uint) (*models.AccountingAccountMapping, error)
+	AccountingAccountMappingExists(mappingType string, reference string, schoolID uint, excludeID uint) (bool, error)
+	CreateAccountingAccountMapping(mapping *models.AccountingAccountMapping) (*models.AccountingAccountMapping, error)
+	UpdateAccountingAccountMapping(mapping *models.AccountingAccountMapping) error
+	BillingTypeBelongsToSchool(billingTypeID uint, schoolID uint) (bool, error)
+	BankAccountBelongsToSchool(bankAccountID uint, schoolID uint) (bool, error)
+	GetUnexportedJournalLines(schoolID uint, startDate time.Time, endDate time.Time) ([]response.AccountingJournalLine, error)
+	GetExportedJournalLines(exportID uint) ([]response.AccountingJournalLine, error)
+	CreateAccountingExport(accountingExport *models.AccountingExport, journalEntryIDs []uint) error
+	GetAllAccountingExport(page int, limit int, schoolID uint) ([]models.AccountingExport, int64, error)
+	GetAccountingExportByID(id uint, schoolID uint) (*models.AccountingExport, error)
+}
+
+type accountingExportRepository struct {
+	db *gorm.DB
+}
+
+func NewAccountingExportRepository(db *gorm.DB) AccountingExportRepository {
+	return &accountingExportRepository{db: db}
+}
+
+func (r *accountingExportRepository) GetAccountingAccountMappings(schoolID uint) ([]models.AccountingAccountMapping, error) {
+	var mappings []models.AccountingAccountMapping
+	err := r.db.Where("school_id = ? AND deleted_at IS NULL", schoolID).
+		Order("mapping_type, reference").
+		Find(&mappings).Error
+	return mappings, err
+}
+
+func (r *accountingExportRepository) GetAccountingAccountMappingByID(id uint, schoolID uint) (*models.AccountingAccountMapping, error) {
+	var mapping models.AccountingAccountMapping
+	err := r.db.Where("id = ? AND school_id = ? AND deleted_at IS NULL", id, schoolID).First(&mapping).Error
+	if err != nil {
+		return nil, err
+	}
+	return &mapping, nil
+}
+
+func (r *accountingExportRepository) AccountingAccountMappingExists(mappingType string, reference string, schoolID uint, excludeID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.AccountingAccountMapping{}).
+		Where("mapping_type = ? AND reference = ? AND school_id = ? AND id <> ? AND deleted_at IS NULL", mappingType, reference, schoolID, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *accountingExportRepository) CreateAccountingAccountMapping(mapping *models.AccountingAccountMapping) (*models.AccountingAccountMapping, error) {
+	if err := r.db.Create(mapping).Error; err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+func (r *accountingExportRepository) UpdateAccountingAccountMapping(mapping *models.AccountingAccountMapping) error {
+	return r.db.Save(mapping).Error
+}
+
+func (r *accountingExportRepository) BillingTypeBelongsToSchool(billingTypeID uint, schoolID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.BillingType{}).
+		Where("id = ? AND school_id = ? AND deleted_at IS NULL", billingTypeID, schoolID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *accountingExportRepository) BankAccountBelongsToSchool(bankAccountID uint, schoolID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.BankAccount{}).
+		Where("id = ? AND school_id = ? AND deleted_at IS NULL", bankAccountID, schoolID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+const accountingJournalLineQuery = `
+	SELECT je.id AS journal_entry_id, je.entry_date, je.source_type, je.billing_type_id, je.bank_account_id,
+		la.code AS ledger_account_code, la.name AS ledger_account_name, jl.debit, jl.credit
+	FROM journal_entries je
+	JOIN journal_lines jl ON jl.journal_entry_id = je.id AND jl.deleted_at IS NULL
+	JOIN ledger_accounts la ON la.id = jl.ledger_account_id
+`
+
+// GetUnexportedJournalLines returns the lines of the school's entries between startDate and
+// endDate, both included, that are not in an export yet.
+func (r *accountingExportRepository) GetUnexportedJournalLines(schoolID uint, startDate time.Time, endDate time.Time) ([]response.AccountingJournalLine, error) {
+	var lines []response.AccountingJournalLine
+	query := accountingJournalLineQuery + `
+		WHERE je.school_id = ? AND je.deleted_at IS NULL AND je.accounting_export_id IS NULL
+		AND je.entry_date >= ? AND je.entry_date < ?
+		ORDER BY je.entry_date, je.id, jl.id
+	`
+	err := r.db.Raw(query, schoolID, startDate, endDate.AddDate(0, 0, 1)).Scan(&lines).Error
+	return lines, err
+}
+
+func (r *accountingExportRepository) GetExportedJournalLines(exportID uint) ([]response.AccountingJournalLine, error) {
+	var lines []response.AccountingJournalLine
+	query := accountingJournalLineQuery + `
+		WHERE je.accounting_export_id = ? AND je.deleted_at IS NULL
+		ORDER BY je.entry_date, je.id, jl.id
+	`
+	err := r.db.Raw(query, exportID).Scan(&lines).Error
+	return lines, err
+}
+
+// CreateAccountingExport saves the export and marks its journal entries as exported. It fails
+// when another export took one of the entries in the meantime.
+func (r *accountingExportRepository) CreateAccountingExport(accountingExport *models.AccountingExport, journalEntryIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(accountingExport).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.JournalEntry{}).
+			Where("id IN ? AND accounting_export_id IS NULL", journalEntryIDs).
+			Update("accounting_export_id", accountingExport.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(journalEntryIDs)) {
+			return ErrAccountingExportConflict
+		}
+		return nil
+	})
+}
+
+func (r *accountingExportRepository) GetAllAccountingExport(page int, limit int, schoolID uint) ([]models.AccountingExport, int64, error) {
+	var exports []models.AccountingExport
+	var total int64
+
+	query := r.db.Model(&models.AccountingExport{}).Where("school_id = ? AND deleted_at IS NULL", schoolID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&exports).Error
+	return exports, total, err
+}
+
+func (r *accountingExportRepository) GetAccountingExportByID(id uint, schoolID uint) (*models.AccountingExport, error) {
+	var accountingExport models.AccountingExport
+	err := r.db.Where("id = ? AND school_id = ? AND deleted_at IS NULL", id, schoolID).First(&accountingExport).Error
+	if err != nil {
+		return nil, err
+	}
+	return &accountingExport, nil
+}
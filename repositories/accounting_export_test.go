@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"schoolPayment/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAccountingExport(t *testing.T) {
+	newExport := func() *models.AccountingExport {
+		return &models.AccountingExport{
+			SchoolID:     1,
+			ExportFormat: AccountingExportFormatAccurate,
+			StartDate:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:      time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+			TotalEntries: 2,
+			TotalAmount:  700000,
+		}
+	}
+
+	t.Run("Marks every entry", func(t *testing.T) {
+		gormDB, mock := setupTestDB(t)
+		repo := NewAccountingExportRepository(gormDB)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "accounting_exports"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectExec(`UPDATE "journal_entries" SET "accounting_export_id"=\$1,"updated_at"=\$2 WHERE id IN \(\$3,\$4\) AND accounting_export_id IS NULL`).
+			WithArgs(7, sqlmock.AnyArg(), 1, 2).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		accountingExport := newExport()
+		err := repo.CreateAccountingExport(accountingExport, []uint{1, 2})
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(7), accountingExport.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Entry exported by another run", func(t *testing.T) {
+		gormDB, mock := setupTestDB(t)
+		repo := NewAccountingExportRepository(gormDB)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "accounting_exports"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectExec(`UPDATE "journal_entries"`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectRollback()
+
+		err := repo.CreateAccountingExport(newExport(), []uint{1, 2})
+
+		assert.ErrorIs(t, err, ErrAccountingExportConflict)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
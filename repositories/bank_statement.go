@@ -0,0 +1,350 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	BankStatementFormatBCA     = "bca_csv"
+	BankStatementFormatBRI     = "bri_csv"
+	BankStatementFormatMandiri = "mandiri_csv"
+	BankStatementFormatMT940   = "mt940"
+
+	BankStatementLineStatusUnmatched = "BS01"
+	BankStatementLineStatusProposed  = "BS02"
+	BankStatementLineStatusPosted    = "BS03"
+	BankStatementLineStatusIgnored   = "BS04"
+
+	BankStatementMatchNis    = "nis"
+	BankStatementMatchVA     = "va"
+	BankStatementMatchAmount = "amount"
+
+	// TransactionTypeBankTransfer marks payments posted from a bank statement
+	TransactionTypeBankTransfer = "PT03"
+)
+
+var ErrBankStatementLineClosed = errors.New("bank statement line has already been posted or ignored")
+
+type BankStatementRepository interface {
+	GetBankAccountForSchool(bankAccountID uint, schoolID uint) (*models.BankAccount, error)
+	GetExistingLineHashes(bankAccountID uint, hashes []string) (map[string]bool, error)
+	CreateBankStatementImport(statementImport *models.BankStatementImport, lines []models.BankStatementLine) error
+	GetAllBankStatementImport(page int, limit int, bankAccountID uint, schoolID uint) ([]models.BankStatementImport, int64, error)
+	GetBankStatementImportByID(id uint, schoolID uint) (*models.BankStatementImport, error)
+	GetAllBankStatementLine(page int, limit int, importID uint, matchStatus string, schoolID uint) ([]models.BankStatementLine, int64, error)
+	GetBankStatementLineByID(id uint, schoolID uint) (*models.BankStatementLine, error)
+	UpdateBankStatementLine(line *models.BankStatementLine) error
+	GetSchoolStudents(schoolID uint) ([]response.BankStatementStudent, error)
+	GetOpenInstallmentsForBankAccount(bankAccountID uint) ([]models.BillingStudent, error)
+	PostBankStatementLine(line *models.BankStatementLine, studentID uint, billingStudentIDs []int, userID int) (*models.TransactionBilling, error)
+}
+
+type bankStatementRepository struct {
+	db *gorm.DB
+}
+
+func NewBankStatementRepository(db *gorm.DB) BankStatementRepository {
+	return &bankStatementRepository{db: db}
+}
+
+func (r *bankStatementRepository) GetBankAccountForSchool(bankAccountID uint, schoolID uint) (*models.BankAccount, error) {
+	var bankAccount models.BankAccount
+	err := r.db.Where("id = ? AND school_id = ? AND deleted_at IS NULL", bankAccountID, schoolID).First(&bankAccount).Error
+	if err != nil {
+		return nil, err
+	}
+	return &bankAccount, nil
+}
+
+func (r *bankStatementRepository) GetExistingLineHashes(bankAccountID uint, hashes []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(hashes) == 0 {
+		return existing, nil
+	}
+
+	var found []string
+	err := r.db.Model(&models.BankStatementLine{}).
+		Where("bank_account_id = ? AND line_hash IN ?", bankAccountID, hashes).
+		Pluck("line_hash", &found).Error
+	for _, hash := range found {
+		existing[hash] = true
+	}
+	return existing, err
+}
+
+func (r *bankStatementRepository) CreateBankStatementImport(statementImport *models.BankStatementImport, lines []models.BankStatementLine) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(statementImport).Error; err != nil {
+			return err
+		}
+		if len(lines) == 0 {
+			return nil
+		}
+
+		for i := range lines {
+			lines[i].BankStatementImportID = statementImport.ID
+		}
+		return tx.CreateInBatches(lines, 500).Error
+	})
+}
+
+func (r *bankStatementRepository) GetAllBankStatementImport(page int, limit int, bankAccountID uint, schoolID uint) ([]models.BankStatementImport, int64, error) {
+	var imports []models.BankStatementImport
+	var total int64
+
+	query := r.db.Model(&models.BankStatementImport{}).Where("school_id = ? AND deleted_at IS NULL", schoolID)
+	if bankAccountID != 0 {
+		query = query.Where("bank_account_id = ?", bankAccountID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if limit != 0 {
+		query = query.Offset((page - 1) * limit).Limit(limit)
+	}
+
+	err := query.Order("id DESC").Find(&imports).Error
+	return imports, total, err
+}
+
+func (r *bankStatementRepository) GetBankStatementImportByID(id uint, schoolID uint) (*models.BankStatementImport, error) {
+	var statementImport models.BankStatementImport
+	err := r.db.Where("id = ? AND school_id = ? AND deleted_at IS NULL", id, schoolID).First(&statementImport).Error
+	if err != nil {
+		return nil, err
+	}
+	return &statementImport, nil
+}
+
+func (r *bankStatementRepository) GetAllBankStatementLine(page int, limit int, importID uint, matchStatus string, schoolID uint) ([]models.BankStatementLine, int64, error) {
+	var lines []models.BankStatementLine
+	var total int64
+
+	query := r.db.Model(&models.BankStatementLine{}).Where("school_id = ? AND deleted_at IS NULL", schoolID)
+	if importID != 0 {
+		query = query.Where("bank_statement_import_id = ?", importID)
+	}
+	if matchStatus != "" {
+		query = query.Where("match_status = ?", matchStatus)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if limit != 0 {
+		query = query.Offset((page - 1) * limit).Limit(limit)
+	}
+
+	err := query.Order("transaction_date, id").Find(&lines).Error
+	return lines, total, err
+}
+
+func (r *bankStatementRepository) GetBankStatementLineByID(id uint, schoolID uint) (*models.BankStatementLine, error) {
+	var line models.BankStatementLine
+	err := r.db.Where("id = ? AND school_id = ? AND deleted_at IS NULL", id, schoolID).First(&line).Error
+	if err != nil {
+		return nil, err
+	}
+	return &line, nil
+}
+
+func (r *bankStatementRepository) UpdateBankStatementLine(line *models.BankStatementLine) error {
+	return r.db.Save(line).Error
+}
+
+// GetSchoolStudents lists the students of the school with a NIS that a transfer remark can refer to.
+func (r *bankStatementRepository) GetSchoolStudents(schoolID uint) ([]response.BankStatementStudent, error) {
+	var students []response.BankStatementStudent
+	err := r.db.Table("students s").
+		Select("s.id AS student_id, s.nis").
+		Joins("JOIN school_classes sc ON sc.id = s.school_class_id").
+		Where("sc.school_id = ? AND s.deleted_at IS NULL AND COALESCE(s.nis, '') <> ''", schoolID).
+		Scan(&students).Error
+	return students, err
+}
+
+// GetOpenInstallmentsForBankAccount returns the installments billed to the bank account that are
+// still waiting for money and are not held by a checkout, oldest first per student.
+func (r *bankStatementRepository) GetOpenInstallmentsForBankAccount(bankAccountID uint) ([]models.BillingStudent, error) {
+	var installments []models.BillingStudent
+	err := r.db.Table("billing_students bs").
+		Select("bs.*").
+		Joins("JOIN billings b ON b.id = bs.billing_id").
+		Where("b.bank_account_id = ? AND b.deleted_at IS NULL AND b.is_donation = ?", bankAccountID, false).
+		Where("bs.deleted_at IS NULL AND bs.payment_status <> ?", BillingStudentStatusPaid).
+		Where("bs.reserved_order_id IS NULL OR bs.reserved_until < ?", time.Now()).
+		Order("bs.student_id, bs.due_date ASC NULLS LAST, bs.id").
+		Find(&installments).Error
+	return installments, err
+}
+
+// PostBankStatementLine books the transfer as a paid bank transfer transaction of the student.
+// The amount settles the installments oldest first; what is left over becomes student credit.
+func (r *bankStatementRepository) PostBankStatementLine(line *models.BankStatementLine, studentID uint, billingStudentIDs []int, userID int) (*models.TransactionBilling, error) {
+	var transaction models.TransactionBilling
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var locked models.BankStatementLine
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND deleted_at IS NULL", line.ID).
+			First(&locked).Error
+		if err != nil {
+			return err
+		}
+		if locked.MatchStatus == BankStatementLineStatusPosted || locked.MatchStatus == BankStatementLineStatusIgnored {
+			return ErrBankStatementLineClosed
+		}
+
+		var bankAccount models.BankAccount
+		if err := tx.Where("id = ?", locked.BankAccountID).First(&bankAccount).Error; err != nil {
+			return err
+		}
+
+		// Installments of another student or paid into another account cannot take this money
+		uniqueIDs := make(map[int]bool, len(billingStudentIDs))
+		for _, id := range billingStudentIDs {
+			uniqueIDs[id] = true
+		}
+		var owned int64
+		err = tx.Table("billing_students bs").
+			Joins("JOIN billings b ON b.id = bs.billing_id").
+			Where("bs.id IN ? AND bs.student_id = ? AND b.bank_account_id = ? AND bs.deleted_at IS NULL", billingStudentIDs, studentID, locked.BankAccountID).
+			Count(&owned).Error
+		if err != nil {
+			return err
+		}
+		if int(owned) != len(uniqueIDs) {
+			return fmt.Errorf("installments do not belong to the student or the bank account")
+		}
+
+		reservationKey := fmt.Sprintf("BANK-%d", locked.ID)
+		if err := ReserveBillingStudents(tx, billingStudentIDs, reservationKey, time.Now().Add(time.Minute)); err != nil {
+			return err
+		}
+
+		billingStudents, err := GetBillingStudentsForPayment(tx, billingStudentIDs)
+		if err != nil {
+			return err
+		}
+
+		invoiceNumber, err := NextInvoiceNumber(tx, locked.SchoolID, studentID)
+		if err != nil {
+			return fmt.Errorf("failed to generate invoice number: %v", err)
+		}
+
+		var billingIDs []int
+		seenBilling := make(map[uint]bool)
+		for _, billingStudent := range billingStudents {
+			if !seenBilling[billingStudent.BillingID] {
+				seenBilling[billingStudent.BillingID] = true
+				billingIDs = append(billingIDs, int(billingStudent.BillingID))
+			}
+		}
+
+		reference := locked.Reference
+		if reference == "" {
+			reference = reservationKey
+		}
+		idStrings := make([]string, 0, len(billingStudentIDs))
+		for _, id := range billingStudentIDs {
+			idStrings = append(idStrings, strconv.Itoa(id))
+		}
+
+		transaction = models.TransactionBilling{
+			StudentID:         studentID,
+			BillingID:         IntsToString(billingIDs),
+			TransactionType:   TransactionTypeBankTransfer,
+			TotalAmount:       int(locked.Amount),
+			ReferenceNumber:   reference,
+			Description:       locked.Description,
+			TransactionStatus: "PS02",
+			BillingStudentIds: strings.Join(idStrings, ","),
+			InvoiceNumber:     invoiceNumber,
+			AccountNumber:     bankAccount.AccountNumber,
+			ExpiryTime:        time.Now().Format("2006-01-02T15:04:05"),
+		}
+		transaction.CreatedBy = userID
+		if err := tx.Create(&transaction).Error; err != nil {
+			return err
+		}
+
+		transactionTime := locked.TransactionDate
+		detail := models.TransactionBillingDetail{
+			TransactionBillingID: transaction.ID,
+			BankName:             &bankAccount.BankName,
+			TransactionTime:      &transactionTime,
+		}
+		detail.CreatedBy = userID
+		if _, err := CreateTransactionBillingDetail(tx, &detail); err != nil {
+			return err
+		}
+
+		history := models.TransactionBillingHistory{
+			TransactionBillingId: transaction.ID,
+			ReferenceNumber:      reference,
+			InvoiceNumber:        invoiceNumber,
+			TransactionStatus:    "PS02",
+		}
+		history.CreatedBy = userID
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+
+		left := locked.Amount
+		for i := range billingStudents {
+			remaining := billingStudents[i].Amount - billingStudents[i].PaidAmount
+			paid := remaining
+			if left < remaining {
+				paid = left
+			}
+			if paid <= 0 {
+				continue
+			}
+			if err := RecordBillingStudentPayment(tx, &billingStudents[i], transaction.ID, paid, userID); err != nil {
+				return err
+			}
+			left -= paid
+		}
+
+		if left > 0 {
+			credit := models.StudentCredit{
+				StudentID:            studentID,
+				EntryType:            StudentCreditTypeOverpayment,
+				Amount:               left,
+				TransactionBillingID: &transaction.ID,
+			}
+			credit.CreatedBy = userID
+			if err := RecordStudentCredit(tx, &credit); err != nil {
+				return err
+			}
+		}
+
+		if err := ReleaseBillingStudentReservation(tx, reservationKey); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		line.MatchStatus = BankStatementLineStatusPosted
+		line.StudentID = &studentID
+		line.BillingStudentIds = transaction.BillingStudentIds
+		line.TransactionBillingID = &transaction.ID
+		line.ConfirmedBy = &userID
+		line.ConfirmedAt = &now
+		line.UpdatedBy = userID
+		return tx.Save(line).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
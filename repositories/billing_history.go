@@ -54,10 +54,14 @@ func (billingHistoryRepository *BillingHistoryRepository) GetAllBillingHistory(p
 				WHEN tb.transaction_status = 'PS01' THEN 'menunggu'
 				WHEN tb.transaction_status = 'PS02' THEN 'lunas'
 				WHEN tb.transaction_status = 'PS03' THEN 'gagal'
+				WHEN tb.transaction_status = 'PS04' THEN 'dikembalikan'
+				WHEN tb.transaction_status = 'PS05' THEN 'dikembalikan sebagian'
+				WHEN tb.transaction_status = 'PS06' THEN 'dibatalkan'
 				ELSE tb.transaction_status  -- Default case, if no match
 			end as transaction_status,
 			CASE 
 				WHEN tb.transaction_type = 'PT01' THEN 'Kasir'
+				WHEN tb.transaction_type = 'PT03' THEN concat('Transfer Bank - ', tbd.bank_name)
 				ELSE (
 					SELECT concat(mp.payment_method, ' - ', mp.bank_name) 
 					FROM master_payment_method mp
@@ -188,6 +192,9 @@ func (billingHistoryRepository *BillingHistoryRepository) GetDetailBillingHistor
 			WHEN tb.transaction_status = 'PS01' THEN 'menunggu'
 			WHEN tb.transaction_status = 'PS02' THEN 'lunas'
 			WHEN tb.transaction_status = 'PS03' THEN 'gagal'
+			WHEN tb.transaction_status = 'PS04' THEN 'dikembalikan'
+			WHEN tb.transaction_status = 'PS05' THEN 'dikembalikan sebagian'
+			WHEN tb.transaction_status = 'PS06' THEN 'dibatalkan'
 			ELSE tb.transaction_status  -- Default case, if no match
 		end as transaction_status,
 		tbd.change_amount,
@@ -196,7 +203,9 @@ func (billingHistoryRepository *BillingHistoryRepository) GetDetailBillingHistor
 		tb.total_amount,
 		tb.billing_student_ids,
 		tbd.master_payment_method_id as payment_method_id,
-		tb.transaction_type
+		tb.transaction_type,
+		tb.credit_amount,
+		tbd.parent_admin_fee
 
 		from transaction_billings tb 
 		JOIN 
@@ -247,6 +256,7 @@ func (billingHistoryRepository *BillingHistoryRepository) GetDataForInvoice(invo
 		NOW() AS print_date, 
 		CASE 
 			WHEN tb.transaction_type = 'PT01' THEN 'kasir'
+			WHEN tb.transaction_type = 'PT03' THEN concat('transfer bank - ', tbd.bank_name)
 			ELSE (
 				SELECT concat(mp.payment_method, ' - ', mp.bank_name) 
 				FROM master_payment_method mp
@@ -261,7 +271,13 @@ func (billingHistoryRepository *BillingHistoryRepository) GetDataForInvoice(invo
 		sc.school_id, 
 		tb.total_amount, 
 		0 AS sub_total,
-		tbd.discount
+		tbd.discount,
+		tb.transaction_status,
+		COALESCE((
+			SELECT tv.reason FROM transaction_voids tv
+			WHERE tv.transaction_billing_id = tb.id AND tv.void_status = 'VS02' AND tv.deleted_at IS NULL
+			LIMIT 1
+		), '') AS void_reason
 	FROM transaction_billings tb 
 	JOIN transaction_billing_details tbd 
 		ON tbd.transaction_billing_id = tb.id
@@ -328,6 +344,8 @@ func (billingHistoryRepository *BillingHistoryRepository) GetDataForInvoice(invo
 			Discount:        invoice.Discount,
 			TotalAmount:     invoice.TotalAmount,
 			BillingStudents: studentDetails,
+			IsVoid:          invoice.TransactionStatus == TransactionStatusVoid,
+			VoidReason:      invoice.VoidReason,
 		}
 		res = append(res, respDataInvoice)
 	}
@@ -94,7 +94,11 @@ func (r *billingReportRepository) GetBillingReport(page int, limit int, sortBy s
 			CONCAT(s.nis, ' - ', s.full_name) AS student_name,
 			sg.school_grade_name AS school_grade_name,
 			sc.school_class_name AS school_class_name,
+			bs.amount + bs.discount_amount AS gross_amount,
+			bs.discount_amount AS discount_amount,
 			bs.amount AS amount,
+			bs.paid_amount AS paid_amount,
+			bs.amount - bs.paid_amount AS remaining_amount,
 			sy.school_year_name AS school_year_name,
 			b.bank_account_id AS bank_account_id,
 			ba.bank_name AS bank_name,
@@ -102,6 +106,7 @@ func (r *billingReportRepository) GetBillingReport(page int, limit int, sortBy s
 			CASE
 				WHEN bs.payment_status = '1' THEN 'belum bayar'
 				WHEN bs.payment_status = '2' THEN 'lunas'
+				WHEN bs.payment_status = '3' THEN 'sebagian'
 				ELSE 'Status Tidak Diketahui'
 			END AS payment_status
 		FROM billing_students bs
@@ -164,11 +169,14 @@ func (r *billingReportRepository) GetSummaryBillingReport(schoolGradeId int, sch
 
 	query := `
 		SELECT 
+			SUM(bs.amount + bs.discount_amount) as total_gross_amount,
+			SUM(bs.discount_amount) as total_discount_amount,
 			SUM(bs.amount) as total_billing_amount,
-			SUM(CASE WHEN bs.payment_status = '2' THEN bs.amount ELSE 0 END) as total_pay_amount,
-			SUM(CASE WHEN bs.payment_status = '1' THEN bs.amount ELSE 0 END) as total_not_pay_amount,
+			SUM(bs.paid_amount) as total_pay_amount,
+			SUM(bs.amount - bs.paid_amount) as total_not_pay_amount,
 			COUNT(CASE WHEN bs.payment_status = '2' THEN 1 END) as total_billing_pay,
 			COUNT(CASE WHEN bs.payment_status = '1' THEN 1 END) as total_billing_not_pay,
+			COUNT(CASE WHEN bs.payment_status = '3' THEN 1 END) as total_billing_partial_pay,
 			COUNT (distinct bs.student_id) as total_student
 		FROM billing_students bs 
 		LEFT JOIN billings b ON b.id = bs.billing_id
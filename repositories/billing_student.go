@@ -20,6 +20,7 @@ type BillingStudentRepository interface {
 	DeleteBillingStudent(billingStudentId int, deletedBy int) error
 	GetTotalAmountByBillingStudentIds(billingStudentIds []string) (int, error)
 	GetListBillingId(billingStudentIds []string) ([]int, error)
+	GetBillingStudentsByIDs(billingStudentIds []int) ([]models.BillingStudent, error)
 	// GetFirstBilling(studentId int) ([]response.LatestBillingStudent, error)
 	// CreateBillingStudent(billingStudent *models.BillingStudent) (*models.BillingStudent, error)
 	// GetDetailBillingIDRepositories(billingId int, studentId int) (response.BillingStudentByStudentIDBillingID, error)
@@ -57,7 +58,10 @@ func (billingStudentRepository *billingStudentRepository) GetAllBillingStudent(p
 			sg.school_grade_name,
 			sc.school_class_name,
 			CONCAT(s.nis, ' - ', s.full_name) as student_name,
-			bs.amount
+			bs.amount,
+			bs.paid_amount,
+			bs.amount - bs.paid_amount as remaining_amount,
+			bs.late_fee_for_id
 			from billing_students bs 
 			join billings b on b.id = bs.billing_id
 			join students s on s.id = bs.student_id 
@@ -67,7 +71,7 @@ func (billingStudentRepository *billingStudentRepository) GetAllBillingStudent(p
 			join user_schools usc on usc.user_id = ust.user_id
 			join schools sch on	sch.id = usc.school_id
 			where bs.deleted_at is null 
-			and bs.payment_status='1'
+			and bs.payment_status in ('1','3')
 			and b.is_donation = false
 	`
 
@@ -195,9 +199,13 @@ func (billingStudentRepository *billingStudentRepository) GetInstallmentDetails(
 			bs.id AS billing_student_id,
 			bs.detail_billing_name,
 			bs.amount,
+			bs.paid_amount,
+			bs.amount - bs.paid_amount AS remaining_amount,
 			bs.due_date,
+			bs.late_fee_for_id,
 			CASE 
 				WHEN bs.payment_status = '1' THEN 'belum bayar' 
+				WHEN bs.payment_status = '3' THEN 'sebagian' 
 				ELSE 'lunas' 
 			END AS payment_status,
 			b.billing_type,
@@ -219,6 +227,8 @@ func (billingStudentRepository *billingStudentRepository) GetInstallmentDetails(
 				bs.payment_status = '1' AND tb.transaction_status IS NULL
 				OR
 				bs.payment_status = '1' AND tb.transaction_status NOT IN ('PS01','PS02')
+				OR
+				bs.payment_status = '3' AND (tb.transaction_status IS NULL OR tb.transaction_status != 'PS01')
 			)
 		ORDER BY 
 			bs.updated_at DESC NULLS LAST,
@@ -310,8 +320,8 @@ func (billingStudentRepository *billingStudentRepository) DeleteBillingStudent(b
 
 func (billingStudentRepository *billingStudentRepository) GetTotalAmountByBillingStudentIds(billingStudentIds []string) (int, error) {
 	var totalAmount int
-	// Sum the amount where id is in the provided billingStudentIds array and deleted_at is null
-	result := database.DB.Model(&models.BillingStudent{}).Where("id IN (?) AND deleted_at IS NULL", billingStudentIds).Select("SUM(amount)").Scan(&totalAmount)
+	// Sum the remaining balance where id is in the provided billingStudentIds array and deleted_at is null
+	result := database.DB.Model(&models.BillingStudent{}).Where("id IN (?) AND deleted_at IS NULL", billingStudentIds).Select("SUM(amount - paid_amount)").Scan(&totalAmount)
 	if result.Error != nil {
 		return 0, result.Error
 	}
@@ -330,6 +340,15 @@ func (billingStudentRepository *billingStudentRepository) GetListBillingId(billi
 	return listBillingId, nil
 }
 
+// GetBillingStudentsByIDs returns the installments with the oldest due date first.
+func (billingStudentRepository *billingStudentRepository) GetBillingStudentsByIDs(billingStudentIds []int) ([]models.BillingStudent, error) {
+	var billingStudents []models.BillingStudent
+	result := database.DB.Where("id IN ? AND deleted_at IS NULL", billingStudentIds).
+		Order("due_date ASC, id ASC").
+		Find(&billingStudents)
+	return billingStudents, result.Error
+}
+
 func (billingStudentRepository *billingStudentRepository) CheckBillingStudentExists(studentID, billingID, billingDetailID uint) (bool, error) {
 	var count int64
 	result := database.DB.Model(&models.BillingStudent{}).
@@ -381,9 +400,13 @@ func GetBillingStudentsDashboard(studentID int, limit int) ([]response.BillingSt
 			bs.id AS billing_student_id,
 			bs.detail_billing_name,
 			bs.amount,
+			bs.paid_amount,
+			bs.amount - bs.paid_amount AS remaining_amount,
 			bs.due_date,
+			bs.late_fee_for_id,
 			CASE 
 				WHEN bs.payment_status = '1' THEN 'belum bayar' 
+				WHEN bs.payment_status = '3' THEN 'sebagian' 
 				ELSE 'lunas' 
 			END AS payment_status,
 			b.billing_type,
@@ -402,6 +425,8 @@ func GetBillingStudentsDashboard(studentID int, limit int) ([]response.BillingSt
 				bs.payment_status = '1' AND tb.transaction_status IS NULL
 				OR
 				bs.payment_status = '1' AND tb.transaction_status NOT IN ('PS01', 'PS02', 'PS03')
+				OR
+				bs.payment_status = '3' AND (tb.transaction_status IS NULL OR tb.transaction_status != 'PS01')
 			)
 		LIMIT ?;
 	`
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	database "schoolPayment/configs"
+	models "schoolPayment/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	BillingStudentStatusUnpaid        = "1"
+	BillingStudentStatusPaid          = "2"
+	BillingStudentStatusPartiallyPaid = "3"
+)
+
+// GetBillingStudentsForPayment returns the installments ordered by due date so
+// partial payments settle the oldest installment first.
+func GetBillingStudentsForPayment(tx *gorm.DB, billingStudentIds []int) ([]models.BillingStudent, error) {
+	var billingStudents []models.BillingStudent
+	result := tx.Where("id IN ? AND deleted_at IS NULL", billingStudentIds).
+		Order("due_date ASC NULLS LAST, id ASC").
+		Find(&billingStudents)
+	return billingStudents, result.Error
+}
+
+// RecordBillingStudentPayment adds an entry to the paid-amount ledger and
+// moves the installment to "Sebagian" or "Lunas" based on the remaining balance.
+func RecordBillingStudentPayment(tx *gorm.DB, billingStudent *models.BillingStudent, transactionBillingID uint, amount int64, userID int) error {
+	if amount <= 0 {
+		return nil
+	}
+
+	remaining := billingStudent.Amount - billingStudent.PaidAmount
+	if amount > remaining {
+		return fmt.Errorf("payment amount %d exceeds remaining balance %d for billing student %d", amount, remaining, billingStudent.ID)
+	}
+
+	payment := models.BillingStudentPayment{
+		BillingStudentID:     billingStudent.ID,
+		TransactionBillingID: transactionBillingID,
+		Amount:               amount,
+	}
+	payment.CreatedBy = userID
+	if err := tx.Create(&payment).Error; err != nil {
+		return err
+	}
+
+	billingStudent.PaidAmount += amount
+	billingStudent.PaymentStatus = BillingStudentStatusPartiallyPaid
+	if billingStudent.PaidAmount >= billingStudent.Amount {
+		billingStudent.PaymentStatus = BillingStudentStatusPaid
+	}
+
+	return tx.Model(&models.BillingStudent{}).
+		Where("id = ?", billingStudent.ID).
+		Updates(map[string]interface{}{
+			"paid_amount":    billingStudent.PaidAmount,
+			"payment_status": billingStudent.PaymentStatus,
+			"updated_by":     userID,
+		}).Error
+}
+
+// SettleBillingStudents pays off the remaining balance of every installment in
+// the comma separated billingStudentIDs, as used by gateway settlements.
+func SettleBillingStudents(db *gorm.DB, billingStudentIDs string, transactionBillingID uint, userID int) error {
+	var ids []int
+	for _, idStr := range strings.Split(billingStudentIDs, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(idStr))
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		billingStudents, err := GetBillingStudentsForPayment(tx, ids)
+		if err != nil {
+			return err
+		}
+
+		for i := range billingStudents {
+			remaining := billingStudents[i].Amount - billingStudents[i].PaidAmount
+			if err := RecordBillingStudentPayment(tx, &billingStudents[i], transactionBillingID, remaining, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// HasBillingStudentPayments tells whether the transaction already paid installments.
+func HasBillingStudentPayments(db *gorm.DB, transactionBillingID uint) (bool, error) {
+	var count int64
+	err := db.Model(&models.BillingStudentPayment{}).
+		Where("transaction_billing_id = ? AND deleted_at IS NULL", transactionBillingID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func GetBillingStudentPaymentsByBillingStudentID(billingStudentID uint) ([]models.BillingStudentPayment, error) {
+	var payments []models.BillingStudentPayment
+	result := database.DB.Where("billing_student_id = ? AND deleted_at IS NULL", billingStudentID).
+		Order("created_at ASC").
+		Find(&payments)
+	return payments, result.Error
+}
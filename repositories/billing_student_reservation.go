@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	database "schoolPayment/configs"
+	models "schoolPayment/models"
+
+	"gorm.io/gorm"
+)
+
+// BillingStudentReservationTTL is how long installments stay locked to an online checkout. The
+// same duration is sent to the gateway as the payment expiry.
+const BillingStudentReservationTTL = 24 * time.Hour
+
+var ErrBillingStudentReserved = errors.New("one or more installments are already being paid in another transaction")
+
+// ReserveBillingStudents locks unpaid installments to the order until expiresAt. A reservation
+// held by another order that has not expired yet makes the whole reservation fail, so two
+// checkouts can never charge the same installment.
+func ReserveBillingStudents(tx *gorm.DB, billingStudentIds []int, orderID string, expiresAt time.Time) error {
+	uniqueIds := make(map[int]bool, len(billingStudentIds))
+	for _, id := range billingStudentIds {
+		uniqueIds[id] = true
+	}
+
+	result := tx.Model(&models.BillingStudent{}).
+		Where("id IN ? AND deleted_at IS NULL AND payment_status <> ?", billingStudentIds, BillingStudentStatusPaid).
+		Where("reserved_order_id IS NULL OR reserved_order_id = ? OR reserved_until < ?", orderID, time.Now()).
+		Updates(map[string]interface{}{
+			"reserved_order_id": orderID,
+			"reserved_until":    expiresAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if int(result.RowsAffected) != len(uniqueIds) {
+		return ErrBillingStudentReserved
+	}
+	return nil
+}
+
+// ReserveBillingStudentsForOrder reserves the comma separated installments in their own transaction.
+func ReserveBillingStudentsForOrder(billingStudentIds []string, orderID string, expiresAt time.Time) error {
+	var ids []int
+	for _, idStr := range billingStudentIds {
+		id, err := strconv.Atoi(strings.TrimSpace(idStr))
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		return ReserveBillingStudents(tx, ids, orderID, expiresAt)
+	})
+}
+
+// ReleaseBillingStudentReservation unlocks every installment reserved by the order.
+func ReleaseBillingStudentReservation(tx *gorm.DB, orderID string) error {
+	if orderID == "" {
+		return nil
+	}
+
+	return tx.Model(&models.BillingStudent{}).
+		Where("reserved_order_id = ?", orderID).
+		Updates(map[string]interface{}{
+			"reserved_order_id": nil,
+			"reserved_until":    nil,
+		}).Error
+}
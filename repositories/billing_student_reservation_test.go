@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReserveBillingStudents(t *testing.T) {
+	gormDB, mock := setupTestDB(t)
+	expiresAt := time.Now().Add(BillingStudentReservationTTL)
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "billing_students" SET "reserved_order_id"=\$1,"reserved_until"=\$2`).
+			WithArgs("ORDER-1", expiresAt, sqlmock.AnyArg(), 11, 12, BillingStudentStatusPaid, "ORDER-1", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		err := ReserveBillingStudents(gormDB, []int{11, 12}, "ORDER-1", expiresAt)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Reserved by another order", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "billing_students" SET "reserved_order_id"=\$1,"reserved_until"=\$2`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := ReserveBillingStudents(gormDB, []int{11, 12}, "ORDER-2", expiresAt)
+
+		assert.Equal(t, ErrBillingStudentReserved, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestReleaseBillingStudentReservation(t *testing.T) {
+	gormDB, mock := setupTestDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "billing_students" SET "reserved_order_id"=\$1,"reserved_until"=\$2,"updated_at"=\$3 WHERE reserved_order_id = \$4`).
+		WithArgs(nil, nil, sqlmock.AnyArg(), "ORDER-1").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err := ReleaseBillingStudentReservation(gormDB, "ORDER-1")
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,221 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	CashierShiftStatusOpen   = "open"
+	CashierShiftStatusClosed = "closed"
+)
+
+var (
+	ErrCashierShiftNotOpen = errors.New("no open cashier shift, please open a shift first")
+	ErrCashierShiftOpen    = errors.New("cashier already has an open shift")
+	ErrCashierShiftClosed  = errors.New("cashier shift is already closed")
+)
+
+type CashierShiftRepository interface {
+	CreateCashierShift(shift *models.CashierShift) error
+	GetOpenCashierShift(userID uint) (*models.CashierShift, error)
+	GetCashierShiftByID(id uint, schoolID uint) (*models.CashierShift, error)
+	GetAllCashierShift(page int, limit int, schoolID uint, userID uint, status string, startDate, endDate time.Time) ([]response.CashierShiftData, int64, error)
+	GetCashierShiftTransactions(shiftID uint) ([]response.CashierShiftTransaction, error)
+	CloseCashierShift(id uint, schoolID uint, countedCash int64, note string, userID int) (*models.CashierShift, error)
+}
+
+type cashierShiftRepository struct {
+	db *gorm.DB
+}
+
+func NewCashierShiftRepository(db *gorm.DB) CashierShiftRepository {
+	return &cashierShiftRepository{db: db}
+}
+
+func (r *cashierShiftRepository) CreateCashierShift(shift *models.CashierShift) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&models.CashierShift{}).
+			Where("user_id = ? AND status = ? AND deleted_at IS NULL", shift.UserID, CashierShiftStatusOpen).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrCashierShiftOpen
+		}
+		// The partial unique index still rejects a second shift opened at the same moment
+		return tx.Create(shift).Error
+	})
+}
+
+func (r *cashierShiftRepository) GetOpenCashierShift(userID uint) (*models.CashierShift, error) {
+	var shift models.CashierShift
+	err := r.db.Where("user_id = ? AND status = ? AND deleted_at IS NULL", userID, CashierShiftStatusOpen).
+		First(&shift).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCashierShiftNotOpen
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cashReceived, totalTransaction, err := sumCashierShiftCash(r.db, shift.ID)
+	if err != nil {
+		return nil, err
+	}
+	shift.CashReceived = cashReceived
+	shift.TotalTransaction = totalTransaction
+	shift.ExpectedCash = shift.OpeningFloat + cashReceived
+	return &shift, nil
+}
+
+func (r *cashierShiftRepository) GetCashierShiftByID(id uint, schoolID uint) (*models.CashierShift, error) {
+	var shift models.CashierShift
+	err := r.db.Where("id = ? AND school_id = ? AND deleted_at IS NULL", id, schoolID).First(&shift).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// An open shift shows the running totals, a closed one keeps what was booked at closing
+	if shift.Status == CashierShiftStatusOpen {
+		cashReceived, totalTransaction, err := sumCashierShiftCash(r.db, shift.ID)
+		if err != nil {
+			return nil, err
+		}
+		shift.CashReceived = cashReceived
+		shift.TotalTransaction = totalTransaction
+		shift.ExpectedCash = shift.OpeningFloat + cashReceived
+	}
+	return &shift, nil
+}
+
+func (r *cashierShiftRepository) GetAllCashierShift(page int, limit int, schoolID uint, userID uint, status string, startDate, endDate time.Time) ([]response.CashierShiftData, int64, error) {
+	var shifts []response.CashierShiftData
+	var total int64
+
+	query := r.db.Table("cashier_shifts cs").
+		Select("cs.*, u.username").
+		Joins("left join users u on u.id = cs.user_id").
+		Where("cs.school_id = ? AND cs.deleted_at IS NULL", schoolID)
+
+	if userID != 0 {
+		query = query.Where("cs.user_id = ?", userID)
+	}
+	if status != "" {
+		query = query.Where("cs.status = ?", status)
+	}
+	if !startDate.IsZero() {
+		query = query.Where("cs.opened_at >= ?", startDate)
+	}
+	if !endDate.IsZero() {
+		query = query.Where("cs.opened_at < ?", endDate.AddDate(0, 0, 1))
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if limit != 0 {
+		query = query.Offset((page - 1) * limit).Limit(limit)
+	}
+
+	err := query.Order("cs.opened_at DESC").Scan(&shifts).Error
+	return shifts, total, err
+}
+
+// GetCashierShiftTransactions lists the kasir payments taken during the shift with the cash
+// each one left in the drawer. Voided payments were handed back and are left out.
+func (r *cashierShiftRepository) GetCashierShiftTransactions(shiftID uint) ([]response.CashierShiftTransaction, error) {
+	var transactions []response.CashierShiftTransaction
+	query := `
+		select tb.id, tb.invoice_number, tb.created_at, s.full_name as student_name, s.nis,
+		tb.total_amount, coalesce(tbd.change_amount, 0) as change_amount,
+		tb.total_amount - coalesce(tbd.change_amount, 0) as cash_amount, tb.transaction_status
+		from transaction_billings tb
+		left join transaction_billing_details tbd on tbd.transaction_billing_id = tb.id
+		left join students s on s.id = tb.student_id
+		where tb.cashier_shift_id = ? and tb.deleted_at is null and tb.transaction_status != ?
+		order by tb.created_at
+	`
+	err := r.db.Raw(query, shiftID, TransactionStatusVoid).Scan(&transactions).Error
+	return transactions, err
+}
+
+// CloseCashierShift books the counted cash against what the drawer should hold. The shift row is
+// locked first so a payment being taken at the same moment either lands before the totals are
+// summed or is rejected because the shift is closed.
+func (r *cashierShiftRepository) CloseCashierShift(id uint, schoolID uint, countedCash int64, note string, userID int) (*models.CashierShift, error) {
+	var shift models.CashierShift
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND school_id = ? AND deleted_at IS NULL", id, schoolID).
+			First(&shift).Error
+		if err != nil {
+			return err
+		}
+		if shift.Status != CashierShiftStatusOpen {
+			return ErrCashierShiftClosed
+		}
+
+		cashReceived, totalTransaction, err := sumCashierShiftCash(tx, shift.ID)
+		if err != nil {
+			return err
+		}
+
+		closedAt := time.Now()
+		variance := countedCash - (shift.OpeningFloat + cashReceived)
+		shift.Status = CashierShiftStatusClosed
+		shift.ClosedAt = &closedAt
+		shift.CashReceived = cashReceived
+		shift.TotalTransaction = totalTransaction
+		shift.ExpectedCash = shift.OpeningFloat + cashReceived
+		shift.CountedCash = &countedCash
+		shift.Variance = &variance
+		shift.Note = note
+		shift.UpdatedBy = userID
+		return tx.Save(&shift).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &shift, nil
+}
+
+// LockOpenCashierShift returns the open shift of the cashier and keeps it locked until the
+// transaction ends, so the shift cannot be closed while a payment is being recorded on it.
+func LockOpenCashierShift(tx *gorm.DB, userID uint) (*models.CashierShift, error) {
+	var shift models.CashierShift
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND status = ? AND deleted_at IS NULL", userID, CashierShiftStatusOpen).
+		First(&shift).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCashierShiftNotOpen
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &shift, nil
+}
+
+// sumCashierShiftCash returns the cash left in the drawer by the shift's payments, which is the
+// amount handed over minus the change given back, and the number of payments.
+func sumCashierShiftCash(db *gorm.DB, shiftID uint) (int64, int, error) {
+	var result struct {
+		CashReceived     int64
+		TotalTransaction int
+	}
+	err := db.Table("transaction_billings tb").
+		Select("coalesce(sum(tb.total_amount - coalesce(tbd.change_amount, 0)), 0) as cash_received, count(tb.id) as total_transaction").
+		Joins("left join transaction_billing_details tbd on tbd.transaction_billing_id = tb.id").
+		Where("tb.cashier_shift_id = ? AND tb.deleted_at IS NULL AND tb.transaction_status != ?", shiftID, TransactionStatusVoid).
+		Scan(&result).Error
+	return result.CashReceived, result.TotalTransaction, err
+}
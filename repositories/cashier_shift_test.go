@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloseCashierShift(t *testing.T) {
+	gormDB, mock := setupTestDB(t)
+	repo := NewCashierShiftRepository(gormDB)
+
+	shiftColumns := []string{"id", "school_id", "user_id", "status", "opening_float"}
+
+	t.Run("Books variance against expected cash", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \* FROM "cashier_shifts" WHERE .* FOR UPDATE`).
+			WithArgs(9, 1, 1).
+			WillReturnRows(sqlmock.NewRows(shiftColumns).AddRow(9, 1, 3, CashierShiftStatusOpen, 200000))
+		mock.ExpectQuery(`SELECT coalesce\(sum\(tb.total_amount - coalesce\(tbd.change_amount, 0\)\), 0\)`).
+			WithArgs(9, TransactionStatusVoid).
+			WillReturnRows(sqlmock.NewRows([]string{"cash_received", "total_transaction"}).AddRow(500000, 4))
+		mock.ExpectExec(`UPDATE "cashier_shifts" SET`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		shift, err := repo.CloseCashierShift(9, 1, 690000, "", 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, CashierShiftStatusClosed, shift.Status)
+		assert.Equal(t, int64(700000), shift.ExpectedCash)
+		assert.Equal(t, int64(-10000), *shift.Variance)
+		assert.Equal(t, 4, shift.TotalTransaction)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Rejects closed shift", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \* FROM "cashier_shifts" WHERE .* FOR UPDATE`).
+			WithArgs(9, 1, 1).
+			WillReturnRows(sqlmock.NewRows(shiftColumns).AddRow(9, 1, 3, CashierShiftStatusClosed, 200000))
+		mock.ExpectRollback()
+
+		_, err := repo.CloseCashierShift(9, 1, 690000, "", 3)
+
+		assert.Equal(t, ErrCashierShiftClosed, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
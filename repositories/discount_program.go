@@ -0,0 +1,293 @@
+package repositories
+
+import (
+	"time"
+
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DiscountTypePercentage = "percentage"
+	DiscountTypeFixed      = "fixed"
+
+	StudentDiscountStatusWaitingApproval = "SD01"
+	StudentDiscountStatusApproved        = "SD02"
+	StudentDiscountStatusRejected        = "SD03"
+)
+
+type DiscountProgramRepository interface {
+	CreateDiscountProgram(program *models.DiscountProgram) (*models.DiscountProgram, error)
+	UpdateDiscountProgram(program *models.DiscountProgram) error
+	GetDiscountProgramByID(id uint, schoolID uint) (*models.DiscountProgram, error)
+	GetAllDiscountProgram(page int, limit int, search string, schoolID uint) ([]models.DiscountProgram, int64, error)
+	BillingTypeBelongsToSchool(billingTypeID uint, schoolID uint) (bool, error)
+	SchoolYearExists(schoolYearID uint) (bool, error)
+	StudentBelongsToSchool(studentID uint, schoolID uint) (bool, error)
+	CreateStudentDiscount(studentDiscount *models.StudentDiscount) (*models.StudentDiscount, error)
+	UpdateStudentDiscount(studentDiscount *models.StudentDiscount) error
+	GetStudentDiscountByID(id uint, schoolID uint) (*models.StudentDiscount, error)
+	GetAllStudentDiscount(page int, limit int, studentID uint, discountStatus string, schoolID uint) ([]models.StudentDiscount, int64, error)
+	GetApprovedStudentDiscounts(studentIDs []uint) ([]response.ApprovedStudentDiscount, error)
+	CreateSiblingDiscountTier(tier *models.SiblingDiscountTier) (*models.SiblingDiscountTier, error)
+	UpdateSiblingDiscountTier(tier *models.SiblingDiscountTier) error
+	GetSiblingDiscountTierByID(id uint, schoolID uint) (*models.SiblingDiscountTier, error)
+	GetAllSiblingDiscountTier(page int, limit int, schoolID uint) ([]models.SiblingDiscountTier, int64, error)
+	SiblingDiscountTierExists(childOrder int, schoolID uint, excludeID uint) (bool, error)
+	GetActiveSiblingDiscountTiers(schoolID uint) ([]models.SiblingDiscountTier, error)
+	GetHouseholdLinks(schoolID uint) ([]response.HouseholdLink, error)
+	GetOpenSiblingDiscountInstallments(schoolID uint) ([]models.BillingStudent, error)
+	UpdateInstallmentSiblingDiscount(billingStudent *models.BillingStudent) (bool, error)
+}
+
+type discountProgramRepository struct {
+	db *gorm.DB
+}
+
+func NewDiscountProgramRepository(db *gorm.DB) DiscountProgramRepository {
+	return &discountProgramRepository{db: db}
+}
+
+func (r *discountProgramRepository) CreateDiscountProgram(program *models.DiscountProgram) (*models.DiscountProgram, error) {
+	if err := r.db.Create(program).Error; err != nil {
+		return nil, err
+	}
+	return program, nil
+}
+
+func (r *discountProgramRepository) UpdateDiscountProgram(program *models.DiscountProgram) error {
+	return r.db.Save(program).Error
+}
+
+func (r *discountProgramRepository) GetDiscountProgramByID(id uint, schoolID uint) (*models.DiscountProgram, error) {
+	var program models.DiscountProgram
+	err := r.db.Where("id = ? AND school_id = ? AND deleted_at IS NULL", id, schoolID).First(&program).Error
+	if err != nil {
+		return nil, err
+	}
+	return &program, nil
+}
+
+func (r *discountProgramRepository) GetAllDiscountProgram(page int, limit int, search string, schoolID uint) ([]models.DiscountProgram, int64, error) {
+	var programs []models.DiscountProgram
+	var total int64
+
+	query := r.db.Model(&models.DiscountProgram{}).Where("school_id = ? AND deleted_at IS NULL", schoolID)
+	if search != "" {
+		query = query.Where("LOWER(program_name) LIKE LOWER(?)", "%"+search+"%")
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if limit != 0 {
+		query = query.Offset((page - 1) * limit).Limit(limit)
+	}
+
+	err := query.Order("id DESC").Find(&programs).Error
+	return programs, total, err
+}
+
+func (r *discountProgramRepository) BillingTypeBelongsToSchool(billingTypeID uint, schoolID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.BillingType{}).
+		Where("id = ? AND school_id = ? AND deleted_at IS NULL", billingTypeID, schoolID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *discountProgramRepository) SchoolYearExists(schoolYearID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.SchoolYear{}).
+		Where("id = ? AND deleted_at IS NULL", schoolYearID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *discountProgramRepository) StudentBelongsToSchool(studentID uint, schoolID uint) (bool, error) {
+	var count int64
+	err := r.db.Table("students s").
+		Joins("JOIN school_classes sc ON sc.id = s.school_class_id").
+		Where("s.id = ? AND sc.school_id = ? AND s.deleted_at IS NULL", studentID, schoolID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *discountProgramRepository) CreateStudentDiscount(studentDiscount *models.StudentDiscount) (*models.StudentDiscount, error) {
+	if err := r.db.Omit("DiscountProgram").Create(studentDiscount).Error; err != nil {
+		return nil, err
+	}
+	return studentDiscount, nil
+}
+
+func (r *discountProgramRepository) UpdateStudentDiscount(studentDiscount *models.StudentDiscount) error {
+	return r.db.Omit("DiscountProgram").Save(studentDiscount).Error
+}
+
+func (r *discountProgramRepository) GetStudentDiscountByID(id uint, schoolID uint) (*models.StudentDiscount, error) {
+	var studentDiscount models.StudentDiscount
+	err := r.db.Preload("DiscountProgram").
+		Where("id = ? AND school_id = ? AND deleted_at IS NULL", id, schoolID).
+		First(&studentDiscount).Error
+	if err != nil {
+		return nil, err
+	}
+	return &studentDiscount, nil
+}
+
+func (r *discountProgramRepository) GetAllStudentDiscount(page int, limit int, studentID uint, discountStatus string, schoolID uint) ([]models.StudentDiscount, int64, error) {
+	var studentDiscounts []models.StudentDiscount
+	var total int64
+
+	query := r.db.Model(&models.StudentDiscount{}).Where("school_id = ? AND deleted_at IS NULL", schoolID)
+	if studentID != 0 {
+		query = query.Where("student_id = ?", studentID)
+	}
+	if discountStatus != "" {
+		query = query.Where("discount_status = ?", discountStatus)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if limit != 0 {
+		query = query.Offset((page - 1) * limit).Limit(limit)
+	}
+
+	err := query.Preload("DiscountProgram").Order("id DESC").Find(&studentDiscounts).Error
+	return studentDiscounts, total, err
+}
+
+// GetApprovedStudentDiscounts returns the approved assignments of active programs for the students.
+func (r *discountProgramRepository) GetApprovedStudentDiscounts(studentIDs []uint) ([]response.ApprovedStudentDiscount, error) {
+	var discounts []response.ApprovedStudentDiscount
+	if len(studentIDs) == 0 {
+		return discounts, nil
+	}
+
+	err := r.db.Table("student_discounts sd").
+		Select("sd.id AS student_discount_id, sd.student_id, sd.valid_from, sd.valid_until, dp.discount_type, dp.amount, dp.billing_type_id, dp.school_year_id").
+		Joins("JOIN discount_programs dp ON dp.id = sd.discount_program_id").
+		Where("sd.student_id IN ? AND sd.discount_status = ? AND sd.deleted_at IS NULL", studentIDs, StudentDiscountStatusApproved).
+		Where("dp.is_active = ? AND dp.deleted_at IS NULL", true).
+		Order("sd.id").
+		Scan(&discounts).Error
+	return discounts, err
+}
+
+func (r *discountProgramRepository) CreateSiblingDiscountTier(tier *models.SiblingDiscountTier) (*models.SiblingDiscountTier, error) {
+	if err := r.db.Create(tier).Error; err != nil {
+		return nil, err
+	}
+	return tier, nil
+}
+
+func (r *discountProgramRepository) UpdateSiblingDiscountTier(tier *models.SiblingDiscountTier) error {
+	return r.db.Save(tier).Error
+}
+
+func (r *discountProgramRepository) GetSiblingDiscountTierByID(id uint, schoolID uint) (*models.SiblingDiscountTier, error) {
+	var tier models.SiblingDiscountTier
+	err := r.db.Where("id = ? AND school_id = ? AND deleted_at IS NULL", id, schoolID).First(&tier).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tier, nil
+}
+
+func (r *discountProgramRepository) GetAllSiblingDiscountTier(page int, limit int, schoolID uint) ([]models.SiblingDiscountTier, int64, error) {
+	var tiers []models.SiblingDiscountTier
+	var total int64
+
+	query := r.db.Model(&models.SiblingDiscountTier{}).Where("school_id = ? AND deleted_at IS NULL", schoolID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if limit != 0 {
+		query = query.Offset((page - 1) * limit).Limit(limit)
+	}
+
+	err := query.Order("child_order").Find(&tiers).Error
+	return tiers, total, err
+}
+
+func (r *discountProgramRepository) SiblingDiscountTierExists(childOrder int, schoolID uint, excludeID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.SiblingDiscountTier{}).
+		Where("child_order = ? AND school_id = ? AND id <> ? AND deleted_at IS NULL", childOrder, schoolID, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *discountProgramRepository) GetActiveSiblingDiscountTiers(schoolID uint) ([]models.SiblingDiscountTier, error) {
+	var tiers []models.SiblingDiscountTier
+	err := r.db.Where("school_id = ? AND is_active = ? AND deleted_at IS NULL", schoolID, true).
+		Order("child_order").
+		Find(&tiers).Error
+	return tiers, err
+}
+
+// GetHouseholdLinks lists what the active students of the school share with their siblings:
+// the parent account in user_students and that parent's email and phone in student_parents.
+func (r *discountProgramRepository) GetHouseholdLinks(schoolID uint) ([]response.HouseholdLink, error) {
+	var links []response.HouseholdLink
+
+	activeStudents := `
+		FROM students s
+		JOIN school_classes sc ON sc.id = s.school_class_id
+		JOIN user_students us ON us.student_id = s.id AND us.deleted_at IS NULL`
+	activeFilter := ` WHERE sc.school_id = ? AND s.deleted_at IS NULL AND LOWER(s.status) = 'aktif'`
+
+	query := `SELECT s.id AS student_id, s.birth_date, 'u:' || us.user_id AS household_key` + activeStudents + activeFilter + `
+		UNION ALL
+		SELECT s.id AS student_id, s.birth_date, 'm:' || LOWER(TRIM(sp.parent_mail)) AS household_key` + activeStudents + `
+		JOIN student_parents sp ON sp.user_id = us.user_id AND sp.deleted_at IS NULL` + activeFilter + `
+		AND TRIM(COALESCE(sp.parent_mail, '')) <> ''
+		UNION ALL
+		SELECT s.id AS student_id, s.birth_date, 'p:' || sp.parent_handphone AS household_key` + activeStudents + `
+		JOIN student_parents sp ON sp.user_id = us.user_id AND sp.deleted_at IS NULL` + activeFilter + `
+		AND COALESCE(sp.parent_handphone, 0) <> 0`
+
+	err := r.db.Raw(query, schoolID, schoolID, schoolID).Scan(&links).Error
+	return links, err
+}
+
+// GetOpenSiblingDiscountInstallments returns the installments of active students whose amount
+// can still change: unpaid or paid only by discounts, not held by a checkout, not a penalty and
+// not a donation.
+func (r *discountProgramRepository) GetOpenSiblingDiscountInstallments(schoolID uint) ([]models.BillingStudent, error) {
+	var installments []models.BillingStudent
+	err := r.db.Table("billing_students bs").
+		Select("bs.*").
+		Joins("JOIN billings b ON b.id = bs.billing_id").
+		Joins("JOIN students s ON s.id = bs.student_id").
+		Joins("JOIN school_classes sc ON sc.id = s.school_class_id").
+		Where("sc.school_id = ? AND s.deleted_at IS NULL AND LOWER(s.status) = 'aktif'", schoolID).
+		Where("bs.deleted_at IS NULL AND bs.paid_amount = 0 AND bs.late_fee_for_id IS NULL").
+		Where("bs.payment_status = ? OR (bs.payment_status = ? AND bs.amount = 0)", BillingStudentStatusUnpaid, BillingStudentStatusPaid).
+		Where("bs.reserved_order_id IS NULL OR bs.reserved_until < ?", time.Now()).
+		Where("b.is_donation = ?", false).
+		Order("bs.id").
+		Find(&installments).Error
+	return installments, err
+}
+
+// UpdateInstallmentSiblingDiscount stores the recalculated amounts and payment status unless the
+// installment was paid or reserved in the meantime, reporting whether it was updated.
+func (r *discountProgramRepository) UpdateInstallmentSiblingDiscount(billingStudent *models.BillingStudent) (bool, error) {
+	result := r.db.Model(&models.BillingStudent{}).
+		Where("id = ? AND paid_amount = 0", billingStudent.ID).
+		Where("payment_status = ? OR (payment_status = ? AND amount = 0)", BillingStudentStatusUnpaid, BillingStudentStatusPaid).
+		Where("reserved_order_id IS NULL OR reserved_until < ?", time.Now()).
+		Updates(map[string]interface{}{
+			"amount":                  billingStudent.Amount,
+			"discount_amount":         billingStudent.DiscountAmount,
+			"sibling_discount_amount": billingStudent.SiblingDiscountAmount,
+			"payment_status":          billingStudent.PaymentStatus,
+			"updated_at":              time.Now(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
@@ -10,6 +10,7 @@ type InvoiceFormatRepositoryInterface interface {
 	GetBySchoolID(schoolID uint) (*models.InvoiceFormat, error)
 	Update(invoiceFormat *models.InvoiceFormat) error
 	Create(invoiceFormat *models.InvoiceFormat) error
+	PreviewInvoiceNumbers(invoiceFormat *models.InvoiceFormat, count int) ([]string, error)
 }
 
 type InvoiceFormatRepository struct {
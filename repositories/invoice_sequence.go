@@ -0,0 +1,236 @@
+package repositories
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"schoolPayment/constants"
+	"schoolPayment/models"
+
+	"gorm.io/gorm"
+)
+
+// NextInvoiceNumber takes the next invoice number of the school from its sequence row. It must
+// run in the database transaction that creates the TransactionBilling: the sequence row stays
+// locked until that transaction ends, so concurrent payments never get the same number and a
+// rolled back payment does not burn one.
+func NextInvoiceNumber(tx *gorm.DB, schoolID uint, studentID uint) (string, error) {
+	invoiceFormat, err := NewInvoiceFormatRepository(tx).GetBySchoolID(schoolID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get invoice format: %v", err)
+	}
+
+	now := time.Now()
+	if invoiceFormat.Template != "" {
+		values, err := getInvoiceTemplateValues(tx, invoiceFormat, studentID, now)
+		if err != nil {
+			return "", err
+		}
+
+		// Templates start counting from zero, the fixed formats' history does not apply to them
+		period := InvoiceResetPeriodKey(invoiceFormat.ResetPeriod, now)
+		sequence, err := nextInvoiceSequence(tx, schoolID, invoiceFormat.Prefix, period, func() (int64, error) {
+			return 0, nil
+		})
+		if err != nil {
+			return "", err
+		}
+		return RenderInvoiceTemplate(invoiceFormat.Template, values, sequence), nil
+	}
+
+	datePart, err := InvoiceNumberDatePart(invoiceFormat.Format, now)
+	if err != nil {
+		return "", err
+	}
+
+	sequence, err := nextInvoiceSequence(tx, schoolID, invoiceFormat.Prefix, datePart, func() (int64, error) {
+		return getLastInvoiceSequence(tx, schoolID, invoiceFormat.Prefix, invoiceFormat.Format, datePart, now)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return FormatInvoiceNumber(invoiceFormat.Prefix, invoiceFormat.Format, datePart, sequence), nil
+}
+
+// PreviewInvoiceNumbers returns the next count numbers the format would issue today without
+// taking them. {GRADE} depends on the paying student and is left as is.
+func (r *InvoiceFormatRepository) PreviewInvoiceNumbers(invoiceFormat *models.InvoiceFormat, count int) ([]string, error) {
+	now := time.Now()
+	numbers := make([]string, 0, count)
+
+	if invoiceFormat.Template != "" {
+		values, err := getInvoiceTemplateValues(r.db, invoiceFormat, 0, now)
+		if err != nil {
+			return nil, err
+		}
+		values.GradeCode = "{GRADE}"
+
+		lastValue, _, err := getInvoiceSequenceLastValue(r.db, invoiceFormat.SchoolID, invoiceFormat.Prefix, InvoiceResetPeriodKey(invoiceFormat.ResetPeriod, now))
+		if err != nil {
+			return nil, err
+		}
+		for i := int64(1); i <= int64(count); i++ {
+			numbers = append(numbers, RenderInvoiceTemplate(invoiceFormat.Template, values, lastValue+i))
+		}
+		return numbers, nil
+	}
+
+	datePart, err := InvoiceNumberDatePart(invoiceFormat.Format, now)
+	if err != nil {
+		return nil, err
+	}
+
+	lastValue, found, err := getInvoiceSequenceLastValue(r.db, invoiceFormat.SchoolID, invoiceFormat.Prefix, datePart)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		lastValue, err = getLastInvoiceSequence(r.db, invoiceFormat.SchoolID, invoiceFormat.Prefix, invoiceFormat.Format, datePart, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i := int64(1); i <= int64(count); i++ {
+		numbers = append(numbers, FormatInvoiceNumber(invoiceFormat.Prefix, invoiceFormat.Format, datePart, lastValue+i))
+	}
+	return numbers, nil
+}
+
+// InvoiceNumberDatePart returns the date part of an invoice number. It is also the period the
+// sequence counts in, so the number starts again at 1 when the date part changes.
+func InvoiceNumberDatePart(format string, date time.Time) (string, error) {
+	switch format {
+	case "DDMMYY00001":
+		return date.Format("020106"), nil
+	case "MMYY00001":
+		return date.Format("0106"), nil
+	case "YY00001":
+		return date.Format("06"), nil
+	case "00001", "1":
+		return "", nil
+	}
+	return "", fmt.Errorf("unsupported invoice format: %s", format)
+}
+
+func FormatInvoiceNumber(prefix, format, datePart string, sequence int64) string {
+	sequenceFormat := "%05d"
+	if format == "1" {
+		sequenceFormat = "%d"
+	}
+	return fmt.Sprintf("%s%s"+sequenceFormat, prefix, datePart, sequence)
+}
+
+// nextInvoiceSequence increments the sequence row of the period. The first number of a period
+// inserts the row, starting after seed; a concurrent insert of the same row falls through to
+// the conflict clause and increments the winner's row instead.
+func nextInvoiceSequence(tx *gorm.DB, schoolID uint, prefix, period string, seed func() (int64, error)) (int64, error) {
+	now := time.Now()
+
+	var values []int64
+	err := tx.Raw(`UPDATE invoice_sequences SET last_value = last_value + 1, updated_at = ?
+		WHERE school_id = ? AND prefix = ? AND period = ? AND deleted_at IS NULL
+		RETURNING last_value`, now, schoolID, prefix, period).Scan(&values).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(values) > 0 {
+		return values[0], nil
+	}
+
+	lastValue, err := seed()
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Raw(`INSERT INTO invoice_sequences (school_id, prefix, period, last_value, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (school_id, prefix, period) DO UPDATE SET last_value = invoice_sequences.last_value + 1, updated_at = EXCLUDED.updated_at
+		RETURNING last_value`, schoolID, prefix, period, lastValue+1, now, now).Scan(&values).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(values) == 0 {
+		return 0, fmt.Errorf("failed to take invoice sequence for school ID: %d", schoolID)
+	}
+	return values[0], nil
+}
+
+func getInvoiceSequenceLastValue(db *gorm.DB, schoolID uint, prefix, period string) (int64, bool, error) {
+	var values []int64
+	err := db.Model(&models.InvoiceSequence{}).
+		Where("school_id = ? AND prefix = ? AND period = ? AND deleted_at IS NULL", schoolID, prefix, period).
+		Pluck("last_value", &values).Error
+	if err != nil || len(values) == 0 {
+		return 0, false, err
+	}
+	return values[0], true, nil
+}
+
+// getInvoiceTemplateValues loads the school code and the student's grade code when the template uses them.
+func getInvoiceTemplateValues(db *gorm.DB, invoiceFormat *models.InvoiceFormat, studentID uint, date time.Time) (InvoiceTemplateValues, error) {
+	values := InvoiceTemplateValues{Prefix: invoiceFormat.Prefix, Date: date}
+
+	if invoiceTemplateUses(invoiceFormat.Template, "SCHOOL_CODE") {
+		var codes []string
+		err := db.Model(&models.School{}).Where("id = ?", invoiceFormat.SchoolID).Pluck("school_code", &codes).Error
+		if err != nil {
+			return values, err
+		}
+		if len(codes) > 0 {
+			values.SchoolCode = codes[0]
+		}
+	}
+
+	if invoiceTemplateUses(invoiceFormat.Template, "GRADE") && studentID != 0 {
+		var codes []string
+		err := db.Table("students").
+			Joins("JOIN school_grades ON school_grades.id = students.school_grade_id").
+			Where("students.id = ?", studentID).
+			Pluck("school_grades.school_grade_code", &codes).Error
+		if err != nil {
+			return values, err
+		}
+		if len(codes) > 0 {
+			values.GradeCode = codes[0]
+		}
+	}
+
+	return values, nil
+}
+
+// getLastInvoiceSequence reads the sequence of the latest invoice issued in the period, so a
+// period that started before the sequence table existed continues where it left off.
+func getLastInvoiceSequence(tx *gorm.DB, schoolID uint, prefix, format, datePart string, date time.Time) (int64, error) {
+	query := tx.Model(&models.TransactionBilling{}).
+		Joins("JOIN students ON students.id = transaction_billings.student_id").
+		Joins("JOIN user_students ON user_students.student_id = students.id").
+		Joins("JOIN user_schools ON user_schools.user_id = user_students.user_id").
+		Where("transaction_billings.invoice_number LIKE ? AND user_schools.school_id = ?", prefix+datePart+"%", schoolID)
+
+	day := date.Format(constants.DateFormatYYYYMMDD)
+	switch format {
+	case "DDMMYY00001":
+		query = query.Where("DATE(transaction_billings.created_at) = DATE(?)", day)
+	case "MMYY00001":
+		query = query.Where("DATE_TRUNC('month', transaction_billings.created_at) = DATE_TRUNC('month', ?::timestamp)", day)
+	case "YY00001":
+		query = query.Where("DATE_TRUNC('year', transaction_billings.created_at) = DATE_TRUNC('year', ?::timestamp)", day)
+	}
+
+	var invoiceNumbers []string
+	err := query.Order("transaction_billings.created_at DESC").Limit(1).Pluck("transaction_billings.invoice_number", &invoiceNumbers).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(invoiceNumbers) == 0 || invoiceNumbers[0] == "" {
+		return 0, nil
+	}
+
+	lastSequence, err := strconv.ParseInt(invoiceNumbers[0][len(prefix)+len(datePart):], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse last sequence number: %v", err)
+	}
+	return lastSequence, nil
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextInvoiceSequence(t *testing.T) {
+	gormDB, mock := setupTestDB(t)
+
+	t.Run("Increments existing period", func(t *testing.T) {
+		mock.ExpectQuery(`UPDATE invoice_sequences SET last_value = last_value \+ 1`).
+			WithArgs(sqlmock.AnyArg(), 1, "INV", "1024").
+			WillReturnRows(sqlmock.NewRows([]string{"last_value"}).AddRow(8))
+
+		sequence, err := nextInvoiceSequence(gormDB, 1, "INV", "1024", func() (int64, error) {
+			t.Fatal("seed must not be read when the period exists")
+			return 0, nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(8), sequence)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Starts new period after seed", func(t *testing.T) {
+		mock.ExpectQuery(`UPDATE invoice_sequences SET last_value = last_value \+ 1`).
+			WithArgs(sqlmock.AnyArg(), 1, "INV", "1124").
+			WillReturnRows(sqlmock.NewRows([]string{"last_value"}))
+		mock.ExpectQuery(`INSERT INTO invoice_sequences .* ON CONFLICT \(school_id, prefix, period\) DO UPDATE`).
+			WithArgs(1, "INV", "1124", int64(4), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"last_value"}).AddRow(4))
+
+		sequence, err := nextInvoiceSequence(gormDB, 1, "INV", "1124", func() (int64, error) {
+			return 3, nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), sequence)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestFormatInvoiceNumber(t *testing.T) {
+	date := time.Date(2024, time.October, 5, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"DDMMYY00001", "INV05102400012"},
+		{"MMYY00001", "INV102400012"},
+		{"YY00001", "INV2400012"},
+		{"00001", "INV00012"},
+		{"1", "INV12"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			datePart, err := InvoiceNumberDatePart(tt.format, date)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, FormatInvoiceNumber("INV", tt.format, datePart, 12))
+		})
+	}
+
+	_, err := InvoiceNumberDatePart("WEEK00001", date)
+	assert.Error(t, err)
+}
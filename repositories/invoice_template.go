@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	InvoiceResetNever   = "never"
+	InvoiceResetYearly  = "yearly"
+	InvoiceResetMonthly = "monthly"
+	InvoiceResetDaily   = "daily"
+
+	maxInvoiceSequenceWidth = 12
+)
+
+var invoiceTemplateToken = regexp.MustCompile(`\{([A-Z_]+)(?::(\d+))?\}`)
+
+// InvoiceTemplateValues are the values substituted into an invoice template besides the sequence.
+type InvoiceTemplateValues struct {
+	Prefix     string
+	SchoolCode string
+	GradeCode  string
+	Date       time.Time
+}
+
+// ValidateInvoiceTemplate checks that a template only uses known tokens, has exactly one
+// {SEQ:n} and carries the date tokens of its reset period. Without them a number issued after
+// the reset would repeat one issued before it.
+func ValidateInvoiceTemplate(template string, resetPeriod string) error {
+	seqCount := 0
+	tokens := map[string]bool{}
+	for _, match := range invoiceTemplateToken.FindAllStringSubmatch(template, -1) {
+		name, width := match[1], match[2]
+		switch name {
+		case "SEQ":
+			seqCount++
+			if width != "" {
+				n, _ := strconv.Atoi(width)
+				if n < 1 || n > maxInvoiceSequenceWidth {
+					return fmt.Errorf("sequence width must be between 1 and %d", maxInvoiceSequenceWidth)
+				}
+			}
+		case "PREFIX", "SCHOOL_CODE", "YYYY", "YY", "MM", "DD", "GRADE":
+			if width != "" {
+				return fmt.Errorf("token {%s} does not take a width", name)
+			}
+		default:
+			return fmt.Errorf("unknown invoice template token: {%s}", name)
+		}
+		tokens[name] = true
+	}
+	if seqCount != 1 {
+		return fmt.Errorf("invoice template must contain exactly one {SEQ:n} token")
+	}
+
+	hasYear := tokens["YYYY"] || tokens["YY"]
+	switch resetPeriod {
+	case "", InvoiceResetNever:
+	case InvoiceResetYearly:
+		if !hasYear {
+			return fmt.Errorf("a yearly reset needs {YYYY} or {YY} in the template")
+		}
+	case InvoiceResetMonthly:
+		if !hasYear || !tokens["MM"] {
+			return fmt.Errorf("a monthly reset needs a year and {MM} in the template")
+		}
+	case InvoiceResetDaily:
+		if !hasYear || !tokens["MM"] || !tokens["DD"] {
+			return fmt.Errorf("a daily reset needs a year, {MM} and {DD} in the template")
+		}
+	default:
+		return fmt.Errorf("unsupported reset period: %s", resetPeriod)
+	}
+	return nil
+}
+
+// InvoiceResetPeriodKey returns the sequence period of a template for a date. The keys start with
+// a letter so they never share a sequence row with the date parts of the fixed formats.
+func InvoiceResetPeriodKey(resetPeriod string, date time.Time) string {
+	switch resetPeriod {
+	case InvoiceResetYearly:
+		return "Y" + date.Format("2006")
+	case InvoiceResetMonthly:
+		return "M" + date.Format("200601")
+	case InvoiceResetDaily:
+		return "D" + date.Format("20060102")
+	}
+	return "ALL"
+}
+
+// RenderInvoiceTemplate substitutes the tokens of a validated template.
+func RenderInvoiceTemplate(template string, values InvoiceTemplateValues, sequence int64) string {
+	return invoiceTemplateToken.ReplaceAllStringFunc(template, func(token string) string {
+		match := invoiceTemplateToken.FindStringSubmatch(token)
+		switch match[1] {
+		case "PREFIX":
+			return values.Prefix
+		case "SCHOOL_CODE":
+			return values.SchoolCode
+		case "GRADE":
+			return values.GradeCode
+		case "YYYY":
+			return values.Date.Format("2006")
+		case "YY":
+			return values.Date.Format("06")
+		case "MM":
+			return values.Date.Format("01")
+		case "DD":
+			return values.Date.Format("02")
+		case "SEQ":
+			if match[2] == "" {
+				return strconv.FormatInt(sequence, 10)
+			}
+			return fmt.Sprintf("%0"+match[2]+"d", sequence)
+		}
+		return token
+	})
+}
+
+func invoiceTemplateUses(template string, name string) bool {
+	return strings.Contains(template, "{"+name+"}")
+}
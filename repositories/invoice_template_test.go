@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderInvoiceTemplate(t *testing.T) {
+	values := InvoiceTemplateValues{
+		Prefix:     "INV",
+		SchoolCode: "SMP-01",
+		GradeCode:  "VII",
+		Date:       time.Date(2025, time.October, 7, 9, 0, 0, 0, time.UTC),
+	}
+
+	assert.Equal(t, "INV/SMP-01/2025/10/000123", RenderInvoiceTemplate("{PREFIX}/{SCHOOL_CODE}/{YYYY}/{MM}/{SEQ:6}", values, 123))
+	assert.Equal(t, "VII-251007-42", RenderInvoiceTemplate("{GRADE}-{YY}{MM}{DD}-{SEQ}", values, 42))
+}
+
+func TestValidateInvoiceTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		template    string
+		resetPeriod string
+		expectedErr string
+	}{
+		{"Yearly with year", "{PREFIX}/{YYYY}/{SEQ:5}", InvoiceResetYearly, ""},
+		{"Daily with full date", "{YY}{MM}{DD}{SEQ:4}", InvoiceResetDaily, ""},
+		{"Never without date", "{SCHOOL_CODE}-{SEQ}", InvoiceResetNever, ""},
+		{"Missing sequence", "{PREFIX}/{YYYY}", InvoiceResetNever, "invoice template must contain exactly one {SEQ:n} token"},
+		{"Two sequences", "{SEQ:3}-{SEQ:3}", InvoiceResetNever, "invoice template must contain exactly one {SEQ:n} token"},
+		{"Sequence too wide", "{SEQ:20}", InvoiceResetNever, "sequence width must be between 1 and 12"},
+		{"Unknown token", "{PREFIX}{WEEK}{SEQ:3}", InvoiceResetNever, "unknown invoice template token: {WEEK}"},
+		{"Daily without day", "{YYYY}{MM}{SEQ:3}", InvoiceResetDaily, "a daily reset needs a year, {MM} and {DD} in the template"},
+		{"Unknown reset", "{SEQ:3}", "weekly", "unsupported reset period: weekly"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateInvoiceTemplate(tt.template, tt.resetPeriod)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestInvoiceResetPeriodKey(t *testing.T) {
+	date := time.Date(2025, time.October, 7, 9, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "ALL", InvoiceResetPeriodKey(InvoiceResetNever, date))
+	assert.Equal(t, "Y2025", InvoiceResetPeriodKey(InvoiceResetYearly, date))
+	assert.Equal(t, "M202510", InvoiceResetPeriodKey(InvoiceResetMonthly, date))
+	assert.Equal(t, "D20251007", InvoiceResetPeriodKey(InvoiceResetDaily, date))
+}
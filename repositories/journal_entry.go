@@ -0,0 +1,674 @@
+package repositories
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"schoolPayment/dtos/response"
+	"schoolPayment/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	LedgerAccountCash            = "1101"
+	LedgerAccountBank            = "1102"
+	LedgerAccountGateway         = "1103"
+	LedgerAccountReceivable      = "1201"
+	LedgerAccountStudentCredit   = "2101"
+	LedgerAccountRevenue         = "4101"
+	LedgerAccountLateFeeRevenue  = "4102"
+	LedgerAccountAdminFeeRevenue = "4103"
+	LedgerAccountDiscount        = "4901"
+	LedgerAccountGatewayFee      = "5101"
+
+	LedgerAccountTypeAsset     = "asset"
+	LedgerAccountTypeLiability = "liability"
+	LedgerAccountTypeRevenue   = "revenue"
+	LedgerAccountTypeExpense   = "expense"
+
+	NormalBalanceDebit  = "debit"
+	NormalBalanceCredit = "credit"
+
+	JournalSourceBillingStudent = "billing_student"
+	JournalSourceTransaction    = "transaction_billing"
+	JournalSourceRefund         = "transaction_refund"
+	JournalSourceVoid           = "transaction_void"
+)
+
+// defaultLedgerAccounts is the chart of accounts every school gets on its first posting.
+// Discounts are a contra revenue account, so its normal balance is debit.
+var defaultLedgerAccounts = []models.LedgerAccount{
+	{Code: LedgerAccountCash, Name: "Kas", AccountType: LedgerAccountTypeAsset, NormalBalance: NormalBalanceDebit},
+	{Code: LedgerAccountBank, Name: "Bank", AccountType: LedgerAccountTypeAsset, NormalBalance: NormalBalanceDebit},
+	{Code: LedgerAccountGateway, Name: "Piutang Payment Gateway", AccountType: LedgerAccountTypeAsset, NormalBalance: NormalBalanceDebit},
+	{Code: LedgerAccountReceivable, Name: "Piutang Siswa", AccountType: LedgerAccountTypeAsset, NormalBalance: NormalBalanceDebit},
+	{Code: LedgerAccountStudentCredit, Name: "Titipan Siswa", AccountType: LedgerAccountTypeLiability, NormalBalance: NormalBalanceCredit},
+	{Code: LedgerAccountRevenue, Name: "Pendapatan Tagihan Sekolah", AccountType: LedgerAccountTypeRevenue, NormalBalance: NormalBalanceCredit},
+	{Code: LedgerAccountLateFeeRevenue, Name: "Pendapatan Denda Keterlambatan", AccountType: LedgerAccountTypeRevenue, NormalBalance: NormalBalanceCredit},
+	{Code: LedgerAccountAdminFeeRevenue, Name: "Pendapatan Biaya Admin", AccountType: LedgerAccountTypeRevenue, NormalBalance: NormalBalanceCredit},
+	{Code: LedgerAccountDiscount, Name: "Potongan dan Beasiswa", AccountType: LedgerAccountTypeRevenue, NormalBalance: NormalBalanceDebit},
+	{Code: LedgerAccountGatewayFee, Name: "Biaya Admin Payment Gateway", AccountType: LedgerAccountTypeExpense, NormalBalance: NormalBalanceDebit},
+}
+
+// DefaultLedgerAccount returns the default account with the code, if there is one.
+func DefaultLedgerAccount(code string) (models.LedgerAccount, bool) {
+	for _, account := range defaultLedgerAccounts {
+		if account.Code == code {
+			return account, true
+		}
+	}
+	return models.LedgerAccount{}, false
+}
+
+// JournalPosting holds the amount of each account of an entry, debit positive and credit negative.
+type JournalPosting map[string]int64
+
+type JournalRepository interface {
+	GetSchoolIDs() ([]uint, error)
+	SyncJournal(schoolID uint) (int, error)
+	GetLedgerAccounts(schoolID uint) ([]models.LedgerAccount, error)
+	GetAllJournalEntry(page int, limit int, sourceType string, startDate time.Time, endDate time.Time, schoolID uint) ([]models.JournalEntry, int64, error)
+	GetJournalEntryByID(id uint, schoolID uint) (*models.JournalEntry, error)
+	GetTrialBalance(schoolID uint, startDate time.Time, endDate time.Time) ([]response.TrialBalanceAccount, error)
+}
+
+type journalRepository struct {
+	db *gorm.DB
+}
+
+func NewJournalRepository(db *gorm.DB) JournalRepository {
+	return &journalRepository{db: db}
+}
+
+func (r *journalRepository) GetSchoolIDs() ([]uint, error) {
+	var schoolIDs []uint
+	err := r.db.Model(&models.School{}).Where("deleted_at IS NULL").Order("id").Pluck("id", &schoolIDs).Error
+	return schoolIDs, err
+}
+
+// SyncJournal posts an entry for every financial event of the school that has none yet and
+// returns how many were posted. Entries are worked out from the records the events leave behind,
+// so running it again posts nothing new:
+//   - installments post their amount as receivable against revenue, discounts included, and
+//     every later change to the amount or discount posts the difference
+//   - paid transactions post the money received against the receivable, with student credit,
+//     kasir discounts and gateway admin fees
+//   - approved refunds reopen the receivable against the money paid back or the student credit
+//   - approved voids reverse the transaction's entry
+func (r *journalRepository) SyncJournal(schoolID uint) (int, error) {
+	var posted int
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Two runs for the same school must not post the same change twice
+		var school models.School
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", schoolID).First(&school).Error; err != nil {
+			return err
+		}
+
+		accountIDs, err := ensureLedgerAccounts(tx, schoolID)
+		if err != nil {
+			return err
+		}
+
+		var entries []models.JournalEntry
+		for _, sync := range []func(*gorm.DB, uint) ([]models.JournalEntry, error){
+			billingStudentJournalEntries,
+			transactionJournalEntries,
+			refundJournalEntries,
+		} {
+			syncEntries, err := sync(tx, schoolID)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, syncEntries...)
+		}
+
+		count, err := createJournalEntries(tx, schoolID, entries, accountIDs)
+		if err != nil {
+			return err
+		}
+		posted += count
+
+		// A void reverses the transaction's entry, which may have been posted just above
+		voidEntries, err := voidJournalEntries(tx, schoolID)
+		if err != nil {
+			return err
+		}
+		count, err = createJournalEntries(tx, schoolID, voidEntries, accountIDs)
+		if err != nil {
+			return err
+		}
+		posted += count
+		return nil
+	})
+	return posted, err
+}
+
+func (r *journalRepository) GetLedgerAccounts(schoolID uint) ([]models.LedgerAccount, error) {
+	var accounts []models.LedgerAccount
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := ensureLedgerAccounts(tx, schoolID); err != nil {
+			return err
+		}
+		return tx.Where("school_id = ? AND deleted_at IS NULL", schoolID).Order("code").Find(&accounts).Error
+	})
+	return accounts, err
+}
+
+func (r *journalRepository) GetAllJournalEntry(page int, limit int, sourceType string, startDate time.Time, endDate time.Time, schoolID uint) ([]models.JournalEntry, int64, error) {
+	var entries []models.JournalEntry
+	var total int64
+
+	query := r.db.Model(&models.JournalEntry{}).Where("school_id = ? AND deleted_at IS NULL", schoolID)
+	if sourceType != "" {
+		query = query.Where("source_type = ?", sourceType)
+	}
+	if !startDate.IsZero() {
+		query = query.Where("entry_date >= ?", startDate)
+	}
+	if !endDate.IsZero() {
+		query = query.Where("entry_date < ?", endDate.AddDate(0, 0, 1))
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	err := query.Preload("Lines.LedgerAccount").
+		Order("entry_date DESC, id DESC").
+		Offset(offset).Limit(limit).
+		Find(&entries).Error
+	return entries, total, err
+}
+
+func (r *journalRepository) GetJournalEntryByID(id uint, schoolID uint) (*models.JournalEntry, error) {
+	var entry models.JournalEntry
+	err := r.db.Preload("Lines.LedgerAccount").
+		Where("id = ? AND school_id = ? AND deleted_at IS NULL", id, schoolID).
+		First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetTrialBalance returns every account of the school with its balance before startDate and
+// what was posted to it from startDate up to and including endDate.
+func (r *journalRepository) GetTrialBalance(schoolID uint, startDate time.Time, endDate time.Time) ([]response.TrialBalanceAccount, error) {
+	var accounts []response.TrialBalanceAccount
+	query := `
+		SELECT la.id AS ledger_account_id, la.code, la.name, la.account_type, la.normal_balance,
+			COALESCE(SUM(CASE WHEN je.entry_date < ? THEN jl.debit - jl.credit ELSE 0 END), 0) AS opening_balance,
+			COALESCE(SUM(CASE WHEN je.entry_date >= ? THEN jl.debit ELSE 0 END), 0) AS debit,
+			COALESCE(SUM(CASE WHEN je.entry_date >= ? THEN jl.credit ELSE 0 END), 0) AS credit
+		FROM ledger_accounts la
+		LEFT JOIN journal_lines jl ON jl.ledger_account_id = la.id AND jl.deleted_at IS NULL
+		LEFT JOIN journal_entries je ON je.id = jl.journal_entry_id AND je.deleted_at IS NULL AND je.entry_date < ?
+		WHERE la.school_id = ? AND la.deleted_at IS NULL
+		GROUP BY la.id, la.code, la.name, la.account_type, la.normal_balance
+		ORDER BY la.code
+	`
+	err := r.db.Raw(query, startDate, startDate, startDate, endDate.AddDate(0, 0, 1), schoolID).Scan(&accounts).Error
+	return accounts, err
+}
+
+// ensureLedgerAccounts creates the default accounts the school does not have yet and returns
+// the id of every account by its code.
+func ensureLedgerAccounts(tx *gorm.DB, schoolID uint) (map[string]uint, error) {
+	var accounts []models.LedgerAccount
+	if err := tx.Where("school_id = ? AND deleted_at IS NULL", schoolID).Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+
+	accountIDs := make(map[string]uint, len(defaultLedgerAccounts))
+	for _, account := range accounts {
+		accountIDs[account.Code] = account.ID
+	}
+
+	var missing []models.LedgerAccount
+	for _, account := range defaultLedgerAccounts {
+		if _, ok := accountIDs[account.Code]; ok {
+			continue
+		}
+		account.SchoolID = schoolID
+		missing = append(missing, account)
+	}
+	if len(missing) > 0 {
+		if err := tx.Create(&missing).Error; err != nil {
+			return nil, err
+		}
+		for _, account := range missing {
+			accountIDs[account.Code] = account.ID
+		}
+	}
+	return accountIDs, nil
+}
+
+// createJournalEntries saves the entries with their lines, leaving out entries with nothing to
+// post, and returns how many were saved.
+func createJournalEntries(tx *gorm.DB, schoolID uint, entries []models.JournalEntry, accountIDs map[string]uint) (int, error) {
+	var count int
+	for i := range entries {
+		entry := &entries[i]
+		if len(entry.Lines) == 0 {
+			continue
+		}
+		for j := range entry.Lines {
+			if entry.Lines[j].LedgerAccountID == 0 {
+				code := entry.Lines[j].LedgerAccount.Code
+				accountID, ok := accountIDs[code]
+				if !ok {
+					return count, fmt.Errorf("ledger account %s not found", code)
+				}
+				entry.Lines[j].LedgerAccountID = accountID
+			}
+			entry.Lines[j].LedgerAccount = nil
+		}
+		entry.SchoolID = schoolID
+		if err := tx.Create(entry).Error; err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// BuildJournalLines turns a posting into balanced lines ordered by account code, debits first.
+// Accounts that net to zero are left out.
+func BuildJournalLines(posting JournalPosting) ([]models.JournalLine, int64, error) {
+	var balance, total int64
+	codes := make([]string, 0, len(posting))
+	for code, amount := range posting {
+		balance += amount
+		if amount != 0 {
+			codes = append(codes, code)
+		}
+	}
+	if balance != 0 {
+		return nil, 0, fmt.Errorf("journal entry is not balanced, debit and credit differ by %d", balance)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if (posting[codes[i]] > 0) != (posting[codes[j]] > 0) {
+			return posting[codes[i]] > 0
+		}
+		return codes[i] < codes[j]
+	})
+
+	lines := make([]models.JournalLine, 0, len(codes))
+	for _, code := range codes {
+		line := models.JournalLine{LedgerAccount: &models.LedgerAccount{Code: code}}
+		if amount := posting[code]; amount > 0 {
+			line.Debit = amount
+			total += amount
+		} else {
+			line.Credit = -amount
+		}
+		lines = append(lines, line)
+	}
+	return lines, total, nil
+}
+
+func newJournalEntry(sourceType string, sourceID uint, entryDate time.Time, description string, posting JournalPosting) (models.JournalEntry, error) {
+	lines, total, err := BuildJournalLines(posting)
+	if err != nil {
+		return models.JournalEntry{}, fmt.Errorf("%s %d: %w", sourceType, sourceID, err)
+	}
+	return models.JournalEntry{
+		EntryDate:   entryDate,
+		SourceType:  sourceType,
+		SourceID:    sourceID,
+		Description: description,
+		TotalAmount: total,
+		Lines:       lines,
+	}, nil
+}
+
+// BillingStudentJournalSource is an installment whose amount or discount differs from what
+// was posted for it. Amount and DiscountAmount are zero for a deleted installment.
+type BillingStudentJournalSource struct {
+	ID                uint
+	DetailBillingName string
+	BillingTypeID     *uint
+	Amount            int64
+	DiscountAmount    int64
+	IsLateFee         bool
+	IsDeleted         bool
+	CreatedAt         time.Time
+	ChangedAt         time.Time
+	PostedReceivable  int64
+	PostedDiscount    int64
+	PostedEntries     int
+}
+
+// BillingStudentJournalPosting books the difference between the installment and what was
+// posted for it: the amount due as receivable, the discount on the discount account and the
+// gross amount as revenue, or as late fee revenue for a penalty line.
+func BillingStudentJournalPosting(source BillingStudentJournalSource) JournalPosting {
+	receivable := source.Amount - source.PostedReceivable
+	discount := source.DiscountAmount - source.PostedDiscount
+	revenueAccount := LedgerAccountRevenue
+	if source.IsLateFee {
+		revenueAccount = LedgerAccountLateFeeRevenue
+	}
+	return JournalPosting{
+		LedgerAccountReceivable: receivable,
+		LedgerAccountDiscount:   discount,
+		revenueAccount:          -(receivable + discount),
+	}
+}
+
+func billingStudentJournalEntries(tx *gorm.DB, schoolID uint) ([]models.JournalEntry, error) {
+	var sources []BillingStudentJournalSource
+	query := `
+		SELECT bs.id, bs.detail_billing_name, bs.late_fee_for_id IS NOT NULL AS is_late_fee,
+			CASE WHEN b.billing_type ~ '^[0-9]+$' THEN b.billing_type::bigint END AS billing_type_id,
+			bs.deleted_at IS NOT NULL AS is_deleted,
+			CASE WHEN bs.deleted_at IS NULL THEN bs.amount ELSE 0 END AS amount,
+			CASE WHEN bs.deleted_at IS NULL THEN bs.discount_amount ELSE 0 END AS discount_amount,
+			bs.created_at, COALESCE(bs.deleted_at, bs.updated_at, bs.created_at) AS changed_at,
+			COALESCE(p.receivable, 0) AS posted_receivable,
+			COALESCE(p.discount, 0) AS posted_discount,
+			COALESCE(p.entries, 0) AS posted_entries
+		FROM billing_students bs
+		JOIN billings b ON b.id = bs.billing_id
+		JOIN students s ON s.id = bs.student_id
+		JOIN school_classes sc ON sc.id = s.school_class_id
+		LEFT JOIN (
+			SELECT je.source_id,
+				SUM(CASE WHEN la.code = ? THEN jl.debit - jl.credit ELSE 0 END) AS receivable,
+				SUM(CASE WHEN la.code = ? THEN jl.debit - jl.credit ELSE 0 END) AS discount,
+				COUNT(DISTINCT je.id) AS entries
+			FROM journal_entries je
+			JOIN journal_lines jl ON jl.journal_entry_id = je.id AND jl.deleted_at IS NULL
+			JOIN ledger_accounts la ON la.id = jl.ledger_account_id
+			WHERE je.school_id = ? AND je.source_type = ? AND je.deleted_at IS NULL
+			GROUP BY je.source_id
+		) p ON p.source_id = bs.id
+		WHERE sc.school_id = ?
+		AND (
+			CASE WHEN bs.deleted_at IS NULL THEN bs.amount ELSE 0 END <> COALESCE(p.receivable, 0)
+			OR CASE WHEN bs.deleted_at IS NULL THEN bs.discount_amount ELSE 0 END <> COALESCE(p.discount, 0)
+		)
+		ORDER BY bs.id
+	`
+	err := tx.Raw(query, LedgerAccountReceivable, LedgerAccountDiscount, schoolID, JournalSourceBillingStudent, schoolID).
+		Scan(&sources).Error
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.JournalEntry, 0, len(sources))
+	for _, source := range sources {
+		entryDate, description := source.ChangedAt, "Perubahan tagihan "+source.DetailBillingName
+		if source.PostedEntries == 0 {
+			entryDate, description = source.CreatedAt, "Tagihan "+source.DetailBillingName
+		} else if source.IsDeleted {
+			description = "Hapus tagihan " + source.DetailBillingName
+		}
+
+		entry, err := newJournalEntry(JournalSourceBillingStudent, source.ID, entryDate, description, BillingStudentJournalPosting(source))
+		if err != nil {
+			return nil, err
+		}
+		entry.BillingTypeID = source.BillingTypeID
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// TransactionJournalSource is a paid transaction with what it allocated to installments and
+// what it left as student credit.
+type TransactionJournalSource struct {
+	ID              uint
+	TransactionType string
+	InvoiceNumber   string
+	BankAccountID   *uint
+	TotalAmount     int64
+	ChangeAmount    int64
+	CreditAmount    int64
+	AdminFee        int64
+	ParentAdminFee  int64
+	Allocated       int64
+	Overpayment     int64
+	PaidAt          time.Time
+}
+
+// TransactionJournalPosting books the money kept by the school on cash, bank or the gateway
+// account and the credit used against the receivable paid and the credit left over. Whatever
+// was settled above the money and credit was a kasir discount. The gateway takes its whole admin
+// fee from the payout; the part the parent paid on top of the bill is admin fee income.
+func TransactionJournalPosting(source TransactionJournalSource) JournalPosting {
+	received := source.TotalAmount - source.ChangeAmount
+	posting := JournalPosting{
+		moneyLedgerAccount(source.TransactionType): received + source.ParentAdminFee - source.AdminFee,
+		LedgerAccountStudentCredit:                 source.CreditAmount - source.Overpayment,
+		LedgerAccountReceivable:                    -source.Allocated,
+		LedgerAccountDiscount:                      source.Allocated + source.Overpayment - received - source.CreditAmount,
+	}
+	if source.AdminFee > 0 {
+		posting[LedgerAccountGatewayFee] = source.AdminFee
+	}
+	if source.ParentAdminFee > 0 {
+		posting[LedgerAccountAdminFeeRevenue] = -source.ParentAdminFee
+	}
+	return posting
+}
+
+// transactionBankAccountColumn selects the bank account of the first billing a transaction paid,
+// which is where the school receives transfers and gateway payouts for it.
+const transactionBankAccountColumn = `NULLIF((
+				SELECT b.bank_account_id FROM billings b WHERE b.id::text = split_part(tb.billing_id, ',', 1)
+			), 0) AS bank_account_id`
+
+// transactionBankAccountID leaves out the bank account of kasir payments, whose money is cash.
+func transactionBankAccountID(transactionType string, bankAccountID *uint) *uint {
+	if moneyLedgerAccount(transactionType) == LedgerAccountCash {
+		return nil
+	}
+	return bankAccountID
+}
+
+// moneyLedgerAccount is where a transaction type keeps the money it receives.
+func moneyLedgerAccount(transactionType string) string {
+	switch transactionType {
+	case "PT02":
+		return LedgerAccountGateway
+	case TransactionTypeBankTransfer:
+		return LedgerAccountBank
+	default:
+		return LedgerAccountCash
+	}
+}
+
+func transactionJournalEntries(tx *gorm.DB, schoolID uint) ([]models.JournalEntry, error) {
+	var sources []TransactionJournalSource
+	query := `
+		SELECT tb.id, tb.transaction_type, tb.invoice_number, tb.total_amount, tb.credit_amount, tb.admin_fee,
+			COALESCE(d.change_amount, 0) AS change_amount,
+			COALESCE(d.parent_admin_fee, 0) AS parent_admin_fee,
+			COALESCE(p.allocated, 0) AS allocated,
+			COALESCE(c.overpayment, 0) AS overpayment,
+			COALESCE(p.paid_at, tb.updated_at, tb.created_at) AS paid_at,
+			` + transactionBankAccountColumn + `
+		FROM transaction_billings tb
+		JOIN students s ON s.id = tb.student_id
+		JOIN school_classes sc ON sc.id = s.school_class_id
+		LEFT JOIN (
+			SELECT transaction_billing_id, SUM(change_amount) AS change_amount, SUM(parent_admin_fee) AS parent_admin_fee
+			FROM transaction_billing_details
+			WHERE deleted_at IS NULL
+			GROUP BY transaction_billing_id
+		) d ON d.transaction_billing_id = tb.id
+		LEFT JOIN (
+			SELECT transaction_billing_id, SUM(amount) AS allocated, MIN(created_at) AS paid_at
+			FROM billing_student_payments
+			WHERE amount > 0 AND deleted_at IS NULL
+			GROUP BY transaction_billing_id
+		) p ON p.transaction_billing_id = tb.id
+		LEFT JOIN (
+			SELECT transaction_billing_id, SUM(amount) AS overpayment
+			FROM student_credits
+			WHERE entry_type = ? AND deleted_at IS NULL
+			GROUP BY transaction_billing_id
+		) c ON c.transaction_billing_id = tb.id
+		WHERE sc.school_id = ? AND tb.deleted_at IS NULL AND tb.transaction_status IN ?
+		AND NOT EXISTS (
+			SELECT 1 FROM journal_entries je
+			WHERE je.source_type = ? AND je.source_id = tb.id AND je.deleted_at IS NULL
+		)
+		ORDER BY tb.id
+	`
+	paidStatuses := []string{"PS02", TransactionStatusRefunded, TransactionStatusPartiallyRefunded, TransactionStatusVoid}
+	err := tx.Raw(query, StudentCreditTypeOverpayment, schoolID, paidStatuses, JournalSourceTransaction).
+		Scan(&sources).Error
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.JournalEntry, 0, len(sources))
+	for _, source := range sources {
+		entry, err := newJournalEntry(JournalSourceTransaction, source.ID, source.PaidAt, "Pembayaran "+source.InvoiceNumber, TransactionJournalPosting(source))
+		if err != nil {
+			return nil, err
+		}
+		entry.BankAccountID = transactionBankAccountID(source.TransactionType, source.BankAccountID)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// RefundJournalSource is an approved refund of a transaction.
+type RefundJournalSource struct {
+	ID              uint
+	TransactionType string
+	InvoiceNumber   string
+	BankAccountID   *uint
+	Amount          int64
+	CreditAmount    int64
+	ReversedAmount  int64
+	ToCredit        bool
+	RefundedAt      time.Time
+}
+
+// RefundJournalPosting reopens the refunded installments as receivable. The money is paid out of
+// the account the transaction's money went to, the part paid with credit and a refund to credit
+// go back to student credit and the discount given on the installments is taken back.
+func RefundJournalPosting(source RefundJournalSource) JournalPosting {
+	cash := source.Amount - source.CreditAmount
+	credit := source.CreditAmount
+	if source.ToCredit {
+		cash, credit = 0, source.Amount
+	}
+	posting := JournalPosting{
+		LedgerAccountReceivable: source.ReversedAmount,
+	}
+	if cash != 0 {
+		posting[moneyLedgerAccount(source.TransactionType)] = -cash
+	}
+	if credit != 0 {
+		posting[LedgerAccountStudentCredit] = -credit
+	}
+	if discount := source.ReversedAmount - source.Amount; discount != 0 {
+		posting[LedgerAccountDiscount] = -discount
+	}
+	return posting
+}
+
+func refundJournalEntries(tx *gorm.DB, schoolID uint) ([]models.JournalEntry, error) {
+	var sources []RefundJournalSource
+	query := `
+		SELECT tr.id, tb.transaction_type, tb.invoice_number, tr.amount, tr.credit_amount, tr.reversed_amount, tr.to_credit,
+			COALESCE(tr.approved_at, tr.updated_at, tr.created_at) AS refunded_at,
+			` + transactionBankAccountColumn + `
+		FROM transaction_refunds tr
+		JOIN transaction_billings tb ON tb.id = tr.transaction_billing_id
+		WHERE tr.school_id = ? AND tr.refund_status = ? AND tr.deleted_at IS NULL
+		AND NOT EXISTS (
+			SELECT 1 FROM journal_entries je
+			WHERE je.source_type = ? AND je.source_id = tr.id AND je.deleted_at IS NULL
+		)
+		ORDER BY tr.id
+	`
+	err := tx.Raw(query, schoolID, RefundStatusSuccess, JournalSourceRefund).Scan(&sources).Error
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.JournalEntry, 0, len(sources))
+	for _, source := range sources {
+		entry, err := newJournalEntry(JournalSourceRefund, source.ID, source.RefundedAt, "Refund "+source.InvoiceNumber, RefundJournalPosting(source))
+		if err != nil {
+			return nil, err
+		}
+		if !source.ToCredit && source.Amount > source.CreditAmount {
+			entry.BankAccountID = transactionBankAccountID(source.TransactionType, source.BankAccountID)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+type voidJournalSource struct {
+	ID                   uint
+	TransactionBillingID uint
+	InvoiceNumber        string
+	VoidedAt             time.Time
+}
+
+// voidJournalEntries reverses the entry of every voided transaction. A void undoes the payment
+// completely, so its entry is the transaction's entry with debit and credit swapped.
+func voidJournalEntries(tx *gorm.DB, schoolID uint) ([]models.JournalEntry, error) {
+	var sources []voidJournalSource
+	query := `
+		SELECT tv.id, tv.transaction_billing_id, tb.invoice_number,
+			COALESCE(tv.approved_at, tv.updated_at, tv.created_at) AS voided_at
+		FROM transaction_voids tv
+		JOIN transaction_billings tb ON tb.id = tv.transaction_billing_id
+		WHERE tv.school_id = ? AND tv.void_status = ? AND tv.deleted_at IS NULL
+		AND NOT EXISTS (
+			SELECT 1 FROM journal_entries je
+			WHERE je.source_type = ? AND je.source_id = tv.id AND je.deleted_at IS NULL
+		)
+		ORDER BY tv.id
+	`
+	err := tx.Raw(query, schoolID, VoidStatusApproved, JournalSourceVoid).Scan(&sources).Error
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.JournalEntry, 0, len(sources))
+	for _, source := range sources {
+		var paymentEntry models.JournalEntry
+		err := tx.Preload("Lines", "deleted_at IS NULL").
+			Where("source_type = ? AND source_id = ? AND deleted_at IS NULL", JournalSourceTransaction, source.TransactionBillingID).
+			First(&paymentEntry).Error
+		if err == gorm.ErrRecordNotFound {
+			// Nothing was posted for the payment, so there is nothing to reverse
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entry := models.JournalEntry{
+			EntryDate:     source.VoidedAt,
+			SourceType:    JournalSourceVoid,
+			SourceID:      source.ID,
+			Description:   "Void " + source.InvoiceNumber,
+			TotalAmount:   paymentEntry.TotalAmount,
+			BankAccountID: paymentEntry.BankAccountID,
+		}
+		for _, line := range paymentEntry.Lines {
+			entry.Lines = append(entry.Lines, models.JournalLine{
+				LedgerAccountID: line.LedgerAccountID,
+				Debit:           line.Credit,
+				Credit:          line.Debit,
+			})
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,214 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBillingStudentJournalPosting(t *testing.T) {
+	t.Run("New installment with a scholarship", func(t *testing.T) {
+		posting := BillingStudentJournalPosting(BillingStudentJournalSource{Amount: 400000, DiscountAmount: 100000})
+
+		assert.Equal(t, JournalPosting{
+			LedgerAccountReceivable: 400000,
+			LedgerAccountDiscount:   100000,
+			LedgerAccountRevenue:    -500000,
+		}, posting)
+	})
+
+	t.Run("Penalty line raised by the late fee run", func(t *testing.T) {
+		posting := BillingStudentJournalPosting(BillingStudentJournalSource{Amount: 25000, IsLateFee: true, PostedReceivable: 15000, PostedEntries: 1})
+
+		assert.Equal(t, int64(10000), posting[LedgerAccountReceivable])
+		assert.Equal(t, int64(-10000), posting[LedgerAccountLateFeeRevenue])
+		assert.Equal(t, int64(0), posting[LedgerAccountRevenue])
+	})
+
+	t.Run("Sibling discount added later", func(t *testing.T) {
+		posting := BillingStudentJournalPosting(BillingStudentJournalSource{Amount: 450000, DiscountAmount: 50000, PostedReceivable: 500000, PostedEntries: 1})
+
+		lines, total, err := BuildJournalLines(posting)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(50000), total)
+		assert.Len(t, lines, 2)
+		assert.Equal(t, LedgerAccountDiscount, lines[0].LedgerAccount.Code)
+		assert.Equal(t, int64(50000), lines[0].Debit)
+		assert.Equal(t, LedgerAccountReceivable, lines[1].LedgerAccount.Code)
+		assert.Equal(t, int64(50000), lines[1].Credit)
+	})
+
+	t.Run("Deleted installment", func(t *testing.T) {
+		posting := BillingStudentJournalPosting(BillingStudentJournalSource{IsDeleted: true, PostedReceivable: 400000, PostedDiscount: 100000, PostedEntries: 1})
+
+		assert.Equal(t, JournalPosting{
+			LedgerAccountReceivable: -400000,
+			LedgerAccountDiscount:   -100000,
+			LedgerAccountRevenue:    500000,
+		}, posting)
+	})
+}
+
+func TestTransactionJournalPosting(t *testing.T) {
+	t.Run("Kasir payment with discount, credit and change kept as credit", func(t *testing.T) {
+		// 600.000 due, 50.000 kasir discount, 100.000 credit used, 500.000 cash of which 50.000 kept as credit
+		posting := TransactionJournalPosting(TransactionJournalSource{
+			TransactionType: "PT01",
+			TotalAmount:     500000,
+			CreditAmount:    100000,
+			Allocated:       600000,
+			Overpayment:     50000,
+		})
+
+		lines, total, err := BuildJournalLines(posting)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(600000), total)
+		assert.Equal(t, JournalPosting{
+			LedgerAccountCash:          500000,
+			LedgerAccountStudentCredit: 50000,
+			LedgerAccountReceivable:    -600000,
+			LedgerAccountDiscount:      50000,
+		}, posting)
+		assert.Equal(t, LedgerAccountCash, lines[0].LedgerAccount.Code)
+	})
+
+	t.Run("Kasir change handed back", func(t *testing.T) {
+		posting := TransactionJournalPosting(TransactionJournalSource{
+			TransactionType: "PT01",
+			TotalAmount:     550000,
+			ChangeAmount:    50000,
+			Allocated:       500000,
+		})
+
+		assert.Equal(t, int64(500000), posting[LedgerAccountCash])
+		assert.Equal(t, int64(0), posting[LedgerAccountDiscount])
+	})
+
+	t.Run("Online payment with admin fee", func(t *testing.T) {
+		posting := TransactionJournalPosting(TransactionJournalSource{
+			TransactionType: "PT02",
+			TotalAmount:     450000,
+			CreditAmount:    50000,
+			AdminFee:        4000,
+			ParentAdminFee:  4000,
+			Allocated:       500000,
+		})
+
+		_, total, err := BuildJournalLines(posting)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(504000), total)
+		assert.Equal(t, int64(450000), posting[LedgerAccountGateway])
+		assert.Equal(t, int64(50000), posting[LedgerAccountStudentCredit])
+		assert.Equal(t, int64(4000), posting[LedgerAccountGatewayFee])
+		assert.Equal(t, int64(-4000), posting[LedgerAccountAdminFeeRevenue])
+	})
+
+	t.Run("Online payment with the admin fee split with the school", func(t *testing.T) {
+		posting := TransactionJournalPosting(TransactionJournalSource{
+			TransactionType: "PT02",
+			TotalAmount:     500000,
+			AdminFee:        4000,
+			ParentAdminFee:  1000,
+			Allocated:       500000,
+		})
+
+		_, total, err := BuildJournalLines(posting)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(501000), total)
+		assert.Equal(t, int64(497000), posting[LedgerAccountGateway])
+		assert.Equal(t, int64(4000), posting[LedgerAccountGatewayFee])
+		assert.Equal(t, int64(-1000), posting[LedgerAccountAdminFeeRevenue])
+	})
+
+	t.Run("Bank transfer above the installments", func(t *testing.T) {
+		posting := TransactionJournalPosting(TransactionJournalSource{
+			TransactionType: TransactionTypeBankTransfer,
+			TotalAmount:     800000,
+			Allocated:       750000,
+			Overpayment:     50000,
+		})
+
+		assert.Equal(t, int64(800000), posting[LedgerAccountBank])
+		assert.Equal(t, int64(-50000), posting[LedgerAccountStudentCredit])
+		assert.Equal(t, int64(0), posting[LedgerAccountDiscount])
+	})
+}
+
+func TestRefundJournalPosting(t *testing.T) {
+	posting := RefundJournalPosting(RefundJournalSource{TransactionType: "PT01", Amount: 300000, ReversedAmount: 300000})
+	assert.Equal(t, JournalPosting{LedgerAccountReceivable: 300000, LedgerAccountCash: -300000}, posting)
+
+	posting = RefundJournalPosting(RefundJournalSource{TransactionType: "PT02", Amount: 300000, ReversedAmount: 300000, ToCredit: true})
+	assert.Equal(t, JournalPosting{LedgerAccountReceivable: 300000, LedgerAccountStudentCredit: -300000}, posting)
+
+	// Discounted kasir payment partly paid with credit
+	posting = RefundJournalPosting(RefundJournalSource{TransactionType: "PT01", Amount: 270000, CreditAmount: 20000, ReversedAmount: 300000})
+	assert.Equal(t, JournalPosting{
+		LedgerAccountReceivable:    300000,
+		LedgerAccountCash:          -250000,
+		LedgerAccountStudentCredit: -20000,
+		LedgerAccountDiscount:      -30000,
+	}, posting)
+	_, _, err := BuildJournalLines(posting)
+	assert.NoError(t, err)
+}
+
+func TestBuildJournalLines_NotBalanced(t *testing.T) {
+	_, _, err := BuildJournalLines(JournalPosting{LedgerAccountCash: 1000, LedgerAccountReceivable: -900})
+
+	assert.EqualError(t, err, "journal entry is not balanced, debit and credit differ by 100")
+}
+
+func TestGetLedgerAccounts_CreatesMissingDefaults(t *testing.T) {
+	gormDB, mock := setupTestDB(t)
+	repo := NewJournalRepository(gormDB)
+
+	existing := sqlmock.NewRows([]string{"id", "school_id", "code"})
+	for i, account := range defaultLedgerAccounts[:8] {
+		existing.AddRow(i+1, 3, account.Code)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "ledger_accounts" WHERE school_id = \$1 AND deleted_at IS NULL`).
+		WithArgs(3).
+		WillReturnRows(existing)
+	mock.ExpectQuery(`INSERT INTO "ledger_accounts"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9).AddRow(10))
+	mock.ExpectQuery(`SELECT \* FROM "ledger_accounts" WHERE school_id = \$1 AND deleted_at IS NULL ORDER BY code`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "school_id", "code"}).AddRow(1, 3, LedgerAccountCash))
+	mock.ExpectCommit()
+
+	accounts, err := repo.GetLedgerAccounts(3)
+
+	assert.NoError(t, err)
+	assert.Len(t, accounts, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTrialBalance(t *testing.T) {
+	gormDB, mock := setupTestDB(t)
+	repo := NewJournalRepository(gormDB)
+
+	startDate := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`FROM ledger_accounts la`).
+		WithArgs(startDate, startDate, startDate, endDate.AddDate(0, 0, 1), 3).
+		WillReturnRows(sqlmock.NewRows([]string{"ledger_account_id", "code", "name", "opening_balance", "debit", "credit"}).
+			AddRow(4, LedgerAccountReceivable, "Piutang Siswa", 1000000, 500000, 750000))
+
+	accounts, err := repo.GetTrialBalance(3, startDate, endDate)
+
+	assert.NoError(t, err)
+	assert.Len(t, accounts, 1)
+	assert.Equal(t, int64(1000000), accounts[0].OpeningBalance)
+	assert.Equal(t, int64(750000), accounts[0].Credit)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,193 @@
+package repositories
+
+import (
+	"time"
+
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	LateFeeCalculationFlat       = "flat"
+	LateFeeCalculationPercentage = "percentage"
+
+	LateFeePeriodOnce    = "once"
+	LateFeePeriodDaily   = "daily"
+	LateFeePeriodMonthly = "monthly"
+
+	LateFeeNamePrefix = "Denda - "
+)
+
+type LateFeeRepository interface {
+	CreateLateFeeRule(rule *models.LateFeeRule) (*models.LateFeeRule, error)
+	UpdateLateFeeRule(rule *models.LateFeeRule) error
+	GetLateFeeRuleByID(id uint, schoolID uint) (*models.LateFeeRule, error)
+	GetAllLateFeeRule(page int, limit int, schoolID uint) ([]models.LateFeeRule, int64, error)
+	GetActiveLateFeeRules() ([]models.LateFeeRule, error)
+	BillingBelongsToSchool(billingID uint, schoolID uint) (bool, error)
+	BillingTypeBelongsToSchool(billingTypeID uint, schoolID uint) (bool, error)
+	GetOverdueInstallments(asOf time.Time) ([]response.LateFeeOverdueInstallment, error)
+	ApplyLateFee(installmentID uint, total int64, dueDate time.Time) (int64, error)
+}
+
+type lateFeeRepository struct {
+	db *gorm.DB
+}
+
+func NewLateFeeRepository(db *gorm.DB) LateFeeRepository {
+	return &lateFeeRepository{db: db}
+}
+
+func (r *lateFeeRepository) CreateLateFeeRule(rule *models.LateFeeRule) (*models.LateFeeRule, error) {
+	if err := r.db.Create(rule).Error; err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (r *lateFeeRepository) UpdateLateFeeRule(rule *models.LateFeeRule) error {
+	return r.db.Save(rule).Error
+}
+
+func (r *lateFeeRepository) GetLateFeeRuleByID(id uint, schoolID uint) (*models.LateFeeRule, error) {
+	var rule models.LateFeeRule
+	err := r.db.Where("id = ? AND school_id = ? AND deleted_at IS NULL", id, schoolID).First(&rule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *lateFeeRepository) GetAllLateFeeRule(page int, limit int, schoolID uint) ([]models.LateFeeRule, int64, error) {
+	var rules []models.LateFeeRule
+	var total int64
+
+	query := r.db.Model(&models.LateFeeRule{}).Where("school_id = ? AND deleted_at IS NULL", schoolID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if limit != 0 {
+		query = query.Offset((page - 1) * limit).Limit(limit)
+	}
+
+	err := query.Order("id DESC").Find(&rules).Error
+	return rules, total, err
+}
+
+func (r *lateFeeRepository) GetActiveLateFeeRules() ([]models.LateFeeRule, error) {
+	var rules []models.LateFeeRule
+	err := r.db.Where("is_active = ? AND deleted_at IS NULL", true).Order("id ASC").Find(&rules).Error
+	return rules, err
+}
+
+// BillingBelongsToSchool checks the billing was created by a user of the school.
+func (r *lateFeeRepository) BillingBelongsToSchool(billingID uint, schoolID uint) (bool, error) {
+	var count int64
+	err := r.db.Table("billings b").
+		Joins("JOIN user_schools us ON us.user_id = b.created_by").
+		Where("b.id = ? AND us.school_id = ? AND b.deleted_at IS NULL AND b.is_donation = false", billingID, schoolID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *lateFeeRepository) BillingTypeBelongsToSchool(billingTypeID uint, schoolID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.BillingType{}).
+		Where("id = ? AND school_id = ? AND deleted_at IS NULL", billingTypeID, schoolID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetOverdueInstallments returns every unpaid or partly paid installment that was due before
+// asOf. Penalty lines themselves are left out so no fee is charged on a fee.
+func (r *lateFeeRepository) GetOverdueInstallments(asOf time.Time) ([]response.LateFeeOverdueInstallment, error) {
+	var installments []response.LateFeeOverdueInstallment
+	query := `
+		select bs.id, bs.student_id, bs.billing_id, bs.billing_detail_id, bs.detail_billing_name,
+		bs.amount, bs.paid_amount, bs.due_date, b.billing_type, sc.school_id
+		from billing_students bs
+		join billings b on b.id = bs.billing_id and b.is_donation = false
+		join students s on s.id = bs.student_id
+		join school_classes sc on sc.id = s.school_class_id
+		where bs.deleted_at is null and bs.late_fee_for_id is null
+		and bs.payment_status in (?, ?) and bs.due_date < ?
+		order by bs.id
+	`
+	result := r.db.Raw(query, BillingStudentStatusUnpaid, BillingStudentStatusPartiallyPaid, asOf).Scan(&installments)
+	return installments, result.Error
+}
+
+// ApplyLateFee brings the penalty charged for an overdue installment up to total and returns
+// what was added. The increase goes onto the open penalty line when nothing has been paid on
+// it and no checkout holds it; otherwise a new line is created so paid or pending amounts
+// never change.
+func (r *lateFeeRepository) ApplyLateFee(installmentID uint, total int64, dueDate time.Time) (int64, error) {
+	var charged int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var installment models.BillingStudent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND deleted_at IS NULL", installmentID).
+			First(&installment).Error
+		if err != nil {
+			return err
+		}
+
+		// Paid while the job was running
+		if installment.PaymentStatus == BillingStudentStatusPaid {
+			return nil
+		}
+
+		var existing int64
+		err = tx.Model(&models.BillingStudent{}).
+			Where("late_fee_for_id = ? AND deleted_at IS NULL", installment.ID).
+			Select("COALESCE(SUM(amount), 0)").
+			Scan(&existing).Error
+		if err != nil {
+			return err
+		}
+
+		delta := total - existing
+		if delta <= 0 {
+			return nil
+		}
+
+		var openLine models.BillingStudent
+		err = tx.Where("late_fee_for_id = ? AND deleted_at IS NULL AND payment_status = ? AND paid_amount = 0", installment.ID, BillingStudentStatusUnpaid).
+			Where("reserved_order_id IS NULL OR reserved_until < ?", time.Now()).
+			Order("id DESC").
+			Take(&openLine).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		if err == nil {
+			err = tx.Model(&openLine).Updates(map[string]interface{}{
+				"amount":     openLine.Amount + delta,
+				"due_date":   dueDate,
+				"updated_at": time.Now(),
+			}).Error
+		} else {
+			err = tx.Create(&models.BillingStudent{
+				BillingID:         installment.BillingID,
+				StudentID:         installment.StudentID,
+				PaymentStatus:     BillingStudentStatusUnpaid,
+				DueDate:           &dueDate,
+				DetailBillingName: LateFeeNamePrefix + installment.DetailBillingName,
+				Amount:            delta,
+				BillingDetailID:   installment.BillingDetailID,
+				LateFeeForID:      &installment.ID,
+			}).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		charged = delta
+		return nil
+	})
+	return charged, err
+}
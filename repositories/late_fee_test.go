@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyLateFee(t *testing.T) {
+	dueDate := time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Creates the first penalty line", func(t *testing.T) {
+		gormDB, mock := setupTestDB(t)
+		repo := NewLateFeeRepository(gormDB)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \* FROM "billing_students" WHERE .* FOR UPDATE`).
+			WithArgs(21, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "billing_id", "student_id", "payment_status", "detail_billing_name", "amount", "billing_detail_id"}).
+				AddRow(21, 4, 7, BillingStudentStatusUnpaid, "SPP Januari", 500000, 9))
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM "billing_students" WHERE late_fee_for_id = \$1`).
+			WithArgs(21).
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(0))
+		mock.ExpectQuery(`SELECT \* FROM "billing_students" WHERE \(late_fee_for_id = \$1`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(`INSERT INTO "billing_students"`).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+				4, 7, BillingStudentStatusUnpaid, dueDate, "Denda - SPP Januari", int64(15000), 0, 9, nil, nil, 21, 0, nil, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(30))
+		mock.ExpectCommit()
+
+		charged, err := repo.ApplyLateFee(21, 15000, dueDate)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(15000), charged)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Already charged up to the total", func(t *testing.T) {
+		gormDB, mock := setupTestDB(t)
+		repo := NewLateFeeRepository(gormDB)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \* FROM "billing_students" WHERE .* FOR UPDATE`).
+			WithArgs(21, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "payment_status"}).AddRow(21, BillingStudentStatusPartiallyPaid))
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM "billing_students" WHERE late_fee_for_id = \$1`).
+			WithArgs(21).
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(15000))
+		mock.ExpectCommit()
+
+		charged, err := repo.ApplyLateFee(21, 15000, dueDate)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), charged)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"schoolPayment/models"
+
+	"gorm.io/gorm"
+)
+
+type PaymentLinkRepository interface {
+	GetPaymentLinksByStudentID(studentID uint) ([]models.PaymentLink, error)
+	GetPaymentLinkByID(id uint) (*models.PaymentLink, error)
+	GetPaymentLinkByCode(code string) (*models.PaymentLink, error)
+	CreatePaymentLink(paymentLink *models.PaymentLink) error
+	UpdatePaymentLink(paymentLink *models.PaymentLink) error
+}
+
+type paymentLinkRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentLinkRepository(db *gorm.DB) PaymentLinkRepository {
+	return &paymentLinkRepository{db: db}
+}
+
+func (r *paymentLinkRepository) GetPaymentLinksByStudentID(studentID uint) ([]models.PaymentLink, error) {
+	var paymentLinks []models.PaymentLink
+	err := r.db.Where("student_id = ? AND deleted_at IS NULL", studentID).
+		Order("id DESC").
+		Find(&paymentLinks).Error
+	return paymentLinks, err
+}
+
+func (r *paymentLinkRepository) GetPaymentLinkByID(id uint) (*models.PaymentLink, error) {
+	var paymentLink models.PaymentLink
+	err := r.db.Where("id = ? AND deleted_at IS NULL", id).First(&paymentLink).Error
+	if err != nil {
+		return nil, err
+	}
+	return &paymentLink, nil
+}
+
+// GetPaymentLinkByCode also returns a revoked link, so whoever opens it is told it was revoked.
+func (r *paymentLinkRepository) GetPaymentLinkByCode(code string) (*models.PaymentLink, error) {
+	var paymentLink models.PaymentLink
+	err := r.db.Where("code = ?", code).First(&paymentLink).Error
+	if err != nil {
+		return nil, err
+	}
+	return &paymentLink, nil
+}
+
+func (r *paymentLinkRepository) CreatePaymentLink(paymentLink *models.PaymentLink) error {
+	return r.db.Create(paymentLink).Error
+}
+
+func (r *paymentLinkRepository) UpdatePaymentLink(paymentLink *models.PaymentLink) error {
+	return r.db.Save(paymentLink).Error
+}
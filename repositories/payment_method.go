@@ -74,6 +74,12 @@ func GetPaymentMethodByID(id int) (*models.PaymentMethod, error) {
 	return &paymentMethod, nil
 }
 
+func GetPaymentMethods() ([]models.PaymentMethod, error) {
+	var paymentMethods []models.PaymentMethod
+	result := database.DB.Order("id").Find(&paymentMethods)
+	return paymentMethods, result.Error
+}
+
 func GetPaymentMethodForPaymentType() ([]response.PaymentType, error) {
 	var paymentTypes []response.PaymentType
 
@@ -50,6 +50,7 @@ func (r *paymentReportRepository) GetPaymentReport(
 			tbd.created_at as payment_date,
 			CASE 
 			WHEN tb.transaction_type = 'PT01' THEN 'kasir'
+			WHEN tb.transaction_type = 'PT03' THEN concat('transfer bank - ', tbd.bank_name)
 				ELSE (
 					SELECT concat(mp.payment_method, '-', mp.bank_name) 
 					FROM master_payment_method mp
@@ -68,6 +69,9 @@ func (r *paymentReportRepository) GetPaymentReport(
 				WHEN tb.transaction_status = 'PS01' THEN 'menunggu'
 				WHEN tb.transaction_status = 'PS02' THEN 'lunas'
 				WHEN tb.transaction_status = 'PS03' THEN 'gagal'
+				WHEN tb.transaction_status = 'PS04' THEN 'dikembalikan'
+				WHEN tb.transaction_status = 'PS05' THEN 'dikembalikan sebagian'
+				WHEN tb.transaction_status = 'PS06' THEN 'dibatalkan'
 				ELSE tb.transaction_status  -- Default case, if no match
 			end as transaction_status,
 			tb.created_at
@@ -80,7 +84,7 @@ func (r *paymentReportRepository) GetPaymentReport(
 		Joins("JOIN user_students us ON us.student_id = s.id").
 		Joins("JOIN user_schools usc ON usc.user_id = us.user_id").
 		Joins("JOIN users u ON u.id = usc.user_id").
-		Where("tb.deleted_at IS NULL and tb.transaction_status != 'PS03'")
+		Where("tb.deleted_at IS NULL and tb.transaction_status NOT IN ('PS03', 'PS06')")
 
 		// Get total count
 	countQuery := database.DB.Table("transaction_billings as tb").
@@ -92,7 +96,7 @@ func (r *paymentReportRepository) GetPaymentReport(
 		Joins("JOIN user_students us ON us.student_id = s.id").
 		Joins("JOIN user_schools usc ON usc.user_id = us.user_id").
 		Joins("JOIN users u ON u.id = usc.user_id").
-		Where("tb.deleted_at IS NULL AND tb.transaction_status != 'PS03'").
+		Where("tb.deleted_at IS NULL AND tb.transaction_status NOT IN ('PS03', 'PS06')").
 		Select("COUNT(DISTINCT tb.id)")
 
 	// Apply filters
@@ -199,7 +203,7 @@ func (r *paymentReportRepository) GetPaymentReportSummary(
 		join school_classes sc on sc.id = s.school_class_id
 		join user_students us on us.student_id = s.id
 		join user_schools usc on usc.user_id = us.user_id
-		where tb.deleted_at is null
+		where tb.deleted_at is null and tb.transaction_status != 'PS06'
 	`
 
 	// Apply filters
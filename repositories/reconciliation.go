@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"time"
+
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DiscrepancyTypePaidNotRecorded = "paid_not_recorded"
+	DiscrepancyTypeAmountMismatch  = "amount_mismatch"
+	DiscrepancyTypeUnknownOrder    = "unknown_order"
+)
+
+type ReconciliationRepository interface {
+	GetPendingOnlineTransactions() ([]response.ReconciliationPendingTransaction, error)
+	GetUnknownWebhookOrderIDs(since time.Time) ([]string, error)
+	CreateReconciliationRun(run *models.ReconciliationRun) error
+	UpdateReconciliationRun(run *models.ReconciliationRun) error
+	CreateReconciliationDiscrepancy(discrepancy *models.ReconciliationDiscrepancy) error
+	GetAllReconciliationRun(page int, limit int) ([]models.ReconciliationRun, int64, error)
+	GetReconciliationRunByID(id uint) (*models.ReconciliationRun, error)
+}
+
+type reconciliationRepository struct {
+	db *gorm.DB
+}
+
+func NewReconciliationRepository(db *gorm.DB) ReconciliationRepository {
+	return &reconciliationRepository{db: db}
+}
+
+// GetPendingOnlineTransactions returns every online order still waiting for payment. The children
+// of a combined checkout share one order, so their amounts and admin fees are added up.
+func (r *reconciliationRepository) GetPendingOnlineTransactions() ([]response.ReconciliationPendingTransaction, error) {
+	var transactions []response.ReconciliationPendingTransaction
+	query := `
+		select distinct on (tb.order_id) tb.id, tb.order_id,
+		(select sum(o.total_amount) from transaction_billings o where o.order_id = tb.order_id and o.deleted_at is null) as total_amount,
+		tb.transaction_status, tb.payment_gateway, mpm.payment_method,
+		(select coalesce(sum(od.parent_admin_fee), 0) from transaction_billing_details od
+			join transaction_billings o on o.id = od.transaction_billing_id
+			where o.order_id = tb.order_id and o.deleted_at is null) as parent_admin_fee
+		from transaction_billings tb
+		join transaction_billing_details tbd on tb.id = tbd.transaction_billing_id
+		join master_payment_method mpm on tbd.master_payment_method_id = mpm.id
+		where tb.deleted_at is null and tb.transaction_type = ? and tb.transaction_status = ?
+		order by tb.order_id, tb.id
+	`
+	result := r.db.Raw(query, "PT02", "PS01").Scan(&transactions)
+	return transactions, result.Error
+}
+
+// GetUnknownWebhookOrderIDs returns order IDs the gateway notified us about that have no transaction.
+func (r *reconciliationRepository) GetUnknownWebhookOrderIDs(since time.Time) ([]string, error) {
+	var orderIDs []string
+	err := r.db.Model(&models.WebhookEvent{}).
+		Distinct("order_id").
+		Where("created_at >= ? AND deleted_at IS NULL", since).
+		Where("NOT EXISTS (SELECT 1 FROM transaction_billings tb WHERE tb.order_id = webhook_events.order_id)").
+		Pluck("order_id", &orderIDs).Error
+	return orderIDs, err
+}
+
+func (r *reconciliationRepository) CreateReconciliationRun(run *models.ReconciliationRun) error {
+	return r.db.Omit("Discrepancies").Create(run).Error
+}
+
+func (r *reconciliationRepository) UpdateReconciliationRun(run *models.ReconciliationRun) error {
+	return r.db.Omit("Discrepancies").Save(run).Error
+}
+
+func (r *reconciliationRepository) CreateReconciliationDiscrepancy(discrepancy *models.ReconciliationDiscrepancy) error {
+	return r.db.Create(discrepancy).Error
+}
+
+func (r *reconciliationRepository) GetAllReconciliationRun(page int, limit int) ([]models.ReconciliationRun, int64, error) {
+	var runs []models.ReconciliationRun
+	var total int64
+
+	query := r.db.Model(&models.ReconciliationRun{}).Where("deleted_at IS NULL")
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if limit != 0 {
+		query = query.Offset((page - 1) * limit).Limit(limit)
+	}
+
+	err := query.Order("started_at DESC").Find(&runs).Error
+	return runs, total, err
+}
+
+func (r *reconciliationRepository) GetReconciliationRunByID(id uint) (*models.ReconciliationRun, error) {
+	var run models.ReconciliationRun
+	err := r.db.Preload("Discrepancies", "deleted_at IS NULL").Where("id = ? AND deleted_at IS NULL", id).First(&run).Error
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
@@ -73,7 +73,11 @@ func UpdateTransactionStatus(id uint) error {
 		return err
 	}
 
-	return nil
+	if err := ReleaseBillingStudentReservation(database.DB, transactionBilling.OrderID); err != nil {
+		return err
+	}
+
+	return ReverseStudentCreditUsage(database.DB, transactionBilling.OrderID, transactionBilling.CreatedBy)
 }
 
 func GetSchoolLogoSendEmailFailed(id uint) (response.SchoolLogoSendEmailFailedResponse, error) {
@@ -0,0 +1,180 @@
+package repositories
+
+import (
+	"errors"
+
+	database "schoolPayment/configs"
+	"schoolPayment/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	AdminFeeBearerParent = "parent"
+	AdminFeeBearerSchool = "school"
+	AdminFeeBearerSplit  = "split"
+)
+
+// AdminFeeSplit is the gateway admin fee of a checkout and the part each side pays. The parent's
+// part is charged on top of the bill, the school's part is taken from its payout.
+type AdminFeeSplit struct {
+	Bearer         string `json:"adminFeeBearer"`
+	AdminFee       int64  `json:"adminFee"`
+	ParentAdminFee int64  `json:"parentAdminFee"`
+	SchoolAdminFee int64  `json:"schoolAdminFee"`
+}
+
+// SplitAdminFee divides the admin fee by the bearer setting. A split rounds the parent's part
+// down, so the parent is never charged more than the percentage.
+func SplitAdminFee(adminFee int64, bearer string, parentPercentage int) AdminFeeSplit {
+	split := AdminFeeSplit{Bearer: bearer, AdminFee: adminFee}
+	switch bearer {
+	case AdminFeeBearerSchool:
+		split.SchoolAdminFee = adminFee
+	case AdminFeeBearerSplit:
+		split.ParentAdminFee = adminFee * int64(parentPercentage) / 100
+		split.SchoolAdminFee = adminFee - split.ParentAdminFee
+	default:
+		split.Bearer = AdminFeeBearerParent
+		split.ParentAdminFee = adminFee
+	}
+	return split
+}
+
+type SchoolPaymentConfigRepository interface {
+	GetSchoolPaymentConfigBySchoolID(schoolID uint) (*models.SchoolPaymentConfig, error)
+	SaveSchoolPaymentConfig(config *models.SchoolPaymentConfig) (*models.SchoolPaymentConfig, error)
+	GetSchoolPaymentMethodFees(schoolID uint) ([]models.SchoolPaymentMethodFee, error)
+	GetSchoolPaymentMethodFee(schoolID uint, paymentMethodID uint) (*models.SchoolPaymentMethodFee, error)
+	SaveSchoolPaymentMethodFee(fee *models.SchoolPaymentMethodFee) (*models.SchoolPaymentMethodFee, error)
+}
+
+type schoolPaymentConfigRepository struct {
+	db *gorm.DB
+}
+
+func NewSchoolPaymentConfigRepository(db *gorm.DB) SchoolPaymentConfigRepository {
+	return &schoolPaymentConfigRepository{db: db}
+}
+
+func (r *schoolPaymentConfigRepository) GetSchoolPaymentConfigBySchoolID(schoolID uint) (*models.SchoolPaymentConfig, error) {
+	var config models.SchoolPaymentConfig
+	err := r.db.Where("school_id = ? AND deleted_at IS NULL", schoolID).First(&config).Error
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (r *schoolPaymentConfigRepository) SaveSchoolPaymentConfig(config *models.SchoolPaymentConfig) (*models.SchoolPaymentConfig, error) {
+	result := r.db.Save(config)
+	return config, result.Error
+}
+
+func (r *schoolPaymentConfigRepository) GetSchoolPaymentMethodFees(schoolID uint) ([]models.SchoolPaymentMethodFee, error) {
+	var fees []models.SchoolPaymentMethodFee
+	err := r.db.Where("school_id = ? AND deleted_at IS NULL", schoolID).
+		Order("master_payment_method_id").
+		Find(&fees).Error
+	return fees, err
+}
+
+func (r *schoolPaymentConfigRepository) GetSchoolPaymentMethodFee(schoolID uint, paymentMethodID uint) (*models.SchoolPaymentMethodFee, error) {
+	var fee models.SchoolPaymentMethodFee
+	err := r.db.Where("school_id = ? AND master_payment_method_id = ? AND deleted_at IS NULL", schoolID, paymentMethodID).
+		First(&fee).Error
+	if err != nil {
+		return nil, err
+	}
+	return &fee, nil
+}
+
+func (r *schoolPaymentConfigRepository) SaveSchoolPaymentMethodFee(fee *models.SchoolPaymentMethodFee) (*models.SchoolPaymentMethodFee, error) {
+	result := r.db.Save(fee)
+	return fee, result.Error
+}
+
+// GetAdminFeeSetting returns who pays the admin fee of the payment method at the school: the
+// payment method's own setting first, then the school's, and the parent when neither is set.
+func GetAdminFeeSetting(schoolID uint, paymentMethodID uint) (string, int, error) {
+	fee, err := getSchoolPaymentMethodFee(schoolID, paymentMethodID)
+	if err != nil {
+		return "", 0, err
+	}
+	if fee != nil && fee.AdminFeeBearer != "" {
+		return fee.AdminFeeBearer, fee.AdminFeeParentPercentage, nil
+	}
+
+	config, err := GetSchoolPaymentConfig(schoolID)
+	if err != nil {
+		return "", 0, err
+	}
+	if config == nil || config.AdminFeeBearer == "" {
+		return AdminFeeBearerParent, 100, nil
+	}
+	return config.AdminFeeBearer, config.AdminFeeParentPercentage, nil
+}
+
+// GetSchoolPaymentConfig returns the payment configuration of a school, or nil when the
+// school has not configured one yet.
+func GetSchoolPaymentConfig(schoolID uint) (*models.SchoolPaymentConfig, error) {
+	var config models.SchoolPaymentConfig
+	err := database.DB.Where("school_id = ? AND deleted_at IS NULL", schoolID).First(&config).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// GetSchoolPaymentConfigByOrderID returns the payment configuration of the school that owns
+// the transaction, or nil when there is none.
+func GetSchoolPaymentConfigByOrderID(orderID string) (*models.SchoolPaymentConfig, error) {
+	var configs []models.SchoolPaymentConfig
+	query := `select spc.* from school_payment_configs spc
+		join user_schools us on us.school_id = spc.school_id
+		join user_students st on st.user_id = us.user_id
+		join transaction_billings tb on tb.student_id = st.student_id
+		where tb.order_id = ? and spc.deleted_at is null
+		limit 1`
+	result := database.DB.Raw(query, orderID).Scan(&configs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	return &configs[0], nil
+}
+
+// GetPaymentMethodGateway returns the payment gateway of the payment method at the school: the
+// payment method's own gateway first, then the school's, and an empty string when neither is set.
+func GetPaymentMethodGateway(schoolID uint, paymentMethodID uint) (string, error) {
+	fee, err := getSchoolPaymentMethodFee(schoolID, paymentMethodID)
+	if err != nil {
+		return "", err
+	}
+	if fee != nil && fee.PaymentGateway != "" {
+		return fee.PaymentGateway, nil
+	}
+
+	config, err := GetSchoolPaymentConfig(schoolID)
+	if err != nil || config == nil {
+		return "", err
+	}
+	return config.PaymentGateway, nil
+}
+
+// getSchoolPaymentMethodFee returns the school's setting of one payment method, or nil when the
+// method follows the school's setting.
+func getSchoolPaymentMethodFee(schoolID uint, paymentMethodID uint) (*models.SchoolPaymentMethodFee, error) {
+	var fees []models.SchoolPaymentMethodFee
+	err := database.DB.Where("school_id = ? AND master_payment_method_id = ? AND deleted_at IS NULL", schoolID, paymentMethodID).
+		Limit(1).Find(&fees).Error
+	if err != nil || len(fees) == 0 {
+		return nil, err
+	}
+	return &fees[0], nil
+}
@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitAdminFee(t *testing.T) {
+	assert.Equal(t, AdminFeeSplit{Bearer: AdminFeeBearerParent, AdminFee: 4000, ParentAdminFee: 4000}, SplitAdminFee(4000, AdminFeeBearerParent, 100))
+	assert.Equal(t, AdminFeeSplit{Bearer: AdminFeeBearerSchool, AdminFee: 4000, SchoolAdminFee: 4000}, SplitAdminFee(4000, AdminFeeBearerSchool, 0))
+
+	// The parent's part of a split is rounded down
+	assert.Equal(t, AdminFeeSplit{Bearer: AdminFeeBearerSplit, AdminFee: 4444, ParentAdminFee: 1333, SchoolAdminFee: 3111}, SplitAdminFee(4444, AdminFeeBearerSplit, 30))
+
+	// A school that never chose falls back to the parent
+	assert.Equal(t, AdminFeeSplit{Bearer: AdminFeeBearerParent, AdminFee: 4000, ParentAdminFee: 4000}, SplitAdminFee(4000, "", 0))
+}
@@ -0,0 +1,211 @@
+package repositories
+
+import (
+	"errors"
+
+	database "schoolPayment/configs"
+	"schoolPayment/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	StudentCreditTypeOverpayment   = "overpayment"
+	StudentCreditTypeRefund        = "refund"
+	StudentCreditTypeAdjustment    = "adjustment"
+	StudentCreditTypeUsage         = "usage"
+	StudentCreditTypeUsageReversal = "usage_reversal"
+	StudentCreditTypeVoid          = "void"
+)
+
+var ErrInsufficientStudentCredit = errors.New("student credit balance is not enough")
+
+type StudentCreditRepository interface {
+	GetStudentCreditBalance(studentID uint) (int64, error)
+	GetStudentCreditHistory(studentID uint, page int, limit int) ([]models.StudentCredit, int64, error)
+	CreateStudentCredit(credit *models.StudentCredit) error
+}
+
+type studentCreditRepository struct {
+	db *gorm.DB
+}
+
+func NewStudentCreditRepository(db *gorm.DB) StudentCreditRepository {
+	return &studentCreditRepository{db: db}
+}
+
+func (r *studentCreditRepository) GetStudentCreditBalance(studentID uint) (int64, error) {
+	return getStudentCreditBalance(r.db, studentID)
+}
+
+func (r *studentCreditRepository) GetStudentCreditHistory(studentID uint, page int, limit int) ([]models.StudentCredit, int64, error) {
+	var credits []models.StudentCredit
+	var total int64
+
+	query := r.db.Model(&models.StudentCredit{}).Where("student_id = ? AND deleted_at IS NULL", studentID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if limit != 0 {
+		query = query.Offset((page - 1) * limit).Limit(limit)
+	}
+
+	err := query.Order("created_at DESC, id DESC").Find(&credits).Error
+	return credits, total, err
+}
+
+func (r *studentCreditRepository) CreateStudentCredit(credit *models.StudentCredit) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return RecordStudentCredit(tx, credit)
+	})
+}
+
+// GetStudentCreditBalanceAndRecent returns the balance with the latest movements, for screens
+// that show the credit next to other student data.
+func GetStudentCreditBalanceAndRecent(studentID uint, limit int) (int64, []models.StudentCredit, error) {
+	repository := NewStudentCreditRepository(database.DB)
+	balance, err := repository.GetStudentCreditBalance(studentID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	credits, _, err := repository.GetStudentCreditHistory(studentID, 1, limit)
+	return balance, credits, err
+}
+
+// RecordStudentCredit adds a movement to the ledger. The student row is locked for the rest of
+// the transaction so concurrent checkouts cannot both spend the same balance, and a movement
+// that would take the balance below zero fails with ErrInsufficientStudentCredit.
+func RecordStudentCredit(tx *gorm.DB, credit *models.StudentCredit) error {
+	if err := lockStudentCredit(tx, credit.StudentID); err != nil {
+		return err
+	}
+
+	if credit.Amount < 0 {
+		balance, err := getStudentCreditBalance(tx, credit.StudentID)
+		if err != nil {
+			return err
+		}
+		if balance+credit.Amount < 0 {
+			return ErrInsufficientStudentCredit
+		}
+	}
+
+	return tx.Create(credit).Error
+}
+
+// UseStudentCreditForOrder spends credit on an online checkout before the gateway charge is
+// opened, so the same balance cannot be offered to two checkouts.
+func UseStudentCreditForOrder(studentID uint, orderID string, amount int64, userID int) error {
+	credit := models.StudentCredit{
+		StudentID: studentID,
+		EntryType: StudentCreditTypeUsage,
+		Amount:    -amount,
+		OrderID:   &orderID,
+	}
+	credit.CreatedBy = userID
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		return RecordStudentCredit(tx, &credit)
+	})
+}
+
+// ReverseStudentCreditUsage gives back the credit an order spent. It only books what is still
+// outstanding, so the webhook and the expiry job can both call it for the same order.
+func ReverseStudentCreditUsage(db *gorm.DB, orderID string, userID int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var studentIDs []uint
+		err := tx.Model(&models.StudentCredit{}).
+			Distinct("student_id").
+			Where("order_id = ? AND entry_type = ? AND deleted_at IS NULL", orderID, StudentCreditTypeUsage).
+			Pluck("student_id", &studentIDs).Error
+		if err != nil {
+			return err
+		}
+
+		for _, studentID := range studentIDs {
+			// Lock before reading what is outstanding, so a concurrent reversal waits and then sees this one
+			if err := lockStudentCredit(tx, studentID); err != nil {
+				return err
+			}
+
+			var outstanding int64
+			err := tx.Model(&models.StudentCredit{}).
+				Select("COALESCE(SUM(amount), 0)").
+				Where("order_id = ? AND student_id = ? AND entry_type IN ? AND deleted_at IS NULL",
+					orderID, studentID, []string{StudentCreditTypeUsage, StudentCreditTypeUsageReversal}).
+				Scan(&outstanding).Error
+			if err != nil {
+				return err
+			}
+			if outstanding >= 0 {
+				continue
+			}
+
+			reversal := models.StudentCredit{
+				StudentID: studentID,
+				EntryType: StudentCreditTypeUsageReversal,
+				Amount:    -outstanding,
+				OrderID:   &orderID,
+				Note:      "Payment was not completed",
+			}
+			reversal.CreatedBy = userID
+			if err := tx.Create(&reversal).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ReverseStudentCreditForTransaction takes back what a transaction booked on the credit: used
+// credit is given back and credit from an overpayment is taken off again. It fails with
+// ErrInsufficientStudentCredit when that credit has already been spent.
+func ReverseStudentCreditForTransaction(tx *gorm.DB, transaction *models.TransactionBilling, note string, userID int) error {
+	var net int64
+	err := tx.Model(&models.StudentCredit{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("transaction_billing_id = ? AND deleted_at IS NULL", transaction.ID).
+		Scan(&net).Error
+	if err != nil {
+		return err
+	}
+	if net == 0 {
+		return nil
+	}
+
+	credit := models.StudentCredit{
+		StudentID:            transaction.StudentID,
+		EntryType:            StudentCreditTypeVoid,
+		Amount:               -net,
+		TransactionBillingID: &transaction.ID,
+		Note:                 note,
+	}
+	credit.CreatedBy = userID
+	return RecordStudentCredit(tx, &credit)
+}
+
+// LinkStudentCreditToTransaction attaches the credit movements of an order to its transaction.
+func LinkStudentCreditToTransaction(tx *gorm.DB, orderID string, transactionBillingID uint) error {
+	return tx.Model(&models.StudentCredit{}).
+		Where("order_id = ? AND transaction_billing_id IS NULL", orderID).
+		Update("transaction_billing_id", transactionBillingID).Error
+}
+
+func lockStudentCredit(tx *gorm.DB, studentID uint) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		Where("id = ?", studentID).
+		First(&models.Student{}).Error
+}
+
+func getStudentCreditBalance(db *gorm.DB, studentID uint) (int64, error) {
+	var balance int64
+	err := db.Model(&models.StudentCredit{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("student_id = ? AND deleted_at IS NULL", studentID).
+		Scan(&balance).Error
+	return balance, err
+}
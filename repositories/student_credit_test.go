@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"testing"
+
+	"schoolPayment/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordStudentCredit(t *testing.T) {
+	gormDB, mock := setupTestDB(t)
+
+	t.Run("Spends available balance", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT "id" FROM "students" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(7, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM "student_credits"`).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(50000))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "student_credits"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		credit := models.StudentCredit{StudentID: 7, EntryType: StudentCreditTypeUsage, Amount: -20000}
+		err := RecordStudentCredit(gormDB, &credit)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(1), credit.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Rejects spending more than the balance", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT "id" FROM "students" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(7, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM "student_credits"`).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(10000))
+
+		credit := models.StudentCredit{StudentID: 7, EntryType: StudentCreditTypeUsage, Amount: -20000}
+		err := RecordStudentCredit(gormDB, &credit)
+
+		assert.Equal(t, ErrInsufficientStudentCredit, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Adds credit without balance check", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT "id" FROM "students" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(7, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "student_credits"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectCommit()
+
+		credit := models.StudentCredit{StudentID: 7, EntryType: StudentCreditTypeOverpayment, Amount: 5000}
+		err := RecordStudentCredit(gormDB, &credit)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestReverseStudentCreditUsage(t *testing.T) {
+	gormDB, mock := setupTestDB(t)
+
+	t.Run("Skips order already reversed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT DISTINCT "student_id" FROM "student_credits"`).
+			WithArgs("ORDER-1", StudentCreditTypeUsage).
+			WillReturnRows(sqlmock.NewRows([]string{"student_id"}).AddRow(7))
+		mock.ExpectQuery(`SELECT "id" FROM "students" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(7, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM "student_credits"`).
+			WithArgs("ORDER-1", 7, StudentCreditTypeUsage, StudentCreditTypeUsageReversal).
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(0))
+		mock.ExpectCommit()
+
+		err := ReverseStudentCreditUsage(gormDB, "ORDER-1", 1)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Gives back outstanding usage", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT DISTINCT "student_id" FROM "student_credits"`).
+			WithArgs("ORDER-2", StudentCreditTypeUsage).
+			WillReturnRows(sqlmock.NewRows([]string{"student_id"}).AddRow(7))
+		mock.ExpectQuery(`SELECT "id" FROM "students" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(7, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM "student_credits"`).
+			WithArgs("ORDER-2", 7, StudentCreditTypeUsage, StudentCreditTypeUsageReversal).
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(-15000))
+		mock.ExpectQuery(`INSERT INTO "student_credits"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectCommit()
+
+		err := ReverseStudentCreditUsage(gormDB, "ORDER-2", 1)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
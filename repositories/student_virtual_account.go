@@ -0,0 +1,272 @@
+package repositories
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	database "schoolPayment/configs"
+	"schoolPayment/dtos/request"
+	"schoolPayment/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrStudentVirtualAccountExists = errors.New("student already has a virtual account for this payment method")
+
+type StudentVirtualAccountRepository interface {
+	GetStudentVirtualAccounts(studentID uint) ([]models.StudentVirtualAccount, error)
+	GetStudentVirtualAccountByID(id uint, schoolID uint) (*models.StudentVirtualAccount, error)
+	CreateStudentVirtualAccount(virtualAccount *models.StudentVirtualAccount) error
+	UpdateStudentVirtualAccount(virtualAccount *models.StudentVirtualAccount) error
+}
+
+type studentVirtualAccountRepository struct {
+	db *gorm.DB
+}
+
+func NewStudentVirtualAccountRepository(db *gorm.DB) StudentVirtualAccountRepository {
+	return &studentVirtualAccountRepository{db: db}
+}
+
+func (r *studentVirtualAccountRepository) GetStudentVirtualAccounts(studentID uint) ([]models.StudentVirtualAccount, error) {
+	var virtualAccounts []models.StudentVirtualAccount
+	err := r.db.Where("student_id = ? AND deleted_at IS NULL", studentID).
+		Order("id ASC").
+		Find(&virtualAccounts).Error
+	return virtualAccounts, err
+}
+
+func (r *studentVirtualAccountRepository) GetStudentVirtualAccountByID(id uint, schoolID uint) (*models.StudentVirtualAccount, error) {
+	var virtualAccount models.StudentVirtualAccount
+	err := r.db.Where("id = ? AND school_id = ? AND deleted_at IS NULL", id, schoolID).First(&virtualAccount).Error
+	if err != nil {
+		return nil, err
+	}
+	return &virtualAccount, nil
+}
+
+// CreateStudentVirtualAccount issues the number unless the student already has one for the
+// payment method or the number is in use at the bank.
+func (r *studentVirtualAccountRepository) CreateStudentVirtualAccount(virtualAccount *models.StudentVirtualAccount) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		err := tx.Model(&models.StudentVirtualAccount{}).
+			Where("student_id = ? AND master_payment_method_id = ? AND deleted_at IS NULL", virtualAccount.StudentID, virtualAccount.MasterPaymentMethodID).
+			Count(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrStudentVirtualAccountExists
+		}
+
+		err = tx.Model(&models.StudentVirtualAccount{}).
+			Where("bank = ? AND va_number = ? AND deleted_at IS NULL", virtualAccount.Bank, virtualAccount.VANumber).
+			Count(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing > 0 {
+			return fmt.Errorf("virtual account number %s is already used", virtualAccount.VANumber)
+		}
+
+		return tx.Create(virtualAccount).Error
+	})
+}
+
+func (r *studentVirtualAccountRepository) UpdateStudentVirtualAccount(virtualAccount *models.StudentVirtualAccount) error {
+	return r.db.Save(virtualAccount).Error
+}
+
+// GetStudentVirtualAccountNumber returns the fixed number the student pays the payment method
+// with, or an empty string when none was issued.
+func GetStudentVirtualAccountNumber(studentID uint, paymentMethodID int) (string, error) {
+	var numbers []string
+	err := database.DB.Model(&models.StudentVirtualAccount{}).
+		Where("student_id = ? AND master_payment_method_id = ? AND deleted_at IS NULL", studentID, paymentMethodID).
+		Limit(1).
+		Pluck("va_number", &numbers).Error
+	if err != nil || len(numbers) == 0 {
+		return "", err
+	}
+	return numbers[0], nil
+}
+
+// GetStudentVirtualAccountOfPayment returns the fixed virtual account a notification was paid
+// into, or nil when the money went to a one-off number.
+func GetStudentVirtualAccountOfPayment(payload request.WebhookPayload) (*models.StudentVirtualAccount, error) {
+	var bank, number string
+	if len(payload.VANumbers) > 0 {
+		bank, number = payload.VANumbers[0].Bank, payload.VANumbers[0].VANumber
+	} else if payload.PermataVaNumber != "" {
+		bank, number = "permata", payload.PermataVaNumber
+	}
+	if number == "" {
+		return nil, nil
+	}
+
+	var virtualAccount models.StudentVirtualAccount
+	err := database.DB.Where("bank = ? AND va_number = ? AND deleted_at IS NULL", strings.ToLower(bank), number).
+		First(&virtualAccount).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &virtualAccount, nil
+}
+
+// PostStaticVirtualAccountPayment books money paid into a fixed virtual account without a
+// checkout. Like a settled checkout it pays installments in due date order, here the student's
+// oldest open ones; a partly covered installment becomes "Sebagian" and what is left over
+// becomes student credit. Only a settlement is booked, and only once per order.
+func PostStaticVirtualAccountPayment(virtualAccount *models.StudentVirtualAccount, payload request.WebhookPayload, adminFee AdminFeeSplit) error {
+	if payload.TransactionStatus != "settlement" {
+		return nil
+	}
+
+	grossAmount, err := strconv.ParseFloat(payload.GrossAmount, 64)
+	if err != nil {
+		return fmt.Errorf("invalid gross amount: %s", payload.GrossAmount)
+	}
+	amount := int64(grossAmount)
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.TransactionBilling{}).Where("order_id = ?", payload.OrderID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return nil
+		}
+
+		var installments []models.BillingStudent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "bs"}}).
+			Table("billing_students bs").
+			Select("bs.*").
+			Joins("JOIN billings b ON b.id = bs.billing_id").
+			Where("bs.student_id = ? AND bs.deleted_at IS NULL AND bs.payment_status <> ?", virtualAccount.StudentID, BillingStudentStatusPaid).
+			Where("b.deleted_at IS NULL AND b.is_donation = ?", false).
+			Where("bs.reserved_order_id IS NULL OR bs.reserved_until < ?", time.Now()).
+			Order("bs.due_date ASC NULLS LAST, bs.id").
+			Find(&installments).Error
+		if err != nil {
+			return err
+		}
+
+		// Only the installments the money reaches are part of the transaction
+		left := amount
+		var paidInstallments []models.BillingStudent
+		for _, installment := range installments {
+			if left <= 0 {
+				break
+			}
+			paidInstallments = append(paidInstallments, installment)
+			left -= installment.Amount - installment.PaidAmount
+		}
+
+		var billingIDs []int
+		idStrings := make([]string, 0, len(paidInstallments))
+		seenBilling := make(map[uint]bool)
+		for _, installment := range paidInstallments {
+			idStrings = append(idStrings, strconv.Itoa(int(installment.ID)))
+			if !seenBilling[installment.BillingID] {
+				seenBilling[installment.BillingID] = true
+				billingIDs = append(billingIDs, int(installment.BillingID))
+			}
+		}
+
+		invoiceNumber, err := NextInvoiceNumber(tx, virtualAccount.SchoolID, virtualAccount.StudentID)
+		if err != nil {
+			return fmt.Errorf("failed to generate invoice number: %v", err)
+		}
+
+		transaction := models.TransactionBilling{
+			StudentID:            virtualAccount.StudentID,
+			BillingID:            IntsToString(billingIDs),
+			TransactionType:      "PT02",
+			TotalAmount:          int(amount),
+			ReferenceNumber:      payload.TransactionID,
+			OrderID:              payload.OrderID,
+			Description:          "Payment to virtual account " + virtualAccount.VANumber,
+			TransactionStatus:    "PS02",
+			BillingStudentIds:    strings.Join(idStrings, ","),
+			InvoiceNumber:        invoiceNumber,
+			VirtualAccountNumber: virtualAccount.VANumber,
+			ExpiryTime:           payload.ExpiryTime,
+			PaymentGateway:       "midtrans",
+			AdminFee:             adminFee.AdminFee,
+		}
+		transaction.CreatedBy = virtualAccount.CreatedBy
+		if err := tx.Create(&transaction).Error; err != nil {
+			return err
+		}
+
+		bankName := strings.ToUpper(virtualAccount.Bank)
+		detail := models.TransactionBillingDetail{
+			TransactionBillingID:  transaction.ID,
+			MasterPaymentMethodID: virtualAccount.MasterPaymentMethodID,
+			BankName:              &bankName,
+			VirtualAccountNumber:  &virtualAccount.VANumber,
+			TransactionTime:       func(t time.Time) *time.Time { return &t }(time.Now()),
+			AdminFeeBearer:        &adminFee.Bearer,
+			AdminFee:              adminFee.AdminFee,
+			ParentAdminFee:        adminFee.ParentAdminFee,
+			SchoolAdminFee:        adminFee.SchoolAdminFee,
+		}
+		detail.CreatedBy = transaction.CreatedBy
+		if _, err := CreateTransactionBillingDetail(tx, &detail); err != nil {
+			return err
+		}
+
+		// The payment log is what the payment notification email reads, as for a checkout
+		payloadJSON, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		paymentLog := models.MidtransPaymentLog{
+			OrderID:          payload.OrderID,
+			StatusCode:       payload.StatusCode,
+			RedirectUrl:      "{}",
+			RequestBodyJson:  string(payloadJSON),
+			ResponseBodyJson: "{}",
+		}
+		if err := tx.Create(&paymentLog).Error; err != nil {
+			return err
+		}
+
+		left = amount
+		for i := range paidInstallments {
+			remaining := paidInstallments[i].Amount - paidInstallments[i].PaidAmount
+			paid := remaining
+			if left < remaining {
+				paid = left
+			}
+			if err := RecordBillingStudentPayment(tx, &paidInstallments[i], transaction.ID, paid, transaction.CreatedBy); err != nil {
+				return err
+			}
+			left -= paid
+		}
+
+		if left > 0 {
+			credit := models.StudentCredit{
+				StudentID:            virtualAccount.StudentID,
+				EntryType:            StudentCreditTypeOverpayment,
+				Amount:               left,
+				TransactionBillingID: &transaction.ID,
+			}
+			credit.CreatedBy = transaction.CreatedBy
+			if err := RecordStudentCredit(tx, &credit); err != nil {
+				return err
+			}
+		}
+
+		return SaveTransactionBillingHistory(tx, &transaction)
+	})
+}
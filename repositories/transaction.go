@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -14,8 +15,8 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/midtrans/midtrans-go/coreapi"
-	"github.com/midtrans/midtrans-go/snap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TransactionRepository struct{}
@@ -47,7 +48,7 @@ func (transactionRepository *TransactionRepository) GetAllTransaction(page int,
 	return transactions, result.Error
 }
 
-func SaveLogPaymentMidtrans(orderId string, requestBody *snap.Request, responseString string) error {
+func SaveLogPaymentMidtrans(orderId string, requestBody interface{}, responseString string) error {
 	requestBodyJSON, err := json.Marshal(requestBody)
 	if err != nil {
 		return err
@@ -86,107 +87,281 @@ func SaveLogCheckPaymentMidtrans(orderID string, res *coreapi.TransactionStatusR
 	return result.Error
 }
 
-func CreateTransactionBilling(orderID string, studendID, billingAmount, paymentMethodId int, billingStudentIds []string, invoiceNumber string, listAccountNumber []string, listBillingId []int, bankName string, userId int) error {
-	epochTime := time.Now().Unix()
-	referenceNumber := fmt.Sprintf("000000%d", epochTime)
-	billingStudentIdsStr := strings.Join(billingStudentIds, ",")
-	listAccountNumberStr := strings.Join(listAccountNumber, ",")
-	listBillingIdStr := IntsToString(listBillingId)
-	expiryTime := time.Now().Format("2006-01-02T15:04:05")
+// CreateTransactionBilling records a pending online payment. The invoice number is taken from
+// the school's sequence in the same database transaction that creates the billing. Student
+// credit spent on the order is taken off the total; the gateway charges the total plus the parent's part of adminFee.
+func CreateTransactionBilling(orderID string, studendID, billingAmount, paymentMethodId int, billingStudentIds []string, schoolID uint, listAccountNumber []string, listBillingId []int, bankName string, userId int, paymentGateway string, creditAmount int64, adminFee AdminFeeSplit) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		rq := newPendingTransactionBilling(orderID, uint(studendID), billingAmount-int(creditAmount), billingStudentIds, listAccountNumber, listBillingId, paymentGateway, userId)
+		rq.CreditAmount = creditAmount
+		rq.AdminFee = adminFee.AdminFee
+
+		return createPendingTransactionBilling(tx, &rq, schoolID, paymentMethodId, bankName, adminFee)
+	})
+}
+
+// GroupTransactionBillingPart is what one child pays in a combined checkout.
+type GroupTransactionBillingPart struct {
+	StudentID         uint
+	BillingAmount     int
+	BillingStudentIds []string
+	ListAccountNumber []string
+	ListBillingId     []int
+}
+
+// CreateGroupTransactionBilling records a pending combined payment: the group of the gateway order
+// and a transaction with its own invoice number for each child. The order has one admin fee, it
+// is booked on the transaction of the first child.
+func CreateGroupTransactionBilling(orderID string, parts []GroupTransactionBillingPart, paymentMethodId int, schoolID uint, bankName string, userId int, paymentGateway string, adminFee AdminFeeSplit) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		group := models.TransactionBillingGroup{
+			OrderID:        orderID,
+			SchoolID:       schoolID,
+			AdminFee:       adminFee.AdminFee,
+			PaymentGateway: paymentGateway,
+		}
+		for _, part := range parts {
+			group.BillingAmount += int64(part.BillingAmount)
+		}
+		group.CreatedBy = userId
+		if err := tx.Create(&group).Error; err != nil {
+			return err
+		}
+
+		for i, part := range parts {
+			rq := newPendingTransactionBilling(orderID, part.StudentID, part.BillingAmount, part.BillingStudentIds, part.ListAccountNumber, part.ListBillingId, paymentGateway, userId)
+			rq.TransactionBillingGroupID = &group.ID
+
+			partAdminFee := AdminFeeSplit{Bearer: adminFee.Bearer}
+			if i == 0 {
+				partAdminFee = adminFee
+			}
+			rq.AdminFee = partAdminFee.AdminFee
 
-	tx := database.DB.Begin()
+			if err := createPendingTransactionBilling(tx, &rq, schoolID, paymentMethodId, bankName, partAdminFee); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
 
+func newPendingTransactionBilling(orderID string, studentID uint, totalAmount int, billingStudentIds []string, listAccountNumber []string, listBillingId []int, paymentGateway string, userId int) models.TransactionBilling {
 	rq := models.TransactionBilling{
-		BillingID:         listBillingIdStr,
-		StudentID:         uint(studendID),
+		BillingID:         IntsToString(listBillingId),
+		StudentID:         studentID,
 		TransactionType:   "PT02",
-		TotalAmount:       billingAmount,
-		ReferenceNumber:   referenceNumber,
+		TotalAmount:       totalAmount,
+		ReferenceNumber:   fmt.Sprintf("000000%d", time.Now().Unix()),
 		OrderID:           orderID,
 		TransactionStatus: "PS01",
 		Description:       "Payment for Billing",
-		BillingStudentIds: billingStudentIdsStr,
-		InvoiceNumber:     invoiceNumber,
-		AccountNumber:     listAccountNumberStr,
-		ExpiryTime:        expiryTime,
+		BillingStudentIds: strings.Join(billingStudentIds, ","),
+		AccountNumber:     strings.Join(listAccountNumber, ","),
+		ExpiryTime:        time.Now().Format("2006-01-02T15:04:05"),
+		PaymentGateway:    paymentGateway,
 	}
 	rq.CreatedBy = userId
+	return rq
+}
+
+// createPendingTransactionBilling numbers the invoice of the transaction and saves it with its
+// detail and first history row.
+func createPendingTransactionBilling(tx *gorm.DB, rq *models.TransactionBilling, schoolID uint, paymentMethodId int, bankName string, adminFee AdminFeeSplit) error {
+	invoiceNumber, err := NextInvoiceNumber(tx, schoolID, rq.StudentID)
+	if err != nil {
+		return fmt.Errorf(constants.MessageErrorGenerateInvoiceNumber, err)
+	}
+	rq.InvoiceNumber = invoiceNumber
+
 	// Save the transaction to the database
-	result := database.DB.Create(&rq)
+	result := tx.Create(rq)
 	if result.Error != nil {
 		return result.Error
 	}
 
+	if rq.CreditAmount > 0 {
+		if err := LinkStudentCreditToTransaction(tx, rq.OrderID, rq.ID); err != nil {
+			return err
+		}
+	}
+
 	// Prepare transaction detail data
 	transactionDetail := models.TransactionBillingDetail{
 		TransactionBillingID:  rq.ID,
 		MasterPaymentMethodID: uint(paymentMethodId),
 		BankName:              &bankName,
 		TransactionTime:       func(t time.Time) *time.Time { return &t }(time.Now()),
+		AdminFeeBearer:        &adminFee.Bearer,
+		AdminFee:              adminFee.AdminFee,
+		ParentAdminFee:        adminFee.ParentAdminFee,
+		SchoolAdminFee:        adminFee.SchoolAdminFee,
 	}
-	transactionDetail.CreatedBy = userId
+	transactionDetail.CreatedBy = rq.CreatedBy
 	_, errTransactionDetail := CreateTransactionBillingDetail(tx, &transactionDetail)
 	if errTransactionDetail != nil {
 		return errTransactionDetail
 	}
 
 	// Call the function to save the transaction history
-	err := SaveTransactionBillingHistory(&rq)
+	return SaveTransactionBillingHistory(tx, rq)
+}
+
+// ErrTransactionStatusRegression is returned for a late notification that would move an order
+// back to an earlier status, e.g. a "pending" arriving after the "settlement".
+var ErrTransactionStatusRegression = errors.New("transaction status would move backwards")
+
+// ErrPaidAfterFailure is returned for a payment reported on an order that had already failed.
+// Its reservation and the credit spent on it were given back when it failed, so settling the
+// installments now would spend that credit twice; the payment is left for an admin to handle.
+var ErrPaidAfterFailure = errors.New("payment arrived after the order failed, needs manual handling")
+
+// UpdateTransactionBilling applies a gateway notification to the transactions of the order, one
+// for a single checkout or one per child for a combined checkout. The status, the settled
+// installments, the released reservation, the credit given back and the history of every
+// transaction of the order are written in one database transaction, so a failure part-way never
+// leaves the order half-settled.
+func UpdateTransactionBilling(payload request.WebhookPayload) error {
+	transactionStatus, err := MapWebhookTransactionStatus(payload)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		return updateTransactionBilling(tx, payload, transactionStatus)
+	})
 }
 
-func UpdateTransactionBilling(payload request.WebhookPayload) error {
-	var transaction models.TransactionBilling
-
-	if err := database.DB.Where(constants.FilterOrderId, payload.OrderID).First(&transaction).Error; err != nil {
+func updateTransactionBilling(tx *gorm.DB, payload request.WebhookPayload, transactionStatus string) error {
+	var transactions []models.TransactionBilling
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where(constants.FilterOrderId, payload.OrderID).
+		Order("id ASC").
+		Find(&transactions).Error
+	if err != nil {
 		return err
 	}
-
-	if len(payload.VANumbers) > 0 {
-		transaction.VirtualAccountNumber = payload.VANumbers[0].VANumber
-	} else if payload.PermataVaNumber != "" {
-		transaction.VirtualAccountNumber = payload.PermataVaNumber
-	} else if payload.PaymentType == "echannel" && payload.BillerCode == "70012" {
-		transaction.VirtualAccountNumber = payload.BillerCode + payload.BillKey
+	if len(transactions) == 0 {
+		return gorm.ErrRecordNotFound
 	}
 
-	switch payload.TransactionStatus {
-	case "settlement", "capture":
-		transaction.TransactionStatus = "PS02"
-	case "expire", "failure", "cancel":
-		transaction.TransactionStatus = "PS03"
-	case "pending":
-		transaction.TransactionStatus = "PS01"
-	default:
-		return fmt.Errorf("unknown status: %s", payload.TransactionStatus)
+	for _, transaction := range transactions {
+		if err := checkTransactionStatusChange(transaction.TransactionStatus, transactionStatus); err != nil {
+			return err
+		}
 	}
 
-	transaction.ExpiryTime = payload.ExpiryTime
+	for i := range transactions {
+		transaction := &transactions[i]
 
-	// Save the updated transaction
-	if err := database.DB.Save(&transaction).Error; err != nil {
-		return err
+		if len(payload.VANumbers) > 0 {
+			transaction.VirtualAccountNumber = payload.VANumbers[0].VANumber
+		} else if payload.PermataVaNumber != "" {
+			transaction.VirtualAccountNumber = payload.PermataVaNumber
+		} else if payload.PaymentType == "echannel" && payload.BillerCode == "70012" {
+			transaction.VirtualAccountNumber = payload.BillerCode + payload.BillKey
+		}
+
+		transaction.TransactionStatus = transactionStatus
+
+		transaction.ExpiryTime = payload.ExpiryTime
+
+		// Save the updated transaction
+		if err := tx.Save(transaction).Error; err != nil {
+			return err
+		}
+
+		// An accepted card capture is already paid, so its installments are booked then and not
+		// left unreserved and unpaid until the settlement
+		if transactionStatus == "PS02" {
+			// A payment to a fixed virtual account booked its installments when it was posted
+			booked, err := HasBillingStudentPayments(tx, transaction.ID)
+			if err != nil {
+				return err
+			}
+			if !booked {
+				if err := SettleBillingStudents(tx, transaction.BillingStudentIds, transaction.ID, transaction.CreatedBy); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Save the transaction history after a successful update
+		if err := SaveTransactionBillingHistory(tx, transaction); err != nil {
+			return err
+		}
 	}
 
-	if payload.TransactionStatus == "settlement" {
-		if err := UpdateBillingStudentPaymentStatus(transaction.BillingStudentIds, "2"); err != nil {
+	// A paid, expired or cancelled order no longer holds its installments, a capture still under
+	// fraud review is pending and keeps them
+	if transactionStatus != "PS01" {
+		if err := ReleaseBillingStudentReservation(tx, payload.OrderID); err != nil {
 			return err
 		}
 	}
 
-	// Save the transaction history after a successful update
-	if err := SaveTransactionBillingHistory(&transaction); err != nil {
-		return err
+	// Credit spent on an order that will never be paid goes back to the student
+	if transactionStatus == "PS03" {
+		if err := ReverseStudentCreditUsage(tx, payload.OrderID, transactions[0].CreatedBy); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func SaveTransactionBillingHistory(transaction *models.TransactionBilling) error {
+// checkTransactionStatusChange tells whether a notification may move a transaction from current
+// to next.
+func checkTransactionStatusChange(current string, next string) error {
+	if current == "PS03" && next == "PS02" {
+		return ErrPaidAfterFailure
+	}
+	if IsTransactionStatusRegression(current, next) {
+		return ErrTransactionStatusRegression
+	}
+	return nil
+}
+
+// IsTransactionStatusRegression tells whether a notification moving a transaction from current
+// to next goes backwards. A paid transaction only takes another paid notification, a refunded or
+// voided one takes none and a failed one does not go back to pending.
+func IsTransactionStatusRegression(current string, next string) bool {
+	switch current {
+	case "PS02":
+		return next != "PS02"
+	case TransactionStatusRefunded, TransactionStatusPartiallyRefunded, TransactionStatusVoid:
+		return true
+	case "PS03":
+		return next == "PS01"
+	default:
+		return false
+	}
+}
+
+// MapWebhookTransactionStatus converts a notification into our payment status code. A card
+// "capture" is only paid once the fraud check accepted it, until then the order is pending.
+func MapWebhookTransactionStatus(payload request.WebhookPayload) (string, error) {
+	if payload.TransactionStatus == "capture" && payload.FraudStatus != "accept" {
+		return "PS01", nil
+	}
+	return MapMidtransTransactionStatus(payload.TransactionStatus)
+}
+
+// MapMidtransTransactionStatus converts a Midtrans transaction_status into our payment status code.
+func MapMidtransTransactionStatus(midtransStatus string) (string, error) {
+	switch midtransStatus {
+	case "settlement", "capture":
+		return "PS02", nil
+	case "expire", "failure", "cancel", "deny":
+		return "PS03", nil
+	case "pending":
+		return "PS01", nil
+	default:
+		return "", fmt.Errorf("unknown status: %s", midtransStatus)
+	}
+}
+
+func SaveTransactionBillingHistory(tx *gorm.DB, transaction *models.TransactionBilling) error {
 	rq := models.TransactionBillingHistory{
 		TransactionBillingId: transaction.ID, // Assuming transaction.ID is set after saving
 		OrderID:              transaction.OrderID,
@@ -195,13 +370,12 @@ func SaveTransactionBillingHistory(transaction *models.TransactionBilling) error
 		InvoiceNumber:        transaction.InvoiceNumber,
 	}
 	rq.CreatedBy = transaction.CreatedBy
-	result := database.DB.Create(&rq)
+	result := tx.Create(&rq)
 	return result.Error
 }
 
 func (transactionRepository *TransactionRepository) CreateTransactionRepository(tx *gorm.DB, transaction *models.TransactionBilling) (*models.TransactionBilling, error) {
-	// result := tx.Create(transaction)
-	result := database.DB.Create(&transaction)
+	result := tx.Create(transaction)
 	return transaction, result.Error
 }
 
@@ -276,7 +450,7 @@ func SaveLogCheckPaymentMidtransFromWebhook(orderID string, res *request.Webhook
 }
 
 func CreateTransactionBillingDetail(tx *gorm.DB, billingTransactionDetail *models.TransactionBillingDetail) (*models.TransactionBillingDetail, error) {
-	result := database.DB.Save(&billingTransactionDetail)
+	result := tx.Save(billingTransactionDetail)
 	return billingTransactionDetail, result.Error
 }
 
@@ -334,6 +508,21 @@ func GetBillingByOrderId(orderID string) (*models.TransactionBilling, error) {
 	return transactionBiling, result.Error
 }
 
+// GetTransactionBillingsByOrderID returns the transactions of a gateway order, more than one when
+// the order is a combined checkout of several children.
+func GetTransactionBillingsByOrderID(orderID string) ([]models.TransactionBilling, error) {
+	var transactions []models.TransactionBilling
+	err := database.DB.Where(constants.FilterOrderId, orderID).Order("id ASC").Find(&transactions).Error
+	return transactions, err
+}
+
+// TransactionBillingExists tells whether a transaction was recorded for the gateway order.
+func TransactionBillingExists(orderID string) (bool, error) {
+	var count int64
+	err := database.DB.Model(&models.TransactionBilling{}).Where(constants.FilterOrderId, orderID).Count(&count).Error
+	return count > 0, err
+}
+
 func GetMidtransPaymentLogByOrderId(orderID string) (*models.MidtransPaymentLog, error) {
 	var midtransPaymentLog *models.MidtransPaymentLog
 
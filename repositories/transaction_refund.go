@@ -0,0 +1,424 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"schoolPayment/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	RefundStatusWaitingApproval = "RS01"
+	RefundStatusSuccess         = "RS02"
+	RefundStatusRejected        = "RS03"
+	RefundStatusFailed          = "RS04"
+	RefundStatusProcessing      = "RS05"
+
+	RefundTypeFull    = "full"
+	RefundTypePartial = "partial"
+
+	TransactionStatusRefunded          = "PS04"
+	TransactionStatusPartiallyRefunded = "PS05"
+)
+
+type TransactionRefundRepository interface {
+	GetTransactionBillingByID(id uint, schoolID uint) (*models.TransactionBilling, error)
+	GetPaidAmountByTransaction(transactionBillingID uint, billingStudentIds []int) (map[uint]int64, error)
+	GetRefundableAmount(transaction *models.TransactionBilling, billingStudentIds []int) (RefundableAmount, error)
+	GetRefundedBillingStudentIds(transactionBillingID uint) ([]int, error)
+	GetBillingStudentsByIds(billingStudentIds []int) ([]models.BillingStudent, error)
+	CreateRefund(refund *models.TransactionRefund) (*models.TransactionRefund, error)
+	GetRefundByID(id uint, schoolID uint) (*models.TransactionRefund, error)
+	ClaimRefund(id uint, refundStatus string, userID int) (bool, error)
+	ReleaseRefund(id uint, userID int) error
+	GetAllRefund(page int, limit int, refundStatus string, schoolID uint) ([]models.TransactionRefund, int64, error)
+	UpdateRefund(refund *models.TransactionRefund) error
+	ApplyRefund(refund *models.TransactionRefund, userID int) error
+}
+
+type transactionRefundRepository struct {
+	db *gorm.DB
+}
+
+func NewTransactionRefundRepository(db *gorm.DB) TransactionRefundRepository {
+	return &transactionRefundRepository{db: db}
+}
+
+func (r *transactionRefundRepository) GetTransactionBillingByID(id uint, schoolID uint) (*models.TransactionBilling, error) {
+	var transaction models.TransactionBilling
+	err := r.db.Table("transaction_billings tb").
+		Select("tb.*").
+		Joins("JOIN students s ON s.id = tb.student_id").
+		Joins("JOIN school_classes sc ON sc.id = s.school_class_id").
+		Where("tb.id = ? AND sc.school_id = ? AND tb.deleted_at IS NULL", id, schoolID).
+		Take(&transaction).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// GetPaidAmountByTransaction sums the ledger entries a transaction has booked per installment,
+// so refunds never give back more than the transaction actually paid.
+func (r *transactionRefundRepository) GetPaidAmountByTransaction(transactionBillingID uint, billingStudentIds []int) (map[uint]int64, error) {
+	return getPaidAmountByTransaction(r.db, transactionBillingID, billingStudentIds)
+}
+
+func getPaidAmountByTransaction(db *gorm.DB, transactionBillingID uint, billingStudentIds []int) (map[uint]int64, error) {
+	var rows []struct {
+		BillingStudentID uint
+		Amount           int64
+	}
+	err := db.Model(&models.BillingStudentPayment{}).
+		Select("billing_student_id, SUM(amount) AS amount").
+		Where("transaction_billing_id = ? AND billing_student_id IN ? AND deleted_at IS NULL", transactionBillingID, billingStudentIds).
+		Group("billing_student_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	paidAmounts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		paidAmounts[row.BillingStudentID] = row.Amount
+	}
+	return paidAmounts, nil
+}
+
+// RefundableAmount is what refunding some installments of a transaction gives back. Reversed is
+// what the installments booked on the transaction, Cash and Credit are the money and the student
+// credit that actually paid for it, so a discount is never paid out.
+type RefundableAmount struct {
+	Reversed int64
+	Cash     int64
+	Credit   int64
+}
+
+// InstallmentAmount is what a transaction booked on one installment.
+type InstallmentAmount struct {
+	BillingStudentID uint
+	Amount           int64
+}
+
+// GetRefundableAmount splits the money and credit a transaction received over all of its
+// installments and returns the share of the given ones.
+func (r *transactionRefundRepository) GetRefundableAmount(transaction *models.TransactionBilling, billingStudentIds []int) (RefundableAmount, error) {
+	var booked []InstallmentAmount
+	err := r.db.Model(&models.BillingStudentPayment{}).
+		Select("billing_student_id, SUM(amount) AS amount").
+		Where("transaction_billing_id = ? AND amount > 0 AND deleted_at IS NULL", transaction.ID).
+		Group("billing_student_id").
+		Order("billing_student_id").
+		Scan(&booked).Error
+	if err != nil {
+		return RefundableAmount{}, err
+	}
+
+	// Change handed back and overpayment kept as credit never paid for the installments
+	var received struct {
+		ChangeAmount int64
+		Overpayment  int64
+	}
+	err = r.db.Raw(`
+		SELECT
+			(SELECT COALESCE(SUM(change_amount), 0) FROM transaction_billing_details
+				WHERE transaction_billing_id = ? AND deleted_at IS NULL) AS change_amount,
+			(SELECT COALESCE(SUM(amount), 0) FROM student_credits
+				WHERE transaction_billing_id = ? AND entry_type = ? AND deleted_at IS NULL) AS overpayment
+	`, transaction.ID, transaction.ID, StudentCreditTypeOverpayment).Scan(&received).Error
+	if err != nil {
+		return RefundableAmount{}, err
+	}
+
+	cash := int64(transaction.TotalAmount) - received.ChangeAmount - received.Overpayment
+	return AllocateRefundableAmount(booked, billingStudentIds, cash, transaction.CreditAmount), nil
+}
+
+// AllocateRefundableAmount spreads cash and credit over the booked installments in proportion to
+// their amount. Each installment gets the difference of the running totals, so refunding all
+// installments one by one gives back exactly cash and credit.
+func AllocateRefundableAmount(booked []InstallmentAmount, billingStudentIds []int, cash int64, credit int64) RefundableAmount {
+	var total int64
+	for _, installment := range booked {
+		total += installment.Amount
+	}
+	if total <= 0 {
+		return RefundableAmount{}
+	}
+
+	if credit > total {
+		credit = total
+	}
+	if cash < 0 {
+		cash = 0
+	}
+	if cash > total-credit {
+		cash = total - credit
+	}
+
+	selected := make(map[uint]bool, len(billingStudentIds))
+	for _, id := range billingStudentIds {
+		selected[uint(id)] = true
+	}
+
+	var refundable RefundableAmount
+	var running, cashBefore, creditBefore int64
+	for _, installment := range booked {
+		running += installment.Amount
+		cashAfter := cash * running / total
+		creditAfter := credit * running / total
+		if selected[installment.BillingStudentID] {
+			refundable.Reversed += installment.Amount
+			refundable.Cash += cashAfter - cashBefore
+			refundable.Credit += creditAfter - creditBefore
+		}
+		cashBefore, creditBefore = cashAfter, creditAfter
+	}
+	return refundable
+}
+
+// GetRefundedBillingStudentIds returns installments already covered by a pending, processing or successful refund.
+func (r *transactionRefundRepository) GetRefundedBillingStudentIds(transactionBillingID uint) ([]int, error) {
+	var refunds []models.TransactionRefund
+	err := r.db.Where("transaction_billing_id = ? AND refund_status IN ? AND deleted_at IS NULL",
+		transactionBillingID, []string{RefundStatusWaitingApproval, RefundStatusProcessing, RefundStatusSuccess}).
+		Find(&refunds).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	for _, refund := range refunds {
+		for _, idStr := range strings.Split(refund.BillingStudentIds, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(idStr))
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (r *transactionRefundRepository) GetBillingStudentsByIds(billingStudentIds []int) ([]models.BillingStudent, error) {
+	return GetBillingStudentsForPayment(r.db, billingStudentIds)
+}
+
+func (r *transactionRefundRepository) CreateRefund(refund *models.TransactionRefund) (*models.TransactionRefund, error) {
+	result := r.db.Omit("TransactionBilling").Create(refund)
+	return refund, result.Error
+}
+
+func (r *transactionRefundRepository) GetRefundByID(id uint, schoolID uint) (*models.TransactionRefund, error) {
+	var refund models.TransactionRefund
+	err := r.db.Preload("TransactionBilling").
+		Where("id = ? AND school_id = ? AND deleted_at IS NULL", id, schoolID).
+		First(&refund).Error
+	if err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// ClaimRefund moves a refund waiting for approval to the given status in one conditional update,
+// so of two approvers acting at the same time only one gets true back and goes on to pay out.
+func (r *transactionRefundRepository) ClaimRefund(id uint, refundStatus string, userID int) (bool, error) {
+	result := r.db.Model(&models.TransactionRefund{}).
+		Where("id = ? AND refund_status = ? AND deleted_at IS NULL", id, RefundStatusWaitingApproval).
+		Updates(map[string]interface{}{
+			"refund_status": refundStatus,
+			"updated_by":    userID,
+			"updated_at":    time.Now(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ReleaseRefund puts a refund that is being processed back to waiting for approval, for when
+// it failed before anything was paid out.
+func (r *transactionRefundRepository) ReleaseRefund(id uint, userID int) error {
+	return r.db.Model(&models.TransactionRefund{}).
+		Where("id = ? AND refund_status = ? AND deleted_at IS NULL", id, RefundStatusProcessing).
+		Updates(map[string]interface{}{
+			"refund_status": RefundStatusWaitingApproval,
+			"updated_by":    userID,
+			"updated_at":    time.Now(),
+		}).Error
+}
+
+func (r *transactionRefundRepository) GetAllRefund(page int, limit int, refundStatus string, schoolID uint) ([]models.TransactionRefund, int64, error) {
+	var refunds []models.TransactionRefund
+	var total int64
+
+	query := r.db.Model(&models.TransactionRefund{}).Where("deleted_at IS NULL")
+	if refundStatus != "" {
+		query = query.Where("refund_status = ?", refundStatus)
+	}
+	if schoolID != 0 {
+		query = query.Where("school_id = ?", schoolID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if limit != 0 {
+		query = query.Offset((page - 1) * limit).Limit(limit)
+	}
+
+	err := query.Preload("TransactionBilling").Order("created_at DESC").Find(&refunds).Error
+	return refunds, total, err
+}
+
+func (r *transactionRefundRepository) UpdateRefund(refund *models.TransactionRefund) error {
+	return r.db.Omit("TransactionBilling").Save(refund).Error
+}
+
+// ApplyRefund books negative ledger entries for the refunded installments, reverts their
+// payment status and moves the transaction to a refunded status with a history row. The part paid
+// with student credit, or the whole amount for a refund to credit, goes back to the credit balance.
+func (r *transactionRefundRepository) ApplyRefund(refund *models.TransactionRefund, userID int) error {
+	var billingStudentIds []int
+	for _, idStr := range strings.Split(refund.BillingStudentIds, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(idStr))
+		if err != nil {
+			return err
+		}
+		billingStudentIds = append(billingStudentIds, id)
+	}
+
+	paidAmounts, err := r.GetPaidAmountByTransaction(refund.TransactionBillingID, billingStudentIds)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Only a refund still being processed is booked, so two retries cannot book it twice
+		var processing models.TransactionRefund
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND refund_status = ? AND deleted_at IS NULL", refund.ID, RefundStatusProcessing).
+			First(&processing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("refund has already been processed")
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := reverseBillingStudentPayments(tx, refund.TransactionBillingID, billingStudentIds, paidAmounts, userID); err != nil {
+			return err
+		}
+
+		var transaction models.TransactionBilling
+		if err := tx.Where("id = ?", refund.TransactionBillingID).First(&transaction).Error; err != nil {
+			return err
+		}
+
+		transaction.TransactionStatus = TransactionStatusPartiallyRefunded
+		if refund.RefundType == RefundTypeFull {
+			transaction.TransactionStatus = TransactionStatusRefunded
+		}
+		transaction.UpdatedBy = userID
+		if err := tx.Save(&transaction).Error; err != nil {
+			return err
+		}
+
+		history := models.TransactionBillingHistory{
+			TransactionBillingId: transaction.ID,
+			OrderID:              transaction.OrderID,
+			ReferenceNumber:      transaction.ReferenceNumber,
+			TransactionStatus:    transaction.TransactionStatus,
+			InvoiceNumber:        transaction.InvoiceNumber,
+		}
+		history.CreatedBy = userID
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+
+		// Credit spent on the transaction always goes back to credit, the money only when asked for
+		creditAmount := refund.CreditAmount
+		if refund.ToCredit {
+			creditAmount = refund.Amount
+		}
+		if creditAmount > 0 {
+			credit := models.StudentCredit{
+				StudentID:            transaction.StudentID,
+				EntryType:            StudentCreditTypeRefund,
+				Amount:               creditAmount,
+				TransactionBillingID: &transaction.ID,
+				TransactionRefundID:  &refund.ID,
+				Note:                 refund.Reason,
+			}
+			credit.CreatedBy = userID
+			if err := RecordStudentCredit(tx, &credit); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		refund.RefundStatus = RefundStatusSuccess
+		refund.ApprovedBy = &userID
+		refund.ApprovedAt = &now
+		refund.UpdatedBy = userID
+		if err := tx.Omit("TransactionBilling").Save(refund).Error; err != nil {
+			return fmt.Errorf("failed to update refund: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// reverseBillingStudentPayments books negative ledger entries for what a transaction paid on
+// the installments and moves them back to unpaid, or partially paid when other payments remain.
+func reverseBillingStudentPayments(tx *gorm.DB, transactionBillingID uint, billingStudentIds []int, paidAmounts map[uint]int64, userID int) error {
+	billingStudents, err := GetBillingStudentsForPayment(tx, billingStudentIds)
+	if err != nil {
+		return err
+	}
+
+	for _, billingStudent := range billingStudents {
+		amount := paidAmounts[billingStudent.ID]
+		if amount <= 0 {
+			continue
+		}
+
+		payment := models.BillingStudentPayment{
+			BillingStudentID:     billingStudent.ID,
+			TransactionBillingID: transactionBillingID,
+			Amount:               -amount,
+		}
+		payment.CreatedBy = userID
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+
+		paidAmount := billingStudent.PaidAmount - amount
+		if paidAmount < 0 {
+			paidAmount = 0
+		}
+		paymentStatus := BillingStudentStatusUnpaid
+		if paidAmount > 0 {
+			paymentStatus = BillingStudentStatusPartiallyPaid
+		}
+
+		err := tx.Model(&models.BillingStudent{}).
+			Where("id = ?", billingStudent.ID).
+			Updates(map[string]interface{}{
+				"paid_amount":    paidAmount,
+				"payment_status": paymentStatus,
+				"updated_by":     userID,
+			}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
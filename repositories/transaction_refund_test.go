@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllocateRefundableAmount(t *testing.T) {
+	booked := []InstallmentAmount{
+		{BillingStudentID: 11, Amount: 100000},
+		{BillingStudentID: 12, Amount: 100000},
+		{BillingStudentID: 13, Amount: 100000},
+	}
+
+	t.Run("Discounted payment partly paid with credit", func(t *testing.T) {
+		// 300.000 less a 10% discount, 50.000 of it paid with credit
+		refundable := AllocateRefundableAmount(booked, []int{12}, 220000, 50000)
+
+		assert.Equal(t, RefundableAmount{Reversed: 100000, Cash: 73333, Credit: 16667}, refundable)
+	})
+
+	t.Run("Refunding every installment gives back exactly what was received", func(t *testing.T) {
+		var total RefundableAmount
+		for _, id := range []int{13, 11, 12} {
+			refundable := AllocateRefundableAmount(booked, []int{id}, 220000, 50000)
+			total.Reversed += refundable.Reversed
+			total.Cash += refundable.Cash
+			total.Credit += refundable.Credit
+		}
+
+		assert.Equal(t, RefundableAmount{Reversed: 300000, Cash: 220000, Credit: 50000}, total)
+	})
+
+	t.Run("Money above the installments is never refunded", func(t *testing.T) {
+		refundable := AllocateRefundableAmount(booked, []int{11, 12, 13}, 350000, 0)
+
+		assert.Equal(t, RefundableAmount{Reversed: 300000, Cash: 300000}, refundable)
+	})
+
+	t.Run("Nothing booked", func(t *testing.T) {
+		assert.Equal(t, RefundableAmount{}, AllocateRefundableAmount(nil, []int{11}, 100000, 0))
+	})
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"testing"
+
+	"schoolPayment/dtos/request"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTransactionStatusRegression(t *testing.T) {
+	assert.False(t, IsTransactionStatusRegression("PS01", "PS02"))
+	assert.False(t, IsTransactionStatusRegression("PS01", "PS03"))
+	assert.False(t, IsTransactionStatusRegression("PS02", "PS02"))
+	// A settlement arriving after the order expired is not backwards, see checkTransactionStatusChange
+	assert.False(t, IsTransactionStatusRegression("PS03", "PS02"))
+
+	assert.True(t, IsTransactionStatusRegression("PS02", "PS01"))
+	assert.True(t, IsTransactionStatusRegression("PS02", "PS03"))
+	assert.True(t, IsTransactionStatusRegression("PS03", "PS01"))
+	assert.True(t, IsTransactionStatusRegression(TransactionStatusRefunded, "PS02"))
+	assert.True(t, IsTransactionStatusRegression(TransactionStatusPartiallyRefunded, "PS03"))
+}
+
+func TestCheckTransactionStatusChange(t *testing.T) {
+	assert.NoError(t, checkTransactionStatusChange("PS01", "PS02"))
+	assert.NoError(t, checkTransactionStatusChange("PS01", "PS03"))
+	assert.NoError(t, checkTransactionStatusChange("PS02", "PS02"))
+	assert.NoError(t, checkTransactionStatusChange("PS03", "PS03"))
+
+	// The credit of a failed order was given back, so its late payment is not settled
+	assert.ErrorIs(t, checkTransactionStatusChange("PS03", "PS02"), ErrPaidAfterFailure)
+	assert.ErrorIs(t, checkTransactionStatusChange("PS02", "PS01"), ErrTransactionStatusRegression)
+	assert.ErrorIs(t, checkTransactionStatusChange(TransactionStatusVoid, "PS02"), ErrTransactionStatusRegression)
+}
+
+func TestMapWebhookTransactionStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload request.WebhookPayload
+		want    string
+	}{
+		{name: "Accepted capture", payload: request.WebhookPayload{TransactionStatus: "capture", FraudStatus: "accept"}, want: "PS02"},
+		{name: "Capture under review", payload: request.WebhookPayload{TransactionStatus: "capture", FraudStatus: "challenge"}, want: "PS01"},
+		{name: "Settlement", payload: request.WebhookPayload{TransactionStatus: "settlement"}, want: "PS02"},
+		{name: "Expire", payload: request.WebhookPayload{TransactionStatus: "expire"}, want: "PS03"},
+		{name: "Deny", payload: request.WebhookPayload{TransactionStatus: "deny"}, want: "PS03"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MapWebhookTransactionStatus(tt.payload)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
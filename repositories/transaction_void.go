@@ -0,0 +1,189 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"schoolPayment/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	VoidStatusWaitingApproval = "VS01"
+	VoidStatusApproved        = "VS02"
+	VoidStatusRejected        = "VS03"
+
+	TransactionStatusVoid = "PS06"
+)
+
+var ErrTransactionNotVoidable = errors.New("only paid kasir transactions can be voided")
+
+type TransactionVoidRepository interface {
+	GetTransactionBillingForSchool(id uint, schoolID uint) (*models.TransactionBilling, error)
+	HasWaitingVoid(transactionBillingID uint) (bool, error)
+	CreateVoid(transactionVoid *models.TransactionVoid) (*models.TransactionVoid, error)
+	GetVoidByID(id uint, schoolID uint) (*models.TransactionVoid, error)
+	GetAllVoid(page int, limit int, voidStatus string, schoolID uint) ([]models.TransactionVoid, int64, error)
+	UpdateVoid(transactionVoid *models.TransactionVoid) error
+	ApplyVoid(transactionVoid *models.TransactionVoid, userID int) error
+}
+
+type transactionVoidRepository struct {
+	db *gorm.DB
+}
+
+func NewTransactionVoidRepository(db *gorm.DB) TransactionVoidRepository {
+	return &transactionVoidRepository{db: db}
+}
+
+// GetTransactionBillingForSchool returns the transaction only when its student belongs to the school.
+func (r *transactionVoidRepository) GetTransactionBillingForSchool(id uint, schoolID uint) (*models.TransactionBilling, error) {
+	var transaction models.TransactionBilling
+	err := r.db.Table("transaction_billings tb").
+		Select("tb.*").
+		Joins("JOIN students s ON s.id = tb.student_id").
+		Joins("JOIN school_classes sc ON sc.id = s.school_class_id").
+		Where("tb.id = ? AND sc.school_id = ? AND tb.deleted_at IS NULL", id, schoolID).
+		Take(&transaction).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+func (r *transactionVoidRepository) HasWaitingVoid(transactionBillingID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.TransactionVoid{}).
+		Where("transaction_billing_id = ? AND void_status = ? AND deleted_at IS NULL", transactionBillingID, VoidStatusWaitingApproval).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *transactionVoidRepository) CreateVoid(transactionVoid *models.TransactionVoid) (*models.TransactionVoid, error) {
+	result := r.db.Omit("TransactionBilling").Create(transactionVoid)
+	return transactionVoid, result.Error
+}
+
+func (r *transactionVoidRepository) GetVoidByID(id uint, schoolID uint) (*models.TransactionVoid, error) {
+	var transactionVoid models.TransactionVoid
+	err := r.db.Preload("TransactionBilling").
+		Where("id = ? AND school_id = ? AND deleted_at IS NULL", id, schoolID).
+		First(&transactionVoid).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transactionVoid, nil
+}
+
+func (r *transactionVoidRepository) GetAllVoid(page int, limit int, voidStatus string, schoolID uint) ([]models.TransactionVoid, int64, error) {
+	var voids []models.TransactionVoid
+	var total int64
+
+	query := r.db.Model(&models.TransactionVoid{}).Where("school_id = ? AND deleted_at IS NULL", schoolID)
+	if voidStatus != "" {
+		query = query.Where("void_status = ?", voidStatus)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if limit != 0 {
+		query = query.Offset((page - 1) * limit).Limit(limit)
+	}
+
+	err := query.Preload("TransactionBilling").Order("created_at DESC").Find(&voids).Error
+	return voids, total, err
+}
+
+func (r *transactionVoidRepository) UpdateVoid(transactionVoid *models.TransactionVoid) error {
+	return r.db.Omit("TransactionBilling").Save(transactionVoid).Error
+}
+
+// ApplyVoid undoes a kasir payment: the installments go back to what they were before it, credit
+// the payment used or created is reversed and the transaction moves to the void status with a
+// history row. The transaction row is locked so a refund cannot be applied at the same time.
+func (r *transactionVoidRepository) ApplyVoid(transactionVoid *models.TransactionVoid, userID int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var transaction models.TransactionBilling
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND deleted_at IS NULL", transactionVoid.TransactionBillingID).
+			First(&transaction).Error
+		if err != nil {
+			return err
+		}
+		if transaction.TransactionType != "PT01" || transaction.TransactionStatus != "PS02" {
+			return ErrTransactionNotVoidable
+		}
+
+		var waitingRefunds int64
+		err = tx.Model(&models.TransactionRefund{}).
+			Where("transaction_billing_id = ? AND refund_status IN ? AND deleted_at IS NULL", transaction.ID, []string{RefundStatusWaitingApproval, RefundStatusProcessing}).
+			Count(&waitingRefunds).Error
+		if err != nil {
+			return err
+		}
+		if waitingRefunds > 0 {
+			return fmt.Errorf("transaction has a refund waiting for approval")
+		}
+
+		var billingStudentIds []int
+		for _, idStr := range strings.Split(transaction.BillingStudentIds, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(idStr))
+			if err != nil {
+				return err
+			}
+			billingStudentIds = append(billingStudentIds, id)
+		}
+
+		paidAmounts, err := getPaidAmountByTransaction(tx, transaction.ID, billingStudentIds)
+		if err != nil {
+			return err
+		}
+		if err := reverseBillingStudentPayments(tx, transaction.ID, billingStudentIds, paidAmounts, userID); err != nil {
+			return err
+		}
+
+		if err := ReverseStudentCreditForTransaction(tx, &transaction, transactionVoid.Reason, userID); err != nil {
+			if errors.Is(err, ErrInsufficientStudentCredit) {
+				return fmt.Errorf("credit from this payment has already been used, the transaction cannot be voided")
+			}
+			return err
+		}
+
+		transaction.TransactionStatus = TransactionStatusVoid
+		transaction.UpdatedBy = userID
+		if err := tx.Save(&transaction).Error; err != nil {
+			return err
+		}
+
+		history := models.TransactionBillingHistory{
+			TransactionBillingId: transaction.ID,
+			OrderID:              transaction.OrderID,
+			ReferenceNumber:      transaction.ReferenceNumber,
+			TransactionStatus:    transaction.TransactionStatus,
+			InvoiceNumber:        transaction.InvoiceNumber,
+		}
+		history.CreatedBy = userID
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		transactionVoid.VoidStatus = VoidStatusApproved
+		transactionVoid.ApprovedBy = &userID
+		transactionVoid.ApprovedAt = &now
+		transactionVoid.UpdatedBy = userID
+		if err := tx.Omit("TransactionBilling").Save(transactionVoid).Error; err != nil {
+			return fmt.Errorf("failed to update void: %w", err)
+		}
+
+		transactionVoid.TransactionBilling = transaction
+		return nil
+	})
+}
@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"testing"
+
+	"schoolPayment/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyVoid_RejectsTransactionNoLongerPaid(t *testing.T) {
+	gormDB, mock := setupTestDB(t)
+	repo := NewTransactionVoidRepository(gormDB)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "transaction_billings" WHERE .* FOR UPDATE`).
+		WithArgs(11, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "transaction_type", "transaction_status"}).
+			AddRow(11, "PT01", TransactionStatusRefunded))
+	mock.ExpectRollback()
+
+	err := repo.ApplyVoid(&models.TransactionVoid{TransactionBillingID: 11}, 5)
+
+	assert.Equal(t, ErrTransactionNotVoidable, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReverseStudentCreditForTransaction(t *testing.T) {
+	gormDB, mock := setupTestDB(t)
+
+	transaction := &models.TransactionBilling{StudentID: 7}
+	transaction.ID = 11
+
+	t.Run("Nothing booked", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM "student_credits" WHERE transaction_billing_id = \$1`).
+			WithArgs(11).
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(0))
+
+		err := ReverseStudentCreditForTransaction(gormDB, transaction, "Salah input", 5)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Overpayment already spent", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM "student_credits" WHERE transaction_billing_id = \$1`).
+			WithArgs(11).
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(20000))
+		mock.ExpectQuery(`SELECT "id" FROM "students" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs(7, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM "student_credits" WHERE student_id = \$1`).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(5000))
+
+		err := ReverseStudentCreditForTransaction(gormDB, transaction, "Salah input", 5)
+
+		assert.Equal(t, ErrInsufficientStudentCredit, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
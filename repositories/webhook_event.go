@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"time"
+
+	"schoolPayment/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	WebhookEventStatusReceived   = "WS01"
+	WebhookEventStatusProcessing = "WS02"
+	WebhookEventStatusProcessed  = "WS03"
+	WebhookEventStatusFailed     = "WS04"
+
+	// WebhookEventProcessingTimeout is how long an event may stay in processing before it is taken
+	// as stuck, e.g. after a crash or a timeout, and may be claimed again
+	WebhookEventProcessingTimeout = 15 * time.Minute
+)
+
+type WebhookEventRepository interface {
+	CreateOrGetWebhookEvent(event *models.WebhookEvent) (*models.WebhookEvent, bool, error)
+	ClaimWebhookEvent(id uint) (bool, error)
+	MarkWebhookEventProcessed(id uint) error
+	MarkWebhookEventFailed(id uint, errorMessage string) error
+	HasProcessedWebhookEventForStatus(orderID string, paymentStatus string, excludeID uint) (bool, error)
+	GetAllWebhookEvent(page int, limit int, processStatus string, orderID string) ([]models.WebhookEvent, int64, error)
+	GetWebhookEventByID(id uint) (*models.WebhookEvent, error)
+}
+
+type webhookEventRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookEventRepository(db *gorm.DB) WebhookEventRepository {
+	return &webhookEventRepository{db: db}
+}
+
+// CreateOrGetWebhookEvent stores the event unless one with the same key already exists.
+// The returned bool is true when the event was newly created.
+func (r *webhookEventRepository) CreateOrGetWebhookEvent(event *models.WebhookEvent) (*models.WebhookEvent, bool, error) {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "event_key"}},
+		DoNothing: true,
+	}).Create(event)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return event, true, nil
+	}
+
+	var existing models.WebhookEvent
+	if err := r.db.Where("event_key = ?", event.EventKey).First(&existing).Error; err != nil {
+		return nil, false, err
+	}
+	return &existing, false, nil
+}
+
+// IsWebhookEventStuck tells whether the event has been processing for longer than any run takes.
+func IsWebhookEventStuck(event *models.WebhookEvent, now time.Time) bool {
+	return event.ProcessStatus == WebhookEventStatusProcessing && event.UpdatedAt.Before(now.Add(-WebhookEventProcessingTimeout))
+}
+
+// ClaimWebhookEvent moves a received, failed or stuck event to processing. Only one caller can
+// win the claim, so concurrent retries of the same notification never run side effects twice.
+func (r *webhookEventRepository) ClaimWebhookEvent(id uint) (bool, error) {
+	now := time.Now()
+	result := r.db.Model(&models.WebhookEvent{}).
+		Where("id = ? AND (process_status IN ? OR (process_status = ? AND updated_at < ?))",
+			id, []string{WebhookEventStatusReceived, WebhookEventStatusFailed},
+			WebhookEventStatusProcessing, now.Add(-WebhookEventProcessingTimeout)).
+		Updates(map[string]interface{}{
+			"process_status": WebhookEventStatusProcessing,
+			"attempts":       gorm.Expr("attempts + 1"),
+			"updated_at":     now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *webhookEventRepository) MarkWebhookEventProcessed(id uint) error {
+	now := time.Now()
+	return r.db.Model(&models.WebhookEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"process_status": WebhookEventStatusProcessed,
+			"error_message":  "",
+			"processed_at":   now,
+			"updated_at":     now,
+		}).Error
+}
+
+func (r *webhookEventRepository) MarkWebhookEventFailed(id uint, errorMessage string) error {
+	return r.db.Model(&models.WebhookEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"process_status": WebhookEventStatusFailed,
+			"error_message":  errorMessage,
+			"updated_at":     time.Now(),
+		}).Error
+}
+
+// HasProcessedWebhookEventForStatus reports whether another event of the order already moved
+// the transaction to the given payment status, e.g. a "capture" followed by a "settlement".
+func (r *webhookEventRepository) HasProcessedWebhookEventForStatus(orderID string, paymentStatus string, excludeID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.WebhookEvent{}).
+		Where("order_id = ? AND payment_status = ? AND process_status = ? AND id <> ? AND deleted_at IS NULL",
+			orderID, paymentStatus, WebhookEventStatusProcessed, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *webhookEventRepository) GetAllWebhookEvent(page int, limit int, processStatus string, orderID string) ([]models.WebhookEvent, int64, error) {
+	var events []models.WebhookEvent
+	var total int64
+
+	query := r.db.Model(&models.WebhookEvent{}).Where("deleted_at IS NULL")
+	if processStatus != "" {
+		query = query.Where("process_status = ?", processStatus)
+	}
+	if orderID != "" {
+		query = query.Where("order_id ILIKE ?", "%"+orderID+"%")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if limit != 0 {
+		query = query.Offset((page - 1) * limit).Limit(limit)
+	}
+
+	err := query.Order("created_at DESC").Find(&events).Error
+	return events, total, err
+}
+
+func (r *webhookEventRepository) GetWebhookEventByID(id uint) (*models.WebhookEvent, error) {
+	var event models.WebhookEvent
+	err := r.db.Where("id = ? AND deleted_at IS NULL", id).First(&event).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
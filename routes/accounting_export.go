@@ -0,0 +1,19 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	utilities "schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupAccountingExportRoutes(api fiber.Router, accountingExportController *controllers.AccountingExportController) {
+	apiAccountingExport := api.Group("/accountingExport")
+	apiAccountingExport.Post("/mapping/create", utilities.JWTProtected, accountingExportController.CreateAccountingAccountMapping)
+	apiAccountingExport.Put("/mapping/update/:id", utilities.JWTProtected, accountingExportController.UpdateAccountingAccountMapping)
+	apiAccountingExport.Delete("/mapping/delete/:id", utilities.JWTProtected, accountingExportController.DeleteAccountingAccountMapping)
+	apiAccountingExport.Get("/mapping/getList", utilities.JWTProtected, accountingExportController.GetAccountingAccountMappings)
+	apiAccountingExport.Post("/create", utilities.JWTProtected, accountingExportController.CreateAccountingExport)
+	apiAccountingExport.Get("/getList", utilities.JWTProtected, accountingExportController.GetAllAccountingExport)
+	apiAccountingExport.Get("/download/:id", utilities.JWTProtected, accountingExportController.DownloadAccountingExport)
+}
@@ -0,0 +1,51 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetupAccountingExportRoutes(t *testing.T) {
+	app := fiber.New()
+	api := app.Group("/api/v1")
+
+	accountingExportController := &controllers.AccountingExportController{}
+
+	SetupAccountingExportRoutes(api, accountingExportController)
+
+	stack := app.Stack()
+	assert.NotEmpty(t, stack)
+
+	expectedRoutes := []struct {
+		method string
+		path   string
+	}{
+		{"POST", "/api/v1/accountingExport/mapping/create"},
+		{"PUT", "/api/v1/accountingExport/mapping/update/:id"},
+		{"DELETE", "/api/v1/accountingExport/mapping/delete/:id"},
+		{"GET", "/api/v1/accountingExport/mapping/getList"},
+		{"POST", "/api/v1/accountingExport/create"},
+		{"GET", "/api/v1/accountingExport/getList"},
+		{"GET", "/api/v1/accountingExport/download/:id"},
+	}
+
+	for _, expectedRoute := range expectedRoutes {
+		found := false
+		for _, routeStack := range stack {
+			for _, route := range routeStack {
+				if route.Method == expectedRoute.method && route.Path == expectedRoute.path {
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		assert.True(t, found, "Route %s %s should be registered",
+			expectedRoute.method, expectedRoute.path)
+	}
+}
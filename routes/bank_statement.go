@@ -0,0 +1,18 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	utilities "schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupBankStatementRoutes(api fiber.Router, bankStatementController *controllers.BankStatementController) {
+	apiBankStatement := api.Group("/bankStatement")
+	apiBankStatement.Post("/import", utilities.JWTProtected, bankStatementController.ImportBankStatement)
+	apiBankStatement.Get("/getList", utilities.JWTProtected, bankStatementController.GetAllBankStatementImport)
+	apiBankStatement.Get("/detail/:id", utilities.JWTProtected, bankStatementController.GetBankStatementImportByID)
+	apiBankStatement.Get("/line/getList", utilities.JWTProtected, bankStatementController.GetAllBankStatementLine)
+	apiBankStatement.Put("/line/confirm/:id", utilities.JWTProtected, bankStatementController.ConfirmBankStatementLine)
+	apiBankStatement.Put("/line/ignore/:id", utilities.JWTProtected, bankStatementController.IgnoreBankStatementLine)
+}
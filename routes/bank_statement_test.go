@@ -0,0 +1,50 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetupBankStatementRoutes(t *testing.T) {
+	app := fiber.New()
+	api := app.Group("/api/v1")
+
+	bankStatementController := &controllers.BankStatementController{}
+
+	SetupBankStatementRoutes(api, bankStatementController)
+
+	stack := app.Stack()
+	assert.NotEmpty(t, stack)
+
+	expectedRoutes := []struct {
+		method string
+		path   string
+	}{
+		{"POST", "/api/v1/bankStatement/import"},
+		{"GET", "/api/v1/bankStatement/getList"},
+		{"GET", "/api/v1/bankStatement/detail/:id"},
+		{"GET", "/api/v1/bankStatement/line/getList"},
+		{"PUT", "/api/v1/bankStatement/line/confirm/:id"},
+		{"PUT", "/api/v1/bankStatement/line/ignore/:id"},
+	}
+
+	for _, expectedRoute := range expectedRoutes {
+		found := false
+		for _, routeStack := range stack {
+			for _, route := range routeStack {
+				if route.Method == expectedRoute.method && route.Path == expectedRoute.path {
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		assert.True(t, found, "Route %s %s should be registered",
+			expectedRoute.method, expectedRoute.path)
+	}
+}
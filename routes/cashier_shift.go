@@ -0,0 +1,19 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	utilities "schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupCashierShiftRoutes(api fiber.Router, cashierShiftController *controllers.CashierShiftController) {
+	apiCashierShift := api.Group("/cashierShift")
+	apiCashierShift.Post("/open", utilities.JWTProtected, cashierShiftController.OpenCashierShift)
+	apiCashierShift.Get("/current", utilities.JWTProtected, cashierShiftController.GetCurrentCashierShift)
+	apiCashierShift.Put("/close/:id", utilities.JWTProtected, cashierShiftController.CloseCashierShift)
+	apiCashierShift.Get("/getList", utilities.JWTProtected, cashierShiftController.GetAllCashierShift)
+	apiCashierShift.Get("/detail/:id", utilities.JWTProtected, cashierShiftController.GetCashierShiftReport)
+	apiCashierShift.Get("/exportPdf/:id", utilities.JWTProtected, cashierShiftController.ExportCashierShiftPDF)
+	apiCashierShift.Get("/exportExcel/:id", utilities.JWTProtected, cashierShiftController.ExportCashierShiftExcel)
+}
@@ -0,0 +1,51 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetupCashierShiftRoutes(t *testing.T) {
+	app := fiber.New()
+	api := app.Group("/api/v1")
+
+	cashierShiftController := &controllers.CashierShiftController{}
+
+	SetupCashierShiftRoutes(api, cashierShiftController)
+
+	stack := app.Stack()
+	assert.NotEmpty(t, stack)
+
+	expectedRoutes := []struct {
+		method string
+		path   string
+	}{
+		{"POST", "/api/v1/cashierShift/open"},
+		{"GET", "/api/v1/cashierShift/current"},
+		{"PUT", "/api/v1/cashierShift/close/:id"},
+		{"GET", "/api/v1/cashierShift/getList"},
+		{"GET", "/api/v1/cashierShift/detail/:id"},
+		{"GET", "/api/v1/cashierShift/exportPdf/:id"},
+		{"GET", "/api/v1/cashierShift/exportExcel/:id"},
+	}
+
+	for _, expectedRoute := range expectedRoutes {
+		found := false
+		for _, routeStack := range stack {
+			for _, route := range routeStack {
+				if route.Method == expectedRoute.method && route.Path == expectedRoute.path {
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		assert.True(t, found, "Route %s %s should be registered",
+			expectedRoute.method, expectedRoute.path)
+	}
+}
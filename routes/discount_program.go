@@ -0,0 +1,24 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	utilities "schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupDiscountProgramRoutes(api fiber.Router, discountProgramController *controllers.DiscountProgramController) {
+	apiDiscountProgram := api.Group("/discountProgram")
+	apiDiscountProgram.Post("/create", utilities.JWTProtected, discountProgramController.CreateDiscountProgram)
+	apiDiscountProgram.Put("/update/:id", utilities.JWTProtected, discountProgramController.UpdateDiscountProgram)
+	apiDiscountProgram.Delete("/delete/:id", utilities.JWTProtected, discountProgramController.DeleteDiscountProgram)
+	apiDiscountProgram.Get("/getList", utilities.JWTProtected, discountProgramController.GetAllDiscountProgram)
+	apiDiscountProgram.Get("/detail/:id", utilities.JWTProtected, discountProgramController.GetDiscountProgramByID)
+
+	apiStudentDiscount := api.Group("/studentDiscount")
+	apiStudentDiscount.Post("/create", utilities.JWTProtected, discountProgramController.CreateStudentDiscount)
+	apiStudentDiscount.Put("/approve/:id", utilities.JWTProtected, discountProgramController.ApproveStudentDiscount)
+	apiStudentDiscount.Put("/reject/:id", utilities.JWTProtected, discountProgramController.RejectStudentDiscount)
+	apiStudentDiscount.Get("/getList", utilities.JWTProtected, discountProgramController.GetAllStudentDiscount)
+	apiStudentDiscount.Get("/detail/:id", utilities.JWTProtected, discountProgramController.GetStudentDiscountByID)
+}
@@ -0,0 +1,54 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetupDiscountProgramRoutes(t *testing.T) {
+	app := fiber.New()
+	api := app.Group("/api/v1")
+
+	discountProgramController := &controllers.DiscountProgramController{}
+
+	SetupDiscountProgramRoutes(api, discountProgramController)
+
+	stack := app.Stack()
+	assert.NotEmpty(t, stack)
+
+	expectedRoutes := []struct {
+		method string
+		path   string
+	}{
+		{"POST", "/api/v1/discountProgram/create"},
+		{"PUT", "/api/v1/discountProgram/update/:id"},
+		{"DELETE", "/api/v1/discountProgram/delete/:id"},
+		{"GET", "/api/v1/discountProgram/getList"},
+		{"GET", "/api/v1/discountProgram/detail/:id"},
+		{"POST", "/api/v1/studentDiscount/create"},
+		{"PUT", "/api/v1/studentDiscount/approve/:id"},
+		{"PUT", "/api/v1/studentDiscount/reject/:id"},
+		{"GET", "/api/v1/studentDiscount/getList"},
+		{"GET", "/api/v1/studentDiscount/detail/:id"},
+	}
+
+	for _, expectedRoute := range expectedRoutes {
+		found := false
+		for _, routeStack := range stack {
+			for _, route := range routeStack {
+				if route.Method == expectedRoute.method && route.Path == expectedRoute.path {
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		assert.True(t, found, "Route %s %s should be registered",
+			expectedRoute.method, expectedRoute.path)
+	}
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	utilities "schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupJournalRoutes(api fiber.Router, journalController *controllers.JournalController) {
+	apiJournal := api.Group("/journal")
+	apiJournal.Get("/run", journalController.RunJournal)
+	apiJournal.Get("/account/getList", utilities.JWTProtected, journalController.GetLedgerAccounts)
+	apiJournal.Get("/getList", utilities.JWTProtected, journalController.GetAllJournalEntry)
+	apiJournal.Get("/detail/:id", utilities.JWTProtected, journalController.GetJournalEntryByID)
+	apiJournal.Get("/trialBalance", utilities.JWTProtected, journalController.GetTrialBalance)
+}
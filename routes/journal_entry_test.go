@@ -0,0 +1,49 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetupJournalRoutes(t *testing.T) {
+	app := fiber.New()
+	api := app.Group("/api/v1")
+
+	journalController := &controllers.JournalController{}
+
+	SetupJournalRoutes(api, journalController)
+
+	stack := app.Stack()
+	assert.NotEmpty(t, stack)
+
+	expectedRoutes := []struct {
+		method string
+		path   string
+	}{
+		{"GET", "/api/v1/journal/run"},
+		{"GET", "/api/v1/journal/account/getList"},
+		{"GET", "/api/v1/journal/getList"},
+		{"GET", "/api/v1/journal/detail/:id"},
+		{"GET", "/api/v1/journal/trialBalance"},
+	}
+
+	for _, expectedRoute := range expectedRoutes {
+		found := false
+		for _, routeStack := range stack {
+			for _, route := range routeStack {
+				if route.Method == expectedRoute.method && route.Path == expectedRoute.path {
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		assert.True(t, found, "Route %s %s should be registered",
+			expectedRoute.method, expectedRoute.path)
+	}
+}
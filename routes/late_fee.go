@@ -0,0 +1,18 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	utilities "schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupLateFeeRoutes(api fiber.Router, lateFeeController *controllers.LateFeeController) {
+	apiLateFee := api.Group("/lateFee")
+	apiLateFee.Get("/run", lateFeeController.RunLateFee)
+	apiLateFee.Post("/create", utilities.JWTProtected, lateFeeController.CreateLateFeeRule)
+	apiLateFee.Put("/update/:id", utilities.JWTProtected, lateFeeController.UpdateLateFeeRule)
+	apiLateFee.Delete("/delete/:id", utilities.JWTProtected, lateFeeController.DeleteLateFeeRule)
+	apiLateFee.Get("/getList", utilities.JWTProtected, lateFeeController.GetAllLateFeeRule)
+	apiLateFee.Get("/detail/:id", utilities.JWTProtected, lateFeeController.GetLateFeeRuleByID)
+}
@@ -0,0 +1,50 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetupLateFeeRoutes(t *testing.T) {
+	app := fiber.New()
+	api := app.Group("/api/v1")
+
+	lateFeeController := &controllers.LateFeeController{}
+
+	SetupLateFeeRoutes(api, lateFeeController)
+
+	stack := app.Stack()
+	assert.NotEmpty(t, stack)
+
+	expectedRoutes := []struct {
+		method string
+		path   string
+	}{
+		{"GET", "/api/v1/lateFee/run"},
+		{"POST", "/api/v1/lateFee/create"},
+		{"PUT", "/api/v1/lateFee/update/:id"},
+		{"DELETE", "/api/v1/lateFee/delete/:id"},
+		{"GET", "/api/v1/lateFee/getList"},
+		{"GET", "/api/v1/lateFee/detail/:id"},
+	}
+
+	for _, expectedRoute := range expectedRoutes {
+		found := false
+		for _, routeStack := range stack {
+			for _, route := range routeStack {
+				if route.Method == expectedRoute.method && route.Path == expectedRoute.path {
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		assert.True(t, found, "Route %s %s should be registered",
+			expectedRoute.method, expectedRoute.path)
+	}
+}
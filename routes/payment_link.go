@@ -0,0 +1,19 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	utilities "schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupPaymentLinkRoutes(api fiber.Router, paymentLinkController *controllers.PaymentLinkController) {
+	apiPaymentLink := api.Group("/paymentLink")
+	apiPaymentLink.Get("/getList/:studentId", utilities.JWTProtected, paymentLinkController.GetPaymentLinks)
+	apiPaymentLink.Post("/create", utilities.JWTProtected, paymentLinkController.CreatePaymentLink)
+	apiPaymentLink.Delete("/revoke/:id", utilities.JWTProtected, paymentLinkController.RevokePaymentLink)
+
+	// Opened by guardians without an account, the signed token is the access
+	apiPaymentLink.Get("/public/:token", paymentLinkController.GetPublicPaymentLink)
+	apiPaymentLink.Post("/public/:token/checkout", paymentLinkController.CheckoutPaymentLink)
+}
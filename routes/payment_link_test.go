@@ -0,0 +1,49 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetupPaymentLinkRoutes(t *testing.T) {
+	app := fiber.New()
+	api := app.Group("/api/v1")
+
+	paymentLinkController := &controllers.PaymentLinkController{}
+
+	SetupPaymentLinkRoutes(api, paymentLinkController)
+
+	stack := app.Stack()
+	assert.NotEmpty(t, stack)
+
+	expectedRoutes := []struct {
+		method string
+		path   string
+	}{
+		{"GET", "/api/v1/paymentLink/getList/:studentId"},
+		{"POST", "/api/v1/paymentLink/create"},
+		{"DELETE", "/api/v1/paymentLink/revoke/:id"},
+		{"GET", "/api/v1/paymentLink/public/:token"},
+		{"POST", "/api/v1/paymentLink/public/:token/checkout"},
+	}
+
+	for _, expectedRoute := range expectedRoutes {
+		found := false
+		for _, routeStack := range stack {
+			for _, route := range routeStack {
+				if route.Method == expectedRoute.method && route.Path == expectedRoute.path {
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		assert.True(t, found, "Route %s %s should be registered",
+			expectedRoute.method, expectedRoute.path)
+	}
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	utilities "schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupReconciliationRoutes(api fiber.Router, reconciliationController *controllers.ReconciliationController) {
+	apiReconciliation := api.Group("/reconciliation")
+	apiReconciliation.Get("/run", reconciliationController.RunReconciliation)
+	apiReconciliation.Get("/getList", utilities.JWTProtected, reconciliationController.GetAllReconciliationRun)
+	apiReconciliation.Get("/detail/:id", utilities.JWTProtected, reconciliationController.GetReconciliationRunByID)
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	utilities "schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupSchoolPaymentConfigRoutes(api fiber.Router, schoolPaymentConfigController *controllers.SchoolPaymentConfigController) {
+	apiSchoolPaymentConfig := api.Group("/schoolPaymentConfig")
+	apiSchoolPaymentConfig.Get("/detail", utilities.JWTProtected, schoolPaymentConfigController.GetSchoolPaymentConfig)
+	apiSchoolPaymentConfig.Put("/update", utilities.JWTProtected, schoolPaymentConfigController.UpdateSchoolPaymentConfig)
+	apiSchoolPaymentConfig.Get("/adminFee/getList", utilities.JWTProtected, schoolPaymentConfigController.GetSchoolPaymentMethodFees)
+	apiSchoolPaymentConfig.Put("/adminFee/save", utilities.JWTProtected, schoolPaymentConfigController.SaveSchoolPaymentMethodFee)
+	apiSchoolPaymentConfig.Delete("/adminFee/delete/:paymentMethodId", utilities.JWTProtected, schoolPaymentConfigController.DeleteSchoolPaymentMethodFee)
+}
@@ -0,0 +1,49 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetupSchoolPaymentConfigRoutes(t *testing.T) {
+	app := fiber.New()
+	api := app.Group("/api/v1")
+
+	schoolPaymentConfigController := &controllers.SchoolPaymentConfigController{}
+
+	SetupSchoolPaymentConfigRoutes(api, schoolPaymentConfigController)
+
+	stack := app.Stack()
+	assert.NotEmpty(t, stack)
+
+	expectedRoutes := []struct {
+		method string
+		path   string
+	}{
+		{"GET", "/api/v1/schoolPaymentConfig/detail"},
+		{"PUT", "/api/v1/schoolPaymentConfig/update"},
+		{"GET", "/api/v1/schoolPaymentConfig/adminFee/getList"},
+		{"PUT", "/api/v1/schoolPaymentConfig/adminFee/save"},
+		{"DELETE", "/api/v1/schoolPaymentConfig/adminFee/delete/:paymentMethodId"},
+	}
+
+	for _, expectedRoute := range expectedRoutes {
+		found := false
+		for _, routeStack := range stack {
+			for _, route := range routeStack {
+				if route.Method == expectedRoute.method && route.Path == expectedRoute.path {
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		assert.True(t, found, "Route %s %s should be registered",
+			expectedRoute.method, expectedRoute.path)
+	}
+}
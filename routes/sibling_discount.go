@@ -0,0 +1,18 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	utilities "schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupSiblingDiscountRoutes(api fiber.Router, siblingDiscountController *controllers.SiblingDiscountController) {
+	apiSiblingDiscount := api.Group("/siblingDiscount")
+	apiSiblingDiscount.Post("/create", utilities.JWTProtected, siblingDiscountController.CreateSiblingDiscountTier)
+	apiSiblingDiscount.Put("/update/:id", utilities.JWTProtected, siblingDiscountController.UpdateSiblingDiscountTier)
+	apiSiblingDiscount.Delete("/delete/:id", utilities.JWTProtected, siblingDiscountController.DeleteSiblingDiscountTier)
+	apiSiblingDiscount.Get("/getList", utilities.JWTProtected, siblingDiscountController.GetAllSiblingDiscountTier)
+	apiSiblingDiscount.Get("/detail/:id", utilities.JWTProtected, siblingDiscountController.GetSiblingDiscountTierByID)
+	apiSiblingDiscount.Post("/recalculate", utilities.JWTProtected, siblingDiscountController.RecalculateSiblingDiscount)
+}
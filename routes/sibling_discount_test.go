@@ -0,0 +1,50 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetupSiblingDiscountRoutes(t *testing.T) {
+	app := fiber.New()
+	api := app.Group("/api/v1")
+
+	siblingDiscountController := &controllers.SiblingDiscountController{}
+
+	SetupSiblingDiscountRoutes(api, siblingDiscountController)
+
+	stack := app.Stack()
+	assert.NotEmpty(t, stack)
+
+	expectedRoutes := []struct {
+		method string
+		path   string
+	}{
+		{"POST", "/api/v1/siblingDiscount/create"},
+		{"PUT", "/api/v1/siblingDiscount/update/:id"},
+		{"DELETE", "/api/v1/siblingDiscount/delete/:id"},
+		{"GET", "/api/v1/siblingDiscount/getList"},
+		{"GET", "/api/v1/siblingDiscount/detail/:id"},
+		{"POST", "/api/v1/siblingDiscount/recalculate"},
+	}
+
+	for _, expectedRoute := range expectedRoutes {
+		found := false
+		for _, routeStack := range stack {
+			for _, route := range routeStack {
+				if route.Method == expectedRoute.method && route.Path == expectedRoute.path {
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		assert.True(t, found, "Route %s %s should be registered",
+			expectedRoute.method, expectedRoute.path)
+	}
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	utilities "schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupStudentCreditRoutes(api fiber.Router, studentCreditController *controllers.StudentCreditController) {
+	apiStudentCredit := api.Group("/studentCredit")
+	apiStudentCredit.Get("/history/:studentId", utilities.JWTProtected, studentCreditController.GetStudentCreditHistory)
+	apiStudentCredit.Post("/adjust", utilities.JWTProtected, studentCreditController.AdjustStudentCredit)
+}
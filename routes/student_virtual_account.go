@@ -0,0 +1,15 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	utilities "schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupStudentVirtualAccountRoutes(api fiber.Router, studentVirtualAccountController *controllers.StudentVirtualAccountController) {
+	apiStudentVirtualAccount := api.Group("/studentVirtualAccount")
+	apiStudentVirtualAccount.Get("/getList/:studentId", utilities.JWTProtected, studentVirtualAccountController.GetStudentVirtualAccounts)
+	apiStudentVirtualAccount.Post("/issue", utilities.JWTProtected, studentVirtualAccountController.IssueStudentVirtualAccount)
+	apiStudentVirtualAccount.Delete("/revoke/:id", utilities.JWTProtected, studentVirtualAccountController.RevokeStudentVirtualAccount)
+}
@@ -11,10 +11,20 @@ func SetupTransactionRoutes(api fiber.Router, transactionController *controllers
 	apiTransaction := api.Group("/transaction")
 	apiTransaction.Post("/create", utilities.JWTProtected, transactionController.CreateTransaction)
 	apiTransaction.Post("/donation", utilities.JWTProtected, transactionController.PaymentDonation)
+	apiTransaction.Put("/cancel/:orderId", utilities.JWTProtected, transactionController.CancelPendingTransaction)
 
 	apiMidtrans := apiTransaction.Group("/midtrans")
 	apiMidtrans.Post("/payment", utilities.JWTProtected, transactionController.MidtransPayment)
+	apiMidtrans.Post("/charge", utilities.JWTProtected, transactionController.MidtransDirectPayment)
+	apiMidtrans.Post("/groupPayment", utilities.JWTProtected, transactionController.MidtransGroupPayment)
+	apiMidtrans.Post("/feePreview", utilities.JWTProtected, transactionController.PreviewAdminFee)
 	apiMidtrans.Get("/checkPayment", utilities.JWTProtected, controllers.MidtransCheckPayment)
 
 	api.Post("/webhook", transactionController.HandleWebhook)
+	api.Post("/webhook/:provider", transactionController.HandleGatewayWebhook)
+
+	apiWebhookEvent := api.Group("/webhookEvent")
+	apiWebhookEvent.Get("/getList", utilities.JWTProtected, transactionController.GetAllWebhookEvent)
+	apiWebhookEvent.Get("/detail/:id", utilities.JWTProtected, transactionController.GetWebhookEventByID)
+	apiWebhookEvent.Post("/replay/:id", utilities.JWTProtected, transactionController.ReplayWebhookEvent)
 }
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	utilities "schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupTransactionRefundRoutes(api fiber.Router, transactionRefundController *controllers.TransactionRefundController) {
+	apiRefund := api.Group("/refund")
+	apiRefund.Post("/create", utilities.JWTProtected, transactionRefundController.CreateRefund)
+	apiRefund.Put("/approve/:id", utilities.JWTProtected, transactionRefundController.ApproveRefund)
+	apiRefund.Put("/reject/:id", utilities.JWTProtected, transactionRefundController.RejectRefund)
+	apiRefund.Get("/getList", utilities.JWTProtected, transactionRefundController.GetAllRefund)
+	apiRefund.Get("/detail/:id", utilities.JWTProtected, transactionRefundController.GetRefundByID)
+}
@@ -31,9 +31,17 @@ func TestSetupTransactionRoutes(t *testing.T) {
 	}{
 		{"POST", "/api/v1/transaction/create"},               
 		{"POST", "/api/v1/transaction/donation"},             
+		{"PUT", "/api/v1/transaction/cancel/:orderId"},
 		{"POST", "/api/v1/transaction/midtrans/payment"},    
+		{"POST", "/api/v1/transaction/midtrans/charge"},
+		{"POST", "/api/v1/transaction/midtrans/groupPayment"},
+		{"POST", "/api/v1/transaction/midtrans/feePreview"},
 		{"GET", "/api/v1/transaction/midtrans/checkPayment"},
 		{"POST", "/api/v1/webhook"},                         
+		{"POST", "/api/v1/webhook/:provider"},
+		{"GET", "/api/v1/webhookEvent/getList"},
+		{"GET", "/api/v1/webhookEvent/detail/:id"},
+		{"POST", "/api/v1/webhookEvent/replay/:id"},
 	}
 
 	// Verify each expected route
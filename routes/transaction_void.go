@@ -0,0 +1,17 @@
+package routes
+
+import (
+	controllers "schoolPayment/controllers"
+	utilities "schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupTransactionVoidRoutes(api fiber.Router, transactionVoidController *controllers.TransactionVoidController) {
+	apiVoid := api.Group("/void")
+	apiVoid.Post("/create", utilities.JWTProtected, transactionVoidController.CreateVoid)
+	apiVoid.Put("/approve/:id", utilities.JWTProtected, transactionVoidController.ApproveVoid)
+	apiVoid.Put("/reject/:id", utilities.JWTProtected, transactionVoidController.RejectVoid)
+	apiVoid.Get("/getList", utilities.JWTProtected, transactionVoidController.GetAllVoid)
+	apiVoid.Get("/detail/:id", utilities.JWTProtected, transactionVoidController.GetVoidByID)
+}
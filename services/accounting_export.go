@@ -0,0 +1,572 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	request "schoolPayment/dtos/request"
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+	"schoolPayment/utilities"
+)
+
+const (
+	accountingExportFileCSV   = "csv"
+	accountingExportFileExcel = "xlsx"
+	accountingExportSheetName = "Jurnal"
+)
+
+// accountingExportLayout is the column layout of an accounting package's journal import.
+type accountingExportLayout struct {
+	Columns []accountingExportColumn
+}
+
+type accountingExportColumn struct {
+	Header string
+	Value  func(row response.AccountingExportRow) interface{}
+}
+
+var (
+	exportVoucherNumber = func(row response.AccountingExportRow) interface{} { return row.VoucherNumber }
+	exportDescription   = func(row response.AccountingExportRow) interface{} { return row.Description }
+	exportAccountCode   = func(row response.AccountingExportRow) interface{} { return row.AccountCode }
+	exportAccountName   = func(row response.AccountingExportRow) interface{} { return row.AccountName }
+	exportDebit         = func(row response.AccountingExportRow) interface{} { return row.Debit }
+	exportCredit        = func(row response.AccountingExportRow) interface{} { return row.Credit }
+)
+
+// exportDate formats the row date for a layout.
+func exportDate(layout string) func(row response.AccountingExportRow) interface{} {
+	return func(row response.AccountingExportRow) interface{} { return row.Date.Format(layout) }
+}
+
+var accountingExportLayouts = map[string]accountingExportLayout{
+	repositories.AccountingExportFormatAccurate: {
+		Columns: []accountingExportColumn{
+			{"No. Bukti", exportVoucherNumber},
+			{"Tanggal", exportDate("02/01/2006")},
+			{"Keterangan", exportDescription},
+			{"No. Akun", exportAccountCode},
+			{"Nama Akun", exportAccountName},
+			{"Debit", exportDebit},
+			{"Kredit", exportCredit},
+		},
+	},
+	repositories.AccountingExportFormatJurnal: {
+		Columns: []accountingExportColumn{
+			{"*Transaction Date", exportDate("02/01/2006")},
+			{"*Transaction No", exportVoucherNumber},
+			{"Memo", exportDescription},
+			{"*Account Code", exportAccountCode},
+			{"Description", exportAccountName},
+			{"*Debit", exportDebit},
+			{"*Credit", exportCredit},
+		},
+	},
+	repositories.AccountingExportFormatGeneric: {
+		Columns: []accountingExportColumn{
+			{"date", exportDate("2006-01-02")},
+			{"voucher_no", exportVoucherNumber},
+			{"description", exportDescription},
+			{"account_code", exportAccountCode},
+			{"account_name", exportAccountName},
+			{"debit", exportDebit},
+			{"credit", exportCredit},
+		},
+	},
+}
+
+// accountingVoucherTypes names the daily journal made of each kind of journal entry.
+// Within a day the journals follow the order of the events: billed, paid, refunded, voided.
+var accountingVoucherTypes = map[string]struct {
+	Order       int
+	Code        string
+	Description string
+}{
+	repositories.JournalSourceBillingStudent: {1, "TAG", "Tagihan siswa"},
+	repositories.JournalSourceTransaction:    {2, "BYR", "Penerimaan pembayaran"},
+	repositories.JournalSourceRefund:         {3, "RFD", "Refund pembayaran"},
+	repositories.JournalSourceVoid:           {4, "VOD", "Void pembayaran"},
+}
+
+type AccountingExportServiceInterface interface {
+	CreateAccountingAccountMapping(mappingRequest *request.AccountingAccountMappingRequest, userID int) (*models.AccountingAccountMapping, error)
+	UpdateAccountingAccountMapping(mappingID uint, mappingRequest *request.AccountingAccountMappingRequest, userID int) (*models.AccountingAccountMapping, error)
+	DeleteAccountingAccountMapping(mappingID uint, userID int) error
+	GetAccountingAccountMappings(userID int) ([]models.AccountingAccountMapping, error)
+	CreateAccountingExport(exportRequest *request.CreateAccountingExportRequest, userID int) (*bytes.Buffer, string, error)
+	GetAllAccountingExport(page int, limit int, userID int) (response.AccountingExportListResponse, error)
+	DownloadAccountingExport(exportID uint, fileType string, userID int) (*bytes.Buffer, string, error)
+}
+
+type AccountingExportService struct {
+	accountingExportRepository repositories.AccountingExportRepository
+	journalRepository          repositories.JournalRepository
+	userRepository             repositories.UserRepository
+}
+
+func NewAccountingExportService(accountingExportRepository repositories.AccountingExportRepository, journalRepository repositories.JournalRepository, userRepository repositories.UserRepository) AccountingExportServiceInterface {
+	return &AccountingExportService{
+		accountingExportRepository: accountingExportRepository,
+		journalRepository:          journalRepository,
+		userRepository:             userRepository,
+	}
+}
+
+func (accountingExportService *AccountingExportService) CreateAccountingAccountMapping(mappingRequest *request.AccountingAccountMappingRequest, userID int) (*models.AccountingAccountMapping, error) {
+	schoolID, err := accountingExportService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := &models.AccountingAccountMapping{SchoolID: schoolID}
+	if err := accountingExportService.fillAccountingAccountMapping(mapping, mappingRequest); err != nil {
+		return nil, err
+	}
+	mapping.CreatedBy = userID
+
+	return accountingExportService.accountingExportRepository.CreateAccountingAccountMapping(mapping)
+}
+
+func (accountingExportService *AccountingExportService) UpdateAccountingAccountMapping(mappingID uint, mappingRequest *request.AccountingAccountMappingRequest, userID int) (*models.AccountingAccountMapping, error) {
+	mapping, err := accountingExportService.getAccountingAccountMapping(mappingID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := accountingExportService.fillAccountingAccountMapping(mapping, mappingRequest); err != nil {
+		return nil, err
+	}
+	mapping.UpdatedBy = userID
+
+	if err := accountingExportService.accountingExportRepository.UpdateAccountingAccountMapping(mapping); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+func (accountingExportService *AccountingExportService) DeleteAccountingAccountMapping(mappingID uint, userID int) error {
+	mapping, err := accountingExportService.getAccountingAccountMapping(mappingID, userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	mapping.DeletedAt = &now
+	mapping.DeletedBy = &userID
+	return accountingExportService.accountingExportRepository.UpdateAccountingAccountMapping(mapping)
+}
+
+func (accountingExportService *AccountingExportService) GetAccountingAccountMappings(userID int) ([]models.AccountingAccountMapping, error) {
+	schoolID, err := accountingExportService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return accountingExportService.accountingExportRepository.GetAccountingAccountMappings(schoolID)
+}
+
+// CreateAccountingExport exports the journal entries of the period that were not exported
+// before as daily journals in the layout of the accounting package. The entries are marked as
+// exported, so running the same period again only brings what was posted since.
+func (accountingExportService *AccountingExportService) CreateAccountingExport(exportRequest *request.CreateAccountingExportRequest, userID int) (*bytes.Buffer, string, error) {
+	if _, ok := accountingExportLayouts[exportRequest.ExportFormat]; !ok {
+		return nil, "", fmt.Errorf("exportFormat must be accurate, jurnal_id or generic")
+	}
+	fileType, err := accountingExportFileType(exportRequest.FileType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	startDate, err := utilities.ParseDate(exportRequest.StartDate)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid start date format")
+	}
+	endDate, err := utilities.ParseDate(exportRequest.EndDate)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid end date format")
+	}
+	if endDate.Before(startDate) {
+		return nil, "", fmt.Errorf("end date must not be before start date")
+	}
+
+	schoolID, err := accountingExportService.getSchoolID(userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Events since the last journal run belong in the export too
+	if _, err := accountingExportService.journalRepository.SyncJournal(schoolID); err != nil {
+		return nil, "", err
+	}
+
+	lines, err := accountingExportService.accountingExportRepository.GetUnexportedJournalLines(schoolID, startDate, endDate)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(lines) == 0 {
+		return nil, "", fmt.Errorf("no journal entries left to export in this period, they may have been exported before")
+	}
+
+	mappings, err := accountingExportService.accountingExportRepository.GetAccountingAccountMappings(schoolID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var journalEntryIDs []uint
+	var totalAmount int64
+	seen := make(map[uint]bool)
+	for _, line := range lines {
+		totalAmount += line.Debit
+		if !seen[line.JournalEntryID] {
+			seen[line.JournalEntryID] = true
+			journalEntryIDs = append(journalEntryIDs, line.JournalEntryID)
+		}
+	}
+
+	accountingExport := &models.AccountingExport{
+		SchoolID:     schoolID,
+		ExportFormat: exportRequest.ExportFormat,
+		StartDate:    startDate,
+		EndDate:      endDate,
+		TotalEntries: len(journalEntryIDs),
+		TotalAmount:  totalAmount,
+	}
+	accountingExport.CreatedBy = userID
+	if err := accountingExportService.accountingExportRepository.CreateAccountingExport(accountingExport, journalEntryIDs); err != nil {
+		return nil, "", err
+	}
+
+	rows := BuildAccountingExportRows(accountingExport.ID, lines, mappings)
+	return renderAccountingExport(accountingExport, rows, fileType)
+}
+
+func (accountingExportService *AccountingExportService) GetAllAccountingExport(page int, limit int, userID int) (response.AccountingExportListResponse, error) {
+	resp := response.AccountingExportListResponse{
+		Page:  page,
+		Limit: limit,
+		Data:  []models.AccountingExport{},
+	}
+
+	schoolID, err := accountingExportService.getSchoolID(userID)
+	if err != nil {
+		return resp, err
+	}
+
+	exports, total, err := accountingExportService.accountingExportRepository.GetAllAccountingExport(page, limit, schoolID)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.TotalData = total
+	if limit != 0 {
+		resp.TotalPage = int((total + int64(limit) - 1) / int64(limit))
+	}
+	if len(exports) > 0 {
+		resp.Data = exports
+	}
+
+	return resp, nil
+}
+
+// DownloadAccountingExport builds the file of an earlier export again, with the account
+// mappings as they are now.
+func (accountingExportService *AccountingExportService) DownloadAccountingExport(exportID uint, fileType string, userID int) (*bytes.Buffer, string, error) {
+	fileType, err := accountingExportFileType(fileType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	schoolID, err := accountingExportService.getSchoolID(userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	accountingExport, err := accountingExportService.accountingExportRepository.GetAccountingExportByID(exportID, schoolID)
+	if err != nil {
+		return nil, "", fmt.Errorf("accounting export not found")
+	}
+
+	lines, err := accountingExportService.accountingExportRepository.GetExportedJournalLines(accountingExport.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	mappings, err := accountingExportService.accountingExportRepository.GetAccountingAccountMappings(schoolID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows := BuildAccountingExportRows(accountingExport.ID, lines, mappings)
+	return renderAccountingExport(accountingExport, rows, fileType)
+}
+
+// BuildAccountingExportRows sums the journal lines into one journal per day and kind of event,
+// with a line per account of the accounting package, debits first.
+func BuildAccountingExportRows(exportID uint, lines []response.AccountingJournalLine, mappings []models.AccountingAccountMapping) []response.AccountingExportRow {
+	mappingByReference := make(map[string]models.AccountingAccountMapping, len(mappings))
+	for _, mapping := range mappings {
+		mappingByReference[mapping.MappingType+":"+mapping.Reference] = mapping
+	}
+
+	type voucher struct {
+		row      response.AccountingExportRow
+		order    int
+		balances map[string]int64
+		names    map[string]string
+	}
+	vouchers := make(map[string]*voucher)
+	var voucherNumbers []string
+
+	for _, line := range lines {
+		voucherType, ok := accountingVoucherTypes[line.SourceType]
+		if !ok {
+			voucherType.Code, voucherType.Description = "JU", "Jurnal umum"
+		}
+		date := time.Date(line.EntryDate.Year(), line.EntryDate.Month(), line.EntryDate.Day(), 0, 0, 0, 0, line.EntryDate.Location())
+		number := fmt.Sprintf("JU%d-%s-%s", exportID, date.Format("20060102"), voucherType.Code)
+
+		v, ok := vouchers[number]
+		if !ok {
+			v = &voucher{
+				row: response.AccountingExportRow{
+					VoucherNumber: number,
+					Date:          date,
+					Description:   fmt.Sprintf("%s %s", voucherType.Description, date.Format("02/01/2006")),
+				},
+				order:    voucherType.Order,
+				balances: make(map[string]int64),
+				names:    make(map[string]string),
+			}
+			vouchers[number] = v
+			voucherNumbers = append(voucherNumbers, number)
+		}
+
+		code, name := resolveAccountingAccount(line, mappingByReference)
+		v.balances[code] += line.Debit - line.Credit
+		v.names[code] = name
+	}
+	sort.Slice(voucherNumbers, func(i, j int) bool {
+		a, b := vouchers[voucherNumbers[i]], vouchers[voucherNumbers[j]]
+		if !a.row.Date.Equal(b.row.Date) {
+			return a.row.Date.Before(b.row.Date)
+		}
+		return a.order < b.order
+	})
+
+	var rows []response.AccountingExportRow
+	for _, number := range voucherNumbers {
+		v := vouchers[number]
+		codes := make([]string, 0, len(v.balances))
+		for code, balance := range v.balances {
+			if balance != 0 {
+				codes = append(codes, code)
+			}
+		}
+		sort.Slice(codes, func(i, j int) bool {
+			if (v.balances[codes[i]] > 0) != (v.balances[codes[j]] > 0) {
+				return v.balances[codes[i]] > 0
+			}
+			return codes[i] < codes[j]
+		})
+
+		for _, code := range codes {
+			row := v.row
+			row.AccountCode = code
+			row.AccountName = v.names[code]
+			if balance := v.balances[code]; balance > 0 {
+				row.Debit = balance
+			} else {
+				row.Credit = -balance
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// resolveAccountingAccount finds the accounting package account of a journal line. Revenue is
+// mapped by billing type and bank money by bank account before the ledger account's own mapping;
+// without any mapping the ledger account is used as it is.
+func resolveAccountingAccount(line response.AccountingJournalLine, mappingByReference map[string]models.AccountingAccountMapping) (string, string) {
+	var references []string
+	if line.LedgerAccountCode == repositories.LedgerAccountRevenue && line.BillingTypeID != nil {
+		references = append(references, repositories.AccountingMappingBillingType+":"+strconv.Itoa(int(*line.BillingTypeID)))
+	}
+	if line.LedgerAccountCode == repositories.LedgerAccountBank && line.BankAccountID != nil {
+		references = append(references, repositories.AccountingMappingBankAccount+":"+strconv.Itoa(int(*line.BankAccountID)))
+	}
+	references = append(references, repositories.AccountingMappingLedgerAccount+":"+line.LedgerAccountCode)
+
+	for _, reference := range references {
+		if mapping, ok := mappingByReference[reference]; ok {
+			name := mapping.AccountName
+			if name == "" {
+				name = line.LedgerAccountName
+			}
+			return mapping.AccountCode, name
+		}
+	}
+	return line.LedgerAccountCode, line.LedgerAccountName
+}
+
+func renderAccountingExport(accountingExport *models.AccountingExport, rows []response.AccountingExportRow, fileType string) (*bytes.Buffer, string, error) {
+	layout := accountingExportLayouts[accountingExport.ExportFormat]
+	filename := fmt.Sprintf("jurnal_%s_%s_%s.%s", accountingExport.ExportFormat,
+		accountingExport.StartDate.Format("20060102"), accountingExport.EndDate.Format("20060102"), fileType)
+
+	var buffer *bytes.Buffer
+	var err error
+	if fileType == accountingExportFileExcel {
+		buffer, err = writeAccountingExportExcel(layout, rows)
+	} else {
+		buffer, err = writeAccountingExportCSV(layout, rows)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return buffer, filename, nil
+}
+
+func writeAccountingExportCSV(layout accountingExportLayout, rows []response.AccountingExportRow) (*bytes.Buffer, error) {
+	buffer := &bytes.Buffer{}
+	writer := csv.NewWriter(buffer)
+
+	headers := make([]string, len(layout.Columns))
+	for i, column := range layout.Columns {
+		headers[i] = column.Header
+	}
+	if err := writer.Write(headers); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		record := make([]string, len(layout.Columns))
+		for i, column := range layout.Columns {
+			record[i] = fmt.Sprint(column.Value(row))
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	return buffer, writer.Error()
+}
+
+func writeAccountingExportExcel(layout accountingExportLayout, rows []response.AccountingExportRow) (*bytes.Buffer, error) {
+	excelUtil := utilities.NewExcelUtility()
+	defer excelUtil.Close()
+
+	excelUtil.File.SetSheetName("Sheet1", accountingExportSheetName)
+
+	headers := make([]string, len(layout.Columns))
+	for i, column := range layout.Columns {
+		headers[i] = column.Header
+	}
+	if err := excelUtil.WriteHeaders(accountingExportSheetName, headers, true); err != nil {
+		return nil, err
+	}
+
+	for i, row := range rows {
+		for j, column := range layout.Columns {
+			cell := fmt.Sprintf("%s%d", utilities.GetColumnName(j), i+2)
+			excelUtil.SetCellValue(accountingExportSheetName, cell, column.Value(row))
+		}
+	}
+
+	for i := range layout.Columns {
+		if err := excelUtil.AutoFitColumn(accountingExportSheetName, utilities.GetColumnName(i)); err != nil {
+			return nil, fmt.Errorf("failed to auto-fit column %s: %v", utilities.GetColumnName(i), err)
+		}
+	}
+
+	return excelUtil.WriteToBuffer()
+}
+
+func accountingExportFileType(fileType string) (string, error) {
+	switch strings.ToLower(fileType) {
+	case "", accountingExportFileCSV:
+		return accountingExportFileCSV, nil
+	case accountingExportFileExcel:
+		return accountingExportFileExcel, nil
+	default:
+		return "", fmt.Errorf("fileType must be csv or xlsx")
+	}
+}
+
+func (accountingExportService *AccountingExportService) fillAccountingAccountMapping(mapping *models.AccountingAccountMapping, mappingRequest *request.AccountingAccountMappingRequest) error {
+	reference := strings.TrimSpace(mappingRequest.Reference)
+	accountCode := strings.TrimSpace(mappingRequest.AccountCode)
+	if err := utilities.ValidateFieldNotEmpty(accountCode, "accountCode"); err != nil {
+		return err
+	}
+
+	switch mappingRequest.MappingType {
+	case repositories.AccountingMappingLedgerAccount:
+		if _, ok := repositories.DefaultLedgerAccount(reference); !ok {
+			return fmt.Errorf("ledger account %s not found", reference)
+		}
+	case repositories.AccountingMappingBillingType, repositories.AccountingMappingBankAccount:
+		id, err := strconv.Atoi(reference)
+		if err != nil || id <= 0 {
+			return fmt.Errorf("reference must be the id of the %s", strings.ReplaceAll(mappingRequest.MappingType, "_", " "))
+		}
+		belongs := accountingExportService.accountingExportRepository.BillingTypeBelongsToSchool
+		if mappingRequest.MappingType == repositories.AccountingMappingBankAccount {
+			belongs = accountingExportService.accountingExportRepository.BankAccountBelongsToSchool
+		}
+		ok, err := belongs(uint(id), mapping.SchoolID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%s not found", strings.ReplaceAll(mappingRequest.MappingType, "_", " "))
+		}
+		reference = strconv.Itoa(id)
+	default:
+		return fmt.Errorf("mappingType must be ledger_account, billing_type or bank_account")
+	}
+
+	exists, err := accountingExportService.accountingExportRepository.AccountingAccountMappingExists(mappingRequest.MappingType, reference, mapping.SchoolID, mapping.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("a mapping for this %s already exists", strings.ReplaceAll(mappingRequest.MappingType, "_", " "))
+	}
+
+	mapping.MappingType = mappingRequest.MappingType
+	mapping.Reference = reference
+	mapping.AccountCode = accountCode
+	mapping.AccountName = strings.TrimSpace(mappingRequest.AccountName)
+	return nil
+}
+
+func (accountingExportService *AccountingExportService) getAccountingAccountMapping(mappingID uint, userID int) (*models.AccountingAccountMapping, error) {
+	schoolID, err := accountingExportService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := accountingExportService.accountingExportRepository.GetAccountingAccountMappingByID(mappingID, schoolID)
+	if err != nil {
+		return nil, fmt.Errorf("accounting account mapping not found")
+	}
+	return mapping, nil
+}
+
+func (accountingExportService *AccountingExportService) getSchoolID(userID int) (uint, error) {
+	user, err := accountingExportService.userRepository.GetUserByID(uint(userID))
+	if err != nil {
+		return 0, err
+	}
+	if user.UserSchool == nil {
+		return 0, fmt.Errorf("user not associated with any school")
+	}
+	return user.UserSchool.SchoolID, nil
+}
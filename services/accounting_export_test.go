@@ -0,0 +1,295 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	request "schoolPayment/dtos/request"
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAccountingExportRepository struct {
+	mock.Mock
+}
+
+func (m *MockAccountingExportRepository) GetAccountingAccountMappings(schoolID uint) ([]models.AccountingAccountMapping, error) {
+	args := m.Called(schoolID)
+	return args.Get(0).([]models.AccountingAccountMapping), args.Error(1)
+}
+
+func (m *MockAccountingExportRepository) GetAccountingAccountMappingByID(id uint, schoolID uint) (*models.AccountingAccountMapping, error) {
+	args := m.Called(id, schoolID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AccountingAccountMapping), args.Error(1)
+}
+
+func (m *MockAccountingExportRepository) AccountingAccountMappingExists(mappingType string, reference string, schoolID uint, excludeID uint) (bool, error) {
+	args := m.Called(mappingType, reference, schoolID, excludeID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAccountingExportRepository) CreateAccountingAccountMapping(mapping *models.AccountingAccountMapping) (*models.AccountingAccountMapping, error) {
+	args := m.Called(mapping)
+	return mapping, args.Error(0)
+}
+
+func (m *MockAccountingExportRepository) UpdateAccountingAccountMapping(mapping *models.AccountingAccountMapping) error {
+	args := m.Called(mapping)
+	return args.Error(0)
+}
+
+func (m *MockAccountingExportRepository) BillingTypeBelongsToSchool(billingTypeID uint, schoolID uint) (bool, error) {
+	args := m.Called(billingTypeID, schoolID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAccountingExportRepository) BankAccountBelongsToSchool(bankAccountID uint, schoolID uint) (bool, error) {
+	args := m.Called(bankAccountID, schoolID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAccountingExportRepository) GetUnexportedJournalLines(schoolID uint, startDate time.Time, endDate time.Time) ([]response.AccountingJournalLine, error) {
+	args := m.Called(schoolID, startDate, endDate)
+	return args.Get(0).([]response.AccountingJournalLine), args.Error(1)
+}
+
+func (m *MockAccountingExportRepository) GetExportedJournalLines(exportID uint) ([]response.AccountingJournalLine, error) {
+	args := m.Called(exportID)
+	return args.Get(0).([]response.AccountingJournalLine), args.Error(1)
+}
+
+func (m *MockAccountingExportRepository) CreateAccountingExport(accountingExport *models.AccountingExport, journalEntryIDs []uint) error {
+	args := m.Called(accountingExport, journalEntryIDs)
+	accountingExport.ID = 7
+	return args.Error(0)
+}
+
+func (m *MockAccountingExportRepository) GetAllAccountingExport(page int, limit int, schoolID uint) ([]models.AccountingExport, int64, error) {
+	args := m.Called(page, limit, schoolID)
+	return args.Get(0).([]models.AccountingExport), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockAccountingExportRepository) GetAccountingExportByID(id uint, schoolID uint) (*models.AccountingExport, error) {
+	args := m.Called(id, schoolID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AccountingExport), args.Error(1)
+}
+
+func accountingExportTestLines() []response.AccountingJournalLine {
+	day := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	tuition, uniform, bca := uint(3), uint(4), uint(9)
+	return []response.AccountingJournalLine{
+		{JournalEntryID: 1, EntryDate: day, SourceType: repositories.JournalSourceBillingStudent, BillingTypeID: &tuition, LedgerAccountCode: repositories.LedgerAccountReceivable, LedgerAccountName: "Piutang Siswa", Debit: 500000},
+		{JournalEntryID: 1, EntryDate: day, SourceType: repositories.JournalSourceBillingStudent, BillingTypeID: &tuition, LedgerAccountCode: repositories.LedgerAccountRevenue, LedgerAccountName: "Pendapatan", Credit: 500000},
+		{JournalEntryID: 2, EntryDate: day, SourceType: repositories.JournalSourceBillingStudent, BillingTypeID: &uniform, LedgerAccountCode: repositories.LedgerAccountReceivable, LedgerAccountName: "Piutang Siswa", Debit: 200000},
+		{JournalEntryID: 2, EntryDate: day, SourceType: repositories.JournalSourceBillingStudent, BillingTypeID: &uniform, LedgerAccountCode: repositories.LedgerAccountRevenue, LedgerAccountName: "Pendapatan", Credit: 200000},
+		{JournalEntryID: 3, EntryDate: day, SourceType: repositories.JournalSourceTransaction, BankAccountID: &bca, LedgerAccountCode: repositories.LedgerAccountBank, LedgerAccountName: "Bank", Debit: 500000},
+		{JournalEntryID: 3, EntryDate: day, SourceType: repositories.JournalSourceTransaction, BankAccountID: &bca, LedgerAccountCode: repositories.LedgerAccountReceivable, LedgerAccountName: "Piutang Siswa", Credit: 500000},
+	}
+}
+
+func TestBuildAccountingExportRows(t *testing.T) {
+	mappings := []models.AccountingAccountMapping{
+		{MappingType: repositories.AccountingMappingBillingType, Reference: "3", AccountCode: "4-1001", AccountName: "Pendapatan SPP"},
+		{MappingType: repositories.AccountingMappingBankAccount, Reference: "9", AccountCode: "1-1102"},
+		{MappingType: repositories.AccountingMappingLedgerAccount, Reference: repositories.LedgerAccountReceivable, AccountCode: "1-1300", AccountName: "Piutang Siswa"},
+	}
+
+	rows := BuildAccountingExportRows(7, accountingExportTestLines(), mappings)
+
+	assert.Len(t, rows, 5)
+
+	assert.Equal(t, "JU7-20240115-TAG", rows[0].VoucherNumber)
+	assert.Equal(t, "Tagihan siswa 15/01/2024", rows[0].Description)
+	assert.Equal(t, "1-1300", rows[0].AccountCode)
+	assert.Equal(t, int64(700000), rows[0].Debit)
+	assert.Equal(t, "4-1001", rows[1].AccountCode)
+	assert.Equal(t, "Pendapatan SPP", rows[1].AccountName)
+	assert.Equal(t, int64(500000), rows[1].Credit)
+	// Revenue of a billing type without a mapping keeps the ledger account
+	assert.Equal(t, repositories.LedgerAccountRevenue, rows[2].AccountCode)
+	assert.Equal(t, int64(200000), rows[2].Credit)
+
+	assert.Equal(t, "JU7-20240115-BYR", rows[3].VoucherNumber)
+	assert.Equal(t, "1-1102", rows[3].AccountCode)
+	assert.Equal(t, "Bank", rows[3].AccountName)
+	assert.Equal(t, int64(500000), rows[3].Debit)
+	assert.Equal(t, "1-1300", rows[4].AccountCode)
+	assert.Equal(t, int64(500000), rows[4].Credit)
+}
+
+func TestCreateAccountingExport(t *testing.T) {
+	startDate := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	exportRequest := &request.CreateAccountingExportRequest{
+		StartDate:    "2024-01-01",
+		EndDate:      "2024-01-31",
+		ExportFormat: repositories.AccountingExportFormatAccurate,
+	}
+
+	t.Run("Exports and marks the entries", func(t *testing.T) {
+		mockExportRepo := new(MockAccountingExportRepository)
+		mockJournalRepo := new(MockJournalRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewAccountingExportService(mockExportRepo, mockJournalRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(4)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+		mockJournalRepo.On("SyncJournal", uint(1)).Return(2, nil)
+		mockExportRepo.On("GetUnexportedJournalLines", uint(1), startDate, endDate).Return(accountingExportTestLines(), nil)
+		mockExportRepo.On("GetAccountingAccountMappings", uint(1)).Return([]models.AccountingAccountMapping{}, nil)
+		mockExportRepo.On("CreateAccountingExport", mock.MatchedBy(func(export *models.AccountingExport) bool {
+			return export.TotalEntries == 3 && export.TotalAmount == 1200000 && export.CreatedBy == 4
+		}), []uint{1, 2, 3}).Return(nil)
+
+		buffer, filename, err := service.CreateAccountingExport(exportRequest, 4)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "jurnal_accurate_20240101_20240131.csv", filename)
+		lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+		assert.Equal(t, "No. Bukti,Tanggal,Keterangan,No. Akun,Nama Akun,Debit,Kredit", lines[0])
+		assert.Equal(t, "JU7-20240115-TAG,15/01/2024,Tagihan siswa 15/01/2024,1201,Piutang Siswa,700000,0", lines[1])
+		assert.Len(t, lines, 5)
+		mockExportRepo.AssertExpectations(t)
+	})
+
+	t.Run("Nothing left to export", func(t *testing.T) {
+		mockExportRepo := new(MockAccountingExportRepository)
+		mockJournalRepo := new(MockJournalRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewAccountingExportService(mockExportRepo, mockJournalRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(4)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+		mockJournalRepo.On("SyncJournal", uint(1)).Return(0, nil)
+		mockExportRepo.On("GetUnexportedJournalLines", uint(1), startDate, endDate).Return([]response.AccountingJournalLine{}, nil)
+
+		_, _, err := service.CreateAccountingExport(exportRequest, 4)
+
+		assert.EqualError(t, err, "no journal entries left to export in this period, they may have been exported before")
+		mockExportRepo.AssertNotCalled(t, "CreateAccountingExport", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Conflict with another export", func(t *testing.T) {
+		mockExportRepo := new(MockAccountingExportRepository)
+		mockJournalRepo := new(MockJournalRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewAccountingExportService(mockExportRepo, mockJournalRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(4)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+		mockJournalRepo.On("SyncJournal", uint(1)).Return(0, nil)
+		mockExportRepo.On("GetUnexportedJournalLines", uint(1), startDate, endDate).Return(accountingExportTestLines(), nil)
+		mockExportRepo.On("GetAccountingAccountMappings", uint(1)).Return([]models.AccountingAccountMapping{}, nil)
+		mockExportRepo.On("CreateAccountingExport", mock.Anything, mock.Anything).Return(repositories.ErrAccountingExportConflict)
+
+		_, _, err := service.CreateAccountingExport(exportRequest, 4)
+
+		assert.ErrorIs(t, err, repositories.ErrAccountingExportConflict)
+	})
+
+	t.Run("Invalid format", func(t *testing.T) {
+		service := NewAccountingExportService(new(MockAccountingExportRepository), new(MockJournalRepository), new(MockUserRepository))
+
+		_, _, err := service.CreateAccountingExport(&request.CreateAccountingExportRequest{ExportFormat: "zahir"}, 4)
+
+		assert.EqualError(t, err, "exportFormat must be accurate, jurnal_id or generic")
+	})
+}
+
+func TestRenderAccountingExportExcel(t *testing.T) {
+	accountingExport := &models.AccountingExport{
+		ExportFormat: repositories.AccountingExportFormatJurnal,
+		StartDate:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:      time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+	}
+	rows := BuildAccountingExportRows(7, accountingExportTestLines(), nil)
+
+	buffer, filename, err := renderAccountingExport(accountingExport, rows, accountingExportFileExcel)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "jurnal_jurnal_id_20240101_20240131.xlsx", filename)
+	assert.True(t, bytes.HasPrefix(buffer.Bytes(), []byte("PK")))
+}
+
+func TestCreateAccountingAccountMapping(t *testing.T) {
+	t.Run("Billing type of the school", func(t *testing.T) {
+		mockExportRepo := new(MockAccountingExportRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewAccountingExportService(mockExportRepo, new(MockJournalRepository), mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(4)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+		mockExportRepo.On("BillingTypeBelongsToSchool", uint(3), uint(1)).Return(true, nil)
+		mockExportRepo.On("AccountingAccountMappingExists", repositories.AccountingMappingBillingType, "3", uint(1), uint(0)).Return(false, nil)
+		mockExportRepo.On("CreateAccountingAccountMapping", mock.Anything).Return(nil)
+
+		mapping, err := service.CreateAccountingAccountMapping(&request.AccountingAccountMappingRequest{
+			MappingType: repositories.AccountingMappingBillingType,
+			Reference:   " 3 ",
+			AccountCode: "4-1001",
+		}, 4)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "3", mapping.Reference)
+		assert.Equal(t, "4-1001", mapping.AccountCode)
+		mockExportRepo.AssertExpectations(t)
+	})
+
+	t.Run("Unknown ledger account", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		service := NewAccountingExportService(new(MockAccountingExportRepository), new(MockJournalRepository), mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(4)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+
+		_, err := service.CreateAccountingAccountMapping(&request.AccountingAccountMappingRequest{
+			MappingType: repositories.AccountingMappingLedgerAccount,
+			Reference:   "9999",
+			AccountCode: "1-1000",
+		}, 4)
+
+		assert.EqualError(t, err, "ledger account 9999 not found")
+	})
+
+	t.Run("Duplicate mapping", func(t *testing.T) {
+		mockExportRepo := new(MockAccountingExportRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewAccountingExportService(mockExportRepo, new(MockJournalRepository), mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(4)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+		mockExportRepo.On("BankAccountBelongsToSchool", uint(9), uint(1)).Return(true, nil)
+		mockExportRepo.On("AccountingAccountMappingExists", repositories.AccountingMappingBankAccount, "9", uint(1), uint(0)).Return(true, nil)
+
+		_, err := service.CreateAccountingAccountMapping(&request.AccountingAccountMappingRequest{
+			MappingType: repositories.AccountingMappingBankAccount,
+			Reference:   "9",
+			AccountCode: "1-1102",
+		}, 4)
+
+		assert.EqualError(t, err, "a mapping for this bank account already exists")
+	})
+
+	t.Run("Other school's bank account", func(t *testing.T) {
+		mockExportRepo := new(MockAccountingExportRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewAccountingExportService(mockExportRepo, new(MockJournalRepository), mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(4)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+		mockExportRepo.On("BankAccountBelongsToSchool", uint(9), uint(1)).Return(false, nil)
+
+		_, err := service.CreateAccountingAccountMapping(&request.AccountingAccountMappingRequest{
+			MappingType: repositories.AccountingMappingBankAccount,
+			Reference:   "9",
+			AccountCode: "1-1102",
+		}, 4)
+
+		assert.EqualError(t, err, "bank account not found")
+	})
+}
@@ -0,0 +1,395 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	request "schoolPayment/dtos/request"
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+)
+
+var bankStatementNumberPattern = regexp.MustCompile(`\d{4,}`)
+
+type BankStatementServiceInterface interface {
+	ImportBankStatement(importRequest *request.ImportBankStatementRequest, fileName string, content []byte, userID int) (*models.BankStatementImport, error)
+	GetAllBankStatementImport(page int, limit int, bankAccountID uint, userID int) (response.BankStatementImportListResponse, error)
+	GetBankStatementImportByID(importID uint, userID int) (*models.BankStatementImport, error)
+	GetAllBankStatementLine(page int, limit int, importID uint, matchStatus string, userID int) (response.BankStatementLineListResponse, error)
+	ConfirmBankStatementLine(lineID uint, confirmRequest *request.ConfirmBankStatementLineRequest, userID int) (*models.BankStatementLine, error)
+	IgnoreBankStatementLine(lineID uint, userID int) (*models.BankStatementLine, error)
+}
+
+type BankStatementService struct {
+	bankStatementRepository repositories.BankStatementRepository
+	userRepository          repositories.UserRepository
+}
+
+func NewBankStatementService(bankStatementRepository repositories.BankStatementRepository, userRepository repositories.UserRepository) BankStatementServiceInterface {
+	return &BankStatementService{
+		bankStatementRepository: bankStatementRepository,
+		userRepository:          userRepository,
+	}
+}
+
+// ImportBankStatement stores the credits of a mutation file of one of the school's bank accounts
+// and proposes a match for each of them. Credits imported before are skipped, so overlapping
+// statements can be uploaded safely. Nothing is paid until a proposal is confirmed.
+func (bankStatementService *BankStatementService) ImportBankStatement(importRequest *request.ImportBankStatementRequest, fileName string, content []byte, userID int) (*models.BankStatementImport, error) {
+	schoolID, err := bankStatementService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	bankAccount, err := bankStatementService.bankStatementRepository.GetBankAccountForSchool(importRequest.BankAccountId, schoolID)
+	if err != nil {
+		return nil, fmt.Errorf("bank account not found")
+	}
+
+	entries, err := ParseBankStatement(importRequest.FileFormat, content)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no incoming transfers found in the file")
+	}
+
+	// Identical transfers on the same day are told apart by their position in the file
+	lines := make([]models.BankStatementLine, 0, len(entries))
+	hashes := make([]string, 0, len(entries))
+	occurrences := make(map[string]int)
+	for _, entry := range entries {
+		key := fmt.Sprintf("%s|%d|%s|%s", entry.TransactionDate.Format("2006-01-02"), entry.Amount, entry.Description, entry.Reference)
+		occurrences[key]++
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d", key, occurrences[key])))
+		hash := hex.EncodeToString(sum[:])
+
+		line := models.BankStatementLine{
+			SchoolID:        schoolID,
+			BankAccountID:   bankAccount.ID,
+			TransactionDate: entry.TransactionDate,
+			Description:     entry.Description,
+			Reference:       entry.Reference,
+			Amount:          entry.Amount,
+			LineHash:        hash,
+			MatchStatus:     repositories.BankStatementLineStatusUnmatched,
+		}
+		line.CreatedBy = userID
+		lines = append(lines, line)
+		hashes = append(hashes, hash)
+	}
+
+	existing, err := bankStatementService.bankStatementRepository.GetExistingLineHashes(bankAccount.ID, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	students, err := bankStatementService.bankStatementRepository.GetSchoolStudents(schoolID)
+	if err != nil {
+		return nil, err
+	}
+
+	installments, err := bankStatementService.bankStatementRepository.GetOpenInstallmentsForBankAccount(bankAccount.ID)
+	if err != nil {
+		return nil, err
+	}
+	installmentsByStudent := make(map[uint][]models.BillingStudent)
+	for _, installment := range installments {
+		installmentsByStudent[installment.StudentID] = append(installmentsByStudent[installment.StudentID], installment)
+	}
+
+	statementImport := &models.BankStatementImport{
+		SchoolID:      schoolID,
+		BankAccountID: bankAccount.ID,
+		FileName:      fileName,
+		FileFormat:    importRequest.FileFormat,
+	}
+	statementImport.CreatedBy = userID
+
+	newLines := make([]models.BankStatementLine, 0, len(lines))
+	proposed := make(map[uint]bool)
+	for i := range lines {
+		if existing[lines[i].LineHash] {
+			statementImport.TotalSkipped++
+			continue
+		}
+
+		MatchBankStatementLine(&lines[i], students, installmentsByStudent, proposed)
+		if lines[i].MatchStatus == repositories.BankStatementLineStatusProposed {
+			statementImport.TotalProposed++
+		}
+		statementImport.TotalLines++
+		statementImport.TotalAmount += lines[i].Amount
+		newLines = append(newLines, lines[i])
+	}
+
+	if err := bankStatementService.bankStatementRepository.CreateBankStatementImport(statementImport, newLines); err != nil {
+		return nil, err
+	}
+	return statementImport, nil
+}
+
+func (bankStatementService *BankStatementService) GetAllBankStatementImport(page int, limit int, bankAccountID uint, userID int) (response.BankStatementImportListResponse, error) {
+	resp := response.BankStatementImportListResponse{
+		Page:  page,
+		Limit: limit,
+		Data:  []models.BankStatementImport{},
+	}
+
+	schoolID, err := bankStatementService.getSchoolID(userID)
+	if err != nil {
+		return resp, err
+	}
+
+	imports, total, err := bankStatementService.bankStatementRepository.GetAllBankStatementImport(page, limit, bankAccountID, schoolID)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.TotalData = total
+	if limit != 0 {
+		resp.TotalPage = int((total + int64(limit) - 1) / int64(limit))
+	}
+	if len(imports) > 0 {
+		resp.Data = imports
+	}
+
+	return resp, nil
+}
+
+func (bankStatementService *BankStatementService) GetBankStatementImportByID(importID uint, userID int) (*models.BankStatementImport, error) {
+	schoolID, err := bankStatementService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return bankStatementService.bankStatementRepository.GetBankStatementImportByID(importID, schoolID)
+}
+
+func (bankStatementService *BankStatementService) GetAllBankStatementLine(page int, limit int, importID uint, matchStatus string, userID int) (response.BankStatementLineListResponse, error) {
+	resp := response.BankStatementLineListResponse{
+		Page:  page,
+		Limit: limit,
+		Data:  []models.BankStatementLine{},
+	}
+
+	schoolID, err := bankStatementService.getSchoolID(userID)
+	if err != nil {
+		return resp, err
+	}
+
+	lines, total, err := bankStatementService.bankStatementRepository.GetAllBankStatementLine(page, limit, importID, matchStatus, schoolID)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.TotalData = total
+	if limit != 0 {
+		resp.TotalPage = int((total + int64(limit) - 1) / int64(limit))
+	}
+	if len(lines) > 0 {
+		resp.Data = lines
+	}
+
+	return resp, nil
+}
+
+// ConfirmBankStatementLine posts the transfer as a paid transaction, on the proposed match or
+// on the student and installments chosen by the reviewer.
+func (bankStatementService *BankStatementService) ConfirmBankStatementLine(lineID uint, confirmRequest *request.ConfirmBankStatementLineRequest, userID int) (*models.BankStatementLine, error) {
+	line, err := bankStatementService.getOpenLine(lineID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var studentID uint
+	var billingStudentIDs []int
+	if len(confirmRequest.BillingStudentIds) > 0 {
+		if confirmRequest.StudentId == 0 {
+			return nil, fmt.Errorf("studentId is required when choosing installments")
+		}
+		studentID = confirmRequest.StudentId
+		billingStudentIDs = confirmRequest.BillingStudentIds
+	} else {
+		if line.StudentID == nil || line.BillingStudentIds == "" {
+			return nil, fmt.Errorf("bank statement line has no proposed match, choose the student and installments")
+		}
+		studentID = *line.StudentID
+		for _, idStr := range strings.Split(line.BillingStudentIds, ",") {
+			id, err := strconv.Atoi(idStr)
+			if err != nil {
+				return nil, err
+			}
+			billingStudentIDs = append(billingStudentIDs, id)
+		}
+	}
+
+	if _, err := bankStatementService.bankStatementRepository.PostBankStatementLine(line, studentID, billingStudentIDs, userID); err != nil {
+		return nil, err
+	}
+	return line, nil
+}
+
+// IgnoreBankStatementLine closes a credit that is not a school payment, such as interest or a
+// transfer between the school's own accounts.
+func (bankStatementService *BankStatementService) IgnoreBankStatementLine(lineID uint, userID int) (*models.BankStatementLine, error) {
+	line, err := bankStatementService.getOpenLine(lineID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	line.MatchStatus = repositories.BankStatementLineStatusIgnored
+	line.ConfirmedBy = &userID
+	line.ConfirmedAt = &now
+	line.UpdatedBy = userID
+
+	if err := bankStatementService.bankStatementRepository.UpdateBankStatementLine(line); err != nil {
+		return nil, err
+	}
+	return line, nil
+}
+
+// MatchBankStatementLine proposes who sent the transfer and which installments it pays. A NIS in
+// the remark, or a virtual account number ending in one, names the student, whose oldest open
+// installments must then add up to the amount. Without a student, the transfer is proposed for
+// the only open installment of exactly that amount, if there is just one. Installments already
+// proposed for another line are not proposed again.
+func MatchBankStatementLine(line *models.BankStatementLine, students []response.BankStatementStudent, installmentsByStudent map[uint][]models.BillingStudent, proposed map[uint]bool) {
+	studentID, method := findBankStatementStudent(line, students)
+	if studentID != 0 {
+		line.StudentID = &studentID
+		line.MatchMethod = method
+
+		var open []models.BillingStudent
+		for _, installment := range installmentsByStudent[studentID] {
+			if !proposed[installment.ID] {
+				open = append(open, installment)
+			}
+		}
+
+		var sum int64
+		for i, installment := range open {
+			sum += installment.Amount - installment.PaidAmount
+			if sum == line.Amount {
+				proposeBankStatementLine(line, open[:i+1], proposed)
+				return
+			}
+			if sum > line.Amount {
+				break
+			}
+		}
+		for _, installment := range open {
+			if installment.Amount-installment.PaidAmount == line.Amount {
+				proposeBankStatementLine(line, []models.BillingStudent{installment}, proposed)
+				return
+			}
+		}
+		return
+	}
+
+	var candidates []models.BillingStudent
+	for _, installments := range installmentsByStudent {
+		for _, installment := range installments {
+			if !proposed[installment.ID] && installment.Amount-installment.PaidAmount == line.Amount {
+				candidates = append(candidates, installment)
+			}
+		}
+	}
+	if len(candidates) == 1 {
+		line.StudentID = &candidates[0].StudentID
+		line.MatchMethod = repositories.BankStatementMatchAmount
+		proposeBankStatementLine(line, candidates, proposed)
+	}
+}
+
+// findBankStatementStudent looks for the one student the remark refers to. An exact NIS wins over
+// a virtual account number; more than one student means the remark is not trusted.
+func findBankStatementStudent(line *models.BankStatementLine, students []response.BankStatementStudent) (uint, string) {
+	amount := strconv.FormatInt(line.Amount, 10)
+	var numbers []string
+	for _, number := range bankStatementNumberPattern.FindAllString(line.Description+" "+line.Reference, -1) {
+		if number != amount {
+			numbers = append(numbers, number)
+		}
+	}
+	if len(numbers) == 0 {
+		return 0, ""
+	}
+
+	nisMatches := make(map[uint]bool)
+	vaMatches := make(map[uint]bool)
+	for _, student := range students {
+		nis := strings.Map(func(r rune) rune {
+			if r < '0' || r > '9' {
+				return -1
+			}
+			return r
+		}, student.Nis)
+		if len(nis) < 4 {
+			continue
+		}
+
+		for _, number := range numbers {
+			if number == nis {
+				nisMatches[student.StudentID] = true
+			} else if len(number) > len(nis) && strings.HasSuffix(number, nis) {
+				vaMatches[student.StudentID] = true
+			}
+		}
+	}
+
+	if len(nisMatches) == 1 {
+		for studentID := range nisMatches {
+			return studentID, repositories.BankStatementMatchNis
+		}
+	}
+	if len(nisMatches) == 0 && len(vaMatches) == 1 {
+		for studentID := range vaMatches {
+			return studentID, repositories.BankStatementMatchVA
+		}
+	}
+	return 0, ""
+}
+
+func proposeBankStatementLine(line *models.BankStatementLine, installments []models.BillingStudent, proposed map[uint]bool) {
+	ids := make([]string, 0, len(installments))
+	for _, installment := range installments {
+		proposed[installment.ID] = true
+		ids = append(ids, strconv.Itoa(int(installment.ID)))
+	}
+	line.BillingStudentIds = strings.Join(ids, ",")
+	line.MatchStatus = repositories.BankStatementLineStatusProposed
+}
+
+func (bankStatementService *BankStatementService) getOpenLine(lineID uint, userID int) (*models.BankStatementLine, error) {
+	schoolID, err := bankStatementService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	line, err := bankStatementService.bankStatementRepository.GetBankStatementLineByID(lineID, schoolID)
+	if err != nil {
+		return nil, fmt.Errorf("bank statement line not found")
+	}
+	if line.MatchStatus == repositories.BankStatementLineStatusPosted || line.MatchStatus == repositories.BankStatementLineStatusIgnored {
+		return nil, repositories.ErrBankStatementLineClosed
+	}
+	return line, nil
+}
+
+func (bankStatementService *BankStatementService) getSchoolID(userID int) (uint, error) {
+	user, err := bankStatementService.userRepository.GetUserByID(uint(userID))
+	if err != nil {
+		return 0, err
+	}
+	if user.UserSchool == nil {
+		return 0, fmt.Errorf("user not associated with any school")
+	}
+	return user.UserSchool.SchoolID, nil
+}
@@ -0,0 +1,376 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"schoolPayment/repositories"
+)
+
+// BankStatementEntry is one incoming transfer read from a mutation file.
+type BankStatementEntry struct {
+	TransactionDate time.Time
+	Description     string
+	Reference       string
+	Amount          int64
+}
+
+// bankStatementColumns lists the header names a bank uses for each column of its CSV export.
+// Banks use either one amount column marked CR/DB or separate debit and credit columns.
+type bankStatementColumns struct {
+	date        []string
+	description []string
+	reference   []string
+	amount      []string
+	credit      []string
+}
+
+var bankStatementCSVColumns = map[string]bankStatementColumns{
+	repositories.BankStatementFormatBCA: {
+		date:        []string{"tanggal transaksi", "tanggal", "tgl"},
+		description: []string{"keterangan", "deskripsi"},
+		amount:      []string{"jumlah", "mutasi"},
+	},
+	repositories.BankStatementFormatBRI: {
+		date:        []string{"tanggal transaksi", "tanggal", "tgl_tran", "tgl"},
+		description: []string{"uraian transaksi", "uraian", "keterangan", "remark"},
+		reference:   []string{"nomor referensi", "no. referensi", "reference no."},
+		credit:      []string{"kredit", "credit", "mutasi kredit"},
+	},
+	repositories.BankStatementFormatMandiri: {
+		date:        []string{"posting date", "date", "tanggal"},
+		description: []string{"description", "description1", "description2", "keterangan", "remark"},
+		reference:   []string{"reference no.", "reference no", "no. referensi"},
+		credit:      []string{"credit", "kredit"},
+	},
+}
+
+var bankStatementDateLayouts = []string{
+	"02/01/2006", "02/01/06", "2006-01-02", "02-01-2006", "02-01-06",
+	"02 Jan 2006", "02-Jan-2006", "02-Jan-06", "02/01/2006 15:04:05", "02/01/2006 15:04", "2006-01-02 15:04:05",
+}
+
+var mt940TagPattern = regexp.MustCompile(`^:(\d{2}[A-Z]?):(.*)$`)
+
+// ParseBankStatement reads the credits of a mutation file. Debits are left out because only
+// money coming in can pay a bill.
+func ParseBankStatement(fileFormat string, content []byte) ([]BankStatementEntry, error) {
+	if fileFormat == repositories.BankStatementFormatMT940 {
+		return parseMT940(content)
+	}
+
+	columns, ok := bankStatementCSVColumns[fileFormat]
+	if !ok {
+		return nil, fmt.Errorf("fileFormat must be one of %s, %s, %s or %s", repositories.BankStatementFormatBCA,
+			repositories.BankStatementFormatBRI, repositories.BankStatementFormatMandiri, repositories.BankStatementFormatMT940)
+	}
+	return parseBankStatementCSV(columns, content)
+}
+
+func parseBankStatementCSV(columns bankStatementColumns, content []byte) ([]BankStatementEntry, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if bytes.Count(content, []byte(";")) > bytes.Count(content, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV file: %v", err)
+	}
+
+	// Exports start with account details before the header row, and end with balance totals
+	dateCol, amountCol, creditCol, referenceCol := -1, -1, -1, -1
+	var descriptionCols []int
+	headerFound := false
+
+	var entries []BankStatementEntry
+	for _, row := range rows {
+		if !headerFound {
+			dateCol, amountCol, creditCol, referenceCol = -1, -1, -1, -1
+			descriptionCols = nil
+			for i, cell := range row {
+				name := strings.ToLower(strings.TrimSpace(cell))
+				switch {
+				case dateCol < 0 && containsString(columns.date, name):
+					dateCol = i
+				case amountCol < 0 && containsString(columns.amount, name):
+					amountCol = i
+				case creditCol < 0 && containsString(columns.credit, name):
+					creditCol = i
+				case referenceCol < 0 && containsString(columns.reference, name):
+					referenceCol = i
+				case containsString(columns.description, name):
+					descriptionCols = append(descriptionCols, i)
+				}
+			}
+			headerFound = dateCol >= 0 && (amountCol >= 0 || creditCol >= 0)
+			continue
+		}
+
+		transactionDate, ok := parseBankStatementDate(cellAt(row, dateCol))
+		if !ok {
+			continue
+		}
+
+		var amount int64
+		if amountCol >= 0 {
+			value, isDebit, ok := parseBankStatementAmount(cellAt(row, amountCol))
+			if !ok || isDebit {
+				continue
+			}
+			amount = value
+		} else {
+			value, _, ok := parseBankStatementAmount(cellAt(row, creditCol))
+			if !ok {
+				continue
+			}
+			amount = value
+		}
+		if amount <= 0 {
+			continue
+		}
+
+		var description []string
+		for _, col := range descriptionCols {
+			if text := strings.TrimSpace(cellAt(row, col)); text != "" {
+				description = append(description, text)
+			}
+		}
+
+		entries = append(entries, BankStatementEntry{
+			TransactionDate: transactionDate,
+			Description:     strings.Join(description, " "),
+			Reference:       strings.TrimSpace(cellAt(row, referenceCol)),
+			Amount:          amount,
+		})
+	}
+
+	if !headerFound {
+		return nil, fmt.Errorf("header row not found, check that the file matches the selected bank")
+	}
+	return entries, nil
+}
+
+// parseMT940 reads the :61: statement lines and the :86: information that follows each of them.
+func parseMT940(content []byte) ([]BankStatementEntry, error) {
+	var entries []BankStatementEntry
+	var current *BankStatementEntry
+	currentIsCredit := false
+	lastTag := ""
+	found := false
+
+	flush := func() {
+		if current != nil && currentIsCredit && current.Amount > 0 {
+			current.Description = strings.TrimSpace(current.Description)
+			entries = append(entries, *current)
+		}
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r ")
+		match := mt940TagPattern.FindStringSubmatch(text)
+		if match == nil {
+			// Continuation of the previous field
+			if lastTag == "86" && current != nil {
+				current.Description += " " + strings.TrimSpace(text)
+			}
+			continue
+		}
+
+		lastTag = match[1]
+		switch lastTag {
+		case "61":
+			flush()
+			entry, isCredit, err := parseMT940StatementLine(match[2])
+			if err != nil {
+				return nil, err
+			}
+			current = &entry
+			currentIsCredit = isCredit
+			found = true
+		case "86":
+			if current != nil {
+				current.Description = strings.TrimSpace(match[2])
+			}
+		default:
+			flush()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read MT940 file: %v", err)
+	}
+	flush()
+
+	if !found {
+		return nil, fmt.Errorf("no :61: statement lines found in the MT940 file")
+	}
+	return entries, nil
+}
+
+// parseMT940StatementLine reads YYMMDD[MMDD]<C|D|RC|RD>[funds code]<amount>N<type><reference>[//bank reference].
+func parseMT940StatementLine(value string) (BankStatementEntry, bool, error) {
+	var entry BankStatementEntry
+	if len(value) < 8 {
+		return entry, false, fmt.Errorf("invalid MT940 statement line %q", value)
+	}
+
+	transactionDate, err := time.Parse("060102", value[:6])
+	if err != nil {
+		return entry, false, fmt.Errorf("invalid MT940 statement line date %q", value[:6])
+	}
+	entry.TransactionDate = transactionDate
+
+	rest := value[6:]
+	if len(rest) >= 4 && isDigits(rest[:4]) {
+		rest = rest[4:]
+	}
+
+	// A reversed debit puts money back on the account, a reversed credit takes it out
+	isCredit := false
+	switch {
+	case strings.HasPrefix(rest, "RC"):
+		rest = rest[2:]
+	case strings.HasPrefix(rest, "RD"):
+		isCredit = true
+		rest = rest[2:]
+	case strings.HasPrefix(rest, "C"):
+		isCredit = true
+		rest = rest[1:]
+	case strings.HasPrefix(rest, "D"):
+		rest = rest[1:]
+	default:
+		return entry, false, fmt.Errorf("invalid MT940 debit/credit mark in %q", value)
+	}
+	if rest != "" && (rest[0] < '0' || rest[0] > '9') {
+		rest = rest[1:]
+	}
+
+	end := 0
+	for end < len(rest) && (rest[end] >= '0' && rest[end] <= '9' || rest[end] == ',') {
+		end++
+	}
+	amount, err := strconv.ParseFloat(strings.Replace(rest[:end], ",", ".", 1), 64)
+	if err != nil {
+		return entry, false, fmt.Errorf("invalid MT940 amount in %q", value)
+	}
+	entry.Amount = int64(math.Round(amount))
+
+	// N followed by the 3 character transaction type
+	rest = rest[end:]
+	if len(rest) >= 4 {
+		rest = rest[4:]
+	}
+	reference, bankReference, _ := strings.Cut(rest, "//")
+	entry.Reference = strings.TrimSpace(reference)
+	if entry.Reference == "" || entry.Reference == "NONREF" {
+		entry.Reference = strings.TrimSpace(bankReference)
+	}
+
+	return entry, isCredit, nil
+}
+
+// parseBankStatementDate accepts the date formats of the supported exports. BCA leaves out the
+// year and prefixes the date with a quote; such dates are taken as the most recent one.
+func parseBankStatementDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "'"))
+	for _, layout := range bankStatementDateLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, true
+		}
+	}
+
+	if parsed, err := time.Parse("02/01", value); err == nil {
+		now := time.Now()
+		parsed = parsed.AddDate(now.Year(), 0, 0)
+		if parsed.After(now) {
+			parsed = parsed.AddDate(-1, 0, 0)
+		}
+		return parsed, true
+	}
+	return time.Time{}, false
+}
+
+// parseBankStatementAmount reads amounts written as 1,500,000.00 or 1.500.000,00, optionally
+// followed by CR or DB. Cents are rounded to whole rupiah.
+func parseBankStatementAmount(value string) (int64, bool, bool) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	isDebit := false
+	switch {
+	case strings.HasSuffix(value, "DB"):
+		isDebit = true
+		value = strings.TrimSuffix(value, "DB")
+	case strings.HasSuffix(value, "CR"):
+		value = strings.TrimSuffix(value, "CR")
+	}
+	value = strings.NewReplacer("IDR", "", "RP", "", " ", "").Replace(value)
+	if strings.HasPrefix(value, "-") {
+		isDebit = true
+		value = strings.TrimPrefix(value, "-")
+	}
+	if value == "" {
+		return 0, isDebit, false
+	}
+
+	decimalSeparator := ""
+	lastDot, lastComma := strings.LastIndex(value, "."), strings.LastIndex(value, ",")
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		decimalSeparator = "."
+		if lastComma > lastDot {
+			decimalSeparator = ","
+		}
+	case lastComma >= 0 && strings.Count(value, ",") == 1 && len(value)-lastComma-1 != 3:
+		decimalSeparator = ","
+	case lastDot >= 0 && strings.Count(value, ".") == 1 && len(value)-lastDot-1 != 3:
+		decimalSeparator = "."
+	}
+
+	whole, fraction := value, ""
+	if decimalSeparator != "" {
+		index := strings.LastIndex(value, decimalSeparator)
+		whole, fraction = value[:index], value[index+1:]
+	}
+	whole = strings.NewReplacer(".", "", ",", "").Replace(whole)
+
+	amount, err := strconv.ParseFloat(whole+"."+fraction+"0", 64)
+	if err != nil {
+		return 0, isDebit, false
+	}
+	return int64(math.Round(amount)), isDebit, true
+}
+
+func cellAt(row []string, index int) string {
+	if index < 0 || index >= len(row) {
+		return ""
+	}
+	return row[index]
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return value != ""
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"testing"
+
+	request "schoolPayment/dtos/request"
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockBankStatementRepository struct {
+	mock.Mock
+}
+
+func (m *MockBankStatementRepository) GetBankAccountForSchool(bankAccountID uint, schoolID uint) (*models.BankAccount, error) {
+	args := m.Called(bankAccountID, schoolID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BankAccount), args.Error(1)
+}
+
+func (m *MockBankStatementRepository) GetExistingLineHashes(bankAccountID uint, hashes []string) (map[string]bool, error) {
+	args := m.Called(bankAccountID, hashes)
+	return args.Get(0).(map[string]bool), args.Error(1)
+}
+
+func (m *MockBankStatementRepository) CreateBankStatementImport(statementImport *models.BankStatementImport, lines []models.BankStatementLine) error {
+	args := m.Called(statementImport, lines)
+	return args.Error(0)
+}
+
+func (m *MockBankStatementRepository) GetAllBankStatementImport(page int, limit int, bankAccountID uint, schoolID uint) ([]models.BankStatementImport, int64, error) {
+	args := m.Called(page, limit, bankAccountID, schoolID)
+	return args.Get(0).([]models.BankStatementImport), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockBankStatementRepository) GetBankStatementImportByID(id uint, schoolID uint) (*models.BankStatementImport, error) {
+	args := m.Called(id, schoolID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BankStatementImport), args.Error(1)
+}
+
+func (m *MockBankStatementRepository) GetAllBankStatementLine(page int, limit int, importID uint, matchStatus string, schoolID uint) ([]models.BankStatementLine, int64, error) {
+	args := m.Called(page, limit, importID, matchStatus, schoolID)
+	return args.Get(0).([]models.BankStatementLine), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockBankStatementRepository) GetBankStatementLineByID(id uint, schoolID uint) (*models.BankStatementLine, error) {
+	args := m.Called(id, schoolID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BankStatementLine), args.Error(1)
+}
+
+func (m *MockBankStatementRepository) UpdateBankStatementLine(line *models.BankStatementLine) error {
+	args := m.Called(line)
+	return args.Error(0)
+}
+
+func (m *MockBankStatementRepository) GetSchoolStudents(schoolID uint) ([]response.BankStatementStudent, error) {
+	args := m.Called(schoolID)
+	return args.Get(0).([]response.BankStatementStudent), args.Error(1)
+}
+
+func (m *MockBankStatementRepository) GetOpenInstallmentsForBankAccount(bankAccountID uint) ([]models.BillingStudent, error) {
+	args := m.Called(bankAccountID)
+	return args.Get(0).([]models.BillingStudent), args.Error(1)
+}
+
+func (m *MockBankStatementRepository) PostBankStatementLine(line *models.BankStatementLine, studentID uint, billingStudentIDs []int, userID int) (*models.TransactionBilling, error) {
+	args := m.Called(line, studentID, billingStudentIDs, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TransactionBilling), args.Error(1)
+}
+
+func TestParseBankStatement(t *testing.T) {
+	t.Run("BCA", func(t *testing.T) {
+		content := "No. Rekening,:,1234567890\n" +
+			"Nama,:,YAYASAN PENDIDIKAN\n" +
+			"Tanggal Transaksi,Keterangan,Cabang,Jumlah,Saldo\n" +
+			"'15/01/2024,TRSF E-BANKING CR 1501/FTSCY/WS95031 1500000.00 SPP 20240017 BUDI,0000,\"1,500,000.00 CR\",\"10,000,000.00\"\n" +
+			"'16/01/2024,BIAYA ADM,0000,\"10,000.00 DB\",\"9,990,000.00\"\n" +
+			"Saldo Awal,:,\"8,500,000.00\"\n"
+
+		entries, err := ParseBankStatement(repositories.BankStatementFormatBCA, []byte(content))
+
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, int64(1500000), entries[0].Amount)
+		assert.Equal(t, "2024-01-15", entries[0].TransactionDate.Format("2006-01-02"))
+		assert.Contains(t, entries[0].Description, "20240017")
+	})
+
+	t.Run("BRI", func(t *testing.T) {
+		content := "Tanggal;Uraian Transaksi;Teller;Debet;Kredit;Saldo\n" +
+			"17/01/24;NBMB SITI KE YAYASAN 8808123420240021;8888;0,00;750.000,00;10.740.000,00\n" +
+			"18/01/24;PAJAK BUNGA;8888;1.250,00;0,00;10.738.750,00\n"
+
+		entries, err := ParseBankStatement(repositories.BankStatementFormatBRI, []byte(content))
+
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, int64(750000), entries[0].Amount)
+		assert.Equal(t, "2024-01-17", entries[0].TransactionDate.Format("2006-01-02"))
+	})
+
+	t.Run("Mandiri", func(t *testing.T) {
+		content := "Account No,Date,Val. Date,Transaction Code,Description1,Description2,Reference No.,Debit,Credit\n" +
+			"1230001234567,19/01/2024,19/01/2024,8,SA CASH DEP,NIS 20240033,FT24019ABC,.00,\"2,000,000.00\"\n"
+
+		entries, err := ParseBankStatement(repositories.BankStatementFormatMandiri, []byte(content))
+
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, int64(2000000), entries[0].Amount)
+		assert.Equal(t, "SA CASH DEP NIS 20240033", entries[0].Description)
+		assert.Equal(t, "FT24019ABC", entries[0].Reference)
+	})
+
+	t.Run("MT940", func(t *testing.T) {
+		content := ":20:STMT240120\n" +
+			":25:1234567890\n" +
+			":28C:00001/001\n" +
+			":60F:C240119IDR8500000,00\n" +
+			":61:2401200120C1500000,00NTRFREF001//BANKREF1\n" +
+			":86:TRANSFER DARI BUDI\n" +
+			"SPP 20240017\n" +
+			":61:240120D10000,00NCHGNONREF\n" +
+			":86:BIAYA ADM\n" +
+			":62F:C240120IDR9990000,00\n" +
+			"-\n"
+
+		entries, err := ParseBankStatement(repositories.BankStatementFormatMT940, []byte(content))
+
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, int64(1500000), entries[0].Amount)
+		assert.Equal(t, "REF001", entries[0].Reference)
+		assert.Equal(t, "TRANSFER DARI BUDI SPP 20240017", entries[0].Description)
+		assert.Equal(t, "2024-01-20", entries[0].TransactionDate.Format("2006-01-02"))
+	})
+
+	t.Run("Header of another bank", func(t *testing.T) {
+		_, err := ParseBankStatement(repositories.BankStatementFormatBRI, []byte("Tanggal Transaksi,Keterangan,Cabang,Jumlah,Saldo\n"))
+
+		assert.EqualError(t, err, "header row not found, check that the file matches the selected bank")
+	})
+}
+
+func TestParseBankStatementAmount(t *testing.T) {
+	cases := map[string]int64{
+		"1,500,000.00 CR": 1500000,
+		"1.500.000,00":    1500000,
+		"150.000":         150000,
+		"150,000":         150000,
+		"250000":          250000,
+		"Rp 99.999,50":    100000,
+	}
+	for value, expected := range cases {
+		amount, isDebit, ok := parseBankStatementAmount(value)
+		assert.True(t, ok, value)
+		assert.False(t, isDebit, value)
+		assert.Equal(t, expected, amount, value)
+	}
+
+	_, isDebit, _ := parseBankStatementAmount("10,000.00 DB")
+	assert.True(t, isDebit)
+}
+
+func TestMatchBankStatementLine(t *testing.T) {
+	students := []response.BankStatementStudent{
+		{StudentID: 1, Nis: "20240017"},
+		{StudentID: 2, Nis: "20240021"},
+	}
+	newInstallments := func() map[uint][]models.BillingStudent {
+		installments := []models.BillingStudent{
+			{StudentID: 1, Amount: 500000},
+			{StudentID: 1, Amount: 500000, PaidAmount: 200000},
+			{StudentID: 2, Amount: 750000},
+			{StudentID: 3, Amount: 325000},
+		}
+		for i := range installments {
+			installments[i].ID = uint(i + 1)
+		}
+		grouped := make(map[uint][]models.BillingStudent)
+		for _, installment := range installments {
+			grouped[installment.StudentID] = append(grouped[installment.StudentID], installment)
+		}
+		return grouped
+	}
+
+	t.Run("NIS and oldest installments", func(t *testing.T) {
+		line := models.BankStatementLine{Description: "TRSF SPP 20240017 BUDI", Amount: 800000}
+
+		MatchBankStatementLine(&line, students, newInstallments(), map[uint]bool{})
+
+		assert.Equal(t, repositories.BankStatementLineStatusProposed, line.MatchStatus)
+		assert.Equal(t, repositories.BankStatementMatchNis, line.MatchMethod)
+		assert.Equal(t, uint(1), *line.StudentID)
+		assert.Equal(t, "1,2", line.BillingStudentIds)
+	})
+
+	t.Run("Virtual account suffix", func(t *testing.T) {
+		line := models.BankStatementLine{Description: "NBMB SITI KE YAYASAN 8808123420240021", Amount: 750000}
+
+		MatchBankStatementLine(&line, students, newInstallments(), map[uint]bool{})
+
+		assert.Equal(t, repositories.BankStatementMatchVA, line.MatchMethod)
+		assert.Equal(t, "3", line.BillingStudentIds)
+	})
+
+	t.Run("Amount only", func(t *testing.T) {
+		line := models.BankStatementLine{Description: "SETORAN TUNAI", Amount: 325000}
+
+		MatchBankStatementLine(&line, students, newInstallments(), map[uint]bool{})
+
+		assert.Equal(t, repositories.BankStatementMatchAmount, line.MatchMethod)
+		assert.Equal(t, uint(3), *line.StudentID)
+		assert.Equal(t, "4", line.BillingStudentIds)
+	})
+
+	t.Run("Amount already proposed", func(t *testing.T) {
+		line := models.BankStatementLine{Description: "SETORAN TUNAI", Amount: 325000}
+
+		MatchBankStatementLine(&line, students, newInstallments(), map[uint]bool{4: true})
+
+		assert.Equal(t, "", line.MatchStatus)
+		assert.Nil(t, line.StudentID)
+	})
+
+	t.Run("Student found but amount does not fit", func(t *testing.T) {
+		line := models.BankStatementLine{Description: "SPP 20240017", Amount: 100000}
+
+		MatchBankStatementLine(&line, students, newInstallments(), map[uint]bool{})
+
+		assert.Equal(t, "", line.MatchStatus)
+		assert.Equal(t, uint(1), *line.StudentID)
+		assert.Equal(t, "", line.BillingStudentIds)
+	})
+}
+
+func TestImportBankStatement(t *testing.T) {
+	mockBankStatementRepo := new(MockBankStatementRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := NewBankStatementService(mockBankStatementRepo, mockUserRepo)
+
+	bankAccount := &models.BankAccount{SchoolID: 1}
+	bankAccount.ID = 6
+	content := "Tanggal;Uraian Transaksi;Teller;Debet;Kredit;Saldo\n" +
+		"17/01/24;SPP 20240017;8888;0,00;500.000,00;1.000.000,00\n" +
+		"17/01/24;SETORAN;8888;0,00;90.000,00;1.090.000,00\n" +
+		"17/01/24;SETORAN;8888;0,00;90.000,00;1.180.000,00\n"
+
+	existing := map[string]bool{}
+	mockUserRepo.On("GetUserByID", uint(4)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+	mockBankStatementRepo.On("GetBankAccountForSchool", uint(6), uint(1)).Return(bankAccount, nil)
+	mockBankStatementRepo.On("GetExistingLineHashes", uint(6), mock.MatchedBy(func(h []string) bool {
+		if len(h) != 3 || h[1] == h[2] {
+			return false
+		}
+		// The second identical row of the day was imported before.
+		existing[h[2]] = true
+		return true
+	})).Return(existing, nil)
+	mockBankStatementRepo.On("GetSchoolStudents", uint(1)).Return([]response.BankStatementStudent{{StudentID: 1, Nis: "20240017"}}, nil)
+	mockBankStatementRepo.On("GetOpenInstallmentsForBankAccount", uint(6)).Return([]models.BillingStudent{{StudentID: 1, Amount: 500000}}, nil)
+	mockBankStatementRepo.On("CreateBankStatementImport", mock.Anything, mock.MatchedBy(func(lines []models.BankStatementLine) bool {
+		return len(lines) == 2 && lines[0].MatchStatus == repositories.BankStatementLineStatusProposed &&
+			lines[1].MatchStatus == repositories.BankStatementLineStatusUnmatched
+	})).Return(nil)
+
+	statementImport, err := service.ImportBankStatement(&request.ImportBankStatementRequest{
+		BankAccountId: 6,
+		FileFormat:    repositories.BankStatementFormatBRI,
+	}, "mutasi.csv", []byte(content), 4)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, statementImport.TotalLines)
+	assert.Equal(t, 1, statementImport.TotalSkipped)
+	assert.Equal(t, 1, statementImport.TotalProposed)
+	assert.Equal(t, int64(590000), statementImport.TotalAmount)
+	mockBankStatementRepo.AssertExpectations(t)
+}
+
+func TestConfirmBankStatementLine(t *testing.T) {
+	studentID := uint(7)
+
+	t.Run("Posts the proposal", func(t *testing.T) {
+		mockBankStatementRepo := new(MockBankStatementRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewBankStatementService(mockBankStatementRepo, mockUserRepo)
+
+		line := &models.BankStatementLine{MatchStatus: repositories.BankStatementLineStatusProposed, StudentID: &studentID, BillingStudentIds: "11,12", Amount: 800000}
+		mockUserRepo.On("GetUserByID", uint(4)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+		mockBankStatementRepo.On("GetBankStatementLineByID", uint(3), uint(1)).Return(line, nil)
+		mockBankStatementRepo.On("PostBankStatementLine", line, uint(7), []int{11, 12}, 4).Return(&models.TransactionBilling{}, nil)
+
+		_, err := service.ConfirmBankStatementLine(3, &request.ConfirmBankStatementLineRequest{}, 4)
+
+		assert.NoError(t, err)
+		mockBankStatementRepo.AssertExpectations(t)
+	})
+
+	t.Run("Unmatched line needs a choice", func(t *testing.T) {
+		mockBankStatementRepo := new(MockBankStatementRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewBankStatementService(mockBankStatementRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(4)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+		mockBankStatementRepo.On("GetBankStatementLineByID", uint(3), uint(1)).
+			Return(&models.BankStatementLine{MatchStatus: repositories.BankStatementLineStatusUnmatched}, nil)
+
+		_, err := service.ConfirmBankStatementLine(3, &request.ConfirmBankStatementLineRequest{}, 4)
+
+		assert.EqualError(t, err, "bank statement line has no proposed match, choose the student and installments")
+	})
+
+	t.Run("Already posted", func(t *testing.T) {
+		mockBankStatementRepo := new(MockBankStatementRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewBankStatementService(mockBankStatementRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(4)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+		mockBankStatementRepo.On("GetBankStatementLineByID", uint(3), uint(1)).
+			Return(&models.BankStatementLine{MatchStatus: repositories.BankStatementLineStatusPosted}, nil)
+
+		_, err := service.ConfirmBankStatementLine(3, &request.ConfirmBankStatementLineRequest{StudentId: 7, BillingStudentIds: []int{11}}, 4)
+
+		assert.ErrorIs(t, err, repositories.ErrBankStatementLineClosed)
+	})
+}
@@ -33,6 +33,7 @@ type BillingService struct {
 	schoolYearRepository  repositories.SchoolYearRepository
 	schoolGradeRepository repositories.SchoolGradeRepositoryInterface
 	studentRepository	  repositories.StudentRepositoryInteface
+	discountProgramRepository repositories.DiscountProgramRepository
 }
 
 func NewBillingService(
@@ -42,6 +43,7 @@ func NewBillingService(
 	schoolYearRepository repositories.SchoolYearRepository, 
 	schoolGradeRepository repositories.SchoolGradeRepositoryInterface,
 	studentRepository	  repositories.StudentRepositoryInteface,
+	discountProgramRepository repositories.DiscountProgramRepository,
 	) BillingServiceInterface {
 	return &BillingService{
 		billingRepository:     billingRepository,
@@ -50,6 +52,7 @@ func NewBillingService(
 		schoolYearRepository:  schoolYearRepository,
 		schoolGradeRepository: schoolGradeRepository,
 		studentRepository: studentRepository,
+		discountProgramRepository: discountProgramRepository,
 	}
 }
 
@@ -255,7 +258,9 @@ func (billingService *BillingService) GenerateBillingToStudent(user models.User,
 		return err
 	}
 
-	for _, detail := range detailBillings {
+	var discounts []response.ApprovedStudentDiscount
+	var siblingTiers map[uint]*models.SiblingDiscountTier
+	for i, detail := range detailBillings {
 		dueDate, err := time.Parse("2006-01-02", detail.DueDate)
 		if err != nil {
 			return fmt.Errorf("error parsing due date: %w", err)
@@ -266,9 +271,28 @@ func (billingService *BillingService) GenerateBillingToStudent(user models.User,
 			return err
 		}
 
+		// Approved scholarships and discounts of the students are loaded once for every installment
+		if i == 0 {
+			studentIDs := make([]uint, 0, len(listStudent))
+			for _, student := range listStudent {
+				studentIDs = append(studentIDs, student.ID)
+			}
+			discounts, err = billingService.discountProgramRepository.GetApprovedStudentDiscounts(studentIDs)
+			if err != nil {
+				return err
+			}
+
+			if user.UserSchool != nil {
+				siblingTiers, err = LoadSiblingDiscountTiers(billingService.discountProgramRepository, user.UserSchool.SchoolID)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
 		for _, student := range listStudent {
 			// Save billing data for each billing detail and student
-			err = SaveDataBillingStudent(user, student, billing, &dueDate, detail, billingDetailId)
+			err = SaveDataBillingStudent(user, student, billing, &dueDate, detail, billingDetailId, discounts, siblingTiers[student.ID])
 			if err != nil {
 				return err
 			}
@@ -377,7 +401,7 @@ func GenerateBillingStudentInstallment(user models.User, student models.Student,
 	for {
 		nextMonth := time.Now().AddDate(0, i*period, 0) // Add one month
 		dueDate := time.Date(nextMonth.Year(), nextMonth.Month(), 10, 0, 0, 0, 0, nextMonth.Location())
-		err := SaveDataBillingStudent(user, student, billing, &dueDate, request.DetailBillings{}, 0)
+		err := SaveDataBillingStudent(user, student, billing, &dueDate, request.DetailBillings{}, 0, nil, nil)
 		if err != nil {
 			return err
 		}
@@ -391,7 +415,7 @@ func GenerateBillingStudentInstallment(user models.User, student models.Student,
 	return nil
 }
 
-func SaveDataBillingStudent(user models.User, student models.Student, billing *models.Billing, dueDate *time.Time, detailBillings request.DetailBillings, billingDetailId uint) error {
+func SaveDataBillingStudent(user models.User, student models.Student, billing *models.Billing, dueDate *time.Time, detailBillings request.DetailBillings, billingDetailId uint, discounts []response.ApprovedStudentDiscount, siblingTier *models.SiblingDiscountTier) error {
 	belumBayarCode, _ := BillingStatusCode("Belum bayar")
 	strBelumBayarCode := strconv.Itoa(belumBayarCode)
 	billingStudent := models.BillingStudent{
@@ -405,6 +429,10 @@ func SaveDataBillingStudent(user models.User, student models.Student, billing *m
 	}
 	billingStudent.Master.CreatedBy = int(user.ID)
 	billingStudent.Master.UpdatedBy = int(user.ID)
+	ApplyStudentDiscount(&billingStudent, billing, discounts)
+	if !billing.IsDonation {
+		ApplySiblingDiscount(&billingStudent, siblingTier)
+	}
 
 	_, err := repositories.CreateBillingStudent(&billingStudent, "", true)
 	if err != nil {
@@ -99,6 +99,15 @@ func (billingHistoryService *BillingHistoryService) GetAllBillingHistory(page in
 	listBilling.TotalData = totalData
 	listBilling.TotalPage = totalPage
 
+	if studentID != 0 {
+		creditBalance, creditHistory, err := repositories.GetStudentCreditBalanceAndRecent(uint(studentID), studentCreditRecentLimit)
+		if err != nil {
+			return listBilling, err
+		}
+		listBilling.CreditBalance = creditBalance
+		listBilling.CreditHistory = creditHistory
+	}
+
 	return listBilling, nil
 }
 
@@ -110,20 +119,15 @@ func (billingHistoryService *BillingHistoryService) GetDetailBillingHistoryIDSer
 
 	paymentMethod, _ := billingHistoryService.paymentMethodRepositories.GetPaymentMethodByID(getBillingId.PaymentMethodId)
 
-	billingAmount, err := billingHistoryService.billingHistoryRepository.TotalAmountBillingStudent(int(getBillingId.ID))
-	if err != nil {
-		return nil, err
-	}
-
 	adminFee := int64(0)
 	paymentMethodStr := "Kasir" // Default for PT01
 
 	if getBillingId.TransactionType == "PT02" {
-		adminFee, err = utilities.CalculateAdminFee(billingAmount, paymentMethod)
-		if err != nil {
-			return nil, err
-		}
+		// Only the part of the admin fee the parent paid was added to the bill
+		adminFee = getBillingId.ParentAdminFee
 		paymentMethodStr = paymentMethod.PaymentMethod + "-" + paymentMethod.BankName
+	} else if getBillingId.TransactionType == repositories.TransactionTypeBankTransfer {
+		paymentMethodStr = "Transfer Bank"
 	}
 
 	response := response.DetailBillingHistory{
@@ -138,6 +142,7 @@ func (billingHistoryService *BillingHistoryService) GetDetailBillingHistoryIDSer
 		PaymentDate:        getBillingId.PaymentDate,
 		AdminFee:           adminFee,
 		PaymentMethod:      paymentMethodStr,
+		CreditAmount:       getBillingId.CreditAmount,
 	}
 
 	ids := strings.Split(getBillingId.BillingStudentIds, ",")
@@ -50,12 +50,15 @@ func (s *billingReportService) GetBillingReport(page int, limit int, sortBy stri
 		return rsp, err
 	}
 
+	rsp.TotalGrossAmount = utilities.FormatBigInt(summary.TotalGrossAmount)
+	rsp.TotalDiscountAmount = utilities.FormatBigInt(summary.TotalDiscountAmount)
 	rsp.TotalBillingAmount = utilities.FormatBigInt(summary.TotalBillingAmount)
 	rsp.TotalPayAmount = utilities.FormatBigInt(summary.TotalPayAmount)
 	rsp.TotalPayAmount = utilities.FormatBigInt(summary.TotalPayAmount)
 	rsp.TotalNotPayAmount = utilities.FormatBigInt(summary.TotalNotPayAmount)
 	rsp.TotalBillingPay = summary.TotalBillingPay
 	rsp.TotalBillingNotPay = summary.TotalBillingNotPay
+	rsp.TotalBillingPartialPay = summary.TotalBillingPartialPay
 	rsp.TotalStudent = summary.TotalStudent
 
 	if sortBy != "" {
@@ -81,7 +84,11 @@ func (s *billingReportService) GetBillingReport(page int, limit int, sortBy stri
 			SchoolGradeName:   report.SchoolGradeName,
 			SchoolClassName:   report.SchoolClassName,
 			SchoolYearName:    report.SchoolYearName,
+			GrossAmount:       report.GrossAmount,
+			DiscountAmount:    report.DiscountAmount,
 			Amount:            report.Amount,
+			PaidAmount:        report.PaidAmount,
+			RemainingAmount:   report.RemainingAmount,
 			BankAccountName:   bankAccountName,
 			PaymentStatus:     report.PaymentStatus,
 		}
@@ -124,38 +131,44 @@ func (s *billingReportService) ExportToExcel(limit int, page int, sortBy string,
 	// Tulis header rekapitulasi di atas tabel
 	fmt.Printf("Rp %s\n", report.TotalBillingAmount)
 	fmt.Printf("Rp %s\n", utilities.FormatCurrency(report.TotalBillingAmount))
-	excelUtil.SetCellValue(sheetName, "B1", "Total Tagihan")
-	excelUtil.SetCellValue(sheetName, "C1", fmt.Sprintf(utilities.FormatCurrency(report.TotalBillingAmount)))
-	excelUtil.SetCellValue(sheetName, "B2", "Total Sudah Dibayar")
-	excelUtil.SetCellValue(sheetName, "C2", fmt.Sprintf(utilities.FormatCurrency(report.TotalPayAmount)))
-	excelUtil.SetCellValue(sheetName, "B3", "Total Belum Dibayar")
-	excelUtil.SetCellValue(sheetName, "C3", fmt.Sprintf(utilities.FormatCurrency(report.TotalNotPayAmount)))
-	excelUtil.SetCellValue(sheetName, "B4", "Jumlah Sudah Dibayar")
-	excelUtil.SetCellValue(sheetName, "C4", utilities.FormatWithThousandsSeparator(report.TotalBillingPay))
-	excelUtil.SetCellValue(sheetName, "B5", "Jumlah Belum Dibayar")
-	excelUtil.SetCellValue(sheetName, "C5", utilities.FormatWithThousandsSeparator(report.TotalBillingNotPay))
-	excelUtil.SetCellValue(sheetName, "B6", "Jumlah Siswa")
-	excelUtil.SetCellValue(sheetName, "C6", utilities.FormatWithThousandsSeparator(report.TotalStudent))
+	excelUtil.SetCellValue(sheetName, "B1", "Total Tagihan Kotor")
+	excelUtil.SetCellValue(sheetName, "C1", fmt.Sprintf(utilities.FormatCurrency(report.TotalGrossAmount)))
+	excelUtil.SetCellValue(sheetName, "B2", "Total Diskon/Beasiswa")
+	excelUtil.SetCellValue(sheetName, "C2", fmt.Sprintf(utilities.FormatCurrency(report.TotalDiscountAmount)))
+	excelUtil.SetCellValue(sheetName, "B3", "Total Tagihan")
+	excelUtil.SetCellValue(sheetName, "C3", fmt.Sprintf(utilities.FormatCurrency(report.TotalBillingAmount)))
+	excelUtil.SetCellValue(sheetName, "B4", "Total Sudah Dibayar")
+	excelUtil.SetCellValue(sheetName, "C4", fmt.Sprintf(utilities.FormatCurrency(report.TotalPayAmount)))
+	excelUtil.SetCellValue(sheetName, "B5", "Total Belum Dibayar")
+	excelUtil.SetCellValue(sheetName, "C5", fmt.Sprintf(utilities.FormatCurrency(report.TotalNotPayAmount)))
+	excelUtil.SetCellValue(sheetName, "B6", "Jumlah Sudah Dibayar")
+	excelUtil.SetCellValue(sheetName, "C6", utilities.FormatWithThousandsSeparator(report.TotalBillingPay))
+	excelUtil.SetCellValue(sheetName, "B7", "Jumlah Belum Dibayar")
+	excelUtil.SetCellValue(sheetName, "C7", utilities.FormatWithThousandsSeparator(report.TotalBillingNotPay))
+	excelUtil.SetCellValue(sheetName, "B8", "Jumlah Dibayar Sebagian")
+	excelUtil.SetCellValue(sheetName, "C8", utilities.FormatWithThousandsSeparator(report.TotalBillingPartialPay))
+	excelUtil.SetCellValue(sheetName, "B9", "Jumlah Siswa")
+	excelUtil.SetCellValue(sheetName, "C9", utilities.FormatWithThousandsSeparator(report.TotalStudent))
 
 	// Terapkan gaya bold pada semua header
-	excelUtil.SetCellStyle(sheetName, "B1", "C6", boldStyle)
+	excelUtil.SetCellStyle(sheetName, "B1", "C9", boldStyle)
 
 	// Tulis header tabel
 	headers := []string{
 		"No.", "Nama Tagihan", "Tipe Tagihan", "Nama Siswa", "Unit",
-		"Kelas", "Tahun Ajaran", "Jumlah Tagihan", "Rekening Bank",
-		"Status",
+		"Kelas", "Tahun Ajaran", "Tagihan Kotor", "Diskon/Beasiswa", "Jumlah Tagihan", "Sudah Dibayar",
+		"Sisa Tagihan", "Rekening Bank", "Status",
 	}
 
 	for i, header := range headers {
-		cell := fmt.Sprintf("%s8", string(rune(65+i))) // Baris 8 untuk header tabel
+		cell := fmt.Sprintf("%s11", string(rune(65+i))) // Baris 11 untuk header tabel
 		excelUtil.SetCellValue(sheetName, cell, header)
 		excelUtil.SetCellStyle(sheetName, cell, cell, boldStyle)
 	}
 
-	// Tulis data billing report ke dalam tabel (mulai dari baris ke-9)
+	// Tulis data billing report ke dalam tabel (mulai dari baris ke-12)
 	for idx, detail := range report.ListBillingReport.Data {
-		rowIdx := idx + 9 // Start from row 9
+		rowIdx := idx + 12 // Start from row 12
 		data := []interface{}{
 			idx + 1,
 			detail.DetailBillingName,
@@ -164,7 +177,11 @@ func (s *billingReportService) ExportToExcel(limit int, page int, sortBy string,
 			detail.SchoolGradeName,
 			detail.SchoolClassName,
 			detail.SchoolYearName,
+			"RP " + utilities.FormatWithThousandsSeparator(int(detail.GrossAmount)),
+			"RP " + utilities.FormatWithThousandsSeparator(int(detail.DiscountAmount)),
 			"RP " + utilities.FormatWithThousandsSeparator(int(detail.Amount)),
+			"RP " + utilities.FormatWithThousandsSeparator(int(detail.PaidAmount)),
+			"RP " + utilities.FormatWithThousandsSeparator(int(detail.RemainingAmount)),
 			detail.BankAccountName,
 			detail.PaymentStatus,
 		}
@@ -175,7 +192,7 @@ func (s *billingReportService) ExportToExcel(limit int, page int, sortBy string,
 	}
 
 	// Sesuaikan lebar kolom secara otomatis
-	columns := []string{"A", "B", "C", "D", "E", "F", "G", "H", "I", "J"}
+	columns := []string{"A", "B", "C", "D", "E", "F", "G", "H", "I", "J", "K", "L", "M", "N"}
 	for _, col := range columns {
 		if err := excelUtil.AutoFitColumn(sheetName, col); err != nil {
 			return nil, fmt.Errorf("failed to auto-fit column %s: %v", col, err)
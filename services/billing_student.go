@@ -30,6 +30,7 @@ type BillingStudentService struct {
 	billingRepository        repositories.BillingRepositoryInterface
 	schoolGradeRepository    repositories.SchoolGradeRepositoryInterface
 	studentRepository		 repositories.StudentRepositoryInteface
+	discountProgramRepository repositories.DiscountProgramRepository
 }
 
 func NewBillingStudentService(
@@ -40,6 +41,7 @@ func NewBillingStudentService(
 	billingRepository repositories.BillingRepositoryInterface, 
 	schoolGradeRepository repositories.SchoolGradeRepositoryInterface,
 	studentRepository repositories.StudentRepositoryInteface,
+	discountProgramRepository repositories.DiscountProgramRepository,
 	) BillingStudentServiceInterface {
 	return &BillingStudentService{
 		billingStudentRepository: billingStudentRepository, 
@@ -49,6 +51,7 @@ func NewBillingStudentService(
 		billingRepository: billingRepository,
 		schoolGradeRepository: schoolGradeRepository,
 		studentRepository: studentRepository,
+		discountProgramRepository: discountProgramRepository,
 	}
 }
 
@@ -283,9 +286,29 @@ func (service *BillingStudentService) CreateBillingStudent(request request.Creat
 		existingMap[key] = true
 	}
 
+	discounts, err := service.discountProgramRepository.GetApprovedStudentDiscounts([]uint{uint(request.StudentID)})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching student discounts: %v", err)
+	}
+
+	user, err := service.userRepository.GetUserByID(uint(userID))
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	var siblingTier *models.SiblingDiscountTier
+	if user.UserSchool != nil {
+		siblingTiers, err := LoadSiblingDiscountTiers(service.discountProgramRepository, user.UserSchool.SchoolID)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching sibling discount tiers: %v", err)
+		}
+		siblingTier = siblingTiers[uint(request.StudentID)]
+	}
+
 	// Prepare batch insert
 	var billingStudentsToCreate []models.BillingStudent
 	uniqueCombinations := make(map[string]bool)
+	billings := make(map[uint]*models.Billing)
 
 	for detailID, detail := range detailsMap {
 		// Check if combination already exists
@@ -313,6 +336,22 @@ func (service *BillingStudentService) CreateBillingStudent(request request.Creat
 			BillingDetailID:   detailID,
 		}
 
+		if len(discounts) > 0 || siblingTier != nil {
+			billing, ok := billings[detail.BillingID]
+			if !ok {
+				found, err := service.billingRepository.GetBillingByID(int(detail.BillingID))
+				if err != nil {
+					return nil, fmt.Errorf("error fetching billing: %v", err)
+				}
+				billing = &found
+				billings[detail.BillingID] = billing
+			}
+			ApplyStudentDiscount(&billingStudent, billing, discounts)
+			if !billing.IsDonation {
+				ApplySiblingDiscount(&billingStudent, siblingTier)
+			}
+		}
+
 		billingStudentsToCreate = append(billingStudentsToCreate, billingStudent)
 		uniqueCombinations[uniqueKey] = true
 	}
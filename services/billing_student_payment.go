@@ -0,0 +1,37 @@
+package services
+
+import "schoolPayment/models"
+
+type BillingStudentPaymentAllocation struct {
+	BillingStudent *models.BillingStudent
+	Amount         int64
+}
+
+// AllocateBillingStudentPayment spreads amount over the installments in the
+// given order, filling each remaining balance before moving to the next one.
+func AllocateBillingStudentPayment(billingStudents []models.BillingStudent, amount int64) []BillingStudentPaymentAllocation {
+	var allocations []BillingStudentPaymentAllocation
+	for i := range billingStudents {
+		if amount <= 0 {
+			break
+		}
+
+		remaining := billingStudents[i].Amount - billingStudents[i].PaidAmount
+		if remaining <= 0 {
+			continue
+		}
+
+		paid := remaining
+		if amount < remaining {
+			paid = amount
+		}
+
+		allocations = append(allocations, BillingStudentPaymentAllocation{
+			BillingStudent: &billingStudents[i],
+			Amount:         paid,
+		})
+		amount -= paid
+	}
+
+	return allocations
+}
@@ -0,0 +1,38 @@
+package services_test
+
+import (
+	"schoolPayment/models"
+	"schoolPayment/services"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllocateBillingStudentPayment_PartialSecondInstallment(t *testing.T) {
+	billingStudents := []models.BillingStudent{
+		{Master: models.Master{ID: 1}, Amount: 500000, PaidAmount: 200000},
+		{Master: models.Master{ID: 2}, Amount: 500000},
+		{Master: models.Master{ID: 3}, Amount: 500000},
+	}
+
+	allocations := services.AllocateBillingStudentPayment(billingStudents, 450000)
+
+	assert.Len(t, allocations, 2)
+	assert.Equal(t, uint(1), allocations[0].BillingStudent.ID)
+	assert.Equal(t, int64(300000), allocations[0].Amount)
+	assert.Equal(t, uint(2), allocations[1].BillingStudent.ID)
+	assert.Equal(t, int64(150000), allocations[1].Amount)
+}
+
+func TestAllocateBillingStudentPayment_SkipsPaidInstallments(t *testing.T) {
+	billingStudents := []models.BillingStudent{
+		{Master: models.Master{ID: 1}, Amount: 500000, PaidAmount: 500000},
+		{Master: models.Master{ID: 2}, Amount: 500000},
+	}
+
+	allocations := services.AllocateBillingStudentPayment(billingStudents, 1000000)
+
+	assert.Len(t, allocations, 1)
+	assert.Equal(t, uint(2), allocations[0].BillingStudent.ID)
+	assert.Equal(t, int64(500000), allocations[0].Amount)
+}
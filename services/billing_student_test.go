@@ -43,6 +43,11 @@ func (m *MockBillingStudentRepository) GetListBillingId(billingStudentIds []stri
 	return args.Get(0).([]int), args.Error(1)
 }
 
+func (m *MockBillingStudentRepository) GetBillingStudentsByIDs(billingStudentIds []int) ([]models.BillingStudent, error) {
+	args := m.Called(billingStudentIds)
+	return args.Get(0).([]models.BillingStudent), args.Error(1)
+}
+
 func (m *MockBillingStudentRepository) CheckBillingStudentExists(studentID, billingID, billingDetailID uint) (bool, error) {
 	args := m.Called(studentID, billingID, billingDetailID)
 	return args.Bool(0), args.Error(1)
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	request "schoolPayment/dtos/request"
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+	"schoolPayment/utilities"
+)
+
+const cashierShiftSheetName = "Laporan Shift Kasir"
+
+type CashierShiftServiceInterface interface {
+	OpenCashierShift(openRequest *request.OpenCashierShiftRequest, userID int) (*models.CashierShift, error)
+	GetCurrentCashierShift(userID int) (*models.CashierShift, error)
+	CloseCashierShift(id uint, closeRequest *request.CloseCashierShiftRequest, userID int) (*models.CashierShift, error)
+	GetAllCashierShift(page int, limit int, cashierID int, status string, startDate, endDate time.Time, userID int) (response.CashierShiftListResponse, error)
+	GetCashierShiftReport(id uint, userID int) (*response.CashierShiftReport, error)
+	ExportCashierShiftPDF(id uint, userID int) (*bytes.Buffer, string, error)
+	ExportCashierShiftExcel(id uint, userID int) (*bytes.Buffer, string, error)
+}
+
+type CashierShiftService struct {
+	cashierShiftRepository repositories.CashierShiftRepository
+	userRepository         repositories.UserRepository
+	schoolRepository       repositories.SchoolRepository
+}
+
+func NewCashierShiftService(cashierShiftRepository repositories.CashierShiftRepository, userRepository repositories.UserRepository, schoolRepository repositories.SchoolRepository) CashierShiftServiceInterface {
+	return &CashierShiftService{
+		cashierShiftRepository: cashierShiftRepository,
+		userRepository:         userRepository,
+		schoolRepository:       schoolRepository,
+	}
+}
+
+func (cashierShiftService *CashierShiftService) OpenCashierShift(openRequest *request.OpenCashierShiftRequest, userID int) (*models.CashierShift, error) {
+	if openRequest.OpeningFloat < 0 {
+		return nil, fmt.Errorf("opening float cannot be negative")
+	}
+
+	user, err := cashierShiftService.getSchoolUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	shift := models.CashierShift{
+		SchoolID:     user.UserSchool.SchoolID,
+		UserID:       user.ID,
+		Status:       repositories.CashierShiftStatusOpen,
+		OpenedAt:     time.Now(),
+		OpeningFloat: openRequest.OpeningFloat,
+		ExpectedCash: openRequest.OpeningFloat,
+		Note:         openRequest.Note,
+	}
+	shift.CreatedBy = userID
+
+	if err := cashierShiftService.cashierShiftRepository.CreateCashierShift(&shift); err != nil {
+		return nil, err
+	}
+	return &shift, nil
+}
+
+func (cashierShiftService *CashierShiftService) GetCurrentCashierShift(userID int) (*models.CashierShift, error) {
+	return cashierShiftService.cashierShiftRepository.GetOpenCashierShift(uint(userID))
+}
+
+// CloseCashierShift records the counted cash. A kasir closes their own drawer, Tata Usaha and
+// Admin Sekolah may close any shift of the school, e.g. one left open overnight.
+func (cashierShiftService *CashierShiftService) CloseCashierShift(id uint, closeRequest *request.CloseCashierShiftRequest, userID int) (*models.CashierShift, error) {
+	if closeRequest.CountedCash < 0 {
+		return nil, fmt.Errorf("counted cash cannot be negative")
+	}
+
+	user, err := cashierShiftService.getSchoolUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	shift, err := cashierShiftService.cashierShiftRepository.GetCashierShiftByID(id, user.UserSchool.SchoolID)
+	if err != nil {
+		return nil, fmt.Errorf("cashier shift not found")
+	}
+	if err := checkCashierShiftOwner(shift, user); err != nil {
+		return nil, err
+	}
+
+	return cashierShiftService.cashierShiftRepository.CloseCashierShift(id, user.UserSchool.SchoolID, closeRequest.CountedCash, closeRequest.Note, userID)
+}
+
+func (cashierShiftService *CashierShiftService) GetAllCashierShift(page int, limit int, cashierID int, status string, startDate, endDate time.Time, userID int) (response.CashierShiftListResponse, error) {
+	resp := response.CashierShiftListResponse{
+		Page:  page,
+		Limit: limit,
+		Data:  []response.CashierShiftData{},
+	}
+
+	user, err := cashierShiftService.getSchoolUser(userID)
+	if err != nil {
+		return resp, err
+	}
+
+	// A kasir only sees their own shifts
+	if user.RoleID == 4 {
+		cashierID = int(user.ID)
+	}
+
+	shifts, total, err := cashierShiftService.cashierShiftRepository.GetAllCashierShift(page, limit, user.UserSchool.SchoolID, uint(cashierID), status, startDate, endDate)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.TotalData = total
+	if limit != 0 {
+		resp.TotalPage = int((total + int64(limit) - 1) / int64(limit))
+	}
+	if len(shifts) > 0 {
+		resp.Data = shifts
+	}
+
+	return resp, nil
+}
+
+func (cashierShiftService *CashierShiftService) GetCashierShiftReport(id uint, userID int) (*response.CashierShiftReport, error) {
+	user, err := cashierShiftService.getSchoolUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	shift, err := cashierShiftService.cashierShiftRepository.GetCashierShiftByID(id, user.UserSchool.SchoolID)
+	if err != nil {
+		return nil, fmt.Errorf("cashier shift not found")
+	}
+	if err := checkCashierShiftOwner(shift, user); err != nil {
+		return nil, err
+	}
+
+	transactions, err := cashierShiftService.cashierShiftRepository.GetCashierShiftTransactions(shift.ID)
+	if err != nil {
+		return nil, err
+	}
+	if transactions == nil {
+		transactions = []response.CashierShiftTransaction{}
+	}
+
+	report := response.CashierShiftReport{
+		Shift:        response.CashierShiftData{CashierShift: *shift},
+		Transactions: transactions,
+	}
+
+	if cashier, err := cashierShiftService.userRepository.GetUserByID(shift.UserID); err == nil {
+		report.Shift.Username = cashier.Username
+	}
+	if school, err := cashierShiftService.schoolRepository.GetSchoolByID(shift.SchoolID); err == nil {
+		report.SchoolName = school.SchoolName
+	}
+
+	return &report, nil
+}
+
+func (cashierShiftService *CashierShiftService) ExportCashierShiftPDF(id uint, userID int) (*bytes.Buffer, string, error) {
+	report, err := cashierShiftService.GetCashierShiftReport(id, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	buffer, err := utilities.GenerateCashierShiftReportPDF(*report)
+	if err != nil {
+		return nil, "", err
+	}
+	return buffer, cashierShiftReportFilename(report, "pdf"), nil
+}
+
+func (cashierShiftService *CashierShiftService) ExportCashierShiftExcel(id uint, userID int) (*bytes.Buffer, string, error) {
+	report, err := cashierShiftService.GetCashierShiftReport(id, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	excelUtil := utilities.NewExcelUtility()
+	defer excelUtil.Close()
+
+	excelUtil.File.SetSheetName("Sheet1", cashierShiftSheetName)
+
+	boldStyle, err := excelUtil.CreateBoldStyle()
+	if err != nil {
+		return nil, "", err
+	}
+	rupiahStyle, err := excelUtil.CreateRupiahStyle()
+	if err != nil {
+		return nil, "", err
+	}
+
+	shift := report.Shift
+	summary := []struct {
+		label string
+		value interface{}
+	}{
+		{"Sekolah", report.SchoolName},
+		{"Kasir", shift.Username},
+		{"Status", strings.Title(shift.Status)},
+		{"Dibuka", shift.OpenedAt.Format("02/01/2006 15:04:05")},
+		{"Ditutup", formatCashierShiftTime(shift.ClosedAt)},
+		{"Jumlah Transaksi", shift.TotalTransaction},
+		{"Modal Awal", shift.OpeningFloat},
+		{"Uang Masuk", shift.CashReceived},
+		{"Kas Seharusnya", shift.ExpectedCash},
+		{"Kas Dihitung", derefInt64(shift.CountedCash)},
+		{"Selisih", derefInt64(shift.Variance)},
+	}
+	for i, item := range summary {
+		labelCell := fmt.Sprintf("A%d", i+1)
+		valueCell := fmt.Sprintf("B%d", i+1)
+		excelUtil.SetCellValue(cashierShiftSheetName, labelCell, item.label)
+		excelUtil.SetCellStyle(cashierShiftSheetName, labelCell, labelCell, boldStyle)
+		excelUtil.SetCellValue(cashierShiftSheetName, valueCell, item.value)
+		if _, ok := item.value.(int64); ok {
+			excelUtil.SetCellStyle(cashierShiftSheetName, valueCell, valueCell, rupiahStyle)
+		}
+	}
+
+	headerRow := len(summary) + 2
+	headers := []string{"No.", "No. Invoice", "Waktu", "Nama Siswa", "NIS", "Dibayar", "Kembalian", "Uang Masuk"}
+	for i, header := range headers {
+		cell := fmt.Sprintf("%s%d", utilities.GetColumnName(i), headerRow)
+		excelUtil.SetCellValue(cashierShiftSheetName, cell, header)
+		excelUtil.SetCellStyle(cashierShiftSheetName, cell, cell, boldStyle)
+	}
+
+	for i, item := range report.Transactions {
+		row := headerRow + i + 1
+		excelUtil.SetCellValue(cashierShiftSheetName, fmt.Sprintf("A%d", row), i+1)
+		excelUtil.SetCellValue(cashierShiftSheetName, fmt.Sprintf("B%d", row), item.InvoiceNumber)
+		excelUtil.SetCellValue(cashierShiftSheetName, fmt.Sprintf("C%d", row), item.CreatedAt.Format("02/01/2006 15:04:05"))
+		excelUtil.SetCellValue(cashierShiftSheetName, fmt.Sprintf("D%d", row), item.StudentName)
+		excelUtil.SetCellValue(cashierShiftSheetName, fmt.Sprintf("E%d", row), item.Nis)
+		excelUtil.SetCellValue(cashierShiftSheetName, fmt.Sprintf("F%d", row), item.TotalAmount)
+		excelUtil.SetCellValue(cashierShiftSheetName, fmt.Sprintf("G%d", row), item.ChangeAmount)
+		excelUtil.SetCellValue(cashierShiftSheetName, fmt.Sprintf("H%d", row), item.CashAmount)
+		excelUtil.SetCellStyle(cashierShiftSheetName, fmt.Sprintf("F%d", row), fmt.Sprintf("H%d", row), rupiahStyle)
+	}
+
+	for _, col := range []string{"A", "B", "C", "D", "E", "F", "G", "H"} {
+		if err := excelUtil.AutoFitColumn(cashierShiftSheetName, col); err != nil {
+			return nil, "", fmt.Errorf("failed to auto-fit column %s: %v", col, err)
+		}
+	}
+
+	buffer, err := excelUtil.WriteToBuffer()
+	if err != nil {
+		return nil, "", err
+	}
+	return buffer, cashierShiftReportFilename(report, "xlsx"), nil
+}
+
+func (cashierShiftService *CashierShiftService) getSchoolUser(userID int) (models.User, error) {
+	user, err := cashierShiftService.userRepository.GetUserByID(uint(userID))
+	if err != nil {
+		return user, err
+	}
+	if user.UserSchool == nil {
+		return user, fmt.Errorf("user not associated with any school")
+	}
+	return user, nil
+}
+
+func checkCashierShiftOwner(shift *models.CashierShift, user models.User) error {
+	if user.RoleID == 4 && shift.UserID != user.ID {
+		return fmt.Errorf("cashier shift not found")
+	}
+	return nil
+}
+
+func cashierShiftReportFilename(report *response.CashierShiftReport, ext string) string {
+	return fmt.Sprintf("Laporan_Shift_Kasir_%s_%s.%s", report.Shift.Username, report.Shift.OpenedAt.Format("02-01-2006"), ext)
+}
+
+func formatCashierShiftTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format("02/01/2006 15:04:05")
+}
+
+func derefInt64(value *int64) int64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
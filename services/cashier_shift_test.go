@@ -0,0 +1,169 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	request "schoolPayment/dtos/request"
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCashierShiftRepository struct {
+	mock.Mock
+}
+
+func (m *MockCashierShiftRepository) CreateCashierShift(shift *models.CashierShift) error {
+	args := m.Called(shift)
+	return args.Error(0)
+}
+
+func (m *MockCashierShiftRepository) GetOpenCashierShift(userID uint) (*models.CashierShift, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CashierShift), args.Error(1)
+}
+
+func (m *MockCashierShiftRepository) GetCashierShiftByID(id uint, schoolID uint) (*models.CashierShift, error) {
+	args := m.Called(id, schoolID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CashierShift), args.Error(1)
+}
+
+func (m *MockCashierShiftRepository) GetAllCashierShift(page int, limit int, schoolID uint, userID uint, status string, startDate, endDate time.Time) ([]response.CashierShiftData, int64, error) {
+	args := m.Called(page, limit, schoolID, userID, status, startDate, endDate)
+	return args.Get(0).([]response.CashierShiftData), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockCashierShiftRepository) GetCashierShiftTransactions(shiftID uint) ([]response.CashierShiftTransaction, error) {
+	args := m.Called(shiftID)
+	return args.Get(0).([]response.CashierShiftTransaction), args.Error(1)
+}
+
+func (m *MockCashierShiftRepository) CloseCashierShift(id uint, schoolID uint, countedCash int64, note string, userID int) (*models.CashierShift, error) {
+	args := m.Called(id, schoolID, countedCash, note, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CashierShift), args.Error(1)
+}
+
+func cashierShiftTestUser(id uint, roleID uint) *models.User {
+	user := &models.User{RoleID: roleID, UserSchool: &models.UserSchool{SchoolID: 1}}
+	user.ID = id
+	return user
+}
+
+func TestOpenCashierShift(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockShiftRepo := new(MockCashierShiftRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewCashierShiftService(mockShiftRepo, mockUserRepo, nil)
+
+		mockUserRepo.On("GetUserByID", uint(3)).Return(cashierShiftTestUser(3, 4), nil)
+		mockShiftRepo.On("CreateCashierShift", mock.MatchedBy(func(shift *models.CashierShift) bool {
+			return shift.UserID == 3 && shift.SchoolID == 1 && shift.OpeningFloat == 200000 &&
+				shift.Status == repositories.CashierShiftStatusOpen
+		})).Return(nil)
+
+		shift, err := service.OpenCashierShift(&request.OpenCashierShiftRequest{OpeningFloat: 200000}, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(200000), shift.ExpectedCash)
+		mockShiftRepo.AssertExpectations(t)
+	})
+
+	t.Run("Already open", func(t *testing.T) {
+		mockShiftRepo := new(MockCashierShiftRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewCashierShiftService(mockShiftRepo, mockUserRepo, nil)
+
+		mockUserRepo.On("GetUserByID", uint(3)).Return(cashierShiftTestUser(3, 4), nil)
+		mockShiftRepo.On("CreateCashierShift", mock.Anything).Return(repositories.ErrCashierShiftOpen)
+
+		_, err := service.OpenCashierShift(&request.OpenCashierShiftRequest{}, 3)
+
+		assert.Equal(t, repositories.ErrCashierShiftOpen, err)
+	})
+
+	t.Run("Negative opening float", func(t *testing.T) {
+		mockShiftRepo := new(MockCashierShiftRepository)
+		service := NewCashierShiftService(mockShiftRepo, new(MockUserRepository), nil)
+
+		_, err := service.OpenCashierShift(&request.OpenCashierShiftRequest{OpeningFloat: -1}, 3)
+
+		assert.EqualError(t, err, "opening float cannot be negative")
+		mockShiftRepo.AssertNotCalled(t, "CreateCashierShift", mock.Anything)
+	})
+}
+
+func TestCloseCashierShift(t *testing.T) {
+	t.Run("Kasir closes own shift", func(t *testing.T) {
+		mockShiftRepo := new(MockCashierShiftRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewCashierShiftService(mockShiftRepo, mockUserRepo, nil)
+
+		variance := int64(-5000)
+		closed := &models.CashierShift{UserID: 3, Status: repositories.CashierShiftStatusClosed, Variance: &variance}
+		mockUserRepo.On("GetUserByID", uint(3)).Return(cashierShiftTestUser(3, 4), nil)
+		mockShiftRepo.On("GetCashierShiftByID", uint(9), uint(1)).Return(&models.CashierShift{UserID: 3, Status: repositories.CashierShiftStatusOpen}, nil)
+		mockShiftRepo.On("CloseCashierShift", uint(9), uint(1), int64(695000), "kurang 5rb", 3).Return(closed, nil)
+
+		shift, err := service.CloseCashierShift(9, &request.CloseCashierShiftRequest{CountedCash: 695000, Note: "kurang 5rb"}, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(-5000), *shift.Variance)
+	})
+
+	t.Run("Kasir cannot close another drawer", func(t *testing.T) {
+		mockShiftRepo := new(MockCashierShiftRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewCashierShiftService(mockShiftRepo, mockUserRepo, nil)
+
+		mockUserRepo.On("GetUserByID", uint(3)).Return(cashierShiftTestUser(3, 4), nil)
+		mockShiftRepo.On("GetCashierShiftByID", uint(9), uint(1)).Return(&models.CashierShift{UserID: 4, Status: repositories.CashierShiftStatusOpen}, nil)
+
+		_, err := service.CloseCashierShift(9, &request.CloseCashierShiftRequest{CountedCash: 1000}, 3)
+
+		assert.EqualError(t, err, "cashier shift not found")
+		mockShiftRepo.AssertNotCalled(t, "CloseCashierShift", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Tata Usaha closes a forgotten shift", func(t *testing.T) {
+		mockShiftRepo := new(MockCashierShiftRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewCashierShiftService(mockShiftRepo, mockUserRepo, nil)
+
+		mockUserRepo.On("GetUserByID", uint(5)).Return(cashierShiftTestUser(5, 3), nil)
+		mockShiftRepo.On("GetCashierShiftByID", uint(9), uint(1)).Return(&models.CashierShift{UserID: 4, Status: repositories.CashierShiftStatusOpen}, nil)
+		mockShiftRepo.On("CloseCashierShift", uint(9), uint(1), int64(1000), "", 5).Return(&models.CashierShift{}, nil)
+
+		_, err := service.CloseCashierShift(9, &request.CloseCashierShiftRequest{CountedCash: 1000}, 5)
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestGetAllCashierShift_KasirSeesOwnShifts(t *testing.T) {
+	mockShiftRepo := new(MockCashierShiftRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := NewCashierShiftService(mockShiftRepo, mockUserRepo, nil)
+
+	mockUserRepo.On("GetUserByID", uint(3)).Return(cashierShiftTestUser(3, 4), nil)
+	mockShiftRepo.On("GetAllCashierShift", 1, 10, uint(1), uint(3), "", time.Time{}, time.Time{}).
+		Return([]response.CashierShiftData{{Username: "kasir"}}, int64(1), nil)
+
+	resp, err := service.GetAllCashierShift(1, 10, 7, "", time.Time{}, time.Time{}, 3)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, resp.TotalPage)
+	assert.Len(t, resp.Data, 1)
+}
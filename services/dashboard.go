@@ -67,11 +67,13 @@ func (dashboardService *DashboardService) GetDashboardFromParent(user models.Use
 		if latestBillings, err := repositories.GetBillingStudentsDashboard(int(student.ID), 2); err == nil {
 			for _, billing := range latestBillings {
 				detailDashboardResponse.ListLatestBilling = append(detailDashboardResponse.ListLatestBilling, response.ListLatestBillingResponse{
-					BillingName: billing.DetailBillingName,
-					StudentName: student.FullName,
-					DueDate:     billing.DueDate.Format("2006-01-02"), // Format date as string
-					Amount:      billing.Amount,
-					Status:      billing.PaymentStatus,
+					BillingName:     billing.DetailBillingName,
+					StudentName:     student.FullName,
+					DueDate:         billing.DueDate.Format("2006-01-02"), // Format date as string
+					Amount:          billing.Amount,
+					PaidAmount:      billing.PaidAmount,
+					RemainingAmount: billing.RemainingAmount,
+					Status:          billing.PaymentStatus,
 				})
 			}
 		} else {
@@ -0,0 +1,403 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	request "schoolPayment/dtos/request"
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+	"schoolPayment/utilities"
+)
+
+type DiscountProgramServiceInterface interface {
+	CreateDiscountProgram(programRequest *request.DiscountProgramRequest, userID int) (*models.DiscountProgram, error)
+	UpdateDiscountProgram(programID uint, programRequest *request.DiscountProgramRequest, userID int) (*models.DiscountProgram, error)
+	DeleteDiscountProgram(programID uint, userID int) error
+	GetAllDiscountProgram(page int, limit int, search string, userID int) (response.DiscountProgramListResponse, error)
+	GetDiscountProgramByID(programID uint, userID int) (*models.DiscountProgram, error)
+	CreateStudentDiscount(discountRequest *request.CreateStudentDiscountRequest, userID int) (*models.StudentDiscount, error)
+	ApproveStudentDiscount(studentDiscountID uint, userID int) (*models.StudentDiscount, error)
+	RejectStudentDiscount(studentDiscountID uint, rejectRequest *request.RejectStudentDiscountRequest, userID int) (*models.StudentDiscount, error)
+	GetAllStudentDiscount(page int, limit int, studentID uint, discountStatus string, userID int) (response.StudentDiscountListResponse, error)
+	GetStudentDiscountByID(studentDiscountID uint, userID int) (*models.StudentDiscount, error)
+}
+
+type DiscountProgramService struct {
+	discountProgramRepository repositories.DiscountProgramRepository
+	userRepository            repositories.UserRepository
+}
+
+func NewDiscountProgramService(discountProgramRepository repositories.DiscountProgramRepository, userRepository repositories.UserRepository) DiscountProgramServiceInterface {
+	return &DiscountProgramService{
+		discountProgramRepository: discountProgramRepository,
+		userRepository:            userRepository,
+	}
+}
+
+func (discountProgramService *DiscountProgramService) CreateDiscountProgram(programRequest *request.DiscountProgramRequest, userID int) (*models.DiscountProgram, error) {
+	schoolID, err := discountProgramService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	program := &models.DiscountProgram{SchoolID: schoolID, IsActive: true}
+	if err := discountProgramService.fillDiscountProgram(program, programRequest); err != nil {
+		return nil, err
+	}
+	program.CreatedBy = userID
+
+	return discountProgramService.discountProgramRepository.CreateDiscountProgram(program)
+}
+
+// UpdateDiscountProgram changes the program for installments created from now on; amounts
+// already billed keep the discount they were given.
+func (discountProgramService *DiscountProgramService) UpdateDiscountProgram(programID uint, programRequest *request.DiscountProgramRequest, userID int) (*models.DiscountProgram, error) {
+	program, err := discountProgramService.GetDiscountProgramByID(programID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("discount program not found")
+	}
+
+	if err := discountProgramService.fillDiscountProgram(program, programRequest); err != nil {
+		return nil, err
+	}
+	program.UpdatedBy = userID
+
+	if err := discountProgramService.discountProgramRepository.UpdateDiscountProgram(program); err != nil {
+		return nil, err
+	}
+	return program, nil
+}
+
+func (discountProgramService *DiscountProgramService) DeleteDiscountProgram(programID uint, userID int) error {
+	program, err := discountProgramService.GetDiscountProgramByID(programID, userID)
+	if err != nil {
+		return fmt.Errorf("discount program not found")
+	}
+
+	now := time.Now()
+	program.DeletedAt = &now
+	program.DeletedBy = &userID
+	return discountProgramService.discountProgramRepository.UpdateDiscountProgram(program)
+}
+
+func (discountProgramService *DiscountProgramService) GetAllDiscountProgram(page int, limit int, search string, userID int) (response.DiscountProgramListResponse, error) {
+	resp := response.DiscountProgramListResponse{
+		Page:  page,
+		Limit: limit,
+		Data:  []models.DiscountProgram{},
+	}
+
+	schoolID, err := discountProgramService.getSchoolID(userID)
+	if err != nil {
+		return resp, err
+	}
+
+	programs, total, err := discountProgramService.discountProgramRepository.GetAllDiscountProgram(page, limit, search, schoolID)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.TotalData = total
+	if limit != 0 {
+		resp.TotalPage = int((total + int64(limit) - 1) / int64(limit))
+	}
+	if len(programs) > 0 {
+		resp.Data = programs
+	}
+
+	return resp, nil
+}
+
+func (discountProgramService *DiscountProgramService) GetDiscountProgramByID(programID uint, userID int) (*models.DiscountProgram, error) {
+	schoolID, err := discountProgramService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return discountProgramService.discountProgramRepository.GetDiscountProgramByID(programID, schoolID)
+}
+
+// CreateStudentDiscount assigns a program to a student. It is only used for billing once
+// Admin Sekolah approves it.
+func (discountProgramService *DiscountProgramService) CreateStudentDiscount(discountRequest *request.CreateStudentDiscountRequest, userID int) (*models.StudentDiscount, error) {
+	if err := utilities.ValidateFieldNotEmpty(discountRequest.ValidFrom, "validFrom"); err != nil {
+		return nil, err
+	}
+
+	validFrom, err := time.Parse("2006-01-02", discountRequest.ValidFrom)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing validFrom: %w", err)
+	}
+
+	var validUntil *time.Time
+	if discountRequest.ValidUntil != "" {
+		until, err := time.Parse("2006-01-02", discountRequest.ValidUntil)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing validUntil: %w", err)
+		}
+		if until.Before(validFrom) {
+			return nil, fmt.Errorf("validUntil cannot be before validFrom")
+		}
+		validUntil = &until
+	}
+
+	schoolID, err := discountProgramService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := discountProgramService.discountProgramRepository.GetDiscountProgramByID(discountRequest.DiscountProgramId, schoolID)
+	if err != nil {
+		return nil, fmt.Errorf("discount program not found")
+	}
+	if !program.IsActive {
+		return nil, fmt.Errorf("discount program is not active")
+	}
+
+	owned, err := discountProgramService.discountProgramRepository.StudentBelongsToSchool(discountRequest.StudentId, schoolID)
+	if err != nil {
+		return nil, err
+	}
+	if !owned {
+		return nil, fmt.Errorf("student not found")
+	}
+
+	studentDiscount := &models.StudentDiscount{
+		SchoolID:          schoolID,
+		StudentID:         discountRequest.StudentId,
+		DiscountProgramID: program.ID,
+		DiscountStatus:    repositories.StudentDiscountStatusWaitingApproval,
+		ValidFrom:         validFrom,
+		ValidUntil:        validUntil,
+		Note:              discountRequest.Note,
+	}
+	studentDiscount.CreatedBy = userID
+
+	return discountProgramService.discountProgramRepository.CreateStudentDiscount(studentDiscount)
+}
+
+func (discountProgramService *DiscountProgramService) ApproveStudentDiscount(studentDiscountID uint, userID int) (*models.StudentDiscount, error) {
+	studentDiscount, err := discountProgramService.getWaitingStudentDiscount(studentDiscountID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	studentDiscount.DiscountStatus = repositories.StudentDiscountStatusApproved
+	studentDiscount.ApprovedBy = &userID
+	studentDiscount.ApprovedAt = &now
+	studentDiscount.UpdatedBy = userID
+
+	if err := discountProgramService.discountProgramRepository.UpdateStudentDiscount(studentDiscount); err != nil {
+		return nil, err
+	}
+	return studentDiscount, nil
+}
+
+func (discountProgramService *DiscountProgramService) RejectStudentDiscount(studentDiscountID uint, rejectRequest *request.RejectStudentDiscountRequest, userID int) (*models.StudentDiscount, error) {
+	if err := utilities.ValidateFieldNotEmpty(rejectRequest.RejectionReason, "rejectionReason"); err != nil {
+		return nil, err
+	}
+
+	studentDiscount, err := discountProgramService.getWaitingStudentDiscount(studentDiscountID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	studentDiscount.DiscountStatus = repositories.StudentDiscountStatusRejected
+	studentDiscount.RejectionReason = rejectRequest.RejectionReason
+	studentDiscount.ApprovedBy = &userID
+	studentDiscount.ApprovedAt = &now
+	studentDiscount.UpdatedBy = userID
+
+	if err := discountProgramService.discountProgramRepository.UpdateStudentDiscount(studentDiscount); err != nil {
+		return nil, err
+	}
+	return studentDiscount, nil
+}
+
+func (discountProgramService *DiscountProgramService) GetAllStudentDiscount(page int, limit int, studentID uint, discountStatus string, userID int) (response.StudentDiscountListResponse, error) {
+	resp := response.StudentDiscountListResponse{
+		Page:  page,
+		Limit: limit,
+		Data:  []models.StudentDiscount{},
+	}
+
+	schoolID, err := discountProgramService.getSchoolID(userID)
+	if err != nil {
+		return resp, err
+	}
+
+	studentDiscounts, total, err := discountProgramService.discountProgramRepository.GetAllStudentDiscount(page, limit, studentID, discountStatus, schoolID)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.TotalData = total
+	if limit != 0 {
+		resp.TotalPage = int((total + int64(limit) - 1) / int64(limit))
+	}
+	if len(studentDiscounts) > 0 {
+		resp.Data = studentDiscounts
+	}
+
+	return resp, nil
+}
+
+func (discountProgramService *DiscountProgramService) GetStudentDiscountByID(studentDiscountID uint, userID int) (*models.StudentDiscount, error) {
+	schoolID, err := discountProgramService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return discountProgramService.discountProgramRepository.GetStudentDiscountByID(studentDiscountID, schoolID)
+}
+
+// ApplyStudentDiscount takes the largest approved discount covering the installment off its
+// amount. A discount covers it when the program matches the billing type and school year and
+// the due date falls inside the assignment's validity period. An installment the discount covers
+// in full is paid, as there is nothing left to pay.
+func ApplyStudentDiscount(billingStudent *models.BillingStudent, billing *models.Billing, discounts []response.ApprovedStudentDiscount) {
+	if billing.IsDonation || billingStudent.Amount <= 0 {
+		return
+	}
+
+	billedOn := time.Now().Format("2006-01-02")
+	if billingStudent.DueDate != nil {
+		billedOn = billingStudent.DueDate.Format("2006-01-02")
+	}
+
+	var best int64
+	var bestID uint
+	for _, discount := range discounts {
+		if discount.StudentID != billingStudent.StudentID {
+			continue
+		}
+		if discount.BillingTypeID != nil && strconv.Itoa(int(*discount.BillingTypeID)) != billing.BillingType {
+			continue
+		}
+		if discount.SchoolYearID != nil && *discount.SchoolYearID != billing.SchoolYearId {
+			continue
+		}
+		if billedOn < discount.ValidFrom.Format("2006-01-02") {
+			continue
+		}
+		if discount.ValidUntil != nil && billedOn > discount.ValidUntil.Format("2006-01-02") {
+			continue
+		}
+
+		amount := CalculateDiscount(discount.DiscountType, discount.Amount, billingStudent.Amount)
+		if amount > best {
+			best = amount
+			bestID = discount.StudentDiscountID
+		}
+	}
+
+	if best == 0 {
+		return
+	}
+	billingStudent.DiscountAmount = best
+	billingStudent.Amount -= best
+	billingStudent.StudentDiscountID = &bestID
+	setDiscountedPaymentStatus(billingStudent)
+}
+
+// setDiscountedPaymentStatus marks an installment that discounts took down to nothing as paid, so
+// it does not show up as unpaid or overdue, and one that has an amount to pay again as unpaid.
+// Installments with money paid on them keep the status their payments gave them.
+func setDiscountedPaymentStatus(billingStudent *models.BillingStudent) {
+	if billingStudent.PaidAmount > 0 {
+		return
+	}
+	if billingStudent.Amount == 0 {
+		billingStudent.PaymentStatus = repositories.BillingStudentStatusPaid
+	} else if billingStudent.PaymentStatus == repositories.BillingStudentStatusPaid {
+		billingStudent.PaymentStatus = repositories.BillingStudentStatusUnpaid
+	}
+}
+
+// CalculateDiscount is the part of amount a program takes off, never more than amount itself.
+func CalculateDiscount(discountType string, value float64, amount int64) int64 {
+	discount := int64(math.Round(value))
+	if discountType == repositories.DiscountTypePercentage {
+		discount = int64(math.Round(float64(amount) * value / 100))
+	}
+	if discount > amount {
+		discount = amount
+	}
+	return discount
+}
+
+func (discountProgramService *DiscountProgramService) fillDiscountProgram(program *models.DiscountProgram, programRequest *request.DiscountProgramRequest) error {
+	programRequest.ProgramName = strings.TrimSpace(programRequest.ProgramName)
+	if err := utilities.ValidateFieldNotEmpty(programRequest.ProgramName, "programName"); err != nil {
+		return err
+	}
+	if programRequest.DiscountType != repositories.DiscountTypePercentage && programRequest.DiscountType != repositories.DiscountTypeFixed {
+		return fmt.Errorf("discountType must be percentage or fixed")
+	}
+	if programRequest.Amount <= 0 {
+		return fmt.Errorf("amount must be greater than 0")
+	}
+	if programRequest.DiscountType == repositories.DiscountTypePercentage && programRequest.Amount > 100 {
+		return fmt.Errorf("percentage cannot be more than 100")
+	}
+
+	if programRequest.BillingTypeId != nil {
+		owned, err := discountProgramService.discountProgramRepository.BillingTypeBelongsToSchool(*programRequest.BillingTypeId, program.SchoolID)
+		if err != nil {
+			return err
+		}
+		if !owned {
+			return fmt.Errorf("billing type not found")
+		}
+	}
+
+	if programRequest.SchoolYearId != nil {
+		exists, err := discountProgramService.discountProgramRepository.SchoolYearExists(*programRequest.SchoolYearId)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("school year not found")
+		}
+	}
+
+	program.ProgramName = programRequest.ProgramName
+	program.DiscountType = programRequest.DiscountType
+	program.Amount = programRequest.Amount
+	program.BillingTypeID = programRequest.BillingTypeId
+	program.SchoolYearID = programRequest.SchoolYearId
+	program.Description = programRequest.Description
+	if programRequest.IsActive != nil {
+		program.IsActive = *programRequest.IsActive
+	}
+	return nil
+}
+
+func (discountProgramService *DiscountProgramService) getWaitingStudentDiscount(studentDiscountID uint, userID int) (*models.StudentDiscount, error) {
+	studentDiscount, err := discountProgramService.GetStudentDiscountByID(studentDiscountID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("student discount not found")
+	}
+
+	if studentDiscount.DiscountStatus != repositories.StudentDiscountStatusWaitingApproval {
+		return nil, fmt.Errorf("student discount has already been processed")
+	}
+	return studentDiscount, nil
+}
+
+func (discountProgramService *DiscountProgramService) getSchoolID(userID int) (uint, error) {
+	user, err := discountProgramService.userRepository.GetUserByID(uint(userID))
+	if err != nil {
+		return 0, err
+	}
+	if user.UserSchool == nil {
+		return 0, fmt.Errorf("user not associated with any school")
+	}
+	return user.UserSchool.SchoolID, nil
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	request "schoolPayment/dtos/request"
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDiscountProgramRepository struct {
+	mock.Mock
+}
+
+func (m *MockDiscountProgramRepository) CreateDiscountProgram(program *models.DiscountProgram) (*models.DiscountProgram, error) {
+	args := m.Called(program)
+	return program, args.Error(0)
+}
+
+func (m *MockDiscountProgramRepository) UpdateDiscountProgram(program *models.DiscountProgram) error {
+	args := m.Called(program)
+	return args.Error(0)
+}
+
+func (m *MockDiscountProgramRepository) GetDiscountProgramByID(id uint, schoolID uint) (*models.DiscountProgram, error) {
+	args := m.Called(id, schoolID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DiscountProgram), args.Error(1)
+}
+
+func (m *MockDiscountProgramRepository) GetAllDiscountProgram(page int, limit int, search string, schoolID uint) ([]models.DiscountProgram, int64, error) {
+	args := m.Called(page, limit, search, schoolID)
+	return args.Get(0).([]models.DiscountProgram), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockDiscountProgramRepository) BillingTypeBelongsToSchool(billingTypeID uint, schoolID uint) (bool, error) {
+	args := m.Called(billingTypeID, schoolID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockDiscountProgramRepository) SchoolYearExists(schoolYearID uint) (bool, error) {
+	args := m.Called(schoolYearID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockDiscountProgramRepository) StudentBelongsToSchool(studentID uint, schoolID uint) (bool, error) {
+	args := m.Called(studentID, schoolID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockDiscountProgramRepository) CreateStudentDiscount(studentDiscount *models.StudentDiscount) (*models.StudentDiscount, error) {
+	args := m.Called(studentDiscount)
+	return studentDiscount, args.Error(0)
+}
+
+func (m *MockDiscountProgramRepository) UpdateStudentDiscount(studentDiscount *models.StudentDiscount) error {
+	args := m.Called(studentDiscount)
+	return args.Error(0)
+}
+
+func (m *MockDiscountProgramRepository) GetStudentDiscountByID(id uint, schoolID uint) (*models.StudentDiscount, error) {
+	args := m.Called(id, schoolID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.StudentDiscount), args.Error(1)
+}
+
+func (m *MockDiscountProgramRepository) GetAllStudentDiscount(page int, limit int, studentID uint, discountStatus string, schoolID uint) ([]models.StudentDiscount, int64, error) {
+	args := m.Called(page, limit, studentID, discountStatus, schoolID)
+	return args.Get(0).([]models.StudentDiscount), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockDiscountProgramRepository) GetApprovedStudentDiscounts(studentIDs []uint) ([]response.ApprovedStudentDiscount, error) {
+	args := m.Called(studentIDs)
+	return args.Get(0).([]response.ApprovedStudentDiscount), args.Error(1)
+}
+
+func (m *MockDiscountProgramRepository) CreateSiblingDiscountTier(tier *models.SiblingDiscountTier) (*models.SiblingDiscountTier, error) {
+	args := m.Called(tier)
+	return tier, args.Error(0)
+}
+
+func (m *MockDiscountProgramRepository) UpdateSiblingDiscountTier(tier *models.SiblingDiscountTier) error {
+	args := m.Called(tier)
+	return args.Error(0)
+}
+
+func (m *MockDiscountProgramRepository) GetSiblingDiscountTierByID(id uint, schoolID uint) (*models.SiblingDiscountTier, error) {
+	args := m.Called(id, schoolID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SiblingDiscountTier), args.Error(1)
+}
+
+func (m *MockDiscountProgramRepository) GetAllSiblingDiscountTier(page int, limit int, schoolID uint) ([]models.SiblingDiscountTier, int64, error) {
+	args := m.Called(page, limit, schoolID)
+	return args.Get(0).([]models.SiblingDiscountTier), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockDiscountProgramRepository) SiblingDiscountTierExists(childOrder int, schoolID uint, excludeID uint) (bool, error) {
+	args := m.Called(childOrder, schoolID, excludeID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockDiscountProgramRepository) GetActiveSiblingDiscountTiers(schoolID uint) ([]models.SiblingDiscountTier, error) {
+	args := m.Called(schoolID)
+	return args.Get(0).([]models.SiblingDiscountTier), args.Error(1)
+}
+
+func (m *MockDiscountProgramRepository) GetHouseholdLinks(schoolID uint) ([]response.HouseholdLink, error) {
+	args := m.Called(schoolID)
+	return args.Get(0).([]response.HouseholdLink), args.Error(1)
+}
+
+func (m *MockDiscountProgramRepository) GetOpenSiblingDiscountInstallments(schoolID uint) ([]models.BillingStudent, error) {
+	args := m.Called(schoolID)
+	return args.Get(0).([]models.BillingStudent), args.Error(1)
+}
+
+func (m *MockDiscountProgramRepository) UpdateInstallmentSiblingDiscount(billingStudent *models.BillingStudent) (bool, error) {
+	args := m.Called(billingStudent)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestApplyStudentDiscount(t *testing.T) {
+	billingTypeID := uint(3)
+	otherSchoolYearID := uint(9)
+	validUntil := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	discounts := []response.ApprovedStudentDiscount{
+		{StudentDiscountID: 1, StudentID: 7, ValidFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), ValidUntil: &validUntil, DiscountType: repositories.DiscountTypePercentage, Amount: 50, BillingTypeID: &billingTypeID},
+		{StudentDiscountID: 2, StudentID: 7, ValidFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), DiscountType: repositories.DiscountTypeFixed, Amount: 100000},
+		{StudentDiscountID: 3, StudentID: 7, ValidFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), DiscountType: repositories.DiscountTypeFixed, Amount: 400000, SchoolYearID: &otherSchoolYearID},
+		{StudentDiscountID: 4, StudentID: 8, ValidFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), DiscountType: repositories.DiscountTypeFixed, Amount: 400000},
+	}
+	billing := &models.Billing{BillingType: "3", SchoolYearId: 2}
+
+	t.Run("Largest matching discount", func(t *testing.T) {
+		dueDate := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+		billingStudent := models.BillingStudent{StudentID: 7, Amount: 500000, DueDate: &dueDate}
+
+		ApplyStudentDiscount(&billingStudent, billing, discounts)
+
+		assert.Equal(t, int64(250000), billingStudent.DiscountAmount)
+		assert.Equal(t, int64(250000), billingStudent.Amount)
+		assert.Equal(t, uint(1), *billingStudent.StudentDiscountID)
+	})
+
+	t.Run("Outside the validity period", func(t *testing.T) {
+		dueDate := time.Date(2024, 7, 10, 0, 0, 0, 0, time.UTC)
+		billingStudent := models.BillingStudent{StudentID: 7, Amount: 500000, DueDate: &dueDate, PaymentStatus: repositories.BillingStudentStatusUnpaid}
+
+		ApplyStudentDiscount(&billingStudent, billing, discounts)
+
+		assert.Equal(t, int64(100000), billingStudent.DiscountAmount)
+		assert.Equal(t, int64(400000), billingStudent.Amount)
+		assert.Equal(t, uint(2), *billingStudent.StudentDiscountID)
+		assert.Equal(t, repositories.BillingStudentStatusUnpaid, billingStudent.PaymentStatus)
+	})
+
+	t.Run("Fully covered installment is paid", func(t *testing.T) {
+		dueDate := time.Date(2024, 7, 10, 0, 0, 0, 0, time.UTC)
+		billingStudent := models.BillingStudent{StudentID: 7, Amount: 100000, DueDate: &dueDate, PaymentStatus: repositories.BillingStudentStatusUnpaid}
+
+		ApplyStudentDiscount(&billingStudent, billing, discounts)
+
+		assert.Equal(t, int64(0), billingStudent.Amount)
+		assert.Equal(t, repositories.BillingStudentStatusPaid, billingStudent.PaymentStatus)
+	})
+
+	t.Run("Donation is never discounted", func(t *testing.T) {
+		dueDate := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+		billingStudent := models.BillingStudent{StudentID: 7, Amount: 500000, DueDate: &dueDate}
+
+		ApplyStudentDiscount(&billingStudent, &models.Billing{IsDonation: true}, discounts)
+
+		assert.Equal(t, int64(0), billingStudent.DiscountAmount)
+		assert.Equal(t, int64(500000), billingStudent.Amount)
+		assert.Nil(t, billingStudent.StudentDiscountID)
+	})
+}
+
+func TestCalculateDiscount(t *testing.T) {
+	assert.Equal(t, int64(75000), CalculateDiscount(repositories.DiscountTypePercentage, 25, 300000))
+	assert.Equal(t, int64(300000), CalculateDiscount(repositories.DiscountTypeFixed, 500000, 300000))
+	assert.Equal(t, int64(300000), CalculateDiscount(repositories.DiscountTypePercentage, 100, 300000))
+}
+
+func TestCreateStudentDiscount(t *testing.T) {
+	program := &models.DiscountProgram{IsActive: true}
+	program.ID = 5
+
+	t.Run("Success", func(t *testing.T) {
+		mockDiscountRepo := new(MockDiscountProgramRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewDiscountProgramService(mockDiscountRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(3)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+		mockDiscountRepo.On("GetDiscountProgramByID", uint(5), uint(1)).Return(program, nil)
+		mockDiscountRepo.On("StudentBelongsToSchool", uint(7), uint(1)).Return(true, nil)
+		mockDiscountRepo.On("CreateStudentDiscount", mock.Anything).Return(nil)
+
+		studentDiscount, err := service.CreateStudentDiscount(&request.CreateStudentDiscountRequest{
+			StudentId:         7,
+			DiscountProgramId: 5,
+			ValidFrom:         "2024-07-01",
+			ValidUntil:        "2025-06-30",
+		}, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, repositories.StudentDiscountStatusWaitingApproval, studentDiscount.DiscountStatus)
+		assert.Equal(t, uint(5), studentDiscount.DiscountProgramID)
+		assert.Equal(t, "2025-06-30", studentDiscount.ValidUntil.Format("2006-01-02"))
+	})
+
+	t.Run("Validity ends before it starts", func(t *testing.T) {
+		service := NewDiscountProgramService(new(MockDiscountProgramRepository), new(MockUserRepository))
+
+		_, err := service.CreateStudentDiscount(&request.CreateStudentDiscountRequest{
+			StudentId:         7,
+			DiscountProgramId: 5,
+			ValidFrom:         "2024-07-01",
+			ValidUntil:        "2024-06-30",
+		}, 3)
+
+		assert.EqualError(t, err, "validUntil cannot be before validFrom")
+	})
+}
+
+func TestApproveStudentDiscount(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockDiscountRepo := new(MockDiscountProgramRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewDiscountProgramService(mockDiscountRepo, mockUserRepo)
+
+		studentDiscount := &models.StudentDiscount{DiscountStatus: repositories.StudentDiscountStatusWaitingApproval}
+		mockUserRepo.On("GetUserByID", uint(4)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+		mockDiscountRepo.On("GetStudentDiscountByID", uint(12), uint(1)).Return(studentDiscount, nil)
+		mockDiscountRepo.On("UpdateStudentDiscount", studentDiscount).Return(nil)
+
+		result, err := service.ApproveStudentDiscount(12, 4)
+
+		assert.NoError(t, err)
+		assert.Equal(t, repositories.StudentDiscountStatusApproved, result.DiscountStatus)
+		assert.Equal(t, 4, *result.ApprovedBy)
+	})
+
+	t.Run("Already processed", func(t *testing.T) {
+		mockDiscountRepo := new(MockDiscountProgramRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewDiscountProgramService(mockDiscountRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(4)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+		mockDiscountRepo.On("GetStudentDiscountByID", uint(12), uint(1)).
+			Return(&models.StudentDiscount{DiscountStatus: repositories.StudentDiscountStatusRejected}, nil)
+
+		_, err := service.ApproveStudentDiscount(12, 4)
+
+		assert.EqualError(t, err, "student discount has already been processed")
+	})
+}
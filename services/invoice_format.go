@@ -14,8 +14,14 @@ import (
 type InvoiceFormatServiceInterface interface {
 	Create(request *request.CreateInvoiceFormatRequest, userID int) (*models.InvoiceFormat, error)
 	GetBySchoolID(schoolID uint) (*models.InvoiceFormat, error)
+	Preview(request *request.CreateInvoiceFormatRequest) ([]string, error)
 }
 
+const (
+	defaultInvoicePreviewCount = 5
+	maxInvoicePreviewCount     = 50
+)
+
 type InvoiceFormatService struct {
 	invoiceFormatRepo repositories.InvoiceFormatRepositoryInterface
 }
@@ -30,7 +36,11 @@ func NewInvoiceFormatService(invoiceFormatRepo repositories.InvoiceFormatReposit
 
 
 func (s *InvoiceFormatService) Create(request *request.CreateInvoiceFormatRequest, userID int) (*models.InvoiceFormat, error) {
-	
+	resetPeriod, err := validateInvoiceFormatTemplate(request)
+	if err != nil {
+		return nil, err
+	}
+
 	existingFormat, err := s.invoiceFormatRepo.GetBySchoolID(request.SchoolID)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
@@ -43,6 +53,8 @@ func (s *InvoiceFormatService) Create(request *request.CreateInvoiceFormatReques
 	if existingFormat != nil {
 		existingFormat.Prefix = request.Prefix
 		existingFormat.Format = request.Format
+		existingFormat.Template = request.Template
+		existingFormat.ResetPeriod = resetPeriod
 		existingFormat.GeneratedInvoiceFormat = request.GeneratedInvoiceFormat
 		existingFormat.UpdatedAt = now
 		existingFormat.UpdatedBy = uint(userID)
@@ -59,6 +71,8 @@ func (s *InvoiceFormatService) Create(request *request.CreateInvoiceFormatReques
 		SchoolID:               request.SchoolID,
 		Prefix:                 request.Prefix,
 		Format:                 request.Format,
+		Template:               request.Template,
+		ResetPeriod:            resetPeriod,
 		GeneratedInvoiceFormat: request.GeneratedInvoiceFormat,
 		CreatedAt:              now,
 		CreatedBy:              uint(userID),
@@ -77,3 +91,41 @@ func (s *InvoiceFormatService) Create(request *request.CreateInvoiceFormatReques
 func (s *InvoiceFormatService) GetBySchoolID(schoolID uint) (*models.InvoiceFormat, error) {
 	return s.invoiceFormatRepo.GetBySchoolID(schoolID)
 }
+
+// Preview returns the next numbers the requested format would issue, continuing the school's
+// current sequence, so the admin can check the format before saving it.
+func (s *InvoiceFormatService) Preview(request *request.CreateInvoiceFormatRequest) ([]string, error) {
+	resetPeriod, err := validateInvoiceFormatTemplate(request)
+	if err != nil {
+		return nil, err
+	}
+
+	count := request.PreviewCount
+	if count <= 0 {
+		count = defaultInvoicePreviewCount
+	}
+	if count > maxInvoicePreviewCount {
+		count = maxInvoicePreviewCount
+	}
+
+	return s.invoiceFormatRepo.PreviewInvoiceNumbers(&models.InvoiceFormat{
+		SchoolID:    request.SchoolID,
+		Prefix:      request.Prefix,
+		Format:      request.Format,
+		Template:    request.Template,
+		ResetPeriod: resetPeriod,
+	}, count)
+}
+
+// validateInvoiceFormatTemplate checks the template of the request and returns its reset period,
+// which defaults to never.
+func validateInvoiceFormatTemplate(request *request.CreateInvoiceFormatRequest) (string, error) {
+	resetPeriod := request.ResetPeriod
+	if resetPeriod == "" {
+		resetPeriod = repositories.InvoiceResetNever
+	}
+	if request.Template == "" {
+		return resetPeriod, nil
+	}
+	return resetPeriod, repositories.ValidateInvoiceTemplate(request.Template, resetPeriod)
+}
@@ -33,6 +33,14 @@ func (m *MockInvoiceFormatRepository) Create(invoiceFormat *models.InvoiceFormat
 	return args.Error(0)
 }
 
+func (m *MockInvoiceFormatRepository) PreviewInvoiceNumbers(invoiceFormat *models.InvoiceFormat, count int) ([]string, error) {
+	args := m.Called(invoiceFormat, count)
+	if args.Get(0) != nil {
+		return args.Get(0).([]string), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 func TestInvoiceFormatService_Create(t *testing.T) {
 	mockRepo := new(MockInvoiceFormatRepository)
 	service := services.NewInvoiceFormatService(mockRepo)
@@ -98,3 +106,59 @@ func TestInvoiceFormatService_Create(t *testing.T) {
 	assert.Equal(t, gorm.ErrInvalidTransaction, err)
 	mockRepo.AssertCalled(t, "GetBySchoolID", uint(3))
 }
+
+func TestInvoiceFormatService_CreateWithTemplate(t *testing.T) {
+	mockRepo := new(MockInvoiceFormatRepository)
+	service := services.NewInvoiceFormatService(mockRepo)
+
+	mockRepo.On("GetBySchoolID", uint(1)).Return(nil, gorm.ErrRecordNotFound)
+	mockRepo.On("Create", mock.Anything).Return(nil)
+
+	invoiceFormat, err := service.Create(&request.CreateInvoiceFormatRequest{
+		SchoolID:    1,
+		Prefix:      "INV",
+		Template:    "{PREFIX}/{SCHOOL_CODE}/{YYYY}/{MM}/{SEQ:6}",
+		ResetPeriod: "monthly",
+	}, 123)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "{PREFIX}/{SCHOOL_CODE}/{YYYY}/{MM}/{SEQ:6}", invoiceFormat.Template)
+	assert.Equal(t, "monthly", invoiceFormat.ResetPeriod)
+
+	// A monthly reset without {MM} would repeat numbers every month
+	_, err = service.Create(&request.CreateInvoiceFormatRequest{
+		SchoolID:    1,
+		Prefix:      "INV",
+		Template:    "{PREFIX}/{YYYY}/{SEQ:6}",
+		ResetPeriod: "monthly",
+	}, 123)
+
+	assert.EqualError(t, err, "a monthly reset needs a year and {MM} in the template")
+	mockRepo.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestInvoiceFormatService_Preview(t *testing.T) {
+	mockRepo := new(MockInvoiceFormatRepository)
+	service := services.NewInvoiceFormatService(mockRepo)
+
+	mockRepo.On("PreviewInvoiceNumbers", mock.MatchedBy(func(invoiceFormat *models.InvoiceFormat) bool {
+		return invoiceFormat.Template == "{PREFIX}-{SEQ:3}" && invoiceFormat.ResetPeriod == "never"
+	}), 5).Return([]string{"INV-001", "INV-002", "INV-003", "INV-004", "INV-005"}, nil)
+
+	numbers, err := service.Preview(&request.CreateInvoiceFormatRequest{
+		SchoolID: 1,
+		Prefix:   "INV",
+		Template: "{PREFIX}-{SEQ:3}",
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, numbers, 5)
+
+	_, err = service.Preview(&request.CreateInvoiceFormatRequest{
+		SchoolID: 1,
+		Prefix:   "INV",
+		Template: "{PREFIX}-{WEEK}-{SEQ:3}",
+	})
+
+	assert.EqualError(t, err, "unknown invoice template token: {WEEK}")
+}
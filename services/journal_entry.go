@@ -0,0 +1,177 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+)
+
+type JournalServiceInterface interface {
+	RunJournal() (*response.JournalSyncResponse, error)
+	GetLedgerAccounts(userID int) ([]models.LedgerAccount, error)
+	GetAllJournalEntry(page int, limit int, sourceType string, startDate time.Time, endDate time.Time, userID int) (response.JournalEntryListResponse, error)
+	GetJournalEntryByID(entryID uint, userID int) (*models.JournalEntry, error)
+	GetTrialBalance(startDate time.Time, endDate time.Time, userID int) (*response.TrialBalanceResponse, error)
+}
+
+type JournalService struct {
+	journalRepository repositories.JournalRepository
+	userRepository    repositories.UserRepository
+}
+
+func NewJournalService(journalRepository repositories.JournalRepository, userRepository repositories.UserRepository) JournalServiceInterface {
+	return &JournalService{
+		journalRepository: journalRepository,
+		userRepository:    userRepository,
+	}
+}
+
+// RunJournal posts the entries of every school that are not in its journal yet.
+func (journalService *JournalService) RunJournal() (*response.JournalSyncResponse, error) {
+	schoolIDs, err := journalService.journalRepository.GetSchoolIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &response.JournalSyncResponse{}
+	for _, schoolID := range schoolIDs {
+		posted, err := journalService.journalRepository.SyncJournal(schoolID)
+		if err != nil {
+			// One school's books must not stop the others
+			fmt.Printf("failed to post journal of school %d: %v\n", schoolID, err)
+			continue
+		}
+		result.TotalSchools++
+		result.TotalPosted += posted
+	}
+
+	return result, nil
+}
+
+func (journalService *JournalService) GetLedgerAccounts(userID int) ([]models.LedgerAccount, error) {
+	schoolID, err := journalService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return journalService.journalRepository.GetLedgerAccounts(schoolID)
+}
+
+func (journalService *JournalService) GetAllJournalEntry(page int, limit int, sourceType string, startDate time.Time, endDate time.Time, userID int) (response.JournalEntryListResponse, error) {
+	resp := response.JournalEntryListResponse{
+		Page:  page,
+		Limit: limit,
+		Data:  []models.JournalEntry{},
+	}
+
+	schoolID, err := journalService.syncSchoolJournal(userID)
+	if err != nil {
+		return resp, err
+	}
+
+	entries, total, err := journalService.journalRepository.GetAllJournalEntry(page, limit, sourceType, startDate, endDate, schoolID)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.TotalData = total
+	if limit != 0 {
+		resp.TotalPage = int((total + int64(limit) - 1) / int64(limit))
+	}
+	if len(entries) > 0 {
+		resp.Data = entries
+	}
+
+	return resp, nil
+}
+
+func (journalService *JournalService) GetJournalEntryByID(entryID uint, userID int) (*models.JournalEntry, error) {
+	schoolID, err := journalService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return journalService.journalRepository.GetJournalEntryByID(entryID, schoolID)
+}
+
+// GetTrialBalance lists the school's accounts with their opening balance, what was posted
+// between startDate and endDate and their closing balance. Without dates it covers the current
+// month up to today.
+func (journalService *JournalService) GetTrialBalance(startDate time.Time, endDate time.Time, userID int) (*response.TrialBalanceResponse, error) {
+	now := time.Now()
+	if startDate.IsZero() {
+		startDate = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	}
+	if endDate.IsZero() {
+		endDate = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	}
+	if endDate.Before(startDate) {
+		return nil, fmt.Errorf("end date must not be before start date")
+	}
+
+	schoolID, err := journalService.syncSchoolJournal(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := journalService.journalRepository.GetTrialBalance(schoolID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildTrialBalance(accounts, startDate, endDate), nil
+}
+
+// BuildTrialBalance puts the opening and closing balance of each account on its debit or credit
+// side and totals the closing balances, which are equal when the books balance.
+func BuildTrialBalance(accounts []response.TrialBalanceAccount, startDate time.Time, endDate time.Time) *response.TrialBalanceResponse {
+	result := &response.TrialBalanceResponse{
+		StartDate: startDate.Format("2006-01-02"),
+		EndDate:   endDate.Format("2006-01-02"),
+		Accounts:  []response.TrialBalanceAccount{},
+	}
+
+	for _, account := range accounts {
+		account.OpeningDebit, account.OpeningCredit = splitLedgerBalance(account.OpeningBalance)
+		account.ClosingDebit, account.ClosingCredit = splitLedgerBalance(account.OpeningBalance + account.Debit - account.Credit)
+		result.TotalDebit += account.ClosingDebit
+		result.TotalCredit += account.ClosingCredit
+		result.Accounts = append(result.Accounts, account)
+	}
+	result.IsBalanced = result.TotalDebit == result.TotalCredit
+
+	return result
+}
+
+// splitLedgerBalance turns a debit minus credit balance into its debit and credit side.
+func splitLedgerBalance(balance int64) (int64, int64) {
+	if balance < 0 {
+		return 0, -balance
+	}
+	return balance, 0
+}
+
+// syncSchoolJournal posts what is missing from the user's school journal so reports include the
+// events since the last run.
+func (journalService *JournalService) syncSchoolJournal(userID int) (uint, error) {
+	schoolID, err := journalService.getSchoolID(userID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := journalService.journalRepository.SyncJournal(schoolID); err != nil {
+		return 0, err
+	}
+	return schoolID, nil
+}
+
+func (journalService *JournalService) getSchoolID(userID int) (uint, error) {
+	user, err := journalService.userRepository.GetUserByID(uint(userID))
+	if err != nil {
+		return 0, err
+	}
+	if user.UserSchool == nil {
+		return 0, fmt.Errorf("user not associated with any school")
+	}
+	return user.UserSchool.SchoolID, nil
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockJournalRepository struct {
+	mock.Mock
+}
+
+func (m *MockJournalRepository) GetSchoolIDs() ([]uint, error) {
+	args := m.Called()
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *MockJournalRepository) SyncJournal(schoolID uint) (int, error) {
+	args := m.Called(schoolID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockJournalRepository) GetLedgerAccounts(schoolID uint) ([]models.LedgerAccount, error) {
+	args := m.Called(schoolID)
+	return args.Get(0).([]models.LedgerAccount), args.Error(1)
+}
+
+func (m *MockJournalRepository) GetAllJournalEntry(page int, limit int, sourceType string, startDate time.Time, endDate time.Time, schoolID uint) ([]models.JournalEntry, int64, error) {
+	args := m.Called(page, limit, sourceType, startDate, endDate, schoolID)
+	return args.Get(0).([]models.JournalEntry), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockJournalRepository) GetJournalEntryByID(id uint, schoolID uint) (*models.JournalEntry, error) {
+	args := m.Called(id, schoolID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.JournalEntry), args.Error(1)
+}
+
+func (m *MockJournalRepository) GetTrialBalance(schoolID uint, startDate time.Time, endDate time.Time) ([]response.TrialBalanceAccount, error) {
+	args := m.Called(schoolID, startDate, endDate)
+	return args.Get(0).([]response.TrialBalanceAccount), args.Error(1)
+}
+
+func TestRunJournal(t *testing.T) {
+	mockJournalRepo := new(MockJournalRepository)
+	service := NewJournalService(mockJournalRepo, new(MockUserRepository))
+
+	mockJournalRepo.On("GetSchoolIDs").Return([]uint{1, 2, 3}, nil)
+	mockJournalRepo.On("SyncJournal", uint(1)).Return(4, nil)
+	mockJournalRepo.On("SyncJournal", uint(2)).Return(0, errors.New("journal entry is not balanced"))
+	mockJournalRepo.On("SyncJournal", uint(3)).Return(2, nil)
+
+	result, err := service.RunJournal()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.TotalSchools)
+	assert.Equal(t, 6, result.TotalPosted)
+	mockJournalRepo.AssertExpectations(t)
+}
+
+func TestGetTrialBalance(t *testing.T) {
+	startDate := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Splits balances on their side", func(t *testing.T) {
+		mockJournalRepo := new(MockJournalRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewJournalService(mockJournalRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(4)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+		mockJournalRepo.On("SyncJournal", uint(1)).Return(0, nil)
+		mockJournalRepo.On("GetTrialBalance", uint(1), startDate, endDate).Return([]response.TrialBalanceAccount{
+			{Code: repositories.LedgerAccountCash, OpeningBalance: 200000, Debit: 500000},
+			{Code: repositories.LedgerAccountReceivable, OpeningBalance: 300000, Debit: 1000000, Credit: 500000},
+			{Code: repositories.LedgerAccountStudentCredit, OpeningBalance: -100000},
+			{Code: repositories.LedgerAccountRevenue, OpeningBalance: -400000, Credit: 1000000},
+		}, nil)
+
+		trialBalance, err := service.GetTrialBalance(startDate, endDate, 4)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "2024-01-01", trialBalance.StartDate)
+		assert.Equal(t, int64(200000), trialBalance.Accounts[0].OpeningDebit)
+		assert.Equal(t, int64(700000), trialBalance.Accounts[0].ClosingDebit)
+		assert.Equal(t, int64(800000), trialBalance.Accounts[1].ClosingDebit)
+		assert.Equal(t, int64(100000), trialBalance.Accounts[2].ClosingCredit)
+		assert.Equal(t, int64(400000), trialBalance.Accounts[3].OpeningCredit)
+		assert.Equal(t, int64(1400000), trialBalance.Accounts[3].ClosingCredit)
+		assert.Equal(t, int64(1500000), trialBalance.TotalDebit)
+		assert.Equal(t, int64(1500000), trialBalance.TotalCredit)
+		assert.True(t, trialBalance.IsBalanced)
+		mockJournalRepo.AssertExpectations(t)
+	})
+
+	t.Run("End date before start date", func(t *testing.T) {
+		service := NewJournalService(new(MockJournalRepository), new(MockUserRepository))
+
+		_, err := service.GetTrialBalance(endDate, startDate, 4)
+
+		assert.EqualError(t, err, "end date must not be before start date")
+	})
+
+	t.Run("Sync fails", func(t *testing.T) {
+		mockJournalRepo := new(MockJournalRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewJournalService(mockJournalRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(4)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+		mockJournalRepo.On("SyncJournal", uint(1)).Return(0, errors.New("journal entry is not balanced"))
+
+		_, err := service.GetTrialBalance(startDate, endDate, 4)
+
+		assert.EqualError(t, err, "journal entry is not balanced")
+		mockJournalRepo.AssertNotCalled(t, "GetTrialBalance", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
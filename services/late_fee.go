@@ -0,0 +1,276 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	request "schoolPayment/dtos/request"
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+)
+
+type LateFeeServiceInterface interface {
+	CreateLateFeeRule(ruleRequest *request.LateFeeRuleRequest, userID int) (*models.LateFeeRule, error)
+	UpdateLateFeeRule(ruleID uint, ruleRequest *request.LateFeeRuleRequest, userID int) (*models.LateFeeRule, error)
+	DeleteLateFeeRule(ruleID uint, userID int) error
+	GetAllLateFeeRule(page int, limit int, userID int) (response.LateFeeRuleListResponse, error)
+	GetLateFeeRuleByID(ruleID uint, userID int) (*models.LateFeeRule, error)
+	RunLateFee() (*response.LateFeeRunResponse, error)
+}
+
+type LateFeeService struct {
+	lateFeeRepository repositories.LateFeeRepository
+	userRepository    repositories.UserRepository
+}
+
+func NewLateFeeService(lateFeeRepository repositories.LateFeeRepository, userRepository repositories.UserRepository) LateFeeServiceInterface {
+	return &LateFeeService{
+		lateFeeRepository: lateFeeRepository,
+		userRepository:    userRepository,
+	}
+}
+
+func (lateFeeService *LateFeeService) CreateLateFeeRule(ruleRequest *request.LateFeeRuleRequest, userID int) (*models.LateFeeRule, error) {
+	schoolID, err := lateFeeService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &models.LateFeeRule{SchoolID: schoolID}
+	if err := lateFeeService.fillLateFeeRule(rule, ruleRequest); err != nil {
+		return nil, err
+	}
+	rule.CreatedBy = userID
+
+	return lateFeeService.lateFeeRepository.CreateLateFeeRule(rule)
+}
+
+func (lateFeeService *LateFeeService) UpdateLateFeeRule(ruleID uint, ruleRequest *request.LateFeeRuleRequest, userID int) (*models.LateFeeRule, error) {
+	rule, err := lateFeeService.GetLateFeeRuleByID(ruleID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("late fee rule not found")
+	}
+
+	if err := lateFeeService.fillLateFeeRule(rule, ruleRequest); err != nil {
+		return nil, err
+	}
+	rule.UpdatedBy = userID
+
+	if err := lateFeeService.lateFeeRepository.UpdateLateFeeRule(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (lateFeeService *LateFeeService) DeleteLateFeeRule(ruleID uint, userID int) error {
+	rule, err := lateFeeService.GetLateFeeRuleByID(ruleID, userID)
+	if err != nil {
+		return fmt.Errorf("late fee rule not found")
+	}
+
+	now := time.Now()
+	rule.DeletedAt = &now
+	rule.DeletedBy = &userID
+	return lateFeeService.lateFeeRepository.UpdateLateFeeRule(rule)
+}
+
+func (lateFeeService *LateFeeService) GetAllLateFeeRule(page int, limit int, userID int) (response.LateFeeRuleListResponse, error) {
+	resp := response.LateFeeRuleListResponse{
+		Page:  page,
+		Limit: limit,
+		Data:  []models.LateFeeRule{},
+	}
+
+	schoolID, err := lateFeeService.getSchoolID(userID)
+	if err != nil {
+		return resp, err
+	}
+
+	rules, total, err := lateFeeService.lateFeeRepository.GetAllLateFeeRule(page, limit, schoolID)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.TotalData = total
+	if limit != 0 {
+		resp.TotalPage = int((total + int64(limit) - 1) / int64(limit))
+	}
+	if len(rules) > 0 {
+		resp.Data = rules
+	}
+
+	return resp, nil
+}
+
+func (lateFeeService *LateFeeService) GetLateFeeRuleByID(ruleID uint, userID int) (*models.LateFeeRule, error) {
+	schoolID, err := lateFeeService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return lateFeeService.lateFeeRepository.GetLateFeeRuleByID(ruleID, schoolID)
+}
+
+// RunLateFee charges the penalty of every overdue installment up to what its rule allows today.
+// Penalty lines are ordinary installments linked to the overdue one, so parents see them in
+// their billing list and both kasir and online checkouts take them with the installment.
+func (lateFeeService *LateFeeService) RunLateFee() (*response.LateFeeRunResponse, error) {
+	rules, err := lateFeeService.lateFeeRepository.GetActiveLateFeeRules()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	installments, err := lateFeeService.lateFeeRepository.GetOverdueInstallments(now)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &response.LateFeeRunResponse{}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, installment := range installments {
+		rule := FindLateFeeRule(rules, installment)
+		if rule == nil {
+			continue
+		}
+		result.TotalChecked++
+
+		total := CalculateLateFee(rule, installment.Amount-installment.PaidAmount, installment.DueDate, now)
+		if total <= 0 {
+			continue
+		}
+
+		charged, err := lateFeeService.lateFeeRepository.ApplyLateFee(installment.ID, total, today)
+		if err != nil {
+			// One failing installment must not stop the rest of the run
+			fmt.Printf("failed to apply late fee to installment %d: %v\n", installment.ID, err)
+			continue
+		}
+		if charged > 0 {
+			result.TotalCharged++
+			result.TotalAmount += charged
+		}
+	}
+
+	return result, nil
+}
+
+// FindLateFeeRule picks the rule of the installment's school, preferring one made for its
+// billing over one made for its billing type.
+func FindLateFeeRule(rules []models.LateFeeRule, installment response.LateFeeOverdueInstallment) *models.LateFeeRule {
+	var typeRule *models.LateFeeRule
+	for i := range rules {
+		rule := &rules[i]
+		if rule.SchoolID != installment.SchoolID {
+			continue
+		}
+		if rule.BillingID != nil && *rule.BillingID == installment.BillingID {
+			return rule
+		}
+		if typeRule == nil && rule.BillingTypeID != nil && strconv.Itoa(int(*rule.BillingTypeID)) == installment.BillingType {
+			typeRule = rule
+		}
+	}
+	return typeRule
+}
+
+// CalculateLateFee is the total penalty owed on asOf for an installment due on dueDate.
+// Nothing is owed during the grace period; after it a flat or percentage amount is charged
+// once, per day or per started month, up to the rule's maximum.
+func CalculateLateFee(rule *models.LateFeeRule, outstanding int64, dueDate time.Time, asOf time.Time) int64 {
+	start := time.Date(dueDate.Year(), dueDate.Month(), dueDate.Day(), 0, 0, 0, 0, asOf.Location()).AddDate(0, 0, rule.GraceDays)
+	end := time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, asOf.Location())
+	if !end.After(start) || outstanding <= 0 {
+		return 0
+	}
+
+	var periods int64
+	switch rule.Period {
+	case repositories.LateFeePeriodOnce:
+		periods = 1
+	case repositories.LateFeePeriodDaily:
+		periods = int64(math.Round(end.Sub(start).Hours() / 24))
+	case repositories.LateFeePeriodMonthly:
+		for t := start; t.Before(end); t = t.AddDate(0, 1, 0) {
+			periods++
+		}
+	}
+
+	unit := int64(math.Round(rule.Amount))
+	if rule.CalculationType == repositories.LateFeeCalculationPercentage {
+		unit = int64(math.Round(float64(outstanding) * rule.Amount / 100))
+	}
+
+	total := unit * periods
+	if rule.MaxAmount != nil && total > *rule.MaxAmount {
+		total = *rule.MaxAmount
+	}
+	return total
+}
+
+func (lateFeeService *LateFeeService) fillLateFeeRule(rule *models.LateFeeRule, ruleRequest *request.LateFeeRuleRequest) error {
+	if (ruleRequest.BillingId == nil) == (ruleRequest.BillingTypeId == nil) {
+		return fmt.Errorf("either billingId or billingTypeId must be set")
+	}
+
+	if ruleRequest.CalculationType != repositories.LateFeeCalculationFlat && ruleRequest.CalculationType != repositories.LateFeeCalculationPercentage {
+		return fmt.Errorf("calculationType must be flat or percentage")
+	}
+	if ruleRequest.Period != repositories.LateFeePeriodOnce && ruleRequest.Period != repositories.LateFeePeriodDaily && ruleRequest.Period != repositories.LateFeePeriodMonthly {
+		return fmt.Errorf("period must be once, daily or monthly")
+	}
+	if ruleRequest.Amount <= 0 {
+		return fmt.Errorf("amount must be greater than 0")
+	}
+	if ruleRequest.CalculationType == repositories.LateFeeCalculationPercentage && ruleRequest.Amount > 100 {
+		return fmt.Errorf("percentage cannot be more than 100")
+	}
+	if ruleRequest.GraceDays < 0 {
+		return fmt.Errorf("graceDays cannot be negative")
+	}
+	if ruleRequest.MaxAmount != nil && *ruleRequest.MaxAmount <= 0 {
+		return fmt.Errorf("maxAmount must be greater than 0")
+	}
+
+	var owned bool
+	var err error
+	if ruleRequest.BillingId != nil {
+		owned, err = lateFeeService.lateFeeRepository.BillingBelongsToSchool(*ruleRequest.BillingId, rule.SchoolID)
+	} else {
+		owned, err = lateFeeService.lateFeeRepository.BillingTypeBelongsToSchool(*ruleRequest.BillingTypeId, rule.SchoolID)
+	}
+	if err != nil {
+		return err
+	}
+	if !owned {
+		return fmt.Errorf("billing or billing type not found")
+	}
+
+	rule.BillingID = ruleRequest.BillingId
+	rule.BillingTypeID = ruleRequest.BillingTypeId
+	rule.CalculationType = ruleRequest.CalculationType
+	rule.Amount = ruleRequest.Amount
+	rule.Period = ruleRequest.Period
+	rule.GraceDays = ruleRequest.GraceDays
+	rule.MaxAmount = ruleRequest.MaxAmount
+	// New rules are active unless asked otherwise
+	if ruleRequest.IsActive != nil {
+		rule.IsActive = *ruleRequest.IsActive
+	} else if rule.ID == 0 {
+		rule.IsActive = true
+	}
+	return nil
+}
+
+func (lateFeeService *LateFeeService) getSchoolID(userID int) (uint, error) {
+	user, err := lateFeeService.userRepository.GetUserByID(uint(userID))
+	if err != nil {
+		return 0, err
+	}
+	if user.UserSchool == nil {
+		return 0, fmt.Errorf("user not associated with any school")
+	}
+	return user.UserSchool.SchoolID, nil
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	request "schoolPayment/dtos/request"
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLateFeeRepository struct {
+	mock.Mock
+}
+
+func (m *MockLateFeeRepository) CreateLateFeeRule(rule *models.LateFeeRule) (*models.LateFeeRule, error) {
+	args := m.Called(rule)
+	return rule, args.Error(0)
+}
+
+func (m *MockLateFeeRepository) UpdateLateFeeRule(rule *models.LateFeeRule) error {
+	args := m.Called(rule)
+	return args.Error(0)
+}
+
+func (m *MockLateFeeRepository) GetLateFeeRuleByID(id uint, schoolID uint) (*models.LateFeeRule, error) {
+	args := m.Called(id, schoolID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LateFeeRule), args.Error(1)
+}
+
+func (m *MockLateFeeRepository) GetAllLateFeeRule(page int, limit int, schoolID uint) ([]models.LateFeeRule, int64, error) {
+	args := m.Called(page, limit, schoolID)
+	return args.Get(0).([]models.LateFeeRule), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockLateFeeRepository) GetActiveLateFeeRules() ([]models.LateFeeRule, error) {
+	args := m.Called()
+	return args.Get(0).([]models.LateFeeRule), args.Error(1)
+}
+
+func (m *MockLateFeeRepository) BillingBelongsToSchool(billingID uint, schoolID uint) (bool, error) {
+	args := m.Called(billingID, schoolID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockLateFeeRepository) BillingTypeBelongsToSchool(billingTypeID uint, schoolID uint) (bool, error) {
+	args := m.Called(billingTypeID, schoolID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockLateFeeRepository) GetOverdueInstallments(asOf time.Time) ([]response.LateFeeOverdueInstallment, error) {
+	args := m.Called(asOf)
+	return args.Get(0).([]response.LateFeeOverdueInstallment), args.Error(1)
+}
+
+func (m *MockLateFeeRepository) ApplyLateFee(installmentID uint, total int64, dueDate time.Time) (int64, error) {
+	args := m.Called(installmentID, total, dueDate)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestCalculateLateFee(t *testing.T) {
+	dueDate := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	maxAmount := int64(30000)
+
+	tests := []struct {
+		name     string
+		rule     models.LateFeeRule
+		asOf     time.Time
+		expected int64
+	}{
+		{
+			name:     "Within grace period",
+			rule:     models.LateFeeRule{CalculationType: repositories.LateFeeCalculationFlat, Amount: 5000, Period: repositories.LateFeePeriodDaily, GraceDays: 3},
+			asOf:     time.Date(2024, 1, 13, 9, 0, 0, 0, time.UTC),
+			expected: 0,
+		},
+		{
+			name:     "Flat per day after grace period",
+			rule:     models.LateFeeRule{CalculationType: repositories.LateFeeCalculationFlat, Amount: 5000, Period: repositories.LateFeePeriodDaily, GraceDays: 3},
+			asOf:     time.Date(2024, 1, 17, 9, 0, 0, 0, time.UTC),
+			expected: 20000,
+		},
+		{
+			name:     "Flat per day capped",
+			rule:     models.LateFeeRule{CalculationType: repositories.LateFeeCalculationFlat, Amount: 5000, Period: repositories.LateFeePeriodDaily, MaxAmount: &maxAmount},
+			asOf:     time.Date(2024, 1, 30, 9, 0, 0, 0, time.UTC),
+			expected: 30000,
+		},
+		{
+			name:     "Percentage per started month",
+			rule:     models.LateFeeRule{CalculationType: repositories.LateFeeCalculationPercentage, Amount: 2, Period: repositories.LateFeePeriodMonthly},
+			asOf:     time.Date(2024, 2, 11, 9, 0, 0, 0, time.UTC),
+			expected: 20000,
+		},
+		{
+			name:     "Flat once",
+			rule:     models.LateFeeRule{CalculationType: repositories.LateFeeCalculationFlat, Amount: 25000, Period: repositories.LateFeePeriodOnce},
+			asOf:     time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC),
+			expected: 25000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, CalculateLateFee(&tt.rule, 500000, dueDate, tt.asOf))
+		})
+	}
+}
+
+func TestFindLateFeeRule(t *testing.T) {
+	billingID := uint(7)
+	billingTypeID := uint(3)
+	rules := []models.LateFeeRule{
+		{SchoolID: 1, BillingTypeID: &billingTypeID, Amount: 1000},
+		{SchoolID: 1, BillingID: &billingID, Amount: 2000},
+		{SchoolID: 2, BillingTypeID: &billingTypeID, Amount: 3000},
+	}
+
+	t.Run("Billing rule wins over billing type rule", func(t *testing.T) {
+		rule := FindLateFeeRule(rules, response.LateFeeOverdueInstallment{SchoolID: 1, BillingID: 7, BillingType: "3"})
+		assert.Equal(t, float64(2000), rule.Amount)
+	})
+
+	t.Run("Billing type rule of the same school", func(t *testing.T) {
+		rule := FindLateFeeRule(rules, response.LateFeeOverdueInstallment{SchoolID: 2, BillingID: 7, BillingType: "3"})
+		assert.Equal(t, float64(3000), rule.Amount)
+	})
+
+	t.Run("No rule", func(t *testing.T) {
+		assert.Nil(t, FindLateFeeRule(rules, response.LateFeeOverdueInstallment{SchoolID: 1, BillingID: 8, BillingType: "4"}))
+	})
+}
+
+func TestRunLateFee(t *testing.T) {
+	mockLateFeeRepo := new(MockLateFeeRepository)
+	service := NewLateFeeService(mockLateFeeRepo, new(MockUserRepository))
+
+	billingTypeID := uint(3)
+	mockLateFeeRepo.On("GetActiveLateFeeRules").Return([]models.LateFeeRule{
+		{SchoolID: 1, BillingTypeID: &billingTypeID, CalculationType: repositories.LateFeeCalculationFlat, Amount: 10000, Period: repositories.LateFeePeriodOnce},
+	}, nil)
+	mockLateFeeRepo.On("GetOverdueInstallments", mock.Anything).Return([]response.LateFeeOverdueInstallment{
+		{ID: 21, SchoolID: 1, BillingType: "3", Amount: 300000, DueDate: time.Now().AddDate(0, 0, -5)},
+		{ID: 22, SchoolID: 1, BillingType: "9", Amount: 300000, DueDate: time.Now().AddDate(0, 0, -5)},
+	}, nil)
+	mockLateFeeRepo.On("ApplyLateFee", uint(21), int64(10000), mock.Anything).Return(int64(10000), nil)
+
+	result, err := service.RunLateFee()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.TotalChecked)
+	assert.Equal(t, 1, result.TotalCharged)
+	assert.Equal(t, int64(10000), result.TotalAmount)
+	mockLateFeeRepo.AssertNotCalled(t, "ApplyLateFee", uint(22), mock.Anything, mock.Anything)
+}
+
+func TestCreateLateFeeRule(t *testing.T) {
+	billingID := uint(7)
+
+	t.Run("Success", func(t *testing.T) {
+		mockLateFeeRepo := new(MockLateFeeRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewLateFeeService(mockLateFeeRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(3)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+		mockLateFeeRepo.On("BillingBelongsToSchool", uint(7), uint(1)).Return(true, nil)
+		mockLateFeeRepo.On("CreateLateFeeRule", mock.Anything).Return(nil)
+
+		rule, err := service.CreateLateFeeRule(&request.LateFeeRuleRequest{
+			BillingId:       &billingID,
+			CalculationType: repositories.LateFeeCalculationPercentage,
+			Amount:          2,
+			Period:          repositories.LateFeePeriodMonthly,
+			GraceDays:       7,
+		}, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(1), rule.SchoolID)
+		assert.True(t, rule.IsActive)
+		assert.Equal(t, 3, rule.CreatedBy)
+	})
+
+	t.Run("Billing and billing type both set", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		service := NewLateFeeService(new(MockLateFeeRepository), mockUserRepo)
+		mockUserRepo.On("GetUserByID", uint(3)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+
+		_, err := service.CreateLateFeeRule(&request.LateFeeRuleRequest{
+			BillingId:       &billingID,
+			BillingTypeId:   &billingID,
+			CalculationType: repositories.LateFeeCalculationFlat,
+			Amount:          5000,
+			Period:          repositories.LateFeePeriodDaily,
+		}, 3)
+
+		assert.EqualError(t, err, "either billingId or billingTypeId must be set")
+	})
+
+	t.Run("Percentage above 100", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		service := NewLateFeeService(new(MockLateFeeRepository), mockUserRepo)
+		mockUserRepo.On("GetUserByID", uint(3)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+
+		_, err := service.CreateLateFeeRule(&request.LateFeeRuleRequest{
+			BillingId:       &billingID,
+			CalculationType: repositories.LateFeeCalculationPercentage,
+			Amount:          150,
+			Period:          repositories.LateFeePeriodOnce,
+		}, 3)
+
+		assert.EqualError(t, err, "percentage cannot be more than 100")
+	})
+}
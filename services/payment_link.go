@@ -0,0 +1,374 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	request "schoolPayment/dtos/request"
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+	"schoolPayment/utilities"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPaymentLinkExpiryHours = 72
+	maxPaymentLinkExpiryHours     = 7 * 24
+)
+
+type PaymentLinkServiceInterface interface {
+	GetPaymentLinks(studentID uint, userID int) ([]response.PaymentLinkResponse, error)
+	CreatePaymentLink(linkRequest *request.CreatePaymentLinkRequest, userID int) (*response.PaymentLinkResponse, error)
+	RevokePaymentLink(id uint, userID int) error
+	GetPublicPaymentLink(token string) (*response.PublicPaymentLinkResponse, error)
+	CheckoutPaymentLink(token string, checkoutRequest *request.PaymentLinkCheckoutRequest) (*response.MidtransResponse, error)
+}
+
+type PaymentLinkService struct {
+	paymentLinkRepository    repositories.PaymentLinkRepository
+	billingStudentRepository repositories.BillingStudentRepository
+	studentRepository        repositories.StudentRepositoryInteface
+	userRepository           repositories.UserRepository
+	schoolRepository         repositories.SchoolRepository
+	transactionService       TransactionService
+}
+
+func NewPaymentLinkService(paymentLinkRepository repositories.PaymentLinkRepository, billingStudentRepository repositories.BillingStudentRepository, studentRepository repositories.StudentRepositoryInteface, userRepository repositories.UserRepository, schoolRepository repositories.SchoolRepository, transactionService TransactionService) PaymentLinkServiceInterface {
+	return &PaymentLinkService{
+		paymentLinkRepository:    paymentLinkRepository,
+		billingStudentRepository: billingStudentRepository,
+		studentRepository:        studentRepository,
+		userRepository:           userRepository,
+		schoolRepository:         schoolRepository,
+		transactionService:       transactionService,
+	}
+}
+
+func (paymentLinkService *PaymentLinkService) GetPaymentLinks(studentID uint, userID int) ([]response.PaymentLinkResponse, error) {
+	student, err := paymentLinkService.getStudent(studentID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	paymentLinks, err := paymentLinkService.paymentLinkRepository.GetPaymentLinksByStudentID(studentID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := []response.PaymentLinkResponse{}
+	for i := range paymentLinks {
+		billingStudents, err := paymentLinkService.getBillingStudents(strings.Split(paymentLinks[i].BillingStudentIds, ","))
+		if err != nil {
+			return nil, err
+		}
+		linkResponse, err := paymentLinkService.toPaymentLinkResponse(&paymentLinks[i], &student, billingStudents)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, *linkResponse)
+	}
+	return resp, nil
+}
+
+// CreatePaymentLink makes a link anyone can pay the installments with, until it expires or is revoked.
+func (paymentLinkService *PaymentLinkService) CreatePaymentLink(linkRequest *request.CreatePaymentLinkRequest, userID int) (*response.PaymentLinkResponse, error) {
+	if len(linkRequest.BillingStudentIds) == 0 {
+		return nil, fmt.Errorf("billingStudentIds is required")
+	}
+	expiryHours := linkRequest.ExpiryHours
+	if expiryHours == 0 {
+		expiryHours = defaultPaymentLinkExpiryHours
+	}
+	if expiryHours < 0 || expiryHours > maxPaymentLinkExpiryHours {
+		return nil, fmt.Errorf("expiryHours must be between 1 and %d", maxPaymentLinkExpiryHours)
+	}
+
+	student, err := paymentLinkService.getStudent(linkRequest.StudentID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	billingStudents, err := paymentLinkService.getBillingStudents(linkRequest.BillingStudentIds)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePaymentLinkBillingStudents(linkRequest.BillingStudentIds, billingStudents, student.ID); err != nil {
+		return nil, err
+	}
+
+	school, err := repositories.GetSchoolByStudentId(student.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := utilities.NewPaymentLinkCode()
+	if err != nil {
+		return nil, err
+	}
+
+	paymentLink := &models.PaymentLink{
+		SchoolID:          school.ID,
+		StudentID:         student.ID,
+		Code:              code,
+		BillingStudentIds: strings.Join(linkRequest.BillingStudentIds, ","),
+		ExpiresAt:         time.Now().Add(time.Duration(expiryHours) * time.Hour),
+	}
+	paymentLink.CreatedBy = userID
+	paymentLink.UpdatedBy = userID
+
+	if err := paymentLinkService.paymentLinkRepository.CreatePaymentLink(paymentLink); err != nil {
+		return nil, err
+	}
+	return paymentLinkService.toPaymentLinkResponse(paymentLink, &student, billingStudents)
+}
+
+func (paymentLinkService *PaymentLinkService) RevokePaymentLink(id uint, userID int) error {
+	paymentLink, err := paymentLinkService.paymentLinkRepository.GetPaymentLinkByID(id)
+	if err != nil {
+		return fmt.Errorf("payment link not found")
+	}
+	if _, err := paymentLinkService.getStudent(paymentLink.StudentID, userID); err != nil {
+		return fmt.Errorf("payment link not found")
+	}
+
+	now := time.Now()
+	paymentLink.DeletedAt = &now
+	paymentLink.DeletedBy = &userID
+	return paymentLinkService.paymentLinkRepository.UpdatePaymentLink(paymentLink)
+}
+
+// GetPublicPaymentLink returns the summary shown to whoever opens the link, without login.
+func (paymentLinkService *PaymentLinkService) GetPublicPaymentLink(token string) (*response.PublicPaymentLinkResponse, error) {
+	paymentLink, err := paymentLinkService.openPaymentLink(token)
+	if err != nil {
+		return nil, err
+	}
+
+	student, err := repositories.GetStudentByIDOnlyStudent(paymentLink.StudentID)
+	if err != nil {
+		return nil, err
+	}
+	school, err := paymentLinkService.schoolRepository.GetSchoolByID(paymentLink.SchoolID)
+	if err != nil {
+		return nil, err
+	}
+	billingStudents, err := paymentLinkService.getBillingStudents(strings.Split(paymentLink.BillingStudentIds, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &response.PublicPaymentLinkResponse{
+		SchoolName:  school.SchoolName,
+		StudentName: utilities.MaskName(student.FullName),
+		Items:       []response.PublicPaymentLinkItem{},
+		ExpiresAt:   paymentLink.ExpiresAt,
+		Paid:        true,
+	}
+	for _, billingStudent := range billingStudents {
+		paid := billingStudent.PaymentStatus == repositories.BillingStudentStatusPaid
+		item := response.PublicPaymentLinkItem{
+			Name:    billingStudent.DetailBillingName,
+			DueDate: billingStudent.DueDate,
+			Paid:    paid,
+		}
+		if !paid {
+			item.Amount = billingStudent.Amount - billingStudent.PaidAmount
+			resp.TotalAmount += item.Amount
+			resp.Paid = false
+		}
+		resp.Items = append(resp.Items, item)
+	}
+	return resp, nil
+}
+
+// CheckoutPaymentLink opens the Snap payment page for the installments of the link that are still
+// open. The order is made on behalf of the user who created the link, but the gateway is given the
+// student's guardian as the customer. Opening the link again returns the page of its pending order.
+func (paymentLinkService *PaymentLinkService) CheckoutPaymentLink(token string, checkoutRequest *request.PaymentLinkCheckoutRequest) (*response.MidtransResponse, error) {
+	paymentLink, err := paymentLinkService.openPaymentLink(token)
+	if err != nil {
+		return nil, err
+	}
+
+	billingStudents, err := paymentLinkService.getBillingStudents(strings.Split(paymentLink.BillingStudentIds, ","))
+	if err != nil {
+		return nil, err
+	}
+	var openBillingStudentIds []string
+	for _, billingStudent := range billingStudents {
+		if billingStudent.PaymentStatus != repositories.BillingStudentStatusPaid {
+			openBillingStudentIds = append(openBillingStudentIds, strconv.Itoa(int(billingStudent.ID)))
+		}
+	}
+	if len(openBillingStudentIds) == 0 {
+		return nil, fmt.Errorf("payment link has already been paid")
+	}
+
+	// An abandoned page must not hold the installments for one more order each time it is opened
+	pendingCheckout, err := paymentLinkService.getPendingCheckout(paymentLink, billingStudents)
+	if err != nil {
+		return nil, err
+	}
+	if pendingCheckout != nil {
+		return pendingCheckout, nil
+	}
+
+	rsp, err := paymentLinkService.transactionService.MidtransPayment(&BillingService{}, int(paymentLink.StudentID), checkoutRequest.PaymentMethodId, paymentLink.CreatedBy, openBillingStudentIds, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	paymentLink.OrderID = &rsp.OrderID
+	if err := paymentLinkService.paymentLinkRepository.UpdatePaymentLink(paymentLink); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// getPendingCheckout returns the payment page of the link's last order while that order still holds
+// every open installment, or nil when a new order is needed.
+func (paymentLinkService *PaymentLinkService) getPendingCheckout(paymentLink *models.PaymentLink, billingStudents []models.BillingStudent) (*response.MidtransResponse, error) {
+	if paymentLink.OrderID == nil {
+		return nil, nil
+	}
+
+	now := time.Now()
+	for _, billingStudent := range billingStudents {
+		if billingStudent.PaymentStatus == repositories.BillingStudentStatusPaid {
+			continue
+		}
+		if billingStudent.ReservedOrderID == nil || *billingStudent.ReservedOrderID != *paymentLink.OrderID ||
+			billingStudent.ReservedUntil == nil || !billingStudent.ReservedUntil.After(now) {
+			return nil, nil
+		}
+	}
+
+	paymentLog, err := repositories.GetMidtransPaymentLogByOrderId(*paymentLink.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The payment log keeps the gateway response in the shape of a Snap response
+	var paymentPage struct {
+		Token       string `json:"token"`
+		RedirectURL string `json:"redirect_url"`
+	}
+	if err := json.Unmarshal([]byte(paymentLog.RedirectUrl), &paymentPage); err != nil {
+		return nil, err
+	}
+	return &response.MidtransResponse{
+		OrderID:     *paymentLink.OrderID,
+		Token:       &paymentPage.Token,
+		RedirectURL: &paymentPage.RedirectURL,
+	}, nil
+}
+
+// openPaymentLink returns the link of the token if it may still be paid with.
+func (paymentLinkService *PaymentLinkService) openPaymentLink(token string) (*models.PaymentLink, error) {
+	code, err := utilities.VerifyPaymentLink(token)
+	if err != nil {
+		return nil, err
+	}
+
+	paymentLink, err := paymentLinkService.paymentLinkRepository.GetPaymentLinkByCode(code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("invalid payment link")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if paymentLink.DeletedAt != nil {
+		return nil, fmt.Errorf("payment link has been revoked")
+	}
+	if time.Now().After(paymentLink.ExpiresAt) {
+		return nil, fmt.Errorf("payment link has expired")
+	}
+	return paymentLink, nil
+}
+
+func (paymentLinkService *PaymentLinkService) getStudent(studentID uint, userID int) (models.Student, error) {
+	user, err := paymentLinkService.userRepository.GetUserByID(uint(userID))
+	if err != nil {
+		return models.Student{}, err
+	}
+
+	student, err := paymentLinkService.studentRepository.GetStudentByID(studentID, user)
+	if err != nil {
+		return models.Student{}, fmt.Errorf("student not found")
+	}
+	return student, nil
+}
+
+func (paymentLinkService *PaymentLinkService) toPaymentLinkResponse(paymentLink *models.PaymentLink, student *models.Student, billingStudents []models.BillingStudent) (*response.PaymentLinkResponse, error) {
+	token, err := utilities.SignPaymentLink(paymentLink.Code)
+	if err != nil {
+		return nil, err
+	}
+	linkURL, err := utilities.PaymentLinkURL(token)
+	if err != nil {
+		return nil, err
+	}
+	school, err := paymentLinkService.schoolRepository.GetSchoolByID(paymentLink.SchoolID)
+	if err != nil {
+		return nil, err
+	}
+
+	var totalAmount int64
+	for _, billingStudent := range billingStudents {
+		if billingStudent.PaymentStatus != repositories.BillingStudentStatusPaid {
+			totalAmount += billingStudent.Amount - billingStudent.PaidAmount
+		}
+	}
+
+	whatsAppText := utilities.PaymentLinkWhatsAppText(school.SchoolName, student.FullName, totalAmount, paymentLink.ExpiresAt, linkURL)
+	return &response.PaymentLinkResponse{
+		ID:                paymentLink.ID,
+		StudentID:         paymentLink.StudentID,
+		BillingStudentIds: strings.Split(paymentLink.BillingStudentIds, ","),
+		TotalAmount:       totalAmount,
+		ExpiresAt:         paymentLink.ExpiresAt,
+		OrderID:           paymentLink.OrderID,
+		URL:               linkURL,
+		WhatsAppText:      whatsAppText,
+		WhatsAppURL:       utilities.WhatsAppShareURL(whatsAppText),
+		CreatedAt:         paymentLink.CreatedAt,
+	}, nil
+}
+
+// getBillingStudents loads the installments of a link. An id that is not a number matches none,
+// so validatePaymentLinkBillingStudents reports it as not found.
+func (paymentLinkService *PaymentLinkService) getBillingStudents(billingStudentIds []string) ([]models.BillingStudent, error) {
+	ids := make([]int, 0, len(billingStudentIds))
+	for _, billingStudentID := range billingStudentIds {
+		if id, err := strconv.Atoi(strings.TrimSpace(billingStudentID)); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return paymentLinkService.billingStudentRepository.GetBillingStudentsByIDs(ids)
+}
+
+// validatePaymentLinkBillingStudents makes sure every installment of the link exists, belongs to
+// the student and is not paid yet.
+func validatePaymentLinkBillingStudents(billingStudentIds []string, billingStudents []models.BillingStudent, studentID uint) error {
+	found := make(map[string]models.BillingStudent, len(billingStudents))
+	for _, billingStudent := range billingStudents {
+		found[strconv.Itoa(int(billingStudent.ID))] = billingStudent
+	}
+
+	for _, id := range billingStudentIds {
+		billingStudent, ok := found[id]
+		if !ok || billingStudent.StudentID != studentID {
+			return fmt.Errorf("billing student %s not found", id)
+		}
+		if billingStudent.PaymentStatus == repositories.BillingStudentStatusPaid {
+			return fmt.Errorf("%s is already paid", billingStudent.DetailBillingName)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	database "schoolPayment/configs"
+	"schoolPayment/dtos/request"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+	"schoolPayment/utilities"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type MockPaymentLinkRepository struct {
+	mock.Mock
+}
+
+func (m *MockPaymentLinkRepository) GetPaymentLinksByStudentID(studentID uint) ([]models.PaymentLink, error) {
+	args := m.Called(studentID)
+	return args.Get(0).([]models.PaymentLink), args.Error(1)
+}
+
+func (m *MockPaymentLinkRepository) GetPaymentLinkByID(id uint) (*models.PaymentLink, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PaymentLink), args.Error(1)
+}
+
+func (m *MockPaymentLinkRepository) GetPaymentLinkByCode(code string) (*models.PaymentLink, error) {
+	args := m.Called(code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PaymentLink), args.Error(1)
+}
+
+func (m *MockPaymentLinkRepository) CreatePaymentLink(paymentLink *models.PaymentLink) error {
+	args := m.Called(paymentLink)
+	return args.Error(0)
+}
+
+func (m *MockPaymentLinkRepository) UpdatePaymentLink(paymentLink *models.PaymentLink) error {
+	args := m.Called(paymentLink)
+	return args.Error(0)
+}
+
+func TestCreatePaymentLink_Validation(t *testing.T) {
+	user := models.User{RoleID: 5}
+	student := models.Student{FullName: "Budi Santoso"}
+	student.ID = 7
+
+	newService := func() (*MockPaymentLinkRepository, *MockBillingStudentRepository, PaymentLinkServiceInterface) {
+		mockLinkRepo := new(MockPaymentLinkRepository)
+		mockBillingStudentRepo := new(MockBillingStudentRepository)
+		mockStudentRepo := new(MockStudentRepository)
+		mockUserRepo := new(MockUserRepository)
+
+		mockUserRepo.On("GetUserByID", uint(1)).Return(&user, nil)
+		mockStudentRepo.On("GetStudentByID", uint(7), user).Return(student, nil)
+
+		return mockLinkRepo, mockBillingStudentRepo, NewPaymentLinkService(mockLinkRepo, mockBillingStudentRepo, mockStudentRepo, mockUserRepo, nil, TransactionService{})
+	}
+
+	billingStudent := func(id uint, studentID uint, status string) models.BillingStudent {
+		billingStudent := models.BillingStudent{StudentID: studentID, PaymentStatus: status, DetailBillingName: "SPP Januari", Amount: 500000}
+		billingStudent.ID = id
+		return billingStudent
+	}
+
+	t.Run("No installments", func(t *testing.T) {
+		_, _, service := newService()
+
+		_, err := service.CreatePaymentLink(&request.CreatePaymentLinkRequest{StudentID: 7}, 1)
+
+		assert.EqualError(t, err, "billingStudentIds is required")
+	})
+
+	t.Run("Expiry too long", func(t *testing.T) {
+		_, _, service := newService()
+
+		_, err := service.CreatePaymentLink(&request.CreatePaymentLinkRequest{StudentID: 7, BillingStudentIds: []string{"11"}, ExpiryHours: 200}, 1)
+
+		assert.EqualError(t, err, "expiryHours must be between 1 and 168")
+	})
+
+	t.Run("Installment of another student", func(t *testing.T) {
+		mockLinkRepo, mockBillingStudentRepo, service := newService()
+		mockBillingStudentRepo.On("GetBillingStudentsByIDs", []int{11, 12}).Return([]models.BillingStudent{
+			billingStudent(11, 7, repositories.BillingStudentStatusUnpaid),
+			billingStudent(12, 8, repositories.BillingStudentStatusUnpaid),
+		}, nil)
+
+		_, err := service.CreatePaymentLink(&request.CreatePaymentLinkRequest{StudentID: 7, BillingStudentIds: []string{"11", "12"}}, 1)
+
+		assert.EqualError(t, err, "billing student 12 not found")
+		mockLinkRepo.AssertNotCalled(t, "CreatePaymentLink", mock.Anything)
+	})
+
+	t.Run("Installment already paid", func(t *testing.T) {
+		mockLinkRepo, mockBillingStudentRepo, service := newService()
+		mockBillingStudentRepo.On("GetBillingStudentsByIDs", []int{11}).Return([]models.BillingStudent{
+			billingStudent(11, 7, repositories.BillingStudentStatusPaid),
+		}, nil)
+
+		_, err := service.CreatePaymentLink(&request.CreatePaymentLinkRequest{StudentID: 7, BillingStudentIds: []string{"11"}}, 1)
+
+		assert.EqualError(t, err, "SPP Januari is already paid")
+		mockLinkRepo.AssertNotCalled(t, "CreatePaymentLink", mock.Anything)
+	})
+}
+
+func TestRevokePaymentLink(t *testing.T) {
+	user := models.User{RoleID: 5}
+	mockLinkRepo := new(MockPaymentLinkRepository)
+	mockBillingStudentRepo := new(MockBillingStudentRepository)
+	mockStudentRepo := new(MockStudentRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := NewPaymentLinkService(mockLinkRepo, mockBillingStudentRepo, mockStudentRepo, mockUserRepo, nil, TransactionService{})
+
+	mockUserRepo.On("GetUserByID", uint(1)).Return(&user, nil)
+	mockStudentRepo.On("GetStudentByID", uint(7), user).Return(models.Student{}, nil)
+	mockLinkRepo.On("GetPaymentLinkByID", uint(3)).Return(&models.PaymentLink{StudentID: 7}, nil)
+	mockLinkRepo.On("UpdatePaymentLink", mock.MatchedBy(func(paymentLink *models.PaymentLink) bool {
+		return paymentLink.DeletedAt != nil && *paymentLink.DeletedBy == 1
+	})).Return(nil)
+
+	err := service.RevokePaymentLink(3, 1)
+
+	assert.NoError(t, err)
+	mockLinkRepo.AssertExpectations(t)
+}
+
+func TestCheckoutPaymentLink_Closed(t *testing.T) {
+	t.Setenv("SECRET_KEY", "test-secret")
+	token, err := utilities.SignPaymentLink("abc123")
+	assert.NoError(t, err)
+
+	revokedAt := time.Now()
+	tests := []struct {
+		name        string
+		paymentLink *models.PaymentLink
+		findErr     error
+		wantErr     string
+	}{
+		{name: "Unknown", findErr: gorm.ErrRecordNotFound, wantErr: "invalid payment link"},
+		{name: "Revoked", paymentLink: &models.PaymentLink{Code: "abc123", ExpiresAt: time.Now().Add(time.Hour), Master: models.Master{DeletedAt: &revokedAt}}, wantErr: "payment link has been revoked"},
+		{name: "Expired", paymentLink: &models.PaymentLink{Code: "abc123", ExpiresAt: time.Now().Add(-time.Hour)}, wantErr: "payment link has expired"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLinkRepo := new(MockPaymentLinkRepository)
+			mockBillingStudentRepo := new(MockBillingStudentRepository)
+			service := NewPaymentLinkService(mockLinkRepo, mockBillingStudentRepo, nil, nil, nil, TransactionService{})
+			if tt.paymentLink != nil {
+				mockLinkRepo.On("GetPaymentLinkByCode", "abc123").Return(tt.paymentLink, nil)
+			} else {
+				mockLinkRepo.On("GetPaymentLinkByCode", "abc123").Return(nil, tt.findErr)
+			}
+
+			_, err := service.CheckoutPaymentLink(token, &request.PaymentLinkCheckoutRequest{PaymentMethodId: 4})
+
+			assert.EqualError(t, err, tt.wantErr)
+			mockBillingStudentRepo.AssertNotCalled(t, "GetBillingStudentsByIDs", mock.Anything)
+		})
+	}
+
+	t.Run("Forged signature", func(t *testing.T) {
+		mockLinkRepo := new(MockPaymentLinkRepository)
+		service := NewPaymentLinkService(mockLinkRepo, nil, nil, nil, nil, TransactionService{})
+
+		_, err := service.CheckoutPaymentLink("abc123.forged", &request.PaymentLinkCheckoutRequest{PaymentMethodId: 4})
+
+		assert.EqualError(t, err, "invalid payment link")
+		mockLinkRepo.AssertNotCalled(t, "GetPaymentLinkByCode", mock.Anything)
+	})
+}
+
+func TestCheckoutPaymentLink_ResumesPendingOrder(t *testing.T) {
+	t.Setenv("SECRET_KEY", "test-secret")
+	token, err := utilities.SignPaymentLink("abc123")
+	assert.NoError(t, err)
+
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db, DriverName: "postgres"}), &gorm.Config{})
+	assert.NoError(t, err)
+	database.DB = gormDB
+
+	orderID := "SCPAY-BCA-1700000000"
+	reservedUntil := time.Now().Add(time.Hour)
+	billingStudent := models.BillingStudent{StudentID: 7, PaymentStatus: repositories.BillingStudentStatusUnpaid, ReservedOrderID: &orderID, ReservedUntil: &reservedUntil}
+	billingStudent.ID = 11
+
+	mockLinkRepo := new(MockPaymentLinkRepository)
+	mockBillingStudentRepo := new(MockBillingStudentRepository)
+	service := NewPaymentLinkService(mockLinkRepo, mockBillingStudentRepo, nil, nil, nil, TransactionService{})
+
+	mockLinkRepo.On("GetPaymentLinkByCode", "abc123").Return(&models.PaymentLink{Code: "abc123", BillingStudentIds: "11", OrderID: &orderID, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	mockBillingStudentRepo.On("GetBillingStudentsByIDs", []int{11}).Return([]models.BillingStudent{billingStudent}, nil)
+	dbMock.ExpectQuery("midtrans_payment_logs").WithArgs(orderID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "redirect_url"}).
+			AddRow(1, orderID, `{"token":"snap-token","redirect_url":"https://app.midtrans.com/snap/v4/redirection/snap-token"}`))
+
+	rsp, err := service.CheckoutPaymentLink(token, &request.PaymentLinkCheckoutRequest{PaymentMethodId: 4})
+
+	assert.NoError(t, err)
+	assert.Equal(t, orderID, rsp.OrderID)
+	assert.Equal(t, "https://app.midtrans.com/snap/v4/redirection/snap-token", *rsp.RedirectURL)
+	mockLinkRepo.AssertNotCalled(t, "UpdatePaymentLink", mock.Anything)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+	"schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Webhook events older than this are not checked for unknown order IDs again.
+const reconciliationLookback = 24 * time.Hour
+
+type ReconciliationServiceInterface interface {
+	RunReconciliation(c *fiber.Ctx) (*models.ReconciliationRun, error)
+	GetAllReconciliationRun(page int, limit int) (response.ReconciliationRunListResponse, error)
+	GetReconciliationRunByID(id uint) (*models.ReconciliationRun, error)
+}
+
+type ReconciliationService struct {
+	reconciliationRepository repositories.ReconciliationRepository
+	transactionService       TransactionService
+}
+
+func NewReconciliationService(reconciliationRepository repositories.ReconciliationRepository, transactionService TransactionService) ReconciliationServiceInterface {
+	return &ReconciliationService{
+		reconciliationRepository: reconciliationRepository,
+		transactionService:       transactionService,
+	}
+}
+
+// RunReconciliation asks the gateway for the real status of every pending online transaction.
+// Status changes are applied through the webhook event log; paid orders that were never
+// notified, amount mismatches and order IDs unknown on either side are reported.
+func (reconciliationService *ReconciliationService) RunReconciliation(c *fiber.Ctx) (*models.ReconciliationRun, error) {
+	run := &models.ReconciliationRun{StartedAt: time.Now()}
+	if err := reconciliationService.reconciliationRepository.CreateReconciliationRun(run); err != nil {
+		return nil, err
+	}
+
+	transactions, err := reconciliationService.reconciliationRepository.GetPendingOnlineTransactions()
+	if err != nil {
+		return nil, err
+	}
+
+	var discrepancies []models.ReconciliationDiscrepancy
+	for _, transaction := range transactions {
+		run.TotalChecked++
+
+		discrepancy, updated, err := reconciliationService.reconcileTransaction(transaction, c)
+		if err != nil {
+			// One unreachable order must not stop the rest of the run
+			fmt.Printf("failed to reconcile order %s: %v\n", transaction.OrderID, err)
+			continue
+		}
+		if updated {
+			run.TotalUpdated++
+		}
+		if discrepancy != nil {
+			discrepancies = append(discrepancies, *discrepancy)
+		}
+	}
+
+	unknownOrderIDs, err := reconciliationService.reconciliationRepository.GetUnknownWebhookOrderIDs(run.StartedAt.Add(-reconciliationLookback))
+	if err != nil {
+		return nil, err
+	}
+	for _, orderID := range unknownOrderIDs {
+		discrepancies = append(discrepancies, models.ReconciliationDiscrepancy{
+			OrderID:         orderID,
+			DiscrepancyType: repositories.DiscrepancyTypeUnknownOrder,
+			Note:            "notified by the payment gateway but no transaction exists",
+		})
+	}
+
+	for i := range discrepancies {
+		discrepancies[i].ReconciliationRunID = run.ID
+		if err := reconciliationService.reconciliationRepository.CreateReconciliationDiscrepancy(&discrepancies[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.TotalDiscrepancies = len(discrepancies)
+	if err := reconciliationService.reconciliationRepository.UpdateReconciliationRun(run); err != nil {
+		return nil, err
+	}
+
+	run.Discrepancies = discrepancies
+	return run, nil
+}
+
+// reconcileTransaction compares one pending transaction with the gateway. The returned bool is
+// true when the gateway status was applied to the transaction.
+func (reconciliationService *ReconciliationService) reconcileTransaction(transaction response.ReconciliationPendingTransaction, c *fiber.Ctx) (*models.ReconciliationDiscrepancy, bool, error) {
+	transactionBillingID := transaction.ID
+	discrepancy := &models.ReconciliationDiscrepancy{
+		TransactionBillingID: &transactionBillingID,
+		OrderID:              transaction.OrderID,
+		LocalStatus:          transaction.TransactionStatus,
+	}
+
+	gateway, err := utilities.GetPaymentGateway(transaction.PaymentGateway)
+	if err != nil {
+		return nil, false, err
+	}
+
+	status, err := gateway.CheckStatus(transaction.OrderID)
+	if errors.Is(err, utilities.ErrPaymentGatewayOrderNotFound) {
+		discrepancy.DiscrepancyType = repositories.DiscrepancyTypeUnknownOrder
+		discrepancy.Note = fmt.Sprintf("order not found at %s", gateway.Name())
+		return discrepancy, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	discrepancy.GatewayStatus = status.TransactionStatus
+
+	expectedAmount := int64(transaction.TotalAmount) + transaction.ParentAdminFee
+	grossAmount, err := strconv.ParseFloat(status.GrossAmount, 64)
+	if err != nil {
+		return nil, false, err
+	}
+	discrepancy.LocalAmount = expectedAmount
+	discrepancy.GatewayAmount = int64(math.Round(grossAmount))
+
+	// A wrong amount needs a person to look at it before any installment is settled
+	if discrepancy.GatewayAmount != expectedAmount {
+		discrepancy.DiscrepancyType = repositories.DiscrepancyTypeAmountMismatch
+		discrepancy.Note = "gross amount at the gateway differs from billing amount plus admin fee"
+		return discrepancy, false, nil
+	}
+
+	paymentStatus, err := repositories.MapWebhookTransactionStatus(status.Notification)
+	if err != nil {
+		return nil, false, err
+	}
+	if paymentStatus == transaction.TransactionStatus {
+		return nil, false, nil
+	}
+
+	// The credit of a failed order was given back, so its late payment is not booked automatically
+	if transaction.TransactionStatus == "PS03" && paymentStatus == "PS02" {
+		discrepancy.DiscrepancyType = repositories.DiscrepancyTypePaidNotRecorded
+		discrepancy.Note = "paid at the gateway after the order failed, needs manual handling"
+		return discrepancy, false, nil
+	}
+
+	if err := reconciliationService.transactionService.ApplyGatewayStatus(status.Notification, c); err != nil {
+		return nil, false, err
+	}
+
+	if paymentStatus != "PS02" {
+		return nil, true, nil
+	}
+	discrepancy.DiscrepancyType = repositories.DiscrepancyTypePaidNotRecorded
+	discrepancy.Note = "paid at the gateway without a processed notification, status applied"
+	return discrepancy, true, nil
+}
+
+func (reconciliationService *ReconciliationService) GetAllReconciliationRun(page int, limit int) (response.ReconciliationRunListResponse, error) {
+	resp := response.ReconciliationRunListResponse{
+		Page:  page,
+		Limit: limit,
+		Data:  []models.ReconciliationRun{},
+	}
+
+	runs, total, err := reconciliationService.reconciliationRepository.GetAllReconciliationRun(page, limit)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.TotalData = total
+	if limit != 0 {
+		resp.TotalPage = int((total + int64(limit) - 1) / int64(limit))
+	}
+	if len(runs) > 0 {
+		resp.Data = runs
+	}
+
+	return resp, nil
+}
+
+func (reconciliationService *ReconciliationService) GetReconciliationRunByID(id uint) (*models.ReconciliationRun, error) {
+	return reconciliationService.reconciliationRepository.GetReconciliationRunByID(id)
+}
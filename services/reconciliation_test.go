@@ -0,0 +1,150 @@
+package services_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"schoolPayment/dtos/request"
+	"schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+	"schoolPayment/services"
+	"schoolPayment/utilities"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockReconciliationRepository is a mock implementation of the ReconciliationRepository interface
+type MockReconciliationRepository struct {
+	mock.Mock
+}
+
+func (m *MockReconciliationRepository) GetPendingOnlineTransactions() ([]response.ReconciliationPendingTransaction, error) {
+	args := m.Called()
+	return args.Get(0).([]response.ReconciliationPendingTransaction), args.Error(1)
+}
+
+func (m *MockReconciliationRepository) GetUnknownWebhookOrderIDs(since time.Time) ([]string, error) {
+	args := m.Called(since)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockReconciliationRepository) CreateReconciliationRun(run *models.ReconciliationRun) error {
+	args := m.Called(run)
+	run.ID = 1
+	return args.Error(0)
+}
+
+func (m *MockReconciliationRepository) UpdateReconciliationRun(run *models.ReconciliationRun) error {
+	args := m.Called(run)
+	return args.Error(0)
+}
+
+func (m *MockReconciliationRepository) CreateReconciliationDiscrepancy(discrepancy *models.ReconciliationDiscrepancy) error {
+	args := m.Called(discrepancy)
+	return args.Error(0)
+}
+
+func (m *MockReconciliationRepository) GetAllReconciliationRun(page int, limit int) ([]models.ReconciliationRun, int64, error) {
+	args := m.Called(page, limit)
+	return args.Get(0).([]models.ReconciliationRun), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockReconciliationRepository) GetReconciliationRunByID(id uint) (*models.ReconciliationRun, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ReconciliationRun), args.Error(1)
+}
+
+// fakeGateway answers CheckStatus from a fixed map of order statuses
+type fakeGateway struct {
+	statuses map[string]*utilities.PaymentGatewayStatus
+}
+
+func (g *fakeGateway) Name() string {
+	return "fake"
+}
+
+func (g *fakeGateway) CreateCharge(charge utilities.PaymentGatewayCharge) (*utilities.PaymentGatewayChargeResult, error) {
+	return nil, nil
+}
+
+func (g *fakeGateway) CheckStatus(orderID string) (*utilities.PaymentGatewayStatus, error) {
+	status, ok := g.statuses[orderID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", utilities.ErrPaymentGatewayOrderNotFound, orderID)
+	}
+	return status, nil
+}
+
+func (g *fakeGateway) Cancel(orderID string) error {
+	return nil
+}
+
+func (g *fakeGateway) Refund(orderID string, refundKey string, amount int64, reason string) (string, error) {
+	return "", nil
+}
+
+func (g *fakeGateway) VerifyNotification(c *fiber.Ctx) (*request.WebhookPayload, error) {
+	return nil, nil
+}
+
+func TestRunReconciliation_ReportsDiscrepancies(t *testing.T) {
+	utilities.RegisterPaymentGateway(&fakeGateway{statuses: map[string]*utilities.PaymentGatewayStatus{
+		"ORDER-PENDING":  {OrderID: "ORDER-PENDING", TransactionStatus: "pending", GrossAmount: "104000.00"},
+		"ORDER-MISMATCH": {OrderID: "ORDER-MISMATCH", TransactionStatus: "settlement", GrossAmount: "99000.00"},
+	}})
+
+	mockReconciliationRepo := new(MockReconciliationRepository)
+	mockWebhookEventRepo := new(MockWebhookEventRepository)
+	transactionService := services.NewTransactionService(repositories.TransactionRepository{}, nil, nil, nil, nil, nil, nil, mockWebhookEventRepo)
+	service := services.NewReconciliationService(mockReconciliationRepo, transactionService)
+
+	pending := func(id uint, orderID string) response.ReconciliationPendingTransaction {
+		return response.ReconciliationPendingTransaction{
+			ID:                id,
+			OrderID:           orderID,
+			TotalAmount:       100000,
+			TransactionStatus: "PS01",
+			PaymentGateway:    "fake",
+			PaymentMethod:     "VA",
+			ParentAdminFee:    4000,
+		}
+	}
+
+	mockReconciliationRepo.On("CreateReconciliationRun", mock.Anything).Return(nil)
+	mockReconciliationRepo.On("GetPendingOnlineTransactions").Return([]response.ReconciliationPendingTransaction{
+		pending(1, "ORDER-PENDING"),
+		pending(2, "ORDER-MISMATCH"),
+		pending(3, "ORDER-MISSING"),
+	}, nil)
+	mockReconciliationRepo.On("GetUnknownWebhookOrderIDs", mock.Anything).Return([]string{"ORDER-FOREIGN"}, nil)
+	mockReconciliationRepo.On("CreateReconciliationDiscrepancy", mock.Anything).Return(nil)
+	mockReconciliationRepo.On("UpdateReconciliationRun", mock.Anything).Return(nil)
+
+	run, err := service.RunReconciliation(nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, run.TotalChecked)
+	assert.Equal(t, 0, run.TotalUpdated)
+	assert.Equal(t, 3, run.TotalDiscrepancies)
+	assert.NotNil(t, run.FinishedAt)
+
+	assert.Equal(t, "ORDER-MISMATCH", run.Discrepancies[0].OrderID)
+	assert.Equal(t, repositories.DiscrepancyTypeAmountMismatch, run.Discrepancies[0].DiscrepancyType)
+	assert.Equal(t, int64(104000), run.Discrepancies[0].LocalAmount)
+	assert.Equal(t, int64(99000), run.Discrepancies[0].GatewayAmount)
+	assert.Equal(t, "ORDER-MISSING", run.Discrepancies[1].OrderID)
+	assert.Equal(t, repositories.DiscrepancyTypeUnknownOrder, run.Discrepancies[1].DiscrepancyType)
+	assert.Equal(t, "ORDER-FOREIGN", run.Discrepancies[2].OrderID)
+	assert.Nil(t, run.Discrepancies[2].TransactionBillingID)
+
+	// Neither the pending order nor the mismatched one may touch the transaction
+	mockWebhookEventRepo.AssertNotCalled(t, "CreateOrGetWebhookEvent", mock.Anything)
+	mockReconciliationRepo.AssertNumberOfCalls(t, "CreateReconciliationDiscrepancy", 3)
+}
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	request "schoolPayment/dtos/request"
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+	"schoolPayment/utilities"
+
+	"gorm.io/gorm"
+)
+
+// maxPaymentExpiryMinutes is the longest a school may give parents to finish an online payment.
+const maxPaymentExpiryMinutes = 24 * 60
+
+// maxVAPrefixLength is the longest prefix a school may set; whether the NIS still fits depends on the bank.
+const maxVAPrefixLength = 8
+
+type SchoolPaymentConfigServiceInterface interface {
+	GetSchoolPaymentConfig(userID int) (*response.SchoolPaymentConfigResponse, error)
+	UpdateSchoolPaymentConfig(configRequest *request.SchoolPaymentConfigRequest, userID int) (*response.SchoolPaymentConfigResponse, error)
+	GetSchoolPaymentMethodFees(userID int) ([]models.SchoolPaymentMethodFee, error)
+	SaveSchoolPaymentMethodFee(feeRequest *request.SchoolPaymentMethodFeeRequest, userID int) (*models.SchoolPaymentMethodFee, error)
+	DeleteSchoolPaymentMethodFee(paymentMethodID uint, userID int) error
+}
+
+type SchoolPaymentConfigService struct {
+	schoolPaymentConfigRepository repositories.SchoolPaymentConfigRepository
+	userRepository                repositories.UserRepository
+}
+
+func NewSchoolPaymentConfigService(schoolPaymentConfigRepository repositories.SchoolPaymentConfigRepository, userRepository repositories.UserRepository) SchoolPaymentConfigServiceInterface {
+	return &SchoolPaymentConfigService{
+		schoolPaymentConfigRepository: schoolPaymentConfigRepository,
+		userRepository:                userRepository,
+	}
+}
+
+// GetSchoolPaymentConfig returns the payment settings of the user's school. Schools that never
+// saved a configuration use Midtrans with the shared sandbox account.
+func (schoolPaymentConfigService *SchoolPaymentConfigService) GetSchoolPaymentConfig(userID int) (*response.SchoolPaymentConfigResponse, error) {
+	schoolID, err := schoolPaymentConfigService.getUserSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := schoolPaymentConfigService.schoolPaymentConfigRepository.GetSchoolPaymentConfigBySchoolID(schoolID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &response.SchoolPaymentConfigResponse{
+			SchoolID:                 schoolID,
+			PaymentGateway:           utilities.PaymentGatewayMidtrans,
+			AdminFeeBearer:           repositories.AdminFeeBearerParent,
+			AdminFeeParentPercentage: 100,
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapSchoolPaymentConfigResponse(config), nil
+}
+
+// UpdateSchoolPaymentConfig saves the provider, the school's own Midtrans and Xendit accounts, who
+// pays the admin fee and the payment page settings. The server key, the Xendit secret key and the
+// callback token are encrypted before they are stored and, like the admin fee bearer, are kept
+// when the request leaves them empty.
+func (schoolPaymentConfigService *SchoolPaymentConfigService) UpdateSchoolPaymentConfig(configRequest *request.SchoolPaymentConfigRequest, userID int) (*response.SchoolPaymentConfigResponse, error) {
+	if !utilities.IsSupportedPaymentGateway(configRequest.PaymentGateway) {
+		return nil, fmt.Errorf("unsupported payment gateway: %s", configRequest.PaymentGateway)
+	}
+
+	schoolID, err := schoolPaymentConfigService.getUserSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := schoolPaymentConfigService.schoolPaymentConfigRepository.GetSchoolPaymentConfigBySchoolID(schoolID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if config == nil {
+		config = &models.SchoolPaymentConfig{SchoolID: schoolID, AdminFeeBearer: repositories.AdminFeeBearerParent, AdminFeeParentPercentage: 100}
+		config.CreatedBy = userID
+	}
+
+	if configRequest.AdminFeeBearer != "" {
+		parentPercentage, err := validateAdminFeeBearer(configRequest.AdminFeeBearer, configRequest.AdminFeeParentPercentage)
+		if err != nil {
+			return nil, err
+		}
+		config.AdminFeeBearer = configRequest.AdminFeeBearer
+		config.AdminFeeParentPercentage = parentPercentage
+	}
+
+	if err := validatePaymentPageSettings(configRequest.PaymentExpiryMinutes, configRequest.FinishRedirectURL); err != nil {
+		return nil, err
+	}
+	if err := validateVAPrefix(configRequest.VAPrefix); err != nil {
+		return nil, err
+	}
+
+	if configRequest.MidtransServerKey != "" {
+		encryptedServerKey, err := utilities.EncryptCredential(configRequest.MidtransServerKey)
+		if err != nil {
+			return nil, err
+		}
+		config.MidtransServerKey = encryptedServerKey
+	}
+
+	if configRequest.XenditSecretKey != "" {
+		encryptedSecretKey, err := utilities.EncryptCredential(configRequest.XenditSecretKey)
+		if err != nil {
+			return nil, err
+		}
+		config.XenditSecretKey = encryptedSecretKey
+	}
+	if configRequest.XenditCallbackToken != "" {
+		encryptedCallbackToken, err := utilities.EncryptCredential(configRequest.XenditCallbackToken)
+		if err != nil {
+			return nil, err
+		}
+		config.XenditCallbackToken = encryptedCallbackToken
+	}
+
+	// Production must never fall back to the shared sandbox server key
+	if configRequest.IsProduction && config.MidtransServerKey == "" {
+		return nil, fmt.Errorf("midtrans server key is required for production")
+	}
+	// Nor to the shared Xendit account, whose callbacks any school could then pass off as its own
+	if configRequest.IsProduction && configRequest.PaymentGateway == utilities.PaymentGatewayXendit &&
+		(config.XenditSecretKey == "" || config.XenditCallbackToken == "") {
+		return nil, fmt.Errorf("xendit secret key and callback token are required for production")
+	}
+
+	config.PaymentGateway = configRequest.PaymentGateway
+	config.MidtransMerchantID = configRequest.MidtransMerchantID
+	config.MidtransClientKey = configRequest.MidtransClientKey
+	config.IsProduction = configRequest.IsProduction
+	config.PaymentExpiryMinutes = configRequest.PaymentExpiryMinutes
+	config.FinishRedirectURL = configRequest.FinishRedirectURL
+	config.VAPrefix = configRequest.VAPrefix
+	config.UpdatedBy = userID
+
+	config, err = schoolPaymentConfigService.schoolPaymentConfigRepository.SaveSchoolPaymentConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return mapSchoolPaymentConfigResponse(config), nil
+}
+
+func mapSchoolPaymentConfigResponse(config *models.SchoolPaymentConfig) *response.SchoolPaymentConfigResponse {
+	return &response.SchoolPaymentConfigResponse{
+		ID:                       config.ID,
+		SchoolID:                 config.SchoolID,
+		PaymentGateway:           config.PaymentGateway,
+		MidtransMerchantID:       config.MidtransMerchantID,
+		MidtransClientKey:        config.MidtransClientKey,
+		HasMidtransServerKey:     config.MidtransServerKey != "",
+		HasXenditSecretKey:       config.XenditSecretKey != "",
+		HasXenditCallbackToken:   config.XenditCallbackToken != "",
+		IsProduction:             config.IsProduction,
+		AdminFeeBearer:           config.AdminFeeBearer,
+		AdminFeeParentPercentage: config.AdminFeeParentPercentage,
+		PaymentExpiryMinutes:     config.PaymentExpiryMinutes,
+		FinishRedirectURL:        config.FinishRedirectURL,
+		VAPrefix:                 config.VAPrefix,
+	}
+}
+
+// GetSchoolPaymentMethodFees returns the payment methods whose gateway or admin fee differs
+// from the school's setting.
+func (schoolPaymentConfigService *SchoolPaymentConfigService) GetSchoolPaymentMethodFees(userID int) ([]models.SchoolPaymentMethodFee, error) {
+	schoolID, err := schoolPaymentConfigService.getUserSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return schoolPaymentConfigService.schoolPaymentConfigRepository.GetSchoolPaymentMethodFees(schoolID)
+}
+
+// SaveSchoolPaymentMethodFee sets the gateway and who pays the admin fee of one payment method,
+// replacing the setting the method had before. A school can so offer the virtual accounts of one
+// gateway next to the QRIS of another.
+func (schoolPaymentConfigService *SchoolPaymentConfigService) SaveSchoolPaymentMethodFee(feeRequest *request.SchoolPaymentMethodFeeRequest, userID int) (*models.SchoolPaymentMethodFee, error) {
+	if feeRequest.PaymentGateway == "" && feeRequest.AdminFeeBearer == "" {
+		return nil, fmt.Errorf("paymentGateway or adminFeeBearer is required")
+	}
+	if feeRequest.PaymentGateway != "" && !utilities.IsSupportedPaymentGateway(feeRequest.PaymentGateway) {
+		return nil, fmt.Errorf("unsupported payment gateway: %s", feeRequest.PaymentGateway)
+	}
+
+	var parentPercentage int
+	if feeRequest.AdminFeeBearer != "" {
+		var err error
+		parentPercentage, err = validateAdminFeeBearer(feeRequest.AdminFeeBearer, feeRequest.AdminFeeParentPercentage)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := repositories.GetPaymentMethodByID(int(feeRequest.MasterPaymentMethodID)); err != nil {
+		return nil, fmt.Errorf("payment method not found")
+	}
+
+	schoolID, err := schoolPaymentConfigService.getUserSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	fee, err := schoolPaymentConfigService.schoolPaymentConfigRepository.GetSchoolPaymentMethodFee(schoolID, feeRequest.MasterPaymentMethodID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if fee == nil {
+		fee = &models.SchoolPaymentMethodFee{SchoolID: schoolID, MasterPaymentMethodID: feeRequest.MasterPaymentMethodID}
+		fee.CreatedBy = userID
+	}
+
+	fee.PaymentGateway = feeRequest.PaymentGateway
+	fee.AdminFeeBearer = feeRequest.AdminFeeBearer
+	fee.AdminFeeParentPercentage = parentPercentage
+	fee.UpdatedBy = userID
+
+	return schoolPaymentConfigService.schoolPaymentConfigRepository.SaveSchoolPaymentMethodFee(fee)
+}
+
+// DeleteSchoolPaymentMethodFee puts the payment method back on the school's setting.
+func (schoolPaymentConfigService *SchoolPaymentConfigService) DeleteSchoolPaymentMethodFee(paymentMethodID uint, userID int) error {
+	schoolID, err := schoolPaymentConfigService.getUserSchoolID(userID)
+	if err != nil {
+		return err
+	}
+
+	fee, err := schoolPaymentConfigService.schoolPaymentConfigRepository.GetSchoolPaymentMethodFee(schoolID, paymentMethodID)
+	if err != nil {
+		return fmt.Errorf("admin fee setting not found")
+	}
+
+	now := time.Now()
+	fee.DeletedAt = &now
+	fee.DeletedBy = &userID
+	_, err = schoolPaymentConfigService.schoolPaymentConfigRepository.SaveSchoolPaymentMethodFee(fee)
+	return err
+}
+
+// validateAdminFeeBearer checks the bearer and returns the parent's percentage of the fee, which
+// only a split sets itself.
+func validateAdminFeeBearer(bearer string, parentPercentage int) (int, error) {
+	switch bearer {
+	case repositories.AdminFeeBearerParent:
+		return 100, nil
+	case repositories.AdminFeeBearerSchool:
+		return 0, nil
+	case repositories.AdminFeeBearerSplit:
+		if parentPercentage <= 0 || parentPercentage >= 100 {
+			return 0, fmt.Errorf("adminFeeParentPercentage of a split must be between 1 and 99")
+		}
+		return parentPercentage, nil
+	default:
+		return 0, fmt.Errorf("adminFeeBearer must be parent, school or split")
+	}
+}
+
+// validatePaymentPageSettings checks the time to pay, at most one day, and that the page the
+// parent returns to after paying is an http(s) address.
+func validatePaymentPageSettings(expiryMinutes int, finishRedirectURL string) error {
+	if expiryMinutes < 0 || expiryMinutes > maxPaymentExpiryMinutes {
+		return fmt.Errorf("paymentExpiryMinutes must be between 0 and %d", maxPaymentExpiryMinutes)
+	}
+	if finishRedirectURL == "" {
+		return nil
+	}
+	parsedURL, err := url.Parse(finishRedirectURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return fmt.Errorf("finishRedirectUrl must be an http or https address")
+	}
+	return nil
+}
+
+// validateVAPrefix checks the prefix of the fixed virtual account numbers is up to 8 digits.
+func validateVAPrefix(prefix string) error {
+	if len(prefix) > maxVAPrefixLength {
+		return fmt.Errorf("vaPrefix must be at most %d digits", maxVAPrefixLength)
+	}
+	for _, r := range prefix {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("vaPrefix must only contain digits")
+		}
+	}
+	return nil
+}
+
+func (schoolPaymentConfigService *SchoolPaymentConfigService) getUserSchoolID(userID int) (uint, error) {
+	user, err := schoolPaymentConfigService.userRepository.GetUserByID(uint(userID))
+	if err != nil {
+		return 0, err
+	}
+
+	if user.UserSchool == nil {
+		return 0, fmt.Errorf("user not associated with any school")
+	}
+	return user.UserSchool.SchoolID, nil
+}
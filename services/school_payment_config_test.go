@@ -0,0 +1,353 @@
+package services_test
+
+import (
+	"testing"
+
+	"schoolPayment/dtos/request"
+	"schoolPayment/models"
+	"schoolPayment/services"
+	"schoolPayment/utilities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockSchoolPaymentConfigRepository is a mock implementation of the SchoolPaymentConfigRepository interface
+type MockSchoolPaymentConfigRepository struct {
+	mock.Mock
+}
+
+func (m *MockSchoolPaymentConfigRepository) GetSchoolPaymentConfigBySchoolID(schoolID uint) (*models.SchoolPaymentConfig, error) {
+	args := m.Called(schoolID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SchoolPaymentConfig), args.Error(1)
+}
+
+func (m *MockSchoolPaymentConfigRepository) SaveSchoolPaymentConfig(config *models.SchoolPaymentConfig) (*models.SchoolPaymentConfig, error) {
+	args := m.Called(config)
+	return args.Get(0).(*models.SchoolPaymentConfig), args.Error(1)
+}
+
+func TestGetSchoolPaymentConfig_DefaultsToMidtrans(t *testing.T) {
+	mockConfigRepo := new(MockSchoolPaymentConfigRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewSchoolPaymentConfigService(mockConfigRepo, mockUserRepo)
+
+	mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 4}}, nil)
+	mockConfigRepo.On("GetSchoolPaymentConfigBySchoolID", uint(4)).Return(nil, gorm.ErrRecordNotFound)
+
+	config, err := service.GetSchoolPaymentConfig(1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(4), config.SchoolID)
+	assert.Equal(t, "midtrans", config.PaymentGateway)
+}
+
+func TestUpdateSchoolPaymentConfig_CreatesConfig(t *testing.T) {
+	mockConfigRepo := new(MockSchoolPaymentConfigRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewSchoolPaymentConfigService(mockConfigRepo, mockUserRepo)
+
+	mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 4}}, nil)
+	mockConfigRepo.On("GetSchoolPaymentConfigBySchoolID", uint(4)).Return(nil, gorm.ErrRecordNotFound)
+	mockConfigRepo.On("SaveSchoolPaymentConfig", mock.MatchedBy(func(config *models.SchoolPaymentConfig) bool {
+		return config.SchoolID == 4 && config.PaymentGateway == "xendit" && config.CreatedBy == 1
+	})).Return(&models.SchoolPaymentConfig{SchoolID: 4, PaymentGateway: "xendit"}, nil)
+
+	config, err := service.UpdateSchoolPaymentConfig(&request.SchoolPaymentConfigRequest{PaymentGateway: "xendit"}, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "xendit", config.PaymentGateway)
+	mockConfigRepo.AssertExpectations(t)
+}
+
+func TestUpdateSchoolPaymentConfig_UnsupportedGateway(t *testing.T) {
+	mockConfigRepo := new(MockSchoolPaymentConfigRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewSchoolPaymentConfigService(mockConfigRepo, mockUserRepo)
+
+	_, err := service.UpdateSchoolPaymentConfig(&request.SchoolPaymentConfigRequest{PaymentGateway: "doku"}, 1)
+
+	assert.EqualError(t, err, "unsupported payment gateway: doku")
+	mockUserRepo.AssertNotCalled(t, "GetUserByID", mock.Anything)
+}
+
+func TestUpdateSchoolPaymentConfig_EncryptsServerKey(t *testing.T) {
+	t.Setenv("PAYMENT_CREDENTIAL_KEY", "test-credential-key")
+	mockConfigRepo := new(MockSchoolPaymentConfigRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewSchoolPaymentConfigService(mockConfigRepo, mockUserRepo)
+
+	mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 4}}, nil)
+	mockConfigRepo.On("GetSchoolPaymentConfigBySchoolID", uint(4)).Return(nil, gorm.ErrRecordNotFound)
+	mockConfigRepo.On("SaveSchoolPaymentConfig", mock.MatchedBy(func(config *models.SchoolPaymentConfig) bool {
+		serverKey, err := utilities.DecryptCredential(config.MidtransServerKey)
+		return err == nil && serverKey == "school-server-key" && config.MidtransServerKey != "school-server-key" && config.IsProduction
+	})).Return(&models.SchoolPaymentConfig{SchoolID: 4, PaymentGateway: "midtrans", MidtransServerKey: "encrypted", IsProduction: true}, nil)
+
+	config, err := service.UpdateSchoolPaymentConfig(&request.SchoolPaymentConfigRequest{
+		PaymentGateway:    "midtrans",
+		MidtransServerKey: "school-server-key",
+		IsProduction:      true,
+	}, 1)
+
+	assert.NoError(t, err)
+	assert.True(t, config.HasMidtransServerKey)
+	assert.True(t, config.IsProduction)
+	mockConfigRepo.AssertExpectations(t)
+}
+
+func TestUpdateSchoolPaymentConfig_ProductionRequiresServerKey(t *testing.T) {
+	mockConfigRepo := new(MockSchoolPaymentConfigRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewSchoolPaymentConfigService(mockConfigRepo, mockUserRepo)
+
+	mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 4}}, nil)
+	mockConfigRepo.On("GetSchoolPaymentConfigBySchoolID", uint(4)).Return(&models.SchoolPaymentConfig{SchoolID: 4, PaymentGateway: "midtrans"}, nil)
+
+	_, err := service.UpdateSchoolPaymentConfig(&request.SchoolPaymentConfigRequest{PaymentGateway: "midtrans", IsProduction: true}, 1)
+
+	assert.EqualError(t, err, "midtrans server key is required for production")
+	mockConfigRepo.AssertNotCalled(t, "SaveSchoolPaymentConfig", mock.Anything)
+}
+
+func TestUpdateSchoolPaymentConfig_EncryptsXenditCredentials(t *testing.T) {
+	t.Setenv("PAYMENT_CREDENTIAL_KEY", "test-credential-key")
+	mockConfigRepo := new(MockSchoolPaymentConfigRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewSchoolPaymentConfigService(mockConfigRepo, mockUserRepo)
+
+	mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 4}}, nil)
+	mockConfigRepo.On("GetSchoolPaymentConfigBySchoolID", uint(4)).Return(nil, gorm.ErrRecordNotFound)
+	mockConfigRepo.On("SaveSchoolPaymentConfig", mock.MatchedBy(func(config *models.SchoolPaymentConfig) bool {
+		secretKey, errSecret := utilities.DecryptCredential(config.XenditSecretKey)
+		callbackToken, errToken := utilities.DecryptCredential(config.XenditCallbackToken)
+		return errSecret == nil && errToken == nil && secretKey == "xnd_school" && callbackToken == "school-token"
+	})).Return(&models.SchoolPaymentConfig{SchoolID: 4, PaymentGateway: "xendit", XenditSecretKey: "encrypted", XenditCallbackToken: "encrypted"}, nil)
+
+	config, err := service.UpdateSchoolPaymentConfig(&request.SchoolPaymentConfigRequest{
+		PaymentGateway:      "xendit",
+		XenditSecretKey:     "xnd_school",
+		XenditCallbackToken: "school-token",
+	}, 1)
+
+	assert.NoError(t, err)
+	assert.True(t, config.HasXenditSecretKey)
+	assert.True(t, config.HasXenditCallbackToken)
+	mockConfigRepo.AssertExpectations(t)
+}
+
+func TestUpdateSchoolPaymentConfig_ProductionRequiresXenditAccount(t *testing.T) {
+	t.Setenv("PAYMENT_CREDENTIAL_KEY", "test-credential-key")
+	mockConfigRepo := new(MockSchoolPaymentConfigRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewSchoolPaymentConfigService(mockConfigRepo, mockUserRepo)
+
+	mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 4}}, nil)
+	mockConfigRepo.On("GetSchoolPaymentConfigBySchoolID", uint(4)).Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := service.UpdateSchoolPaymentConfig(&request.SchoolPaymentConfigRequest{
+		PaymentGateway:    "xendit",
+		MidtransServerKey: "school-server-key",
+		XenditSecretKey:   "xnd_school",
+		IsProduction:      true,
+	}, 1)
+
+	assert.EqualError(t, err, "xendit secret key and callback token are required for production")
+	mockConfigRepo.AssertNotCalled(t, "SaveSchoolPaymentConfig", mock.Anything)
+}
+
+func TestUpdateSchoolPaymentConfig_PaymentPageSettings(t *testing.T) {
+	t.Run("Saves expiry and finish redirect", func(t *testing.T) {
+		mockConfigRepo := new(MockSchoolPaymentConfigRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := services.NewSchoolPaymentConfigService(mockConfigRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 4}}, nil)
+		mockConfigRepo.On("GetSchoolPaymentConfigBySchoolID", uint(4)).Return(&models.SchoolPaymentConfig{SchoolID: 4, PaymentGateway: "midtrans"}, nil)
+		mockConfigRepo.On("SaveSchoolPaymentConfig", mock.Anything).Return(&models.SchoolPaymentConfig{
+			SchoolID:             4,
+			PaymentGateway:       "midtrans",
+			PaymentExpiryMinutes: 60,
+			FinishRedirectURL:    "https://sekolah.example/bayar/selesai",
+		}, nil)
+
+		config, err := service.UpdateSchoolPaymentConfig(&request.SchoolPaymentConfigRequest{
+			PaymentGateway:       "midtrans",
+			PaymentExpiryMinutes: 60,
+			FinishRedirectURL:    "https://sekolah.example/bayar/selesai",
+		}, 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 60, config.PaymentExpiryMinutes)
+		assert.Equal(t, "https://sekolah.example/bayar/selesai", config.FinishRedirectURL)
+	})
+
+	t.Run("Rejects expiry longer than a day", func(t *testing.T) {
+		mockConfigRepo := new(MockSchoolPaymentConfigRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := services.NewSchoolPaymentConfigService(mockConfigRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 4}}, nil)
+		mockConfigRepo.On("GetSchoolPaymentConfigBySchoolID", uint(4)).Return(&models.SchoolPaymentConfig{SchoolID: 4, PaymentGateway: "midtrans"}, nil)
+
+		_, err := service.UpdateSchoolPaymentConfig(&request.SchoolPaymentConfigRequest{PaymentGateway: "midtrans", PaymentExpiryMinutes: 1441}, 1)
+
+		assert.EqualError(t, err, "paymentExpiryMinutes must be between 0 and 1440")
+		mockConfigRepo.AssertNotCalled(t, "SaveSchoolPaymentConfig", mock.Anything)
+	})
+
+	t.Run("Rejects a finish redirect that is not http", func(t *testing.T) {
+		mockConfigRepo := new(MockSchoolPaymentConfigRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := services.NewSchoolPaymentConfigService(mockConfigRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 4}}, nil)
+		mockConfigRepo.On("GetSchoolPaymentConfigBySchoolID", uint(4)).Return(&models.SchoolPaymentConfig{SchoolID: 4, PaymentGateway: "midtrans"}, nil)
+
+		_, err := service.UpdateSchoolPaymentConfig(&request.SchoolPaymentConfigRequest{PaymentGateway: "midtrans", FinishRedirectURL: "javascript:alert(1)"}, 1)
+
+		assert.EqualError(t, err, "finishRedirectUrl must be an http or https address")
+		mockConfigRepo.AssertNotCalled(t, "SaveSchoolPaymentConfig", mock.Anything)
+	})
+}
+
+func TestUpdateSchoolPaymentConfig_VAPrefix(t *testing.T) {
+	mockConfigRepo := new(MockSchoolPaymentConfigRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewSchoolPaymentConfigService(mockConfigRepo, mockUserRepo)
+
+	mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 4}}, nil)
+	mockConfigRepo.On("GetSchoolPaymentConfigBySchoolID", uint(4)).Return(&models.SchoolPaymentConfig{SchoolID: 4, PaymentGateway: "midtrans"}, nil)
+
+	_, err := service.UpdateSchoolPaymentConfig(&request.SchoolPaymentConfigRequest{PaymentGateway: "midtrans", VAPrefix: "12A4"}, 1)
+	assert.EqualError(t, err, "vaPrefix must only contain digits")
+
+	_, err = service.UpdateSchoolPaymentConfig(&request.SchoolPaymentConfigRequest{PaymentGateway: "midtrans", VAPrefix: "123456789"}, 1)
+	assert.EqualError(t, err, "vaPrefix must be at most 8 digits")
+
+	mockConfigRepo.AssertNotCalled(t, "SaveSchoolPaymentConfig", mock.Anything)
+}
+
+func (m *MockSchoolPaymentConfigRepository) GetSchoolPaymentMethodFees(schoolID uint) ([]models.SchoolPaymentMethodFee, error) {
+	args := m.Called(schoolID)
+	return args.Get(0).([]models.SchoolPaymentMethodFee), args.Error(1)
+}
+
+func (m *MockSchoolPaymentConfigRepository) GetSchoolPaymentMethodFee(schoolID uint, paymentMethodID uint) (*models.SchoolPaymentMethodFee, error) {
+	args := m.Called(schoolID, paymentMethodID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SchoolPaymentMethodFee), args.Error(1)
+}
+
+func (m *MockSchoolPaymentConfigRepository) SaveSchoolPaymentMethodFee(fee *models.SchoolPaymentMethodFee) (*models.SchoolPaymentMethodFee, error) {
+	args := m.Called(fee)
+	return fee, args.Error(0)
+}
+
+func TestUpdateSchoolPaymentConfig_AdminFeeBearer(t *testing.T) {
+	t.Run("Split keeps the parent's share", func(t *testing.T) {
+		mockConfigRepo := new(MockSchoolPaymentConfigRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := services.NewSchoolPaymentConfigService(mockConfigRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 4}}, nil)
+		mockConfigRepo.On("GetSchoolPaymentConfigBySchoolID", uint(4)).Return(&models.SchoolPaymentConfig{SchoolID: 4, PaymentGateway: "midtrans", AdminFeeBearer: "parent", AdminFeeParentPercentage: 100}, nil)
+		mockConfigRepo.On("SaveSchoolPaymentConfig", mock.MatchedBy(func(config *models.SchoolPaymentConfig) bool {
+			return config.AdminFeeBearer == "split" && config.AdminFeeParentPercentage == 40
+		})).Return(&models.SchoolPaymentConfig{SchoolID: 4, PaymentGateway: "midtrans", AdminFeeBearer: "split", AdminFeeParentPercentage: 40}, nil)
+
+		config, err := service.UpdateSchoolPaymentConfig(&request.SchoolPaymentConfigRequest{PaymentGateway: "midtrans", AdminFeeBearer: "split", AdminFeeParentPercentage: 40}, 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "split", config.AdminFeeBearer)
+		assert.Equal(t, 40, config.AdminFeeParentPercentage)
+		mockConfigRepo.AssertExpectations(t)
+	})
+
+	t.Run("Empty bearer keeps the stored setting", func(t *testing.T) {
+		mockConfigRepo := new(MockSchoolPaymentConfigRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := services.NewSchoolPaymentConfigService(mockConfigRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 4}}, nil)
+		mockConfigRepo.On("GetSchoolPaymentConfigBySchoolID", uint(4)).Return(&models.SchoolPaymentConfig{SchoolID: 4, PaymentGateway: "midtrans", AdminFeeBearer: "school"}, nil)
+		mockConfigRepo.On("SaveSchoolPaymentConfig", mock.MatchedBy(func(config *models.SchoolPaymentConfig) bool {
+			return config.AdminFeeBearer == "school"
+		})).Return(&models.SchoolPaymentConfig{SchoolID: 4, PaymentGateway: "xendit", AdminFeeBearer: "school"}, nil)
+
+		_, err := service.UpdateSchoolPaymentConfig(&request.SchoolPaymentConfigRequest{PaymentGateway: "xendit"}, 1)
+
+		assert.NoError(t, err)
+		mockConfigRepo.AssertExpectations(t)
+	})
+
+	t.Run("Split needs a share between 1 and 99", func(t *testing.T) {
+		mockConfigRepo := new(MockSchoolPaymentConfigRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := services.NewSchoolPaymentConfigService(mockConfigRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 4}}, nil)
+		mockConfigRepo.On("GetSchoolPaymentConfigBySchoolID", uint(4)).Return(nil, gorm.ErrRecordNotFound)
+
+		_, err := service.UpdateSchoolPaymentConfig(&request.SchoolPaymentConfigRequest{PaymentGateway: "midtrans", AdminFeeBearer: "split", AdminFeeParentPercentage: 100}, 1)
+
+		assert.EqualError(t, err, "adminFeeParentPercentage of a split must be between 1 and 99")
+		mockConfigRepo.AssertNotCalled(t, "SaveSchoolPaymentConfig", mock.Anything)
+	})
+}
+
+func TestSaveSchoolPaymentMethodFee_UnknownBearer(t *testing.T) {
+	mockConfigRepo := new(MockSchoolPaymentConfigRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewSchoolPaymentConfigService(mockConfigRepo, mockUserRepo)
+
+	_, err := service.SaveSchoolPaymentMethodFee(&request.SchoolPaymentMethodFeeRequest{MasterPaymentMethodID: 2, AdminFeeBearer: "student"}, 1)
+
+	assert.EqualError(t, err, "adminFeeBearer must be parent, school or split")
+	mockConfigRepo.AssertNotCalled(t, "SaveSchoolPaymentMethodFee", mock.Anything)
+}
+
+func TestSaveSchoolPaymentMethodFee_UnsupportedGateway(t *testing.T) {
+	mockConfigRepo := new(MockSchoolPaymentConfigRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewSchoolPaymentConfigService(mockConfigRepo, mockUserRepo)
+
+	_, err := service.SaveSchoolPaymentMethodFee(&request.SchoolPaymentMethodFeeRequest{MasterPaymentMethodID: 2, PaymentGateway: "paypal"}, 1)
+
+	assert.EqualError(t, err, "unsupported payment gateway: paypal")
+	mockConfigRepo.AssertNotCalled(t, "SaveSchoolPaymentMethodFee", mock.Anything)
+}
+
+func TestSaveSchoolPaymentMethodFee_NothingToSave(t *testing.T) {
+	mockConfigRepo := new(MockSchoolPaymentConfigRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewSchoolPaymentConfigService(mockConfigRepo, mockUserRepo)
+
+	_, err := service.SaveSchoolPaymentMethodFee(&request.SchoolPaymentMethodFeeRequest{MasterPaymentMethodID: 2}, 1)
+
+	assert.EqualError(t, err, "paymentGateway or adminFeeBearer is required")
+	mockConfigRepo.AssertNotCalled(t, "SaveSchoolPaymentMethodFee", mock.Anything)
+}
+
+func TestDeleteSchoolPaymentMethodFee(t *testing.T) {
+	mockConfigRepo := new(MockSchoolPaymentConfigRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewSchoolPaymentConfigService(mockConfigRepo, mockUserRepo)
+
+	mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 4}}, nil)
+	mockConfigRepo.On("GetSchoolPaymentMethodFee", uint(4), uint(2)).Return(&models.SchoolPaymentMethodFee{SchoolID: 4, MasterPaymentMethodID: 2, AdminFeeBearer: "school"}, nil)
+	mockConfigRepo.On("SaveSchoolPaymentMethodFee", mock.MatchedBy(func(fee *models.SchoolPaymentMethodFee) bool {
+		return fee.DeletedAt != nil && *fee.DeletedBy == 1
+	})).Return(nil)
+
+	err := service.DeleteSchoolPaymentMethodFee(2, 1)
+
+	assert.NoError(t, err)
+	mockConfigRepo.AssertExpectations(t)
+}
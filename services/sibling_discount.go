@@ -0,0 +1,313 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	request "schoolPayment/dtos/request"
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+)
+
+type SiblingDiscountServiceInterface interface {
+	CreateSiblingDiscountTier(tierRequest *request.SiblingDiscountTierRequest, userID int) (*models.SiblingDiscountTier, error)
+	UpdateSiblingDiscountTier(tierID uint, tierRequest *request.SiblingDiscountTierRequest, userID int) (*models.SiblingDiscountTier, error)
+	DeleteSiblingDiscountTier(tierID uint, userID int) error
+	GetAllSiblingDiscountTier(page int, limit int, userID int) (response.SiblingDiscountTierListResponse, error)
+	GetSiblingDiscountTierByID(tierID uint, userID int) (*models.SiblingDiscountTier, error)
+	RecalculateSiblingDiscount(userID int) (response.SiblingDiscountRecalculateResponse, error)
+}
+
+type SiblingDiscountService struct {
+	discountProgramRepository repositories.DiscountProgramRepository
+	userRepository            repositories.UserRepository
+}
+
+func NewSiblingDiscountService(discountProgramRepository repositories.DiscountProgramRepository, userRepository repositories.UserRepository) SiblingDiscountServiceInterface {
+	return &SiblingDiscountService{
+		discountProgramRepository: discountProgramRepository,
+		userRepository:            userRepository,
+	}
+}
+
+func (siblingDiscountService *SiblingDiscountService) CreateSiblingDiscountTier(tierRequest *request.SiblingDiscountTierRequest, userID int) (*models.SiblingDiscountTier, error) {
+	schoolID, err := siblingDiscountService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tier := &models.SiblingDiscountTier{SchoolID: schoolID, IsActive: true}
+	if err := siblingDiscountService.fillSiblingDiscountTier(tier, tierRequest); err != nil {
+		return nil, err
+	}
+	tier.CreatedBy = userID
+
+	return siblingDiscountService.discountProgramRepository.CreateSiblingDiscountTier(tier)
+}
+
+// UpdateSiblingDiscountTier changes the tier for installments created from now on. Open
+// installments follow once the school's sibling discounts are recalculated.
+func (siblingDiscountService *SiblingDiscountService) UpdateSiblingDiscountTier(tierID uint, tierRequest *request.SiblingDiscountTierRequest, userID int) (*models.SiblingDiscountTier, error) {
+	tier, err := siblingDiscountService.GetSiblingDiscountTierByID(tierID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("sibling discount tier not found")
+	}
+
+	if err := siblingDiscountService.fillSiblingDiscountTier(tier, tierRequest); err != nil {
+		return nil, err
+	}
+	tier.UpdatedBy = userID
+
+	if err := siblingDiscountService.discountProgramRepository.UpdateSiblingDiscountTier(tier); err != nil {
+		return nil, err
+	}
+	return tier, nil
+}
+
+func (siblingDiscountService *SiblingDiscountService) DeleteSiblingDiscountTier(tierID uint, userID int) error {
+	tier, err := siblingDiscountService.GetSiblingDiscountTierByID(tierID, userID)
+	if err != nil {
+		return fmt.Errorf("sibling discount tier not found")
+	}
+
+	now := time.Now()
+	tier.DeletedAt = &now
+	tier.DeletedBy = &userID
+	return siblingDiscountService.discountProgramRepository.UpdateSiblingDiscountTier(tier)
+}
+
+func (siblingDiscountService *SiblingDiscountService) GetAllSiblingDiscountTier(page int, limit int, userID int) (response.SiblingDiscountTierListResponse, error) {
+	resp := response.SiblingDiscountTierListResponse{
+		Page:  page,
+		Limit: limit,
+		Data:  []models.SiblingDiscountTier{},
+	}
+
+	schoolID, err := siblingDiscountService.getSchoolID(userID)
+	if err != nil {
+		return resp, err
+	}
+
+	tiers, total, err := siblingDiscountService.discountProgramRepository.GetAllSiblingDiscountTier(page, limit, schoolID)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.TotalData = total
+	if limit != 0 {
+		resp.TotalPage = int((total + int64(limit) - 1) / int64(limit))
+	}
+	if len(tiers) > 0 {
+		resp.Data = tiers
+	}
+
+	return resp, nil
+}
+
+func (siblingDiscountService *SiblingDiscountService) GetSiblingDiscountTierByID(tierID uint, userID int) (*models.SiblingDiscountTier, error) {
+	schoolID, err := siblingDiscountService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return siblingDiscountService.discountProgramRepository.GetSiblingDiscountTierByID(tierID, schoolID)
+}
+
+// RecalculateSiblingDiscount brings the open installments of the school in line with the
+// current households, after a sibling enrolls, withdraws or the tiers change. Installments
+// that are already (partly) paid or held by a checkout keep the amount they were billed with.
+func (siblingDiscountService *SiblingDiscountService) RecalculateSiblingDiscount(userID int) (response.SiblingDiscountRecalculateResponse, error) {
+	var resp response.SiblingDiscountRecalculateResponse
+
+	schoolID, err := siblingDiscountService.getSchoolID(userID)
+	if err != nil {
+		return resp, err
+	}
+
+	tiers, err := LoadSiblingDiscountTiers(siblingDiscountService.discountProgramRepository, schoolID)
+	if err != nil {
+		return resp, err
+	}
+
+	installments, err := siblingDiscountService.discountProgramRepository.GetOpenSiblingDiscountInstallments(schoolID)
+	if err != nil {
+		return resp, err
+	}
+
+	for i := range installments {
+		installment := &installments[i]
+		resp.TotalChecked++
+		if !ApplySiblingDiscount(installment, tiers[installment.StudentID]) {
+			continue
+		}
+
+		updated, err := siblingDiscountService.discountProgramRepository.UpdateInstallmentSiblingDiscount(installment)
+		if err != nil {
+			return resp, err
+		}
+		if updated {
+			resp.TotalUpdated++
+		}
+	}
+
+	for _, installment := range installments {
+		resp.TotalDiscount += installment.SiblingDiscountAmount
+	}
+
+	return resp, nil
+}
+
+// LoadSiblingDiscountTiers returns the tier each active student of the school gets from their
+// position among their siblings. Students without a tier are left out.
+func LoadSiblingDiscountTiers(discountProgramRepository repositories.DiscountProgramRepository, schoolID uint) (map[uint]*models.SiblingDiscountTier, error) {
+	studentTiers := make(map[uint]*models.SiblingDiscountTier)
+
+	tiers, err := discountProgramRepository.GetActiveSiblingDiscountTiers(schoolID)
+	if err != nil || len(tiers) == 0 {
+		return studentTiers, err
+	}
+
+	links, err := discountProgramRepository.GetHouseholdLinks(schoolID)
+	if err != nil {
+		return nil, err
+	}
+
+	for studentID, order := range RankSiblings(links) {
+		if tier := FindSiblingDiscountTier(tiers, order); tier != nil {
+			studentTiers[studentID] = tier
+		}
+	}
+	return studentTiers, nil
+}
+
+// RankSiblings groups students that share a parent account, email or phone into households
+// and numbers the children of each household from the oldest, starting at 1.
+func RankSiblings(links []response.HouseholdLink) map[uint]int {
+	parent := make(map[uint]uint)
+	birthDates := make(map[uint]*time.Time)
+
+	var find func(studentID uint) uint
+	find = func(studentID uint) uint {
+		if parent[studentID] != studentID {
+			parent[studentID] = find(parent[studentID])
+		}
+		return parent[studentID]
+	}
+
+	firstByKey := make(map[string]uint)
+	for _, link := range links {
+		if _, ok := parent[link.StudentID]; !ok {
+			parent[link.StudentID] = link.StudentID
+			birthDates[link.StudentID] = link.BirthDate
+		}
+
+		first, ok := firstByKey[link.HouseholdKey]
+		if !ok {
+			firstByKey[link.HouseholdKey] = link.StudentID
+			continue
+		}
+		if a, b := find(first), find(link.StudentID); a != b {
+			parent[b] = a
+		}
+	}
+
+	households := make(map[uint][]uint)
+	for studentID := range parent {
+		root := find(studentID)
+		households[root] = append(households[root], studentID)
+	}
+
+	ranks := make(map[uint]int, len(parent))
+	for _, children := range households {
+		sort.Slice(children, func(i, j int) bool {
+			a, b := birthDates[children[i]], birthDates[children[j]]
+			if a != nil && b != nil && !a.Equal(*b) {
+				return a.Before(*b)
+			}
+			if (a == nil) != (b == nil) {
+				return a != nil
+			}
+			return children[i] < children[j]
+		})
+		for i, studentID := range children {
+			ranks[studentID] = i + 1
+		}
+	}
+	return ranks
+}
+
+// FindSiblingDiscountTier picks the tier with the highest child order the child has reached,
+// so a tier for the 3rd child also covers the 4th when no later tier is set.
+func FindSiblingDiscountTier(tiers []models.SiblingDiscountTier, childOrder int) *models.SiblingDiscountTier {
+	var found *models.SiblingDiscountTier
+	for i := range tiers {
+		if tiers[i].ChildOrder <= childOrder && (found == nil || tiers[i].ChildOrder > found.ChildOrder) {
+			found = &tiers[i]
+		}
+	}
+	return found
+}
+
+// ApplySiblingDiscount replaces the sibling discount on the installment with the one of tier,
+// taken off what is left after any scholarship. A nil tier removes it. It reports whether the
+// amounts changed. The payment status follows whether anything is left to pay.
+func ApplySiblingDiscount(billingStudent *models.BillingStudent, tier *models.SiblingDiscountTier) bool {
+	base := billingStudent.Amount + billingStudent.SiblingDiscountAmount
+
+	var discount int64
+	if tier != nil && base > 0 {
+		discount = CalculateDiscount(tier.DiscountType, tier.Amount, base)
+	}
+	if discount == billingStudent.SiblingDiscountAmount {
+		return false
+	}
+
+	billingStudent.DiscountAmount += discount - billingStudent.SiblingDiscountAmount
+	billingStudent.SiblingDiscountAmount = discount
+	billingStudent.Amount = base - discount
+	setDiscountedPaymentStatus(billingStudent)
+	return true
+}
+
+func (siblingDiscountService *SiblingDiscountService) fillSiblingDiscountTier(tier *models.SiblingDiscountTier, tierRequest *request.SiblingDiscountTierRequest) error {
+	if tierRequest.ChildOrder < 2 {
+		return fmt.Errorf("childOrder must be 2 or more")
+	}
+	if tierRequest.DiscountType != repositories.DiscountTypePercentage && tierRequest.DiscountType != repositories.DiscountTypeFixed {
+		return fmt.Errorf("discountType must be percentage or fixed")
+	}
+	if tierRequest.Amount <= 0 {
+		return fmt.Errorf("amount must be greater than 0")
+	}
+	if tierRequest.DiscountType == repositories.DiscountTypePercentage && tierRequest.Amount > 100 {
+		return fmt.Errorf("percentage cannot be more than 100")
+	}
+
+	exists, err := siblingDiscountService.discountProgramRepository.SiblingDiscountTierExists(tierRequest.ChildOrder, tier.SchoolID, tier.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("a tier for child %d already exists", tierRequest.ChildOrder)
+	}
+
+	tier.ChildOrder = tierRequest.ChildOrder
+	tier.DiscountType = tierRequest.DiscountType
+	tier.Amount = tierRequest.Amount
+	if tierRequest.IsActive != nil {
+		tier.IsActive = *tierRequest.IsActive
+	}
+	return nil
+}
+
+func (siblingDiscountService *SiblingDiscountService) getSchoolID(userID int) (uint, error) {
+	user, err := siblingDiscountService.userRepository.GetUserByID(uint(userID))
+	if err != nil {
+		return 0, err
+	}
+	if user.UserSchool == nil {
+		return 0, fmt.Errorf("user not associated with any school")
+	}
+	return user.UserSchool.SchoolID, nil
+}
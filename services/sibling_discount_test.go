@@ -0,0 +1,195 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	request "schoolPayment/dtos/request"
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRankSiblings(t *testing.T) {
+	older := time.Date(2012, 5, 1, 0, 0, 0, 0, time.UTC)
+	middle := time.Date(2014, 8, 1, 0, 0, 0, 0, time.UTC)
+	younger := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	ranks := RankSiblings([]response.HouseholdLink{
+		// Students 1 and 2 share a parent account; student 3 is linked through the parent's email
+		{StudentID: 2, BirthDate: &middle, HouseholdKey: "u:10"},
+		{StudentID: 1, BirthDate: &younger, HouseholdKey: "u:10"},
+		{StudentID: 1, BirthDate: &younger, HouseholdKey: "m:ibu@mail.com"},
+		{StudentID: 3, BirthDate: &older, HouseholdKey: "u:11"},
+		{StudentID: 3, BirthDate: &older, HouseholdKey: "m:ibu@mail.com"},
+		{StudentID: 4, BirthDate: &older, HouseholdKey: "u:12"},
+	})
+
+	assert.Equal(t, map[uint]int{3: 1, 2: 2, 1: 3, 4: 1}, ranks)
+}
+
+func TestFindSiblingDiscountTier(t *testing.T) {
+	tiers := []models.SiblingDiscountTier{
+		{ChildOrder: 2, DiscountType: repositories.DiscountTypePercentage, Amount: 10},
+		{ChildOrder: 3, DiscountType: repositories.DiscountTypePercentage, Amount: 15},
+	}
+
+	assert.Nil(t, FindSiblingDiscountTier(tiers, 1))
+	assert.Equal(t, float64(10), FindSiblingDiscountTier(tiers, 2).Amount)
+	assert.Equal(t, float64(15), FindSiblingDiscountTier(tiers, 3).Amount)
+	assert.Equal(t, float64(15), FindSiblingDiscountTier(tiers, 5).Amount)
+}
+
+func TestApplySiblingDiscount(t *testing.T) {
+	tier := &models.SiblingDiscountTier{DiscountType: repositories.DiscountTypePercentage, Amount: 10}
+
+	t.Run("Taken off after the scholarship", func(t *testing.T) {
+		billingStudent := models.BillingStudent{Amount: 400000, DiscountAmount: 100000}
+
+		assert.True(t, ApplySiblingDiscount(&billingStudent, tier))
+		assert.Equal(t, int64(360000), billingStudent.Amount)
+		assert.Equal(t, int64(140000), billingStudent.DiscountAmount)
+		assert.Equal(t, int64(40000), billingStudent.SiblingDiscountAmount)
+	})
+
+	t.Run("Replaced when the tier changes", func(t *testing.T) {
+		billingStudent := models.BillingStudent{Amount: 360000, DiscountAmount: 140000, SiblingDiscountAmount: 40000}
+
+		assert.True(t, ApplySiblingDiscount(&billingStudent, &models.SiblingDiscountTier{DiscountType: repositories.DiscountTypeFixed, Amount: 60000}))
+		assert.Equal(t, int64(340000), billingStudent.Amount)
+		assert.Equal(t, int64(160000), billingStudent.DiscountAmount)
+		assert.Equal(t, int64(60000), billingStudent.SiblingDiscountAmount)
+	})
+
+	t.Run("Removed without a tier", func(t *testing.T) {
+		billingStudent := models.BillingStudent{Amount: 360000, DiscountAmount: 140000, SiblingDiscountAmount: 40000}
+
+		assert.True(t, ApplySiblingDiscount(&billingStudent, nil))
+		assert.Equal(t, int64(400000), billingStudent.Amount)
+		assert.Equal(t, int64(100000), billingStudent.DiscountAmount)
+		assert.Equal(t, int64(0), billingStudent.SiblingDiscountAmount)
+	})
+
+	t.Run("Fully covered installment is paid", func(t *testing.T) {
+		billingStudent := models.BillingStudent{Amount: 50000, PaymentStatus: repositories.BillingStudentStatusUnpaid}
+
+		assert.True(t, ApplySiblingDiscount(&billingStudent, &models.SiblingDiscountTier{DiscountType: repositories.DiscountTypeFixed, Amount: 60000}))
+		assert.Equal(t, int64(0), billingStudent.Amount)
+		assert.Equal(t, repositories.BillingStudentStatusPaid, billingStudent.PaymentStatus)
+	})
+
+	t.Run("Unpaid again when the tier is removed", func(t *testing.T) {
+		billingStudent := models.BillingStudent{DiscountAmount: 50000, SiblingDiscountAmount: 50000, PaymentStatus: repositories.BillingStudentStatusPaid}
+
+		assert.True(t, ApplySiblingDiscount(&billingStudent, nil))
+		assert.Equal(t, int64(50000), billingStudent.Amount)
+		assert.Equal(t, repositories.BillingStudentStatusUnpaid, billingStudent.PaymentStatus)
+	})
+
+	t.Run("Unchanged", func(t *testing.T) {
+		billingStudent := models.BillingStudent{Amount: 360000, DiscountAmount: 40000, SiblingDiscountAmount: 40000}
+
+		assert.False(t, ApplySiblingDiscount(&billingStudent, tier))
+		assert.Equal(t, int64(360000), billingStudent.Amount)
+	})
+}
+
+func TestRecalculateSiblingDiscount(t *testing.T) {
+	mockDiscountRepo := new(MockDiscountProgramRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := NewSiblingDiscountService(mockDiscountRepo, mockUserRepo)
+
+	birthDate := time.Date(2014, 8, 1, 0, 0, 0, 0, time.UTC)
+	laterBirthDate := time.Date(2016, 8, 1, 0, 0, 0, 0, time.UTC)
+	installments := []models.BillingStudent{
+		{StudentID: 1, Amount: 500000},
+		{StudentID: 2, Amount: 500000},
+		// The older sibling of student 3 withdrew, so the discount it had is removed
+		{StudentID: 3, Amount: 450000, DiscountAmount: 50000, SiblingDiscountAmount: 50000},
+	}
+	installments[0].ID = 11
+	installments[1].ID = 12
+	installments[2].ID = 13
+
+	mockUserRepo.On("GetUserByID", uint(4)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+	mockDiscountRepo.On("GetActiveSiblingDiscountTiers", uint(1)).Return([]models.SiblingDiscountTier{
+		{ChildOrder: 2, DiscountType: repositories.DiscountTypePercentage, Amount: 10},
+	}, nil)
+	mockDiscountRepo.On("GetHouseholdLinks", uint(1)).Return([]response.HouseholdLink{
+		{StudentID: 1, BirthDate: &birthDate, HouseholdKey: "u:10"},
+		{StudentID: 2, BirthDate: &laterBirthDate, HouseholdKey: "u:10"},
+		{StudentID: 3, BirthDate: &laterBirthDate, HouseholdKey: "u:20"},
+	}, nil)
+	mockDiscountRepo.On("GetOpenSiblingDiscountInstallments", uint(1)).Return(installments, nil)
+	mockDiscountRepo.On("UpdateInstallmentSiblingDiscount", mock.MatchedBy(func(billingStudent *models.BillingStudent) bool {
+		return billingStudent.ID == 12 && billingStudent.Amount == 450000 && billingStudent.SiblingDiscountAmount == 50000
+	})).Return(true, nil).Once()
+	mockDiscountRepo.On("UpdateInstallmentSiblingDiscount", mock.MatchedBy(func(billingStudent *models.BillingStudent) bool {
+		return billingStudent.ID == 13 && billingStudent.Amount == 500000 && billingStudent.DiscountAmount == 0
+	})).Return(true, nil).Once()
+
+	result, err := service.RecalculateSiblingDiscount(4)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.TotalChecked)
+	assert.Equal(t, 2, result.TotalUpdated)
+	assert.Equal(t, int64(50000), result.TotalDiscount)
+	mockDiscountRepo.AssertExpectations(t)
+}
+
+func TestCreateSiblingDiscountTier(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockDiscountRepo := new(MockDiscountProgramRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewSiblingDiscountService(mockDiscountRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(4)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+		mockDiscountRepo.On("SiblingDiscountTierExists", 2, uint(1), uint(0)).Return(false, nil)
+		mockDiscountRepo.On("CreateSiblingDiscountTier", mock.Anything).Return(nil)
+
+		tier, err := service.CreateSiblingDiscountTier(&request.SiblingDiscountTierRequest{
+			ChildOrder:   2,
+			DiscountType: repositories.DiscountTypePercentage,
+			Amount:       10,
+		}, 4)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(1), tier.SchoolID)
+		assert.True(t, tier.IsActive)
+	})
+
+	t.Run("First child", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		service := NewSiblingDiscountService(new(MockDiscountProgramRepository), mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(4)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+
+		_, err := service.CreateSiblingDiscountTier(&request.SiblingDiscountTierRequest{
+			ChildOrder:   1,
+			DiscountType: repositories.DiscountTypePercentage,
+			Amount:       10,
+		}, 4)
+
+		assert.EqualError(t, err, "childOrder must be 2 or more")
+	})
+
+	t.Run("Duplicate child order", func(t *testing.T) {
+		mockDiscountRepo := new(MockDiscountProgramRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewSiblingDiscountService(mockDiscountRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(4)).Return(&models.User{UserSchool: &models.UserSchool{SchoolID: 1}}, nil)
+		mockDiscountRepo.On("SiblingDiscountTierExists", 3, uint(1), uint(0)).Return(true, nil)
+
+		_, err := service.CreateSiblingDiscountTier(&request.SiblingDiscountTierRequest{
+			ChildOrder:   3,
+			DiscountType: repositories.DiscountTypeFixed,
+			Amount:       50000,
+		}, 4)
+
+		assert.EqualError(t, err, "a tier for child 3 already exists")
+	})
+}
@@ -34,12 +34,13 @@ var (
 )
 
 type StudentService struct {
-	studentRepository     repositories.StudentRepositoryInteface
-	userRepository        repositories.UserRepository
-	schoolClassRepository repositories.SchoolClassRepositoryInterface
-	schoolYearRepository  repositories.SchoolYearRepository
+	studentRepository      repositories.StudentRepositoryInteface
+	userRepository         repositories.UserRepository
+	schoolClassRepository  repositories.SchoolClassRepositoryInterface
+	schoolYearRepository   repositories.SchoolYearRepository
 	schoolGradeRepository  repositories.SchoolGradeRepositoryInterface
-	userService           UserService
+	userService            UserService
+	siblingDiscountService SiblingDiscountServiceInterface
 }
 
 func NewStudentService(
@@ -49,14 +50,16 @@ func NewStudentService(
 	schoolYearRepo repositories.SchoolYearRepository,
 	schoolGredeRepo repositories.SchoolGradeRepositoryInterface,
 	userService UserService,
+	siblingDiscountService SiblingDiscountServiceInterface,
 ) *StudentService {
 	return &StudentService{
-		studentRepository:     studentRepo,
-		userRepository:        userRepo,
-		schoolClassRepository: schoolClassRepo,
-		schoolYearRepository:  schoolYearRepo,
-		schoolGradeRepository: schoolGredeRepo,
-		userService:           userService,
+		studentRepository:      studentRepo,
+		userRepository:         userRepo,
+		schoolClassRepository:  schoolClassRepo,
+		schoolYearRepository:   schoolYearRepo,
+		schoolGradeRepository:  schoolGredeRepo,
+		userService:            userService,
+		siblingDiscountService: siblingDiscountService,
 	}
 }
 
@@ -67,13 +70,15 @@ func GetStudentService() StudentService {
 		schoolClassRepository := repositories.NewSchoolClassRepository()
 		schoolYearRepository := repositories.NewSchoolYearRepository(configs.DB)
 		userService := GetUserService()
+		siblingDiscountService := NewSiblingDiscountService(repositories.NewDiscountProgramRepository(configs.DB), userRepository)
 
 		studentServiceInstance = &StudentService{
-			studentRepository:     studentRepository,
-			userRepository:        userRepository,
-			schoolClassRepository: schoolClassRepository,
-			schoolYearRepository:  schoolYearRepository,
-			userService:           userService,
+			studentRepository:      studentRepository,
+			userRepository:         userRepository,
+			schoolClassRepository:  schoolClassRepository,
+			schoolYearRepository:   schoolYearRepository,
+			userService:            userService,
+			siblingDiscountService: siblingDiscountService,
 		}
 	})
 	return *studentServiceInstance
@@ -160,6 +165,15 @@ func (studentService *StudentService) GetStudentByID(id uint, userID int) (*resp
 		}
 	}
 
+	// Credit balance with the latest movements
+	var creditBalance int64
+	creditHistory := []models.StudentCredit{}
+	getCreditBalance, getCreditHistory, err := repositories.GetStudentCreditBalanceAndRecent(getStudent.ID, studentCreditRecentLimit)
+	if err == nil {
+		creditBalance = getCreditBalance
+		creditHistory = getCreditHistory
+	}
+
 	detailStudent := response.DetailStudentResponse{
 		ID:                 getStudent.ID,
 		Nisn:               getStudent.Nisn,
@@ -192,6 +206,8 @@ func (studentService *StudentService) GetStudentByID(id uint, userID int) (*resp
 		SchoolID:           schoolID,
 		SchoolName:         schoolName,
 		SchoolYearName:     schoolYearName,
+		CreditBalance:      creditBalance,
+		CreditHistory:      creditHistory,
 	}
 
 	return &detailStudent, nil
@@ -340,6 +356,8 @@ func (studentService *StudentService) CreateStudent(createStudentRequest request
 		return nil, err
 	}
 
+	studentService.recalculateSiblingDiscount(userID)
+
 	return dataStudent, err
 }
 
@@ -366,6 +384,8 @@ func (studentService *StudentService) UpdateStudent(id uint, updateStudentReques
 	if err != nil {
 		return nil, err
 	}
+	oldStatus := existingStudent.Status
+	oldBirthDate := existingStudent.BirthDate
 
 	// file, err := c.FormFile("image")
 	// if err == nil {
@@ -463,6 +483,11 @@ func (studentService *StudentService) UpdateStudent(id uint, updateStudentReques
 		return nil, err
 	}
 
+	// A status change enrolls or withdraws the student and the birth date decides the child order
+	if oldStatus != existingStudent.Status || oldBirthDate == nil || !oldBirthDate.Equal(*existingStudent.BirthDate) {
+		studentService.recalculateSiblingDiscount(userID)
+	}
+
 	return updatedStudent, nil
 }
 
@@ -509,9 +534,22 @@ func (studentService *StudentService) DeleteStudentService(id uint, userID int)
 		return nil, err
 	}
 
+	studentService.recalculateSiblingDiscount(userID)
+
 	return dataStudent, nil
 }
 
+// recalculateSiblingDiscount moves the household discounts after a student enrolls or
+// withdraws. The student change is already saved, so a failure is only logged.
+func (studentService *StudentService) recalculateSiblingDiscount(userID int) {
+	if studentService.siblingDiscountService == nil {
+		return
+	}
+	if _, err := studentService.siblingDiscountService.RecalculateSiblingDiscount(userID); err != nil {
+		log.Printf("failed to recalculate sibling discount: %v", err)
+	}
+}
+
 func (studentService *StudentService) CreateUserStudentService(id_user int, studentID int, auth int) (*models.UserStudent, error) {
 	userStudent := &models.UserStudent{
 		Master: models.Master{
@@ -768,6 +806,8 @@ func (studentService *StudentService) HandleExcelFileStudent(file *multipart.Fil
 		allErrors = append(allErrors, errors...)
 	}
 
+	studentService.recalculateSiblingDiscount(userID)
+
 	// Prepare response
 	message := "Success upload data"
 	if len(allErrors) > 0 {
@@ -1465,6 +1505,8 @@ func (studentService *StudentService) HandleCSVFileStudent(file *multipart.FileH
 		allErrors = append(allErrors, errors...)
 	}
 
+	studentService.recalculateSiblingDiscount(userID)
+
 	// Prepare response
 	message := "Success upload data"
 	if len(allErrors) > 0 {
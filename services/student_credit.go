@@ -0,0 +1,141 @@
+package services
+
+import (
+	"fmt"
+
+	request "schoolPayment/dtos/request"
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+	"schoolPayment/utilities"
+
+	"gorm.io/gorm"
+)
+
+// studentCreditRecentLimit is how many movements the student detail and billing history screens show.
+const studentCreditRecentLimit = 10
+
+type StudentCreditServiceInterface interface {
+	GetStudentCreditHistory(studentID uint, page int, limit int, userID int) (response.StudentCreditHistoryResponse, error)
+	AdjustStudentCredit(adjustmentRequest *request.StudentCreditAdjustmentRequest, userID int) (*models.StudentCredit, error)
+}
+
+type StudentCreditService struct {
+	studentCreditRepository repositories.StudentCreditRepository
+	studentRepository       repositories.StudentRepositoryInteface
+	userRepository          repositories.UserRepository
+}
+
+func NewStudentCreditService(studentCreditRepository repositories.StudentCreditRepository, studentRepository repositories.StudentRepositoryInteface, userRepository repositories.UserRepository) StudentCreditServiceInterface {
+	return &StudentCreditService{
+		studentCreditRepository: studentCreditRepository,
+		studentRepository:       studentRepository,
+		userRepository:          userRepository,
+	}
+}
+
+func (studentCreditService *StudentCreditService) GetStudentCreditHistory(studentID uint, page int, limit int, userID int) (response.StudentCreditHistoryResponse, error) {
+	resp := response.StudentCreditHistoryResponse{
+		Page:  page,
+		Limit: limit,
+		Data:  []models.StudentCredit{},
+	}
+
+	if err := studentCreditService.checkStudentAccess(studentID, userID); err != nil {
+		return resp, err
+	}
+
+	balance, err := studentCreditService.studentCreditRepository.GetStudentCreditBalance(studentID)
+	if err != nil {
+		return resp, err
+	}
+	resp.Balance = balance
+
+	credits, total, err := studentCreditService.studentCreditRepository.GetStudentCreditHistory(studentID, page, limit)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.TotalData = total
+	if limit != 0 {
+		resp.TotalPage = int((total + int64(limit) - 1) / int64(limit))
+	}
+	if len(credits) > 0 {
+		resp.Data = credits
+	}
+
+	return resp, nil
+}
+
+// AdjustStudentCredit books a manual correction of the balance, e.g. a deposit paid outside the app.
+func (studentCreditService *StudentCreditService) AdjustStudentCredit(adjustmentRequest *request.StudentCreditAdjustmentRequest, userID int) (*models.StudentCredit, error) {
+	if adjustmentRequest.Amount == 0 {
+		return nil, fmt.Errorf("amount cannot be zero")
+	}
+	if err := utilities.ValidateFieldNotEmpty(adjustmentRequest.Note, "note"); err != nil {
+		return nil, err
+	}
+
+	if err := studentCreditService.checkStudentAccess(adjustmentRequest.StudentID, userID); err != nil {
+		return nil, err
+	}
+
+	credit := &models.StudentCredit{
+		StudentID: adjustmentRequest.StudentID,
+		EntryType: repositories.StudentCreditTypeAdjustment,
+		Amount:    adjustmentRequest.Amount,
+		Note:      adjustmentRequest.Note,
+	}
+	credit.CreatedBy = userID
+
+	if err := studentCreditService.studentCreditRepository.CreateStudentCredit(credit); err != nil {
+		return nil, err
+	}
+	return credit, nil
+}
+
+// checkStudentAccess makes sure the student is visible to the user: a school user only sees
+// students of the school, a parent only their own children.
+func (studentCreditService *StudentCreditService) checkStudentAccess(studentID uint, userID int) error {
+	user, err := studentCreditService.userRepository.GetUserByID(uint(userID))
+	if err != nil {
+		return err
+	}
+
+	if _, err := studentCreditService.studentRepository.GetStudentByID(studentID, user); err != nil {
+		return fmt.Errorf("student not found")
+	}
+	return nil
+}
+
+// recordCashierStudentCredit books the credit a cashier payment spent and the overpayment it
+// left on the student's balance, in the transaction of the payment.
+func recordCashierStudentCredit(tx *gorm.DB, transaction *models.TransactionBilling, used int64, overpayment int64) error {
+	if used > 0 {
+		usage := models.StudentCredit{
+			StudentID:            transaction.StudentID,
+			EntryType:            repositories.StudentCreditTypeUsage,
+			Amount:               -used,
+			TransactionBillingID: &transaction.ID,
+		}
+		usage.CreatedBy = transaction.CreatedBy
+		if err := repositories.RecordStudentCredit(tx, &usage); err != nil {
+			return err
+		}
+	}
+
+	if overpayment > 0 {
+		credit := models.StudentCredit{
+			StudentID:            transaction.StudentID,
+			EntryType:            repositories.StudentCreditTypeOverpayment,
+			Amount:               overpayment,
+			TransactionBillingID: &transaction.ID,
+		}
+		credit.CreatedBy = transaction.CreatedBy
+		if err := repositories.RecordStudentCredit(tx, &credit); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
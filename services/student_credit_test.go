@@ -0,0 +1,126 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"schoolPayment/dtos/request"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockStudentCreditRepository struct {
+	mock.Mock
+}
+
+func (m *MockStudentCreditRepository) GetStudentCreditBalance(studentID uint) (int64, error) {
+	args := m.Called(studentID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockStudentCreditRepository) GetStudentCreditHistory(studentID uint, page int, limit int) ([]models.StudentCredit, int64, error) {
+	args := m.Called(studentID, page, limit)
+	return args.Get(0).([]models.StudentCredit), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockStudentCreditRepository) CreateStudentCredit(credit *models.StudentCredit) error {
+	args := m.Called(credit)
+	return args.Error(0)
+}
+
+func TestAdjustStudentCredit(t *testing.T) {
+	user := models.User{RoleID: 5}
+
+	t.Run("Success", func(t *testing.T) {
+		mockCreditRepo := new(MockStudentCreditRepository)
+		mockStudentRepo := new(MockStudentRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewStudentCreditService(mockCreditRepo, mockStudentRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(1)).Return(&user, nil)
+		mockStudentRepo.On("GetStudentByID", uint(7), user).Return(models.Student{}, nil)
+		mockCreditRepo.On("CreateStudentCredit", mock.MatchedBy(func(credit *models.StudentCredit) bool {
+			return credit.StudentID == 7 && credit.Amount == -50000 && credit.EntryType == repositories.StudentCreditTypeAdjustment
+		})).Return(nil)
+
+		credit, err := service.AdjustStudentCredit(&request.StudentCreditAdjustmentRequest{
+			StudentID: 7,
+			Amount:    -50000,
+			Note:      "Koreksi setoran",
+		}, 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, credit.CreatedBy)
+		mockCreditRepo.AssertExpectations(t)
+	})
+
+	t.Run("Zero amount", func(t *testing.T) {
+		mockCreditRepo := new(MockStudentCreditRepository)
+		service := NewStudentCreditService(mockCreditRepo, new(MockStudentRepository), new(MockUserRepository))
+
+		_, err := service.AdjustStudentCredit(&request.StudentCreditAdjustmentRequest{StudentID: 7, Note: "Koreksi"}, 1)
+
+		assert.EqualError(t, err, "amount cannot be zero")
+		mockCreditRepo.AssertNotCalled(t, "CreateStudentCredit", mock.Anything)
+	})
+
+	t.Run("Balance would go negative", func(t *testing.T) {
+		mockCreditRepo := new(MockStudentCreditRepository)
+		mockStudentRepo := new(MockStudentRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewStudentCreditService(mockCreditRepo, mockStudentRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(1)).Return(&user, nil)
+		mockStudentRepo.On("GetStudentByID", uint(7), user).Return(models.Student{}, nil)
+		mockCreditRepo.On("CreateStudentCredit", mock.Anything).Return(repositories.ErrInsufficientStudentCredit)
+
+		_, err := service.AdjustStudentCredit(&request.StudentCreditAdjustmentRequest{StudentID: 7, Amount: -1, Note: "Koreksi"}, 1)
+
+		assert.Equal(t, repositories.ErrInsufficientStudentCredit, err)
+	})
+}
+
+func TestGetStudentCreditHistory(t *testing.T) {
+	user := models.User{RoleID: 2}
+
+	t.Run("Success", func(t *testing.T) {
+		mockCreditRepo := new(MockStudentCreditRepository)
+		mockStudentRepo := new(MockStudentRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewStudentCreditService(mockCreditRepo, mockStudentRepo, mockUserRepo)
+
+		credits := []models.StudentCredit{
+			{StudentID: 7, EntryType: repositories.StudentCreditTypeUsage, Amount: -20000},
+			{StudentID: 7, EntryType: repositories.StudentCreditTypeOverpayment, Amount: 50000},
+		}
+		mockUserRepo.On("GetUserByID", uint(1)).Return(&user, nil)
+		mockStudentRepo.On("GetStudentByID", uint(7), user).Return(models.Student{}, nil)
+		mockCreditRepo.On("GetStudentCreditBalance", uint(7)).Return(int64(30000), nil)
+		mockCreditRepo.On("GetStudentCreditHistory", uint(7), 1, 10).Return(credits, int64(2), nil)
+
+		history, err := service.GetStudentCreditHistory(7, 1, 10, 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(30000), history.Balance)
+		assert.Equal(t, 1, history.TotalPage)
+		assert.Len(t, history.Data, 2)
+	})
+
+	t.Run("Student of another parent", func(t *testing.T) {
+		mockCreditRepo := new(MockStudentCreditRepository)
+		mockStudentRepo := new(MockStudentRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewStudentCreditService(mockCreditRepo, mockStudentRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(1)).Return(&user, nil)
+		mockStudentRepo.On("GetStudentByID", uint(8), user).Return(models.Student{}, errors.New("record not found"))
+
+		_, err := service.GetStudentCreditHistory(8, 1, 10, 1)
+
+		assert.EqualError(t, err, "student not found")
+		mockCreditRepo.AssertNotCalled(t, "GetStudentCreditBalance", mock.Anything)
+	})
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	request "schoolPayment/dtos/request"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+	"schoolPayment/utilities"
+
+	"gorm.io/gorm"
+)
+
+type StudentVirtualAccountServiceInterface interface {
+	GetStudentVirtualAccounts(studentID uint, userID int) ([]models.StudentVirtualAccount, error)
+	IssueStudentVirtualAccount(issueRequest *request.IssueStudentVirtualAccountRequest, userID int) (*models.StudentVirtualAccount, error)
+	RevokeStudentVirtualAccount(id uint, userID int) error
+}
+
+type StudentVirtualAccountService struct {
+	studentVirtualAccountRepository repositories.StudentVirtualAccountRepository
+	studentRepository               repositories.StudentRepositoryInteface
+	userRepository                  repositories.UserRepository
+	schoolPaymentConfigRepository   repositories.SchoolPaymentConfigRepository
+	paymentMethodRepository         repositories.PaymentMethodRepository
+}
+
+func NewStudentVirtualAccountService(studentVirtualAccountRepository repositories.StudentVirtualAccountRepository, studentRepository repositories.StudentRepositoryInteface, userRepository repositories.UserRepository, schoolPaymentConfigRepository repositories.SchoolPaymentConfigRepository, paymentMethodRepository repositories.PaymentMethodRepository) StudentVirtualAccountServiceInterface {
+	return &StudentVirtualAccountService{
+		studentVirtualAccountRepository: studentVirtualAccountRepository,
+		studentRepository:               studentRepository,
+		userRepository:                  userRepository,
+		schoolPaymentConfigRepository:   schoolPaymentConfigRepository,
+		paymentMethodRepository:         paymentMethodRepository,
+	}
+}
+
+func (studentVirtualAccountService *StudentVirtualAccountService) GetStudentVirtualAccounts(studentID uint, userID int) ([]models.StudentVirtualAccount, error) {
+	user, err := studentVirtualAccountService.userRepository.GetUserByID(uint(userID))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := studentVirtualAccountService.studentRepository.GetStudentByID(studentID, user); err != nil {
+		return nil, fmt.Errorf("student not found")
+	}
+
+	virtualAccounts, err := studentVirtualAccountService.studentVirtualAccountRepository.GetStudentVirtualAccounts(studentID)
+	if err != nil {
+		return nil, err
+	}
+	if virtualAccounts == nil {
+		virtualAccounts = []models.StudentVirtualAccount{}
+	}
+	return virtualAccounts, nil
+}
+
+// IssueStudentVirtualAccount gives the student a fixed number at the bank of the payment method,
+// made of the school's VA prefix and the student's NIS.
+func (studentVirtualAccountService *StudentVirtualAccountService) IssueStudentVirtualAccount(issueRequest *request.IssueStudentVirtualAccountRequest, userID int) (*models.StudentVirtualAccount, error) {
+	user, err := studentVirtualAccountService.userRepository.GetUserByID(uint(userID))
+	if err != nil {
+		return nil, err
+	}
+	if user.UserSchool == nil {
+		return nil, fmt.Errorf("user not associated with any school")
+	}
+	schoolID := user.UserSchool.SchoolID
+
+	student, err := studentVirtualAccountService.studentRepository.GetStudentByID(issueRequest.StudentID, user)
+	if err != nil {
+		return nil, fmt.Errorf("student not found")
+	}
+
+	config, err := studentVirtualAccountService.schoolPaymentConfigRepository.GetSchoolPaymentConfigBySchoolID(schoolID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if config == nil || config.VAPrefix == "" {
+		return nil, fmt.Errorf("set the VA prefix of the school before issuing virtual accounts")
+	}
+
+	paymentMethod, err := studentVirtualAccountService.paymentMethodRepository.GetPaymentMethodByID(int(issueRequest.PaymentMethodID))
+	if err != nil {
+		return nil, fmt.Errorf("payment method not found")
+	}
+	bank, length, err := utilities.StaticVirtualAccountBank(paymentMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	number, err := staticVirtualAccountNumber(config.VAPrefix, student.Nis, length)
+	if err != nil {
+		return nil, err
+	}
+
+	virtualAccount := &models.StudentVirtualAccount{
+		SchoolID:              schoolID,
+		StudentID:             student.ID,
+		MasterPaymentMethodID: paymentMethod.ID,
+		Bank:                  bank,
+		VANumber:              number,
+	}
+	virtualAccount.CreatedBy = userID
+	virtualAccount.UpdatedBy = userID
+
+	if err := studentVirtualAccountService.studentVirtualAccountRepository.CreateStudentVirtualAccount(virtualAccount); err != nil {
+		return nil, err
+	}
+	return virtualAccount, nil
+}
+
+// RevokeStudentVirtualAccount stops the number from being used; later checkouts get a one-off number again.
+func (studentVirtualAccountService *StudentVirtualAccountService) RevokeStudentVirtualAccount(id uint, userID int) error {
+	user, err := studentVirtualAccountService.userRepository.GetUserByID(uint(userID))
+	if err != nil {
+		return err
+	}
+	if user.UserSchool == nil {
+		return fmt.Errorf("user not associated with any school")
+	}
+
+	virtualAccount, err := studentVirtualAccountService.studentVirtualAccountRepository.GetStudentVirtualAccountByID(id, user.UserSchool.SchoolID)
+	if err != nil {
+		return fmt.Errorf("virtual account not found")
+	}
+
+	now := time.Now()
+	virtualAccount.DeletedAt = &now
+	virtualAccount.DeletedBy = &userID
+	return studentVirtualAccountService.studentVirtualAccountRepository.UpdateStudentVirtualAccount(virtualAccount)
+}
+
+// staticVirtualAccountNumber joins the prefix and the digits of the NIS, padded with zeros in
+// between to the length the bank expects.
+func staticVirtualAccountNumber(prefix string, nis string, length int) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, nis)
+	if digits == "" {
+		return "", fmt.Errorf("student has no NIS to derive a virtual account number from")
+	}
+
+	padding := length - len(prefix) - len(digits)
+	if padding < 0 {
+		return "", fmt.Errorf("NIS %s does not fit in a %d digit virtual account number with prefix %s", nis, length, prefix)
+	}
+	return prefix + strings.Repeat("0", padding) + digits, nil
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"schoolPayment/dtos/request"
+	"schoolPayment/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockStudentVirtualAccountRepository struct {
+	mock.Mock
+}
+
+func (m *MockStudentVirtualAccountRepository) GetStudentVirtualAccounts(studentID uint) ([]models.StudentVirtualAccount, error) {
+	args := m.Called(studentID)
+	return args.Get(0).([]models.StudentVirtualAccount), args.Error(1)
+}
+
+func (m *MockStudentVirtualAccountRepository) GetStudentVirtualAccountByID(id uint, schoolID uint) (*models.StudentVirtualAccount, error) {
+	args := m.Called(id, schoolID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.StudentVirtualAccount), args.Error(1)
+}
+
+func (m *MockStudentVirtualAccountRepository) CreateStudentVirtualAccount(virtualAccount *models.StudentVirtualAccount) error {
+	args := m.Called(virtualAccount)
+	return args.Error(0)
+}
+
+func (m *MockStudentVirtualAccountRepository) UpdateStudentVirtualAccount(virtualAccount *models.StudentVirtualAccount) error {
+	args := m.Called(virtualAccount)
+	return args.Error(0)
+}
+
+type MockSchoolPaymentConfigRepository struct {
+	mock.Mock
+}
+
+func (m *MockSchoolPaymentConfigRepository) GetSchoolPaymentConfigBySchoolID(schoolID uint) (*models.SchoolPaymentConfig, error) {
+	args := m.Called(schoolID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SchoolPaymentConfig), args.Error(1)
+}
+
+func (m *MockSchoolPaymentConfigRepository) SaveSchoolPaymentConfig(config *models.SchoolPaymentConfig) (*models.SchoolPaymentConfig, error) {
+	args := m.Called(config)
+	return args.Get(0).(*models.SchoolPaymentConfig), args.Error(1)
+}
+
+func (m *MockSchoolPaymentConfigRepository) GetSchoolPaymentMethodFees(schoolID uint) ([]models.SchoolPaymentMethodFee, error) {
+	args := m.Called(schoolID)
+	return args.Get(0).([]models.SchoolPaymentMethodFee), args.Error(1)
+}
+
+func (m *MockSchoolPaymentConfigRepository) GetSchoolPaymentMethodFee(schoolID uint, paymentMethodID uint) (*models.SchoolPaymentMethodFee, error) {
+	args := m.Called(schoolID, paymentMethodID)
+	return args.Get(0).(*models.SchoolPaymentMethodFee), args.Error(1)
+}
+
+func (m *MockSchoolPaymentConfigRepository) SaveSchoolPaymentMethodFee(fee *models.SchoolPaymentMethodFee) (*models.SchoolPaymentMethodFee, error) {
+	args := m.Called(fee)
+	return args.Get(0).(*models.SchoolPaymentMethodFee), args.Error(1)
+}
+
+type MockPaymentMethodRepository struct {
+	mock.Mock
+}
+
+func (m *MockPaymentMethodRepository) GetAllPaymentMethod(search string) ([]models.PaymentMethod, error) {
+	args := m.Called(search)
+	return args.Get(0).([]models.PaymentMethod), args.Error(1)
+}
+
+func (m *MockPaymentMethodRepository) GetPaymentMethodByID(id int) (*models.PaymentMethod, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PaymentMethod), args.Error(1)
+}
+
+func (m *MockPaymentMethodRepository) CreatePaymentMethod(paymentMethod *models.PaymentMethod) (*models.PaymentMethod, error) {
+	args := m.Called(paymentMethod)
+	return args.Get(0).(*models.PaymentMethod), args.Error(1)
+}
+
+func (m *MockPaymentMethodRepository) UpdatePaymentMethod(paymentMethod *models.PaymentMethod) (*models.PaymentMethod, error) {
+	args := m.Called(paymentMethod)
+	return args.Get(0).(*models.PaymentMethod), args.Error(1)
+}
+
+func TestIssueStudentVirtualAccount(t *testing.T) {
+	user := models.User{RoleID: 2, UserSchool: &models.UserSchool{SchoolID: 3}}
+	student := models.Student{Nis: "2024.017"}
+	student.ID = 7
+	bca := &models.PaymentMethod{ID: 4, PaymentMethod: "VA", BankCode: "014", BankName: "BCA"}
+
+	newService := func() (*MockStudentVirtualAccountRepository, *MockSchoolPaymentConfigRepository, *MockPaymentMethodRepository, StudentVirtualAccountServiceInterface) {
+		mockVirtualAccountRepo := new(MockStudentVirtualAccountRepository)
+		mockStudentRepo := new(MockStudentRepository)
+		mockUserRepo := new(MockUserRepository)
+		mockConfigRepo := new(MockSchoolPaymentConfigRepository)
+		mockPaymentMethodRepo := new(MockPaymentMethodRepository)
+
+		mockUserRepo.On("GetUserByID", uint(1)).Return(&user, nil)
+		mockStudentRepo.On("GetStudentByID", uint(7), user).Return(student, nil)
+
+		service := NewStudentVirtualAccountService(mockVirtualAccountRepo, mockStudentRepo, mockUserRepo, mockConfigRepo, mockPaymentMethodRepo)
+		return mockVirtualAccountRepo, mockConfigRepo, mockPaymentMethodRepo, service
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockVirtualAccountRepo, mockConfigRepo, mockPaymentMethodRepo, service := newService()
+		mockConfigRepo.On("GetSchoolPaymentConfigBySchoolID", uint(3)).Return(&models.SchoolPaymentConfig{VAPrefix: "88"}, nil)
+		mockPaymentMethodRepo.On("GetPaymentMethodByID", 4).Return(bca, nil)
+		mockVirtualAccountRepo.On("CreateStudentVirtualAccount", mock.Anything).Return(nil)
+
+		virtualAccount, err := service.IssueStudentVirtualAccount(&request.IssueStudentVirtualAccountRequest{StudentID: 7, PaymentMethodID: 4}, 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "bca", virtualAccount.Bank)
+		assert.Equal(t, "88002024017", virtualAccount.VANumber)
+		assert.Equal(t, uint(3), virtualAccount.SchoolID)
+		assert.Equal(t, uint(4), virtualAccount.MasterPaymentMethodID)
+		mockVirtualAccountRepo.AssertExpectations(t)
+	})
+
+	t.Run("No VA prefix", func(t *testing.T) {
+		mockVirtualAccountRepo, mockConfigRepo, _, service := newService()
+		mockConfigRepo.On("GetSchoolPaymentConfigBySchoolID", uint(3)).Return(&models.SchoolPaymentConfig{}, nil)
+
+		_, err := service.IssueStudentVirtualAccount(&request.IssueStudentVirtualAccountRequest{StudentID: 7, PaymentMethodID: 4}, 1)
+
+		assert.EqualError(t, err, "set the VA prefix of the school before issuing virtual accounts")
+		mockVirtualAccountRepo.AssertNotCalled(t, "CreateStudentVirtualAccount", mock.Anything)
+	})
+
+	t.Run("Not a virtual account", func(t *testing.T) {
+		mockVirtualAccountRepo, mockConfigRepo, mockPaymentMethodRepo, service := newService()
+		mockConfigRepo.On("GetSchoolPaymentConfigBySchoolID", uint(3)).Return(&models.SchoolPaymentConfig{VAPrefix: "88"}, nil)
+		mockPaymentMethodRepo.On("GetPaymentMethodByID", 5).Return(&models.PaymentMethod{ID: 5, PaymentMethod: "QR"}, nil)
+
+		_, err := service.IssueStudentVirtualAccount(&request.IssueStudentVirtualAccountRequest{StudentID: 7, PaymentMethodID: 5}, 1)
+
+		assert.EqualError(t, err, "fixed virtual accounts need a virtual account payment method")
+		mockVirtualAccountRepo.AssertNotCalled(t, "CreateStudentVirtualAccount", mock.Anything)
+	})
+}
+
+func TestRevokeStudentVirtualAccount(t *testing.T) {
+	user := models.User{RoleID: 2, UserSchool: &models.UserSchool{SchoolID: 3}}
+
+	t.Run("Success", func(t *testing.T) {
+		mockVirtualAccountRepo := new(MockStudentVirtualAccountRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewStudentVirtualAccountService(mockVirtualAccountRepo, nil, mockUserRepo, nil, nil)
+
+		mockUserRepo.On("GetUserByID", uint(1)).Return(&user, nil)
+		mockVirtualAccountRepo.On("GetStudentVirtualAccountByID", uint(9), uint(3)).Return(&models.StudentVirtualAccount{VANumber: "88002024017"}, nil)
+		mockVirtualAccountRepo.On("UpdateStudentVirtualAccount", mock.MatchedBy(func(virtualAccount *models.StudentVirtualAccount) bool {
+			return virtualAccount.DeletedAt != nil && *virtualAccount.DeletedBy == 1
+		})).Return(nil)
+
+		err := service.RevokeStudentVirtualAccount(9, 1)
+
+		assert.NoError(t, err)
+		mockVirtualAccountRepo.AssertExpectations(t)
+	})
+
+	t.Run("Other school", func(t *testing.T) {
+		mockVirtualAccountRepo := new(MockStudentVirtualAccountRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewStudentVirtualAccountService(mockVirtualAccountRepo, nil, mockUserRepo, nil, nil)
+
+		mockUserRepo.On("GetUserByID", uint(1)).Return(&user, nil)
+		mockVirtualAccountRepo.On("GetStudentVirtualAccountByID", uint(9), uint(3)).Return(nil, errors.New("record not found"))
+
+		err := service.RevokeStudentVirtualAccount(9, 1)
+
+		assert.EqualError(t, err, "virtual account not found")
+		mockVirtualAccountRepo.AssertNotCalled(t, "UpdateStudentVirtualAccount", mock.Anything)
+	})
+}
+
+func TestStaticVirtualAccountNumber(t *testing.T) {
+	number, err := staticVirtualAccountNumber("123", "45", 8)
+	assert.NoError(t, err)
+	assert.Equal(t, "12300045", number)
+
+	_, err = staticVirtualAccountNumber("123", "", 8)
+	assert.Error(t, err)
+
+	_, err = staticVirtualAccountNumber("12345", "67890", 8)
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"math/big"
@@ -23,6 +24,7 @@ import (
 	"github.com/midtrans/midtrans-go/coreapi"
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
+	"gorm.io/gorm"
 )
 
 type TransactionService struct {
@@ -33,7 +35,7 @@ type TransactionService struct {
 	billingStudentRepositories repositories.BillingStudentRepository
 	billingRepositories        repositories.BillingRepositoryInterface
 	studentRepository          repositories.StudentRepositoryInteface
-	
+	webhookEventRepository     repositories.WebhookEventRepository
 }
 
 func NewTransactionService(
@@ -44,6 +46,7 @@ func NewTransactionService(
 	billingStudentRepositories repositories.BillingStudentRepository,
 	billingRepositories repositories.BillingRepositoryInterface,
 	studentRepository repositories.StudentRepositoryInteface,
+	webhookEventRepository repositories.WebhookEventRepository,
 ) TransactionService {
 	return TransactionService{
 		transactionRepository:      transactionRepository,
@@ -53,6 +56,7 @@ func NewTransactionService(
 		billingStudentRepositories: billingStudentRepositories,
 		billingRepositories:        billingRepositories,
 		studentRepository:          studentRepository,
+		webhookEventRepository:     webhookEventRepository,
 	}
 }
 
@@ -87,61 +91,265 @@ func (transactionService *TransactionService) GetAllTransaction(page int, limit
 	return resp, nil
 }
 
-func (transactionService *TransactionService) MidtransPayment(billingService *BillingService, studendID, paymentMethodId, userId int, billingStudentIds []string) (*response.MidtransResponse, error) {
+func (transactionService *TransactionService) MidtransPayment(billingService *BillingService, studendID, paymentMethodId, userId int, billingStudentIds []string, useCredit int) (*response.MidtransResponse, error) {
 	var rspPayment response.MidtransResponse
 
-	listBillingId, err := transactionService.billingStudentRepositories.GetListBillingId(billingStudentIds)
+	orderID, resp, err := transactionService.sendBillingPayment(studendID, paymentMethodId, userId, billingStudentIds, useCredit, utilities.SendRequestPayment)
 	if err != nil {
 		return nil, err
 	}
+	rspPayment.OrderID = orderID
+	rspPayment.Token = &resp.Token
+	rspPayment.RedirectURL = &resp.RedirectURL
 
-	totalAmount, err := transactionService.billingStudentRepositories.GetTotalAmountByBillingStudentIds(billingStudentIds)
+	return &rspPayment, nil
+}
+
+// MidtransDirectPayment charges the installments without the Snap page and returns the virtual
+// account number, QRIS string or deeplink for the app to show. The payment is settled by the
+// same webhook as a Snap payment.
+func (transactionService *TransactionService) MidtransDirectPayment(studendID, paymentMethodId, userId int, billingStudentIds []string, useCredit int) (*response.DirectPaymentResponse, error) {
+	orderID, resp, err := transactionService.sendBillingPayment(studendID, paymentMethodId, userId, billingStudentIds, useCredit, utilities.SendDirectPayment)
 	if err != nil {
 		return nil, err
 	}
+	if resp.Instruction == nil {
+		return nil, fmt.Errorf("payment gateway did not return a payment instruction")
+	}
 
-	listAccountNumber, err := repositories.GetListAccountNumber(billingStudentIds)
+	return &response.DirectPaymentResponse{
+		OrderID:     orderID,
+		PaymentType: resp.Instruction.PaymentType,
+		GrossAmount: resp.Instruction.GrossAmount,
+		Bank:        resp.Instruction.Bank,
+		VaNumber:    resp.Instruction.VANumber,
+		BillerCode:  resp.Instruction.BillerCode,
+		BillKey:     resp.Instruction.BillKey,
+		QrString:    resp.Instruction.QRString,
+		QrImageURL:  resp.Instruction.QRImageURL,
+		Deeplink:    resp.Instruction.Deeplink,
+		ExpiryTime:  resp.Instruction.ExpiryTime,
+	}, nil
+}
+
+// MidtransGroupPayment pays installments of several children of the logged-in parent with one
+// gateway order and one admin fee. Every installment must belong to a student linked to the user.
+func (transactionService *TransactionService) MidtransGroupPayment(paymentMethodId, userId int, billingStudentIds []string) (*response.MidtransResponse, error) {
+	if len(billingStudentIds) == 0 {
+		return nil, fmt.Errorf("billingStudentIds is required")
+	}
+
+	students, err := transactionService.studentRepository.GetStudentByUserIdRepository(uint(userId))
 	if err != nil {
 		return nil, err
 	}
+	linkedStudents := make(map[uint]bool, len(students))
+	for _, student := range students {
+		linkedStudents[student.ID] = true
+	}
 
-	user, err := transactionService.userRepositories.GetUserByID(uint(userId))
+	parts, err := transactionService.groupBillingStudentsByStudent(billingStudentIds, linkedStudents)
 	if err != nil {
 		return nil, err
 	}
 
-	invoiceNumber, err := utilities.GenerateInvoiceNumber(user.UserSchool.SchoolID)
+	paymentMethod, err := repositories.GetPaymentMethodByID(paymentMethodId)
 	if err != nil {
-		fmt.Errorf(constants.MessageErrorGenerateInvoiceNumber, err)
+		return nil, err
 	}
 
-	paymentMethod, err := repositories.GetPaymentMethodByID(paymentMethodId)
+	orderID := fmt.Sprintf("SCPAY-%s-%d", paymentMethod.PaymentMethod, time.Now().Unix())
+
+	resp, err := utilities.SendGroupRequestPayment(orderID, parts, paymentMethodId, paymentMethod.BankName, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response.MidtransResponse{
+		OrderID:     orderID,
+		Token:       &resp.Token,
+		RedirectURL: &resp.RedirectURL,
+	}, nil
+}
+
+// groupBillingStudentsByStudent splits the installments of a combined checkout into what each
+// child pays, in the order the children first appear.
+func (transactionService *TransactionService) groupBillingStudentsByStudent(billingStudentIds []string, linkedStudents map[uint]bool) ([]repositories.GroupTransactionBillingPart, error) {
+	ids := make([]int, 0, len(billingStudentIds))
+	for _, billingStudentID := range billingStudentIds {
+		id, err := strconv.Atoi(billingStudentID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid billing student id: %s", billingStudentID)
+		}
+		ids = append(ids, id)
+	}
+
+	billingStudents, err := transactionService.billingStudentRepositories.GetBillingStudentsByIDs(ids)
 	if err != nil {
 		return nil, err
 	}
+	studentOf := make(map[string]uint, len(billingStudents))
+	for _, billingStudent := range billingStudents {
+		studentOf[strconv.Itoa(int(billingStudent.ID))] = billingStudent.StudentID
+	}
+
+	var studentIDs []uint
+	idsByStudent := map[uint][]string{}
+	for _, billingStudentID := range billingStudentIds {
+		studentID, ok := studentOf[billingStudentID]
+		if !ok || !linkedStudents[studentID] {
+			return nil, fmt.Errorf("billing student %s not found", billingStudentID)
+		}
+		if _, seen := idsByStudent[studentID]; !seen {
+			studentIDs = append(studentIDs, studentID)
+		}
+		idsByStudent[studentID] = append(idsByStudent[studentID], billingStudentID)
+	}
+
+	parts := make([]repositories.GroupTransactionBillingPart, 0, len(studentIDs))
+	for _, studentID := range studentIDs {
+		studentBillingStudentIds := idsByStudent[studentID]
+
+		listBillingId, err := transactionService.billingStudentRepositories.GetListBillingId(studentBillingStudentIds)
+		if err != nil {
+			return nil, err
+		}
+		billingAmount, err := transactionService.billingStudentRepositories.GetTotalAmountByBillingStudentIds(studentBillingStudentIds)
+		if err != nil {
+			return nil, err
+		}
+		listAccountNumber, err := repositories.GetListAccountNumber(studentBillingStudentIds)
+		if err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, repositories.GroupTransactionBillingPart{
+			StudentID:         studentID,
+			BillingAmount:     billingAmount,
+			BillingStudentIds: studentBillingStudentIds,
+			ListAccountNumber: listAccountNumber,
+			ListBillingId:     listBillingId,
+		})
+	}
+	return parts, nil
+}
+
+// sendBillingPayment collects what the gateway charge of the installments needs and sends it
+// with send, either to the payment page or as a direct charge.
+func (transactionService *TransactionService) sendBillingPayment(
+	studendID, paymentMethodId, userId int,
+	billingStudentIds []string,
+	useCredit int,
+	send func(orderID string, studendID, billingAmount, paymentMethodId int, billingStudentIds []string, listAccountNumber []string, listBillingId []int, bankName string, userId int, creditAmount int64) (*utilities.PaymentGatewayChargeResult, error),
+) (string, *utilities.PaymentGatewayChargeResult, error) {
+	listBillingId, err := transactionService.billingStudentRepositories.GetListBillingId(billingStudentIds)
+	if err != nil {
+		return "", nil, err
+	}
+
+	totalAmount, err := transactionService.billingStudentRepositories.GetTotalAmountByBillingStudentIds(billingStudentIds)
+	if err != nil {
+		return "", nil, err
+	}
+
+	listAccountNumber, err := repositories.GetListAccountNumber(billingStudentIds)
+	if err != nil {
+		return "", nil, err
+	}
+
+	paymentMethod, err := repositories.GetPaymentMethodByID(paymentMethodId)
+	if err != nil {
+		return "", nil, err
+	}
 
 	orderID := fmt.Sprintf("SCPAY-%s-%d", paymentMethod.PaymentMethod, time.Now().Unix())
 
-	resp, err := utilities.SendRequestPayment(
+	resp, err := send(
 		orderID,
 		studendID,
 		totalAmount,
 		paymentMethodId,
 		billingStudentIds,
-		invoiceNumber,
 		listAccountNumber,
 		listBillingId,
 		paymentMethod.BankName,
 		userId,
+		int64(useCredit),
 	)
+	if err != nil {
+		return "", nil, err
+	}
+	return orderID, resp, nil
+}
+
+// PreviewAdminFee works out the admin fee and the gross amount the gateway will charge for the
+// installments with each payment method, or only with the one given. Methods the fee cannot be
+// calculated for are left out of the list.
+func (transactionService *TransactionService) PreviewAdminFee(studentID, paymentMethodID int, billingStudentIds []string, useCredit int) ([]response.AdminFeePreviewResponse, error) {
+	if len(billingStudentIds) == 0 {
+		return nil, fmt.Errorf("billingStudentIds is required")
+	}
+
+	billingAmount, err := transactionService.billingStudentRepositories.GetTotalAmountByBillingStudentIds(billingStudentIds)
 	if err != nil {
 		return nil, err
 	}
-	rspPayment.OrderID = orderID
-	rspPayment.Token = &resp.Token
-	rspPayment.RedirectURL = &resp.RedirectURL
 
-	return &rspPayment, nil
+	creditAmount := int64(useCredit)
+	if creditAmount < 0 {
+		return nil, fmt.Errorf("credit amount cannot be negative")
+	}
+	if creditAmount >= int64(billingAmount) {
+		return nil, fmt.Errorf("credit covers the whole amount, please pay it at the cashier")
+	}
+
+	school, err := repositories.GetSchoolByStudentId(uint(studentID))
+	if err != nil {
+		return nil, err
+	}
+
+	var paymentMethods []models.PaymentMethod
+	if paymentMethodID != 0 {
+		paymentMethod, err := repositories.GetPaymentMethodByID(paymentMethodID)
+		if err != nil {
+			return nil, err
+		}
+		paymentMethods = append(paymentMethods, *paymentMethod)
+	} else {
+		paymentMethods, err = repositories.GetPaymentMethods()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	chargeAmount := int64(billingAmount) - creditAmount
+	previews := []response.AdminFeePreviewResponse{}
+	for i := range paymentMethods {
+		paymentMethod := &paymentMethods[i]
+		adminFee, err := utilities.CalculateAdminFeeSplit(school.ID, chargeAmount, paymentMethod)
+		if err != nil {
+			if paymentMethodID != 0 {
+				return nil, err
+			}
+			continue
+		}
+
+		previews = append(previews, response.AdminFeePreviewResponse{
+			PaymentMethodID: paymentMethod.ID,
+			PaymentMethod:   paymentMethod.PaymentMethod,
+			BankName:        paymentMethod.BankName,
+			MethodLogo:      paymentMethod.MethodLogo,
+			BillingAmount:   int64(billingAmount),
+			CreditAmount:    creditAmount,
+			AdminFeeBearer:  adminFee.Bearer,
+			AdminFee:        adminFee.AdminFee,
+			ParentAdminFee:  adminFee.ParentAdminFee,
+			SchoolAdminFee:  adminFee.SchoolAdminFee,
+			GrossAmount:     chargeAmount + adminFee.ParentAdminFee,
+		})
+	}
+
+	return previews, nil
 }
 
 func (transactionService *TransactionService) PaymentDonation(studentID, billingID, amount, paymentMethodID, userID int) (*response.MidtransResponse, error) {
@@ -186,11 +394,6 @@ func (transactionService *TransactionService) PaymentDonation(studentID, billing
 		return nil, err
 	}
 
-	invoiceNumber, err := utilities.GenerateInvoiceNumber(user.UserSchool.SchoolID)
-	if err != nil {
-		fmt.Errorf(constants.MessageErrorGenerateInvoiceNumber, err)
-	}
-
 	paymentMethod, err := repositories.GetPaymentMethodByID(paymentMethodID)
 	if err != nil {
 		return nil, err
@@ -209,11 +412,11 @@ func (transactionService *TransactionService) PaymentDonation(studentID, billing
 		amount,
 		paymentMethodID,
 		[]string{strconv.Itoa(int(rspBillingStudent.ID))},
-		invoiceNumber,
 		listAccountNumber,
 		[]int{int(billing.ID)},
 		paymentMethod.BankName,
 		userID,
+		0,
 	)
 	if err != nil {
 		return nil, err
@@ -244,29 +447,43 @@ func (transactionService *TransactionService) CreateTransactionService(request r
 		return models.TransactionBilling{}, fmt.Errorf("user not associated with any school")
 	}
 
-	invoiceNumber, err := utilities.GenerateInvoiceNumber(user.UserSchool.SchoolID)
-	if err != nil {
-		return models.TransactionBilling{}, fmt.Errorf(constants.MessageErrorGenerateInvoiceNumber, err)
-	}
-
 	epoch := time.Now().Unix()
 	referenceNumber := fmt.Sprintf("%s%d", "000000", epoch)
 	expiryTime := time.Now().Format("2006-01-02T15:04:05")
 
 	tx := database.DB.Begin()
 
+	// Every kasir payment belongs to the drawer of the user taking it; the shift stays locked
+	// so it cannot be closed before this payment is counted
+	cashierShift, err := repositories.LockOpenCashierShift(tx, user.ID)
+	if err != nil {
+		tx.Rollback()
+		return models.TransactionBilling{}, err
+	}
+
+	// Taken inside the transaction so the sequence row stays locked until the billing is
+	// committed or rolled back
+	invoiceNumber, err := repositories.NextInvoiceNumber(tx, user.UserSchool.SchoolID, uint(request.StudentId))
+	if err != nil {
+		tx.Rollback()
+		return models.TransactionBilling{}, fmt.Errorf(constants.MessageErrorGenerateInvoiceNumber, err)
+	}
+
 	listBillingId, err := transactionService.billingStudentRepositories.GetListBillingId(request.BillingStudentIds)
 	if err != nil {
+		tx.Rollback()
 		return models.TransactionBilling{}, err
 	}
 
 	totalAmount, err := transactionService.billingStudentRepositories.GetTotalAmountByBillingStudentIds(request.BillingStudentIds)
 	if err != nil {
+		tx.Rollback()
 		return models.TransactionBilling{}, err
 	}
 
 	listAccountNumber, err := repositories.GetListAccountNumber(request.BillingStudentIds)
 	if err != nil {
+		tx.Rollback()
 		return models.TransactionBilling{}, err
 	}
 
@@ -281,9 +498,30 @@ func (transactionService *TransactionService) CreateTransactionService(request r
 		}
 	}
 
-	if totalBillingAfterDiscon > request.AmountToPay {
+	if request.UseCredit < 0 || request.UseCredit > totalBillingAfterDiscon {
+		tx.Rollback()
+		return models.TransactionBilling{}, fmt.Errorf("ERROR: credit used must be between 0 and the amount due")
+	}
+
+	// An amount below the remaining balance is recorded as a partial payment
+	paidTotal := request.AmountToPay + request.UseCredit
+	isPartialPayment := totalBillingAfterDiscon > paidTotal
+	if isPartialPayment && !request.AllowPartial {
+		tx.Rollback()
 		return models.TransactionBilling{}, fmt.Errorf("ERROR: total billing does not match amount to pay")
 	}
+	if isPartialPayment && request.Discount != 0 {
+		tx.Rollback()
+		return models.TransactionBilling{}, fmt.Errorf("ERROR: discount cannot be applied to a partial payment")
+	}
+
+	// Whatever was paid above the amount due and not handed back as change stays with the student
+	changeAmount := request.ChangeAmount
+	overpayment := paidTotal - totalBillingAfterDiscon - changeAmount
+	if request.KeepChangeAsCredit {
+		overpayment += changeAmount
+		changeAmount = 0
+	}
 
 	// accountNumber := repositories.GetVirtualAccountNumberFromTransaction(request.BillingId)
 
@@ -302,11 +540,19 @@ func (transactionService *TransactionService) CreateTransactionService(request r
 		InvoiceNumber:     invoiceNumber,
 		AccountNumber:     listAccountNumberStr,
 		ExpiryTime:        expiryTime,
+		CreditAmount:      int64(request.UseCredit),
+		CashierShiftID:    &cashierShift.ID,
 	}
 	transaction.CreatedBy = userId
 	// Create transaction in the repository
 	dataTransaction, err := transactionService.transactionRepository.CreateTransactionRepository(tx, &transaction)
 	if err != nil {
+		tx.Rollback()
+		return models.TransactionBilling{}, err
+	}
+
+	if err := recordCashierStudentCredit(tx, &transaction, int64(request.UseCredit), int64(overpayment)); err != nil {
+		tx.Rollback()
 		return models.TransactionBilling{}, err
 	}
 
@@ -315,7 +561,7 @@ func (transactionService *TransactionService) CreateTransactionService(request r
 		TransactionBillingID: transaction.ID,
 		Discount:             request.Discount,
 		DiscountType:         request.DiscountType,
-		ChangeAmount:         request.ChangeAmount,
+		ChangeAmount:         changeAmount,
 		BankName:             nil,
 		VirtualAccountNumber: nil,
 		TransactionTime:      func(t time.Time) *time.Time { return &t }(time.Now()),
@@ -325,6 +571,7 @@ func (transactionService *TransactionService) CreateTransactionService(request r
 	if dataTransaction != nil {
 		_, err := repositories.CreateTransactionBillingDetail(tx, &transactionDetail)
 		if err != nil {
+			tx.Rollback()
 			return models.TransactionBilling{}, err
 		}
 	}
@@ -344,7 +591,7 @@ func (transactionService *TransactionService) CreateTransactionService(request r
 		history.CreatedBy = userId
 		_, err = transactionService.transactionRepository.CreateTransactionHistoryRepository(tx, &history)
 		if err != nil {
-			// tx.Rollback()
+			tx.Rollback()
 			return models.TransactionBilling{}, err
 		}
 	}
@@ -356,15 +603,46 @@ func (transactionService *TransactionService) CreateTransactionService(request r
 		if err != nil {
 			// Tangani error jika konversi gagal
 			fmt.Println("Error konversi:", err)
+			tx.Rollback()
 			return models.TransactionBilling{}, err
 		}
 		billingStudentIdsInt = append(billingStudentIdsInt, idInt)
 	}
+
+	// The cashier holds the installments only while this database transaction runs, which is
+	// enough to reject installments that are locked to a pending online payment
+	reservationKey := fmt.Sprintf("CASHIER-%s-%d", referenceNumber, userId)
+	err = repositories.ReserveBillingStudents(tx, billingStudentIdsInt, reservationKey, time.Now().Add(time.Minute))
+	if err != nil {
+		tx.Rollback()
+		return models.TransactionBilling{}, err
+	}
+
 	if dataTransaction != nil {
-		err := repositories.UpdateStatusPayment(billingStudentIdsInt)
+		billingStudents, err := repositories.GetBillingStudentsForPayment(tx, billingStudentIdsInt)
 		if err != nil {
+			tx.Rollback()
 			return models.TransactionBilling{}, err
 		}
+
+		// Discounted full payments settle the whole remaining balance
+		paidAmount := int64(totalAmount)
+		if isPartialPayment {
+			paidAmount = int64(paidTotal)
+		}
+
+		for _, allocation := range AllocateBillingStudentPayment(billingStudents, paidAmount) {
+			err := repositories.RecordBillingStudentPayment(tx, allocation.BillingStudent, dataTransaction.ID, allocation.Amount, userId)
+			if err != nil {
+				tx.Rollback()
+				return models.TransactionBilling{}, err
+			}
+		}
+	}
+
+	if err := repositories.ReleaseBillingStudentReservation(tx, reservationKey); err != nil {
+		tx.Rollback()
+		return models.TransactionBilling{}, err
 	}
 
 	// Commit the transaction
@@ -429,19 +707,150 @@ func (transactionService *TransactionService) CreateTransactionService(request r
 // 	return nil
 // }
 
+// Refund notifications only echo a refund that the refund workflow has already booked.
+var midtransRefundStatuses = map[string]bool{"refund": true, "partial_refund": true}
+
+// UpdateFromWebHook stores every notification as a webhook event keyed by order, status and
+// status code, so Midtrans retries of the same notification are acknowledged without running
+// the status update, invoice, email and push notification a second time. The signature is
+// checked with the server key of the school that owns the order.
 func (transactionService *TransactionService) UpdateFromWebHook(payload request.WebhookPayload, c *fiber.Ctx) error {
-	err := utilities.ValidateSignature(payload.OrderID, payload.StatusCode, payload.GrossAmount, os.Getenv("SERVER_KEY"), payload.SignatureKey)
+	err := utilities.ValidateMidtransNotification(payload)
 	if err != nil {
 		return err
 	}
 
-	err = utilities.ChangeStatusTransactionFromWebhook(payload)
+	return transactionService.recordWebhookEvent(payload, c)
+}
+
+// UpdateFromGatewayWebhook verifies a notification with the provider's own scheme and feeds the
+// normalised payload into the same event log and state machine as Midtrans notifications.
+func (transactionService *TransactionService) UpdateFromGatewayWebhook(provider string, c *fiber.Ctx) error {
+	gateway, err := utilities.GetPaymentGateway(provider)
 	if err != nil {
 		return err
 	}
 
-	midtransPayment, err := repositories.GetMidtransPaymentLogByOrderId(payload.OrderID)
+	payload, err := gateway.VerifyNotification(c)
+	if err != nil {
+		return err
+	}
+
+	return transactionService.recordWebhookEvent(*payload, c)
+}
+
+// ApplyGatewayStatus feeds a status fetched from the gateway into the webhook event log, so a
+// payment found by reconciliation is booked exactly like a pushed notification would be.
+func (transactionService *TransactionService) ApplyGatewayStatus(payload request.WebhookPayload, c *fiber.Ctx) error {
+	return transactionService.recordWebhookEvent(payload, c)
+}
+
+func (transactionService *TransactionService) recordWebhookEvent(payload request.WebhookPayload, c *fiber.Ctx) error {
+	var errStatus error
+	paymentStatus := ""
+	if !midtransRefundStatuses[payload.TransactionStatus] {
+		paymentStatus, errStatus = repositories.MapWebhookTransactionStatus(payload)
+	}
+
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	event, _, err := transactionService.webhookEventRepository.CreateOrGetWebhookEvent(&models.WebhookEvent{
+		EventKey:          payload.OrderID + ":" + payload.TransactionStatus + ":" + payload.StatusCode,
+		OrderID:           payload.OrderID,
+		TransactionStatus: payload.TransactionStatus,
+		StatusCode:        payload.StatusCode,
+		PaymentStatus:     paymentStatus,
+		Payload:           string(payloadJson),
+		ProcessStatus:     repositories.WebhookEventStatusReceived,
+	})
+	if err != nil {
+		return err
+	}
+
+	if event.ProcessStatus == repositories.WebhookEventStatusProcessed ||
+		(event.ProcessStatus == repositories.WebhookEventStatusProcessing && !repositories.IsWebhookEventStuck(event, time.Now())) {
+		fmt.Printf("Webhook event %s already handled, skipping\n", event.EventKey)
+		return nil
+	}
+
+	// A status we do not know is still logged, failed, so it can be seen and replayed once it is
+	// handled, and acknowledged so the gateway stops sending it
+	if errStatus != nil {
+		return transactionService.webhookEventRepository.MarkWebhookEventFailed(event.ID, errStatus.Error())
+	}
+
+	err = transactionService.processWebhookEvent(event, payload, c)
+	if errors.Is(err, repositories.ErrPaidAfterFailure) {
+		// The event stays failed for an admin to handle, the gateway sending it again changes nothing
+		fmt.Printf("Webhook event %s paid order %s after it failed, left for manual handling\n", event.EventKey, payload.OrderID)
+		return nil
+	}
+	return err
+}
+
+// processWebhookEvent claims the event and records whether handling it succeeded, so a failed
+// event can be replayed later.
+func (transactionService *TransactionService) processWebhookEvent(event *models.WebhookEvent, payload request.WebhookPayload, c *fiber.Ctx) error {
+	claimed, err := transactionService.webhookEventRepository.ClaimWebhookEvent(event.ID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+
+	if err := transactionService.applyWebhookPayload(event, payload, c); err != nil {
+		if errMark := transactionService.webhookEventRepository.MarkWebhookEventFailed(event.ID, err.Error()); errMark != nil {
+			fmt.Printf("failed to mark webhook event %d as failed: %v\n", event.ID, errMark)
+		}
+		return err
+	}
+
+	return transactionService.webhookEventRepository.MarkWebhookEventProcessed(event.ID)
+}
+
+func (transactionService *TransactionService) applyWebhookPayload(event *models.WebhookEvent, payload request.WebhookPayload, c *fiber.Ctx) error {
+	if midtransRefundStatuses[payload.TransactionStatus] {
+		return repositories.SaveLogCheckPaymentMidtransFromWebhook(payload.OrderID, &payload)
+	}
+
+	err := utilities.ChangeStatusTransactionFromWebhook(payload)
+	if errors.Is(err, repositories.ErrTransactionStatusRegression) {
+		// A late notification of an earlier status changes nothing and is not sent to the parent
+		fmt.Printf("Webhook event %s would move order %s backwards, ignoring\n", event.EventKey, payload.OrderID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// A card payment reports "capture" and then "settlement"; both mean paid, so the parent is
+	// only notified for the first one.
+	alreadyNotified, err := transactionService.webhookEventRepository.HasProcessedWebhookEventForStatus(payload.OrderID, event.PaymentStatus, event.ID)
+	if err != nil {
+		return err
+	}
+	if alreadyNotified {
+		return nil
+	}
 
+	return transactionService.sendWebhookNotification(payload, c)
+}
+
+func (transactionService *TransactionService) sendWebhookNotification(payload request.WebhookPayload, c *fiber.Ctx) error {
+	// An accepted card capture booked the installments, so the parent gets the receipts for it
+	if payload.TransactionStatus == "capture" && payload.FraudStatus == "accept" {
+		payload.TransactionStatus = "settlement"
+	}
+
+	midtransPayment, err := repositories.GetMidtransPaymentLogByOrderId(payload.OrderID)
+	// Nothing is booked for a payment to a fixed virtual account until it settles
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
@@ -456,11 +865,45 @@ func (transactionService *TransactionService) UpdateFromWebHook(payload request.
 
 	redirectURL, _ := data["redirect_url"].(string)
 
-	bodyEmail, user, transactionID, err := transactionService.prepareEmailData(payload)
+	transactions, err := repositories.GetTransactionBillingsByOrderID(payload.OrderID)
 	if err != nil {
 		return err
 	}
+	if len(transactions) == 0 {
+		return gorm.ErrRecordNotFound
+	}
 
+	// A combined checkout of several children sends a receipt for each child once paid, the other
+	// messages are about the whole order
+	totalAmount := 0
+	for _, transaction := range transactions {
+		totalAmount += transaction.TotalAmount
+	}
+	notified := transactions[:1]
+	if payload.TransactionStatus == "settlement" {
+		notified = transactions
+	}
+
+	for i := range notified {
+		amount := totalAmount
+		if payload.TransactionStatus == "settlement" {
+			amount = notified[i].TotalAmount
+		}
+
+		bodyEmail, user, transactionID, err := transactionService.prepareEmailData(payload, &notified[i], amount)
+		if err != nil {
+			return err
+		}
+		if err := transactionService.notifyTransaction(payload, bodyEmail, user, transactionID, redirectURL, c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (transactionService *TransactionService) notifyTransaction(payload request.WebhookPayload, bodyEmail BodyEmailTransaction, user models.User, transactionID string, redirectURL string, c *fiber.Ctx) error {
+	var err error
 	switch payload.TransactionStatus {
 	case "pending":
 		emailTemplate := utilities.GenerateEmailBodyTransactionWaiting()
@@ -519,7 +962,7 @@ func (transactionService *TransactionService) UpdateFromWebHook(payload request.
 		if err != nil {
 			return err
 		}
-	case "expire":
+	case "expire", "cancel":
 		emailTemplate := utilities.GenerateEmailBodyTransactionFailedMidtrans()
 
 		cleanedTotalPayment := strings.ReplaceAll(bodyEmail.TotalPayment, "Rp", "")
@@ -532,6 +975,9 @@ func (transactionService *TransactionService) UpdateFromWebHook(payload request.
 
 		bodyEmail.TotalPayment = utilities.RupiahFormat(totalPaymentBigInt)
 		message := fmt.Sprintf("Hai %s, Waktu pembayaran telah habis, sehingga transaksi tidak dapat diproses. Untuk informasi lebih lanjut, cek detail tagihan Anda di aplikasi", user.Username)
+		if payload.TransactionStatus == "cancel" {
+			message = fmt.Sprintf("Hai %s, Transaksi Anda telah dibatalkan. Silakan pilih metode pembayaran lain untuk melanjutkan pembayaran tagihan Anda", user.Username)
+		}
 
 		err = SendEmailPaymentWaiting(user.Email, "Pembayaran Anda Dibatalkan", emailTemplate, bodyEmail)
 		if err != nil {
@@ -551,6 +997,97 @@ func (transactionService *TransactionService) UpdateFromWebHook(payload request.
 	return nil
 }
 
+// ReplayWebhookEvent re-runs a failed webhook event after its underlying data has been fixed, or
+// an event left processing by a run that crashed or timed out.
+func (transactionService *TransactionService) ReplayWebhookEvent(eventID uint, c *fiber.Ctx) (*models.WebhookEvent, error) {
+	event, err := transactionService.webhookEventRepository.GetWebhookEventByID(eventID)
+	if err != nil {
+		return nil, fmt.Errorf(constants.DataNotFoundMessage)
+	}
+
+	if event.ProcessStatus != repositories.WebhookEventStatusFailed && event.ProcessStatus != repositories.WebhookEventStatusReceived &&
+		!repositories.IsWebhookEventStuck(event, time.Now()) {
+		return nil, fmt.Errorf("only failed webhook events can be replayed")
+	}
+
+	var payload request.WebhookPayload
+	if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+		return nil, err
+	}
+
+	if err := transactionService.processWebhookEvent(event, payload, c); err != nil {
+		return nil, err
+	}
+
+	return transactionService.webhookEventRepository.GetWebhookEventByID(eventID)
+}
+
+// CancelPendingTransaction lets the parent who opened an online payment cancel it at the gateway,
+// e.g. after choosing the wrong bank. The cancellation is recorded as a "cancel" webhook event, so
+// the transaction moves to PS03 with a history row, its installments become payable again and
+// the parent is notified, while the gateway's own cancel notification is recognised as a repeat.
+func (transactionService *TransactionService) CancelPendingTransaction(orderID string, userID int, c *fiber.Ctx) (*models.TransactionBilling, error) {
+	transaction, err := repositories.GetBillingByOrderId(orderID)
+	if err != nil {
+		return nil, fmt.Errorf(constants.DataNotFoundMessage)
+	}
+
+	if transaction.CreatedBy != userID {
+		return nil, fmt.Errorf("transaction does not belong to this user")
+	}
+	if transaction.TransactionType != "PT02" || transaction.TransactionStatus != "PS01" {
+		return nil, fmt.Errorf("only pending online payments can be cancelled")
+	}
+
+	gateway, err := utilities.GetPaymentGateway(transaction.PaymentGateway)
+	if err != nil {
+		return nil, err
+	}
+	if err := gateway.Cancel(orderID); err != nil {
+		return nil, err
+	}
+
+	payload := request.WebhookPayload{
+		OrderID:           orderID,
+		TransactionStatus: "cancel",
+		StatusCode:        "200",
+		GrossAmount:       fmt.Sprintf("%d.00", transaction.TotalAmount),
+		ExpiryTime:        transaction.ExpiryTime,
+	}
+	if err := transactionService.recordWebhookEvent(payload, c); err != nil {
+		return nil, err
+	}
+
+	return repositories.GetBillingByOrderId(orderID)
+}
+
+func (transactionService *TransactionService) GetAllWebhookEvent(page int, limit int, processStatus string, orderID string) (response.WebhookEventListResponse, error) {
+	resp := response.WebhookEventListResponse{
+		Page:  page,
+		Limit: limit,
+		Data:  []models.WebhookEvent{},
+	}
+
+	events, total, err := transactionService.webhookEventRepository.GetAllWebhookEvent(page, limit, processStatus, orderID)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.TotalData = total
+	if limit != 0 {
+		resp.TotalPage = int((total + int64(limit) - 1) / int64(limit))
+	}
+	if len(events) > 0 {
+		resp.Data = events
+	}
+
+	return resp, nil
+}
+
+func (transactionService *TransactionService) GetWebhookEventByID(eventID uint) (*models.WebhookEvent, error) {
+	return transactionService.webhookEventRepository.GetWebhookEventByID(eventID)
+}
+
 type BodyEmailTransaction struct {
 	StudentName    string
 	StudentNis     string
@@ -571,12 +1108,7 @@ func formatToIDR(amount int64) string {
 }
 
 // prepareEmailData retrieves and formats data for email notifications
-func (transactionService *TransactionService) prepareEmailData(payload request.WebhookPayload) (BodyEmailTransaction, models.User, string, error) {
-	transactionBilling, err := repositories.GetBillingByOrderId(payload.OrderID)
-	if err != nil {
-		return BodyEmailTransaction{}, models.User{}, "", err
-	}
-
+func (transactionService *TransactionService) prepareEmailData(payload request.WebhookPayload, transactionBilling *models.TransactionBilling, totalAmount int) (BodyEmailTransaction, models.User, string, error) {
 	transactionID := strconv.Itoa(int(transactionBilling.ID))
 
 	student, err := repositories.GetStudentByIDOnlyStudent(transactionBilling.StudentID)
@@ -608,7 +1140,7 @@ func (transactionService *TransactionService) prepareEmailData(payload request.W
 	expireTimeParse, _ := time.Parse("2006-01-02 15:04:05", payload.ExpiryTime)
 	expireTime := expireTimeParse.Format(constants.DateFormatDDMMMYYYhhmm)
 	schoolLogo := utilities.ConvertPath(school.SchoolLogo)
-	stringAmount := strconv.Itoa(transactionBilling.TotalAmount)
+	stringAmount := strconv.Itoa(totalAmount)
 	bodyEmail := BodyEmailTransaction{
 		StudentName:    student.FullName,
 		StudentNis:     student.Nis,
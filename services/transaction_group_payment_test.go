@@ -0,0 +1,73 @@
+package services
+
+import (
+	"testing"
+
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMidtransGroupPayment_Validation(t *testing.T) {
+	firstChild := models.Student{FullName: "Budi"}
+	firstChild.ID = 7
+	secondChild := models.Student{FullName: "Sari"}
+	secondChild.ID = 8
+
+	billingStudent := func(id uint, studentID uint) models.BillingStudent {
+		billingStudent := models.BillingStudent{StudentID: studentID, Amount: 500000}
+		billingStudent.ID = id
+		return billingStudent
+	}
+
+	newService := func() (*MockStudentRepository, *MockBillingStudentRepository, TransactionService) {
+		mockStudentRepo := new(MockStudentRepository)
+		mockBillingStudentRepo := new(MockBillingStudentRepository)
+		mockStudentRepo.On("GetStudentByUserIdRepository", uint(1)).Return([]models.Student{firstChild, secondChild}, nil)
+
+		service := NewTransactionService(repositories.TransactionRepository{}, nil, nil, nil, mockBillingStudentRepo, nil, mockStudentRepo, nil)
+		return mockStudentRepo, mockBillingStudentRepo, service
+	}
+
+	t.Run("No installments", func(t *testing.T) {
+		_, _, service := newService()
+
+		_, err := service.MidtransGroupPayment(4, 1, nil)
+
+		assert.EqualError(t, err, "billingStudentIds is required")
+	})
+
+	t.Run("Invalid installment id", func(t *testing.T) {
+		_, _, service := newService()
+
+		_, err := service.MidtransGroupPayment(4, 1, []string{"11", "abc"})
+
+		assert.EqualError(t, err, "invalid billing student id: abc")
+	})
+
+	t.Run("Installment of a child of another parent", func(t *testing.T) {
+		_, mockBillingStudentRepo, service := newService()
+		mockBillingStudentRepo.On("GetBillingStudentsByIDs", []int{11, 21, 31}).Return([]models.BillingStudent{
+			billingStudent(11, 7),
+			billingStudent(21, 8),
+			billingStudent(31, 9),
+		}, nil)
+
+		_, err := service.MidtransGroupPayment(4, 1, []string{"11", "21", "31"})
+
+		assert.EqualError(t, err, "billing student 31 not found")
+		mockBillingStudentRepo.AssertNotCalled(t, "GetTotalAmountByBillingStudentIds")
+	})
+
+	t.Run("Unknown installment", func(t *testing.T) {
+		_, mockBillingStudentRepo, service := newService()
+		mockBillingStudentRepo.On("GetBillingStudentsByIDs", []int{11, 12}).Return([]models.BillingStudent{
+			billingStudent(11, 7),
+		}, nil)
+
+		_, err := service.MidtransGroupPayment(4, 1, []string{"11", "12"})
+
+		assert.EqualError(t, err, "billing student 12 not found")
+	})
+}
@@ -0,0 +1,431 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"schoolPayment/constants"
+	request "schoolPayment/dtos/request"
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+	"schoolPayment/utilities"
+)
+
+type TransactionRefundServiceInterface interface {
+	CreateRefund(refundRequest *request.CreateRefundRequest, userID int) (*models.TransactionRefund, error)
+	ApproveRefund(refundID uint, userID int) (*models.TransactionRefund, error)
+	RejectRefund(refundID uint, rejectRequest *request.RejectRefundRequest, userID int) (*models.TransactionRefund, error)
+	GetAllRefund(page int, limit int, refundStatus string, userID int) (response.TransactionRefundListResponse, error)
+	GetRefundByID(refundID uint, userID int) (*models.TransactionRefund, error)
+}
+
+type TransactionRefundService struct {
+	transactionRefundRepository repositories.TransactionRefundRepository
+	userRepository              repositories.UserRepository
+}
+
+func NewTransactionRefundService(transactionRefundRepository repositories.TransactionRefundRepository, userRepository repositories.UserRepository) TransactionRefundServiceInterface {
+	return &TransactionRefundService{
+		transactionRefundRepository: transactionRefundRepository,
+		userRepository:              userRepository,
+	}
+}
+
+func (transactionRefundService *TransactionRefundService) CreateRefund(refundRequest *request.CreateRefundRequest, userID int) (*models.TransactionRefund, error) {
+	if err := utilities.ValidateFieldNotEmpty(refundRequest.Reason, "reason"); err != nil {
+		return nil, err
+	}
+
+	schoolID, err := transactionRefundService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err := transactionRefundService.transactionRefundRepository.GetTransactionBillingByID(refundRequest.TransactionBillingId, schoolID)
+	if err != nil {
+		return nil, fmt.Errorf("transaction not found")
+	}
+
+	if transaction.TransactionStatus != "PS02" && transaction.TransactionStatus != repositories.TransactionStatusPartiallyRefunded {
+		return nil, fmt.Errorf("only paid transactions can be refunded")
+	}
+
+	transactionBillingStudentIds := utilities.SplitBillingDetailIds(transaction.BillingStudentIds)
+	inTransaction := make(map[int]bool, len(transactionBillingStudentIds))
+	for _, id := range transactionBillingStudentIds {
+		inTransaction[id] = true
+	}
+
+	requestedIds := refundRequest.BillingStudentIds
+	if len(requestedIds) == 0 {
+		requestedIds = strings.Split(transaction.BillingStudentIds, ",")
+	}
+
+	refundedIds, err := transactionRefundService.transactionRefundRepository.GetRefundedBillingStudentIds(transaction.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	refunded := make(map[int]bool, len(refundedIds))
+	for _, id := range refundedIds {
+		refunded[id] = true
+	}
+
+	var billingStudentIds []int
+	for _, idStr := range requestedIds {
+		id, err := strconv.Atoi(strings.TrimSpace(idStr))
+		if err != nil {
+			return nil, err
+		}
+
+		if !inTransaction[id] {
+			return nil, fmt.Errorf("billing student %d is not part of this transaction", id)
+		}
+
+		if refunded[id] {
+			if len(refundRequest.BillingStudentIds) == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("billing student %d already has a refund", id)
+		}
+		billingStudentIds = append(billingStudentIds, id)
+	}
+
+	if len(billingStudentIds) == 0 {
+		return nil, fmt.Errorf("nothing left to refund on this transaction")
+	}
+
+	// Only the money and credit actually received are given back, not the discount
+	refundable, err := transactionRefundService.transactionRefundRepository.GetRefundableAmount(transaction, billingStudentIds)
+	if err != nil {
+		return nil, err
+	}
+	if refundable.Reversed <= 0 {
+		return nil, fmt.Errorf("no paid amount found for the selected billing students")
+	}
+
+	// The refund is "full" when it covers every installment left on the transaction
+	refundType := repositories.RefundTypePartial
+	if len(billingStudentIds)+len(refundedIds) >= len(transactionBillingStudentIds) {
+		refundType = repositories.RefundTypeFull
+	}
+
+	refund := &models.TransactionRefund{
+		TransactionBillingID: transaction.ID,
+		SchoolID:             schoolID,
+		RefundType:           refundType,
+		BillingStudentIds:    utilities.IntsToString(billingStudentIds),
+		Amount:               refundable.Cash + refundable.Credit,
+		CreditAmount:         refundable.Credit,
+		ReversedAmount:       refundable.Reversed,
+		Reason:               refundRequest.Reason,
+		RefundStatus:         repositories.RefundStatusWaitingApproval,
+		ToCredit:             refundRequest.ToCredit,
+	}
+	refund.CreatedBy = userID
+
+	return transactionRefundService.transactionRefundRepository.CreateRefund(refund)
+}
+
+func (transactionRefundService *TransactionRefundService) ApproveRefund(refundID uint, userID int) (*models.TransactionRefund, error) {
+	refund, err := transactionRefundService.getRefund(refundID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A refund already paid out at the gateway but not booked is only booked again, never paid twice
+	paidOut := refund.RefundStatus == repositories.RefundStatusProcessing && refund.GatewayResponse != ""
+	if refund.RefundStatus != repositories.RefundStatusWaitingApproval && !paidOut {
+		return nil, fmt.Errorf("refund has already been processed")
+	}
+
+	// Build the receipt lines before the ledger entries are reversed
+	receiptItems, err := transactionRefundService.getRefundReceiptItems(refund)
+	if err != nil {
+		return nil, err
+	}
+
+	if !paidOut {
+		// Claim the refund before anything is paid out, so a second approval of the same refund
+		// cannot reach the gateway as well
+		if err := transactionRefundService.claimRefund(refund, repositories.RefundStatusProcessing, userID); err != nil {
+			return nil, err
+		}
+
+		paidOut, err = transactionRefundService.payOutRefund(refund, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := transactionRefundService.transactionRefundRepository.ApplyRefund(refund, userID); err != nil {
+		if paidOut {
+			return nil, fmt.Errorf("refund was paid out but could not be booked, approve it again to finish: %w", err)
+		}
+		return nil, transactionRefundService.releaseRefund(refund, userID, err)
+	}
+
+	if err := transactionRefundService.sendRefundReceipt(refund, receiptItems); err != nil {
+		fmt.Printf("failed to send refund receipt: %v\n", err)
+	}
+
+	return refund, nil
+}
+
+// payOutRefund pays the cash part of a claimed refund back through the payment gateway and saves
+// the gateway response before anything is booked. It reports whether money left the school.
+func (transactionRefundService *TransactionRefundService) payOutRefund(refund *models.TransactionRefund, userID int) (bool, error) {
+	// A refund to credit stays in the school, so nothing is paid out at the gateway. The part paid
+	// with student credit never went through the gateway either
+	transaction := refund.TransactionBilling
+	cashAmount := refund.Amount - refund.CreditAmount
+	if transaction.TransactionType != "PT02" || refund.ToCredit || cashAmount <= 0 {
+		return false, nil
+	}
+
+	gateway, err := utilities.GetPaymentGateway(transaction.PaymentGateway)
+	if err != nil {
+		return false, transactionRefundService.releaseRefund(refund, userID, err)
+	}
+
+	refund.RefundKey = fmt.Sprintf("REFUND-%d-%d", refund.ID, time.Now().Unix())
+	refund.UpdatedBy = userID
+	gatewayResponse, err := gateway.Refund(transaction.OrderID, refund.RefundKey, cashAmount, refund.Reason)
+	if err != nil {
+		refund.RefundStatus = repositories.RefundStatusFailed
+		refund.GatewayResponse = err.Error()
+		if errUpdate := transactionRefundService.transactionRefundRepository.UpdateRefund(refund); errUpdate != nil {
+			return false, errUpdate
+		}
+		return false, fmt.Errorf("failed to refund at payment gateway: %v", err)
+	}
+
+	// Save the payout while the refund is still processing, so a failed booking can be retried
+	// without calling the gateway again
+	refund.GatewayResponse = gatewayResponse
+	if err := transactionRefundService.transactionRefundRepository.UpdateRefund(refund); err != nil {
+		return true, fmt.Errorf("refund was paid out but could not be saved: %w", err)
+	}
+	return true, nil
+}
+
+func (transactionRefundService *TransactionRefundService) RejectRefund(refundID uint, rejectRequest *request.RejectRefundRequest, userID int) (*models.TransactionRefund, error) {
+	if err := utilities.ValidateFieldNotEmpty(rejectRequest.RejectionReason, "rejectionReason"); err != nil {
+		return nil, err
+	}
+
+	refund, err := transactionRefundService.getWaitingRefund(refundID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := transactionRefundService.claimRefund(refund, repositories.RefundStatusRejected, userID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	refund.RejectionReason = rejectRequest.RejectionReason
+	refund.ApprovedBy = &userID
+	refund.ApprovedAt = &now
+	refund.UpdatedBy = userID
+
+	if err := transactionRefundService.transactionRefundRepository.UpdateRefund(refund); err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+func (transactionRefundService *TransactionRefundService) GetAllRefund(page int, limit int, refundStatus string, userID int) (response.TransactionRefundListResponse, error) {
+	resp := response.TransactionRefundListResponse{
+		Page:  page,
+		Limit: limit,
+		Data:  []models.TransactionRefund{},
+	}
+
+	user, err := transactionRefundService.userRepository.GetUserByID(uint(userID))
+	if err != nil {
+		return resp, err
+	}
+
+	var schoolID uint
+	if user.UserSchool != nil {
+		schoolID = user.UserSchool.SchoolID
+	}
+
+	refunds, total, err := transactionRefundService.transactionRefundRepository.GetAllRefund(page, limit, refundStatus, schoolID)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.TotalData = total
+	if limit != 0 {
+		resp.TotalPage = int((total + int64(limit) - 1) / int64(limit))
+	}
+	if len(refunds) > 0 {
+		resp.Data = refunds
+	}
+
+	return resp, nil
+}
+
+func (transactionRefundService *TransactionRefundService) GetRefundByID(refundID uint, userID int) (*models.TransactionRefund, error) {
+	schoolID, err := transactionRefundService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return transactionRefundService.transactionRefundRepository.GetRefundByID(refundID, schoolID)
+}
+
+func (transactionRefundService *TransactionRefundService) getRefund(refundID uint, userID int) (*models.TransactionRefund, error) {
+	schoolID, err := transactionRefundService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	refund, err := transactionRefundService.transactionRefundRepository.GetRefundByID(refundID, schoolID)
+	if err != nil {
+		return nil, fmt.Errorf("refund not found")
+	}
+	return refund, nil
+}
+
+func (transactionRefundService *TransactionRefundService) getWaitingRefund(refundID uint, userID int) (*models.TransactionRefund, error) {
+	refund, err := transactionRefundService.getRefund(refundID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if refund.RefundStatus != repositories.RefundStatusWaitingApproval {
+		return nil, fmt.Errorf("refund has already been processed")
+	}
+	return refund, nil
+}
+
+// claimRefund moves the refund out of waiting for approval, failing when another user got there first.
+func (transactionRefundService *TransactionRefundService) claimRefund(refund *models.TransactionRefund, refundStatus string, userID int) error {
+	claimed, err := transactionRefundService.transactionRefundRepository.ClaimRefund(refund.ID, refundStatus, userID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return fmt.Errorf("refund has already been processed")
+	}
+	refund.RefundStatus = refundStatus
+	return nil
+}
+
+// releaseRefund puts a claimed refund back to waiting for approval after a failure that paid
+// nothing out, so it can be approved again, and returns the failure.
+func (transactionRefundService *TransactionRefundService) releaseRefund(refund *models.TransactionRefund, userID int, cause error) error {
+	if err := transactionRefundService.transactionRefundRepository.ReleaseRefund(refund.ID, userID); err != nil {
+		return err
+	}
+	refund.RefundStatus = repositories.RefundStatusWaitingApproval
+	return cause
+}
+
+func (transactionRefundService *TransactionRefundService) getSchoolID(userID int) (uint, error) {
+	user, err := transactionRefundService.userRepository.GetUserByID(uint(userID))
+	if err != nil {
+		return 0, err
+	}
+	if user.UserSchool == nil {
+		return 0, fmt.Errorf("user not associated with any school")
+	}
+	return user.UserSchool.SchoolID, nil
+}
+
+func (transactionRefundService *TransactionRefundService) getRefundReceiptItems(refund *models.TransactionRefund) ([]response.RefundReceiptItem, error) {
+	billingStudentIds := utilities.SplitBillingDetailIds(refund.BillingStudentIds)
+
+	billingStudents, err := transactionRefundService.transactionRefundRepository.GetBillingStudentsByIds(billingStudentIds)
+	if err != nil {
+		return nil, err
+	}
+
+	paidAmounts, err := transactionRefundService.transactionRefundRepository.GetPaidAmountByTransaction(refund.TransactionBillingID, billingStudentIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []response.RefundReceiptItem
+	for _, billingStudent := range billingStudents {
+		items = append(items, response.RefundReceiptItem{
+			DetailBillingName: billingStudent.DetailBillingName,
+			Amount:            paidAmounts[billingStudent.ID],
+		})
+	}
+	return items, nil
+}
+
+// sendRefundReceipt emails the refund receipt PDF to the guardian of the student.
+func (transactionRefundService *TransactionRefundService) sendRefundReceipt(refund *models.TransactionRefund, items []response.RefundReceiptItem) error {
+	transaction := refund.TransactionBilling
+
+	student, err := repositories.GetStudentByIDOnlyStudent(transaction.StudentID)
+	if err != nil {
+		return err
+	}
+
+	school, err := repositories.GetSchoolByStudentId(transaction.StudentID)
+	if err != nil {
+		return err
+	}
+
+	guardian, err := repositories.GetEmailParentById(int(transaction.StudentID))
+	if err != nil {
+		return err
+	}
+
+	refundType := "Pengembalian Penuh"
+	if refund.RefundType == repositories.RefundTypePartial {
+		refundType = "Pengembalian Sebagian"
+	}
+
+	refundMethod := "Tunai (Kasir)"
+	if refund.ToCredit || (refund.CreditAmount > 0 && refund.CreditAmount == refund.Amount) {
+		refundMethod = "Saldo Kredit Siswa"
+	} else if transaction.TransactionType == "PT02" {
+		refundMethod = "Payment Gateway"
+	}
+	if !refund.ToCredit && refund.CreditAmount > 0 && refund.CreditAmount < refund.Amount {
+		refundMethod += " dan Saldo Kredit Siswa"
+	}
+
+	refundDate := time.Now().Format(constants.DateFormatDDMMMYYYhhmm)
+	receipt := response.RefundReceipt{
+		InvoiceNumber: transaction.InvoiceNumber,
+		StudentName:   student.FullName,
+		StudentNis:    student.Nis,
+		RefundType:    refundType,
+		RefundMethod:  refundMethod,
+		RefundDate:    refundDate,
+		Reason:        refund.Reason,
+		Amount:        refund.Amount,
+		ListBilling:   items,
+	}
+
+	filename, err := utilities.GenerateRefundReceiptPDF(receipt, *school)
+	if err != nil {
+		return err
+	}
+
+	bodyEmail := BodyEmailTransaction{
+		StudentName:   student.FullName,
+		StudentNis:    student.Nis,
+		InvoiceNumber: transaction.InvoiceNumber,
+		PaymentDate:   refundDate,
+		TotalPayment:  formatToIDR(refund.Amount),
+		SchoolName:    school.SchoolName,
+		Year:          time.Now().Year(),
+		SchoolLogo:    utilities.ConvertPath(school.SchoolLogo),
+	}
+
+	emailTemplate := utilities.GenerateEmailBodyRefund()
+	_, err = SendEmailPaymentSuccess(guardian.Email, "Bukti Pengembalian Dana", emailTemplate, bodyEmail, filename)
+	return err
+}
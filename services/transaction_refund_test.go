@@ -0,0 +1,259 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"schoolPayment/dtos/request"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+	"schoolPayment/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockTransactionRefundRepository is a mock implementation of the TransactionRefundRepository interface
+type MockTransactionRefundRepository struct {
+	mock.Mock
+}
+
+func (m *MockTransactionRefundRepository) GetTransactionBillingByID(id uint, schoolID uint) (*models.TransactionBilling, error) {
+	args := m.Called(id, schoolID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TransactionBilling), args.Error(1)
+}
+
+func (m *MockTransactionRefundRepository) GetPaidAmountByTransaction(transactionBillingID uint, billingStudentIds []int) (map[uint]int64, error) {
+	args := m.Called(transactionBillingID, billingStudentIds)
+	return args.Get(0).(map[uint]int64), args.Error(1)
+}
+
+func (m *MockTransactionRefundRepository) GetRefundableAmount(transaction *models.TransactionBilling, billingStudentIds []int) (repositories.RefundableAmount, error) {
+	args := m.Called(transaction, billingStudentIds)
+	return args.Get(0).(repositories.RefundableAmount), args.Error(1)
+}
+
+func (m *MockTransactionRefundRepository) GetRefundedBillingStudentIds(transactionBillingID uint) ([]int, error) {
+	args := m.Called(transactionBillingID)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockTransactionRefundRepository) GetBillingStudentsByIds(billingStudentIds []int) ([]models.BillingStudent, error) {
+	args := m.Called(billingStudentIds)
+	return args.Get(0).([]models.BillingStudent), args.Error(1)
+}
+
+func (m *MockTransactionRefundRepository) CreateRefund(refund *models.TransactionRefund) (*models.TransactionRefund, error) {
+	args := m.Called(refund)
+	return args.Get(0).(*models.TransactionRefund), args.Error(1)
+}
+
+func (m *MockTransactionRefundRepository) GetRefundByID(id uint, schoolID uint) (*models.TransactionRefund, error) {
+	args := m.Called(id, schoolID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TransactionRefund), args.Error(1)
+}
+
+func (m *MockTransactionRefundRepository) ClaimRefund(id uint, refundStatus string, userID int) (bool, error) {
+	args := m.Called(id, refundStatus, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTransactionRefundRepository) ReleaseRefund(id uint, userID int) error {
+	args := m.Called(id, userID)
+	return args.Error(0)
+}
+
+func (m *MockTransactionRefundRepository) GetAllRefund(page int, limit int, refundStatus string, schoolID uint) ([]models.TransactionRefund, int64, error) {
+	args := m.Called(page, limit, refundStatus, schoolID)
+	return args.Get(0).([]models.TransactionRefund), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockTransactionRefundRepository) UpdateRefund(refund *models.TransactionRefund) error {
+	args := m.Called(refund)
+	return args.Error(0)
+}
+
+func (m *MockTransactionRefundRepository) ApplyRefund(refund *models.TransactionRefund, userID int) error {
+	args := m.Called(refund, userID)
+	return args.Error(0)
+}
+
+func TestCreateRefund_PartialRefund(t *testing.T) {
+	mockRefundRepo := new(MockTransactionRefundRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewTransactionRefundService(mockRefundRepo, mockUserRepo)
+
+	user := models.User{UserSchool: &models.UserSchool{SchoolID: 7}}
+	transaction := &models.TransactionBilling{TransactionStatus: "PS02", BillingStudentIds: "11,12"}
+	transaction.ID = 3
+
+	mockUserRepo.On("GetUserByID", uint(1)).Return(user, nil)
+	mockRefundRepo.On("GetTransactionBillingByID", uint(3), uint(7)).Return(transaction, nil)
+	mockRefundRepo.On("GetRefundedBillingStudentIds", uint(3)).Return([]int{}, nil)
+	mockRefundRepo.On("GetRefundableAmount", transaction, []int{12}).
+		Return(repositories.RefundableAmount{Reversed: 250000, Cash: 200000, Credit: 25000}, nil)
+	mockRefundRepo.On("CreateRefund", mock.AnythingOfType("*models.TransactionRefund")).
+		Return(&models.TransactionRefund{}, nil).
+		Run(func(args mock.Arguments) {
+			refund := args.Get(0).(*models.TransactionRefund)
+			assert.Equal(t, repositories.RefundTypePartial, refund.RefundType)
+			assert.Equal(t, repositories.RefundStatusWaitingApproval, refund.RefundStatus)
+			assert.Equal(t, int64(225000), refund.Amount)
+			assert.Equal(t, int64(25000), refund.CreditAmount)
+			assert.Equal(t, int64(250000), refund.ReversedAmount)
+			assert.Equal(t, "12", refund.BillingStudentIds)
+			assert.Equal(t, uint(7), refund.SchoolID)
+		})
+
+	_, err := service.CreateRefund(&request.CreateRefundRequest{
+		TransactionBillingId: 3,
+		BillingStudentIds:    []string{"12"},
+		Reason:               "Siswa pindah sekolah",
+	}, 1)
+
+	assert.NoError(t, err)
+	mockRefundRepo.AssertExpectations(t)
+}
+
+func TestCreateRefund_BillingStudentNotInTransaction(t *testing.T) {
+	mockRefundRepo := new(MockTransactionRefundRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewTransactionRefundService(mockRefundRepo, mockUserRepo)
+
+	transaction := &models.TransactionBilling{TransactionStatus: "PS02", BillingStudentIds: "11,12"}
+	transaction.ID = 3
+
+	mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 7}}, nil)
+	mockRefundRepo.On("GetTransactionBillingByID", uint(3), uint(7)).Return(transaction, nil)
+	mockRefundRepo.On("GetRefundedBillingStudentIds", uint(3)).Return([]int{}, nil)
+
+	_, err := service.CreateRefund(&request.CreateRefundRequest{
+		TransactionBillingId: 3,
+		BillingStudentIds:    []string{"99"},
+		Reason:               "Salah input",
+	}, 1)
+
+	assert.EqualError(t, err, "billing student 99 is not part of this transaction")
+	mockRefundRepo.AssertNotCalled(t, "CreateRefund", mock.Anything)
+}
+
+func TestRejectRefund_AlreadyProcessed(t *testing.T) {
+	mockRefundRepo := new(MockTransactionRefundRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewTransactionRefundService(mockRefundRepo, mockUserRepo)
+
+	mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 7}}, nil)
+	mockRefundRepo.On("GetRefundByID", uint(5), uint(7)).Return(&models.TransactionRefund{RefundStatus: repositories.RefundStatusSuccess}, nil)
+
+	_, err := service.RejectRefund(5, &request.RejectRefundRequest{RejectionReason: "Tidak valid"}, 1)
+
+	assert.Equal(t, errors.New("refund has already been processed"), err)
+	mockRefundRepo.AssertNotCalled(t, "UpdateRefund", mock.Anything)
+}
+
+func TestApproveRefund_OtherSchool(t *testing.T) {
+	mockRefundRepo := new(MockTransactionRefundRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewTransactionRefundService(mockRefundRepo, mockUserRepo)
+
+	mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 8}}, nil)
+	mockRefundRepo.On("GetRefundByID", uint(5), uint(8)).Return(nil, errors.New("record not found"))
+
+	_, err := service.ApproveRefund(5, 1)
+
+	assert.EqualError(t, err, "refund not found")
+	mockRefundRepo.AssertNotCalled(t, "ClaimRefund", mock.Anything, mock.Anything, mock.Anything)
+	mockRefundRepo.AssertNotCalled(t, "ApplyRefund", mock.Anything, mock.Anything)
+}
+
+func TestApproveRefund_ClaimedByAnotherApprover(t *testing.T) {
+	mockRefundRepo := new(MockTransactionRefundRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewTransactionRefundService(mockRefundRepo, mockUserRepo)
+
+	refund := &models.TransactionRefund{
+		TransactionBillingID: 3,
+		BillingStudentIds:    "12",
+		RefundStatus:         repositories.RefundStatusWaitingApproval,
+		TransactionBilling:   models.TransactionBilling{TransactionType: "PT02"},
+	}
+	refund.ID = 5
+
+	mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 7}}, nil)
+	mockRefundRepo.On("GetRefundByID", uint(5), uint(7)).Return(refund, nil)
+	mockRefundRepo.On("GetBillingStudentsByIds", []int{12}).Return([]models.BillingStudent{}, nil)
+	mockRefundRepo.On("GetPaidAmountByTransaction", uint(3), []int{12}).Return(map[uint]int64{12: 250000}, nil)
+	mockRefundRepo.On("ClaimRefund", uint(5), repositories.RefundStatusProcessing, 1).Return(false, nil)
+
+	_, err := service.ApproveRefund(5, 1)
+
+	assert.EqualError(t, err, "refund has already been processed")
+	mockRefundRepo.AssertNotCalled(t, "UpdateRefund", mock.Anything)
+	mockRefundRepo.AssertNotCalled(t, "ApplyRefund", mock.Anything, mock.Anything)
+}
+
+func TestApproveRefund_ReleasedWhenNothingPaidOut(t *testing.T) {
+	mockRefundRepo := new(MockTransactionRefundRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewTransactionRefundService(mockRefundRepo, mockUserRepo)
+
+	refund := &models.TransactionRefund{
+		TransactionBillingID: 3,
+		BillingStudentIds:    "12",
+		Amount:               250000,
+		RefundStatus:         repositories.RefundStatusWaitingApproval,
+		TransactionBilling:   models.TransactionBilling{TransactionType: "PT02", PaymentGateway: "unknown"},
+	}
+	refund.ID = 5
+
+	mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 7}}, nil)
+	mockRefundRepo.On("GetRefundByID", uint(5), uint(7)).Return(refund, nil)
+	mockRefundRepo.On("GetBillingStudentsByIds", []int{12}).Return([]models.BillingStudent{}, nil)
+	mockRefundRepo.On("GetPaidAmountByTransaction", uint(3), []int{12}).Return(map[uint]int64{12: 250000}, nil)
+	mockRefundRepo.On("ClaimRefund", uint(5), repositories.RefundStatusProcessing, 1).Return(true, nil)
+	mockRefundRepo.On("ReleaseRefund", uint(5), 1).Return(nil)
+
+	_, err := service.ApproveRefund(5, 1)
+
+	assert.EqualError(t, err, "unsupported payment gateway: unknown")
+	assert.Equal(t, repositories.RefundStatusWaitingApproval, refund.RefundStatus)
+	mockRefundRepo.AssertCalled(t, "ReleaseRefund", uint(5), 1)
+	mockRefundRepo.AssertNotCalled(t, "ApplyRefund", mock.Anything, mock.Anything)
+}
+
+func TestApproveRefund_RetryBooksPaidOutRefund(t *testing.T) {
+	mockRefundRepo := new(MockTransactionRefundRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := services.NewTransactionRefundService(mockRefundRepo, mockUserRepo)
+
+	refund := &models.TransactionRefund{
+		TransactionBillingID: 3,
+		BillingStudentIds:    "12",
+		Amount:               250000,
+		RefundStatus:         repositories.RefundStatusProcessing,
+		RefundKey:            "REFUND-5-1700000000",
+		GatewayResponse:      `{"status_code":"200"}`,
+		TransactionBilling:   models.TransactionBilling{TransactionType: "PT02"},
+	}
+	refund.ID = 5
+
+	mockUserRepo.On("GetUserByID", uint(1)).Return(models.User{UserSchool: &models.UserSchool{SchoolID: 7}}, nil)
+	mockRefundRepo.On("GetRefundByID", uint(5), uint(7)).Return(refund, nil)
+	mockRefundRepo.On("GetBillingStudentsByIds", []int{12}).Return([]models.BillingStudent{}, nil)
+	mockRefundRepo.On("GetPaidAmountByTransaction", uint(3), []int{12}).Return(map[uint]int64{12: 250000}, nil)
+	mockRefundRepo.On("ApplyRefund", refund, 1).Return(errors.New("connection reset"))
+
+	_, err := service.ApproveRefund(5, 1)
+
+	assert.EqualError(t, err, "refund was paid out but could not be booked, approve it again to finish: connection reset")
+	mockRefundRepo.AssertCalled(t, "ApplyRefund", refund, 1)
+	mockRefundRepo.AssertNotCalled(t, "ClaimRefund", mock.Anything, mock.Anything, mock.Anything)
+	mockRefundRepo.AssertNotCalled(t, "ReleaseRefund", mock.Anything, mock.Anything)
+	mockRefundRepo.AssertNotCalled(t, "UpdateRefund", mock.Anything)
+}
@@ -0,0 +1,59 @@
+package services_test
+
+import (
+	"testing"
+
+	database "schoolPayment/configs"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+	"schoolPayment/services"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func mockTransactionBillingByOrder(t *testing.T, transaction models.TransactionBilling) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db, DriverName: "postgres"}), &gorm.Config{})
+	assert.NoError(t, err)
+	database.DB = gormDB
+
+	rows := sqlmock.NewRows([]string{"id", "order_id", "transaction_type", "transaction_status", "total_amount", "payment_gateway", "created_by"}).
+		AddRow(transaction.ID, transaction.OrderID, transaction.TransactionType, transaction.TransactionStatus, transaction.TotalAmount, transaction.PaymentGateway, transaction.CreatedBy)
+	dbMock.ExpectQuery("transaction_billings").WithArgs(transaction.OrderID, 1).WillReturnRows(rows)
+}
+
+func TestCancelPendingTransaction_OtherParent(t *testing.T) {
+	mockWebhookEventRepo := new(MockWebhookEventRepository)
+	service := services.NewTransactionService(repositories.TransactionRepository{}, nil, nil, nil, nil, nil, nil, mockWebhookEventRepo)
+
+	transaction := models.TransactionBilling{OrderID: "ORDER-1", TransactionType: "PT02", TransactionStatus: "PS01", TotalAmount: 150000}
+	transaction.ID = 1
+	transaction.CreatedBy = 7
+	mockTransactionBillingByOrder(t, transaction)
+
+	_, err := service.CancelPendingTransaction("ORDER-1", 8, nil)
+
+	assert.EqualError(t, err, "transaction does not belong to this user")
+	mockWebhookEventRepo.AssertNotCalled(t, "CreateOrGetWebhookEvent", mock.Anything)
+}
+
+func TestCancelPendingTransaction_AlreadyPaid(t *testing.T) {
+	mockWebhookEventRepo := new(MockWebhookEventRepository)
+	service := services.NewTransactionService(repositories.TransactionRepository{}, nil, nil, nil, nil, nil, nil, mockWebhookEventRepo)
+
+	transaction := models.TransactionBilling{OrderID: "ORDER-1", TransactionType: "PT02", TransactionStatus: "PS02", TotalAmount: 150000}
+	transaction.ID = 1
+	transaction.CreatedBy = 7
+	mockTransactionBillingByOrder(t, transaction)
+
+	_, err := service.CancelPendingTransaction("ORDER-1", 7, nil)
+
+	assert.EqualError(t, err, "only pending online payments can be cancelled")
+	mockWebhookEventRepo.AssertNotCalled(t, "CreateOrGetWebhookEvent", mock.Anything)
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	request "schoolPayment/dtos/request"
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+	"schoolPayment/utilities"
+)
+
+type TransactionVoidServiceInterface interface {
+	CreateVoid(voidRequest *request.CreateVoidRequest, userID int) (*models.TransactionVoid, error)
+	ApproveVoid(voidID uint, userID int) (*models.TransactionVoid, error)
+	RejectVoid(voidID uint, rejectRequest *request.RejectVoidRequest, userID int) (*models.TransactionVoid, error)
+	GetAllVoid(page int, limit int, voidStatus string, userID int) (response.TransactionVoidListResponse, error)
+	GetVoidByID(voidID uint, userID int) (*models.TransactionVoid, error)
+}
+
+type TransactionVoidService struct {
+	transactionVoidRepository repositories.TransactionVoidRepository
+	userRepository            repositories.UserRepository
+}
+
+func NewTransactionVoidService(transactionVoidRepository repositories.TransactionVoidRepository, userRepository repositories.UserRepository) TransactionVoidServiceInterface {
+	return &TransactionVoidService{
+		transactionVoidRepository: transactionVoidRepository,
+		userRepository:            userRepository,
+	}
+}
+
+// CreateVoid asks for a kasir payment to be undone. Nothing changes until Admin Sekolah approves it.
+func (transactionVoidService *TransactionVoidService) CreateVoid(voidRequest *request.CreateVoidRequest, userID int) (*models.TransactionVoid, error) {
+	if err := utilities.ValidateFieldNotEmpty(voidRequest.Reason, "reason"); err != nil {
+		return nil, err
+	}
+
+	schoolID, err := transactionVoidService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err := transactionVoidService.transactionVoidRepository.GetTransactionBillingForSchool(voidRequest.TransactionBillingId, schoolID)
+	if err != nil {
+		return nil, fmt.Errorf("transaction not found")
+	}
+
+	if transaction.TransactionType != "PT01" || transaction.TransactionStatus != "PS02" {
+		return nil, repositories.ErrTransactionNotVoidable
+	}
+
+	waiting, err := transactionVoidService.transactionVoidRepository.HasWaitingVoid(transaction.ID)
+	if err != nil {
+		return nil, err
+	}
+	if waiting {
+		return nil, fmt.Errorf("transaction already has a void waiting for approval")
+	}
+
+	transactionVoid := &models.TransactionVoid{
+		TransactionBillingID: transaction.ID,
+		SchoolID:             schoolID,
+		Reason:               voidRequest.Reason,
+		VoidStatus:           repositories.VoidStatusWaitingApproval,
+	}
+	transactionVoid.CreatedBy = userID
+
+	return transactionVoidService.transactionVoidRepository.CreateVoid(transactionVoid)
+}
+
+func (transactionVoidService *TransactionVoidService) ApproveVoid(voidID uint, userID int) (*models.TransactionVoid, error) {
+	transactionVoid, err := transactionVoidService.getWaitingVoid(voidID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The one asking for the void cannot approve it
+	if transactionVoid.CreatedBy == userID {
+		return nil, fmt.Errorf("void must be approved by another user")
+	}
+
+	if err := transactionVoidService.transactionVoidRepository.ApplyVoid(transactionVoid, userID); err != nil {
+		return nil, err
+	}
+
+	return transactionVoid, nil
+}
+
+func (transactionVoidService *TransactionVoidService) RejectVoid(voidID uint, rejectRequest *request.RejectVoidRequest, userID int) (*models.TransactionVoid, error) {
+	if err := utilities.ValidateFieldNotEmpty(rejectRequest.RejectionReason, "rejectionReason"); err != nil {
+		return nil, err
+	}
+
+	transactionVoid, err := transactionVoidService.getWaitingVoid(voidID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	transactionVoid.VoidStatus = repositories.VoidStatusRejected
+	transactionVoid.RejectionReason = rejectRequest.RejectionReason
+	transactionVoid.ApprovedBy = &userID
+	transactionVoid.ApprovedAt = &now
+	transactionVoid.UpdatedBy = userID
+
+	if err := transactionVoidService.transactionVoidRepository.UpdateVoid(transactionVoid); err != nil {
+		return nil, err
+	}
+
+	return transactionVoid, nil
+}
+
+func (transactionVoidService *TransactionVoidService) GetAllVoid(page int, limit int, voidStatus string, userID int) (response.TransactionVoidListResponse, error) {
+	resp := response.TransactionVoidListResponse{
+		Page:  page,
+		Limit: limit,
+		Data:  []models.TransactionVoid{},
+	}
+
+	schoolID, err := transactionVoidService.getSchoolID(userID)
+	if err != nil {
+		return resp, err
+	}
+
+	voids, total, err := transactionVoidService.transactionVoidRepository.GetAllVoid(page, limit, voidStatus, schoolID)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.TotalData = total
+	if limit != 0 {
+		resp.TotalPage = int((total + int64(limit) - 1) / int64(limit))
+	}
+	if len(voids) > 0 {
+		resp.Data = voids
+	}
+
+	return resp, nil
+}
+
+func (transactionVoidService *TransactionVoidService) GetVoidByID(voidID uint, userID int) (*models.TransactionVoid, error) {
+	schoolID, err := transactionVoidService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return transactionVoidService.transactionVoidRepository.GetVoidByID(voidID, schoolID)
+}
+
+func (transactionVoidService *TransactionVoidService) getWaitingVoid(voidID uint, userID int) (*models.TransactionVoid, error) {
+	schoolID, err := transactionVoidService.getSchoolID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionVoid, err := transactionVoidService.transactionVoidRepository.GetVoidByID(voidID, schoolID)
+	if err != nil {
+		return nil, fmt.Errorf("void not found")
+	}
+
+	if transactionVoid.VoidStatus != repositories.VoidStatusWaitingApproval {
+		return nil, fmt.Errorf("void has already been processed")
+	}
+	return transactionVoid, nil
+}
+
+func (transactionVoidService *TransactionVoidService) getSchoolID(userID int) (uint, error) {
+	user, err := transactionVoidService.userRepository.GetUserByID(uint(userID))
+	if err != nil {
+		return 0, err
+	}
+	if user.UserSchool == nil {
+		return 0, fmt.Errorf("user not associated with any school")
+	}
+	return user.UserSchool.SchoolID, nil
+}
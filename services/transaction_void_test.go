@@ -0,0 +1,184 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	request "schoolPayment/dtos/request"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTransactionVoidRepository struct {
+	mock.Mock
+}
+
+func (m *MockTransactionVoidRepository) GetTransactionBillingForSchool(id uint, schoolID uint) (*models.TransactionBilling, error) {
+	args := m.Called(id, schoolID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TransactionBilling), args.Error(1)
+}
+
+func (m *MockTransactionVoidRepository) HasWaitingVoid(transactionBillingID uint) (bool, error) {
+	args := m.Called(transactionBillingID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTransactionVoidRepository) CreateVoid(transactionVoid *models.TransactionVoid) (*models.TransactionVoid, error) {
+	args := m.Called(transactionVoid)
+	return transactionVoid, args.Error(0)
+}
+
+func (m *MockTransactionVoidRepository) GetVoidByID(id uint, schoolID uint) (*models.TransactionVoid, error) {
+	args := m.Called(id, schoolID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TransactionVoid), args.Error(1)
+}
+
+func (m *MockTransactionVoidRepository) GetAllVoid(page int, limit int, voidStatus string, schoolID uint) ([]models.TransactionVoid, int64, error) {
+	args := m.Called(page, limit, voidStatus, schoolID)
+	return args.Get(0).([]models.TransactionVoid), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockTransactionVoidRepository) UpdateVoid(transactionVoid *models.TransactionVoid) error {
+	args := m.Called(transactionVoid)
+	return args.Error(0)
+}
+
+func (m *MockTransactionVoidRepository) ApplyVoid(transactionVoid *models.TransactionVoid, userID int) error {
+	args := m.Called(transactionVoid, userID)
+	return args.Error(0)
+}
+
+func voidTestUser() *models.User {
+	return &models.User{RoleID: 5, UserSchool: &models.UserSchool{SchoolID: 1}}
+}
+
+func TestCreateVoid(t *testing.T) {
+	paidKasir := &models.TransactionBilling{TransactionType: "PT01", TransactionStatus: "PS02"}
+	paidKasir.ID = 11
+
+	t.Run("Success", func(t *testing.T) {
+		mockVoidRepo := new(MockTransactionVoidRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewTransactionVoidService(mockVoidRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(3)).Return(voidTestUser(), nil)
+		mockVoidRepo.On("GetTransactionBillingForSchool", uint(11), uint(1)).Return(paidKasir, nil)
+		mockVoidRepo.On("HasWaitingVoid", uint(11)).Return(false, nil)
+		mockVoidRepo.On("CreateVoid", mock.Anything).Return(nil)
+
+		transactionVoid, err := service.CreateVoid(&request.CreateVoidRequest{TransactionBillingId: 11, Reason: "Salah pilih siswa"}, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, repositories.VoidStatusWaitingApproval, transactionVoid.VoidStatus)
+		assert.Equal(t, uint(1), transactionVoid.SchoolID)
+		assert.Equal(t, 3, transactionVoid.CreatedBy)
+	})
+
+	t.Run("Reason required", func(t *testing.T) {
+		mockVoidRepo := new(MockTransactionVoidRepository)
+		service := NewTransactionVoidService(mockVoidRepo, new(MockUserRepository))
+
+		_, err := service.CreateVoid(&request.CreateVoidRequest{TransactionBillingId: 11}, 3)
+
+		assert.Error(t, err)
+		mockVoidRepo.AssertNotCalled(t, "CreateVoid", mock.Anything)
+	})
+
+	t.Run("Online payment cannot be voided", func(t *testing.T) {
+		mockVoidRepo := new(MockTransactionVoidRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewTransactionVoidService(mockVoidRepo, mockUserRepo)
+
+		online := &models.TransactionBilling{TransactionType: "PT02", TransactionStatus: "PS02"}
+		mockUserRepo.On("GetUserByID", uint(3)).Return(voidTestUser(), nil)
+		mockVoidRepo.On("GetTransactionBillingForSchool", uint(12), uint(1)).Return(online, nil)
+
+		_, err := service.CreateVoid(&request.CreateVoidRequest{TransactionBillingId: 12, Reason: "Salah"}, 3)
+
+		assert.Equal(t, repositories.ErrTransactionNotVoidable, err)
+	})
+
+	t.Run("Transaction of another school", func(t *testing.T) {
+		mockVoidRepo := new(MockTransactionVoidRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewTransactionVoidService(mockVoidRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(3)).Return(voidTestUser(), nil)
+		mockVoidRepo.On("GetTransactionBillingForSchool", uint(13), uint(1)).Return(nil, errors.New("record not found"))
+
+		_, err := service.CreateVoid(&request.CreateVoidRequest{TransactionBillingId: 13, Reason: "Salah"}, 3)
+
+		assert.EqualError(t, err, "transaction not found")
+	})
+
+	t.Run("Void already waiting", func(t *testing.T) {
+		mockVoidRepo := new(MockTransactionVoidRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewTransactionVoidService(mockVoidRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(3)).Return(voidTestUser(), nil)
+		mockVoidRepo.On("GetTransactionBillingForSchool", uint(11), uint(1)).Return(paidKasir, nil)
+		mockVoidRepo.On("HasWaitingVoid", uint(11)).Return(true, nil)
+
+		_, err := service.CreateVoid(&request.CreateVoidRequest{TransactionBillingId: 11, Reason: "Salah"}, 3)
+
+		assert.EqualError(t, err, "transaction already has a void waiting for approval")
+	})
+}
+
+func TestApproveVoid(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockVoidRepo := new(MockTransactionVoidRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewTransactionVoidService(mockVoidRepo, mockUserRepo)
+
+		transactionVoid := &models.TransactionVoid{TransactionBillingID: 11, VoidStatus: repositories.VoidStatusWaitingApproval}
+		transactionVoid.CreatedBy = 3
+		mockUserRepo.On("GetUserByID", uint(5)).Return(voidTestUser(), nil)
+		mockVoidRepo.On("GetVoidByID", uint(2), uint(1)).Return(transactionVoid, nil)
+		mockVoidRepo.On("ApplyVoid", transactionVoid, 5).Return(nil)
+
+		_, err := service.ApproveVoid(2, 5)
+
+		assert.NoError(t, err)
+		mockVoidRepo.AssertExpectations(t)
+	})
+
+	t.Run("Requester cannot approve", func(t *testing.T) {
+		mockVoidRepo := new(MockTransactionVoidRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewTransactionVoidService(mockVoidRepo, mockUserRepo)
+
+		transactionVoid := &models.TransactionVoid{TransactionBillingID: 11, VoidStatus: repositories.VoidStatusWaitingApproval}
+		transactionVoid.CreatedBy = 5
+		mockUserRepo.On("GetUserByID", uint(5)).Return(voidTestUser(), nil)
+		mockVoidRepo.On("GetVoidByID", uint(2), uint(1)).Return(transactionVoid, nil)
+
+		_, err := service.ApproveVoid(2, 5)
+
+		assert.EqualError(t, err, "void must be approved by another user")
+		mockVoidRepo.AssertNotCalled(t, "ApplyVoid", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Already processed", func(t *testing.T) {
+		mockVoidRepo := new(MockTransactionVoidRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := NewTransactionVoidService(mockVoidRepo, mockUserRepo)
+
+		mockUserRepo.On("GetUserByID", uint(5)).Return(voidTestUser(), nil)
+		mockVoidRepo.On("GetVoidByID", uint(2), uint(1)).Return(&models.TransactionVoid{VoidStatus: repositories.VoidStatusRejected}, nil)
+
+		_, err := service.ApproveVoid(2, 5)
+
+		assert.EqualError(t, err, "void has already been processed")
+	})
+}
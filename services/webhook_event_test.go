@@ -0,0 +1,225 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	database "schoolPayment/configs"
+	"schoolPayment/dtos/request"
+	"schoolPayment/models"
+	"schoolPayment/repositories"
+	"schoolPayment/services"
+	"schoolPayment/utilities"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// MockWebhookEventRepository is a mock implementation of the WebhookEventRepository interface
+type MockWebhookEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebhookEventRepository) CreateOrGetWebhookEvent(event *models.WebhookEvent) (*models.WebhookEvent, bool, error) {
+	args := m.Called(event)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*models.WebhookEvent), args.Bool(1), args.Error(2)
+}
+
+func (m *MockWebhookEventRepository) ClaimWebhookEvent(id uint) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWebhookEventRepository) MarkWebhookEventProcessed(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookEventRepository) MarkWebhookEventFailed(id uint, errorMessage string) error {
+	args := m.Called(id, errorMessage)
+	return args.Error(0)
+}
+
+func (m *MockWebhookEventRepository) HasProcessedWebhookEventForStatus(orderID string, paymentStatus string, excludeID uint) (bool, error) {
+	args := m.Called(orderID, paymentStatus, excludeID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWebhookEventRepository) GetAllWebhookEvent(page int, limit int, processStatus string, orderID string) ([]models.WebhookEvent, int64, error) {
+	args := m.Called(page, limit, processStatus, orderID)
+	return args.Get(0).([]models.WebhookEvent), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockWebhookEventRepository) GetWebhookEventByID(id uint) (*models.WebhookEvent, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookEvent), args.Error(1)
+}
+
+// mockSchoolPaymentConfigByOrder points database.DB at a sqlmock that answers the lookup of
+// the order's school payment configuration. A nil config means the school has no own credentials.
+func mockSchoolPaymentConfigByOrder(t *testing.T, config *models.SchoolPaymentConfig) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db, DriverName: "postgres"}), &gorm.Config{})
+	assert.NoError(t, err)
+	database.DB = gormDB
+
+	rows := sqlmock.NewRows([]string{"id", "school_id", "payment_gateway", "midtrans_server_key", "is_production"})
+	if config != nil {
+		rows.AddRow(config.ID, config.SchoolID, config.PaymentGateway, config.MidtransServerKey, config.IsProduction)
+	}
+	dbMock.ExpectQuery("from school_payment_configs").WithArgs("ORDER-1").WillReturnRows(rows)
+}
+
+func signedWebhookPayload(t *testing.T, transactionStatus string, statusCode string) request.WebhookPayload {
+	t.Setenv("SERVER_KEY", "test-server-key")
+	mockSchoolPaymentConfigByOrder(t, nil)
+	payload := request.WebhookPayload{
+		OrderID:           "ORDER-1",
+		TransactionStatus: transactionStatus,
+		StatusCode:        statusCode,
+		GrossAmount:       "150000.00",
+	}
+	payload.SignatureKey = utilities.GenerateSignature(payload.OrderID, payload.StatusCode, payload.GrossAmount, "test-server-key")
+	return payload
+}
+
+func TestUpdateFromWebHook_DuplicateEventIsSkipped(t *testing.T) {
+	mockWebhookEventRepo := new(MockWebhookEventRepository)
+	service := services.NewTransactionService(repositories.TransactionRepository{}, nil, nil, nil, nil, nil, nil, mockWebhookEventRepo)
+
+	payload := signedWebhookPayload(t, "settlement", "200")
+	processed := &models.WebhookEvent{EventKey: "ORDER-1:settlement:200", ProcessStatus: repositories.WebhookEventStatusProcessed}
+	processed.ID = 9
+
+	mockWebhookEventRepo.On("CreateOrGetWebhookEvent", mock.MatchedBy(func(event *models.WebhookEvent) bool {
+		return event.EventKey == "ORDER-1:settlement:200" && event.PaymentStatus == "PS02"
+	})).Return(processed, false, nil)
+
+	err := service.UpdateFromWebHook(payload, nil)
+
+	assert.NoError(t, err)
+	mockWebhookEventRepo.AssertNotCalled(t, "ClaimWebhookEvent", mock.Anything)
+}
+
+func TestUpdateFromWebHook_EventClaimedByAnotherRetry(t *testing.T) {
+	mockWebhookEventRepo := new(MockWebhookEventRepository)
+	service := services.NewTransactionService(repositories.TransactionRepository{}, nil, nil, nil, nil, nil, nil, mockWebhookEventRepo)
+
+	payload := signedWebhookPayload(t, "pending", "201")
+	received := &models.WebhookEvent{EventKey: "ORDER-1:pending:201", ProcessStatus: repositories.WebhookEventStatusReceived}
+	received.ID = 10
+
+	mockWebhookEventRepo.On("CreateOrGetWebhookEvent", mock.Anything).Return(received, true, nil)
+	mockWebhookEventRepo.On("ClaimWebhookEvent", uint(10)).Return(false, nil)
+
+	err := service.UpdateFromWebHook(payload, nil)
+
+	assert.NoError(t, err)
+	mockWebhookEventRepo.AssertNotCalled(t, "MarkWebhookEventProcessed", mock.Anything)
+	mockWebhookEventRepo.AssertNotCalled(t, "MarkWebhookEventFailed", mock.Anything, mock.Anything)
+}
+
+func TestUpdateFromWebHook_UnknownStatusIsLogged(t *testing.T) {
+	mockWebhookEventRepo := new(MockWebhookEventRepository)
+	service := services.NewTransactionService(repositories.TransactionRepository{}, nil, nil, nil, nil, nil, nil, mockWebhookEventRepo)
+
+	payload := signedWebhookPayload(t, "authorize", "201")
+	received := &models.WebhookEvent{EventKey: "ORDER-1:authorize:201", ProcessStatus: repositories.WebhookEventStatusReceived}
+	received.ID = 11
+
+	mockWebhookEventRepo.On("CreateOrGetWebhookEvent", mock.MatchedBy(func(event *models.WebhookEvent) bool {
+		return event.EventKey == "ORDER-1:authorize:201" && event.PaymentStatus == ""
+	})).Return(received, true, nil)
+	mockWebhookEventRepo.On("MarkWebhookEventFailed", uint(11), "unknown status: authorize").Return(nil)
+
+	err := service.UpdateFromWebHook(payload, nil)
+
+	assert.NoError(t, err)
+	mockWebhookEventRepo.AssertExpectations(t)
+	mockWebhookEventRepo.AssertNotCalled(t, "ClaimWebhookEvent", mock.Anything)
+}
+
+func TestUpdateFromWebHook_InvalidSignature(t *testing.T) {
+	mockWebhookEventRepo := new(MockWebhookEventRepository)
+	service := services.NewTransactionService(repositories.TransactionRepository{}, nil, nil, nil, nil, nil, nil, mockWebhookEventRepo)
+
+	payload := signedWebhookPayload(t, "settlement", "200")
+	payload.SignatureKey = "tampered"
+
+	err := service.UpdateFromWebHook(payload, nil)
+
+	assert.Error(t, err)
+	mockWebhookEventRepo.AssertNotCalled(t, "CreateOrGetWebhookEvent", mock.Anything)
+}
+
+func TestUpdateFromWebHook_UsesSchoolServerKey(t *testing.T) {
+	t.Setenv("PAYMENT_CREDENTIAL_KEY", "test-credential-key")
+	mockWebhookEventRepo := new(MockWebhookEventRepository)
+	service := services.NewTransactionService(repositories.TransactionRepository{}, nil, nil, nil, nil, nil, nil, mockWebhookEventRepo)
+
+	// Signed with the shared sandbox key while the school has its own merchant account
+	payload := signedWebhookPayload(t, "settlement", "200")
+	encryptedServerKey, err := utilities.EncryptCredential("school-server-key")
+	assert.NoError(t, err)
+	mockSchoolPaymentConfigByOrder(t, &models.SchoolPaymentConfig{SchoolID: 1, PaymentGateway: "midtrans", MidtransServerKey: encryptedServerKey})
+
+	err = service.UpdateFromWebHook(payload, nil)
+
+	assert.Error(t, err)
+	mockWebhookEventRepo.AssertNotCalled(t, "CreateOrGetWebhookEvent", mock.Anything)
+}
+
+func TestReplayWebhookEvent_OnlyFailedEvents(t *testing.T) {
+	mockWebhookEventRepo := new(MockWebhookEventRepository)
+	service := services.NewTransactionService(repositories.TransactionRepository{}, nil, nil, nil, nil, nil, nil, mockWebhookEventRepo)
+
+	processed := &models.WebhookEvent{ProcessStatus: repositories.WebhookEventStatusProcessed}
+	mockWebhookEventRepo.On("GetWebhookEventByID", uint(3)).Return(processed, nil)
+
+	_, err := service.ReplayWebhookEvent(3, nil)
+
+	assert.Equal(t, errors.New("only failed webhook events can be replayed"), err)
+	mockWebhookEventRepo.AssertNotCalled(t, "ClaimWebhookEvent", mock.Anything)
+}
+
+func TestReplayWebhookEvent_StuckEvent(t *testing.T) {
+	mockWebhookEventRepo := new(MockWebhookEventRepository)
+	service := services.NewTransactionService(repositories.TransactionRepository{}, nil, nil, nil, nil, nil, nil, mockWebhookEventRepo)
+
+	stuck := &models.WebhookEvent{ProcessStatus: repositories.WebhookEventStatusProcessing, Payload: "{}"}
+	stuck.ID = 4
+	stuck.UpdatedAt = time.Now().Add(-time.Hour)
+	mockWebhookEventRepo.On("GetWebhookEventByID", uint(4)).Return(stuck, nil)
+	mockWebhookEventRepo.On("ClaimWebhookEvent", uint(4)).Return(false, nil)
+
+	_, err := service.ReplayWebhookEvent(4, nil)
+
+	assert.NoError(t, err)
+	mockWebhookEventRepo.AssertCalled(t, "ClaimWebhookEvent", uint(4))
+}
+
+func TestReplayWebhookEvent_EventStillProcessing(t *testing.T) {
+	mockWebhookEventRepo := new(MockWebhookEventRepository)
+	service := services.NewTransactionService(repositories.TransactionRepository{}, nil, nil, nil, nil, nil, nil, mockWebhookEventRepo)
+
+	processing := &models.WebhookEvent{ProcessStatus: repositories.WebhookEventStatusProcessing}
+	processing.UpdatedAt = time.Now()
+	mockWebhookEventRepo.On("GetWebhookEventByID", uint(5)).Return(processing, nil)
+
+	_, err := service.ReplayWebhookEvent(5, nil)
+
+	assert.Equal(t, errors.New("only failed webhook events can be replayed"), err)
+	mockWebhookEventRepo.AssertNotCalled(t, "ClaimWebhookEvent", mock.Anything)
+}
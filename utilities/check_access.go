@@ -24,6 +24,21 @@ func CheckAccessAdminSekolah(c *fiber.Ctx) error {
 	return nil
 }
 
+func CheckAccessSuperAdmin(c *fiber.Ctx) error {
+	userClaims := c.Locals("user").(jwt.MapClaims)
+	roleID := int(userClaims["role_id"].(float64))
+	_, err := repositories.GetRoleByID(uint(roleID))
+	if err != nil {
+		return err
+	}
+
+	if roleID != 1 {
+		return fmt.Errorf(constants.MessageUserCantAccessPage)
+	}
+
+	return nil
+}
+
 func CheckAccessToBillingType(c *fiber.Ctx) error {
 	// Get user information from the token claims stored in context
 	userClaims := c.Locals("user").(jwt.MapClaims)
@@ -0,0 +1,66 @@
+package utilities
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+)
+
+// credentialCipher builds an AES-256-GCM cipher from PAYMENT_CREDENTIAL_KEY.
+func credentialCipher() (cipher.AEAD, error) {
+	secret := os.Getenv("PAYMENT_CREDENTIAL_KEY")
+	if secret == "" {
+		return nil, errors.New("PAYMENT_CREDENTIAL_KEY is not set")
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptCredential encrypts a payment gateway secret for storage. The nonce is prepended
+// to the ciphertext and the result is base64 encoded.
+func EncryptCredential(plainText string) (string, error) {
+	gcm, err := credentialCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plainText), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func DecryptCredential(cipherText string) (string, error) {
+	gcm, err := credentialCipher()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(cipherText)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("invalid encrypted credential")
+	}
+
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plainText, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plainText), nil
+}
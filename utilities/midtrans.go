@@ -6,23 +6,24 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
+	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	database "schoolPayment/configs"
 	request "schoolPayment/dtos/request"
 	"schoolPayment/dtos/response"
 	models "schoolPayment/models"
 	"schoolPayment/repositories"
 
+	"github.com/gofiber/fiber/v2"
 	"github.com/midtrans/midtrans-go"
 	"github.com/midtrans/midtrans-go/coreapi"
 	"github.com/midtrans/midtrans-go/snap"
 )
 
 var (
-	s             snap.Client
-	c             coreapi.Client
 	errorMidtrans *midtrans.Error
 )
 
@@ -76,39 +77,616 @@ func CalculateAdminFee(billingAmount int64, paymentMethod *models.PaymentMethod)
 	return adminFee, nil
 }
 
-func SendRequestPayment(orderID string, studendID, billingAmount, paymentMethodId int, billingStudentIds []string, invoiceNumber string, listAccountNumber []string, listBillingId []int, bankName string, userId int) (*snap.Response, error) {
-	s.New(os.Getenv("SERVER_KEY"), midtrans.Sandbox)
+// CalculateAdminFeeSplit calculates the admin fee of the payment method and splits it between
+// the parent and the school by the school's setting.
+func CalculateAdminFeeSplit(schoolID uint, chargeAmount int64, paymentMethod *models.PaymentMethod) (repositories.AdminFeeSplit, error) {
+	adminFee, err := CalculateAdminFee(chargeAmount, paymentMethod)
+	if err != nil {
+		return repositories.AdminFeeSplit{}, err
+	}
+
+	bearer, parentPercentage, err := repositories.GetAdminFeeSetting(schoolID, paymentMethod.ID)
+	if err != nil {
+		return repositories.AdminFeeSplit{}, err
+	}
+	return repositories.SplitAdminFee(adminFee, bearer, parentPercentage), nil
+}
+
+// SendRequestPayment opens the online payment on the gateway configured for the student's school
+// and records the pending transaction. Student credit reduces the amount charged at the gateway,
+// and the parent's part of the admin fee is added to it.
+func SendRequestPayment(orderID string, studendID, billingAmount, paymentMethodId int, billingStudentIds []string, listAccountNumber []string, listBillingId []int, bankName string, userId int, creditAmount int64) (*PaymentGatewayChargeResult, error) {
+	return sendPayment(orderID, studendID, billingAmount, paymentMethodId, billingStudentIds, listAccountNumber, listBillingId, bankName, userId, creditAmount, false)
+}
+
+// SendDirectPayment is SendRequestPayment without the gateway's payment page: the result carries
+// the payment instruction instead. The transaction is recorded the same way, so its status still
+// arrives through the webhook.
+func SendDirectPayment(orderID string, studendID, billingAmount, paymentMethodId int, billingStudentIds []string, listAccountNumber []string, listBillingId []int, bankName string, userId int, creditAmount int64) (*PaymentGatewayChargeResult, error) {
+	return sendPayment(orderID, studendID, billingAmount, paymentMethodId, billingStudentIds, listAccountNumber, listBillingId, bankName, userId, creditAmount, true)
+}
+
+func sendPayment(orderID string, studendID, billingAmount, paymentMethodId int, billingStudentIds []string, listAccountNumber []string, listBillingId []int, bankName string, userId int, creditAmount int64, direct bool) (*PaymentGatewayChargeResult, error) {
+	if creditAmount < 0 {
+		return nil, fmt.Errorf("credit amount cannot be negative")
+	}
+	// The gateway cannot open a charge of zero, a bill fully covered by credit is paid at the cashier
+	if creditAmount >= int64(billingAmount) {
+		return nil, fmt.Errorf("credit covers the whole amount, please pay it at the cashier")
+	}
 
 	paymentMethod, err := repositories.GetPaymentMethodByID(paymentMethodId)
 	if err != nil {
 		return nil, err
 	}
 
-	// Map the payment method to SnapPaymentType
-	enabledPayment := mapToSnapPaymentType(paymentMethod)
-	if enabledPayment == "" {
-		return nil, fmt.Errorf("unsupported payment method or bank code")
+	school, err := repositories.GetSchoolByStudentId(uint(studendID))
+	if err != nil {
+		return nil, err
+	}
+
+	chargeAmount := int64(billingAmount) - creditAmount
+	adminFee, err := CalculateAdminFeeSplit(school.ID, chargeAmount, paymentMethod)
+	if err != nil {
+		return nil, err
 	}
 
-	// Calculate the admin fee using the helper function
-	adminFee, err := CalculateAdminFee(int64(billingAmount), paymentMethod)
+	totalAmount := chargeAmount + adminFee.ParentAdminFee
+
+	gateway, err := GetPaymentGatewayByPaymentMethod(school.ID, paymentMethod.ID)
 	if err != nil {
 		return nil, err
 	}
+	createCharge := gateway.CreateCharge
+	if direct {
+		directCharger, ok := gateway.(PaymentGatewayDirectCharger)
+		if !ok {
+			return nil, fmt.Errorf("payment gateway %s does not support direct charges", gateway.Name())
+		}
+		createCharge = directCharger.CreateDirectCharge
+	}
 
-	totalAmount := int64(billingAmount) + adminFee
+	expiry, finishURL, err := paymentPageSettings(school.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := paymentGatewayItems(billingStudentIds, creditAmount, adminFee.ParentAdminFee, totalAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	student, err := repositories.GetStudentByIDOnlyStudent(uint(studendID))
+	if err != nil {
+		return nil, err
+	}
+
+	vaNumber, err := repositories.GetStudentVirtualAccountNumber(uint(studendID), paymentMethodId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lock the installments before charging so a concurrent checkout gets a clear error
+	// instead of a second charge for the same installments
+	err = repositories.ReserveBillingStudentsForOrder(billingStudentIds, orderID, time.Now().Add(expiry))
+	if err != nil {
+		return nil, err
+	}
+
+	if creditAmount > 0 {
+		if err := repositories.UseStudentCreditForOrder(uint(studendID), orderID, creditAmount, userId); err != nil {
+			releaseBillingStudentReservation(orderID, userId)
+			return nil, err
+		}
+	}
+
+	resp, err := createCharge(PaymentGatewayCharge{
+		SchoolID:      school.ID,
+		OrderID:       orderID,
+		Amount:        totalAmount,
+		Description:   "Payment for Billing",
+		PaymentMethod: paymentMethod,
+		Expiry:        expiry,
+		Items:         items,
+		Customer:      paymentGatewayCustomer(userId, student),
+		CustomFields:  []string{school.SchoolCode, student.Nis, student.FullName},
+		FinishURL:     finishURL,
+		VANumber:      vaNumber,
+	})
+	if err != nil {
+		releaseBillingStudentReservation(orderID, userId)
+		return nil, err
+	}
+
+	errPayment := repositories.SaveLogPaymentMidtrans(orderID, resp.RequestBody, resp.ResponseBody)
+	if errPayment != nil {
+		releaseBillingStudentReservation(orderID, userId)
+		return nil, errPayment
+	}
+
+	errTransaction := repositories.CreateTransactionBilling(orderID, studendID, billingAmount, paymentMethodId, billingStudentIds, school.ID, listAccountNumber, listBillingId, bankName, userId, gateway.Name(), creditAmount, adminFee)
+	if errTransaction != nil {
+		releaseBillingStudentReservation(orderID, userId)
+		return nil, errTransaction
+	}
+
+	return resp, nil
+}
+
+// SendGroupRequestPayment opens one payment page for the installments of several children of the
+// same school and records a transaction for each child under the group of the order. The parent
+// pays a single admin fee. Student credit is not used by a combined checkout.
+func SendGroupRequestPayment(orderID string, parts []repositories.GroupTransactionBillingPart, paymentMethodId int, bankName string, userId int) (*PaymentGatewayChargeResult, error) {
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("billingStudentIds is required")
+	}
+
+	paymentMethod, err := repositories.GetPaymentMethodByID(paymentMethodId)
+	if err != nil {
+		return nil, err
+	}
+
+	var school *models.School
+	var billingAmount int64
+	var billingStudentIds, nisList, names []string
+	var firstStudent *models.Student
+	for _, part := range parts {
+		partSchool, err := repositories.GetSchoolByStudentId(part.StudentID)
+		if err != nil {
+			return nil, err
+		}
+		if school == nil {
+			school = partSchool
+		} else if partSchool.ID != school.ID {
+			return nil, fmt.Errorf("students of different schools cannot be paid in one checkout")
+		}
+
+		student, err := repositories.GetStudentByIDOnlyStudent(part.StudentID)
+		if err != nil {
+			return nil, err
+		}
+		if firstStudent == nil {
+			firstStudent = student
+		}
+
+		billingAmount += int64(part.BillingAmount)
+		billingStudentIds = append(billingStudentIds, part.BillingStudentIds...)
+		nisList = append(nisList, student.Nis)
+		names = append(names, student.FullName)
+	}
+
+	adminFee, err := CalculateAdminFeeSplit(school.ID, billingAmount, paymentMethod)
+	if err != nil {
+		return nil, err
+	}
+	totalAmount := billingAmount + adminFee.ParentAdminFee
+
+	gateway, err := GetPaymentGatewayByPaymentMethod(school.ID, paymentMethod.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	expiry, finishURL, err := paymentPageSettings(school.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := paymentGatewayItems(billingStudentIds, 0, adminFee.ParentAdminFee, totalAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	// One reservation holds the installments of every child
+	err = repositories.ReserveBillingStudentsForOrder(billingStudentIds, orderID, time.Now().Add(expiry))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := gateway.CreateCharge(PaymentGatewayCharge{
+		SchoolID:      school.ID,
+		OrderID:       orderID,
+		Amount:        totalAmount,
+		Description:   "Payment for Billing",
+		PaymentMethod: paymentMethod,
+		Expiry:        expiry,
+		Items:         items,
+		Customer:      paymentGatewayCustomer(userId, firstStudent),
+		CustomFields:  []string{school.SchoolCode, strings.Join(nisList, ","), strings.Join(names, ", ")},
+		FinishURL:     finishURL,
+	})
+	if err != nil {
+		releaseBillingStudentReservation(orderID, userId)
+		return nil, err
+	}
+
+	errPayment := repositories.SaveLogPaymentMidtrans(orderID, resp.RequestBody, resp.ResponseBody)
+	if errPayment != nil {
+		releaseBillingStudentReservation(orderID, userId)
+		return nil, errPayment
+	}
+
+	errTransaction := repositories.CreateGroupTransactionBilling(orderID, parts, paymentMethodId, school.ID, bankName, userId, gateway.Name(), adminFee)
+	if errTransaction != nil {
+		releaseBillingStudentReservation(orderID, userId)
+		return nil, errTransaction
+	}
+
+	return resp, nil
+}
+
+// paymentPageSettings returns how long the school's payment page stays open and where it sends
+// the parent when done.
+func paymentPageSettings(schoolID uint) (time.Duration, string, error) {
+	config, err := repositories.GetSchoolPaymentConfig(schoolID)
+	if err != nil {
+		return 0, "", err
+	}
+	expiry := repositories.BillingStudentReservationTTL
+	finishURL := ""
+	if config != nil {
+		if config.PaymentExpiryMinutes > 0 {
+			expiry = time.Duration(config.PaymentExpiryMinutes) * time.Minute
+		}
+		finishURL = config.FinishRedirectURL
+	}
+	return expiry, finishURL, nil
+}
+
+// paymentGatewayItems lists what the parent pays for: the remaining balance of each installment,
+// the credit used as a negative item and the parent's admin fee. The gateway rejects items that do
+// not add up to the charged amount, so any difference is sent as an adjustment item.
+func paymentGatewayItems(billingStudentIds []string, creditAmount, parentAdminFee, totalAmount int64) ([]PaymentGatewayItem, error) {
+	ids := make([]int, 0, len(billingStudentIds))
+	for _, billingStudentID := range billingStudentIds {
+		id, err := strconv.Atoi(billingStudentID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid billing student id: %s", billingStudentID)
+		}
+		ids = append(ids, id)
+	}
+
+	billingStudents, err := repositories.GetBillingStudentsForPayment(database.DB, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]PaymentGatewayItem, 0, len(billingStudents)+2)
+	for _, billingStudent := range billingStudents {
+		items = append(items, PaymentGatewayItem{
+			ID:       strconv.Itoa(int(billingStudent.ID)),
+			Name:     billingStudent.DetailBillingName,
+			Category: "Tagihan",
+			Price:    billingStudent.Amount - billingStudent.PaidAmount,
+			Quantity: 1,
+		})
+	}
+	if creditAmount > 0 {
+		items = append(items, PaymentGatewayItem{ID: "CREDIT", Name: "Saldo kredit", Category: "Kredit", Price: -creditAmount, Quantity: 1})
+	}
+	if parentAdminFee > 0 {
+		items = append(items, PaymentGatewayItem{ID: "ADMIN-FEE", Name: "Biaya admin", Category: "Biaya admin", Price: parentAdminFee, Quantity: 1})
+	}
+
+	return balancePaymentGatewayItems(items, totalAmount), nil
+}
+
+// balancePaymentGatewayItems adds an adjustment item for whatever the items miss or exceed of the
+// charged amount, so the breakdown is kept and the difference shows on the payment page.
+func balancePaymentGatewayItems(items []PaymentGatewayItem, totalAmount int64) []PaymentGatewayItem {
+	var itemTotal int64
+	for _, item := range items {
+		itemTotal += item.Price * int64(item.Quantity)
+	}
+	if difference := totalAmount - itemTotal; difference != 0 {
+		fmt.Printf("payment gateway items add up to %d instead of %d, adding an adjustment\n", itemTotal, totalAmount)
+		items = append(items, PaymentGatewayItem{ID: "ADJUSTMENT", Name: "Penyesuaian", Category: "Penyesuaian", Price: difference, Quantity: 1})
+	}
+	return items
+}
+
+// paymentGatewayCustomer describes the guardian who checks out. Missing guardian data falls back
+// to the login and the student so a lookup failure never blocks the payment. When staff make the
+// order, e.g. through a payment link created by TU, the guardian of the student is the customer
+// and the staff member's own name and email are never sent.
+func paymentGatewayCustomer(userID int, student *models.Student) *PaymentGatewayCustomer {
+	customer := &PaymentGatewayCustomer{Phone: student.NoHandphone}
+
+	customerUserID := uint(userID)
+	user, err := repositories.GetUserByID2(customerUserID)
+	// Role 2 is the parent login
+	if err == nil && user.RoleID != 2 {
+		user, err = repositories.GetEmailParentById(int(student.ID))
+		customerUserID = user.ID
+	}
+	if err == nil {
+		customer.FirstName = user.Username
+		customer.Email = user.Email
+	}
+
+	parent, err := repositories.GetParentByUserLogin(customerUserID)
+	if err == nil && parent != nil {
+		if parent.ParentName != "" {
+			customer.FirstName = parent.ParentName
+		}
+		if customer.Email == "" {
+			customer.Email = parent.ParentMail
+		}
+		if parent.ParentHandphone > 0 {
+			customer.Phone = strconv.Itoa(parent.ParentHandphone)
+		}
+	}
+
+	if customer.FirstName == "" {
+		customer.FirstName = student.FullName
+	}
+	return customer
+}
+
+// releaseBillingStudentReservation undoes what a failed checkout held: the installments and any
+// credit it spent.
+func releaseBillingStudentReservation(orderID string, userID int) {
+	if err := repositories.ReleaseBillingStudentReservation(database.DB, orderID); err != nil {
+		fmt.Printf("failed to release reservation of order %s: %v\n", orderID, err)
+	}
+	if err := repositories.ReverseStudentCreditUsage(database.DB, orderID, userID); err != nil {
+		fmt.Printf("failed to give back credit of order %s: %v\n", orderID, err)
+	}
+}
+
+// Snap rejects item names and ids longer than this, and custom fields longer than 255 characters.
+const (
+	snapItemFieldLength   = 50
+	snapCustomFieldLength = 255
+)
+
+// newSnapRequest builds the Snap request of a charge with its item breakdown, the guardian as
+// customer, the expiry, the finish redirect and up to three custom fields.
+func newSnapRequest(charge PaymentGatewayCharge) (*snap.Request, error) {
+	enabledPayment := mapToSnapPaymentType(charge.PaymentMethod)
+	if enabledPayment == "" {
+		return nil, fmt.Errorf("unsupported payment method or bank code")
+	}
 
-	// Request body
 	req := &snap.Request{
 		TransactionDetails: midtrans.TransactionDetails{
-			OrderID:  orderID,
-			GrossAmt: totalAmount,
+			OrderID:  charge.OrderID,
+			GrossAmt: charge.Amount,
 		},
 		CreditCard: &snap.CreditCardDetails{
 			Secure: true,
 		},
 		EnabledPayments: []snap.SnapPaymentType{enabledPayment},
 	}
+	if charge.Expiry > 0 {
+		req.Expiry = &snap.ExpiryDetails{
+			Unit:     "minute",
+			Duration: int64(charge.Expiry / time.Minute),
+		}
+	}
+
+	req.Items = midtransItemDetails(charge.Items)
+	req.CustomerDetail = midtransCustomerDetails(charge.Customer)
+
+	if charge.FinishURL != "" {
+		req.Callbacks = &snap.Callbacks{Finish: charge.FinishURL}
+	}
+
+	if charge.VANumber != "" {
+		switch enabledPayment {
+		case snap.PaymentTypeBCAVA:
+			req.BcaVa = &snap.BcaVa{VaNumber: charge.VANumber}
+		case snap.PaymentTypeBNIVA:
+			req.BniVa = &snap.BniVa{VaNumber: charge.VANumber}
+		case snap.PaymentTypeBRIVA:
+			req.BriVa = &snap.BriVa{VaNumber: charge.VANumber}
+		}
+	}
+
+	customFields := []*string{&req.CustomField1, &req.CustomField2, &req.CustomField3}
+	for i, value := range charge.CustomFields {
+		if i == len(customFields) {
+			break
+		}
+		*customFields[i] = truncateText(value, snapCustomFieldLength)
+	}
+
+	return req, nil
+}
+
+func midtransItemDetails(chargeItems []PaymentGatewayItem) *[]midtrans.ItemDetails {
+	if len(chargeItems) == 0 {
+		return nil
+	}
+	items := make([]midtrans.ItemDetails, 0, len(chargeItems))
+	for _, item := range chargeItems {
+		items = append(items, midtrans.ItemDetails{
+			ID:       truncateText(item.ID, snapItemFieldLength),
+			Name:     truncateText(item.Name, snapItemFieldLength),
+			Category: truncateText(item.Category, snapItemFieldLength),
+			Price:    item.Price,
+			Qty:      item.Quantity,
+		})
+	}
+	return &items
+}
+
+func midtransCustomerDetails(customer *PaymentGatewayCustomer) *midtrans.CustomerDetails {
+	if customer == nil {
+		return nil
+	}
+	return &midtrans.CustomerDetails{
+		FName: customer.FirstName,
+		LName: customer.LastName,
+		Email: customer.Email,
+		Phone: customer.Phone,
+	}
+}
+
+// newCoreApiChargeRequest builds the Core API charge of a payment method the app can show
+// itself: a bank virtual account, a Mandiri bill, QRIS or an e-wallet deeplink. Cards need the
+// card to be tokenised on the payment page, so they stay on Snap.
+func newCoreApiChargeRequest(charge PaymentGatewayCharge) (*coreapi.ChargeReq, error) {
+	req := &coreapi.ChargeReq{
+		TransactionDetails: midtrans.TransactionDetails{
+			OrderID:  charge.OrderID,
+			GrossAmt: charge.Amount,
+		},
+		Items:           midtransItemDetails(charge.Items),
+		CustomerDetails: midtransCustomerDetails(charge.Customer),
+	}
+
+	switch charge.PaymentMethod.PaymentMethod {
+	case "VA":
+		bank := mapToMidtransBank(charge.PaymentMethod.BankCode)
+		switch bank {
+		case "":
+			return nil, fmt.Errorf("unsupported payment method or bank code")
+		case midtrans.BankMandiri:
+			req.PaymentType = coreapi.PaymentTypeEChannel
+			req.EChannel = &coreapi.EChannelDetail{BillInfo1: "Tagihan:", BillInfo2: truncateText(charge.Description, 30)}
+		default:
+			req.PaymentType = coreapi.PaymentTypeBankTransfer
+			req.BankTransfer = &coreapi.BankTransferDetails{Bank: bank, VaNumber: charge.VANumber}
+		}
+	case "QR":
+		req.PaymentType = coreapi.PaymentTypeQris
+		req.Qris = &coreapi.QrisDetails{Acquirer: "gopay"}
+	case "EW":
+		switch strings.ToLower(charge.PaymentMethod.BankCode) {
+		case "gopay":
+			req.PaymentType = coreapi.PaymentTypeGopay
+			req.Gopay = &coreapi.GopayDetails{EnableCallback: charge.FinishURL != "", CallbackUrl: charge.FinishURL}
+		case "shopeepay":
+			req.PaymentType = coreapi.PaymentTypeShopeepay
+			req.ShopeePay = &coreapi.ShopeePayDetails{CallbackUrl: charge.FinishURL}
+		default:
+			return nil, fmt.Errorf("unsupported payment method or bank code")
+		}
+	default:
+		return nil, fmt.Errorf("payment method %s is not available for direct charges", charge.PaymentMethod.PaymentMethod)
+	}
+
+	if charge.Expiry > 0 {
+		req.CustomExpiry = &coreapi.CustomExpiry{
+			ExpiryDuration: int(charge.Expiry / time.Minute),
+			Unit:           "minute",
+		}
+	}
+
+	customFields := []**string{&req.CustomField1, &req.CustomField2, &req.CustomField3}
+	for i, value := range charge.CustomFields {
+		if i == len(customFields) {
+			break
+		}
+		customField := truncateText(value, snapCustomFieldLength)
+		*customFields[i] = &customField
+	}
+
+	return req, nil
+}
+
+// staticVirtualAccountLengths is the length of the custom virtual account number each bank
+// accepts. Other banks only give out one-off numbers.
+var staticVirtualAccountLengths = map[midtrans.Bank]int{
+	midtrans.BankBca: 11,
+	midtrans.BankBni: 8,
+	midtrans.BankBri: 13,
+}
+
+// StaticVirtualAccountBank returns the Midtrans bank of a virtual account payment method and the
+// length of the fixed number it accepts.
+func StaticVirtualAccountBank(paymentMethod *models.PaymentMethod) (string, int, error) {
+	if paymentMethod.PaymentMethod != "VA" {
+		return "", 0, fmt.Errorf("fixed virtual accounts need a virtual account payment method")
+	}
+	bank := mapToMidtransBank(paymentMethod.BankCode)
+	length, ok := staticVirtualAccountLengths[bank]
+	if !ok {
+		return "", 0, fmt.Errorf("bank %s does not support fixed virtual accounts", paymentMethod.BankName)
+	}
+	return string(bank), length, nil
+}
+
+// mapToMidtransBank maps the bank code of a virtual account payment method to the Core API bank.
+func mapToMidtransBank(bankCode string) midtrans.Bank {
+	switch bankCode {
+	case "002":
+		return midtrans.BankBri
+	case "008":
+		return midtrans.BankMandiri
+	case "009":
+		return midtrans.BankBni
+	case "014":
+		return midtrans.BankBca
+	case "013":
+		return midtrans.BankPermata
+	case "022":
+		return midtrans.BankCimb
+	}
+	return ""
+}
+
+// newPaymentGatewayInstruction reads what the parent needs to pay from a Core API charge response.
+func newPaymentGatewayInstruction(resp *coreapi.ChargeResponse) *PaymentGatewayInstruction {
+	instruction := &PaymentGatewayInstruction{
+		PaymentType: resp.PaymentType,
+		GrossAmount: resp.GrossAmount,
+		BillerCode:  resp.BillerCode,
+		BillKey:     resp.BillKey,
+		QRString:    resp.QRString,
+		ExpiryTime:  resp.ExpiryTime,
+	}
+
+	if len(resp.VaNumbers) > 0 {
+		instruction.Bank = resp.VaNumbers[0].Bank
+		instruction.VANumber = resp.VaNumbers[0].VANumber
+	} else if resp.PermataVaNumber != "" {
+		instruction.Bank = string(midtrans.BankPermata)
+		instruction.VANumber = resp.PermataVaNumber
+	} else if resp.BillKey != "" {
+		instruction.Bank = string(midtrans.BankMandiri)
+	}
+
+	for _, action := range resp.Actions {
+		switch action.Name {
+		case "generate-qr-code":
+			instruction.QRImageURL = action.URL
+		case "deeplink-redirect":
+			instruction.Deeplink = action.URL
+		}
+	}
+	return instruction
+}
+
+// truncateText cuts the text to at most length characters.
+func truncateText(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return string(runes[:length])
+}
+
+// midtransGateway is the PaymentGateway backed by Midtrans Snap and Core API.
+type midtransGateway struct{}
+
+func (gateway *midtransGateway) Name() string {
+	return PaymentGatewayMidtrans
+}
+
+func (gateway *midtransGateway) CreateCharge(charge PaymentGatewayCharge) (*PaymentGatewayChargeResult, error) {
+	credential, err := GetMidtransCredentialBySchoolID(charge.SchoolID)
+	if err != nil {
+		return nil, err
+	}
+
+	var s snap.Client
+	s.New(credential.ServerKey, credential.Environment)
+
+	req, err := newSnapRequest(charge)
+	if err != nil {
+		return nil, err
+	}
 
 	resp, errorMidtrans := s.CreateTransaction(req)
 	if errorMidtrans != nil {
@@ -125,23 +703,136 @@ func SendRequestPayment(orderID string, studendID, billingAmount, paymentMethodI
 		fmt.Println("Error:", err)
 	}
 
-	errPayment := repositories.SaveLogPaymentMidtrans(orderID, req, string(userBytes))
-	if errPayment != nil {
-		return nil, errPayment
+	return &PaymentGatewayChargeResult{
+		Token:        resp.Token,
+		RedirectURL:  resp.RedirectURL,
+		RequestBody:  req,
+		ResponseBody: string(userBytes),
+	}, nil
+}
+
+// CreateDirectCharge opens the payment through the Core API and returns its instruction instead
+// of a Snap page. Midtrans sends the notifications of both to the same webhook.
+func (gateway *midtransGateway) CreateDirectCharge(charge PaymentGatewayCharge) (*PaymentGatewayChargeResult, error) {
+	credential, err := GetMidtransCredentialBySchoolID(charge.SchoolID)
+	if err != nil {
+		return nil, err
 	}
 
-	errTransaction := repositories.CreateTransactionBilling(orderID, studendID, billingAmount, paymentMethodId, billingStudentIds, invoiceNumber, listAccountNumber, listBillingId, bankName, userId)
-	if errTransaction != nil {
-		return nil, errTransaction
+	var c coreapi.Client
+	c.New(credential.ServerKey, credential.Environment)
+
+	req, err := newCoreApiChargeRequest(charge)
+	if err != nil {
+		return nil, err
 	}
 
-	return resp, nil
+	resp, errorMidtrans := c.ChargeTransaction(req)
+	if errorMidtrans != nil {
+		apiError, err := ExtractErrorMessage(errorMidtrans.Message)
+		if err != nil {
+			return nil, err
+		}
+		return nil, errors.New(apiError.StatusMessage)
+	}
+	// A charge the gateway refused still comes back as a response, only 201 is a pending payment
+	if resp.StatusCode != strconv.Itoa(fiber.StatusCreated) {
+		return nil, errors.New(resp.StatusMessage)
+	}
+
+	responseBody, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	instruction := newPaymentGatewayInstruction(resp)
+	return &PaymentGatewayChargeResult{
+		Token:        resp.TransactionID,
+		RedirectURL:  instruction.Deeplink,
+		RequestBody:  req,
+		ResponseBody: string(responseBody),
+		Instruction:  instruction,
+	}, nil
+}
+
+func (gateway *midtransGateway) CheckStatus(orderID string) (*PaymentGatewayStatus, error) {
+	res, err := CheckTransaction(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+
+	// The status response carries the same fields as the HTTP notification
+	var notification request.WebhookPayload
+	if err := json.Unmarshal(responseBody, &notification); err != nil {
+		return nil, err
+	}
+
+	return &PaymentGatewayStatus{
+		OrderID:           orderID,
+		TransactionStatus: res.TransactionStatus,
+		GrossAmount:       res.GrossAmount,
+		ResponseBody:      string(responseBody),
+		Notification:      notification,
+	}, nil
+}
+
+func (gateway *midtransGateway) Cancel(orderID string) error {
+	_, err := CancelTransaction(orderID)
+	return err
+}
+
+func (gateway *midtransGateway) Refund(orderID string, refundKey string, amount int64, reason string) (string, error) {
+	res, err := RefundTransaction(orderID, refundKey, amount, reason)
+	if err != nil {
+		return "", err
+	}
+
+	responseBody, err := json.Marshal(res)
+	if err != nil {
+		return "", err
+	}
+	return string(responseBody), nil
+}
+
+func (gateway *midtransGateway) VerifyNotification(ctx *fiber.Ctx) (*request.WebhookPayload, error) {
+	var payload request.WebhookPayload
+	if err := ctx.BodyParser(&payload); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateMidtransNotification(payload); err != nil {
+		return nil, err
+	}
+	return &payload, nil
+}
+
+// newCoreApiClientByOrderID returns a Core API client for the merchant of the order's school.
+func newCoreApiClientByOrderID(orderID string) (*coreapi.Client, error) {
+	credential, err := GetMidtransCredentialByOrderID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	var c coreapi.Client
+	c.New(credential.ServerKey, credential.Environment)
+	return &c, nil
 }
 
 func CheckTransaction(orderID string) (*coreapi.TransactionStatusResponse, error) {
-	c.New(os.Getenv("SERVER_KEY"), midtrans.Sandbox)
+	c, err := newCoreApiClientByOrderID(orderID)
+	if err != nil {
+		return nil, err
+	}
 	res, errorMidtrans := c.CheckTransaction(orderID)
 	if errorMidtrans != nil {
+		if errorMidtrans.StatusCode == http.StatusNotFound {
+			return nil, ErrPaymentGatewayOrderNotFound
+		}
 		apiError, err := ExtractErrorMessage(errorMidtrans.Message)
 		if err != nil {
 			return nil, err
@@ -151,7 +842,7 @@ func CheckTransaction(orderID string) (*coreapi.TransactionStatusResponse, error
 	}
 	fmt.Println("Response: ", res)
 
-	err := repositories.SaveLogCheckPaymentMidtrans(orderID, res)
+	err = repositories.SaveLogCheckPaymentMidtrans(orderID, res)
 	if err != nil {
 		return nil, err
 	}
@@ -165,7 +856,10 @@ func CheckTransaction(orderID string) (*coreapi.TransactionStatusResponse, error
 }
 
 func CancelTransaction(orderID string) (*coreapi.CancelResponse, error) {
-	c.New(os.Getenv("SERVER_KEY"), midtrans.Sandbox)
+	c, err := newCoreApiClientByOrderID(orderID)
+	if err != nil {
+		return nil, err
+	}
 	res, errorMidtrans := c.CancelTransaction(orderID)
 	if errorMidtrans != nil {
 		apiError, err := ExtractErrorMessage(errorMidtrans.Message)
@@ -179,18 +873,28 @@ func CancelTransaction(orderID string) (*coreapi.CancelResponse, error) {
 	return res, nil
 }
 
-func RefundTransaction(orderID string) *coreapi.RefundResponse {
+func RefundTransaction(orderID string, refundKey string, amount int64, reason string) (*coreapi.RefundResponse, error) {
+	c, err := newCoreApiClientByOrderID(orderID)
+	if err != nil {
+		return nil, err
+	}
 	refundRequest := &coreapi.RefundReq{
-		Amount: 5000,
-		Reason: "Item out of stock",
+		RefundKey: refundKey,
+		Amount:    amount,
+		Reason:    reason,
 	}
 
-	res, err := c.RefundTransaction(orderID, refundRequest)
-	if err != nil {
-		// do something on error handle
+	res, errorMidtrans := c.RefundTransaction(orderID, refundRequest)
+	if errorMidtrans != nil {
+		apiError, err := ExtractErrorMessage(errorMidtrans.Message)
+		if err != nil {
+			return nil, err
+		}
+		err = errors.New(apiError.StatusMessage)
+		return nil, err
 	}
-	fmt.Println("Response: ", res)
-	return nil
+
+	return res, nil
 }
 
 func ExtractErrorMessage(errorMessage string) (*response.MidtransExtractAPIError, error) {
@@ -221,6 +925,11 @@ func ChangeStatusTransactionFromWebhook(payload request.WebhookPayload) error {
 		return err
 	}
 
+	posted, err := postStaticVirtualAccountPayment(payload)
+	if err != nil || posted {
+		return err
+	}
+
 	err = repositories.UpdateTransactionBilling(payload) // Update the status
 	if err != nil {
 		return err
@@ -229,6 +938,36 @@ func ChangeStatusTransactionFromWebhook(payload request.WebhookPayload) error {
 	return nil
 }
 
+// postStaticVirtualAccountPayment books a payment into a student's fixed virtual account that
+// no checkout opened. The school bears the admin fee, the parent decided what to transfer.
+func postStaticVirtualAccountPayment(payload request.WebhookPayload) (bool, error) {
+	virtualAccount, err := repositories.GetStudentVirtualAccountOfPayment(payload)
+	if err != nil || virtualAccount == nil {
+		return false, err
+	}
+
+	exists, err := repositories.TransactionBillingExists(payload.OrderID)
+	if err != nil || exists {
+		return false, err
+	}
+
+	paymentMethod, err := repositories.GetPaymentMethodByID(int(virtualAccount.MasterPaymentMethodID))
+	if err != nil {
+		return false, err
+	}
+	grossAmount, err := strconv.ParseFloat(payload.GrossAmount, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid gross amount: %s", payload.GrossAmount)
+	}
+	fee, err := CalculateAdminFee(int64(grossAmount), paymentMethod)
+	if err != nil {
+		return false, err
+	}
+
+	adminFee := repositories.SplitAdminFee(fee, repositories.AdminFeeBearerSchool, 0)
+	return true, repositories.PostStaticVirtualAccountPayment(virtualAccount, payload, adminFee)
+}
+
 func GenerateSignature(orderID, statusCode, grossAmount, serverKey string) string {
 	// Gabungkan data
 	rawSignature := orderID + statusCode + grossAmount + serverKey
@@ -0,0 +1,92 @@
+package utilities
+
+import (
+	"os"
+
+	request "schoolPayment/dtos/request"
+	models "schoolPayment/models"
+	"schoolPayment/repositories"
+
+	"github.com/midtrans/midtrans-go"
+)
+
+// MidtransCredential is the merchant account used to talk to Midtrans for one school.
+type MidtransCredential struct {
+	ServerKey   string
+	ClientKey   string
+	MerchantID  string
+	Environment midtrans.EnvironmentType
+}
+
+// midtransCredentialFromConfig decrypts the school's credentials. Schools without their own
+// server key keep using the shared sandbox merchant from the environment.
+func midtransCredentialFromConfig(config *models.SchoolPaymentConfig) (*MidtransCredential, error) {
+	if config == nil || config.MidtransServerKey == "" {
+		return &MidtransCredential{
+			ServerKey:   os.Getenv("SERVER_KEY"),
+			ClientKey:   os.Getenv("CLIENT_KEY"),
+			MerchantID:  os.Getenv("MERCHANT_ID"),
+			Environment: midtrans.Sandbox,
+		}, nil
+	}
+
+	serverKey, err := DecryptCredential(config.MidtransServerKey)
+	if err != nil {
+		return nil, err
+	}
+
+	environment := midtrans.Sandbox
+	if config.IsProduction {
+		environment = midtrans.Production
+	}
+
+	return &MidtransCredential{
+		ServerKey:   serverKey,
+		ClientKey:   config.MidtransClientKey,
+		MerchantID:  config.MidtransMerchantID,
+		Environment: environment,
+	}, nil
+}
+
+func GetMidtransCredentialBySchoolID(schoolID uint) (*MidtransCredential, error) {
+	config, err := repositories.GetSchoolPaymentConfig(schoolID)
+	if err != nil {
+		return nil, err
+	}
+	return midtransCredentialFromConfig(config)
+}
+
+func GetMidtransCredentialByOrderID(orderID string) (*MidtransCredential, error) {
+	config, err := repositories.GetSchoolPaymentConfigByOrderID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	return midtransCredentialFromConfig(config)
+}
+
+// ValidateMidtransNotification checks the notification signature against the server key of
+// the school that owns the order, or of the school whose fixed virtual account was paid.
+func ValidateMidtransNotification(payload request.WebhookPayload) error {
+	config, err := repositories.GetSchoolPaymentConfigByOrderID(payload.OrderID)
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		virtualAccount, err := repositories.GetStudentVirtualAccountOfPayment(payload)
+		if err != nil {
+			return err
+		}
+		if virtualAccount != nil {
+			config, err = repositories.GetSchoolPaymentConfig(virtualAccount.SchoolID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	credential, err := midtransCredentialFromConfig(config)
+	if err != nil {
+		return err
+	}
+	return ValidateSignature(payload.OrderID, payload.StatusCode, payload.GrossAmount, credential.ServerKey, payload.SignatureKey)
+}
@@ -0,0 +1,229 @@
+package utilities
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	models "schoolPayment/models"
+
+	"github.com/midtrans/midtrans-go"
+	"github.com/midtrans/midtrans-go/coreapi"
+	"github.com/midtrans/midtrans-go/snap"
+)
+
+func TestNewSnapRequest(t *testing.T) {
+	charge := PaymentGatewayCharge{
+		OrderID:       "INV-001",
+		Amount:        154000,
+		PaymentMethod: &models.PaymentMethod{PaymentMethod: "VA", BankCode: "014"},
+		Expiry:        90 * time.Minute,
+		Items: []PaymentGatewayItem{
+			{ID: "11", Name: "SPP " + strings.Repeat("Juli ", 20), Category: "Tagihan", Price: 150000, Quantity: 1},
+			{ID: "ADMIN-FEE", Name: "Biaya admin", Category: "Biaya admin", Price: 4000, Quantity: 1},
+		},
+		Customer:     &PaymentGatewayCustomer{FirstName: "Budi", Email: "budi@example.com", Phone: "08123456789"},
+		CustomFields: []string{"SCH01", "12345", "Andi", "ignored"},
+		FinishURL:    "https://sekolah.example/bayar/selesai",
+	}
+
+	req, err := newSnapRequest(charge)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if req.TransactionDetails.GrossAmt != 154000 {
+		t.Errorf("expected gross amount 154000, got %d", req.TransactionDetails.GrossAmt)
+	}
+	if req.EnabledPayments[0] != snap.PaymentTypeBCAVA {
+		t.Errorf("expected bca_va, got %s", req.EnabledPayments[0])
+	}
+	if req.Expiry == nil || req.Expiry.Duration != 90 {
+		t.Errorf("expected an expiry of 90 minutes, got %+v", req.Expiry)
+	}
+	if req.Items == nil || len(*req.Items) != 2 {
+		t.Fatalf("expected 2 items, got %+v", req.Items)
+	}
+	items := *req.Items
+	if len(items[0].Name) != snapItemFieldLength {
+		t.Errorf("expected the item name cut to %d characters, got %d", snapItemFieldLength, len(items[0].Name))
+	}
+	if items[1].Price != 4000 || items[1].Qty != 1 {
+		t.Errorf("expected the admin fee item of 4000, got %+v", items[1])
+	}
+	if req.CustomerDetail == nil || req.CustomerDetail.FName != "Budi" || req.CustomerDetail.Phone != "08123456789" {
+		t.Errorf("unexpected customer details %+v", req.CustomerDetail)
+	}
+	if req.Callbacks == nil || req.Callbacks.Finish != "https://sekolah.example/bayar/selesai" {
+		t.Errorf("unexpected callbacks %+v", req.Callbacks)
+	}
+	if req.CustomField1 != "SCH01" || req.CustomField2 != "12345" || req.CustomField3 != "Andi" {
+		t.Errorf("unexpected custom fields %q %q %q", req.CustomField1, req.CustomField2, req.CustomField3)
+	}
+}
+
+func TestNewSnapRequest_WithoutDetails(t *testing.T) {
+	req, err := newSnapRequest(PaymentGatewayCharge{
+		OrderID:       "INV-002",
+		Amount:        50000,
+		PaymentMethod: &models.PaymentMethod{PaymentMethod: "VA", BankCode: "014"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if req.Items != nil || req.CustomerDetail != nil || req.Callbacks != nil || req.Expiry != nil {
+		t.Errorf("expected no optional details, got %+v", req)
+	}
+}
+
+func TestNewSnapRequest_UnsupportedPaymentMethod(t *testing.T) {
+	_, err := newSnapRequest(PaymentGatewayCharge{
+		OrderID:       "INV-003",
+		Amount:        50000,
+		PaymentMethod: &models.PaymentMethod{PaymentMethod: "CASH"},
+	})
+	if err == nil || err.Error() != "unsupported payment method or bank code" {
+		t.Errorf("expected unsupported payment method error, got %v", err)
+	}
+}
+
+func TestNewCoreApiChargeRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		paymentMethod *models.PaymentMethod
+		paymentType   coreapi.CoreapiPaymentType
+		wantErr       string
+	}{
+		{name: "BCA virtual account", paymentMethod: &models.PaymentMethod{PaymentMethod: "VA", BankCode: "014"}, paymentType: coreapi.PaymentTypeBankTransfer},
+		{name: "Mandiri bill", paymentMethod: &models.PaymentMethod{PaymentMethod: "VA", BankCode: "008"}, paymentType: coreapi.PaymentTypeEChannel},
+		{name: "QRIS", paymentMethod: &models.PaymentMethod{PaymentMethod: "QR"}, paymentType: coreapi.PaymentTypeQris},
+		{name: "GoPay", paymentMethod: &models.PaymentMethod{PaymentMethod: "EW", BankCode: "GOPAY"}, paymentType: coreapi.PaymentTypeGopay},
+		{name: "Unknown bank", paymentMethod: &models.PaymentMethod{PaymentMethod: "VA", BankCode: "999"}, wantErr: "unsupported payment method or bank code"},
+		{name: "Credit card", paymentMethod: &models.PaymentMethod{PaymentMethod: "CC"}, wantErr: "payment method CC is not available for direct charges"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := newCoreApiChargeRequest(PaymentGatewayCharge{
+				OrderID:       "INV-004",
+				Amount:        150000,
+				Description:   "Payment for Billing",
+				PaymentMethod: tt.paymentMethod,
+				Expiry:        time.Hour,
+				CustomFields:  []string{"SCH01", "12345"},
+			})
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if req.PaymentType != tt.paymentType {
+				t.Errorf("expected payment type %s, got %s", tt.paymentType, req.PaymentType)
+			}
+			if req.CustomExpiry == nil || req.CustomExpiry.ExpiryDuration != 60 {
+				t.Errorf("expected an expiry of 60 minutes, got %+v", req.CustomExpiry)
+			}
+			if req.CustomField1 == nil || *req.CustomField1 != "SCH01" || req.CustomField3 != nil {
+				t.Errorf("unexpected custom fields %v %v", req.CustomField1, req.CustomField3)
+			}
+		})
+	}
+}
+
+func TestNewPaymentGatewayInstruction(t *testing.T) {
+	t.Run("Virtual account", func(t *testing.T) {
+		instruction := newPaymentGatewayInstruction(&coreapi.ChargeResponse{
+			PaymentType: "bank_transfer",
+			GrossAmount: "150000.00",
+			VaNumbers:   []coreapi.VANumber{{Bank: "bca", VANumber: "12345678901"}},
+			ExpiryTime:  "2026-10-19 10:00:00",
+		})
+		if instruction.Bank != "bca" || instruction.VANumber != "12345678901" || instruction.ExpiryTime != "2026-10-19 10:00:00" {
+			t.Errorf("unexpected instruction %+v", instruction)
+		}
+	})
+
+	t.Run("Permata virtual account", func(t *testing.T) {
+		instruction := newPaymentGatewayInstruction(&coreapi.ChargeResponse{PaymentType: "bank_transfer", PermataVaNumber: "8562000001"})
+		if instruction.Bank != string(midtrans.BankPermata) || instruction.VANumber != "8562000001" {
+			t.Errorf("unexpected instruction %+v", instruction)
+		}
+	})
+
+	t.Run("Mandiri bill", func(t *testing.T) {
+		instruction := newPaymentGatewayInstruction(&coreapi.ChargeResponse{PaymentType: "echannel", BillerCode: "70012", BillKey: "990000001"})
+		if instruction.Bank != string(midtrans.BankMandiri) || instruction.BillerCode != "70012" || instruction.BillKey != "990000001" {
+			t.Errorf("unexpected instruction %+v", instruction)
+		}
+	})
+
+	t.Run("GoPay", func(t *testing.T) {
+		instruction := newPaymentGatewayInstruction(&coreapi.ChargeResponse{
+			PaymentType: "gopay",
+			Actions: []coreapi.Action{
+				{Name: "generate-qr-code", URL: "https://api.midtrans.com/v2/gopay/1/qr-code"},
+				{Name: "deeplink-redirect", URL: "gojek://gopay/merchanttransfer?tref=1"},
+			},
+		})
+		if instruction.QRImageURL != "https://api.midtrans.com/v2/gopay/1/qr-code" || instruction.Deeplink != "gojek://gopay/merchanttransfer?tref=1" {
+			t.Errorf("unexpected instruction %+v", instruction)
+		}
+	})
+}
+
+func TestStaticVirtualAccountBank(t *testing.T) {
+	bank, length, err := StaticVirtualAccountBank(&models.PaymentMethod{PaymentMethod: "VA", BankCode: "009"})
+	if err != nil || bank != "bni" || length != 8 {
+		t.Errorf("expected bni with 8 digits, got %s %d %v", bank, length, err)
+	}
+
+	if _, _, err := StaticVirtualAccountBank(&models.PaymentMethod{PaymentMethod: "VA", BankCode: "008", BankName: "Mandiri"}); err == nil || err.Error() != "bank Mandiri does not support fixed virtual accounts" {
+		t.Errorf("expected Mandiri to be rejected, got %v", err)
+	}
+	if _, _, err := StaticVirtualAccountBank(&models.PaymentMethod{PaymentMethod: "QR"}); err == nil {
+		t.Error("expected QRIS to be rejected")
+	}
+}
+
+func TestNewSnapRequest_FixedVirtualAccount(t *testing.T) {
+	req, err := newSnapRequest(PaymentGatewayCharge{
+		OrderID:       "INV-005",
+		Amount:        150000,
+		Description:   "Payment for Billing",
+		PaymentMethod: &models.PaymentMethod{PaymentMethod: "VA", BankCode: "014"},
+		Expiry:        time.Hour,
+		VANumber:      "88002024017",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if req.BcaVa == nil || req.BcaVa.VaNumber != "88002024017" {
+		t.Errorf("expected the fixed BCA number, got %+v", req.BcaVa)
+	}
+}
+
+func TestBalancePaymentGatewayItems(t *testing.T) {
+	items := []PaymentGatewayItem{
+		{ID: "11", Name: "SPP Juli", Category: "Tagihan", Price: 150000, Quantity: 1},
+		{ID: "CREDIT", Name: "Saldo kredit", Category: "Kredit", Price: -20000, Quantity: 1},
+	}
+
+	balanced := balancePaymentGatewayItems(items, 130000)
+	if len(balanced) != 2 {
+		t.Fatalf("expected no adjustment for matching items, got %+v", balanced)
+	}
+
+	// The installment changed after the charge amount was worked out
+	balanced = balancePaymentGatewayItems(items, 125000)
+	if len(balanced) != 3 {
+		t.Fatalf("expected an adjustment item, got %+v", balanced)
+	}
+	adjustment := balanced[2]
+	if adjustment.ID != "ADJUSTMENT" || adjustment.Price != -5000 || adjustment.Quantity != 1 {
+		t.Errorf("expected an adjustment of -5000, got %+v", adjustment)
+	}
+}
@@ -0,0 +1,151 @@
+package utilities
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	request "schoolPayment/dtos/request"
+	models "schoolPayment/models"
+	"schoolPayment/repositories"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	PaymentGatewayMidtrans = "midtrans"
+	PaymentGatewayXendit   = "xendit"
+)
+
+// PaymentGatewayCharge is the provider independent request to open an online payment.
+type PaymentGatewayCharge struct {
+	SchoolID      uint
+	OrderID       string
+	Amount        int64
+	Description   string
+	PaymentMethod *models.PaymentMethod
+	// Expiry is how long the payment stays open, zero keeps the gateway default
+	Expiry time.Duration
+	// Items break the amount down on the gateway's payment page; they add up to Amount
+	Items    []PaymentGatewayItem
+	Customer *PaymentGatewayCustomer
+	// CustomFields are passed through to the gateway's reports, at most three are sent
+	CustomFields []string
+	// FinishURL is where the parent is sent back to after paying, empty keeps the gateway default
+	FinishURL string
+	// VANumber is the student's fixed virtual account number, empty lets the gateway pick one
+	VANumber string
+}
+
+// PaymentGatewayItem is one line of the charge. Student credit is a line with a negative price.
+type PaymentGatewayItem struct {
+	ID       string
+	Name     string
+	Category string
+	Price    int64
+	Quantity int32
+}
+
+// PaymentGatewayCustomer is the guardian paying the charge.
+type PaymentGatewayCustomer struct {
+	FirstName string
+	LastName  string
+	Email     string
+	Phone     string
+}
+
+// PaymentGatewayChargeResult carries what the parent needs to continue the payment, plus the
+// raw request and response so they can be logged.
+type PaymentGatewayChargeResult struct {
+	Token        string
+	RedirectURL  string
+	RequestBody  interface{}
+	ResponseBody string
+	// Instruction is only set by a direct charge, the parent pays with it instead of the RedirectURL
+	Instruction *PaymentGatewayInstruction
+}
+
+// PaymentGatewayInstruction is how the parent completes a direct charge: a virtual account
+// number, a Mandiri bill key, a QRIS string or an e-wallet deeplink, depending on the method.
+type PaymentGatewayInstruction struct {
+	PaymentType string
+	GrossAmount string
+	Bank        string
+	VANumber    string
+	BillerCode  string
+	BillKey     string
+	QRString    string
+	QRImageURL  string
+	Deeplink    string
+	ExpiryTime  string
+}
+
+// PaymentGatewayStatus is a payment status normalised to the Midtrans transaction_status
+// vocabulary (pending, settlement, expire, cancel, ...) used by the transaction state machine.
+type PaymentGatewayStatus struct {
+	OrderID           string
+	TransactionStatus string
+	GrossAmount       string
+	ResponseBody      string
+	// Notification is the status in the shape of a webhook notification, so it can be applied
+	// through the same path as a notification the gateway pushed.
+	Notification request.WebhookPayload
+}
+
+// ErrPaymentGatewayOrderNotFound is returned by CheckStatus when the gateway has no record of the order.
+var ErrPaymentGatewayOrderNotFound = errors.New("order not found at payment gateway")
+
+type PaymentGateway interface {
+	Name() string
+	CreateCharge(charge PaymentGatewayCharge) (*PaymentGatewayChargeResult, error)
+	CheckStatus(orderID string) (*PaymentGatewayStatus, error)
+	Cancel(orderID string) error
+	Refund(orderID string, refundKey string, amount int64, reason string) (string, error)
+	// VerifyNotification authenticates an incoming notification and converts it to a WebhookPayload.
+	VerifyNotification(c *fiber.Ctx) (*request.WebhookPayload, error)
+}
+
+// PaymentGatewayDirectCharger is implemented by gateways that can open a payment without their
+// hosted payment page, so the mobile app can show the payment instructions itself.
+type PaymentGatewayDirectCharger interface {
+	CreateDirectCharge(charge PaymentGatewayCharge) (*PaymentGatewayChargeResult, error)
+}
+
+var paymentGateways = map[string]PaymentGateway{
+	PaymentGatewayMidtrans: &midtransGateway{},
+	PaymentGatewayXendit:   &xenditGateway{},
+}
+
+// RegisterPaymentGateway adds a provider to the registry, replacing one with the same name.
+func RegisterPaymentGateway(gateway PaymentGateway) {
+	paymentGateways[gateway.Name()] = gateway
+}
+
+func IsSupportedPaymentGateway(provider string) bool {
+	_, ok := paymentGateways[provider]
+	return ok
+}
+
+// GetPaymentGateway returns the gateway for a provider name. An empty name means Midtrans,
+// which was the only provider before schools could choose one.
+func GetPaymentGateway(provider string) (PaymentGateway, error) {
+	if provider == "" {
+		provider = PaymentGatewayMidtrans
+	}
+
+	gateway, ok := paymentGateways[provider]
+	if !ok {
+		return nil, fmt.Errorf("unsupported payment gateway: %s", provider)
+	}
+	return gateway, nil
+}
+
+// GetPaymentGatewayByPaymentMethod returns the gateway the school uses for the payment method,
+// which is the school's gateway unless the payment method has its own.
+func GetPaymentGatewayByPaymentMethod(schoolID uint, paymentMethodID uint) (PaymentGateway, error) {
+	provider, err := repositories.GetPaymentMethodGateway(schoolID, paymentMethodID)
+	if err != nil {
+		return nil, err
+	}
+	return GetPaymentGateway(provider)
+}
@@ -0,0 +1,98 @@
+package utilities
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// NewPaymentLinkCode returns the random part of a payment link.
+func NewPaymentLinkCode() (string, error) {
+	code := make([]byte, 16)
+	if _, err := rand.Read(code); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(code), nil
+}
+
+// SignPaymentLink returns the token of a payment link: the code and an HMAC of it, so a token
+// cannot be made up from a guessed code.
+func SignPaymentLink(code string) (string, error) {
+	signature, err := paymentLinkSignature(code)
+	if err != nil {
+		return "", err
+	}
+	return code + "." + signature, nil
+}
+
+// VerifyPaymentLink checks the signature of a payment link token and returns its code.
+func VerifyPaymentLink(token string) (string, error) {
+	code, signature, found := strings.Cut(token, ".")
+	if !found || code == "" {
+		return "", errors.New("invalid payment link")
+	}
+
+	expected, err := paymentLinkSignature(code)
+	if err != nil {
+		return "", err
+	}
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return "", errors.New("invalid payment link")
+	}
+	return code, nil
+}
+
+func paymentLinkSignature(code string) (string, error) {
+	secretKey := os.Getenv("SECRET_KEY")
+	if secretKey == "" {
+		return "", errors.New("SECRET_KEY is not set")
+	}
+
+	hmacHash := hmac.New(sha256.New, []byte(secretKey))
+	hmacHash.Write([]byte("payment-link:" + code))
+	return base64.RawURLEncoding.EncodeToString(hmacHash.Sum(nil)), nil
+}
+
+// PaymentLinkURL is the page a payment link token opens, under PAYMENT_LINK_URL.
+func PaymentLinkURL(token string) (string, error) {
+	baseURL := os.Getenv("PAYMENT_LINK_URL")
+	if baseURL == "" {
+		return "", errors.New("PAYMENT_LINK_URL is not set")
+	}
+	return strings.TrimRight(baseURL, "/") + "/" + token, nil
+}
+
+// PaymentLinkWhatsAppText is the message a payment link is shared with.
+func PaymentLinkWhatsAppText(schoolName string, studentName string, totalAmount int64, expiresAt time.Time, linkURL string) string {
+	return fmt.Sprintf("Tagihan %s untuk %s sebesar %s.\nSilakan bayar melalui tautan berikut sebelum %s:\n%s",
+		schoolName,
+		studentName,
+		RupiahFormat(big.NewInt(totalAmount)),
+		expiresAt.Format("02-01-2006 15:04"),
+		linkURL,
+	)
+}
+
+// WhatsAppShareURL opens WhatsApp with the text ready to be sent to any contact.
+func WhatsAppShareURL(text string) string {
+	return "https://wa.me/?text=" + url.QueryEscape(text)
+}
+
+// MaskName keeps the first letter of each word of a name, e.g. "Budi Santoso" becomes "B*** S******".
+func MaskName(name string) string {
+	words := strings.Fields(name)
+	for i, word := range words {
+		letters := []rune(word)
+		words[i] = string(letters[0]) + strings.Repeat("*", len(letters)-1)
+	}
+	return strings.Join(words, " ")
+}
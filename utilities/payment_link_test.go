@@ -0,0 +1,58 @@
+package utilities
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignPaymentLink(t *testing.T) {
+	t.Setenv("SECRET_KEY", "test-secret")
+
+	token, err := SignPaymentLink("abc123")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	code, err := VerifyPaymentLink(token)
+	if err != nil || code != "abc123" {
+		t.Errorf("expected code abc123, got %q %v", code, err)
+	}
+
+	if _, err := VerifyPaymentLink("abd123" + token[len("abc123"):]); err == nil {
+		t.Error("expected a token with another code to be rejected")
+	}
+	if _, err := VerifyPaymentLink("abc123"); err == nil {
+		t.Error("expected a token without signature to be rejected")
+	}
+
+	t.Setenv("SECRET_KEY", "other-secret")
+	if _, err := VerifyPaymentLink(token); err == nil {
+		t.Error("expected a token signed with another key to be rejected")
+	}
+}
+
+func TestMaskName(t *testing.T) {
+	if got := MaskName("Budi  Santoso"); got != "B*** S******" {
+		t.Errorf("expected B*** S******, got %q", got)
+	}
+	if got := MaskName(""); got != "" {
+		t.Errorf("expected an empty name, got %q", got)
+	}
+}
+
+func TestPaymentLinkWhatsAppText(t *testing.T) {
+	expiresAt := time.Date(2026, 3, 5, 14, 30, 0, 0, time.UTC)
+	text := PaymentLinkWhatsAppText("SD Harapan", "Budi Santoso", 1500000, expiresAt, "https://bayar.example/abc.def")
+
+	for _, want := range []string{"SD Harapan", "Budi Santoso", "Rp 1.500.000", "05-03-2026 14:30", "https://bayar.example/abc.def"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in %q", want, text)
+		}
+	}
+
+	shareURL := WhatsAppShareURL(text)
+	if !strings.HasPrefix(shareURL, "https://wa.me/?text=") || strings.Contains(shareURL, " ") {
+		t.Errorf("unexpected share url %q", shareURL)
+	}
+}
@@ -40,6 +40,25 @@ func renderRow(pdf *gofpdf.Fpdf, label string, value string, labelFontSize, valu
 	pdf.Ln(yOffset)
 }
 
+// renderVoidMark prints the void label under the title and a large diagonal mark over the page.
+func renderVoidMark(pdf *gofpdf.Fpdf) {
+	x, y := pdf.GetXY()
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.SetTextColor(200, 0, 0)
+	pdf.CellFormat(190, 6, "DIBATALKAN (VOID)", "0", 1, "C", false, 0, "")
+
+	pdf.TransformBegin()
+	pdf.TransformRotate(35, 105, 160)
+	pdf.SetFont("Arial", "B", 90)
+	pdf.SetTextColor(235, 170, 170)
+	pdf.Text(55, 170, "VOID")
+	pdf.TransformEnd()
+
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetXY(x, y+6)
+}
+
 func GeneratePDF(c *fiber.Ctx, dataInvoice []response.RespDataInvoice, school models.School, isPrint bool) (string, error) {
 	ext := filepath.Ext(school.SchoolLetterhead)
 	schoolLogo := ConvertPath(school.SchoolLetterhead)
@@ -135,6 +154,9 @@ func GeneratePDF(c *fiber.Ctx, dataInvoice []response.RespDataInvoice, school mo
 	pdf.Ln(5)
 	pdf.SetFont("Arial", "B", 16)
 	pdf.CellFormat(190, 10, "BUKTI PEMBAYARAN SISWA", "0", 1, "C", false, 0, "")
+	if dataInvoice[0].IsVoid {
+		renderVoidMark(pdf)
+	}
 	pdf.SetLineWidth(0.5)
 	pdf.Line(10, pdf.GetY()+2, 200, pdf.GetY()+2)
 	pdf.Ln(10)
@@ -228,7 +250,13 @@ func GeneratePDF(c *fiber.Ctx, dataInvoice []response.RespDataInvoice, school mo
 	pdf.CellFormat(95, 6, "Catatan:", "0", 1, "", false, 0, "")
 	pdf.SetTextColor(0, 0, 0)
 	pdf.SetFont("Arial", "B", 10)
-	pdf.MultiCell(0, 5, " - Disimpan sebagai bukti pembayaran yang SAH\n - Uang yang sudah dibayarkan tidak dapat diminta kembali.", "", "L", false)
+	if dataInvoice[0].IsVoid {
+		pdf.SetTextColor(200, 0, 0)
+		pdf.MultiCell(0, 5, " - Pembayaran ini telah DIBATALKAN dan bukan bukti pembayaran yang SAH\n - Alasan pembatalan: "+dataInvoice[0].VoidReason, "", "L", false)
+		pdf.SetTextColor(0, 0, 0)
+	} else {
+		pdf.MultiCell(0, 5, " - Disimpan sebagai bukti pembayaran yang SAH\n - Uang yang sudah dibayarkan tidak dapat diminta kembali.", "", "L", false)
+	}
 
 	// Output the PDF
 	// err = pdf.Output(c.Response().BodyWriter())
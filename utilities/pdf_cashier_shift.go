@@ -0,0 +1,121 @@
+package utilities
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	response "schoolPayment/dtos/response"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// GenerateCashierShiftReportPDF renders the end-of-shift cash report the Tata Usaha office uses
+// to reconcile the daily deposit.
+func GenerateCashierShiftReportPDF(report response.CashierShiftReport) (*bytes.Buffer, error) {
+	shift := report.Shift
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+
+	pdf.SetFont("Arial", "B", 14)
+	pdf.CellFormat(190, 8, report.SchoolName, "0", 1, "C", false, 0, "")
+	pdf.SetDrawColor(89, 89, 89)
+	pdf.SetLineWidth(0.3)
+	pdf.Line(10, pdf.GetY()+2, 200, pdf.GetY()+2)
+	pdf.Ln(5)
+	pdf.SetFont("Arial", "B", 16)
+	pdf.CellFormat(190, 10, "LAPORAN SHIFT KASIR", "0", 1, "C", false, 0, "")
+	pdf.SetLineWidth(0.5)
+	pdf.Line(10, pdf.GetY()+2, 200, pdf.GetY()+2)
+	pdf.Ln(10)
+
+	closedAt := "-"
+	if shift.ClosedAt != nil {
+		closedAt = shift.ClosedAt.Format("02/01/2006 15:04")
+	}
+
+	renderShiftField := func(leftLabel, leftValue, rightLabel, rightValue string) {
+		pdf.SetFont("Arial", "", 10)
+		pdf.SetTextColor(89, 89, 89)
+		pdf.CellFormat(95, 6, leftLabel, "0", 0, "", false, 0, "")
+		pdf.CellFormat(95, 6, rightLabel, "0", 1, "", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetFont("Arial", "B", 10)
+		pdf.CellFormat(95, 6, leftValue, "0", 0, "", false, 0, "")
+		pdf.CellFormat(95, 6, rightValue, "0", 1, "", false, 0, "")
+		pdf.Ln(1)
+	}
+
+	renderShiftField("Kasir", shift.Username, "Status", strings.Title(shift.Status))
+	renderShiftField("Dibuka", shift.OpenedAt.Format("02/01/2006 15:04"), "Ditutup", closedAt)
+	pdf.Ln(4)
+
+	pdf.SetFont("Arial", "", 10)
+	pdf.Line(10, pdf.GetY(), 200, pdf.GetY())
+	pdf.Ln(2)
+	pdf.SetTextColor(89, 89, 89)
+	pdf.CellFormat(10, 6, "No", "0", 0, "C", false, 0, "")
+	pdf.CellFormat(40, 6, "No Invoice", "0", 0, "", false, 0, "")
+	pdf.CellFormat(25, 6, "Waktu", "0", 0, "", false, 0, "")
+	pdf.CellFormat(70, 6, "Nama Siswa", "0", 0, "", false, 0, "")
+	pdf.CellFormat(45, 6, "Uang Masuk (Rp.)", "0", 1, "R", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(2)
+
+	for rw, item := range report.Transactions {
+		pdf.SetFont("Arial", "", 9)
+		pdf.Line(10, pdf.GetY(), 200, pdf.GetY())
+		pdf.Ln(1)
+		pdf.CellFormat(10, 6, fmt.Sprintf("%d.", rw+1), "", 0, "C", false, 0, "")
+		pdf.CellFormat(40, 6, item.InvoiceNumber, "0", 0, "", false, 0, "")
+		pdf.CellFormat(25, 6, item.CreatedAt.Format("15:04"), "0", 0, "", false, 0, "")
+		pdf.CellFormat(70, 6, item.StudentName, "0", 0, "", false, 0, "")
+		pdf.CellFormat(45, 6, formatToIDR(item.CashAmount)+",00", "0", 1, "R", false, 0, "")
+		pdf.Ln(1)
+	}
+
+	pdf.Line(10, pdf.GetY(), 200, pdf.GetY())
+	pdf.Ln(4)
+
+	countedCash := "-"
+	if shift.CountedCash != nil {
+		countedCash = formatToIDR(*shift.CountedCash)
+	}
+	variance := "-"
+	if shift.Variance != nil {
+		variance = formatToIDR(*shift.Variance)
+	}
+
+	renderCashierShiftTotal(pdf, fmt.Sprintf("Jumlah Transaksi: %d", shift.TotalTransaction), "")
+	renderCashierShiftTotal(pdf, "Modal Awal", formatToIDR(shift.OpeningFloat))
+	renderCashierShiftTotal(pdf, "Uang Masuk", formatToIDR(shift.CashReceived))
+	renderCashierShiftTotal(pdf, "Kas Seharusnya", formatToIDR(shift.ExpectedCash))
+	renderCashierShiftTotal(pdf, "Kas Dihitung", countedCash)
+	renderCashierShiftTotal(pdf, "Selisih", variance)
+
+	if shift.Note != "" {
+		pdf.Ln(6)
+		pdf.SetFont("Arial", "", 10)
+		pdf.SetTextColor(89, 89, 89)
+		pdf.CellFormat(95, 6, "Catatan:", "0", 1, "", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetFont("Arial", "B", 10)
+		pdf.MultiCell(0, 5, shift.Note, "", "L", false)
+	}
+
+	buffer := new(bytes.Buffer)
+	if err := pdf.Output(buffer); err != nil {
+		return nil, fmt.Errorf("failed to generate PDF: %v", err)
+	}
+	return buffer, nil
+}
+
+func renderCashierShiftTotal(pdf *gofpdf.Fpdf, label string, value string) {
+	pdf.SetFont("Arial", "", 10)
+	pdf.SetTextColor(89, 89, 89)
+	pdf.CellFormat(140, 6, label, "0", 0, "R", false, 0, "")
+	pdf.SetFont("Arial", "B", 10)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(50, 6, value, "0", 1, "R", false, 0, "")
+}
@@ -0,0 +1,87 @@
+package utilities
+
+import (
+	"fmt"
+	"time"
+
+	response "schoolPayment/dtos/response"
+	"schoolPayment/models"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// GenerateRefundReceiptPDF writes the refund receipt to a temporary file and returns its name.
+// The caller is responsible for removing the file once it has been sent.
+func GenerateRefundReceiptPDF(receipt response.RefundReceipt, school models.School) (string, error) {
+	filename := "Bukti_Pengembalian_Dana " + receipt.InvoiceNumber + " " + time.Now().Format("02-01-2006 15.04") + ".pdf"
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+
+	pdf.SetFont("Arial", "B", 14)
+	pdf.CellFormat(190, 8, school.SchoolName, "0", 1, "C", false, 0, "")
+	pdf.SetDrawColor(89, 89, 89)
+	pdf.SetLineWidth(0.3)
+	pdf.Line(10, pdf.GetY()+2, 200, pdf.GetY()+2)
+	pdf.Ln(5)
+	pdf.SetFont("Arial", "B", 16)
+	pdf.CellFormat(190, 10, "BUKTI PENGEMBALIAN DANA", "0", 1, "C", false, 0, "")
+	pdf.SetLineWidth(0.5)
+	pdf.Line(10, pdf.GetY()+2, 200, pdf.GetY()+2)
+	pdf.Ln(10)
+
+	renderRefundField := func(leftLabel, leftValue, rightLabel, rightValue string) {
+		pdf.SetFont("Arial", "", 10)
+		pdf.SetTextColor(89, 89, 89)
+		pdf.CellFormat(95, 6, leftLabel, "0", 0, "", false, 0, "")
+		pdf.CellFormat(95, 6, rightLabel, "0", 1, "", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetFont("Arial", "B", 10)
+		pdf.CellFormat(95, 6, leftValue, "0", 0, "", false, 0, "")
+		pdf.CellFormat(95, 6, rightValue, "0", 1, "", false, 0, "")
+		pdf.Ln(1)
+	}
+
+	renderRefundField("No Invoice", receipt.InvoiceNumber, "NIS", receipt.StudentNis)
+	renderRefundField("Tanggal Pengembalian", receipt.RefundDate, "Nama Siswa", receipt.StudentName)
+	renderRefundField("Jenis Pengembalian", receipt.RefundType, "Metode Pengembalian", receipt.RefundMethod)
+	pdf.Ln(4)
+
+	pdf.SetFont("Arial", "", 10)
+	pdf.Line(10, pdf.GetY(), 200, pdf.GetY())
+	pdf.Ln(2)
+	pdf.SetTextColor(89, 89, 89)
+	pdf.CellFormat(10, 6, "No", "0", 0, "C", false, 0, "")
+	pdf.CellFormat(120, 6, "Keterangan Tagihan", "0", 0, "", false, 0, "")
+	pdf.CellFormat(60, 6, "Jumlah (Rp.)", "0", 1, "R", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(2)
+
+	for rw, item := range receipt.ListBilling {
+		pdf.SetFont("Arial", "B", 10)
+		pdf.Line(10, pdf.GetY(), 200, pdf.GetY())
+		pdf.Ln(2)
+		pdf.CellFormat(10, 6, fmt.Sprintf("%d.", rw+1), "", 0, "C", false, 0, "")
+		pdf.CellFormat(120, 6, item.DetailBillingName, "0", 0, "", false, 0, "")
+		pdf.CellFormat(60, 6, formatToIDR(item.Amount)+",00", "0", 1, "R", false, 0, "")
+		pdf.Ln(2)
+	}
+
+	pdf.Line(10, pdf.GetY(), 200, pdf.GetY())
+	pdf.Ln(2)
+	renderRow(pdf, "Total Pengembalian", formatToIDR(receipt.Amount), 10, 15, 4, 200.0)
+
+	pdf.Ln(10)
+	pdf.SetFont("Arial", "", 10)
+	pdf.SetTextColor(89, 89, 89)
+	pdf.CellFormat(95, 6, "Alasan:", "0", 1, "", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont("Arial", "B", 10)
+	pdf.MultiCell(0, 5, receipt.Reason, "", "L", false)
+
+	if err := pdf.OutputFileAndClose(filename); err != nil {
+		return "", fmt.Errorf("failed to save generated PDF: %v", err)
+	}
+
+	return filename, nil
+}
@@ -966,3 +966,100 @@ func GenerateEmailBodyBillingReminder() string {
     return transactionTemplate
 }
 
+
+func GenerateEmailBodyRefund() string {
+	const refundTemplate = `
+		<!DOCTYPE html>
+		<html lang="id">
+		<head>
+			<meta charset="UTF-8">
+			<meta name="viewport" content="width=device-width, initial-scale=1.0">
+			<style>
+				body {
+					font-family: Arial, sans-serif;
+					background-color: #f5f5f5;
+					color: #333333;
+					margin: 0;
+					padding: 20px;
+				}
+				.email-container {
+					width: 100%;
+					max-width: 600px;
+					margin: 0 auto;
+					background-color: #ffffff;
+					padding: 20px;
+					border-radius: 8px;
+					box-shadow: 0 0 10px rgba(0, 0, 0, 0.1);
+				}
+				.email-header img {
+					max-width: 80px;
+				}
+				.email-header h1 {
+					font-size: 18px;
+					font-weight: 700;
+					color: #333333;
+					margin-top: 5px;
+				}
+				.invoice-info {
+					background-color: #f7f7f7;
+					border-radius: 8px;
+					padding: 15px;
+					margin-bottom: 20px;
+					width: 70%;
+				}
+				.invoice-info .label {
+					color: #595959;
+					font-weight: 500;
+				}
+				.invoice-info .total-payment {
+					color: #388AAF;
+					font-size: 24px;
+					font-weight: bold;
+					padding: 10px;
+				}
+				.invoice-field {
+					display: flex;
+					justify-content:space-between;
+					padding: 10px;
+				}
+				.footer {
+					font-size: 12px;
+					color: #666666;
+					margin-top: 20px;
+				}
+				.footer p {
+					margin: 2px 0;
+				}
+			</style>
+		</head>
+		<body>
+			<div class="email-container">
+				<div class="email-header">
+					<img src="{{.SchoolLogo}}" alt="Logo Sekolah">
+					<h1>Pengembalian Dana Berhasil</h1>
+				</div>
+
+				<div class="email-content">
+					<h2>Kepada bapak/ibu wali murid dari <span>{{.StudentName}}</span>,</h2>
+					<p>Dana pembayaran Anda telah <b>dikembalikan</b> dengan detail sebagai berikut. Bukti pengembalian dana terlampir pada email ini.</p>
+
+					<div style="display:flex; justify-content:center">
+						<div class="invoice-info">
+							<div class="invoice-field"><span class="label">No Invoice</span> <b>{{.InvoiceNumber}}</b></div>
+							<div class="invoice-field"><span class="label">Tanggal Pengembalian</span> <b>{{.PaymentDate}}</b></div>
+							<div class="invoice-field"><span class="label">Total Pengembalian</span></div>
+							<p class="total-payment">Rp{{.TotalPayment}}</p>
+						</div>
+					</div>
+				</div>
+
+				<div class="footer">
+					<p>{{.SchoolName}}</p>
+					<p>©{{.Year}} {{.SchoolName}}. All Rights Reserved</p>
+				</div>
+			</div>
+		</body>
+		</html>
+	`
+	return refundTemplate
+}
@@ -0,0 +1,320 @@
+package utilities
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	request "schoolPayment/dtos/request"
+	models "schoolPayment/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const defaultEndpointXendit = "https://api.xendit.co"
+
+var xenditHttpClient = &http.Client{Timeout: 30 * time.Second}
+
+// xenditGateway is the PaymentGateway backed by the Xendit Invoice API.
+type xenditGateway struct{}
+
+type xenditInvoiceRequest struct {
+	ExternalID      string   `json:"external_id"`
+	Amount          int64    `json:"amount"`
+	Description     string   `json:"description"`
+	PaymentMethods  []string `json:"payment_methods,omitempty"`
+	InvoiceDuration int64    `json:"invoice_duration,omitempty"`
+	// SuccessRedirectURL is where the parent returns after paying, like the Snap finish callback
+	SuccessRedirectURL string                 `json:"success_redirect_url,omitempty"`
+	Customer           *xenditInvoiceCustomer `json:"customer,omitempty"`
+}
+
+type xenditInvoiceCustomer struct {
+	GivenNames   string `json:"given_names,omitempty"`
+	Email        string `json:"email,omitempty"`
+	MobileNumber string `json:"mobile_number,omitempty"`
+}
+
+type xenditInvoice struct {
+	ID            string  `json:"id"`
+	ExternalID    string  `json:"external_id"`
+	Status        string  `json:"status"`
+	Amount        float64 `json:"amount"`
+	InvoiceURL    string  `json:"invoice_url"`
+	ExpiryDate    string  `json:"expiry_date"`
+	PaymentMethod string  `json:"payment_method"`
+	PaidAt        string  `json:"paid_at"`
+	Currency      string  `json:"currency"`
+}
+
+type xenditInvoiceCallback struct {
+	ID             string  `json:"id"`
+	ExternalID     string  `json:"external_id"`
+	Status         string  `json:"status"`
+	Amount         float64 `json:"amount"`
+	PaidAmount     float64 `json:"paid_amount"`
+	PaymentMethod  string  `json:"payment_method"`
+	BankCode       string  `json:"bank_code"`
+	PaymentChannel string  `json:"payment_channel"`
+	PaidAt         string  `json:"paid_at"`
+	Currency       string  `json:"currency"`
+}
+
+type xenditRefundRequest struct {
+	InvoiceID   string            `json:"invoice_id"`
+	ReferenceID string            `json:"reference_id"`
+	Amount      int64             `json:"amount"`
+	Reason      string            `json:"reason"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+type xenditErrorResponse struct {
+	ErrorCode string `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+func (gateway *xenditGateway) Name() string {
+	return PaymentGatewayXendit
+}
+
+// mapToXenditPaymentMethod maps our master payment method to the Xendit invoice payment_methods value.
+func mapToXenditPaymentMethod(paymentMethod *models.PaymentMethod) string {
+	switch paymentMethod.PaymentMethod {
+	case "VA":
+		switch paymentMethod.BankCode {
+		case "002":
+			return "BRI"
+		case "008":
+			return "MANDIRI"
+		case "009":
+			return "BNI"
+		case "014":
+			return "BCA"
+		case "013":
+			return "PERMATA"
+		case "022":
+			return "CIMB"
+		}
+	case "CC":
+		return "CREDIT_CARD"
+	case "QR":
+		return "QRIS"
+	}
+	return ""
+}
+
+// mapXenditInvoiceStatus converts a Xendit invoice status to the Midtrans transaction_status vocabulary.
+func mapXenditInvoiceStatus(status string) string {
+	switch status {
+	case "PAID", "SETTLED":
+		return "settlement"
+	case "EXPIRED":
+		return "expire"
+	case "PENDING":
+		return "pending"
+	}
+	return status
+}
+
+func (gateway *xenditGateway) CreateCharge(charge PaymentGatewayCharge) (*PaymentGatewayChargeResult, error) {
+	paymentMethod := mapToXenditPaymentMethod(charge.PaymentMethod)
+	if paymentMethod == "" {
+		return nil, fmt.Errorf("unsupported payment method or bank code")
+	}
+
+	req := xenditInvoiceRequest{
+		ExternalID:         charge.OrderID,
+		Amount:             charge.Amount,
+		Description:        charge.Description,
+		PaymentMethods:     []string{paymentMethod},
+		InvoiceDuration:    int64(charge.Expiry / time.Second),
+		SuccessRedirectURL: charge.FinishURL,
+	}
+	if charge.Customer != nil {
+		req.Customer = &xenditInvoiceCustomer{
+			GivenNames:   strings.TrimSpace(charge.Customer.FirstName + " " + charge.Customer.LastName),
+			Email:        charge.Customer.Email,
+			MobileNumber: charge.Customer.Phone,
+		}
+	}
+
+	credential, err := GetXenditCredentialBySchoolID(charge.SchoolID)
+	if err != nil {
+		return nil, err
+	}
+
+	var invoice xenditInvoice
+	_, err = sendXenditRequest(credential, http.MethodPost, "/v2/invoices", req, &invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stored in the same shape as a Snap response so the payment log can be read the same way
+	redirect, err := json.Marshal(map[string]string{
+		"token":        invoice.ID,
+		"redirect_url": invoice.InvoiceURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaymentGatewayChargeResult{
+		Token:        invoice.ID,
+		RedirectURL:  invoice.InvoiceURL,
+		RequestBody:  req,
+		ResponseBody: string(redirect),
+	}, nil
+}
+
+// getInvoiceByOrderID looks the invoice up with the Xendit account of the school that owns the order.
+func (gateway *xenditGateway) getInvoiceByOrderID(orderID string) (*xenditInvoice, *XenditCredential, string, error) {
+	credential, err := GetXenditCredentialByOrderID(orderID)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	var invoices []xenditInvoice
+	responseBody, err := sendXenditRequest(credential, http.MethodGet, "/v2/invoices?external_id="+url.QueryEscape(orderID), nil, &invoices)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if len(invoices) == 0 {
+		return nil, nil, "", fmt.Errorf("%w: %s", ErrPaymentGatewayOrderNotFound, orderID)
+	}
+	return &invoices[0], credential, responseBody, nil
+}
+
+func (gateway *xenditGateway) CheckStatus(orderID string) (*PaymentGatewayStatus, error) {
+	invoice, _, responseBody, err := gateway.getInvoiceByOrderID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaymentGatewayStatus{
+		OrderID:           orderID,
+		TransactionStatus: mapXenditInvoiceStatus(invoice.Status),
+		GrossAmount:       strconv.FormatFloat(invoice.Amount, 'f', 2, 64),
+		ResponseBody:      responseBody,
+		Notification:      *newXenditWebhookPayload(invoice.ID, invoice.ExternalID, invoice.Status, invoice.Amount, invoice.PaymentMethod, invoice.Currency, invoice.PaidAt),
+	}, nil
+}
+
+func (gateway *xenditGateway) Cancel(orderID string) error {
+	invoice, credential, _, err := gateway.getInvoiceByOrderID(orderID)
+	if err != nil {
+		return err
+	}
+
+	_, err = sendXenditRequest(credential, http.MethodPost, "/invoices/"+invoice.ID+"/expire!", nil, nil)
+	return err
+}
+
+func (gateway *xenditGateway) Refund(orderID string, refundKey string, amount int64, reason string) (string, error) {
+	invoice, credential, _, err := gateway.getInvoiceByOrderID(orderID)
+	if err != nil {
+		return "", err
+	}
+
+	req := xenditRefundRequest{
+		InvoiceID:   invoice.ID,
+		ReferenceID: refundKey,
+		Amount:      amount,
+		Reason:      "OTHERS",
+		Metadata:    map[string]string{"reason": reason},
+	}
+	return sendXenditRequest(credential, http.MethodPost, "/refunds", req, nil)
+}
+
+// VerifyNotification checks the callback token Xendit sends with every invoice callback against
+// the token of the school that owns the order.
+func (gateway *xenditGateway) VerifyNotification(ctx *fiber.Ctx) (*request.WebhookPayload, error) {
+	var callback xenditInvoiceCallback
+	if err := ctx.BodyParser(&callback); err != nil {
+		return nil, err
+	}
+
+	credential, err := GetXenditCredentialByOrderID(callback.ExternalID)
+	if err != nil {
+		return nil, err
+	}
+	if credential.CallbackToken == "" || ctx.Get("x-callback-token") != credential.CallbackToken {
+		return nil, fmt.Errorf("invalid xendit callback token")
+	}
+
+	return newXenditWebhookPayload(callback.ID, callback.ExternalID, callback.Status, callback.Amount, callback.PaymentMethod, callback.Currency, callback.PaidAt), nil
+}
+
+// newXenditWebhookPayload converts a Xendit invoice into the notification payload the webhook
+// state machine understands. The Xendit status is kept as status code.
+func newXenditWebhookPayload(invoiceID, externalID, status string, amount float64, paymentMethod, currency, paidAt string) *request.WebhookPayload {
+	payload := &request.WebhookPayload{
+		TransactionID:     invoiceID,
+		OrderID:           externalID,
+		TransactionStatus: mapXenditInvoiceStatus(status),
+		StatusCode:        status,
+		GrossAmount:       strconv.FormatFloat(amount, 'f', 2, 64),
+		PaymentType:       paymentMethod,
+		Currency:          currency,
+	}
+	if paidAt != "" {
+		payload.SettlementTime = &paidAt
+	}
+	return payload
+}
+
+// sendXenditRequest calls the Xendit API with the school's secret key as basic auth and returns the raw body.
+func sendXenditRequest(credential *XenditCredential, method string, path string, body interface{}, result interface{}) (string, error) {
+	endpoint := os.Getenv("ENDPOINT_XENDIT")
+	if endpoint == "" {
+		endpoint = defaultEndpointXendit
+	}
+
+	var reader io.Reader
+	if body != nil {
+		requestBody, err := json.Marshal(body)
+		if err != nil {
+			return "", err
+		}
+		reader = bytes.NewReader(requestBody)
+	}
+
+	req, err := http.NewRequest(method, endpoint+path, reader)
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(credential.SecretKey, "")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := xenditHttpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiError xenditErrorResponse
+		if err := json.Unmarshal(responseBody, &apiError); err != nil || apiError.Message == "" {
+			return "", fmt.Errorf("xendit request failed with status %d", resp.StatusCode)
+		}
+		return "", fmt.Errorf("%s", apiError.Message)
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(responseBody, result); err != nil {
+			return "", err
+		}
+	}
+	return string(responseBody), nil
+}
@@ -0,0 +1,60 @@
+package utilities
+
+import (
+	"os"
+
+	models "schoolPayment/models"
+	"schoolPayment/repositories"
+)
+
+// XenditCredential is the Xendit account used to talk to Xendit for one school.
+type XenditCredential struct {
+	SecretKey     string
+	CallbackToken string
+}
+
+// xenditCredentialFromConfig decrypts the school's Xendit account. Schools without their own
+// secret key keep using the shared account from the environment.
+func xenditCredentialFromConfig(config *models.SchoolPaymentConfig) (*XenditCredential, error) {
+	if config == nil || config.XenditSecretKey == "" {
+		return &XenditCredential{
+			SecretKey:     os.Getenv("XENDIT_SECRET_KEY"),
+			CallbackToken: os.Getenv("XENDIT_CALLBACK_TOKEN"),
+		}, nil
+	}
+
+	secretKey, err := DecryptCredential(config.XenditSecretKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// A school with its own account must have its own callback token, an empty one fails every callback
+	var callbackToken string
+	if config.XenditCallbackToken != "" {
+		callbackToken, err = DecryptCredential(config.XenditCallbackToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &XenditCredential{
+		SecretKey:     secretKey,
+		CallbackToken: callbackToken,
+	}, nil
+}
+
+func GetXenditCredentialBySchoolID(schoolID uint) (*XenditCredential, error) {
+	config, err := repositories.GetSchoolPaymentConfig(schoolID)
+	if err != nil {
+		return nil, err
+	}
+	return xenditCredentialFromConfig(config)
+}
+
+func GetXenditCredentialByOrderID(orderID string) (*XenditCredential, error) {
+	config, err := repositories.GetSchoolPaymentConfigByOrderID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	return xenditCredentialFromConfig(config)
+}
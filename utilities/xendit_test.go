@@ -0,0 +1,47 @@
+package utilities
+
+import (
+	"testing"
+
+	models "schoolPayment/models"
+)
+
+func TestXenditCredentialFromConfig(t *testing.T) {
+	t.Setenv("PAYMENT_CREDENTIAL_KEY", "test-credential-key")
+	t.Setenv("XENDIT_SECRET_KEY", "xnd_shared")
+	t.Setenv("XENDIT_CALLBACK_TOKEN", "shared-token")
+
+	credential, err := xenditCredentialFromConfig(&models.SchoolPaymentConfig{PaymentGateway: PaymentGatewayXendit})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if credential.SecretKey != "xnd_shared" || credential.CallbackToken != "shared-token" {
+		t.Errorf("expected the shared account for a school without its own, got %+v", credential)
+	}
+
+	secretKey, err := EncryptCredential("xnd_school")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	callbackToken, err := EncryptCredential("school-token")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	credential, err = xenditCredentialFromConfig(&models.SchoolPaymentConfig{XenditSecretKey: secretKey, XenditCallbackToken: callbackToken})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if credential.SecretKey != "xnd_school" || credential.CallbackToken != "school-token" {
+		t.Errorf("expected the school's own account, got %+v", credential)
+	}
+
+	// The shared callback token must never pass for a school with its own account
+	credential, err = xenditCredentialFromConfig(&models.SchoolPaymentConfig{XenditSecretKey: secretKey})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if credential.CallbackToken != "" {
+		t.Errorf("expected no callback token, got %q", credential.CallbackToken)
+	}
+}